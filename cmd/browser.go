@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	browserDir    string
+	browserSHA256 string
+)
+
+var browserCmd = &cobra.Command{
+	Use:   "browser",
+	Short: "Manage the headless Chromium used by --renderer chrome",
+}
+
+var browserInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: fmt.Sprintf("Download headless Chromium %s", scraper.PinnedBrowserVersion),
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		exe, err := scraper.InstallBrowser(browserDir, browserSHA256, messages())
+		if err != nil {
+			return fmt.Errorf("failed to install browser: %w", err)
+		}
+		if jsonOutput() {
+			return printJSON(browserResult{Path: exe, Version: scraper.PinnedBrowserVersion})
+		}
+		return nil
+	},
+}
+
+var browserPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the installed headless Chromium",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		exe := scraper.InstalledBrowser(browserDir)
+		if exe == "" {
+			return fmt.Errorf("no browser installed in %s, run \"scrapdf browser install\"", browserDir)
+		}
+		if jsonOutput() {
+			return printJSON(browserResult{Path: exe})
+		}
+		fmt.Println(exe)
+		return nil
+	},
+}
+
+var browserUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the downloaded headless Chromium",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if err := scraper.RemoveBrowser(browserDir); err != nil {
+			return fmt.Errorf("failed to remove browser: %w", err)
+		}
+		if jsonOutput() {
+			return printJSON(browserResult{Removed: browserDir})
+		}
+		fmt.Printf("Removed %s\n", browserDir)
+		return nil
+	},
+}
+
+// browserResult is printed by the browser commands with --output-format
+// json.
+type browserResult struct {
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Removed string `json:"removed,omitempty"`
+}
+
+func init() {
+	browserCmd.PersistentFlags().StringVar(&browserDir, "dir", scraper.DefaultBrowserDir(), "Directory the browser is installed in")
+	browserInstallCmd.Flags().StringVar(&browserSHA256, "sha256", "", "SHA-256 the download must have, in hex, for platforms without a pinned one")
+	browserCmd.AddCommand(browserInstallCmd, browserPathCmd, browserUninstallCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+)
+
+// printEstimate samples the crawl of inputURL with the settings of s and
+// prints the projected archive size and duration.
+func printEstimate(s *scraper.Scraper, inputURL string, sample int) error {
+	fmt.Fprintf(messages(), "Sampling %d pages of %s\n", sample, s.Redact(inputURL))
+	est, err := s.EstimateCrawl(inputURL, sample)
+	if err != nil {
+		return fmt.Errorf("failed to estimate the crawl: %w", err)
+	}
+	if jsonLines {
+		return printJSONLine(est)
+	}
+	if jsonOutput() {
+		return printJSON(est)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\nSampled:\t%d pages in %s, %d archived\n", est.Sampled, est.Elapsed.Round(time.Millisecond), est.Archived)
+	if est.Archived > 0 {
+		fmt.Fprintf(w, "Average page:\t%s, rendered in %s\n", scraper.FormatSize(uint64(est.AverageSize)), est.AverageRender.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "Linked, not fetched:\t%d pages\n", est.Discovered)
+	if est.SitemapPages >= 0 {
+		fmt.Fprintf(w, "In sitemaps:\t%d pages\n", est.SitemapPages)
+	}
+	switch {
+	case est.FromSitemap:
+		fmt.Fprintf(w, "Projected pages:\t%d, from the sitemaps\n", est.Pages)
+	case est.Discovered > 0:
+		fmt.Fprintf(w, "Projected pages:\tat least %d, more behind the pages not fetched\n", est.Pages)
+	default:
+		fmt.Fprintf(w, "Projected pages:\t%d, the whole site was sampled\n", est.Pages)
+	}
+	fmt.Fprintf(w, "Projected archive:\t%s\n", scraper.FormatSize(uint64(est.ArchiveSize)))
+	precision := time.Second
+	if est.Duration < time.Minute {
+		precision = 100 * time.Millisecond
+	}
+	fmt.Fprintf(w, "Projected duration:\t%s\n", est.Duration.Round(precision))
+	return w.Flush()
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+)
+
+// maxListedFailures is the most failures listed at the end of a run, the
+// others are only in errors.csv.
+const maxListedFailures = 20
+
+// printFailures lists the pages the crawl failed on at the end of the run,
+// so they don't get lost in its messages.
+func printFailures(failures []scraper.Failure) {
+	if len(failures) == 0 {
+		return
+	}
+	if singlePDF || format == scraper.FormatEPUB {
+		fmt.Printf("\n%d pages failed:\n", len(failures))
+	} else {
+		fmt.Printf("\n%d pages failed, all listed in errors.csv in the archive:\n", len(failures))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, f := range failures {
+		if i == maxListedFailures {
+			fmt.Fprintf(w, "  ... and %d more\n", len(failures)-i)
+			break
+		}
+		status := ""
+		if f.Status != 0 {
+			status = strconv.Itoa(f.Status)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", f.Stage, status, f.URL, f.Error)
+	}
+	w.Flush()
+}
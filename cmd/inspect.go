@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [archive]",
+	Short: "Describe the format and contents of an archive",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		info, err := scraper.InspectArchive(args[0])
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(info)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Archive:\t%s\n", info.Path)
+		fmt.Fprintf(w, "Kind:\t%s\n", strings.ToUpper(info.Kind))
+		fmt.Fprintf(w, "Size:\t%s\n", scraper.FormatSize(uint64(info.Size)))
+		if info.Kind != scraper.KindPDF {
+			var methods []string
+			for name, n := range info.Methods {
+				methods = append(methods, fmt.Sprintf("%d %s", n, name))
+			}
+			sort.Strings(methods)
+			fmt.Fprintf(w, "Entries:\t%d (%s)\n", info.Entries, strings.Join(methods, ", "))
+			fmt.Fprintf(w, "Uncompressed:\t%s\n", scraper.FormatSize(info.UncompressedSize))
+			zip64 := "no"
+			if info.Zip64 {
+				zip64 = "yes"
+			}
+			if len(info.LargeEntries) > 0 {
+				zip64 += fmt.Sprintf(", %d entries of 4GB or more", len(info.LargeEntries))
+			}
+			fmt.Fprintf(w, "ZIP64:\t%s\n", zip64)
+		}
+		if m := info.Manifest; m != nil {
+			fmt.Fprintf(w, "Start URL:\t%s\n", m.StartURL)
+			fmt.Fprintf(w, "Generated:\t%s\n", m.GeneratedAt.Local().Format("2006-01-02 15:04"))
+			fmt.Fprintf(w, "Format:\t%s (renderer %s)\n", m.Format, m.Renderer)
+			fmt.Fprintf(w, "Pages:\t%d, %d failed\n", m.Pages, m.Failures)
+		}
+		return w.Flush()
+	},
+}
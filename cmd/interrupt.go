@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptContext returns a context done on the first SIGINT or SIGTERM,
+// so the crawl stops and archives the pages converted so far. Signals are
+// handled as usual again after the first, so a second one quits at once.
+// stop releases the signals.
+func interruptContext(out io.Writer) (ctx context.Context, stop func()) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			cancel()
+			fmt.Fprintln(out, "\nStopping the crawl, interrupt again to quit at once")
+		case <-done:
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		<-exited
+		cancel()
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output formats of --output-format.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var outputFormat string
+
+// checkOutputFormat rejects unknown --output-format values.
+func checkOutputFormat() error {
+	switch outputFormat {
+	case outputText, outputJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q (available: %s, %s)", outputFormat, outputText, outputJSON)
+}
+
+// jsonOutput reports whether results are printed as JSON for scripts.
+func jsonOutput() bool {
+	return outputFormat == outputJSON
+}
+
+// printJSON prints a command's result to stdout as indented JSON.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// printJSONLine prints v to stdout as one line of JSON, for --json.
+func printJSONLine(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// scripted reports whether stdout is read by a script, with --output-format
+// json or scrape's --json, so nothing is asked and no prose is printed.
+func scripted() bool {
+	return jsonOutput() || jsonLines
+}
+
+// messages returns where messages meant for people go: stdout, or nowhere
+// when stdout is kept for the JSON result.
+func messages() io.Writer {
+	if scripted() {
+		return io.Discard
+	}
+	return os.Stdout
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+)
+
+// printPlan crawls from inputURL with the settings of s without rendering
+// and prints the pages it would archive, with their depth.
+func printPlan(s *scraper.Scraper, inputURL string) error {
+	fmt.Fprintf(messages(), "Crawling %s without converting pages\n", s.Redact(inputURL))
+	plan, err := s.PlanCrawl(inputURL)
+	if err != nil {
+		return fmt.Errorf("failed to plan the crawl: %w", err)
+	}
+	if jsonLines {
+		// The last line, after the events of the crawl
+		return printJSONLine(struct {
+			Type string `json:"type"`
+			*scraper.CrawlPlan
+		}{"plan", plan})
+	}
+	if jsonOutput() {
+		return printJSON(plan)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nDEPTH\tURL\tTITLE")
+	for _, p := range plan.Pages {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", p.Depth, p.URL, p.Title)
+	}
+	if len(plan.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSKIPPED\tREASON")
+		for _, p := range plan.Skipped {
+			fmt.Fprintf(w, "%s\t%s\n", p.URL, p.Reason)
+		}
+	}
+	fmt.Fprintf(w, "\n%d pages would be archived, %d URLs skipped\n", len(plan.Pages), len(plan.Skipped))
+	return w.Flush()
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+// largeSite is the estimated page count above which probe suggests
+// fetching pages in parallel.
+const largeSite = 1000
+
+var probeCmd = &cobra.Command{
+	Use:   "probe [url]",
+	Short: "Summarize a site's robots.txt, sitemaps and server before crawling it",
+	Long: `Probe fetches the start page, robots.txt and sitemaps of a site, without
+crawling it, and suggests settings for the crawl: the delay robots.txt asks
+for, whether the site rate limits or needs JavaScript, and how many pages its
+sitemaps list.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		s := scraper.NewScraper(false, false)
+		var err error
+		if s.Headers, s.Cookies, err = requestAuth(); err != nil {
+			return err
+		}
+		if s.Include, err = parseURLPatterns(includes); err != nil {
+			return err
+		}
+		if s.Exclude, err = parseURLPatterns(excludes); err != nil {
+			return err
+		}
+		r, err := s.Probe(args[0])
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			r.URL, r.FinalURL = s.Redact(r.URL), s.Redact(r.FinalURL)
+			return printJSON(struct {
+				Report      *scraper.ProbeReport `json:"report"`
+				Suggestions []string             `json:"suggestions"`
+			}{r, append([]string{}, probeSuggestions(r)...)})
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "URL:\t%s\n", s.Redact(r.URL))
+		if r.FinalURL != r.URL {
+			fmt.Fprintf(w, "Redirected to:\t%s\n", s.Redact(r.FinalURL))
+		}
+		fmt.Fprintf(w, "Status:\t%d %s in %s (%s)\n", r.Status, http.StatusText(r.Status), r.Elapsed.Round(time.Millisecond), r.Proto)
+		if r.Server != "" {
+			fmt.Fprintf(w, "Server:\t%s\n", r.Server)
+		}
+		if r.CDN != "" {
+			fmt.Fprintf(w, "CDN:\t%s\n", r.CDN)
+		}
+		fmt.Fprintf(w, "Content type:\t%s\n", r.ContentType)
+		fmt.Fprintf(w, "Compressed:\t%s\n", yesNo(r.Compressed))
+		for i, h := range r.RateLimit {
+			label := ""
+			if i == 0 {
+				label = "Rate limits:"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", label, h)
+		}
+		if strings.Contains(r.ContentType, "html") {
+			fmt.Fprintf(w, "Start page:\t%d links to the site, %d scripts, %d characters of text\n", r.Links, r.Scripts, r.TextLength)
+		}
+
+		if rb := r.Robots; rb != nil {
+			fmt.Fprintf(w, "robots.txt:\t%s\n", rb.URL)
+			if rb.Group != "" {
+				fmt.Fprintf(w, "  Group:\t%s (as %q)\n", rb.Group, r.UserAgent)
+			}
+			if len(rb.Disallow) > 0 {
+				fmt.Fprintf(w, "  Disallow:\t%s\n", strings.Join(rb.Disallow, " "))
+			}
+			if len(rb.Allow) > 0 {
+				fmt.Fprintf(w, "  Allow:\t%s\n", strings.Join(rb.Allow, " "))
+			}
+			if rb.CrawlDelay > 0 {
+				fmt.Fprintf(w, "  Crawl delay:\t%s\n", rb.CrawlDelay)
+			}
+			start := "allowed"
+			if !rb.StartAllowed {
+				start = "disallowed"
+			}
+			fmt.Fprintf(w, "  Start URL:\t%s\n", start)
+		} else {
+			fmt.Fprintf(w, "robots.txt:\tnone\n")
+		}
+
+		if len(r.Sitemaps) == 0 {
+			fmt.Fprintf(w, "Sitemaps:\tnone\n")
+		}
+		for i, sm := range r.Sitemaps {
+			label := ""
+			if i == 0 {
+				label = "Sitemaps:"
+			}
+			var desc string
+			switch {
+			case sm.Err != "":
+				desc = "error: " + sm.Err
+			case sm.Index:
+				desc = fmt.Sprintf("index of %d sitemaps", sm.URLs)
+			default:
+				desc = fmt.Sprintf("%d pages, %d in scope", sm.URLs, sm.InScope)
+				if sm.LastMod != "" {
+					desc += ", last modified " + sm.LastMod
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s: %s\n", label, sm.URL, desc)
+		}
+		if r.EstimatedPages >= 0 {
+			fmt.Fprintf(w, "Estimated pages:\t%d\n", r.EstimatedPages)
+		} else {
+			fmt.Fprintf(w, "Estimated pages:\tunknown, no sitemap\n")
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		if suggestions := probeSuggestions(r); len(suggestions) > 0 {
+			fmt.Println("\nSuggested settings:")
+			for _, sg := range suggestions {
+				fmt.Println("  " + sg)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	f := probeCmd.Flags()
+	f.StringArrayVar(&includes, "include", nil, "Only count sitemap pages whose path matches this glob, e.g. '/docs/*', or 're:regex' on the URL (repeatable)")
+	f.StringArrayVar(&excludes, "exclude", nil, "Don't count sitemap pages whose path matches this glob, e.g. '/tags/*', or 're:regex' on the URL (repeatable)")
+	f.StringArrayVar(&headers, "header", nil, "Request header sent with every request, e.g. \"Authorization: Bearer ...\" (repeatable)")
+	f.StringArrayVar(&cookies, "cookie", nil, "Cookies sent with every request to the site, as name=value pairs separated by semicolons (repeatable)")
+	f.StringVar(&cookieFile, "cookie-file", "", "Cookies.txt file in the Netscape format, or a file holding a Cookie header, sent with requests to the site")
+}
+
+// probeSuggestions turns a probe report into scrape flags, each with the
+// reason it is suggested.
+func probeSuggestions(r *scraper.ProbeReport) []string {
+	var out []string
+	switch r.Status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		out = append(out, fmt.Sprintf("--header, --cookie or --session: the start page answered %d %s", r.Status, http.StatusText(r.Status)))
+	}
+	if rb := r.Robots; rb != nil {
+		if rb.CrawlDelay > 0 {
+			out = append(out, fmt.Sprintf("--delay %s: robots.txt asks for a crawl delay", rb.CrawlDelay))
+		}
+		if !rb.StartAllowed {
+			out = append(out, "robots.txt disallows the start URL: with --respect-robots nothing would be archived")
+		} else if len(rb.Disallow) > 0 {
+			out = append(out, fmt.Sprintf("--respect-robots: robots.txt disallows %d paths", len(rb.Disallow)))
+		}
+	}
+	limited := len(r.RateLimit) > 0 || r.Status == http.StatusTooManyRequests
+	if limited {
+		out = append(out, "--adaptive: the site rate limits, back off when it answers 429")
+	}
+	if strings.Contains(r.ContentType, "html") && r.Scripts > 0 && r.TextLength < 200 {
+		out = append(out, fmt.Sprintf("--render-js: the start page has %d scripts and little text, it is likely built by JavaScript", r.Scripts))
+	}
+	if r.EstimatedPages > largeSite && !limited && (r.Robots == nil || r.Robots.CrawlDelay == 0) {
+		out = append(out, fmt.Sprintf("--parallelism 4 --adaptive: the sitemaps list %d pages", r.EstimatedPages))
+	}
+	return out
+}
+
+// yesNo formats b for humans.
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
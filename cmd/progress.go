@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+)
+
+// progressWidth is the number of cells of the progress bar.
+const progressWidth = 24
+
+// clearLine moves to the start of the terminal line and erases it.
+const clearLine = "\r\033[K"
+
+// progressBar draws the progress of a crawl on the last line of the
+// terminal, redrawn in place, with the crawl's messages printed above it.
+type progressBar struct {
+	mu       sync.Mutex
+	term     io.Writer // where the bar is drawn
+	messages io.Writer
+	last     scraper.Progress
+	drawn    bool
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Write prints a message of the crawl above the bar.
+func (b *progressBar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.drawn {
+		fmt.Fprint(b.term, clearLine)
+	}
+	n, err := b.messages.Write(p)
+	if b.drawn {
+		b.draw()
+	}
+	return n, err
+}
+
+// update redraws the bar with p, and leaves it on its own line once the
+// crawl is done.
+func (b *progressBar) update(p scraper.Progress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last = p
+	b.draw()
+	b.drawn = !p.Done
+	if p.Done {
+		fmt.Fprintln(b.term)
+	}
+}
+
+// draw draws the bar with the last progress reported.
+func (b *progressBar) draw() {
+	p := b.last
+	filled := 0
+	if p.Discovered > 0 {
+		filled = progressWidth * p.Processed / p.Discovered
+	}
+	eta := "--"
+	switch {
+	case p.Done:
+		eta = "done"
+	case p.ETA > 0:
+		eta = p.ETA.Round(time.Second).String()
+	}
+	fmt.Fprintf(b.term, "%s[%s%s] %d/%d pages, %d archived, %d failed, %s elapsed, ETA %s",
+		clearLine, strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled),
+		p.Processed, p.Discovered, p.Converted, p.Failed, p.Elapsed.Round(time.Second), eta)
+}
+
+// showProgress draws the progress of the crawl of s on stderr when it is
+// a terminal, instead of a line per page, unless --no-progress is given
+// or a script reads the output.
+func showProgress(s *scraper.Scraper) {
+	if noProgress || scripted() || !isTerminal(os.Stderr) {
+		return
+	}
+	bar := &progressBar{term: os.Stderr, messages: os.Stdout}
+	s.Log = bar
+	s.OnProgress = bar.update
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var renderFrom string
+
+var renderCmd = &cobra.Command{
+	Use:   "render --from warc|cache [path] [url]",
+	Short: "Render the pages of a WARC file or response cache again, offline",
+	Long: `Render the pages of a WARC file or of a --cache-dir kept by scrape again,
+with the settings given, without fetching anything from the network.
+The crawl starts from the URL given, by default the first HTML page of
+the WARC file; a cache doesn't record its URLs, so it needs one.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var startURL string
+		if len(args) == 2 {
+			startURL = args[1]
+		}
+		switch renderFrom {
+		case "warc":
+			w, err := scraper.OpenWARC(args[0])
+			if err != nil {
+				return err
+			}
+			if startURL == "" {
+				if startURL = w.StartURL(); startURL == "" {
+					return fmt.Errorf("%s has no HTML page to start from, give the start URL", args[0])
+				}
+			}
+			fmt.Fprintf(messages(), "Replaying %d URLs captured in %s\n", w.Len(), args[0])
+			replayWARC = w
+		case "cache":
+			if _, err := os.Stat(args[0]); err != nil {
+				return err
+			}
+			if startURL == "" {
+				return fmt.Errorf("a cache doesn't record its URLs, give the start URL after %s", args[0])
+			}
+			cacheDir = args[0]
+		default:
+			return fmt.Errorf("--from must be warc or cache")
+		}
+		offline = true
+		result, err := runScrape(cmd, []string{startURL}, nil)
+		if result != nil {
+			if err := printResult(result); err != nil {
+				return err
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	addScrapeFlags(renderCmd)
+	renderCmd.Flags().StringVar(&renderFrom, "from", "", "Where the pages were captured: warc for a WARC file, or cache for a --cache-dir")
+	renderCmd.MarkFlagRequired("from")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	repairOutput string
+	repairForce  bool
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair [work directory]",
+	Short: "Salvage the pages of an archive a killed run left unfinished",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		output := repairOutput
+		if output == "" {
+			output = strings.TrimSuffix(filepath.Clean(args[0]), ".partial")
+		}
+		if _, err := os.Stat(output); err == nil && !repairForce && output != filepath.Clean(args[0]) {
+			return fmt.Errorf("%s already exists, use --force to replace it", output)
+		}
+		result, err := scraper.RepairArchive(args[0], repairOutput)
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(result)
+		}
+		fmt.Printf("Salvaged %d entries into %s\n", result.Entries, result.Output)
+		if result.Lost != "" {
+			fmt.Printf("Lost %s and the entries after it, which were not written yet\n", result.Lost)
+		}
+		return nil
+	},
+}
+
+func init() {
+	repairCmd.Flags().StringVarP(&repairOutput, "output", "o", "", "Path of the repaired archive (default: the work directory without .partial)")
+	repairCmd.Flags().BoolVarP(&repairForce, "force", "f", false, "Replace the output if it exists")
+}
@@ -10,6 +10,9 @@ var rootCmd = &cobra.Command{
 	Long: `scrapdf is a CLI tool that scrapes web pages and converts them to PDF.
 It recursively follows links within the same domain and creates a ZIP file
 containing all scraped pages as PDFs.`,
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		return checkOutputFormat()
+	},
 }
 
 func Execute() error {
@@ -17,5 +20,14 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output-format", outputText, "How results are printed: text, or json for scripts, with the progress of crawls as JSON Lines on stderr")
 	rootCmd.AddCommand(scrapeCmd)
+	rootCmd.AddCommand(browserCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(runsCmd)
+	rootCmd.AddCommand(inspectCmd)
+	rootCmd.AddCommand(repairCmd)
+	rootCmd.AddCommand(renderCmd)
+	rootCmd.AddCommand(probeCmd)
+	rootCmd.AddCommand(testCrawlCmd)
 }
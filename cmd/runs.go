@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runsHistory string
+	pruneKeep   int
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List past runs and apply retention to their archives",
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "ls [site]",
+	Short: "List recorded runs, optionally only those of a site",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		runs, err := (&scraper.History{Path: runsHistory}).Runs()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			site := runs[:0]
+			for _, r := range runs {
+				if r.Site == args[0] {
+					site = append(site, r)
+				}
+			}
+			runs = site
+		}
+		if jsonOutput() {
+			return printJSON(append([]scraper.Run{}, runs...))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTARTED\tSITE\tPAGES\tFAILURES\tARCHIVE")
+		for _, r := range runs {
+			archive := r.Archive
+			switch {
+			case r.Error != "":
+				archive = "failed: " + r.Error
+			case r.Pruned:
+				archive += " (pruned)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\n", r.ID, r.StartedAt.Local().Format("2006-01-02 15:04"), r.Site, r.Pages, r.Failures, archive)
+		}
+		return w.Flush()
+	},
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Print a recorded run as JSON",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid run ID %q", args[0])
+		}
+		run, err := (&scraper.History{Path: runsHistory}).Run(id)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+var runsPruneCmd = &cobra.Command{
+	Use:   "prune [site]",
+	Short: "Delete all but the newest archives of each site, or of one site",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		var site string
+		if len(args) == 1 {
+			site = args[0]
+		}
+		pruned, err := (&scraper.History{Path: runsHistory}).Prune(site, pruneKeep)
+		if jsonOutput() {
+			if err != nil {
+				return err
+			}
+			return printJSON(append([]scraper.Run{}, pruned...))
+		}
+		for _, r := range pruned {
+			fmt.Printf("Pruned run %d: %s\n", r.ID, r.Archive)
+		}
+		if err != nil {
+			return err
+		}
+		if len(pruned) == 0 {
+			fmt.Println("Nothing to prune")
+		}
+		return nil
+	},
+}
+
+func init() {
+	runsCmd.PersistentFlags().StringVar(&runsHistory, "history", scraper.DefaultHistoryPath(), "Run history file")
+	runsPruneCmd.Flags().IntVar(&pruneKeep, "keep", 5, "Number of archives to keep per site")
+	runsCmd.AddCommand(runsListCmd, runsShowCmd, runsPruneCmd)
+}
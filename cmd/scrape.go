@@ -2,23 +2,152 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
-	"github.com/ppicom/scrapedf/internal/scraper"
+	"github.com/ppicom/scrapedf/pkg/scraper"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputDir string
-	stripHTML bool
-	force     bool
-	clean     bool
+	outputDir   string
+	urlFile     string
+	maxDepth    int
+	maxPages    int
+	subdomains  bool
+	acceptTypes []string
+	stripHTML   bool
+	force       bool
+	resume      bool
+	clean       bool
+
+	readability bool
+
+	historyPath string
+	noHistory   bool
+	keep        int
+
+	stampSource      bool
+	pageHeader       string
+	pageFooter       string
+	locale           string
+	timeZone         string
+	noTOC            bool
+	archiveCover     string
+	respectRobots    bool
+	complianceReport bool
+
+	minWords           int
+	maxLinkDensity     float64
+	repeatPages        int
+	boilerplatePreview bool
+	stripRepeated      float64
+
+	logRequests bool
+
+	recipesDir string
+	noRecipes  bool
+	configPath string
+	includes   []string
+	excludes   []string
+	filter     string
+	redact     []string
+	pipeHTML   string
+	pipeText   string
+
+	noImages      bool
+	imageQuality  int
+	maxImageWidth int
+	cacheDir      string
+	offline       bool
+	replayWARC    *scraper.WARC
+
+	preset      string
+	order       string
+	tocSelector string
+	stitchPages bool
+	prefer      string
+	pageTimeout time.Duration
+	timeout     time.Duration
+	retries     int
+	backoff     time.Duration
+
+	streamThreshold string
+	maxBandwidth    string
+	delay           time.Duration
+	randomDelay     time.Duration
+	parallelism     int
+	maxConnsPerHost int
+	adaptive        bool
+
+	format        string
+	renderer      string
+	singlePDF     bool
+	outputMode    string
+	archiveFormat string
+	noZip         bool
+	renderJS      bool
+	browserPath   string
+	browserScript string
+	autoScroll    bool
+	maxScrolls    int
+	scrollSettle  time.Duration
+	typography    string
+	font          string
+	meta          []string
+	thumbnails    bool
+	searchIndex   bool
+	checkLinks    bool
+	attachments   bool
+	linkGraph     string
+	estimate      int
+	dryRun        bool
+	rewrites      []string
+	docsVersion   string
+
+	events        bool
+	eventsFile    string
+	evidence      bool
+	frontMatter   string
+	selfContained bool
+	timeServer    string
+
+	session     string
+	loginURL    string
+	loginFields []string
+
+	headers    []string
+	cookies    []string
+	cookieFile string
+
+	userAgent        string
+	rotateUserAgents bool
+
+	baseline string
+
+	archivePerURL bool
+	shareVisited  bool
+	jsonLines     bool
+
+	groupByAuthor bool
+	noProgress    bool
+	splitBy       string
+	noFallback    bool
+	pageSize      string
+	orientation   string
+	margin        string
+	noJavaScript  bool
+	linkURLs      bool
+	embeddings    string
 )
 
 // openDirectory opens the specified directory in the default file manager
@@ -41,67 +170,786 @@ func openDirectory(path string) error {
 }
 
 var scrapeCmd = &cobra.Command{
-	Use:   "scrape [url]",
+	Use:   "scrape [url]...",
 	Short: "Scrape a website and convert pages to PDF",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(_ *cobra.Command, args []string) error {
-		inputURL := args[0]
+	Long: `Scrape a website and convert pages to PDF. With several URLs, given as
+arguments or listed in --url-file, the crawl starts from each of them in
+turn and all pages go to one archive, named after the first URL, or with
+--archive-per-url each URL is crawled in its own job and archive.
 
-		parsedURL, err := url.Parse(inputURL)
-		if err != nil {
-			return fmt.Errorf("invalid URL: %w", err)
+The URL may also be a local directory of HTML files, such as a static
+site export: every HTML file in the tree is archived, following the links
+between them, and the archive is named after the directory.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputURLs := args
+		if urlFile != "" {
+			listed, err := readURLFile(urlFile)
+			if err != nil {
+				return err
+			}
+			inputURLs = append(inputURLs, listed...)
+		}
+		if len(inputURLs) == 0 {
+			return fmt.Errorf("give the URL to scrape, or a --url-file listing them")
+		}
+		if jsonLines && jsonOutput() {
+			return fmt.Errorf("--json prints JSON Lines, drop --output-format json")
+		}
+		if archivePerURL {
+			return runBatch(cmd, inputURLs)
+		}
+		if shareVisited {
+			return fmt.Errorf("--share-visited needs --archive-per-url")
 		}
+		result, err := runScrape(cmd, inputURLs, nil)
+		if result != nil {
+			if err := printResult(result); err != nil {
+				return err
+			}
+		}
+		return err
+	},
+}
 
-		outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.zip", parsedURL.Host))
-		absOutputPath, err := filepath.Abs(outputPath)
+// runBatch crawls from each of inputURLs in its own job, one after the
+// other, each to its own archive. A failed job doesn't stop the others.
+func runBatch(cmd *cobra.Command, inputURLs []string) error {
+	var shared *scraper.VisitedStore
+	if shareVisited {
+		shared = scraper.NewVisitedStore()
+	}
+	failed := 0
+	results := []*scrapeResult{}
+	for _, u := range inputURLs {
+		result, err := runScrape(cmd, []string{u}, shared)
+		if result != nil {
+			results = append(results, result)
+			if jsonLines {
+				// Each job's result as soon as it is done
+				if err := printResult(result); err != nil {
+					return err
+				}
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("failed to get absolute path: %w", err)
+			fmt.Fprintf(messages(), "Error: %s: %v\n", u, err)
+			failed++
+		}
+		if result != nil && result.Interrupted {
+			// The remaining jobs aren't started
+			break
+		}
+	}
+	if jsonOutput() {
+		if err := printJSON(results); err != nil {
+			return err
 		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs failed", failed, len(inputURLs))
+	}
+	if scripted() {
+		return nil
+	}
+	if err := openDirectory(outputDir); err != nil {
+		fmt.Printf("Note: Could not open the output directory automatically: %v\n", err)
+	}
+	return nil
+}
 
-		// Check if file exists and prompt for confirmation
-		if _, err := os.Stat(outputPath); err == nil && !force {
-			fmt.Printf("Warning: The file %s already exists.\n", outputPath)
-			fmt.Print("Do you want to replace it? [y/N]: ")
+// readURLFile reads the start URLs listed in file, one per line. Blank
+// lines and lines starting with # are skipped.
+func readURLFile(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --url-file: %w", err)
+	}
+	var urls []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("--url-file %s lists no URLs", file)
+	}
+	return urls, nil
+}
 
-			reader := bufio.NewReader(os.Stdin)
-			response, err := reader.ReadString('\n')
-			if err != nil {
-				return fmt.Errorf("failed to read user input: %w", err)
+// runScrape crawls from inputURLs with the settings of the scrape flags,
+// leaving out the pages of shared fetched by other jobs when it isn't nil.
+// The archive is named after the first URL. It returns the outcome of the
+// crawl, nil when none was made, such as for --estimate and --dry-run.
+func runScrape(cmd *cobra.Command, inputURLs []string, shared *scraper.VisitedStore) (*scrapeResult, error) {
+	s, err := newScraper(cmd)
+	if err != nil {
+		return nil, err
+	}
+	s.Shared = shared
+	out := messages()
+	if scripted() {
+		s.Log = io.Discard
+		s.EventStream = os.Stderr
+		if jsonLines {
+			s.EventStream = os.Stdout
+		}
+	}
+	inputURL := inputURLs[0]
+	s.Seeds = inputURLs[1:]
+	if !strings.Contains(inputURL, "://") {
+		if _, err := os.Stat(inputURL); err == nil {
+			// A local directory of HTML files
+			if inputURL, err = scraper.LocalURL(inputURL); err != nil {
+				return nil, err
 			}
+		}
+	}
 
-			response = strings.ToLower(strings.TrimSpace(response))
-			if response != "y" && response != "yes" {
-				fmt.Println("Operation cancelled")
-				return nil
-			}
+	parsedURL, err := url.Parse(inputURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	site := scraper.SiteName(parsedURL)
+
+	ext := "zip"
+	if singlePDF {
+		ext = "pdf"
+	}
+	if format == scraper.FormatEPUB {
+		ext = "epub"
+	}
+	if outputMode == scraper.OutputZip && !singlePDF && format != scraper.FormatEPUB && archiveFormat != "" {
+		ext = archiveFormat
+	}
+	if keep < 0 {
+		return nil, fmt.Errorf("--keep must be 0 or more")
+	}
+	if keep > 0 && noHistory {
+		return nil, fmt.Errorf("--keep needs the run history, drop --no-history")
+	}
+	if keep > 0 && splitBy != "" {
+		return nil, fmt.Errorf("--keep can't be combined with --split-by, which writes several archives per run")
+	}
+	if keep > 0 && resume {
+		return nil, fmt.Errorf("--resume can't be combined with --keep, which names every run's archive differently")
+	}
+	name := site
+	if keep > 0 {
+		// Kept archives need distinct names
+		name += "-" + time.Now().UTC().Format("20060102T150405Z")
+	}
+	outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.%s", name, ext))
+	kind := "file"
+	if outputMode == scraper.OutputDir {
+		outputPath, kind = filepath.Join(outputDir, name), "directory"
+	}
+	absOutputPath, err := filepath.Abs(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Check if file exists and prompt for confirmation
+	if _, err := os.Stat(outputPath); err == nil && !force && estimate == 0 && !dryRun {
+		if scripted() {
+			// stdin and stdout are the script's
+			return nil, fmt.Errorf("the %s %s already exists, use --force to replace it", kind, outputPath)
 		}
+		fmt.Printf("Warning: The %s %s already exists.\n", kind, outputPath)
+		fmt.Print("Do you want to replace it? [y/N]: ")
 
-		s := scraper.NewScraper(stripHTML, clean)
-		fmt.Printf("Starting to scrape %s\n", inputURL)
-		if err := s.ScrapeAndSave(inputURL, outputPath); err != nil {
-			return fmt.Errorf("failed to scrape website: %w", err)
+		reader := bufio.NewReader(os.Stdin)
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user input: %w", err)
+		}
+
+		response = strings.ToLower(strings.TrimSpace(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Operation cancelled")
+			return nil, nil
 		}
+	}
 
-		dir, file := filepath.Split(absOutputPath)
-		fmt.Printf("Successfully created ZIP file:\n")
+	if dryRun {
+		if estimate > 0 {
+			return nil, fmt.Errorf("--dry-run and --estimate can't be combined")
+		}
+		return nil, printPlan(s, inputURL)
+	}
+	if estimate > 0 {
+		return nil, printEstimate(s, inputURL, estimate)
+	}
+	if len(s.Seeds) > 0 {
+		fmt.Fprintf(out, "Starting to scrape %s and %d more start URLs\n", s.Redact(inputURL), len(s.Seeds))
+	} else {
+		fmt.Fprintf(out, "Starting to scrape %s\n", s.Redact(inputURL))
+	}
+	showProgress(s)
+	startedAt := time.Now().UTC()
+	ctx, stop := interruptContext(out)
+	scrapeErr := s.ScrapeAndSaveContext(ctx, inputURL, outputPath)
+	stop()
+	if !noHistory {
+		recordRun(s, site, inputURL, absOutputPath, startedAt, scrapeErr)
+	}
+	result := newScrapeResult(s, inputURL, startedAt)
+	if errors.Is(scrapeErr, scraper.ErrInterrupted) && result.Pages > 0 {
+		// The output holds the pages converted until then
+		result.Interrupted = true
+		scrapeErr = nil
+	}
+	if errors.Is(scrapeErr, scraper.ErrNoChanges) {
+		fmt.Fprintf(out, "No pages changed since %s, no archive written\n", baseline)
+		result.Unchanged = true
+		return result, nil
+	}
+	if scrapeErr != nil {
+		result.Error = scrapeErr.Error()
+		return result, fmt.Errorf("failed to scrape website: %w", scrapeErr)
+	}
+	if splitBy != "" {
+		return splitResult(s, result)
+	}
+	result.Archive = absOutputPath
+	if info, err := os.Stat(absOutputPath); err == nil && !info.IsDir() {
+		result.Bytes = info.Size()
+	}
+	if keep > 0 {
+		pruned, err := (&scraper.History{Path: historyPath}).Prune(site, keep)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: failed to prune old archives: %v\n", err)
+		}
+		for _, r := range pruned {
+			fmt.Fprintf(out, "Pruned run %d: %s\n", r.ID, r.Archive)
+			result.Pruned = append(result.Pruned, r.Archive)
+		}
+	}
+	if scripted() {
+		return result, nil
+	}
+
+	dir, file := filepath.Split(absOutputPath)
+	if outputMode == scraper.OutputDir {
+		dir = absOutputPath
+		fmt.Println("Successfully created output directory:")
+		fmt.Printf("  Directory: %s\n", dir)
+	} else {
+		fmt.Printf("Successfully created %s file:\n", strings.ToUpper(ext))
 		fmt.Printf("  Directory: %s\n", dir)
 		fmt.Printf("  File:      %s\n", file)
+	}
+	printFailures(result.Failures)
+
+	// Try to open the directory, once the whole batch is done
+	if archivePerURL {
+		return result, nil
+	}
+	if err := openDirectory(dir); err != nil {
+		fmt.Printf("Note: Could not open the output directory automatically: %v\n", err)
+	}
+
+	return result, nil
+}
+
+// scrapeResult is the outcome of a crawl, printed by --output-format json,
+// and by --json as the last line, of type "result".
+type scrapeResult struct {
+	Type      string            `json:"type,omitempty"`
+	URL       string            `json:"url"`
+	Archive   string            `json:"archive,omitempty"`
+	Bytes     int64             `json:"bytes,omitempty"`
+	Pages     int               `json:"pages"`
+	Failures  []scraper.Failure `json:"failures"`
+	StartedAt time.Time         `json:"started_at"`
+	Duration  float64           `json:"duration_ms"`
+	// Unchanged is set when --baseline found no page changed, and no
+	// archive was written.
+	Unchanged bool `json:"unchanged,omitempty"`
+	// Archives are the archives of the sections with --split-by, instead
+	// of Archive.
+	Archives []string `json:"archives,omitempty"`
+	// Interrupted is set when the crawl was stopped by SIGINT or SIGTERM,
+	// and the archive holds only the pages converted until then.
+	Interrupted bool     `json:"interrupted,omitempty"`
+	Pruned      []string `json:"pruned,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
 
-		// Try to open the directory
-		if err := openDirectory(dir); err != nil {
-			fmt.Printf("Note: Could not open the output directory automatically: %v\n", err)
+// newScrapeResult returns the outcome of the crawl of s from inputURL.
+func newScrapeResult(s *scraper.Scraper, inputURL string, startedAt time.Time) *scrapeResult {
+	pages, _ := s.Stats()
+	return &scrapeResult{
+		URL:       s.Redact(inputURL),
+		Pages:     pages,
+		Failures:  s.Failures(),
+		StartedAt: startedAt,
+		Duration:  float64(time.Since(startedAt).Microseconds()) / 1000,
+	}
+}
+
+// printResult prints the outcome of a crawl for scripts: as a JSON line
+// after the events with --json, or as a JSON document with --output-format
+// json. It prints nothing for people, who got the messages.
+func printResult(result *scrapeResult) error {
+	switch {
+	case jsonLines:
+		result.Type = "result"
+		return printJSONLine(result)
+	case jsonOutput():
+		return printJSON(result)
+	}
+	return nil
+}
+
+// newScraper configures a scraper with the settings of the scrape flags
+// parsed on cmd.
+func newScraper(cmd *cobra.Command) (*scraper.Scraper, error) {
+	if preset != "" {
+		if _, err := scraper.LookupPreset(preset); err != nil {
+			return nil, err
 		}
+		// Presets extract content with selectors, which needs stripping
+		stripHTML = true
+	}
 
-		return nil
-	},
+	if readability {
+		// Readability works on the DOM of the extracted text
+		stripHTML = true
+	}
+
+	if pipeText != "" {
+		// The text filter gets the extracted text, not the HTML
+		stripHTML = true
+	}
+
+	if format == scraper.FormatMarkdown || format == scraper.FormatEPUB {
+		// Markdown and EPUB chapters are converted from the DOM,
+		// recipes included
+		stripHTML = true
+	}
+
+	if !stripHTML && (clean || minWords > 0 || maxLinkDensity > 0 || repeatPages > 0 || boilerplatePreview || stripRepeated > 0) {
+		return nil, fmt.Errorf("--clean and the boilerplate filter flags require --strip")
+	}
+
+	if maxDepth < 0 {
+		return nil, fmt.Errorf("--max-depth must be 0 or more")
+	}
+	if maxPages < 0 {
+		return nil, fmt.Errorf("--max-pages must be 0 or more")
+	}
+
+	threshold, err := scraper.ParseSize(streamThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --stream-threshold: %w", err)
+	}
+
+	var bandwidth int64
+	if maxBandwidth != "" {
+		if bandwidth, err = scraper.ParseBandwidth(maxBandwidth); err != nil {
+			return nil, fmt.Errorf("invalid --max-bandwidth: %w", err)
+		}
+	}
+
+	archiveMeta, err := scraper.ParseMeta(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := scraper.LoadConfig(configPath, cmd.Flags().Changed("config"))
+	if err != nil {
+		return nil, err
+	}
+	skipRules, err := cfg.SkipRules()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	renderOverrides, err := cfg.RenderOverrides()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+	if filter == "" {
+		filter = cfg.Filter
+	}
+	var pageFilter *scraper.Filter
+	if filter != "" {
+		if pageFilter, err = scraper.ParseFilter(filter); err != nil {
+			return nil, err
+		}
+	}
+
+	include, err := parseURLPatterns(includes)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := parseURLPatterns(excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	redactRules, err := scraper.ParseRedactRules(redact)
+	if err != nil {
+		return nil, err
+	}
+
+	var rewriteRules []scraper.RewriteRule
+	for _, r := range rewrites {
+		rule, err := scraper.ParseRewriteRule(r)
+		if err != nil {
+			return nil, err
+		}
+		rewriteRules = append(rewriteRules, rule)
+	}
+
+	if session == "" && (loginURL != "" || len(loginFields) > 0) {
+		return nil, fmt.Errorf("--login-url and --login-field require --session")
+	}
+	fields := make(map[string]string, len(loginFields))
+	for _, f := range loginFields {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --login-field %q, expected name=value", f)
+		}
+		fields[name] = value
+	}
+
+	requestHeaders, requestCookies, err := requestAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	var zone *time.Location
+	if timeZone != "" {
+		if zone, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("invalid --timezone: %w", err)
+		}
+	}
+
+	if noZip {
+		if outputMode != scraper.OutputZip && outputMode != scraper.OutputDir {
+			return nil, fmt.Errorf("--no-zip can't be combined with --output-mode %s", outputMode)
+		}
+		outputMode = scraper.OutputDir
+	}
+
+	s := scraper.NewScraper(stripHTML, clean)
+	s.MaxDepth = maxDepth
+	s.MaxPages = maxPages
+	s.IncludeSubdomains = subdomains
+	s.AcceptTypes = acceptTypes
+	s.Readability = readability
+	s.StampSource = stampSource
+	s.Header = pageHeader
+	s.Footer = pageFooter
+	s.Locale = locale
+	s.TimeZone = zone
+	s.NoTOC = noTOC
+	s.CoverTemplate = archiveCover
+	s.RespectRobots = respectRobots
+	s.ComplianceReport = complianceReport
+	if minWords > 0 {
+		s.Boilerplate.MinWords = minWords
+	}
+	s.Boilerplate.MaxLinkDensity = maxLinkDensity
+	s.Boilerplate.RepeatPages = repeatPages
+	s.Boilerplate.Preview = boilerplatePreview
+	s.RepeatedBlocks = stripRepeated
+	s.LogRequests = logRequests
+	s.Preset = preset
+	s.Order = order
+	s.GroupByAuthor = groupByAuthor
+	s.NavSelector = tocSelector
+	s.StitchPages = stitchPages
+	s.PreferVariant = prefer
+	s.PageTimeout = pageTimeout
+	s.RequestTimeout = timeout
+	s.Retries = retries
+	s.RetryBackoff = backoff
+	s.StreamThreshold = threshold
+	s.MaxBandwidth = bandwidth
+	s.Delay = delay
+	s.RandomDelay = randomDelay
+	s.Parallelism = parallelism
+	s.MaxConnsPerHost = maxConnsPerHost
+	s.Adaptive = adaptive
+	s.Format = format
+	s.Renderer = renderer
+	s.RenderJS = renderJS
+	s.NoJavaScript = noJavaScript
+	s.RenderOverrides = renderOverrides
+	s.NoRenderFallback = noFallback
+	s.SinglePDF = singlePDF
+	s.OutputMode = outputMode
+	s.ArchiveFormat = archiveFormat
+	s.SplitBy = splitBy
+	s.BrowserPath = browserPath
+	s.BrowserDir = scraper.DefaultBrowserDir()
+	if browserScript != "" {
+		scripts, err := scraper.LoadBrowserScripts(browserScript)
+		if err != nil {
+			return nil, err
+		}
+		s.BrowserScripts = scripts
+	}
+	s.AutoScroll = autoScroll
+	s.MaxScrolls = maxScrolls
+	s.ScrollSettle = scrollSettle
+	s.Typography = typography
+	s.LinkURLs = linkURLs
+	s.PageSize = pageSize
+	s.Orientation = orientation
+	if margin != "" {
+		if s.Margins, err = scraper.ParseMargins(margin); err != nil {
+			return nil, err
+		}
+	}
+	s.Font = font
+	s.Meta = archiveMeta
+	s.Thumbnails = thumbnails
+	s.SearchIndex = searchIndex
+	if embeddings != "" {
+		if s.Embeddings, err = scraper.ParseEmbeddings(embeddings); err != nil {
+			return nil, err
+		}
+	}
+	s.CheckLinks = checkLinks
+	s.IncludeAttachments = attachments
+	s.LinkGraph = linkGraph
+	s.Rewrites = rewriteRules
+	s.SkipRules = skipRules
+	s.Include = include
+	s.Exclude = exclude
+	s.Filter = pageFilter
+	s.RedactContent = redactRules
+	s.PipeHTML = pipeHTML
+	s.PipeText = pipeText
+	s.NoImages = noImages
+	s.ImageQuality = imageQuality
+	s.MaxImageWidth = maxImageWidth
+	s.CacheDir = cacheDir
+	s.Offline = offline
+	s.WARC = replayWARC
+	s.DocsVersion = docsVersion
+	s.Events = events
+	s.EventsFile = eventsFile
+	s.Evidence = evidence
+	s.FrontMatter = frontMatter
+	s.SelfContained = selfContained
+	s.Resume = resume
+	s.TimeServer = timeServer
+	s.Headers = requestHeaders
+	s.Cookies = requestCookies
+	s.UserAgent = userAgent
+	s.Baseline = baseline
+	s.RotateUserAgents = rotateUserAgents
+	if session != "" {
+		s.Session = &scraper.Session{
+			Name:        session,
+			Dir:         scraper.DefaultSessionDir(),
+			LoginURL:    loginURL,
+			LoginFields: fields,
+		}
+		for _, v := range fields {
+			if len(v) >= 4 {
+				s.Secrets = append(s.Secrets, v)
+			}
+		}
+	}
+	if stripHTML && !noRecipes {
+		recipes, err := scraper.LoadRecipes(recipesDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load recipes: %w", err)
+		}
+		s.Recipes = recipes
+	}
+	return s, nil
+}
+
+// parseURLPatterns parses the values of --include or --exclude.
+func parseURLPatterns(exprs []string) ([]scraper.URLPattern, error) {
+	var patterns []scraper.URLPattern
+	for _, expr := range exprs {
+		p, err := scraper.ParseURLPattern(expr)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// recordRun adds the run to the history. A history that can't be written
+// doesn't fail the scrape.
+func recordRun(s *scraper.Scraper, site, startURL, archive string, startedAt time.Time, scrapeErr error) {
+	run := scraper.Run{
+		Site:       site,
+		StartURL:   s.Redact(startURL),
+		StartedAt:  startedAt,
+		FinishedAt: time.Now().UTC(),
+	}
+	run.Pages, run.Failures = s.Stats()
+	if scrapeErr != nil {
+		run.Error = scrapeErr.Error()
+	}
+	// An interrupted run archives the pages converted until then
+	if scrapeErr == nil || errors.Is(scrapeErr, scraper.ErrInterrupted) && run.Pages > 0 {
+		if info, err := os.Stat(archive); err == nil {
+			run.Archive = archive
+			run.Bytes = info.Size()
+		}
+	}
+	if _, err := (&scraper.History{Path: historyPath}).Add(run); err != nil {
+		fmt.Printf("Warning: failed to record run: %v\n", err)
+	}
 }
 
 func init() {
-	scrapeCmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Output directory for the ZIP file")
-	scrapeCmd.Flags().BoolVar(&stripHTML, "strip", false, "Strip HTML tags from content before creating PDF")
-	scrapeCmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite if output file exists")
-	scrapeCmd.Flags().BoolVar(&clean, "clean", false, "Remove lines with two words or less (requires --strip)")
+	addScrapeFlags(scrapeCmd)
+	scrapeCmd.Flags().StringVar(&urlFile, "url-file", "", "File listing more URLs to start from, one per line, archived together with the URLs given")
+	scrapeCmd.Flags().BoolVar(&archivePerURL, "archive-per-url", false, "Crawl each URL given in its own job, one after the other, to its own archive")
+	scrapeCmd.Flags().BoolVar(&jsonLines, "json", false, "Print the progress of the crawl and its result (archive, page count, failures) as JSON Lines on stdout, for scripts and CI")
+	scrapeCmd.Flags().BoolVar(&shareVisited, "share-visited", false, "With --archive-per-url, don't fetch pages an earlier job of the batch already archived")
+}
 
-	// Make clean flag require strip flag
-	scrapeCmd.MarkFlagsRequiredTogether("clean", "strip")
+// addScrapeFlags defines the flags of the scrape command on cmd, so render
+// shares them.
+func addScrapeFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVarP(&outputDir, "output", "o", ".", "Output directory for the ZIP file")
+	f.IntVar(&maxDepth, "max-depth", scraper.DefaultMaxDepth, "How many links deep to crawl from the start page: 1 for the start page only, 0 for no limit")
+	f.BoolVar(&subdomains, "include-subdomains", false, "Also follow links to other subdomains of the start page's domain, e.g. api.example.com from docs.example.com")
+	f.StringArrayVar(&acceptTypes, "accept-type", nil, "Also convert responses of this media type to pages besides HTML, e.g. text/plain or text/* (repeatable)")
+	f.IntVar(&maxPages, "max-pages", 0, "Stop the crawl once this many pages were converted and finish the archive with them, 0 for no limit")
+	f.BoolVar(&stripHTML, "strip", false, "Strip HTML tags from content before creating PDF")
+	f.BoolVarP(&force, "force", "f", false, "Force overwrite if output file exists")
+	f.BoolVar(&resume, "resume", false, "Continue the interrupted run writing the same output instead of starting over")
+	f.StringVar(&baseline, "baseline", "", "Earlier archive of the site; only pages new or changed since it are archived")
+	f.BoolVar(&readability, "readability", false, "Keep only the main article of each page, leaving out menus, banners and footers (implies --strip)")
+	f.BoolVar(&clean, "clean", false, "Remove lines with two words or less (requires --strip)")
+	f.IntVar(&minWords, "min-words", 0, "Remove lines with fewer words than this (requires --strip)")
+	f.Float64Var(&maxLinkDensity, "max-link-density", 0, "Remove blocks whose share of link text exceeds this ratio, e.g. 0.5 (requires --strip)")
+	f.IntVar(&repeatPages, "repeat-pages", 0, "Remove lines already seen on this many earlier pages (requires --strip)")
+	f.Float64Var(&stripRepeated, "strip-repeated", 0, "Remove text blocks found on at least this fraction of pages, e.g. 0.5 (requires --strip)")
+	f.StringVar(&preset, "preset", "", fmt.Sprintf("Documentation platform preset for scope, selectors and page order (%s)", strings.Join(scraper.PresetNames(), ", ")))
+	f.StringVar(&order, "order", scraper.OrderCrawl, "Order of the pages in the archive: crawl, nav to follow the site's sidebar, or date for oldest published first")
+	f.BoolVar(&noProgress, "no-progress", false, "Print a line for every page archived instead of a progress bar with the ETA")
+	f.BoolVar(&groupByAuthor, "group-by-author", false, "Group pages by the author of their byline, in a directory per author and under their name in the table of contents")
+	f.StringVar(&tocSelector, "toc-selector", "", "CSS selector for the site's table of contents links, implies --order nav")
+	f.BoolVar(&stitchPages, "stitch-pages", false, "Merge articles split across numbered pages (rel=next, ?page=N) into one PDF")
+	f.StringVar(&prefer, "prefer", "", "Take page content from the simplified amp or print variant when a page advertises one")
+	f.DurationVar(&pageTimeout, "page-timeout", time.Minute, "Give up converting a single page after this long (0 for no limit)")
+	f.DurationVar(&timeout, "timeout", scraper.DefaultRequestTimeout, "Give up a request after this long, its download included (0 for no limit)")
+	f.IntVar(&retries, "retries", scraper.DefaultRetries, "Times a request failing with a 429, a 5xx or a timeout is tried again")
+	f.DurationVar(&backoff, "retry-backoff", scraper.DefaultRetryBackoff, "Wait before the first retry, doubled for each next one unless the server sends Retry-After")
+	f.StringVar(&streamThreshold, "stream-threshold", "8MB", "Extract stripped pages larger than this without building a DOM; recipes and link density are skipped for them (0 to disable)")
+	f.StringVar(&maxBandwidth, "max-bandwidth", "", "Cap the download rate of the whole crawl, e.g. 2MB/s")
+	f.DurationVar(&delay, "delay", 0, "Wait at least this long between requests to the same host, e.g. 500ms")
+	f.DurationVar(&randomDelay, "random-delay", 0, "Add up to this much random wait to --delay, e.g. 2s")
+	f.IntVar(&parallelism, "parallelism", 1, "Requests to the same host in flight at once; above 1 pages are fetched concurrently")
+	f.IntVar(&parallelism, "concurrency", 1, "Same as --parallelism")
+	f.IntVar(&maxConnsPerHost, "max-conns-per-host", 0, "Most requests to the same host in flight at once, so --parallelism workers can convert pages with fewer connections (0 leaves it to --parallelism)")
+	f.BoolVar(&adaptive, "adaptive", false, fmt.Sprintf("Adjust parallel requests to how the site copes, backing off on 429s, 5xxs and timeouts, up to --parallelism or %d", scraper.DefaultAdaptiveMax))
+	f.StringVar(&format, "format", scraper.FormatPDF, "Format of the pages: pdf, markdown to convert each page's HTML to Markdown, or epub for one e-book with a chapter per page")
+	f.StringVar(&renderer, "renderer", scraper.RendererText, "How pages become PDFs: text, chrome to print them with a headless browser, or both to compare them")
+	f.BoolVar(&noFallback, "no-render-fallback", false, "Record pages the browser fails on as failed instead of rendering them as text, and don't print pages without text with the browser")
+	f.StringVar(&outputMode, "output-mode", scraper.OutputZip, "How the archive is written: zip, or dir for a directory with the pages in a tree mirroring their URL paths")
+	f.BoolVar(&noZip, "no-zip", false, "Shorthand for --output-mode dir")
+	f.StringVar(&archiveFormat, "archive-format", scraper.ArchiveZip, "File format of the archive: zip, tar or tar.gz")
+	f.StringVar(&splitBy, "split-by", "", "Write an archive per site section instead of one, each with its own index and manifest: top-level-path for one per first path segment (/docs, /blog, ...)")
+	f.BoolVar(&singlePDF, "single-pdf", false, "Write all pages to one PDF with a bookmark per page instead of a ZIP file")
+	f.BoolVar(&renderJS, "render-js", false, "Fetch pages with a headless browser so content built by JavaScript is captured")
+	f.BoolVar(&noJavaScript, "no-javascript", false, "Print pages in the headless browser without running their scripts, leaving out ads and trackers")
+	f.StringVar(&browserPath, "browser-path", "", "Chrome or Chromium executable for --renderer chrome and --render-js (default: search PATH)")
+	f.StringVar(&browserScript, "browser-script", "", "YAML file of per-site actions (click, type, wait) the browser runs before capturing a page")
+	f.BoolVar(&autoScroll, "auto-scroll", false, "Scroll pages in the headless browser to their bottom before capture, so content loaded on scroll is included")
+	f.IntVar(&maxScrolls, "max-scrolls", scraper.DefaultMaxScrolls, "Most scrolls per page with --auto-scroll")
+	f.DurationVar(&scrollSettle, "scroll-settle", scraper.DefaultScrollSettle, "Wait this long after each scroll for new content with --auto-scroll")
+	f.BoolVar(&noImages, "no-images", false, "Leave images out of the PDFs of the text renderer")
+	f.IntVar(&imageQuality, "image-quality", 0, "Recompress the images of the text renderer's PDFs at this JPEG quality, 1-100 (default: keep them as they are)")
+	f.IntVar(&maxImageWidth, "max-image-width", 0, "Downscale images of the text renderer's PDFs wider than this many pixels (default: keep their size)")
+	f.BoolVar(&linkURLs, "link-urls", false, "Follow the text of links in the PDFs with their targets in parentheses, so they survive printing")
+	f.StringVar(&typography, "typography", "", fmt.Sprintf("Page layout of the text renderer (%s)", strings.Join(scraper.TypographyNames(), ", ")))
+	f.StringVar(&pageSize, "page-size", scraper.PageSizeA4, "Size of the PDF pages: A4, A3, Letter or Legal")
+	f.StringVar(&orientation, "orientation", scraper.OrientationPortrait, "Orientation of the PDF pages: portrait, or landscape for wide tables")
+	f.StringVar(&margin, "margin", "", "Margins of the PDF pages in millimetres, like in CSS: 15 for every side, 20,15 for top and bottom then the sides, or top,right,bottom,left")
+	f.StringVar(&font, "font", "", "TrueType font file for the text renderer, e.g. for CJK scripts (default: embedded DejaVu)")
+	f.StringArrayVar(&meta, "meta", nil, "Metadata key=value stored in the manifest and every PDF, e.g. case=2024-117 (repeatable)")
+	f.BoolVar(&thumbnails, "thumbnails", false, "Add a PNG preview of each PDF's first page to thumbs/ and index.html")
+	f.BoolVar(&searchIndex, "search-index", false, "Add a full-text index and an offline search box to index.html")
+	f.StringVar(&embeddings, "embeddings", "", "Add embeddings.jsonl with vector embeddings of the pages' text in chunks, e.g. provider=openai,model=text-embedding-3-small (API key in $OPENAI_API_KEY)")
+	f.BoolVar(&checkLinks, "check-links", false, "Report internal links that fail to load in broken-links.csv")
+	f.BoolVar(&attachments, "include-attachments", false, "Download linked PDF, Office and OpenDocument files into the archive's attachments folder as they are")
+	f.StringVar(&linkGraph, "link-graph", "", "Add the links between pages to the archive as a graph: graphml, dot or json")
+	f.IntVar(&estimate, "estimate", 0, "Fetch this many pages, e.g. 20, and project the archive size and crawl time instead of archiving")
+	f.BoolVar(&dryRun, "dry-run", false, "Crawl without converting pages and list the URLs that would be archived with their depth, to tune --include and --exclude")
+	f.StringArrayVar(&rewrites, "rewrite", nil, "Rewrite discovered URLs before visiting them, as 'regex=>replacement' (repeatable)")
+	f.StringVar(&docsVersion, "docs-version", "", "Crawl one version of a documentation site, by name (latest, v2, 1.4) or as a path such as /docs/v2/")
+	f.StringArrayVar(&headers, "header", nil, "Request header sent with every request, e.g. \"Authorization: Bearer ...\" (repeatable)")
+	f.StringArrayVar(&cookies, "cookie", nil, "Cookies sent with every request to the site, as name=value pairs separated by semicolons (repeatable)")
+	f.StringVar(&cookieFile, "cookie-file", "", "Cookies.txt file in the Netscape format, or a file holding a Cookie header, sent with requests to the site")
+	f.StringVar(&userAgent, "user-agent", "", "User-Agent sent with every request instead of colly's, e.g. a browser's for sites blocking unknown clients")
+	f.BoolVar(&rotateUserAgents, "rotate-user-agents", false, "Send each request with the next user agent of a built-in pool of desktop and mobile browsers")
+	f.StringVar(&session, "session", "", "Save the site's cookies encrypted under this name and reuse them on later runs")
+	f.StringVar(&loginURL, "login-url", "", "Login form URL posted to when the saved session is missing or expired (requires --session)")
+	f.StringArrayVar(&loginFields, "login-field", nil, "Login form field as name=value, e.g. password=... (repeatable, requires --session)")
+	f.BoolVar(&events, "events", false, "Add events.jsonl, a timeline of discoveries, fetches, renders and errors, to the archive")
+	f.StringVar(&eventsFile, "events-file", "", "Write the event timeline to this file as the crawl runs")
+	f.BoolVar(&selfContained, "self-contained", false, "Add a single file HTML copy of every page, with CSS, images and fonts inlined, next to its PDF")
+	f.StringVar(&frontMatter, "front-matter", "", "Prepend YAML front matter for a static site generator, hugo or jekyll, to Markdown files (requires --format markdown)")
+	f.BoolVar(&evidence, "evidence", false, "Add raw responses, headers, TLS certificates, trusted timestamps, hashes and a chain of custody report to the archive")
+	f.StringVar(&timeServer, "time-server", scraper.DefaultTimeServer, "NTP server evidence timestamps are taken from")
+	f.StringArrayVar(&includes, "include", nil, "Only follow links whose path matches this glob, e.g. '/docs/*', or 're:regex' on the URL (repeatable)")
+	f.StringArrayVar(&excludes, "exclude", nil, "Don't follow links whose path matches this glob, e.g. '/tags/*', or 're:regex' on the URL (repeatable)")
+	f.StringArrayVar(&redact, "redact", nil, "Black out text matching this regular expression, e.g. e-mail addresses, before rendering (repeatable)")
+	f.StringVar(&cacheDir, "cache-dir", "", "Keep every response in this directory and reuse it instead of fetching again, e.g. to render offline later")
+	f.StringVar(&pipeHTML, "pipe-html", "", "Shell command each page's HTML is passed through, stdin to stdout, before its content is extracted")
+	f.StringVar(&pipeText, "pipe-text", "", "Shell command each page's extracted text is passed through, stdin to stdout, before it is rendered (implies --strip)")
+	f.StringVar(&filter, "filter", "", `Only archive pages matching an expression, e.g. 'status == 200 && words > 100 && path =~ "^/docs"'`)
+	f.StringVar(&configPath, "config", scraper.DefaultConfigPath(), "YAML config file with skip rules and render overrides")
+	f.StringVar(&historyPath, "history", scraper.DefaultHistoryPath(), "Run history file")
+	f.BoolVar(&noHistory, "no-history", false, "Don't record this run in the run history")
+	f.IntVar(&keep, "keep", 0, "Name archives by date and keep only this many per site, deleting older ones (0 keeps all)")
+	f.StringVar(&recipesDir, "recipes-dir", scraper.DefaultRecipesDir(), "Directory of YAML site recipes overriding the built-in ones")
+	f.BoolVar(&noRecipes, "no-recipes", false, "Don't apply site recipes when stripping HTML")
+	f.BoolVar(&boilerplatePreview, "boilerplate-preview", false, "Print what the boilerplate filter would remove without removing it (requires --strip)")
+	f.BoolVar(&stampSource, "stamp-source", false, "Stamp the source URL, fetch date and HTTP status on the first page of each PDF")
+	f.StringVar(&pageHeader, "page-header", "", "Header printed on every PDF page, with {url}, {title}, {date}, {page} and {pages} filled in")
+	f.StringVar(&pageFooter, "footer", "", "Footer printed on every PDF page, e.g. '{url} — page {page}/{pages}'")
+	f.StringVar(&locale, "locale", "", fmt.Sprintf("Write the dates and numbers shown in PDFs and index.html for this locale (%s; default: ISO dates)", strings.Join(scraper.LocaleNames(), ", ")))
+	f.StringVar(&timeZone, "timezone", "", "Time zone of the dates shown in PDFs and index.html, e.g. Europe/Berlin or Local (default: UTC)")
+	f.BoolVar(&noTOC, "no-toc", false, "Don't add a table of contents, _toc.pdf or the first section of --single-pdf")
+	f.StringVar(&archiveCover, "archive-cover", "", "HTML template of a cover page with the site's logo, run statistics and captured pages, _cover.pdf or the first section of --single-pdf")
+	f.BoolVar(&logRequests, "log-requests", false, "Print every request and response with headers, credentials masked")
+	f.BoolVar(&respectRobots, "respect-robots", false, "Skip pages disallowed by robots.txt or meta robots noarchive, and honour nofollow")
+	f.BoolVar(&complianceReport, "compliance-report", false, "Write compliance.json recording the robots policy applied to each URL")
+}
+
+// requestAuth parses the --header, --cookie and --cookie-file flags.
+func requestAuth() (http.Header, []*http.Cookie, error) {
+	requestHeaders := make(http.Header)
+	for _, h := range headers {
+		name, value, err := scraper.ParseHeader(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		requestHeaders.Add(name, value)
+	}
+	var requestCookies []*http.Cookie
+	for _, c := range cookies {
+		parsed, err := scraper.ParseCookies(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		requestCookies = append(requestCookies, parsed...)
+	}
+	if cookieFile != "" {
+		loaded, err := scraper.LoadCookieFile(cookieFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		requestCookies = append(requestCookies, loaded...)
+	}
+	return requestHeaders, requestCookies, nil
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var searchLimit int
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query] [archive.zip]",
+	Short: "Search the text of the pages in an archive",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		results, err := scraper.SearchArchive(args[1], args[0])
+		if err != nil {
+			return err
+		}
+		if searchLimit > 0 && len(results) > searchLimit {
+			results = results[:searchLimit]
+		}
+		if jsonOutput() {
+			return printJSON(append([]scraper.SearchResult{}, results...))
+		}
+		if len(results) == 0 {
+			fmt.Println("No matching pages")
+			return nil
+		}
+		for i, r := range results {
+			if r.Title != "" {
+				fmt.Printf("%d. %s (%s)\n", i+1, r.Title, r.File)
+			} else {
+				fmt.Printf("%d. %s\n", i+1, r.File)
+			}
+			fmt.Printf("   %s\n", r.URL)
+			fmt.Printf("   %s\n\n", r.Snippet)
+		}
+		return nil
+	},
+}
+
+func init() {
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 20, "Maximum number of pages to list (0 for all)")
+}
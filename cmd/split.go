@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+)
+
+// splitResult completes result with the archives of the sections written
+// by s with --split-by, and lists them.
+func splitResult(s *scraper.Scraper, result *scrapeResult) (*scrapeResult, error) {
+	for _, output := range s.Outputs() {
+		abs, err := filepath.Abs(output)
+		if err != nil {
+			return result, fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		result.Archives = append(result.Archives, abs)
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			result.Bytes += info.Size()
+		}
+	}
+	if scripted() {
+		return result, nil
+	}
+
+	fmt.Printf("Successfully created %d archives, one per section:\n", len(result.Archives))
+	for _, archive := range result.Archives {
+		fmt.Printf("  %s\n", archive)
+	}
+	printFailures(result.Failures)
+	if archivePerURL {
+		return result, nil
+	}
+	if err := openDirectory(outputDir); err != nil {
+		fmt.Printf("Note: Could not open the output directory automatically: %v\n", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ppicom/scrapedf/pkg/scraper"
+	"github.com/spf13/cobra"
+)
+
+var updateGoldens bool
+
+var testCrawlCmd = &cobra.Command{
+	Use:   "test-crawl [fixture dir]...",
+	Short: "Crawl fixture sites and compare the archives with their golden files",
+	Long: `test-crawl serves each fixture's pages/ directory on a local server, crawls
+it with the scrape flags of its fixture.yaml and compares the manifest and
+the text of every page with the fixture's golden/ files. Use --update to
+write the golden files from the current output instead.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		failed := 0
+		results := []*fixtureResult{}
+		for _, dir := range args {
+			result, err := testCrawl(dir)
+			if err != nil {
+				return fmt.Errorf("%s: %w", dir, err)
+			}
+			results = append(results, result)
+			if result.Status == fixtureFail {
+				failed++
+			}
+		}
+		if jsonOutput() {
+			if err := printJSON(results); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d fixtures differ from their golden files", failed, len(args))
+		}
+		return nil
+	},
+}
+
+// Outcomes of a fixture's crawl.
+const (
+	fixturePass    = "pass"
+	fixtureFail    = "fail"
+	fixtureUpdated = "updated"
+)
+
+// fixtureResult is the outcome of a fixture's crawl, printed by
+// --output-format json.
+type fixtureResult struct {
+	Fixture string   `json:"fixture"`
+	Status  string   `json:"status"`
+	Files   int      `json:"files,omitempty"` // golden files written
+	Diffs   []string `json:"diffs,omitempty"`
+}
+
+// testCrawl crawls the fixture in dir and compares or updates its golden
+// files.
+func testCrawl(dir string) (*fixtureResult, error) {
+	fixture, err := scraper.LoadFixture(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The fixture's flags are parsed on a command of their own, which also
+	// resets them to their defaults between fixtures
+	cmd := &cobra.Command{Use: "scrape"}
+	addScrapeFlags(cmd)
+	if err := cmd.ParseFlags(fixture.Args); err != nil {
+		return nil, fmt.Errorf("fixture args: %w", err)
+	}
+	if !cmd.Flags().Changed("config") {
+		configPath = fixture.ConfigPath()
+	}
+	if !cmd.Flags().Changed("recipes-dir") {
+		recipesDir = fixture.RecipesDir()
+	}
+	s, err := newScraper(cmd)
+	if err != nil {
+		return nil, err
+	}
+	out := messages()
+	s.Log = out
+
+	got, err := fixture.Crawl(s)
+	if err != nil {
+		return nil, err
+	}
+	if updateGoldens {
+		if err := fixture.Update(got); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(out, "UPDATED %s (%d files)\n", dir, len(got))
+		return &fixtureResult{Fixture: dir, Status: fixtureUpdated, Files: len(got)}, nil
+	}
+	diffs, err := fixture.Compare(got)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		fmt.Fprintf(out, "PASS %s\n", dir)
+		return &fixtureResult{Fixture: dir, Status: fixturePass}, nil
+	}
+	fmt.Fprintf(out, "FAIL %s\n", dir)
+	for _, d := range diffs {
+		fmt.Fprintf(out, "  %s\n", d)
+	}
+	return &fixtureResult{Fixture: dir, Status: fixtureFail, Diffs: diffs}, nil
+}
+
+func init() {
+	testCrawlCmd.Flags().BoolVar(&updateGoldens, "update", false, "Write the golden files from the archives instead of comparing")
+}
@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultAdaptiveMax is the most requests in flight in adaptive mode when
+// Parallelism doesn't set a higher ceiling.
+const DefaultAdaptiveMax = 8
+
+// adaptiveSlowdown is how much slower than the fastest response seen a
+// response may be before the limit stops growing.
+const adaptiveSlowdown = 2
+
+// adaptiveLimiter caps the requests in flight with additive increase,
+// multiplicative decrease: the limit grows by one request per round of
+// fast, successful responses, and halves on 429s, 5xxs and timeouts.
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	max      int
+	inFlight int
+	fastest  time.Duration
+	// decreased is when the limit was last halved; failures of requests
+	// started before then were caused by the old limit.
+	decreased time.Time
+	now       func() time.Time
+	logf      func(format string, args ...interface{})
+}
+
+func newAdaptiveLimiter(max int, logf func(string, ...interface{})) *adaptiveLimiter {
+	l := &adaptiveLimiter{limit: 1, max: max, now: time.Now, logf: logf}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire waits for a free slot and returns when the request started.
+func (l *adaptiveLimiter) acquire() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= int(l.limit) {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	return l.now()
+}
+
+// release frees the slot of a request started at started, adjusting the
+// limit to its outcome: failed reports an overloaded origin.
+func (l *adaptiveLimiter) release(started time.Time, failed bool, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	defer l.cond.Broadcast()
+
+	latency := l.now().Sub(started)
+	if failed {
+		if started.Before(l.decreased) || l.limit == 1 {
+			return
+		}
+		before := int(l.limit)
+		l.limit = math.Max(1, math.Floor(l.limit/2))
+		l.decreased = l.now()
+		if l.logf != nil {
+			l.logf("Backing off to %d parallel requests after %s (was %d)\n", int(l.limit), reason, before)
+		}
+		return
+	}
+	if l.fastest == 0 || latency < l.fastest {
+		l.fastest = latency
+	}
+	if latency > adaptiveSlowdown*l.fastest {
+		return
+	}
+	l.limit = math.Min(float64(l.max), l.limit+1/l.limit)
+}
+
+// current returns the current limit.
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// overloaded reports whether a response status means the origin wants
+// fewer requests.
+func overloaded(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// adaptiveTransport holds every request to a slot of the limiter until its
+// body is closed.
+type adaptiveTransport struct {
+	base    http.RoundTripper
+	limiter *adaptiveLimiter
+}
+
+func (t *adaptiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := t.limiter.acquire()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.limiter.release(started, true, err.Error())
+		return nil, err
+	}
+	if overloaded(resp.StatusCode) {
+		t.limiter.release(started, true, "HTTP "+http.StatusText(resp.StatusCode))
+		return resp, nil
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: func(failed bool, reason string) {
+		t.limiter.release(started, failed, reason)
+	}}
+	return resp, nil
+}
+
+// releasingBody frees the request's slot once the body is closed, counting
+// a failed read, such as a timeout, against the origin.
+type releasingBody struct {
+	io.ReadCloser
+	release func(failed bool, reason string)
+	err     error
+	once    sync.Once
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		if b.err != nil {
+			b.release(true, b.err.Error())
+		} else {
+			b.release(false, "")
+		}
+	})
+	return err
+}
+
+// adaptiveMax returns the ceiling of the adaptive limit.
+func (s *Scraper) adaptiveMax() int {
+	if s.Parallelism > 1 {
+		return s.Parallelism
+	}
+	return DefaultAdaptiveMax
+}
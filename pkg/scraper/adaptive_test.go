@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := newAdaptiveLimiter(4, nil)
+	l.now = func() time.Time { return now }
+
+	// fetch acquires a slot, lets latency pass and releases it
+	fetch := func(latency time.Duration, failed bool) {
+		started := l.acquire()
+		now = now.Add(latency)
+		l.release(started, failed, "HTTP Service Unavailable")
+	}
+
+	fetch(100*time.Millisecond, false)
+	if got := l.current(); got != 2 {
+		t.Fatalf("limit after a fast response = %d, want 2", got)
+	}
+	for i := 0; i < 10; i++ {
+		fetch(100*time.Millisecond, false)
+	}
+	if got := l.current(); got != 4 {
+		t.Errorf("limit = %d, want the ceiling 4", got)
+	}
+
+	fetch(time.Second, true)
+	if got := l.current(); got != 2 {
+		t.Errorf("limit after a failure = %d, want 2", got)
+	}
+
+	// A failure of a request started before the back off doesn't count again
+	started := now.Add(-time.Second)
+	l.inFlight++
+	l.release(started, true, "timeout")
+	if got := l.current(); got != 2 {
+		t.Errorf("limit after a stale failure = %d, want 2", got)
+	}
+
+	// Slow responses hold the limit
+	fetch(time.Second, false)
+	if got := l.current(); got != 2 {
+		t.Errorf("limit after a slow response = %d, want 2", got)
+	}
+
+	fetch(time.Second, true)
+	fetch(time.Second, true)
+	if got := l.current(); got != 1 {
+		t.Errorf("limit = %d, want at least 1", got)
+	}
+	if l.inFlight != 0 {
+		t.Errorf("%d requests still in flight", l.inFlight)
+	}
+}
+
+func TestOverloaded(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	} {
+		if got := overloaded(status); got != want {
+			t.Errorf("overloaded(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// attachmentExtensions are the documents archived as they are with
+// IncludeAttachments.
+var attachmentExtensions = []string{
+	".pdf", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx",
+	".odt", ".ods", ".odp", ".rtf", ".csv",
+}
+
+// maxAttachment bounds the size of a downloaded attachment.
+const maxAttachment = 200 << 20
+
+// stageAttachment is reported in the failures of attachments.
+const stageAttachment = "attachment"
+
+// attachment is a document linked from the archived pages.
+type attachment struct {
+	URL  string `json:"url"`
+	File string `json:"file,omitempty"`
+	Type string `json:"type,omitempty"`
+	Size int64  `json:"size,omitempty"`
+	// LinkedFrom are the pages linking to it.
+	LinkedFrom []string `json:"linked_from"`
+
+	path string // downloaded file in the work directory
+}
+
+// isAttachment reports whether u links to a document kept as is.
+func isAttachment(u *url.URL) bool {
+	return slices.Contains(attachmentExtensions, strings.ToLower(path.Ext(u.Path)))
+}
+
+// recordAttachment records a link from the page at from to the document u,
+// downloaded once the crawl has finished.
+func (s *Scraper) recordAttachment(u, from *url.URL) {
+	target := stripFragment(u).String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attachments == nil {
+		s.attachments = make(map[string]*attachment)
+	}
+	a, ok := s.attachments[target]
+	if !ok {
+		a = &attachment{URL: target}
+		s.attachments[target] = a
+		s.attachmentOrder = append(s.attachmentOrder, target)
+	}
+	if source := from.String(); !slices.Contains(a.LinkedFrom, source) {
+		a.LinkedFrom = append(a.LinkedFrom, source)
+	}
+}
+
+// attachmentEntryName returns the archive name of the document at u:
+// attachments/, its host and its path.
+func attachmentEntryName(u *url.URL) string {
+	return path.Join("attachments", strings.ToLower(u.Host), path.Clean("/"+u.Path))
+}
+
+// downloadAttachments fetches the recorded attachments into dir with the
+// client, user agent and cookies of f, sending the crawl's Headers only to
+// the site of start. A failed download is recorded and leaves the document
+// out.
+func (s *Scraper) downloadAttachments(dir string, start *url.URL, f *assetFetcher) {
+	if len(s.attachmentOrder) == 0 {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "attachments"), 0755); err != nil {
+		s.logf("Warning: no attachments: %v\n", err)
+		return
+	}
+	names := make(map[string]bool)
+	for i, target := range s.attachmentOrder {
+		a := s.attachments[target]
+		u, err := url.Parse(target)
+		if err != nil {
+			continue
+		}
+		file := filepath.Join(dir, "attachments", fmt.Sprintf("%d%s", i, path.Ext(u.Path)))
+		if err := s.fetchAttachment(f, a, u, start, file); err != nil {
+			s.logf("Failed to download %s: %v\n", u, err)
+			s.event(event{Type: eventError, URL: target, Stage: stageAttachment, Error: err.Error()})
+			s.mu.Lock()
+			s.failures = append(s.failures, pageFailure{
+				URL:   s.Redact(target),
+				Stage: stageAttachment,
+				Error: s.Redact(err.Error()),
+			})
+			s.mu.Unlock()
+			os.Remove(file)
+			continue
+		}
+		// Documents at the same path with other queries get a number
+		base := attachmentEntryName(u)
+		ext := path.Ext(base)
+		name := base
+		for n := 2; names[name]; n++ {
+			name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), n, ext)
+		}
+		names[name] = true
+		a.File, a.path = name, file
+		s.logf("Downloaded %s\n", u)
+	}
+}
+
+// fetchAttachment downloads the document u to file.
+func (s *Scraper) fetchAttachment(f *assetFetcher, a *attachment, u, start *url.URL, file string) error {
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if s.sendsHeaders(start, u) {
+		for name, values := range s.Headers {
+			req.Header[name] = values
+		}
+	}
+	if ua := f.agent(); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if f.cookies != nil {
+		for _, c := range f.cookies(u.String()) {
+			req.AddCookie(c)
+		}
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", u, resp.StatusCode)
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(resp.Body, maxAttachment+1))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if n > maxAttachment {
+		return fmt.Errorf("%s is larger than %d bytes", u, maxAttachment)
+	}
+	a.Size = n
+	a.Type = mediaType(resp.Header.Get("Content-Type"))
+	switch a.Type {
+	case "", "application/octet-stream":
+		a.Type, _, _ = mime.ParseMediaType(mime.TypeByExtension(path.Ext(u.Path)))
+	}
+	return nil
+}
+
+// attachmentEntries returns the downloaded attachments as archive entries.
+func (s *Scraper) attachmentEntries() []archiveEntry {
+	var entries []archiveEntry
+	for _, target := range s.attachmentOrder {
+		if a := s.attachments[target]; a.path != "" {
+			entries = append(entries, archiveEntry{Name: a.File, File: a.path})
+		}
+	}
+	return entries
+}
+
+// archivedAttachments returns the downloaded attachments for the manifest,
+// with their URLs redacted.
+func (s *Scraper) archivedAttachments() []attachment {
+	var list []attachment
+	for _, target := range s.attachmentOrder {
+		a := s.attachments[target]
+		if a.path == "" {
+			continue
+		}
+		list = append(list, attachment{
+			URL:        s.Redact(a.URL),
+			File:       a.File,
+			Type:       a.Type,
+			Size:       a.Size,
+			LinkedFrom: s.redactAll(a.LinkedFrom),
+		})
+	}
+	return list
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsAttachment(t *testing.T) {
+	for link, want := range map[string]bool{
+		"https://example.com/files/datasheet.pdf":   true,
+		"https://example.com/files/Report.DOCX?v=2": true,
+		"https://example.com/prices.xlsx#sheet2":    true,
+		"https://example.com/docs/pdf":              false,
+		"https://example.com/guide.html":            false,
+		"https://example.com/logo.png":              false,
+	} {
+		u, _ := url.Parse(link)
+		if got := isAttachment(u); got != want {
+			t.Errorf("isAttachment(%s) = %v, want %v", link, got, want)
+		}
+	}
+}
+
+func TestIncludeAttachments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><p>Home</p>
+				<a href="/files/datasheet.pdf">Datasheet</a> <a href="/guide">Guide</a>
+				<a href="/files/missing.docx">Missing</a></body></html>`)
+		case "/guide":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><p>Guide</p><a href="/files/datasheet.pdf#page=2">Datasheet</a></body></html>`)
+		case "/files/datasheet.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4 original")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.IncludeAttachments = true
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	entries := zipEntries(t, zipname)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	name := "attachments/" + host + "/files/datasheet.pdf"
+	if entries[name] != "%PDF-1.4 original" {
+		t.Errorf("attachment %s = %q, want the document as served", name, entries[name])
+	}
+	if _, ok := entries[host+"_files_datasheet.pdf"]; ok {
+		t.Error("attachment was also converted as a page")
+	}
+
+	var m manifest
+	if err := json.Unmarshal([]byte(entries["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Attachments) != 1 {
+		t.Fatalf("manifest attachments = %+v, want the datasheet", m.Attachments)
+	}
+	a := m.Attachments[0]
+	if a.File != name || a.Type != "application/pdf" || len(a.LinkedFrom) != 2 {
+		t.Errorf("manifest attachment = %+v", a)
+	}
+	var failed bool
+	for _, f := range m.Failures {
+		failed = failed || (f.Stage == stageAttachment && strings.HasSuffix(f.URL, "/files/missing.docx"))
+	}
+	if !failed {
+		t.Errorf("missing attachment not recorded in failures: %+v", m.Failures)
+	}
+}
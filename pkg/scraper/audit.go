@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Kinds of transform recorded in a page's audit trail.
+const (
+	transformCharset       = "charset"
+	transformPipeHTML      = "pipe_html"
+	transformRecipeRemove  = "recipe_remove"
+	transformRecipeContent = "recipe_content"
+	transformReadability   = "readability"
+	transformLinkDensity   = "link_density"
+	transformMinWords      = "min_words"
+	transformRepeatLines   = "repeated_lines"
+	transformPipeText      = "pipe_text"
+	transformRedact        = "redact"
+	transformRepeated      = "repeated_blocks"
+)
+
+// transform records a rule that changed a page's content, so surprising
+// output can be traced back to it from manifest.json. Only rules that
+// changed something are recorded, in the order they were applied.
+type transform struct {
+	Kind string `json:"kind"`
+	// Rule is the selector, threshold, expression or command applied,
+	// prefixed with the recipe name for recipe selectors.
+	Rule string `json:"rule,omitempty"`
+	// NodesRemoved counts the HTML elements dropped, nested ones included.
+	NodesRemoved int `json:"nodes_removed,omitempty"`
+	// LinesRemoved counts the lines of extracted text dropped.
+	LinesRemoved int `json:"lines_removed,omitempty"`
+	// Matches counts the matches replaced.
+	Matches int `json:"matches,omitempty"`
+}
+
+// audit records t in the page's audit trail.
+func (p *page) audit(t transform) {
+	p.Transforms = append(p.Transforms, t)
+}
+
+// auditNodes records a DOM transform that took doc from before elements to
+// fewer.
+func (p *page) auditNodes(kind, rule string, doc *html.Node, before int) {
+	if removed := before - countElements(doc); removed > 0 {
+		p.audit(transform{Kind: kind, Rule: rule, NodesRemoved: removed})
+	}
+}
+
+// auditLines records a text transform that dropped lines from before to
+// after.
+func (p *page) auditLines(kind, rule, before, after string) {
+	if removed := countLines(before) - countLines(after); removed > 0 {
+		p.audit(transform{Kind: kind, Rule: rule, LinesRemoved: removed})
+	} else if before != after {
+		p.audit(transform{Kind: kind, Rule: rule})
+	}
+}
+
+// countElements returns the number of element nodes in the tree of n.
+func countElements(n *html.Node) int {
+	count := 0
+	if n.Type == html.ElementNode {
+		count++
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		count += countElements(c)
+	}
+	return count
+}
+
+// countLines returns the number of non-blank lines of text.
+func countLines(text string) int {
+	count := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// redactTransforms returns transforms with secrets masked in their rules,
+// e.g. tokens in pipe commands.
+func (s *Scraper) redactTransforms(transforms []transform) []transform {
+	if transforms == nil {
+		return nil
+	}
+	redacted := make([]transform, len(transforms))
+	for i, t := range transforms {
+		t.Rule = s.Redact(t.Rule)
+		redacted[i] = t
+	}
+	return redacted
+}
+
+// recipeRule names a selector of recipe r in the audit trail.
+func recipeRule(r *Recipe, selector string) string {
+	return fmt.Sprintf("%s: %s", r.Name, selector)
+}
@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExtractTextAudit(t *testing.T) {
+	r := &Recipe{Name: "docs", Domains: []string{"example.com"}, Content: "main", Remove: []string{".ad", ".missing"}}
+	if err := r.compile(); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(true, false)
+	s.Recipes = []*Recipe{r}
+	s.Boilerplate = BoilerplateFilter{MinWords: 2, MaxLinkDensity: 0.5}
+
+	u, _ := url.Parse("https://example.com/guide")
+	p := &page{URL: u, Body: []byte(`<html><body><nav><a href="/">Home</a></nav>
+		<main><div class="ad"><p>Buy now</p></div>
+		<ul><li><a href="/a">First link</a></li><li><a href="/b">Second link</a></li></ul>
+		<p>Body text with words.</p><p>Short</p></main>
+		<footer>Footer</footer></body></html>`)}
+	if _, err := s.extractText(p); err != nil {
+		t.Fatal(err)
+	}
+	want := []transform{
+		{Kind: transformRecipeRemove, Rule: "docs: .ad", NodesRemoved: 2},
+		{Kind: transformRecipeContent, Rule: "docs: main", NodesRemoved: 5},
+		{Kind: transformLinkDensity, Rule: "0.5", NodesRemoved: 5},
+		{Kind: transformMinWords, Rule: "2 words", LinesRemoved: 1},
+	}
+	if !reflect.DeepEqual(p.Transforms, want) {
+		t.Errorf("Transforms = %+v\nwant %+v", p.Transforms, want)
+	}
+
+	// A preview removes nothing, so nothing is recorded
+	s.Boilerplate = BoilerplateFilter{MinWords: 2, Preview: true}
+	s.Recipes = nil
+	p = &page{URL: u, Body: []byte(`<p>Short</p><p>Long enough text.</p>`)}
+	if _, err := s.extractText(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Transforms) != 0 {
+		t.Errorf("preview recorded %+v", p.Transforms)
+	}
+}
+
+func TestRedactPageAudit(t *testing.T) {
+	s := NewScraper(true, false)
+	s.RedactContent = []*regexp.Regexp{regexp.MustCompile(`\d{4}`), regexp.MustCompile(`nowhere`)}
+	p := &page{Title: "Invoice 2024", Text: "Card 1234 5678"}
+	s.redactPage(p)
+	want := []transform{{Kind: transformRedact, Rule: `\d{4}`, Matches: 3}}
+	if !reflect.DeepEqual(p.Transforms, want) {
+		t.Errorf("Transforms = %+v, want %+v", p.Transforms, want)
+	}
+	if p.Text != "Card ████ ████" || p.Title != "Invoice ████" {
+		t.Errorf("redacted to %q, %q", p.Title, p.Text)
+	}
+}
+
+func TestRemoveRepeatedBlocksAudit(t *testing.T) {
+	pages := []*page{
+		{Text: "Intro one\n\nShared footer"},
+		{Text: "Intro two\n\nShared footer"},
+	}
+	removeRepeatedBlocks(pages, 0.5)
+	for _, p := range pages {
+		want := []transform{{Kind: transformRepeated, Rule: "0.5", LinesRemoved: 1}}
+		if !reflect.DeepEqual(p.Transforms, want) {
+			t.Errorf("Transforms = %+v, want %+v", p.Transforms, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// noAuthor is the group of pages without a byline in the table of
+// contents of GroupByAuthor archives.
+const noAuthor = "Unknown author"
+
+// authorMeta are the meta tags, by name or property, that give an
+// article's author, most specific first.
+var authorMeta = []string{"author", "article:author", "parsely-author", "sailthru.author", "dc.creator", "citation_author", "twitter:creator"}
+
+// jsonLDAuthor matches the author of JSON-LD metadata, a name or an
+// object with one.
+var jsonLDAuthor = regexp.MustCompile(`"author"\s*:\s*(?:\[\s*)?(?:"([^"]+)"|\{[^{}]*?"name"\s*:\s*"([^"]+)")`)
+
+// bylineSelector selects the elements holding an article's byline.
+var bylineSelector = cascadia.MustCompile(`[rel=author], [itemprop=author], .byline, .author, .post-author, .entry-author`)
+
+// metaContentSelector selects the meta tags with a value.
+var metaContentSelector = cascadia.MustCompile("meta[content]")
+
+// bylinePrefix matches the "By" a byline starts with.
+var bylinePrefix = regexp.MustCompile(`(?i)^(written\s+)?by\s+`)
+
+// maxAuthorLength bounds a byline, longer text is taken for content.
+const maxAuthorLength = 80
+
+// pageAuthor returns the author of the page body, "" when it can't tell.
+// The author is taken from meta tags, then JSON-LD metadata, then the
+// page's byline. Bodies over threshold bytes, when it is set, aren't
+// searched for a byline.
+func pageAuthor(body []byte, threshold int64) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	meta := make(map[string]string)
+	for _, n := range cascadia.QueryAll(doc, metaContentSelector) {
+		var key, content string
+		for _, a := range n.Attr {
+			switch a.Key {
+			case "name", "property":
+				key = strings.ToLower(a.Val)
+			case "content":
+				content = a.Val
+			}
+		}
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = content
+		}
+	}
+	for _, name := range authorMeta {
+		// article:author is often a profile URL
+		if author := cleanAuthor(meta[name]); author != "" && !strings.Contains(author, "://") {
+			return author
+		}
+	}
+	if m := jsonLDAuthor.FindSubmatch(body); m != nil {
+		if author := cleanAuthor(string(m[1]) + string(m[2])); author != "" {
+			return author
+		}
+	}
+	if threshold > 0 && int64(len(body)) > threshold {
+		return ""
+	}
+	for _, n := range cascadia.QueryAll(doc, bylineSelector) {
+		if n.Data == "meta" || n.Data == "link" {
+			continue
+		}
+		if author := cleanAuthor(nodeText(n)); author != "" {
+			return author
+		}
+	}
+	return ""
+}
+
+// cleanAuthor returns a byline without its "By" and extra spaces, or ""
+// when it is too long to be a name.
+func cleanAuthor(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = strings.TrimPrefix(bylinePrefix.ReplaceAllString(s, ""), "@")
+	if len(s) > maxAuthorLength {
+		return ""
+	}
+	return s
+}
+
+// authorDir returns the directory the page at u is archived in with
+// GroupByAuthor, named after its author, or "" for pages without one.
+func (s *Scraper) authorDir(u *url.URL) string {
+	if !s.GroupByAuthor {
+		return ""
+	}
+	author, ok := s.authors.Load(stripFragment(u).String())
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(author.(string)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// recordAuthor keeps the author of p for the name of its files.
+func (s *Scraper) recordAuthor(p *page) {
+	if p.Author != "" {
+		s.authors.Store(stripFragment(p.URL).String(), p.Author)
+	}
+}
+
+// groupByAuthor orders the archived pages by author for GroupByAuthor,
+// the authors in the order their first page came, and pages without an
+// author last. The order of each author's pages is kept.
+func (s *Scraper) groupByAuthor() {
+	if !s.GroupByAuthor {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := make(map[string]int)
+	for i, p := range s.pdfs {
+		if _, seen := first[p.Author]; !seen && p.Author != "" {
+			first[p.Author] = i
+		}
+	}
+	rank := func(p *page) int {
+		if i, ok := first[p.Author]; ok {
+			return i
+		}
+		return len(s.pdfs)
+	}
+	sort.SliceStable(s.pdfs, func(i, j int) bool {
+		return rank(s.pdfs[i]) < rank(s.pdfs[j])
+	})
+}
+
+// authorHeading returns the author heading to put before the i-th archived
+// page with GroupByAuthor, "" when it continues the group of the page
+// before it.
+func (s *Scraper) authorHeading(i int) string {
+	if !s.GroupByAuthor || (i > 0 && s.pdfs[i-1].Author == s.pdfs[i].Author) {
+		return ""
+	}
+	if s.pdfs[i].Author == "" {
+		return noAuthor
+	}
+	return s.pdfs[i].Author
+}
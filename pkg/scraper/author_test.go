@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPageAuthor(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"meta", `<head><meta name="author" content="Ada Lovelace"></head><p class="byline">By Someone Else</p>`, "Ada Lovelace"},
+		{"profile url", `<head><meta property="article:author" content="https://example.com/ada"><meta name="parsely-author" content="Ada"></head>`, "Ada"},
+		{"json-ld object", `<script type="application/ld+json">{"author": {"@type": "Person", "name": "Grace Hopper"}}</script>`, "Grace Hopper"},
+		{"json-ld list", `<script type="application/ld+json">{"author": [{"@type": "Person", "name": "Alan Turing"}]}</script>`, "Alan Turing"},
+		{"json-ld name", `<script type="application/ld+json">{"author": "Edsger Dijkstra"}</script>`, "Edsger Dijkstra"},
+		{"byline", `<article><p class="byline">By  <a href="/u/kay">Alan Kay</a></p></article>`, "Alan Kay"},
+		{"rel author", `<span>Written by <a rel="author" href="/u/lisp">John McCarthy</a></span>`, "John McCarthy"},
+		{"long byline", `<div class="author">` + strings.Repeat("not a name ", 20) + `</div>`, ""},
+		{"none", `<p>No byline</p>`, ""},
+	}
+	for _, tt := range tests {
+		if got := pageAuthor([]byte("<html>"+tt.body+"</html>"), 0); got != tt.want {
+			t.Errorf("%s: pageAuthor() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGroupByAuthor(t *testing.T) {
+	mustPage := func(raw, author string) *page {
+		u, _ := url.Parse(raw)
+		return &page{URL: u, Author: author}
+	}
+	s := NewScraper(true, false)
+	s.GroupByAuthor = true
+	s.pdfs = []*page{
+		mustPage("https://example.com/", ""),
+		mustPage("https://example.com/a1", "Ada"),
+		mustPage("https://example.com/g1", "Grace"),
+		mustPage("https://example.com/a2", "Ada"),
+		mustPage("https://example.com/g2", "Grace"),
+	}
+
+	s.groupByAuthor()
+
+	var got, headings []string
+	for i, p := range s.pdfs {
+		got = append(got, p.URL.Path)
+		headings = append(headings, s.authorHeading(i))
+	}
+	if want := []string{"/a1", "/a2", "/g1", "/g2", "/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	if want := []string{"Ada", "", "Grace", "", noAuthor}; !reflect.DeepEqual(headings, want) {
+		t.Errorf("headings = %q, want %q", headings, want)
+	}
+}
+
+func TestScrapeGroupByAuthor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/one">1</a> <a href="/two">2</a></body></html>`)
+		case "/one":
+			fmt.Fprint(w, `<html><head><meta name="author" content="Ada Lovelace"></head><body><p>Notes</p></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><p class="byline">By Grace Hopper</p><p>Compilers</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.GroupByAuthor = true
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	entries := zipEntries(t, zipname)
+	for _, name := range []string{"ada-lovelace/" + host + "_one.pdf", "grace-hopper/" + host + "_two.pdf", host + "_index.pdf"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("archive lacks %s", name)
+		}
+	}
+	if !strings.Contains(entries["manifest.json"], `"author": "Ada Lovelace"`) {
+		t.Errorf("manifest lacks the author:\n%s", entries["manifest.json"])
+	}
+	if text := pdfText([]byte(entries["_toc.pdf"])); !strings.Contains(text, "Grace Hopper") || !strings.Contains(text, noAuthor) {
+		t.Errorf("table of contents = %q, want the authors as headings", text)
+	}
+
+	// A redacted byline names neither files nor groups
+	s = NewScraper(true, false)
+	s.GroupByAuthor = true
+	s.RedactContent = []*regexp.Regexp{regexp.MustCompile(`Hopper`)}
+	zipname = filepath.Join(t.TempDir(), "redacted.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	entries = zipEntries(t, zipname)
+	// The audit trail of the manifest names the rule itself
+	for name, data := range entries {
+		if strings.Contains(name, "hopper") || strings.Contains(data, "Grace Hopper") {
+			t.Errorf("redacted author left in %s", name)
+		}
+	}
+	if _, ok := entries["grace/"+host+"_two.pdf"]; !ok {
+		t.Errorf("archive lacks grace/%s_two.pdf", host)
+	}
+}
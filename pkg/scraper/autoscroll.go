@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// Defaults of Scraper.MaxScrolls and Scraper.ScrollSettle.
+const (
+	DefaultMaxScrolls   = 20
+	DefaultScrollSettle = time.Second
+)
+
+// scrollToBottom scrolls the page to its end and returns its height before
+// the scroll.
+const scrollToBottom = `(() => {
+	const height = document.documentElement.scrollHeight;
+	window.scrollTo(0, height);
+	return height;
+})()`
+
+// autoScroll scrolls the page to its bottom until its height stops
+// growing, up to times scrolls, waiting settle after each one for the
+// content it triggers to load. It ends back at the top so prints start
+// there.
+func autoScroll(times int, settle time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		last := -1.0
+		for range times {
+			var height float64
+			if err := chromedp.Run(ctx, chromedp.Evaluate(scrollToBottom, &height), chromedp.Sleep(settle)); err != nil {
+				return fmt.Errorf("failed to scroll: %w", err)
+			}
+			if height == last {
+				break
+			}
+			last = height
+		}
+		return chromedp.Run(ctx, chromedp.Evaluate(`window.scrollTo(0, 0)`, nil))
+	})
+}
+
+// beforeCapture returns the actions run on a page at host once it has
+// loaded in the browser: its site's BrowserScript, then AutoScroll.
+func (s *Scraper) beforeCapture(host string) chromedp.Tasks {
+	tasks := s.browserScript(host)
+	if s.AutoScroll {
+		tasks = append(tasks, autoScroll(s.MaxScrolls, s.ScrollSettle))
+	}
+	return tasks
+}
+
+// checkAutoScroll rejects scroll settings the run can't use.
+func (s *Scraper) checkAutoScroll() error {
+	switch {
+	case !s.AutoScroll:
+		return nil
+	case !s.needsBrowser():
+		return fmt.Errorf("scrolling needs the %s renderer or JavaScript rendering", RendererChrome)
+	case s.MaxScrolls < 1:
+		return fmt.Errorf("auto-scroll needs at least one scroll, got %d", s.MaxScrolls)
+	case s.ScrollSettle < 0:
+		return fmt.Errorf("the scroll settle time can't be negative")
+	}
+	return nil
+}
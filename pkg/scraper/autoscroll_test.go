@@ -0,0 +1,49 @@
+package scraper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckAutoScroll(t *testing.T) {
+	tests := []struct {
+		name     string
+		renderer string
+		renderJS bool
+		scrolls  int
+		settle   time.Duration
+		wantErr  bool
+	}{
+		{"chrome", RendererChrome, false, DefaultMaxScrolls, DefaultScrollSettle, false},
+		{"render-js", RendererText, true, 5, 0, false},
+		{"text renderer", RendererText, false, DefaultMaxScrolls, DefaultScrollSettle, true},
+		{"no scrolls", RendererChrome, false, 0, DefaultScrollSettle, true},
+		{"negative settle", RendererBoth, false, 3, -time.Second, true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.AutoScroll = true
+		s.Renderer, s.RenderJS = tt.renderer, tt.renderJS
+		s.MaxScrolls, s.ScrollSettle = tt.scrolls, tt.settle
+		if err := s.checkAutoScroll(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkAutoScroll() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBeforeCapture(t *testing.T) {
+	s := NewScraper(false, false)
+	s.BrowserScripts = []*BrowserScript{
+		{Domains: []string{"example.com"}, Actions: []BrowserAction{{Click: "button"}, {Wait: "1s"}}},
+	}
+	if got := len(s.beforeCapture("example.com")); got != 2 {
+		t.Errorf("got %d actions without scrolling, want 2", got)
+	}
+	s.AutoScroll = true
+	if got := len(s.beforeCapture("example.com")); got != 3 {
+		t.Errorf("got %d actions with scrolling, want 3", got)
+	}
+	if got := len(s.beforeCapture("example.org")); got != 1 {
+		t.Errorf("got %d actions on another site, want the scroll", got)
+	}
+}
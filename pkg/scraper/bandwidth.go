@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseBandwidth parses a transfer rate such as "2MB/s" or "512KB" into
+// bytes per second. The "/s" suffix is optional; units are those of
+// ParseSize.
+func ParseBandwidth(s string) (int64, error) {
+	text := strings.TrimSpace(s)
+	if strings.HasSuffix(strings.ToLower(text), "/s") {
+		text = text[:len(text)-2]
+	}
+	n, err := ParseSize(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q", s)
+	}
+	return n, nil
+}
+
+// bandwidthChunk is the most read from a response body before waiting, so
+// the rate stays smooth instead of alternating between bursts and stalls.
+const bandwidthChunk = 16 << 10
+
+// bandwidthLimiter paces reads so that, together, they stay under a byte
+// rate. It is shared by every request of the crawl.
+type bandwidthLimiter struct {
+	mu   sync.Mutex
+	rate int64     // bytes per second
+	next time.Time // when the bytes already granted have been paid for
+	// now and sleep are replaced in tests.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newBandwidthLimiter(rate int64) *bandwidthLimiter {
+	return &bandwidthLimiter{rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+// wait blocks until n more bytes fit in the rate.
+func (l *bandwidthLimiter) wait(n int) {
+	l.mu.Lock()
+	now := l.now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	l.mu.Unlock()
+	if delay > 0 {
+		l.sleep(delay)
+	}
+}
+
+// throttledBody reads a response body at the limiter's pace.
+type throttledBody struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > bandwidthChunk {
+		p = p[:bandwidthChunk]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.limiter.wait(n)
+	}
+	return n, err
+}
+
+// throttledTransport limits the download rate of every response it
+// returns.
+type throttledTransport struct {
+	base    http.RoundTripper
+	limiter *bandwidthLimiter
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &throttledBody{ReadCloser: resp.Body, limiter: t.limiter}
+	return resp, nil
+}
+
+// bandwidthTransport returns a transport enforcing MaxBandwidth, or nil
+// when there is no limit.
+func (s *Scraper) bandwidthTransport() http.RoundTripper {
+	if s.MaxBandwidth <= 0 {
+		return nil
+	}
+	return &throttledTransport{
+		base:    s.baseTransport(),
+		limiter: newBandwidthLimiter(s.MaxBandwidth),
+	}
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"2MB/s", 2 << 20, false},
+		{"512kb/S", 512 << 10, false},
+		{"1000", 1000, false},
+		{"fast", 0, true},
+		{"/s", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseBandwidth(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBandwidth(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBandwidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestThrottledBody(t *testing.T) {
+	l := newBandwidthLimiter(1000)
+	clock := time.Now()
+	l.now = func() time.Time { return clock }
+	l.sleep = func(d time.Duration) { clock = clock.Add(d) }
+	start := clock
+
+	body := &throttledBody{ReadCloser: io.NopCloser(strings.NewReader(strings.Repeat("x", 3000))), limiter: l}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3000 {
+		t.Fatalf("read %d bytes, want 3000", len(data))
+	}
+	// The first read is free, the rest waits for the bytes before it: about
+	// two seconds for 3000 bytes at 1000 bytes per second.
+	slept := clock.Sub(start)
+	if slept < 1900*time.Millisecond || slept > 3*time.Second {
+		t.Errorf("slept %v, want about 2s", slept)
+	}
+}
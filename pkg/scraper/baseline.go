@@ -0,0 +1,189 @@
+package scraper
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoChanges is returned by ScrapeAndSave when no page changed since the
+// Baseline; no archive is written.
+var ErrNoChanges = errors.New("no pages changed since the baseline")
+
+// Changes of a page from the Baseline, recorded in the manifest.
+const (
+	changeNew     = "new"
+	changeChanged = "changed"
+)
+
+// baselinePage is what the Baseline recorded of a page.
+type baselinePage struct {
+	SHA256  string
+	Headers map[string]string
+}
+
+// baselineSummary describes the Baseline in the manifest of a delta
+// archive.
+type baselineSummary struct {
+	Archive     string    `json:"archive"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Unchanged are the pages of the baseline fetched again unchanged, and
+	// left out.
+	Unchanged []string `json:"unchanged,omitempty"`
+	// Removed are the pages of the baseline that weren't archived or found
+	// unchanged: gone, failed or out of the crawl's reach.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// bodySHA256 returns the hex SHA-256 of a response body, as recorded in
+// the manifest.
+func bodySHA256(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadBaseline reads the manifest of the Baseline archive.
+func (s *Scraper) loadBaseline() error {
+	if s.Baseline == "" {
+		return nil
+	}
+	m, err := readArchiveManifest(s.Baseline)
+	if err != nil {
+		return fmt.Errorf("failed to read baseline: %w", err)
+	}
+	s.baseline = make(map[string]baselinePage, len(m.Pages))
+	for _, p := range m.Pages {
+		s.baseline[p.URL] = baselinePage{SHA256: p.SHA256, Headers: p.Headers}
+	}
+	s.baselineTime = m.GeneratedAt
+	return nil
+}
+
+// readArchiveManifest returns the manifest of an archive written by
+// scrapdf: a ZIP file, a tarball or an output directory.
+func readArchiveManifest(archive string) (*manifest, error) {
+	var data []byte
+	info, err := os.Stat(archive)
+	switch {
+	case err != nil:
+		return nil, err
+	case info.IsDir():
+		data, err = os.ReadFile(filepath.Join(archive, "manifest.json"))
+	case strings.HasSuffix(archive, ".tar"), strings.HasSuffix(archive, ".tar.gz"), strings.HasSuffix(archive, ".tgz"):
+		data, err = readTarEntry(archive, "manifest.json")
+	default:
+		var r *zip.ReadCloser
+		if r, err = zip.OpenReader(archive); err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		data, err = readZipEntry(&r.Reader, "manifest.json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s has no manifest: %w", archive, err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// readTarEntry returns the content of the named entry of a tarball,
+// gzipped or not.
+func readTarEntry(tarname, name string) ([]byte, error) {
+	f, err := os.Open(tarname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if gz, err := gzip.NewReader(f); err == nil {
+		defer gz.Close()
+		r = gz
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fs.ErrNotExist
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// baselineChange compares a page fetched with header and a body of the
+// given hash to the Baseline: "" when it is unchanged, otherwise
+// changeNew or changeChanged. Baselines from before hashes were recorded
+// are compared by their ETag and Last-Modified validators.
+func (s *Scraper) baselineChange(u string, sum string, header http.Header) string {
+	old, ok := s.baseline[s.Redact(u)]
+	switch {
+	case !ok:
+		return changeNew
+	case old.SHA256 != "":
+		if old.SHA256 == sum {
+			return ""
+		}
+	case header.Get("ETag") != "" && header.Get("ETag") == old.Headers["ETag"],
+		header.Get("Last-Modified") != "" && header.Get("Last-Modified") == old.Headers["Last-Modified"]:
+		return ""
+	}
+	return changeChanged
+}
+
+// recordUnchanged notes a page of the Baseline fetched again unchanged.
+func (s *Scraper) recordUnchanged(u string) {
+	s.logf("Unchanged since the baseline: %s\n", u)
+	s.event(event{Type: eventSkip, URL: u, Reason: "unchanged since the baseline"})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unchanged = append(s.unchanged, s.Redact(u))
+}
+
+// baselineSummary returns the Baseline's part of the manifest, nil without
+// one. Call with s.mu held.
+func (s *Scraper) baselineSummary() *baselineSummary {
+	if s.baseline == nil {
+		return nil
+	}
+	summary := &baselineSummary{
+		Archive:     filepath.Base(s.Baseline),
+		GeneratedAt: s.baselineTime,
+		Unchanged:   s.unchanged,
+	}
+	seen := make(map[string]bool, len(s.pdfs)+len(s.unchanged))
+	for _, p := range s.pdfs {
+		seen[s.Redact(p.URL.String())] = true
+	}
+	for _, u := range s.unchanged {
+		seen[u] = true
+	}
+	for u := range s.baseline {
+		if !seen[u] {
+			summary.Removed = append(summary.Removed, u)
+		}
+	}
+	sort.Strings(summary.Removed)
+	return summary
+}
@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBaselineDelta(t *testing.T) {
+	var changed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><p>Home</p><a href="/a">A</a> <a href="/b">B</a> <a href="/gone">Gone</a></body></html>`)
+		case "/a":
+			fmt.Fprint(w, `<html><body><p>Page A</p><a href="/c">C</a></body></html>`)
+		case "/b":
+			if changed.Load() {
+				fmt.Fprint(w, `<html><body><p>Page B, edited</p></body></html>`)
+			} else {
+				fmt.Fprint(w, `<html><body><p>Page B</p></body></html>`)
+			}
+		case "/c":
+			// The new page is only linked from an unchanged one
+			if !changed.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, `<html><body><p>Page C</p></body></html>`)
+		case "/gone":
+			if changed.Load() {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, `<html><body><p>Soon gone</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	baseline := filepath.Join(dir, "baseline.tar.gz")
+	s := NewScraper(true, false)
+	s.ArchiveFormat = ArchiveTarGz
+	if err := s.ScrapeAndSave(srv.URL+"/", baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	// Serving the same pages again gives no archive
+	s = NewScraper(true, false)
+	s.Baseline = baseline
+	if err := s.ScrapeAndSave(srv.URL+"/", filepath.Join(dir, "same.zip")); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("ScrapeAndSave() without changes = %v, want ErrNoChanges", err)
+	}
+
+	changed.Store(true)
+	s = NewScraper(true, false)
+	s.Baseline = baseline
+	zipname := filepath.Join(dir, "delta.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(zipEntries(t, zipname)["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	changes := make(map[string]string)
+	for _, p := range m.Pages {
+		if p.SHA256 == "" {
+			t.Errorf("%s has no hash", p.URL)
+		}
+		changes[strings.TrimPrefix(p.URL, srv.URL)] = p.Change
+	}
+	if len(changes) != 2 || changes["/b"] != changeChanged || changes["/c"] != changeNew {
+		t.Errorf("archived changes = %v, want /b changed and /c new", changes)
+	}
+	if m.Baseline == nil {
+		t.Fatal("manifest lacks the baseline")
+	}
+	if m.Baseline.Archive != "baseline.tar.gz" || m.Baseline.GeneratedAt.IsZero() {
+		t.Errorf("baseline = %+v", m.Baseline)
+	}
+	if len(m.Baseline.Unchanged) != 2 {
+		t.Errorf("unchanged = %v, want / and /a", m.Baseline.Unchanged)
+	}
+	if len(m.Baseline.Removed) != 1 || m.Baseline.Removed[0] != srv.URL+"/gone" {
+		t.Errorf("removed = %v, want /gone", m.Baseline.Removed)
+	}
+}
+
+func TestBaselineChange(t *testing.T) {
+	s := NewScraper(true, false)
+	s.baseline = map[string]baselinePage{
+		"https://example.com/hashed": {SHA256: "abc"},
+		"https://example.com/etag":   {Headers: map[string]string{"ETag": `"v1"`}},
+		"https://example.com/dated":  {Headers: map[string]string{"Last-Modified": "Mon, 05 Oct 2026 10:00:00 GMT"}},
+		"https://example.com/bare":   {},
+	}
+	tests := []struct {
+		url, sum string
+		header   http.Header
+		want     string
+	}{
+		{"https://example.com/new", "abc", nil, changeNew},
+		{"https://example.com/hashed", "abc", nil, ""},
+		{"https://example.com/hashed", "def", nil, changeChanged},
+		{"https://example.com/etag", "def", http.Header{"Etag": {`"v1"`}}, ""},
+		{"https://example.com/etag", "def", http.Header{"Etag": {`"v2"`}}, changeChanged},
+		{"https://example.com/dated", "def", http.Header{"Last-Modified": {"Mon, 05 Oct 2026 10:00:00 GMT"}}, ""},
+		{"https://example.com/bare", "def", http.Header{}, changeChanged},
+	}
+	for _, tt := range tests {
+		if got := s.baselineChange(tt.url, tt.sum, tt.header); got != tt.want {
+			t.Errorf("baselineChange(%s, %s, %v) = %q, want %q", tt.url, tt.sum, tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// BoilerplateFilter configures how navigation menus, link lists and other
+// boilerplate are removed from stripped pages. The zero value removes nothing.
+type BoilerplateFilter struct {
+	// MinWords drops lines with fewer words than this. Zero disables the check.
+	MinWords int
+	// MaxLinkDensity drops block elements where the share of characters
+	// inside links exceeds this ratio (0-1). Zero disables the check.
+	MaxLinkDensity float64
+	// RepeatPages drops lines that were already seen on this many earlier
+	// pages of the crawl. Zero disables the check.
+	RepeatPages int
+	// Preview reports what would be removed without removing it.
+	Preview bool
+}
+
+// linkDensityContainers are the elements scored for link density. Inline
+// elements are scored as part of their enclosing block.
+var linkDensityContainers = map[string]bool{
+	"div": true, "nav": true, "header": true, "footer": true, "aside": true,
+	"section": true, "ul": true, "ol": true, "table": true, "p": true, "menu": true,
+}
+
+// removal records a piece of text dropped by the filter and why.
+type removal struct {
+	Reason string
+	Text   string
+	kind   string // transform kind of the line filters
+}
+
+// extractText strips the page's HTML and applies the site recipe, or the
+// readability extractor, and the boilerplate filter.
+func (s *Scraper) extractText(p *page) (string, error) {
+	f := s.Boilerplate
+	var removed []removal
+	var content string
+
+	if s.StreamThreshold > 0 && int64(len(p.Body)) > s.StreamThreshold {
+		// Recipes, readability and link density need the DOM, so huge
+		// pages only get the line filters.
+		s.logf("Streaming %s (%d bytes) without building a DOM\n", p.URL, len(p.Body))
+		text, err := streamText(bytes.NewReader(p.Body))
+		if err != nil {
+			return "", err
+		}
+		content = text
+		if s.Format == FormatEPUB {
+			content = textChapter(text)
+		}
+	} else {
+		doc, err := html.Parse(bytes.NewReader(p.Body))
+		if err != nil {
+			return "", err
+		}
+
+		r := s.recipeFor(p.URL.Host)
+		if r != nil && !r.apply(doc, p) {
+			s.logf("Recipe %s found no content on %s, keeping the whole page\n", r.Name, p.URL)
+		}
+		// A recipe knows the site better than the heuristic
+		if s.Readability && r == nil {
+			before := countElements(doc)
+			if !readability(doc) {
+				s.logf("No article found on %s, keeping the whole page\n", p.URL)
+			}
+			p.auditNodes(transformReadability, "", doc, before)
+		}
+
+		if f.MaxLinkDensity > 0 {
+			before := countElements(doc)
+			removed = append(removed, pruneLinkDense(doc, f.MaxLinkDensity, !f.Preview)...)
+			p.auditNodes(transformLinkDensity, fmt.Sprintf("%g", f.MaxLinkDensity), doc, before)
+		}
+		switch s.Format {
+		case FormatMarkdown:
+			content = htmlToMarkdown(doc, contentBase(p))
+		case FormatEPUB:
+			content = chapterXHTML(doc, contentBase(p))
+		default:
+			content = layoutText(doc, s.embedsImages())
+			p.Links = pageLinks(doc, contentBase(p))
+			if s.SinglePDF {
+				p.Anchors = pageAnchors(doc, p.URL)
+			}
+		}
+	}
+
+	var lineRemovals []removal
+	content, lineRemovals = s.filterLines(content)
+	removed = append(removed, lineRemovals...)
+	if !f.Preview {
+		s.auditLineFilters(p, lineRemovals)
+	}
+
+	if f.Preview && len(removed) > 0 {
+		s.logf("Boilerplate preview for %s (%d blocks would be removed):\n", p.URL, len(removed))
+		for _, r := range removed {
+			s.logf("  - [%s] %s\n", r.Reason, previewText(r.Text))
+		}
+	}
+	return content, nil
+}
+
+// pruneLinkDense removes container elements whose link density exceeds max.
+// Nested containers are scored first, so a link list inside an article is
+// removed without taking the article with it. With apply false the tree is
+// left untouched and only the removals are reported.
+func pruneLinkDense(doc *html.Node, max float64, apply bool) []removal {
+	dropped := make(map[*html.Node]bool)
+	var removed []removal
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		mark := len(removed)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+		if n.Type != html.ElementNode || !linkDensityContainers[n.Data] {
+			return
+		}
+		total, links := textChars(n, false, dropped)
+		if total == 0 || float64(links)/float64(total) <= max {
+			return
+		}
+		// The whole container goes, so its nested removals need no entry
+		// of their own.
+		removed = append(removed[:mark], removal{
+			Reason: fmt.Sprintf("link density %.2f", float64(links)/float64(total)),
+			Text:   strings.Join(strings.Fields(nodeText(n)), " "),
+		})
+		dropped[n] = true
+	}
+	visit(doc)
+
+	if apply {
+		for n := range dropped {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+	return removed
+}
+
+// textChars counts the visible characters below n, and how many of them are
+// inside links, ignoring subtrees in skip.
+func textChars(n *html.Node, inLink bool, skip map[*html.Node]bool) (total, links int) {
+	if skip[n] {
+		return 0, 0
+	}
+	if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style" || n.Data == "noscript") {
+		return 0, 0
+	}
+	if n.Type == html.ElementNode && n.Data == "a" {
+		inLink = true
+	}
+	if n.Type == html.TextNode {
+		chars := utf8.RuneCountInString(strings.Join(strings.Fields(n.Data), " "))
+		if inLink {
+			return chars, chars
+		}
+		return chars, 0
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t, l := textChars(c, inLink, skip)
+		total += t
+		links += l
+	}
+	return total, links
+}
+
+// filterLines applies the line based checks of the filter to content. Empty
+// lines are always kept so the paragraph layout survives.
+func (s *Scraper) filterLines(content string) (string, []removal) {
+	f := s.Boilerplate
+	if f.MinWords <= 0 && f.RepeatPages <= 0 {
+		return content, nil
+	}
+
+	var repeated map[string]bool
+	if f.RepeatPages > 0 {
+		repeated = s.trackRepeatedLines(content)
+	}
+
+	var kept []string
+	var removed []removal
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		reason, kind := "", ""
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, imageMarker):
+		case f.MinWords > 0 && len(strings.Fields(trimmed)) < f.MinWords:
+			reason, kind = fmt.Sprintf("fewer than %d words", f.MinWords), transformMinWords
+		case repeated[trimmed]:
+			reason, kind = fmt.Sprintf("seen on %d+ earlier pages", f.RepeatPages), transformRepeatLines
+		}
+		if reason != "" {
+			removed = append(removed, removal{Reason: reason, Text: trimmed, kind: kind})
+			if !f.Preview {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// auditLineFilters records the lines the line filters dropped from p in its
+// audit trail.
+func (s *Scraper) auditLineFilters(p *page, removed []removal) {
+	lines := make(map[string]int)
+	for _, r := range removed {
+		lines[r.kind]++
+	}
+	if n := lines[transformMinWords]; n > 0 {
+		p.audit(transform{Kind: transformMinWords, Rule: fmt.Sprintf("%d words", s.Boilerplate.MinWords), LinesRemoved: n})
+	}
+	if n := lines[transformRepeatLines]; n > 0 {
+		p.audit(transform{Kind: transformRepeatLines, Rule: fmt.Sprintf("%d pages", s.Boilerplate.RepeatPages), LinesRemoved: n})
+	}
+}
+
+// trackRepeatedLines records the lines of content as seen on one more page
+// and returns those that had already been seen on RepeatPages earlier pages.
+func (s *Scraper) trackRepeatedLines(content string) map[string]bool {
+	unique := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			unique[trimmed] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repeated := make(map[string]bool)
+	for line := range unique {
+		if s.seenLines[line] >= s.Boilerplate.RepeatPages {
+			repeated[line] = true
+		}
+		s.seenLines[line]++
+	}
+	return repeated
+}
+
+// previewText shortens text for display in the preview output.
+func previewText(text string) string {
+	const max = 80
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max-3]) + "..."
+}
@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestPruneLinkDense(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		max  float64
+		want string
+	}{
+		{
+			name: "navigation removed",
+			html: `<nav><a href="/">Home</a><a href="/about">About</a></nav><p>Body text here</p>`,
+			max:  0.5,
+			want: "Body text here\n\n",
+		},
+		{
+			name: "paragraph with an inline link kept",
+			html: `<p>Read the <a href="/guide">guide</a> before you start.</p>`,
+			max:  0.5,
+			want: "Read the\n\nguidebefore you start.\n\n",
+		},
+		{
+			name: "link list inside article removed",
+			html: `<div><p>Article body</p><ul><li><a href="/a">Related A</a></li><li><a href="/b">Related B</a></li></ul></div>`,
+			max:  0.5,
+			want: "Article body\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("Failed to parse HTML: %v", err)
+			}
+			pruneLinkDense(doc, tt.max, true)
+			if got := nodeText(doc); got != tt.want {
+				t.Errorf("nodeText() after prune = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	s := NewScraper(true, false)
+	s.Boilerplate = BoilerplateFilter{MinWords: 3, RepeatPages: 1}
+
+	first, removed := s.filterLines("Short one\n\nA line with enough words\nShared footer text line")
+	if want := "\nA line with enough words\nShared footer text line"; first != want {
+		t.Errorf("first page = %q, want %q", first, want)
+	}
+	if len(removed) != 1 {
+		t.Errorf("first page removed %d lines, want 1", len(removed))
+	}
+
+	second, _ := s.filterLines("Another line with words\nShared footer text line")
+	if want := "Another line with words"; second != want {
+		t.Errorf("second page = %q, want %q", second, want)
+	}
+}
+
+func TestFilterLinesPreview(t *testing.T) {
+	s := NewScraper(true, false)
+	s.Boilerplate = BoilerplateFilter{MinWords: 3, Preview: true}
+
+	content := "Too short\nThis line has enough words"
+	got, removed := s.filterLines(content)
+	if got != content {
+		t.Errorf("preview modified content: %q", got)
+	}
+	if len(removed) != 1 || removed[0].Text != "Too short" {
+		t.Errorf("removed = %v, want the short line", removed)
+	}
+}
@@ -0,0 +1,293 @@
+package scraper
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PinnedBrowserVersion is the Chrome for Testing release installed by
+// InstallBrowser. It is bumped together with chromedp so the protocol
+// versions match.
+const PinnedBrowserVersion = "131.0.6778.85"
+
+// browserDownloadURL is the Chrome for Testing headless shell archive for a
+// version and platform.
+var browserDownloadURL = "https://storage.googleapis.com/chrome-for-testing-public/%[1]s/%[2]s/chrome-headless-shell-%[2]s.zip"
+
+// browserChecksums are the SHA-256 digests of the PinnedBrowserVersion
+// archives by platform, bumped with it. A download is only unpacked when
+// it matches, and platforms without a digest can't install the browser
+// unless one is given to InstallBrowser.
+var browserChecksums = map[string]string{}
+
+// browserMarker is written into every version directory InstallBrowser
+// creates. Only directories holding it are ever removed, so a browser
+// directory pointed at existing data leaves that data alone.
+const browserMarker = ".scrapdf-browser"
+
+// browserPlatform returns the Chrome for Testing name of the running
+// platform.
+func browserPlatform() (string, error) {
+	switch runtime.GOOS + "/" + runtime.GOARCH {
+	case "linux/amd64":
+		return "linux64", nil
+	case "darwin/amd64":
+		return "mac-x64", nil
+	case "darwin/arm64":
+		return "mac-arm64", nil
+	case "windows/amd64":
+		return "win64", nil
+	case "windows/386":
+		return "win32", nil
+	}
+	return "", fmt.Errorf("no headless Chromium build is published for %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// DefaultBrowserDir returns the directory managed browsers are installed in.
+func DefaultBrowserDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scrapdf", "browser")
+}
+
+// browserExecutable returns the path of the headless shell inside an install
+// of version in dir.
+func browserExecutable(dir, version, platform string) string {
+	name := "chrome-headless-shell"
+	if strings.HasPrefix(platform, "win") {
+		name += ".exe"
+	}
+	return filepath.Join(dir, version, "chrome-headless-shell-"+platform, name)
+}
+
+// InstalledBrowser returns the executable of the managed browser in dir, or
+// "" if it isn't installed.
+func InstalledBrowser(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	platform, err := browserPlatform()
+	if err != nil {
+		return ""
+	}
+	exe := browserExecutable(dir, PinnedBrowserVersion, platform)
+	if _, err := os.Stat(exe); err != nil {
+		return ""
+	}
+	return exe
+}
+
+// InstallBrowser downloads the pinned headless Chromium into dir and returns
+// its executable. The download must have the SHA-256 digest checksum, in
+// hex, or the one pinned for the platform when checksum is empty. Older
+// managed versions are removed. Progress is written to out.
+func InstallBrowser(dir, checksum string, out io.Writer) (string, error) {
+	if exe := InstalledBrowser(dir); exe != "" {
+		fmt.Fprintf(out, "Chromium %s is already installed\n", PinnedBrowserVersion)
+		return exe, nil
+	}
+	platform, err := browserPlatform()
+	if err != nil {
+		return "", err
+	}
+	if checksum == "" {
+		checksum = browserChecksums[platform]
+	}
+	if checksum == "" {
+		return "", fmt.Errorf("no checksum is pinned for Chromium %s on %s, pass the SHA-256 of the download with --sha256, or install Chrome yourself and use --browser-path", PinnedBrowserVersion, platform)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create browser directory: %w", err)
+	}
+
+	// Download and unpack next to the final location so an interrupted
+	// install never leaves a half extracted browser behind.
+	tmp, err := os.MkdirTemp(dir, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	archive := filepath.Join(tmp, "chromium.zip")
+	src := fmt.Sprintf(browserDownloadURL, PinnedBrowserVersion, platform)
+	fmt.Fprintf(out, "Downloading Chromium %s for %s\n", PinnedBrowserVersion, platform)
+	if err := downloadFile(src, archive); err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(archive, checksum); err != nil {
+		return "", fmt.Errorf("refusing to install %s: %w", src, err)
+	}
+	staged := filepath.Join(tmp, PinnedBrowserVersion)
+	if err := unzipArchive(archive, staged); err != nil {
+		return "", fmt.Errorf("failed to unpack %s: %w", src, err)
+	}
+	if err := os.WriteFile(filepath.Join(staged, browserMarker), []byte(PinnedBrowserVersion+"\n"), 0644); err != nil {
+		return "", err
+	}
+
+	if err := removeManagedBrowsers(dir); err != nil {
+		return "", err
+	}
+	if err := os.Rename(staged, filepath.Join(dir, PinnedBrowserVersion)); err != nil {
+		return "", fmt.Errorf("failed to install browser: %w", err)
+	}
+
+	exe := browserExecutable(dir, PinnedBrowserVersion, platform)
+	if _, err := os.Stat(exe); err != nil {
+		return "", fmt.Errorf("downloaded archive has no browser executable: %w", err)
+	}
+	fmt.Fprintf(out, "Installed %s\n", exe)
+	return exe, nil
+}
+
+// RemoveBrowser deletes every managed browser in dir, and dir itself once
+// nothing else is left in it.
+func RemoveBrowser(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("no browser directory")
+	}
+	if err := removeManagedBrowsers(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	// Fails when dir holds other files, which are kept
+	os.Remove(dir)
+	return nil
+}
+
+// removeManagedBrowsers deletes the version directories of dir that
+// InstallBrowser created, those holding browserMarker.
+func removeManagedBrowsers(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		version := filepath.Join(dir, e.Name())
+		if _, err := os.Stat(filepath.Join(version, browserMarker)); err != nil {
+			continue
+		}
+		if err := os.RemoveAll(version); err != nil {
+			return fmt.Errorf("failed to remove old browser %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// verifyChecksum checks that the SHA-256 digest of filename is want, in
+// hex.
+func verifyChecksum(filename, want string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch: got SHA-256 %s, want %s", got, want)
+	}
+	return nil
+}
+
+// downloadFile saves the body of src to filename.
+func downloadFile(src, filename string) error {
+	resp, err := http.Get(src)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", src, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", src, resp.Status)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download %s: %w", src, err)
+	}
+	return f.Close()
+}
+
+// unzipArchive extracts archive into dest, keeping file modes and symlinks
+// and rejecting entries that would escape dest.
+func unzipArchive(archive, dest string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	root := filepath.Clean(dest) + string(os.PathSeparator)
+	for _, f := range r.File {
+		target := filepath.Join(dest, f.Name)
+		if !strings.HasPrefix(target, root) {
+			return fmt.Errorf("archive entry %q escapes the destination", f.Name)
+		}
+		if err := extractEntry(f, target, root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractEntry writes a single zip entry to target, in the directory root.
+// Symlinks must point inside root too, or later entries could be written
+// through them.
+func extractEntry(f *zip.File, target, root string) error {
+	mode := f.Mode()
+	if mode.IsDir() {
+		return os.MkdirAll(target, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&os.ModeSymlink != 0 {
+		link, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(filepath.Dir(target), string(link))
+		if filepath.IsAbs(string(link)) || !strings.HasPrefix(dest, root) {
+			return fmt.Errorf("archive symlink %q to %q escapes the destination", f.Name, link)
+		}
+		return os.Symlink(string(link), target)
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,195 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// zipFiles builds a zip archive holding files with the given modes.
+func zipFiles(t *testing.T, files map[string]os.FileMode) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, mode := range files {
+		h := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		h.SetMode(mode)
+		f, err := w.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte("content of " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUnzipArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]os.FileMode
+		wantErr bool
+	}{
+		{"regular files", map[string]os.FileMode{"shell/chrome": 0755, "shell/data.pak": 0644}, false},
+		{"path traversal rejected", map[string]os.FileMode{"../evil": 0644}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "a.zip")
+			if err := os.WriteFile(archive, zipFiles(t, tt.files), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(dir, "out")
+			err := unzipArchive(archive, dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unzipArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for name, mode := range tt.files {
+				info, err := os.Stat(filepath.Join(dest, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != mode.Perm() {
+					t.Errorf("%s mode = %v, want %v", name, info.Mode().Perm(), mode.Perm())
+				}
+			}
+		})
+	}
+}
+
+func TestUnzipArchiveSymlinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    string
+		wantErr bool
+	}{
+		{"inside", "data.pak", false},
+		{"parent inside", "../shell/data.pak", false},
+		{"relative escape", "../../etc/passwd", true},
+		{"absolute", "/etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := zip.NewWriter(&buf)
+			h := &zip.FileHeader{Name: "shell/lib.so"}
+			h.SetMode(os.ModeSymlink | 0777)
+			f, err := w.CreateHeader(h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Write([]byte(tt.link)); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "a.zip")
+			if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(dir, "out")
+			err = unzipArchive(archive, dest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unzipArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, err := os.Lstat(filepath.Join(dest, "shell", "lib.so")); !os.IsNotExist(err) {
+					t.Error("escaping symlink was created")
+				}
+				return
+			}
+			if got, err := os.Readlink(filepath.Join(dest, "shell", "lib.so")); err != nil || got != tt.link {
+				t.Errorf("Readlink() = %q, %v, want %q", got, err, tt.link)
+			}
+		})
+	}
+}
+
+func TestInstallBrowser(t *testing.T) {
+	platform, err := browserPlatform()
+	if err != nil {
+		t.Skip(err)
+	}
+	exe := filepath.Base(browserExecutable("", "", platform))
+	archive := zipFiles(t, map[string]os.FileMode{"chrome-headless-shell-" + platform + "/" + exe: 0755})
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		w.Write(archive)
+	}))
+	defer srv.Close()
+	defer func(u string) { browserDownloadURL = u }(browserDownloadURL)
+	browserDownloadURL = srv.URL + "/%s/%s.zip"
+
+	dir := t.TempDir()
+	for _, d := range []string{"120.0.0.0", "photos"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "120.0.0.0", browserMarker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if InstalledBrowser(dir) != "" {
+		t.Fatal("browser reported installed before install")
+	}
+
+	defer func(c map[string]string) { browserChecksums = c }(browserChecksums)
+	browserChecksums = map[string]string{}
+	if _, err := InstallBrowser(dir, "", io.Discard); err == nil || !strings.Contains(err.Error(), "no checksum is pinned") {
+		t.Fatalf("InstallBrowser() without a checksum = %v", err)
+	}
+	if n := downloads.Load(); n != 0 {
+		t.Fatalf("InstallBrowser() without a checksum downloaded %d times", n)
+	}
+	browserChecksums = map[string]string{platform: strings.Repeat("0", 64)}
+	if _, err := InstallBrowser(dir, "", io.Discard); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("InstallBrowser() of a tampered archive = %v", err)
+	}
+	sum := sha256.Sum256(archive)
+
+	// A given checksum wins over the pinned one
+	got, err := InstallBrowser(dir, hex.EncodeToString(sum[:]), io.Discard)
+	if err != nil {
+		t.Fatalf("InstallBrowser() error = %v", err)
+	}
+	if got != InstalledBrowser(dir) {
+		t.Errorf("InstallBrowser() = %q, InstalledBrowser() = %q", got, InstalledBrowser(dir))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "120.0.0.0")); !os.IsNotExist(err) {
+		t.Error("old browser version not removed")
+	}
+
+	if err := RemoveBrowser(dir); err != nil {
+		t.Fatal(err)
+	}
+	if InstalledBrowser(dir) != "" {
+		t.Error("browser still installed after RemoveBrowser")
+	}
+	// Directories scrapdf didn't create are left alone
+	if _, err := os.Stat(filepath.Join(dir, "photos")); err != nil {
+		t.Errorf("unrelated directory removed: %v", err)
+	}
+}
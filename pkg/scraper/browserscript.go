@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"gopkg.in/yaml.v3"
+)
+
+// BrowserScript is a list of actions the headless browser performs on the
+// pages of a site before capturing them, e.g. to press "Load more" or get
+// past an age gate.
+type BrowserScript struct {
+	// Domains the script applies to, including their subdomains.
+	Domains []string `yaml:"domains"`
+	// Actions run in order once the page has loaded.
+	Actions []BrowserAction `yaml:"actions"`
+}
+
+// BrowserAction is one step of a BrowserScript. Exactly one of Click, Type
+// and Wait is set.
+type BrowserAction struct {
+	// Click is the selector of an element to click. Repeat clicks it again
+	// while it is on the page, up to that many times in all, for buttons
+	// that load one more batch each time.
+	Click  string `yaml:"click"`
+	Repeat int    `yaml:"repeat"`
+	// Type is the selector of a field Text is typed into.
+	Type string `yaml:"type"`
+	Text string `yaml:"text"`
+	// Wait is a duration such as "2s" to pause for, or the selector of an
+	// element to wait for.
+	Wait string `yaml:"wait"`
+}
+
+// clickPause gives the page time to react to a click before the next
+// action.
+const clickPause = 500 * time.Millisecond
+
+// LoadBrowserScripts reads the scripts in the YAML file at path, a list of
+// BrowserScript.
+func LoadBrowserScripts(path string) ([]*BrowserScript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read browser scripts: %w", err)
+	}
+	var scripts []*BrowserScript
+	if err := yaml.Unmarshal(data, &scripts); err != nil {
+		return nil, fmt.Errorf("failed to parse browser scripts %s: %w", path, err)
+	}
+	for i, sc := range scripts {
+		if err := sc.check(); err != nil {
+			return nil, fmt.Errorf("%s: script %d: %w", path, i+1, err)
+		}
+	}
+	return scripts, nil
+}
+
+// check validates the script's actions.
+func (sc *BrowserScript) check() error {
+	if len(sc.Domains) == 0 {
+		return fmt.Errorf("script has no domains")
+	}
+	for i, a := range sc.Actions {
+		set := 0
+		for _, v := range []string{a.Click, a.Type, a.Wait} {
+			if v != "" {
+				set++
+			}
+		}
+		switch {
+		case set != 1:
+			return fmt.Errorf("action %d needs exactly one of click, type and wait", i+1)
+		case a.Repeat < 0:
+			return fmt.Errorf("action %d repeats a negative number of times", i+1)
+		case a.Repeat > 0 && a.Click == "":
+			return fmt.Errorf("action %d repeats something other than a click", i+1)
+		case a.Text != "" && a.Type == "":
+			return fmt.Errorf("action %d has text but nothing to type it into", i+1)
+		}
+	}
+	return nil
+}
+
+// tasks returns the browser actions of the script. Clicks and typing skip
+// elements that aren't on the page, so a gate that was already passed
+// doesn't fail the capture.
+func (sc *BrowserScript) tasks() chromedp.Tasks {
+	var tasks chromedp.Tasks
+	for _, a := range sc.Actions {
+		switch {
+		case a.Click != "":
+			tasks = append(tasks, clickWhilePresent(a.Click, max(a.Repeat, 1)))
+		case a.Type != "":
+			sel, text := a.Type, a.Text
+			tasks = append(tasks, ifPresent(sel, chromedp.SendKeys(sel, text, chromedp.ByQuery)))
+		default:
+			if d, err := time.ParseDuration(a.Wait); err == nil {
+				tasks = append(tasks, chromedp.Sleep(d))
+			} else {
+				tasks = append(tasks, chromedp.WaitVisible(a.Wait, chromedp.ByQuery))
+			}
+		}
+	}
+	return tasks
+}
+
+// clickWhilePresent clicks the element matching sel up to times times,
+// stopping once it's gone.
+func clickWhilePresent(sel string, times int) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for range times {
+			present, err := elementPresent(ctx, sel)
+			if err != nil || !present {
+				return err
+			}
+			if err := chromedp.Run(ctx, chromedp.Click(sel, chromedp.ByQuery), chromedp.Sleep(clickPause)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ifPresent runs action when an element matches sel.
+func ifPresent(sel string, action chromedp.Action) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		present, err := elementPresent(ctx, sel)
+		if err != nil || !present {
+			return err
+		}
+		return action.Do(ctx)
+	})
+}
+
+// elementPresent reports whether an element matches sel, without waiting
+// for one to appear.
+func elementPresent(ctx context.Context, sel string) (bool, error) {
+	var nodes []*cdp.Node
+	if err := chromedp.Run(ctx, chromedp.Nodes(sel, &nodes, chromedp.ByQuery, chromedp.AtLeast(0))); err != nil {
+		return false, err
+	}
+	return len(nodes) > 0, nil
+}
+
+// browserScript returns the actions to run on the page at host before it's
+// captured, or nil.
+func (s *Scraper) browserScript(host string) chromedp.Tasks {
+	for _, sc := range s.BrowserScripts {
+		if domainMatches(sc.Domains, host) {
+			return sc.tasks()
+		}
+	}
+	return nil
+}
+
+// checkBrowserScripts rejects scripts the run would never execute.
+func (s *Scraper) checkBrowserScripts() error {
+	if len(s.BrowserScripts) > 0 && !s.needsBrowser() {
+		return fmt.Errorf("browser scripts need the %s renderer or JavaScript rendering", RendererChrome)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBrowserScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		actions int
+		wantErr bool
+	}{
+		{"valid", `
+- domains: [example.com]
+  actions:
+    - type: "#age"
+      text: "1990"
+    - click: "button.load-more"
+      repeat: 5
+    - wait: 2s
+    - wait: "#content"
+`, 4, false},
+		{"no domains", "- actions: [{click: a}]", 0, true},
+		{"two actions in a step", "- domains: [a.com]\n  actions: [{click: a, wait: 1s}]", 0, true},
+		{"empty step", "- domains: [a.com]\n  actions: [{}]", 0, true},
+		{"repeated wait", "- domains: [a.com]\n  actions: [{wait: 1s, repeat: 2}]", 0, true},
+		{"negative repeat", "- domains: [a.com]\n  actions: [{click: a, repeat: -1}]", 0, true},
+		{"text without field", "- domains: [a.com]\n  actions: [{click: a, text: hi}]", 0, true},
+		{"not a list", "domains: [a.com]", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scripts.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			scripts, err := LoadBrowserScripts(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadBrowserScripts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(scripts[0].tasks()) != tt.actions {
+				t.Errorf("got %d tasks, want %d", len(scripts[0].tasks()), tt.actions)
+			}
+		})
+	}
+}
+
+func TestBrowserScriptFor(t *testing.T) {
+	s := NewScraper(false, false)
+	s.BrowserScripts = []*BrowserScript{
+		{Domains: []string{"example.com"}, Actions: []BrowserAction{{Click: "button"}}},
+	}
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"example.com", 1},
+		{"docs.example.com:8080", 1},
+		{"example.org", 0},
+	}
+	for _, tt := range tests {
+		if got := len(s.browserScript(tt.host)); got != tt.want {
+			t.Errorf("browserScript(%q) has %d actions, want %d", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBrowserScripts(t *testing.T) {
+	tests := []struct {
+		renderer string
+		renderJS bool
+		wantErr  bool
+	}{
+		{RendererText, false, true},
+		{RendererText, true, false},
+		{RendererChrome, false, false},
+		{RendererBoth, false, false},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.BrowserScripts = []*BrowserScript{{Domains: []string{"example.com"}}}
+		s.Renderer, s.RenderJS = tt.renderer, tt.renderJS
+		if err := s.checkBrowserScripts(); (err != nil) != tt.wantErr {
+			t.Errorf("checkBrowserScripts() with renderer %s, RenderJS %v error = %v, want error %v", tt.renderer, tt.renderJS, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// metaCharsetPattern matches the character encoding declarations of an
+// HTML document: <meta charset> and <meta http-equiv="Content-Type">.
+var metaCharsetPattern = regexp.MustCompile(`(?i)<meta\s[^>]*charset\s*=[^>]*>`)
+
+// utf8Labels are the names UTF-8 is declared with.
+var utf8Labels = []string{"utf-8", "utf8", "unicode-1-1-utf-8"}
+
+// headerCharset returns the charset parameter of a Content-Type header
+// value, lower case, or "".
+func headerCharset(contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(params["charset"]))
+}
+
+// toUTF8 returns the body of a page served with contentType as UTF-8, and
+// the encoding it was converted from, "" when it was already UTF-8. The
+// encoding is taken from a byte order mark, then the header's charset,
+// then the document's meta tags; undeclared bodies that aren't valid UTF-8
+// are read as Windows-1252, like browsers do. colly already converts bodies
+// whose header names a charset, so those are only relabelled. The meta
+// declarations of converted documents are replaced by UTF-8 ones, so copies
+// of the HTML open right.
+func toUTF8(body []byte, contentType string) ([]byte, string) {
+	if cs := headerCharset(contentType); cs != "" {
+		for _, label := range utf8Labels {
+			if cs == label {
+				return body, ""
+			}
+		}
+		if _, name := charset.Lookup(cs); name != "" && name != "utf-8" {
+			return relabelUTF8(body), name
+		}
+		return body, ""
+	}
+	enc, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" || (name == "windows-1252" && utf8.Valid(body)) {
+		return body, ""
+	}
+	decoded, err := enc.NewDecoder().Bytes(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if err != nil {
+		return body, ""
+	}
+	return relabelUTF8(decoded), name
+}
+
+// relabelUTF8 replaces the charset declarations of an HTML document with
+// a UTF-8 one.
+func relabelUTF8(body []byte) []byte {
+	return metaCharsetPattern.ReplaceAll(body, []byte(`<meta charset="utf-8">`))
+}
@@ -0,0 +1,60 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+		from        string
+	}{
+		{"utf-8", "<p>Café</p>", "text/html", "<p>Café</p>", ""},
+		{"ascii", "<p>Cafe</p>", "text/html", "<p>Cafe</p>", ""},
+		{"meta latin-1", `<meta charset="iso-8859-1"><p>Caf` + "\xe9" + `</p>`, "text/html", `<meta charset="utf-8"><p>Café</p>`, "windows-1252"},
+		{"http-equiv shift_jis", `<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>` + "\x93\xfa\x96\x7b" + `</p>`, "text/html", `<meta charset="utf-8"><p>日本</p>`, "shift_jis"},
+		{"undeclared windows-1252", "<p>\x93quoted\x94</p>", "text/html", "<p>“quoted”</p>", "windows-1252"},
+		{"bom", "\xef\xbb\xbf<p>Café</p>", "text/html", "\xef\xbb\xbf<p>Café</p>", ""},
+		// colly converted the body already
+		{"header charset", `<meta charset="iso-8859-1"><p>Café</p>`, "text/html; charset=ISO-8859-1", `<meta charset="utf-8"><p>Café</p>`, "windows-1252"},
+		{"header utf-8", `<meta charset="utf-8"><p>Café</p>`, "text/html; charset=UTF-8", `<meta charset="utf-8"><p>Café</p>`, ""},
+	}
+	for _, tt := range tests {
+		got, from := toUTF8([]byte(tt.body), tt.contentType)
+		if string(got) != tt.want || from != tt.from {
+			t.Errorf("%s: toUTF8() = %q from %q, want %q from %q", tt.name, got, from, tt.want, tt.from)
+		}
+	}
+}
+
+func TestScrapeLatin1Page(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><meta charset="iso-8859-1"><title>Men`+"\xfa"+`</title></head>
+			<body><p>Caf`+"\xe9"+` cr`+"\xe8"+`me br`+"\xfb"+`l`+"\xe9"+`e</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.NoTOC = true
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	entries := zipEntries(t, zipname)
+	text := pdfText([]byte(entries[strings.TrimPrefix(srv.URL, "http://")+"_index.pdf"]))
+	if !strings.Contains(text, "Café crème brûlée") {
+		t.Errorf("page text = %q, want the accents converted", text)
+	}
+	if !strings.Contains(entries["manifest.json"], `"kind": "charset"`) {
+		t.Errorf("manifest lacks the charset transform:\n%s", entries["manifest.json"])
+	}
+}
@@ -0,0 +1,351 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// checkpointInterval is the least time between two checkpoints. Pages
+// finished after the last one are fetched again on resume.
+const checkpointInterval = 2 * time.Second
+
+// ctxDepthOffset holds, in the context of requests resumed from a
+// checkpoint, how deep their crawl had gone in the interrupted run.
+const ctxDepthOffset = "depthOffset"
+
+// checkpoint is the crawl state saved in the work directory, from which an
+// interrupted run is resumed.
+type checkpoint struct {
+	StartURL string           `json:"start_url"`
+	Pages    []checkpointPage `json:"pages"`
+	Failures []pageFailure    `json:"failures,omitempty"`
+	NavOrder []string         `json:"nav_order,omitempty"`
+	// Done are the URLs that need not be fetched again.
+	Done []string `json:"done"`
+	// Queue are the URLs discovered but not finished, with their depth.
+	Queue []queuedURL `json:"queue"`
+	// Attachments are the documents linked from the pages so far, which
+	// are downloaded at the end of the crawl.
+	Attachments []attachment `json:"attachments,omitempty"`
+	// Unchanged are the pages found unchanged since the Baseline.
+	Unchanged []string `json:"unchanged,omitempty"`
+}
+
+// checkpointPage is a page whose PDF was written before the checkpoint.
+type checkpointPage struct {
+	URL          string            `json:"url"`
+	RequestedURL string            `json:"requested_url,omitempty"`
+	Status       int               `json:"status"`
+	FetchedAt    time.Time         `json:"fetched_at"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Title        string            `json:"title,omitempty"`
+	Canonical    string            `json:"canonical,omitempty"`
+	Depth        int               `json:"depth"`
+	Parts        []string          `json:"parts,omitempty"`
+	Variant      string            `json:"variant,omitempty"`
+	Text         string            `json:"text,omitempty"`
+	File         string            `json:"file"`
+	ChromeFile   string            `json:"chrome_file,omitempty"`
+	Snapshot     string            `json:"snapshot,omitempty"`
+	Thumb        []byte            `json:"thumb,omitempty"`
+	// Streamed pages are in the ZIP of the work directory, not in files.
+	Streamed   bool        `json:"streamed,omitempty"`
+	Transforms []transform `json:"transforms,omitempty"`
+	SHA256     string      `json:"sha256,omitempty"`
+	Change     string      `json:"change,omitempty"`
+	Published  time.Time   `json:"published"`
+	Author     string      `json:"author,omitempty"`
+	// Fallback is set when the page's renderer failed on it.
+	Fallback *renderFallback `json:"fallback,omitempty"`
+}
+
+type queuedURL struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// crawlState tracks what a checkpoint needs to record.
+type crawlState struct {
+	dir   string
+	start string          // URL the crawl started from
+	queue map[string]int  // map[url]depth of links not yet finished
+	done  map[string]bool // URLs finished, in this run or a resumed one
+	saved time.Time       // last checkpoint
+}
+
+// workDirName returns the directory the PDFs and the checkpoint of a run
+// writing outputPath are kept in until the archive is complete.
+func workDirName(outputPath string) string {
+	return outputPath + ".partial"
+}
+
+// checkpointFile returns the path of the checkpoint in dir.
+func checkpointFile(dir string) string {
+	return filepath.Join(dir, "state.json")
+}
+
+// openWorkDir prepares the work directory of outputPath. With Resume the
+// checkpoint of an interrupted run is loaded from it and the queued URLs to
+// fetch are returned; otherwise any leftover state is discarded.
+func (s *Scraper) openWorkDir(outputPath, startURL string) ([]queuedURL, error) {
+	dir := workDirName(outputPath)
+	s.state = &crawlState{
+		dir:   dir,
+		start: startURL,
+		queue: make(map[string]int),
+		done:  make(map[string]bool),
+	}
+
+	if !s.Resume {
+		if _, err := os.Stat(dir); err == nil {
+			s.logf("Discarding the state of an interrupted run in %s\n", dir)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to clear work directory: %w", err)
+		}
+		return nil, os.MkdirAll(dir, 0755)
+	}
+
+	data, err := os.ReadFile(checkpointFile(dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		s.logf("No interrupted run to resume, starting over\n")
+		s.Resume = false
+		return nil, os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", checkpointFile(dir), err)
+	}
+	if cp.StartURL != startURL {
+		return nil, fmt.Errorf("the interrupted run started from %s, not %s", cp.StartURL, startURL)
+	}
+
+	for _, cpp := range cp.Pages {
+		u, err := url.Parse(cpp.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %q in checkpoint: %w", cpp.URL, err)
+		}
+		s.pdfs = append(s.pdfs, &page{
+			URL:          u,
+			RequestedURL: cpp.RequestedURL,
+			Status:       cpp.Status,
+			FetchedAt:    cpp.FetchedAt,
+			Header:       restoreHeaders(cpp.Headers),
+			Title:        cpp.Title,
+			Canonical:    cpp.Canonical,
+			Depth:        cpp.Depth,
+			Parts:        cpp.Parts,
+			Variant:      cpp.Variant,
+			Text:         cpp.Text,
+			File:         cpp.File,
+			ChromeFile:   cpp.ChromeFile,
+			SnapshotFile: cpp.Snapshot,
+			Thumb:        cpp.Thumb,
+			Streamed:     cpp.Streamed,
+			Transforms:   cpp.Transforms,
+			SHA256:       cpp.SHA256,
+			Change:       cpp.Change,
+			Published:    cpp.Published,
+			Author:       cpp.Author,
+			Fallback:     cpp.Fallback,
+		})
+		s.recordAuthor(s.pdfs[len(s.pdfs)-1])
+	}
+	s.failures = cp.Failures
+	s.navOrder = cp.NavOrder
+	s.unchanged = cp.Unchanged
+	for _, a := range cp.Attachments {
+		for _, from := range a.LinkedFrom {
+			u, err1 := url.Parse(a.URL)
+			source, err2 := url.Parse(from)
+			if err1 == nil && err2 == nil {
+				s.recordAttachment(u, source)
+			}
+		}
+	}
+	// Pages already archived are not archived again, but those whose links
+	// were not all queued are fetched again to find them
+	for _, p := range s.pdfs {
+		s.visited.Store(stripFragment(p.URL).String(), true)
+	}
+	for _, u := range cp.Done {
+		s.state.done[u] = true
+		s.visited.Store(u, true)
+	}
+	for _, q := range cp.Queue {
+		s.state.queue[q.URL] = q.Depth
+	}
+	s.logf("Resuming: %d pages archived, %d URLs left in the queue\n", len(s.pdfs), len(cp.Queue))
+	return cp.Queue, nil
+}
+
+// resumeQueue visits the URLs left in the queue of the interrupted run.
+// Their context carries the depth they were found at, so MaxDepth still
+// counts from the start page.
+func (s *Scraper) resumeQueue(c *colly.Collector, queue []queuedURL) {
+	for _, q := range queue {
+		ctx := colly.NewContext()
+		ctx.Put(ctxDepthOffset, strconv.Itoa(q.Depth-1))
+		// Errors are links colly refuses, as on a first run
+		_ = c.Request("GET", q.URL, nil, ctx, nil)
+	}
+}
+
+// depth returns how many links away from the start page r is, including the
+// depth reached before a resume.
+func depth(r *colly.Request) int {
+	offset, _ := strconv.Atoi(r.Ctx.Get(ctxDepthOffset))
+	return r.Depth + offset
+}
+
+// resumedDone reports whether u was finished by the interrupted run.
+func (s *Scraper) resumedDone(u *url.URL) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.done[u.String()]
+}
+
+// enqueue records a link about to be visited.
+func (s *Scraper) enqueue(u string, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.state.done[u] {
+		if _, queued := s.state.queue[u]; !queued {
+			s.state.queue[u] = depth
+		}
+	}
+}
+
+// dequeue forgets a link the collector refused to visit.
+func (s *Scraper) dequeue(u string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.state.queue, u)
+}
+
+// finish records that u was fetched and processed and its links queued, and
+// writes a checkpoint if the last one is old enough.
+func (s *Scraper) finish(u string) {
+	s.mu.Lock()
+	delete(s.state.queue, u)
+	s.state.done[u] = true
+	due := time.Since(s.state.saved) >= checkpointInterval
+	s.mu.Unlock()
+	if due {
+		s.saveCheckpoint()
+	}
+}
+
+// checkpointHeaders returns the response headers kept for a page in the
+// checkpoint: those of the manifest, and Content-Language for EPUBs, masked
+// like in the manifest. Cookies and other headers are left out.
+func (s *Scraper) checkpointHeaders(h http.Header) map[string]string {
+	clean := s.redactHeader(h)
+	captured := captureHeaders(clean)
+	if lang := clean.Get("Content-Language"); lang != "" {
+		if captured == nil {
+			captured = make(map[string]string)
+		}
+		captured["Content-Language"] = lang
+	}
+	return captured
+}
+
+// restoreHeaders turns headers kept by checkpointHeaders back into a header.
+func restoreHeaders(headers map[string]string) http.Header {
+	h := make(http.Header, len(headers))
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+	return h
+}
+
+// saveCheckpoint writes the crawl state to the work directory. Pages
+// waiting for cross-page analysis have no PDF yet, so they are saved as
+// queued.
+func (s *Scraper) saveCheckpoint() {
+	s.mu.Lock()
+	cp := checkpoint{
+		StartURL:  s.state.start,
+		Failures:  s.failures,
+		NavOrder:  s.navOrder,
+		Unchanged: s.unchanged,
+		Done:      []string{},
+		Queue:     []queuedURL{},
+	}
+	pending := make(map[string]int, len(s.pending))
+	for _, p := range s.pending {
+		pending[p.URL.String()] = p.Depth
+	}
+	for _, p := range s.pdfs {
+		cp.Pages = append(cp.Pages, checkpointPage{
+			URL:          p.URL.String(),
+			RequestedURL: p.RequestedURL,
+			Status:       p.Status,
+			FetchedAt:    p.FetchedAt,
+			Headers:      s.checkpointHeaders(p.Header),
+			Title:        p.Title,
+			Canonical:    p.Canonical,
+			Depth:        p.Depth,
+			Parts:        p.Parts,
+			Variant:      p.Variant,
+			Text:         p.Text,
+			File:         p.File,
+			ChromeFile:   p.ChromeFile,
+			Snapshot:     p.SnapshotFile,
+			Thumb:        p.Thumb,
+			Streamed:     p.Streamed,
+			Transforms:   p.Transforms,
+			SHA256:       p.SHA256,
+			Change:       p.Change,
+			Published:    p.Published,
+			Author:       p.Author,
+			Fallback:     p.Fallback,
+		})
+	}
+	for _, u := range s.attachmentOrder {
+		a := s.attachments[u]
+		cp.Attachments = append(cp.Attachments, attachment{URL: a.URL, LinkedFrom: a.LinkedFrom})
+	}
+	for u := range s.state.done {
+		if _, ok := pending[u]; !ok {
+			cp.Done = append(cp.Done, u)
+		}
+	}
+	for u, d := range s.state.queue {
+		pending[u] = d
+	}
+	for u, d := range pending {
+		cp.Queue = append(cp.Queue, queuedURL{URL: u, Depth: d})
+	}
+	s.state.saved = time.Now()
+	dir := s.state.dir
+	s.mu.Unlock()
+
+	sort.Strings(cp.Done)
+	sort.Slice(cp.Queue, func(i, j int) bool { return cp.Queue[i].URL < cp.Queue[j].URL })
+	data, err := json.Marshal(cp)
+	if err == nil {
+		tmp := checkpointFile(dir) + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, checkpointFile(dir))
+		}
+	}
+	if err != nil {
+		s.logf("Warning: failed to save checkpoint: %v\n", err)
+	}
+}
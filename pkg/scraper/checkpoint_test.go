@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "example.com.zip")
+	start := "https://example.com/"
+
+	s := NewScraper(true, false)
+	if _, err := s.openWorkDir(output, start); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/a")
+	header := http.Header{
+		"Content-Type":     {"text/html"},
+		"Content-Language": {"de"},
+		"Set-Cookie":       {"sid=cookie-secret; Path=/"},
+	}
+	s.pdfs = []*page{{URL: u, Status: 200, Header: header, Title: "A", Depth: 2, Text: "text of a", File: filepath.Join(s.state.dir, entryName(u))}}
+	pending, _ := url.Parse("https://example.com/c")
+	s.pending = []*page{{URL: pending, Depth: 3}}
+	s.enqueue(start, 1)
+	s.enqueue("https://example.com/a", 2)
+	s.enqueue("https://example.com/b", 2)
+	s.enqueue("https://example.com/c", 3)
+	s.finish("https://example.com/a")
+	s.finish("https://example.com/c")
+	s.saveCheckpoint()
+	state, err := os.ReadFile(checkpointFile(s.state.dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(state), "cookie-secret") {
+		t.Error("Set-Cookie value saved in the checkpoint")
+	}
+
+	resumed := NewScraper(true, false)
+	resumed.Resume = true
+	queue, err := resumed.openWorkDir(output, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Resume {
+		t.Fatal("checkpoint not found")
+	}
+
+	if len(resumed.pdfs) != 1 || resumed.pdfs[0].Title != "A" || resumed.pdfs[0].Text != "text of a" {
+		t.Fatalf("restored pages %+v", resumed.pdfs)
+	}
+	if h := resumed.pdfs[0].Header; h.Get("Content-Type") != "text/html" || h.Get("Content-Language") != "de" {
+		t.Errorf("restored headers %v", h)
+	}
+	want := map[string]int{start: 1, "https://example.com/b": 2, "https://example.com/c": 3}
+	if len(queue) != len(want) {
+		t.Errorf("queue = %v, want %v", queue, want)
+	}
+	for _, q := range queue {
+		if want[q.URL] != q.Depth {
+			t.Errorf("queued %s at depth %d, want %d", q.URL, q.Depth, want[q.URL])
+		}
+	}
+	if !resumed.resumedDone(u) {
+		t.Error("finished page not restored as done")
+	}
+	if resumed.resumedDone(pending) {
+		t.Error("page waiting to be rendered restored as done")
+	}
+	if _, ok := resumed.visited.Load("https://example.com/a"); !ok {
+		t.Error("archived page would be archived again")
+	}
+
+	// A run without --resume starts over
+	fresh := NewScraper(true, false)
+	if _, err := fresh.openWorkDir(output, start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(checkpointFile(fresh.state.dir)); !os.IsNotExist(err) {
+		t.Error("checkpoint kept by a fresh run")
+	}
+
+	other := NewScraper(true, false)
+	other.Resume = true
+	if _, err := other.openWorkDir(output, start); err != nil {
+		t.Fatal(err)
+	}
+	other.saveCheckpoint()
+	mismatch := NewScraper(true, false)
+	mismatch.Resume = true
+	if _, err := mismatch.openWorkDir(output, "https://example.org/"); err == nil {
+		t.Error("resumed a run with a different start URL")
+	}
+}
+
+func TestDepth(t *testing.T) {
+	ctx := colly.NewContext()
+	r := &colly.Request{Depth: 2, Ctx: ctx}
+	if got := depth(r); got != 2 {
+		t.Errorf("depth() = %d, want 2", got)
+	}
+	ctx.Put(ctxDepthOffset, "3")
+	if got := depth(r); got != 5 {
+		t.Errorf("depth() with offset = %d, want 5", got)
+	}
+}
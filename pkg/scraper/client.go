@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"net/http"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// WithHTTPClient sends the requests of the crawl through client, so an
+// application embedding the scraper can bring its own authentication,
+// tracing or proxy in client's Transport. The transports the scraper adds
+// for its own settings, such as MaxBandwidth or Adaptive, wrap client's
+// Transport. client's Jar holds the cookies of the crawl, and its Timeout,
+// if any, replaces RequestTimeout; its CheckRedirect isn't used. Pages
+// rendered by the browser are still fetched by the browser. It returns s.
+func (s *Scraper) WithHTTPClient(client *http.Client) *Scraper {
+	s.client = client
+	return s
+}
+
+// baseTransport returns the transport the scraper's own transports wrap:
+// that of the client given to WithHTTPClient, or http.DefaultTransport.
+func (s *Scraper) baseTransport() http.RoundTripper {
+	if s.client != nil && s.client.Transport != nil {
+		return s.client.Transport
+	}
+	return http.DefaultTransport
+}
+
+// useClient sets the cookie jar and timeout of the client given to
+// WithHTTPClient on c.
+func (s *Scraper) useClient(c *colly.Collector) {
+	if s.client == nil {
+		return
+	}
+	if s.client.Jar != nil {
+		c.SetCookieJar(s.client.Jar)
+	}
+	if s.client.Timeout > 0 {
+		c.SetRequestTimeout(s.client.Timeout)
+	}
+}
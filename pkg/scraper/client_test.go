@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// authTransport signs every request it passes to http.DefaultTransport.
+type authTransport struct {
+	sent atomic.Int64
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer secret")
+	t.sent.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if c, err := r.Cookie("tenant"); err != nil || c.Value != "acme" {
+			http.Error(w, "no tenant", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p><a href="/a">A</a> <a href="/b">B</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, _ := url.Parse(srv.URL + "/")
+	jar.SetCookies(start, []*http.Cookie{{Name: "tenant", Value: "acme", Path: "/"}})
+	transport := &authTransport{}
+	s := New(Options{StripHTML: true}).WithHTTPClient(&http.Client{Transport: transport, Jar: jar})
+	s.Log = io.Discard
+	// Wraps the client's transport
+	s.MaxBandwidth = 1 << 20
+	if err := s.ScrapeAndSave(start.String(), filepath.Join(t.TempDir(), "site.zip")); err != nil {
+		t.Fatal(err)
+	}
+	if pages, failures := s.Stats(); pages != 3 || failures != 0 {
+		t.Errorf("%d pages archived, %d failed, want 3 and none", pages, failures)
+	}
+	if n := transport.sent.Load(); n != 3 {
+		t.Errorf("%d requests sent through the client, want 3", n)
+	}
+}
@@ -0,0 +1,156 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+	"golang.org/x/net/html"
+)
+
+// Decisions recorded in the compliance report.
+const (
+	decisionArchived = "archived"
+	decisionSkipped  = "skipped"
+)
+
+// complianceRecord is the policy outcome for a single URL.
+type complianceRecord struct {
+	URL           string   `json:"url"`
+	RobotsAllowed bool     `json:"robots_txt_allowed"`
+	MetaRobots    []string `json:"meta_robots,omitempty"`
+	Decision      string   `json:"decision"`
+	Reason        string   `json:"reason,omitempty"`
+}
+
+// complianceReport is written to compliance.json in the archive.
+type complianceReport struct {
+	GeneratedAt   time.Time          `json:"generated_at"`
+	UserAgent     string             `json:"user_agent"`
+	RespectRobots bool               `json:"respect_robots"`
+	Pages         []complianceRecord `json:"pages"`
+}
+
+// robotsChecker fetches and caches robots.txt per host.
+type robotsChecker struct {
+	mu     sync.Mutex
+	client *http.Client
+	hosts  map[string]*robotstxt.RobotsData
+}
+
+func newRobotsChecker() *robotsChecker {
+	return &robotsChecker{
+		client: &http.Client{Timeout: 5 * time.Second},
+		hosts:  make(map[string]*robotstxt.RobotsData),
+	}
+}
+
+// allowed reports whether robots.txt on the URL's host permits agent to
+// fetch it. Hosts whose robots.txt cannot be retrieved are treated as allowing
+// everything, matching how crawlers handle a missing file.
+func (rc *robotsChecker) allowed(u *url.URL, agent string) bool {
+	rc.mu.Lock()
+	robots, ok := rc.hosts[u.Host]
+	rc.mu.Unlock()
+
+	if !ok {
+		resp, err := rc.client.Get(u.Scheme + "://" + u.Host + "/robots.txt")
+		if err == nil {
+			robots, err = robotstxt.FromResponse(resp)
+			resp.Body.Close()
+		}
+		if err != nil {
+			robots = nil
+		}
+		rc.mu.Lock()
+		rc.hosts[u.Host] = robots
+		rc.mu.Unlock()
+	}
+
+	if robots == nil {
+		return true
+	}
+	group := robots.FindGroup(agent)
+	if group == nil {
+		return true
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return group.Test(path)
+}
+
+// metaRobots returns the lower-cased directives of the page's
+// <meta name="robots"> tags.
+func metaRobots(body []byte) []string {
+	var directives []string
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return directives
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data == "body" {
+				return directives
+			}
+			if t.Data != "meta" {
+				continue
+			}
+			var name, content string
+			for _, a := range t.Attr {
+				switch a.Key {
+				case "name":
+					name = strings.ToLower(strings.TrimSpace(a.Val))
+				case "content":
+					content = a.Val
+				}
+			}
+			if name != "robots" {
+				continue
+			}
+			for _, d := range strings.Split(content, ",") {
+				if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+					directives = append(directives, d)
+				}
+			}
+		}
+	}
+}
+
+// hasDirective reports whether directives contains any of names.
+func hasDirective(directives []string, names ...string) bool {
+	for _, d := range directives {
+		for _, n := range names {
+			if d == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Scraper) recordCompliance(rec complianceRecord) {
+	rec.URL = s.Redact(rec.URL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compliance = append(s.compliance, rec)
+}
+
+func (s *Scraper) complianceJSON(userAgent string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.MarshalIndent(complianceReport{
+		GeneratedAt:   time.Now().UTC(),
+		UserAgent:     userAgent,
+		RespectRobots: s.RespectRobots,
+		Pages:         s.compliance,
+	}, "", "  ")
+}
@@ -0,0 +1,38 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetaRobots(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "no meta robots",
+			html: `<html><head><title>x</title></head><body></body></html>`,
+			want: nil,
+		},
+		{
+			name: "multiple directives",
+			html: `<html><head><meta name="Robots" content="NoIndex, nofollow ,noarchive"></head></html>`,
+			want: []string{"noindex", "nofollow", "noarchive"},
+		},
+		{
+			name: "other meta tags ignored",
+			html: `<html><head><meta name="description" content="nofollow"><meta name="robots" content="none"></head></html>`,
+			want: []string{"none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metaRobots([]byte(tt.html)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metaRobots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
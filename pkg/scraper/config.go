@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds settings read from the YAML config file.
+type Config struct {
+	// Skip lists skip rules, see ParseSkipRule.
+	Skip []string `yaml:"skip"`
+	// Filter is a page filter expression, see ParseFilter.
+	Filter string `yaml:"filter"`
+	// Render lists render settings overrides for URL patterns.
+	Render []*RenderOverride `yaml:"render"`
+}
+
+// LoadConfig reads the config file at path. A missing file is only an
+// error when required is set, so the default location is optional.
+func LoadConfig(path string, required bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// SkipRules parses the config's skip rules.
+func (c *Config) SkipRules() ([]SkipRule, error) {
+	rules := make([]SkipRule, 0, len(c.Skip))
+	for _, expr := range c.Skip {
+		r, err := ParseSkipRule(expr)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// RenderOverrides checks the config's render overrides and returns them.
+func (c *Config) RenderOverrides() ([]*RenderOverride, error) {
+	for _, o := range c.Render {
+		if err := o.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Render, nil
+}
+
+// DefaultConfigPath returns the config file read when --config is not set.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scrapdf", "config.yaml")
+}
@@ -0,0 +1,60 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	data := "skip:\n  - header X-Robots-Tag contains noarchive\n  - meta og:type == product\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := cfg.SkipRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[1].Source != "meta" || rules[1].Name != "og:type" || rules[1].Value != "product" {
+		t.Errorf("SkipRules() = %+v", rules)
+	}
+
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := LoadConfig(missing, false); err != nil {
+		t.Errorf("optional missing config: %v", err)
+	}
+	if _, err := LoadConfig(missing, true); err == nil {
+		t.Error("required missing config was accepted")
+	}
+}
+
+func TestConfigRenderOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "render:\n  - match: /diagrams/*\n    orientation: landscape\n  - match: /app/*\n    renderer: chrome\n    render_js: true\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overrides, err := cfg.RenderOverrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides) != 2 || overrides[0].Orientation != OrientationLandscape || overrides[1].RenderJS == nil || !*overrides[1].RenderJS {
+		t.Errorf("RenderOverrides() = %+v", overrides)
+	}
+
+	cfg.Render[0].Orientation = "sideways"
+	if _, err := cfg.RenderOverrides(); err == nil {
+		t.Error("unknown orientation was accepted")
+	}
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactBlock replaces every character of redacted content.
+const redactBlock = "█"
+
+// ParseRedactRules compiles the --redact regular expressions.
+func ParseRedactRules(exprs []string) ([]*regexp.Regexp, error) {
+	rules := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule %q: %w", expr, err)
+		}
+		rules = append(rules, re)
+	}
+	return rules, nil
+}
+
+// redactContent blacks out the matches of the RedactContent rules in text,
+// keeping their length so the layout of the page is preserved.
+func (s *Scraper) redactContent(text string) string {
+	for _, re := range s.RedactContent {
+		text = re.ReplaceAllStringFunc(text, blackOut)
+	}
+	return text
+}
+
+// redactPage redacts the page's text and title, recording the matches of
+// every rule in its audit trail.
+func (s *Scraper) redactPage(p *page) {
+	for _, re := range s.RedactContent {
+		matches := 0
+		redact := func(m string) string {
+			matches++
+			return blackOut(m)
+		}
+		p.Text = re.ReplaceAllStringFunc(p.Text, redact)
+		p.Title = re.ReplaceAllStringFunc(p.Title, redact)
+		if matches > 0 {
+			p.audit(transform{Kind: transformRedact, Rule: re.String(), Matches: matches})
+		}
+	}
+}
+
+// blackOut replaces every character of m with a redactBlock.
+func blackOut(m string) string {
+	return strings.Repeat(redactBlock, utf8.RuneCountInString(m))
+}
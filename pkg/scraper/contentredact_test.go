@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactContent(t *testing.T) {
+	rules, err := ParseRedactRules([]string{`[\w.+-]+@[\w-]+\.[\w.]+`, `sk_live_\w+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(true, false)
+	s.RedactContent = rules
+
+	got := s.redactContent("Mail jo@example.com, key sk_live_abc123 or José.")
+	want := "Mail ██████████████, key ██████████████ or José."
+	if got != want {
+		t.Errorf("redactContent() = %q, want %q", got, want)
+	}
+
+	if _, err := ParseRedactRules([]string{"("}); err == nil {
+		t.Error("ParseRedactRules accepted an invalid expression")
+	}
+}
+
+func TestRedactContentRawCopies(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*Scraper)
+	}{
+		{"self-contained copies", func(s *Scraper) { s.SelfContained = true }},
+		{"evidence", func(s *Scraper) { s.Evidence = true }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper(true, false)
+			s.RedactContent = []*regexp.Regexp{regexp.MustCompile(`secret`)}
+			tt.setup(s)
+			err := s.ScrapeAndSave("http://127.0.0.1:1/", filepath.Join(t.TempDir(), "out.zip"))
+			if err == nil || !strings.Contains(err.Error(), "redaction") {
+				t.Errorf("ScrapeAndSave() with redaction = %v", err)
+			}
+		})
+	}
+}
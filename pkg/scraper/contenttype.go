@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// pageTypes are the media types always converted to pages.
+var pageTypes = []string{"text/html", "application/xhtml+xml"}
+
+// mediaType returns the lower case media type of a Content-Type header
+// value, without its parameters.
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// checkAcceptTypes rejects AcceptTypes that aren't media types.
+func (s *Scraper) checkAcceptTypes() error {
+	for _, t := range s.AcceptTypes {
+		major, minor, ok := strings.Cut(t, "/")
+		if !ok || major == "" || minor == "" || major == "*" || strings.ContainsAny(t, "; ") {
+			return fmt.Errorf("invalid accepted type %q, expected a media type such as text/plain or text/*", t)
+		}
+	}
+	return nil
+}
+
+// acceptsType reports whether responses of the media type mt are converted
+// to pages: HTML, and the AcceptTypes, where text/* matches every text
+// type.
+func (s *Scraper) acceptsType(mt string) bool {
+	if slices.Contains(pageTypes, mt) {
+		return true
+	}
+	for _, t := range s.AcceptTypes {
+		t = strings.ToLower(t)
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseType returns the media type of a response, sniffed from its body
+// when it has no Content-Type header.
+func responseType(h http.Header, body []byte) string {
+	if ct := h.Get("Content-Type"); ct != "" {
+		return mediaType(ct)
+	}
+	return mediaType(http.DetectContentType(body))
+}
+
+// skipType reports whether a response of the media type mt is skipped as
+// not a page, logging it.
+func (s *Scraper) skipType(u *url.URL, mt string) bool {
+	if s.acceptsType(mt) {
+		return false
+	}
+	reason := fmt.Sprintf("%s isn't a page", mt)
+	s.logf("Skipping %s: %s\n", u, reason)
+	s.event(event{Type: eventSkip, URL: u.String(), Reason: reason})
+	return true
+}
+
+// isHTML reports whether mt is an HTML media type.
+func isHTML(mt string) bool {
+	return slices.Contains(pageTypes, mt)
+}
+
+// wrapText turns the body of a page that isn't HTML, such as text/plain,
+// into an HTML document keeping its lines, so it is extracted and rendered
+// like any page.
+func wrapText(body []byte) []byte {
+	return []byte("<html><body><pre>" + html.EscapeString(string(body)) + "</pre></body></html>")
+}
@@ -0,0 +1,110 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsType(t *testing.T) {
+	tests := []struct {
+		accept []string
+		mt     string
+		want   bool
+	}{
+		{nil, "text/html", true},
+		{nil, "application/xhtml+xml", true},
+		{nil, "text/plain", false},
+		{nil, "image/png", false},
+		{[]string{"text/plain"}, "text/plain", true},
+		{[]string{"TEXT/Plain"}, "text/plain", true},
+		{[]string{"text/*"}, "text/markdown", true},
+		{[]string{"text/*"}, "application/pdf", false},
+	}
+	for _, tt := range tests {
+		s := &Scraper{AcceptTypes: tt.accept}
+		if got := s.acceptsType(tt.mt); got != tt.want {
+			t.Errorf("acceptsType(%q) with %q = %v, want %v", tt.mt, tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAcceptTypes(t *testing.T) {
+	for _, tt := range []struct {
+		accept  string
+		wantErr bool
+	}{
+		{"text/plain", false},
+		{"text/*", false},
+		{"text", true},
+		{"*/*", true},
+		{"text/plain; charset=utf-8", true},
+	} {
+		s := &Scraper{AcceptTypes: []string{tt.accept}}
+		if err := s.checkAcceptTypes(); (err != nil) != tt.wantErr {
+			t.Errorf("checkAcceptTypes(%q) error = %v, wantErr %v", tt.accept, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSkipNonPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<html><body><p>Home</p>
+				<a href="/logo.png">Logo</a> <a href="/style.css">CSS</a>
+				<a href="/notes.txt">Notes</a> <a href="/untyped">Untyped</a></body></html>`)
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		case "/style.css":
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, "body { color: red }")
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "First <line>\nSecond line")
+		case "/untyped":
+			// Sniffed as HTML
+			w.Header()["Content-Type"] = nil
+			fmt.Fprint(w, "<html><body><p>Untyped</p></body></html>")
+		}
+	}))
+	defer srv.Close()
+
+	for _, tt := range []struct {
+		accept []string
+		want   []string
+	}{
+		{nil, []string{"index.pdf", "untyped.pdf"}},
+		{[]string{"text/plain"}, []string{"index.pdf", "notes.txt.pdf", "untyped.pdf"}},
+	} {
+		s := NewScraper(true, false)
+		s.AcceptTypes = tt.accept
+		zipname := filepath.Join(t.TempDir(), "site.zip")
+		if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+			t.Fatal(err)
+		}
+		entries := zipEntries(t, zipname)
+		var got []string
+		for name := range entries {
+			if strings.HasSuffix(name, ".pdf") && !strings.HasPrefix(name, "_") {
+				got = append(got, strings.TrimPrefix(name, strings.TrimPrefix(srv.URL, "http://")+"_"))
+			}
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("accept %q: archived %q, want %q", tt.accept, got, tt.want)
+		}
+		if tt.accept != nil {
+			text := pdfText([]byte(entries[strings.TrimPrefix(srv.URL, "http://")+"_notes.txt.pdf"]))
+			if !strings.Contains(text, "First <line>") || !strings.Contains(text, "Second line") {
+				t.Errorf("text page rendered as %q", text)
+			}
+		}
+	}
+}
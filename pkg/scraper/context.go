@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrInterrupted is returned when the context of ScrapeAndSaveContext is
+// done before the crawl is.
+var ErrInterrupted = errors.New("crawl interrupted")
+
+// ScrapeAndSaveContext is ScrapeAndSave stopped when ctx is done: no more
+// pages are fetched and the requests in flight are cancelled, then the
+// output is written with the pages converted so far and marked as
+// interrupted in its manifest. The error returned then wraps both
+// ErrInterrupted and ctx.Err(), even though the output was written, unless
+// no page was converted at all.
+func (s *Scraper) ScrapeAndSaveContext(ctx context.Context, startURL string, outputPath string) error {
+	s.ctx = ctx
+	s.interrupted = false
+	defer func() { s.ctx = nil }()
+	if err := s.ScrapeAndSave(startURL, outputPath); err != nil {
+		return err
+	}
+	if s.interrupted {
+		return fmt.Errorf("%w: %w", ErrInterrupted, ctx.Err())
+	}
+	return nil
+}
+
+// cancelled returns the error of the context of the crawl, nil while it
+// runs or when it has none.
+func (s *Scraper) cancelled() error {
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.Err()
+}
+
+// crawlContext returns the context of the crawl, or a context that is
+// never done when it has none, for the work done for its pages.
+func (s *Scraper) crawlContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// wait sleeps for d, and reports whether it did or the crawl was cancelled
+// first.
+func (s *Scraper) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.crawlContext().Done():
+		return false
+	}
+}
+
+// contextTransport cancels the requests passed to base when ctx is done.
+type contextTransport struct {
+	base http.RoundTripper
+	ctx  context.Context
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(mergedContext{t.ctx, req.Context()}))
+}
+
+// mergedContext is cancelled with its Context and holds the values of
+// both, such as those colly sets on its requests.
+type mergedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c mergedContext) Value(key any) any {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
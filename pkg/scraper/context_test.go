@@ -0,0 +1,105 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestScrapeAndSaveContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Every page links to the next, and the third never answers
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/p%d", &n)
+		if n == 2 {
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><p>Page %d</p><a href="/p%d">next</a></body></html>`, n, n+1)
+	}))
+	defer srv.Close()
+
+	s := New(Options{StripHTML: true})
+	s.MaxDepth = 0
+	s.Log = io.Discard
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	done := make(chan error)
+	go func() { done <- s.ScrapeAndSaveContext(ctx, srv.URL+"/", zipname) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.Canceled) {
+			t.Errorf("ScrapeAndSaveContext() = %v, want it interrupted", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the crawl didn't stop when cancelled")
+	}
+	if pages, _ := s.Stats(); pages != 2 {
+		t.Errorf("%d pages converted before the interruption, want 2", pages)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(zipEntries(t, zipname)["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Interrupted {
+		t.Error("the manifest doesn't tell the crawl was interrupted")
+	}
+	if len(m.Pages) != 2 {
+		t.Errorf("%d pages archived, want the 2 converted", len(m.Pages))
+	}
+}
+
+func TestScrapeAndSaveContextBeforeAnyPage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSaveContext(ctx, srv.URL+"/", zipname); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("ScrapeAndSaveContext() = %v, want it interrupted", err)
+	}
+	if _, err := os.Stat(zipname); err == nil {
+		t.Error("an archive was written without any page")
+	}
+}
+
+func TestCancelledCrawlStopsWaiting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New(Options{StripHTML: true})
+	s.ctx = ctx
+	if !s.wait(time.Millisecond) {
+		t.Error("wait() stopped while the crawl runs")
+	}
+	cancel()
+	start := time.Now()
+	if s.wait(time.Minute) || time.Since(start) > time.Second {
+		t.Error("wait() kept sleeping after the crawl was cancelled")
+	}
+
+	// Page commands are killed with the crawl
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if _, err := s.pipe("sleep 5", &page{URL: &url.URL{Scheme: "https", Host: "example.com"}}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("pipe() = %v, want it stopped", err)
+	}
+}
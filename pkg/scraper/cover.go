@@ -0,0 +1,208 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+// coverEntryName is the archive entry of the cover, the first of the
+// archive.
+const coverEntryName = "_cover.pdf"
+
+// coverData is what a cover template is executed with.
+type coverData struct {
+	Site      string // host of the start URL
+	StartURL  string
+	Logo      string // absolute URL of the site's logo, if the start page shows one
+	Generated string // date and time, in the Locale and TimeZone
+	Pages     int
+	Failures  int
+	Version   string // documentation version, see DocsVersion
+	Meta      map[string]string
+	Entries   []coverEntry
+}
+
+// coverEntry is an archived page listed on the cover.
+type coverEntry struct {
+	Number int
+	Title  string
+	URL    string
+	File   string
+}
+
+// checkCover parses the CoverTemplate and rejects formats the PDF cover
+// can't be combined with.
+func (s *Scraper) checkCover() error {
+	if s.CoverTemplate == "" {
+		return nil
+	}
+	if s.Format != "" && s.Format != FormatPDF {
+		return fmt.Errorf("the archive cover is a PDF, it can't be combined with the %s format", s.Format)
+	}
+	data, err := os.ReadFile(s.CoverTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to read cover template: %w", err)
+	}
+	s.cover, err = template.New("cover").Funcs(template.FuncMap{
+		"number": s.formatNumber,
+	}).Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid cover template %s: %w", s.CoverTemplate, err)
+	}
+	return nil
+}
+
+// recordLogo keeps the logo of the first page crawled for the cover.
+func (s *Scraper) recordLogo(body []byte, pageURL *url.URL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.logo == "" {
+		s.logo = siteLogo(body, pageURL)
+	}
+}
+
+// siteLogo returns the absolute URL of the logo of a page: an image whose
+// src, alt, class or id mentions a logo, else its Apple touch icon, else
+// its Open Graph image. Favicons are left out, they are mostly ICO files.
+func siteLogo(body []byte, pageURL *url.URL) string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var logo, touchIcon, ogImage string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "img":
+				if logo != "" {
+					break
+				}
+				for _, a := range n.Attr {
+					switch a.Key {
+					case "src", "alt", "class", "id":
+						if strings.Contains(strings.ToLower(a.Val), "logo") {
+							logo = imageSource(n)
+						}
+					}
+				}
+			case "link":
+				if touchIcon == "" && strings.Contains(strings.ToLower(getAttr(n, "rel")), "apple-touch-icon") {
+					touchIcon = getAttr(n, "href")
+				}
+			case "meta":
+				if ogImage == "" && getAttr(n, "property") == "og:image" {
+					ogImage = getAttr(n, "content")
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	for _, src := range []string{logo, touchIcon, ogImage} {
+		if src == "" {
+			continue
+		}
+		if u, err := pageURL.Parse(src); err == nil {
+			return u.String()
+		}
+	}
+	return ""
+}
+
+// coverPage executes the cover template for the archive and lays it out
+// like a stripped page, images included.
+func (s *Scraper) coverPage(startURL string) (*page, error) {
+	start, err := url.Parse(startURL)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	data := coverData{
+		Site:      start.Host,
+		StartURL:  s.Redact(startURL),
+		Logo:      s.logo,
+		Generated: s.formatTime(time.Now(), isoDateTime),
+		Pages:     len(s.pdfs),
+		Failures:  len(s.failures),
+		Version:   s.docsVersion,
+		Meta:      s.Meta,
+	}
+	for i, p := range s.pdfs {
+		data.Entries = append(data.Entries, coverEntry{
+			Number: i + 1,
+			Title:  s.bookmarkTitle(p),
+			URL:    s.Redact(p.URL.String()),
+			File:   s.pageEntryName(p.URL),
+		})
+	}
+	s.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := s.cover.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("cover template %s: %w", s.CoverTemplate, err)
+	}
+	doc, err := html.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	title := pageTitle(buf.Bytes())
+	if title == "" {
+		title = "Cover"
+	}
+	return &page{URL: start, Title: title, Body: buf.Bytes(), Text: layoutText(doc, s.embedsImages())}, nil
+}
+
+// writeCover writes the cover from the current page of pdf.
+func (s *Scraper) writeCover(pdf *gofpdf.Fpdf, cover *page, t Typography) {
+	pdf.SetFont(t.Font, "", t.FontSize)
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	for _, line := range strings.Split(cover.Text, "\n") {
+		line = strings.TrimSpace(line)
+		srcs := imageLines(line, false)
+		if line != "" && len(srcs) == 0 {
+			pdf.MultiCell(pageWidth-left-right, t.LineHeight, line, "0", "L", false)
+		}
+		if s.embedsImages() {
+			for _, src := range srcs {
+				s.writeImage(pdf, src, cover.URL)
+			}
+		}
+	}
+}
+
+// coverPDF returns the cover of the archive as a PDF of its own.
+func (s *Scraper) coverPDF(startURL string) ([]byte, error) {
+	cover, err := s.coverPage(startURL)
+	if err != nil {
+		return nil, err
+	}
+	t, err := LookupTypography(s.Typography)
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := s.newPDF(t)
+	if err != nil {
+		return nil, err
+	}
+	stampMetadata(pdf, s.Meta, "")
+	pdf.SetTitle(cover.Title, true)
+	pdf.AddPage()
+	s.writeCover(pdf, cover, t)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
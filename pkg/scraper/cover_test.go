@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSiteLogo(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/")
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`<img src="/img/header.png" alt="Example logo">`, "https://example.com/img/header.png"},
+		{`<img src="brand/logo.svg">`, "https://example.com/docs/brand/logo.svg"},
+		{`<link rel="apple-touch-icon" href="/touch.png"><meta property="og:image" content="/og.png">`, "https://example.com/touch.png"},
+		{`<meta property="og:image" content="https://cdn.example.com/og.png">`, "https://cdn.example.com/og.png"},
+		{`<link rel="icon" href="/favicon.ico"><img src="/photo.jpg">`, ""},
+	}
+	for _, tt := range tests {
+		if got := siteLogo([]byte(tt.body), base); got != tt.want {
+			t.Errorf("siteLogo(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestCoverPDF(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "cover.html")
+	if err := os.WriteFile(tmpl, []byte(`<html><head><title>Archive of {{.Site}}</title></head><body>
+<h1>{{.Site}}</h1>
+<p>{{number .Pages}} pages, {{.Failures}} failed</p>
+{{range .Entries}}<p>{{.Number}}. {{.Title}} ({{.File}})</p>{{end}}
+</body></html>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(false, false)
+	s.CoverTemplate = tmpl
+	s.NoImages = true
+	if err := s.checkCover(); err != nil {
+		t.Fatal(err)
+	}
+	home, _ := url.Parse("https://example.com/")
+	guide, _ := url.Parse("https://example.com/guide")
+	s.pdfs = []*page{{URL: home, Title: "Welcome"}, {URL: guide}}
+
+	cover, err := s.coverPage("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover.Title != "Archive of example.com" {
+		t.Errorf("cover title = %q, want the template's title", cover.Title)
+	}
+	data, err := s.coverPDF("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := pdfText(data)
+	for _, want := range []string{
+		"2 pages, 0 failed",
+		"1. Welcome (" + s.pageEntryName(home) + ")",
+		"2. https://example.com/guide (" + s.pageEntryName(guide) + ")",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("cover lacks %q\n%s", want, text)
+		}
+	}
+}
+
+func TestCheckCover(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "cover.html")
+	if err := os.WriteFile(tmpl, []byte(`<h1>{{.Site}</h1>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(false, false)
+	s.CoverTemplate = tmpl
+	if err := s.checkCover(); err == nil {
+		t.Error("checkCover accepted a template that doesn't parse")
+	}
+	s.Format = FormatMarkdown
+	if err := s.checkCover(); err == nil || !strings.Contains(err.Error(), "markdown") {
+		t.Errorf("checkCover() with the Markdown format = %v, want an error", err)
+	}
+}
+
+func TestArchiveEntriesCoverFirst(t *testing.T) {
+	s := NewScraper(false, false)
+	home, _ := url.Parse("https://example.com/")
+	s.pdfs = []*page{{URL: home, File: "home.pdf"}}
+	entries := s.archiveEntries([]archiveEntry{{Name: "index.html"}, {Name: coverEntryName}})
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	want := []string{coverEntryName, s.pageEntryName(home), "index.html"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("archive entries = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,26 @@
+// Package scraper crawls websites and archives their pages as PDFs,
+// Markdown or EPUB. It is the library behind the scrapdf command, and can
+// be embedded by other Go programs instead of running the command.
+//
+// A Scraper is configured through its exported fields, which match the
+// flags of scrapdf scrape, and crawls from a start URL to an archive:
+//
+//	s := scraper.New(scraper.Options{StripHTML: true})
+//	s.MaxDepth = 2
+//	blog, err := scraper.ParseURLPattern("/blog/*")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	s.Exclude = []scraper.URLPattern{blog}
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	if err := s.ScrapeAndSaveContext(ctx, "https://docs.example.com/", "docs.zip"); err != nil {
+//		log.Fatal(err)
+//	}
+//	pages, failures := s.Stats()
+//
+// A Scraper runs one crawl: create a new one for every crawl. Its messages
+// go to Log, its timeline of events to EventStream and its progress to
+// OnProgress. Archives can then be searched with SearchArchive, described
+// with InspectArchive and repaired with RepairArchive.
+package scraper
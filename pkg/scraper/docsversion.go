@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// versionSegment matches path segments naming a documentation version, such
+// as v2, 1.4, 3.x, latest or stable.
+var versionSegment = regexp.MustCompile(`(?i)^(v?\d+(\.\d+)*(\.x)?|latest|stable|current|master|main|dev|develop|next|nightly)$`)
+
+// applyDocsVersion limits the crawl to one version of a documentation site
+// and returns the URL to start from. version is either a version name, which
+// replaces the version segment found in the start URL (or is appended to its
+// directory when there is none), or an explicit path starting with "/".
+func (s *Scraper) applyDocsVersion(version string, start *url.URL) (*url.URL, error) {
+	u := stripFragment(start)
+
+	var prefix string
+	switch {
+	case strings.HasPrefix(version, "/"):
+		prefix = strings.TrimSuffix(path.Clean(version), "/") + "/"
+		s.docsVersion = path.Base(prefix)
+	case strings.Contains(version, "/"):
+		return nil, fmt.Errorf("invalid docs version %q: use a version name or a path starting with /", version)
+	default:
+		segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+		found := -1
+		for i, seg := range segments {
+			if versionSegment.MatchString(seg) {
+				found = i
+				break
+			}
+		}
+		if found >= 0 {
+			prefix = "/" + path.Join(append(segments[:found:found], version)...) + "/"
+		} else {
+			prefix = directoryScope(u) + version + "/"
+		}
+		s.docsVersion = version
+	}
+
+	s.PathPrefix = prefix
+	if !strings.HasPrefix(u.Path, prefix) {
+		// The start page belongs to another version; begin at the root of
+		// the requested one.
+		u.Path = prefix
+		u.RawPath = ""
+		u.RawQuery = ""
+	}
+	return u, nil
+}
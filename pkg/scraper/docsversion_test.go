@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestApplyDocsVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		start       string
+		version     string
+		wantStart   string
+		wantPrefix  string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name:        "same version keeps the start page",
+			start:       "https://lib.readthedocs.io/en/latest/usage.html",
+			version:     "latest",
+			wantStart:   "https://lib.readthedocs.io/en/latest/usage.html",
+			wantPrefix:  "/en/latest/",
+			wantVersion: "latest",
+		},
+		{
+			name:        "other version starts at its root",
+			start:       "https://lib.readthedocs.io/en/stable/usage.html",
+			version:     "latest",
+			wantStart:   "https://lib.readthedocs.io/en/latest/",
+			wantPrefix:  "/en/latest/",
+			wantVersion: "latest",
+		},
+		{
+			name:        "numeric versions",
+			start:       "https://example.com/docs/v1.2/guide/intro",
+			version:     "v2.0",
+			wantStart:   "https://example.com/docs/v2.0/",
+			wantPrefix:  "/docs/v2.0/",
+			wantVersion: "v2.0",
+		},
+		{
+			name:        "no version in the start URL",
+			start:       "https://example.com/docs/",
+			version:     "3.x",
+			wantStart:   "https://example.com/docs/3.x/",
+			wantPrefix:  "/docs/3.x/",
+			wantVersion: "3.x",
+		},
+		{
+			name:        "explicit path",
+			start:       "https://example.com/",
+			version:     "/reference/2024/",
+			wantStart:   "https://example.com/reference/2024/",
+			wantPrefix:  "/reference/2024/",
+			wantVersion: "2024",
+		},
+		{
+			name:    "relative path rejected",
+			start:   "https://example.com/",
+			version: "docs/v2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, err := url.Parse(tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewScraper(false, false)
+			got, err := s.applyDocsVersion(tt.version, start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyDocsVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.wantStart {
+				t.Errorf("start = %q, want %q", got, tt.wantStart)
+			}
+			if s.PathPrefix != tt.wantPrefix {
+				t.Errorf("PathPrefix = %q, want %q", s.PathPrefix, tt.wantPrefix)
+			}
+			if s.docsVersion != tt.wantVersion {
+				t.Errorf("docsVersion = %q, want %q", s.docsVersion, tt.wantVersion)
+			}
+		})
+	}
+}
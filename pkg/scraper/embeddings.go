@@ -0,0 +1,331 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Providers of the embeddings accepted by Embeddings.Provider.
+const EmbeddingsOpenAI = "openai"
+
+// Defaults of the Embeddings settings.
+const (
+	DefaultEmbeddingsModel    = "text-embedding-3-small"
+	DefaultEmbeddingsEndpoint = "https://api.openai.com/v1/embeddings"
+	DefaultChunkSize          = 2000
+)
+
+// embeddingsKeyEnv holds the API key of the OpenAI embeddings.
+const embeddingsKeyEnv = "OPENAI_API_KEY"
+
+// embeddingsEntryName is the archive entry holding the embeddings.
+const embeddingsEntryName = "embeddings.jsonl"
+
+// embeddingsBatch is the most chunks embedded by one request.
+const embeddingsBatch = 64
+
+// Embeddings are the settings of the vector embeddings computed for the
+// extracted text of the pages, so the archive can be searched by meaning.
+type Embeddings struct {
+	// Provider computes the embeddings, EmbeddingsOpenAI.
+	Provider string
+	// Model names the provider's embedding model. Empty uses
+	// DefaultEmbeddingsModel.
+	Model string
+	// Endpoint is the URL of the embeddings API, e.g. of a server
+	// compatible with OpenAI's. Empty uses DefaultEmbeddingsEndpoint.
+	Endpoint string
+	// ChunkSize is the most characters of a page's text embedded
+	// together; longer pages are split at paragraphs, lines or words.
+	// Zero uses DefaultChunkSize.
+	ChunkSize int
+	// APIKey authenticates with the provider. Empty reads $OPENAI_API_KEY.
+	APIKey string
+	// Client sends the requests to the provider. Nil uses a client of its
+	// own, never the one given to WithHTTPClient, whose credentials are
+	// the crawled site's.
+	Client *http.Client
+}
+
+// ParseEmbeddings parses the embeddings settings written as comma
+// separated key=value pairs: provider, model, endpoint and chunk-size, e.g.
+// "provider=openai,model=text-embedding-3-large".
+func ParseEmbeddings(spec string) (*Embeddings, error) {
+	e := &Embeddings{}
+	for _, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid embeddings setting %q: want key=value, e.g. provider=%s", field, EmbeddingsOpenAI)
+		}
+		switch key {
+		case "provider":
+			e.Provider = value
+		case "model":
+			e.Model = value
+		case "endpoint":
+			e.Endpoint = value
+		case "chunk-size":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid embeddings chunk size %q: want a number of characters", value)
+			}
+			e.ChunkSize = n
+		default:
+			return nil, fmt.Errorf("unknown embeddings setting %q (available: provider, model, endpoint, chunk-size)", key)
+		}
+	}
+	return e, nil
+}
+
+// model returns the embedding model used.
+func (e *Embeddings) model() string {
+	if e.Model == "" {
+		return DefaultEmbeddingsModel
+	}
+	return e.Model
+}
+
+// endpoint returns the URL the chunks are sent to.
+func (e *Embeddings) endpoint() string {
+	if e.Endpoint == "" {
+		return DefaultEmbeddingsEndpoint
+	}
+	return e.Endpoint
+}
+
+// chunkSize returns the most characters embedded together.
+func (e *Embeddings) chunkSize() int {
+	if e.ChunkSize == 0 {
+		return DefaultChunkSize
+	}
+	return e.ChunkSize
+}
+
+// apiKey returns the key sent to the provider.
+func (e *Embeddings) apiKey() string {
+	if e.APIKey != "" {
+		return e.APIKey
+	}
+	return os.Getenv(embeddingsKeyEnv)
+}
+
+// checkEmbeddings rejects unknown providers, a missing API key and outputs
+// without room for embeddings.jsonl before the crawl rather than after it.
+func (s *Scraper) checkEmbeddings() error {
+	e := s.Embeddings
+	if e == nil {
+		return nil
+	}
+	switch {
+	case s.SinglePDF:
+		return fmt.Errorf("a single PDF has no room for the embeddings, write an archive instead")
+	case s.Format == FormatEPUB:
+		return fmt.Errorf("an EPUB book has no room for the embeddings, write an archive instead")
+	}
+	if e.Provider != EmbeddingsOpenAI {
+		return fmt.Errorf("unknown embeddings provider %q (available: %s)", e.Provider, EmbeddingsOpenAI)
+	}
+	if e.ChunkSize < 0 {
+		return fmt.Errorf("the embeddings chunk size can't be negative")
+	}
+	if e.apiKey() == "" {
+		return fmt.Errorf("the %s embeddings need an API key in $%s", e.Provider, embeddingsKeyEnv)
+	}
+	return nil
+}
+
+// textChunk is a piece of a page's text embedded on its own.
+type textChunk struct {
+	Start, End int // offsets in the text, in characters
+	Text       string
+}
+
+// chunkText splits text into chunks of at most size characters, cut at
+// the last paragraph break, line break or space that fits, and trimmed of
+// spaces.
+func chunkText(text string, size int) []textChunk {
+	runes := []rune(text)
+	var chunks []textChunk
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		if end-start > size {
+			end = start + size
+			if cut := chunkCut(runes[start:end]); cut > 0 {
+				end = start + cut
+			}
+		}
+		from, to := start, end
+		for from < to && unicode.IsSpace(runes[from]) {
+			from++
+		}
+		for to > from && unicode.IsSpace(runes[to-1]) {
+			to--
+		}
+		if from < to {
+			chunks = append(chunks, textChunk{Start: from, End: to, Text: string(runes[from:to])})
+		}
+		start = end
+	}
+	return chunks
+}
+
+// chunkCut returns where to end a chunk of the runes: after the last
+// paragraph break, else the last line break, else the last space, or 0
+// when there is none.
+func chunkCut(runes []rune) int {
+	s := string(runes)
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(s, sep); i > 0 {
+			return len([]rune(s[:i+len(sep)]))
+		}
+	}
+	return 0
+}
+
+// embeddingRecord is a line of embeddings.jsonl: a chunk of a page's text
+// with its embedding.
+type embeddingRecord struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	File  string `json:"file"`
+	Chunk int    `json:"chunk"`
+	// Start and End locate the chunk in the page's extracted text, in
+	// characters.
+	Start     int       `json:"start"`
+	End       int       `json:"end"`
+	Text      string    `json:"text"`
+	Model     string    `json:"model"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// embeddingsJSONL embeds the text of the archived pages, chunk by chunk,
+// and returns embeddings.jsonl: a JSON record per chunk.
+func (s *Scraper) embeddingsJSONL() ([]byte, error) {
+	e := s.Embeddings
+	var records []embeddingRecord
+	s.mu.Lock()
+	for _, p := range s.pdfs {
+		for i, c := range chunkText(s.Redact(s.searchText(p)), e.chunkSize()) {
+			records = append(records, embeddingRecord{
+				URL:   s.Redact(p.URL.String()),
+				Title: s.Redact(p.Title),
+				File:  s.pageEntryName(p.URL),
+				Chunk: i,
+				Start: c.Start,
+				End:   c.End,
+				Text:  c.Text,
+				Model: e.model(),
+			})
+		}
+	}
+	s.mu.Unlock()
+
+	for start := 0; start < len(records); start += embeddingsBatch {
+		batch := records[start:min(start+embeddingsBatch, len(records))]
+		inputs := make([]string, len(batch))
+		for i, r := range batch {
+			inputs[i] = r.Text
+		}
+		vectors, err := s.embed(inputs)
+		if err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			batch[i].Embedding = vectors[i]
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// embed returns the embeddings of inputs, in order, from the OpenAI
+// embeddings API. Overloaded servers are retried like pages.
+func (s *Scraper) embed(inputs []string) ([][]float32, error) {
+	e := s.Embeddings
+	body, err := json.Marshal(struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{e.model(), inputs})
+	if err != nil {
+		return nil, err
+	}
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Transport: http.DefaultTransport, Timeout: s.RequestTimeout}
+	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(s.crawlContext(), http.MethodPost, e.endpoint(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+e.apiKey())
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("embeddings request failed: %w", err)
+		}
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("embeddings request failed: %w", err)
+		}
+		if overloaded(resp.StatusCode) && attempt <= s.Retries {
+			if !s.wait(s.retryDelay(attempt, &resp.Header)) {
+				return nil, fmt.Errorf("embeddings request failed: %w", s.cancelled())
+			}
+			continue
+		}
+		return parseEmbeddings(resp.StatusCode, data, len(inputs))
+	}
+}
+
+// parseEmbeddings reads the n embeddings of an OpenAI embeddings response.
+func parseEmbeddings(status int, data []byte, n int) ([][]float32, error) {
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	err := json.Unmarshal(data, &result)
+	if status != http.StatusOK {
+		if err == nil && result.Error != nil {
+			return nil, fmt.Errorf("embeddings API returned %d: %s", status, result.Error.Message)
+		}
+		return nil, fmt.Errorf("embeddings API returned %d", status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	vectors := make([][]float32, n)
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= n {
+			return nil, fmt.Errorf("invalid embeddings response: index %d of %d inputs", d.Index, n)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("invalid embeddings response: no embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEmbeddings(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *Embeddings
+		wantErr bool
+	}{
+		{"provider=openai", &Embeddings{Provider: EmbeddingsOpenAI}, false},
+		{"provider=openai, model=text-embedding-3-large,chunk-size=500", &Embeddings{Provider: EmbeddingsOpenAI, Model: "text-embedding-3-large", ChunkSize: 500}, false},
+		{"provider=openai,endpoint=http://localhost:8080/v1/embeddings", &Embeddings{Provider: EmbeddingsOpenAI, Endpoint: "http://localhost:8080/v1/embeddings"}, false},
+		{"openai", nil, true},
+		{"provider=openai,chunk-size=0", nil, true},
+		{"provider=openai,dimensions=256", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEmbeddings(tt.spec)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEmbeddings(%q) = %+v, %v", tt.spec, got, err)
+		}
+	}
+}
+
+func TestCheckEmbeddings(t *testing.T) {
+	t.Setenv(embeddingsKeyEnv, "")
+	s := NewScraper(true, false)
+	s.Embeddings = &Embeddings{Provider: EmbeddingsOpenAI}
+	if err := s.checkEmbeddings(); err == nil {
+		t.Error("embeddings without an API key accepted")
+	}
+	t.Setenv(embeddingsKeyEnv, "sk-test")
+	if err := s.checkEmbeddings(); err != nil {
+		t.Errorf("checkEmbeddings() = %v", err)
+	}
+	// The key is masked like the site's credentials
+	if secrets := s.requestSecrets(); !contains(secrets, "sk-test") {
+		t.Errorf("requestSecrets() = %v, want the API key", secrets)
+	}
+	s.Embeddings.Provider = "word2vec"
+	if err := s.checkEmbeddings(); err == nil {
+		t.Error("unknown provider accepted")
+	}
+	s.Embeddings.Provider = EmbeddingsOpenAI
+	s.Format = FormatEPUB
+	if err := s.checkEmbeddings(); err == nil {
+		t.Error("embeddings accepted for an EPUB book")
+	}
+	s.Format = ""
+	s.SinglePDF = true
+	if err := s.checkEmbeddings(); err == nil {
+		t.Error("embeddings accepted for a single PDF")
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	text := "First paragraph here.\n\nSecond one, a bit longer.\nWith a line. Ünïcode words"
+	chunks := chunkText(text, 30)
+	want := []string{"First paragraph here.", "Second one, a bit longer.", "With a line. Ünïcode words"}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunkText() = %+v, want %q", chunks, want)
+	}
+	runes := []rune(text)
+	for i, c := range chunks {
+		if c.Text != want[i] {
+			t.Errorf("chunk %d = %q, want %q", i, c.Text, want[i])
+		}
+		if string(runes[c.Start:c.End]) != c.Text {
+			t.Errorf("chunk %d at %d-%d doesn't match the text", i, c.Start, c.End)
+		}
+	}
+	// Words longer than a chunk are cut
+	if got := chunkText("abcdefghij", 4); len(got) != 3 || got[2].Text != "ij" {
+		t.Errorf("chunkText() of a long word = %+v", got)
+	}
+}
+
+func TestEmbeddingsJSONL(t *testing.T) {
+	overloaded := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Incorrect API key"}}`))
+			return
+		}
+		if overloaded {
+			overloaded = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var resp struct {
+			Data []map[string]any `json:"data"`
+		}
+		// Out of order, as the API doesn't promise it
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, map[string]any{"index": i, "embedding": []float32{float32(len(req.Input[i])), 0.5}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse("https://example.com/guide?token=secret")
+	s := NewScraper(true, false)
+	s.Secrets = []string{"secret"}
+	s.RetryBackoff = time.Millisecond
+	s.Embeddings = &Embeddings{Provider: EmbeddingsOpenAI, Endpoint: server.URL, ChunkSize: 20, APIKey: "sk-test"}
+	// The site's credentials stay with the crawl
+	crawl := &authTransport{}
+	s.WithHTTPClient(&http.Client{Transport: crawl})
+	s.pdfs = []*page{{URL: u, Title: "Guide for secret", Text: "Install the tool.\n\nRun it with care.\n\n"}}
+
+	data, err := s.embeddingsJSONL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(lines), data)
+	}
+	var r embeddingRecord
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+		t.Fatal(err)
+	}
+	want := embeddingRecord{
+		URL: "https://example.com/guide?token=" + redactedMask, Title: "Guide for " + redactedMask, File: s.pageEntryName(u),
+		Chunk: 1, Start: 19, End: 36, Text: "Run it with care.", Model: DefaultEmbeddingsModel, Embedding: []float32{17, 0.5},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("record = %+v, want %+v", r, want)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Error("secret written to the embeddings")
+	}
+	if crawl.sent.Load() != 0 {
+		t.Error("embeddings requested through the crawl's client")
+	}
+
+	s.Embeddings.APIKey = "sk-wrong"
+	if _, err := s.embeddingsJSONL(); err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("embeddingsJSONL() with a wrong key = %v", err)
+	}
+}
@@ -0,0 +1,343 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// chapterElements are the elements kept in EPUB chapters, with the
+// attributes kept on them besides id. Other elements are replaced by their
+// content, so the chapters keep their structure but not the site's layout.
+var chapterElements = map[atom.Atom][]string{
+	atom.A: {"href"}, atom.Abbr: nil, atom.B: nil, atom.Blockquote: nil,
+	atom.Br: nil, atom.Caption: nil, atom.Cite: nil, atom.Code: nil,
+	atom.Dd: nil, atom.Del: nil, atom.Dl: nil, atom.Dt: nil,
+	atom.Em: nil, atom.Figcaption: nil, atom.Figure: nil, atom.H1: nil,
+	atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil,
+	atom.H6: nil, atom.Hr: nil, atom.I: nil, atom.Kbd: nil,
+	atom.Li: nil, atom.Mark: nil, atom.Ol: {"start"}, atom.P: nil,
+	atom.Pre: nil, atom.Q: nil, atom.S: nil, atom.Samp: nil,
+	atom.Small: nil, atom.Strong: nil, atom.Sub: nil, atom.Sup: nil,
+	atom.Table: nil, atom.Tbody: nil, atom.Td: {"colspan", "rowspan"}, atom.Tfoot: nil,
+	atom.Th: {"colspan", "rowspan"}, atom.Thead: nil, atom.Tr: nil, atom.U: nil,
+	atom.Ul: nil,
+}
+
+// epubStyle is the stylesheet of every chapter. Readers apply their own
+// fonts and margins, so it only sets what they usually don't.
+const epubStyle = `pre { white-space: pre-wrap; font-size: 0.85em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 0.2em 0.4em; }
+blockquote { margin-left: 1.5em; font-style: italic; }
+`
+
+// chapterXHTML returns the content of doc as the body of an EPUB chapter,
+// with links made absolute against base.
+func chapterXHTML(doc *html.Node, base *url.URL) string {
+	base = documentBase(doc, base)
+	root := doc
+	if body := findElement(doc, atom.Body); body != nil {
+		root = body
+	}
+	var b strings.Builder
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		writeXHTML(&b, c, func(href string) string {
+			if strings.HasPrefix(href, "#") {
+				return href
+			}
+			if strings.HasPrefix(strings.ToLower(href), "javascript:") {
+				return ""
+			}
+			target, err := base.Parse(href)
+			if err != nil {
+				return ""
+			}
+			return target.String()
+		})
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// textChapter returns plain text as the body of an EPUB chapter, a
+// paragraph per block of lines.
+func textChapter(text string) string {
+	var b strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			b.WriteString("<p>" + strings.ReplaceAll(xmlEscape(para), "\n", "<br/>") + "</p>\n")
+		}
+	}
+	return b.String()
+}
+
+// writeXHTML writes n to b as well-formed XHTML, keeping only the
+// chapterElements and the ids of the others. link maps the href of every link, dropping it when it
+// returns "".
+func writeXHTML(b *strings.Builder, n *html.Node, link func(href string) string) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(xmlEscape(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skippedElements[n.DataAtom] {
+		return
+	}
+	attrs, kept := chapterElements[n.DataAtom]
+	if !kept {
+		// Links to the element's #fragment land where it was
+		if id := getAttr(n, "id"); id != "" {
+			b.WriteString(`<a id="` + xmlEscape(id) + `"></a>`)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeXHTML(b, c, link)
+		}
+		return
+	}
+
+	b.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		if a.Namespace != "" || (a.Key != "id" && !contains(attrs, a.Key)) {
+			continue
+		}
+		val := a.Val
+		if a.Key == "href" {
+			if val = link(strings.TrimSpace(val)); val == "" {
+				continue
+			}
+		}
+		b.WriteString(" " + a.Key + `="` + xmlEscape(val) + `"`)
+	}
+	if n.DataAtom == atom.Br || n.DataAtom == atom.Hr {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeXHTML(b, c, link)
+	}
+	b.WriteString("</" + n.Data + ">")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// xmlEscape escapes s for XML text and attributes, dropping the control
+// characters XML doesn't allow.
+func xmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, s)
+	return html.EscapeString(s)
+}
+
+// chapterEntryName returns the name of the chapter of the page at u in the
+// EPUB.
+func chapterEntryName(u *url.URL) string {
+	return strings.TrimSuffix(entryName(u), ".pdf") + ".xhtml"
+}
+
+// chapterHref returns name as a relative URL.
+func chapterHref(name string) string {
+	return (&url.URL{Path: name}).String()
+}
+
+// renderChapter writes the body of the page's chapter.
+func (s *Scraper) renderChapter(p *page) error {
+	if err := os.WriteFile(p.File, []byte(p.Text), 0644); err != nil {
+		return fmt.Errorf("failed to write chapter: %w", err)
+	}
+	return nil
+}
+
+// internalLinks maps the URL of every archived page, and of the parts
+// stitched into it, to its chapter.
+func (s *Scraper) internalLinks() map[string]string {
+	links := make(map[string]string)
+	for _, p := range s.pdfs {
+		href := chapterHref(chapterEntryName(p.URL))
+		for _, u := range s.pageURLs(p) {
+			links[u] = href
+		}
+	}
+	return links
+}
+
+// chapterDocument returns the chapter of p as an XHTML document, its links
+// to other archived pages pointing at their chapters.
+func (s *Scraper) chapterDocument(p *page, links map[string]string) ([]byte, error) {
+	body, err := os.ReadFile(p.File)
+	if err != nil {
+		return nil, err
+	}
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(body), context)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+<title>` + xmlEscape(s.bookmarkTitle(p)) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+`)
+	for _, n := range nodes {
+		writeXHTML(&b, n, func(href string) string {
+			u, err := url.Parse(href)
+			if err != nil || !u.IsAbs() {
+				return href
+			}
+			chapter, ok := links[stripFragment(u).String()]
+			if !ok {
+				return href
+			}
+			if u.Fragment != "" {
+				chapter += "#" + u.EscapedFragment()
+			}
+			return chapter
+		})
+	}
+	b.WriteString("\n</body>\n</html>\n")
+	return []byte(b.String()), nil
+}
+
+// bookLanguage returns the language of the book, from the first page the
+// server labelled, or English.
+func (s *Scraper) bookLanguage() string {
+	for _, p := range s.pdfs {
+		if lang := strings.TrimSpace(strings.Split(p.Header.Get("Content-Language"), ",")[0]); lang != "" {
+			return lang
+		}
+	}
+	return "en"
+}
+
+// createEPUB writes every archived page, in archive order, to an EPUB 3
+// book at filename with one chapter per page and a table of contents. An
+// NCX table of contents is included for EPUB 2 readers.
+func (s *Scraper) createEPUB(filename, startURL string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	archive := zip.NewWriter(f)
+
+	// The mimetype comes first and uncompressed, so readers can sniff it
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	title := s.bookmarkTitle(s.pdfs[0])
+	identifier := s.Redact(startURL)
+	modified := time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	links := s.internalLinks()
+
+	var items, spine, nav, ncx strings.Builder
+	files := []archiveEntry{
+		{Name: "META-INF/container.xml", Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`)},
+		{Name: "OEBPS/style.css", Data: []byte(epubStyle)},
+	}
+	for i, p := range s.pdfs {
+		name := chapterEntryName(p.URL)
+		data, err := s.chapterDocument(p, links)
+		if err != nil {
+			return fmt.Errorf("failed to build chapter of %s: %w", p.URL, err)
+		}
+		files = append(files, archiveEntry{Name: "OEBPS/" + name, Data: data})
+
+		id := fmt.Sprintf("chapter%d", i+1)
+		href := xmlEscape(chapterHref(name))
+		chapterTitle := xmlEscape(s.bookmarkTitle(p))
+		fmt.Fprintf(&items, "<item id=%q href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", id, href)
+		fmt.Fprintf(&spine, "<itemref idref=%q/>\n", id)
+		fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a></li>\n", href, chapterTitle)
+		fmt.Fprintf(&ncx, "<navPoint id=%q playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n", id, i+1, chapterTitle, href)
+	}
+
+	files = append(files,
+		archiveEntry{Name: "OEBPS/content.opf", Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">` + xmlEscape(identifier) + `</dc:identifier>
+<dc:title>` + xmlEscape(title) + `</dc:title>
+<dc:language>` + xmlEscape(s.bookLanguage()) + `</dc:language>
+<dc:source>` + xmlEscape(identifier) + `</dc:source>
+<meta property="dcterms:modified">` + modified + `</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="style" href="style.css" media-type="text/css"/>
+` + items.String() + `</manifest>
+<spine toc="ncx">
+` + spine.String() + `</spine>
+</package>
+`)},
+		archiveEntry{Name: "OEBPS/nav.xhtml", Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>` + xmlEscape(title) + `</title></head>
+<body>
+<nav epub:type="toc" id="toc">
+<h1>Contents</h1>
+<ol>
+` + nav.String() + `</ol>
+</nav>
+</body>
+</html>
+`)},
+		archiveEntry{Name: "OEBPS/toc.ncx", Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:uid" content="` + xmlEscape(identifier) + `"/></head>
+<docTitle><text>` + xmlEscape(title) + `</text></docTitle>
+<navMap>
+` + ncx.String() + `</navMap>
+</ncx>
+`)},
+	)
+
+	for _, entry := range files {
+		w, err := archive.Create(entry.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(entry.Data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,122 @@
+package scraper
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestChapterXHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/intro")
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"structure is kept",
+			`<h2 id="a" class="x">Title</h2><p>Some <b>bold</b> text<br>and more</p><ol start="2"><li>One</li></ol>`,
+			`<h2 id="a">Title</h2><p>Some <b>bold</b> text<br/>and more</p><ol start="2"><li>One</li></ol>`},
+		{"layout is dropped",
+			`<div class="nav"><span style="x">Kept</span><img src="a.png"></div><script>alert(1)</script>`,
+			`Kept`},
+		{"ids of dropped elements are kept",
+			`<section id="install"><div><span id="step-1">Run</span></div></section>`,
+			`<a id="install"></a><a id="step-1"></a>Run`},
+		{"links are absolute",
+			`<p><a href="../guide#x">Guide</a> <a href="#top">top</a> <a href="javascript:go()">menu</a></p>`,
+			`<p><a href="https://example.com/guide#x">Guide</a> <a href="#top">top</a> <a>menu</a></p>`},
+		{"text is escaped",
+			"<pre>a &lt; b &amp;&amp; c\x01</pre>",
+			`<pre>a &lt; b &amp;&amp; c</pre>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chapterXHTML(doc, base); got != tt.want {
+				t.Errorf("chapterXHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateEPUB(t *testing.T) {
+	dir := t.TempDir()
+	s := &Scraper{Format: FormatEPUB}
+	var pages []*page
+	for _, item := range []struct{ path, title, body string }{
+		{"/", "Home", `<h1>Home</h1><p>See <a href="https://example.com/guide#setup">the guide</a> and <a href="https://example.org/">elsewhere</a>.</p>`},
+		{"/guide", "Guide & more", `<h1>Guide</h1><h2 id="setup">Setup</h2><p>Steps</p>`},
+	} {
+		u, _ := url.Parse("https://example.com" + item.path)
+		p := &page{URL: u, Title: item.title, Header: http.Header{"Content-Language": {"de"}}, Text: item.body, File: filepath.Join(dir, s.pageEntryName(u))}
+		if err := s.render(p); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, p)
+	}
+	s.pdfs = pages
+
+	book := filepath.Join(dir, "example.com.epub")
+	if err := s.createEPUB(book, "https://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if first := r.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("first entry %s is not the stored mimetype", first.Name)
+	}
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+		if strings.HasSuffix(f.Name, ".xhtml") || strings.HasSuffix(f.Name, ".opf") || strings.HasSuffix(f.Name, ".ncx") {
+			d := xml.NewDecoder(strings.NewReader(string(data)))
+			for {
+				if _, err := d.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Errorf("%s is not well-formed: %v", f.Name, err)
+					break
+				}
+			}
+		}
+	}
+
+	home := files["OEBPS/example.com_index.xhtml"]
+	if !strings.Contains(home, `<a href="example.com_guide.xhtml#setup">the guide</a>`) {
+		t.Errorf("internal link not pointing at its chapter:\n%s", home)
+	}
+	if !strings.Contains(home, `<a href="https://example.org/">elsewhere</a>`) {
+		t.Errorf("external link changed:\n%s", home)
+	}
+	opf := files["OEBPS/content.opf"]
+	if !strings.Contains(opf, "<dc:language>de</dc:language>") || !strings.Contains(opf, "<dc:title>Home</dc:title>") {
+		t.Errorf("metadata:\n%s", opf)
+	}
+	if strings.Index(opf, `href="example.com_index.xhtml"`) > strings.Index(opf, `href="example.com_guide.xhtml"`) {
+		t.Error("chapters out of archive order")
+	}
+	if !strings.Contains(files["OEBPS/nav.xhtml"], "Guide &amp; more") {
+		t.Errorf("table of contents:\n%s", files["OEBPS/nav.xhtml"])
+	}
+}
@@ -0,0 +1,153 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CrawlEstimate projects the size and duration of a crawl from a sample of
+// its pages.
+type CrawlEstimate struct {
+	Sampled  int           `json:"sampled"`  // pages fetched
+	Archived int           `json:"archived"` // sampled pages that made it to the archive
+	Elapsed  time.Duration `json:"-"`
+	// AverageSize is the mean size of an archived page's file and
+	// AverageRender the mean time to render it.
+	AverageSize   int64         `json:"average_size"`
+	AverageRender time.Duration `json:"-"`
+	// Discovered counts the pages linked from the sample that weren't
+	// fetched, and SitemapPages the pages the sitemaps list in scope, -1
+	// without a sitemap.
+	Discovered   int `json:"discovered"`
+	SitemapPages int `json:"sitemap_pages"`
+	// Pages is the projected page count: the sitemaps' when they list more
+	// than the sample reached, otherwise a lower bound.
+	Pages       int  `json:"pages"`
+	FromSitemap bool `json:"from_sitemap"`
+	// ArchiveSize is the projected size of the archive, its pages
+	// compressed as the ZIP stores them, and Duration the projected time
+	// of the crawl at the sample's pace.
+	ArchiveSize int64         `json:"archive_size"`
+	Duration    time.Duration `json:"-"`
+}
+
+// MarshalJSON writes the estimate with its durations in milliseconds, like
+// the event log.
+func (e CrawlEstimate) MarshalJSON() ([]byte, error) {
+	type plain CrawlEstimate
+	return json.Marshal(struct {
+		plain
+		Elapsed       float64 `json:"elapsed_ms"`
+		AverageRender float64 `json:"average_render_ms"`
+		Duration      float64 `json:"duration_ms"`
+	}{plain(e), milliseconds(e.Elapsed), milliseconds(e.AverageRender), milliseconds(e.Duration)})
+}
+
+// estimator samples a crawl for EstimateCrawl.
+type estimator struct {
+	sample  int
+	fetched atomic.Int64
+	render  atomic.Int64 // nanoseconds spent rendering archived pages
+	skipped sync.Map     // map[url]bool of pages left out of the sample
+}
+
+// admit reports whether the page at u may be fetched for the sample.
+func (e *estimator) admit(u string) bool {
+	if e.fetched.Add(1) <= int64(e.sample) {
+		return true
+	}
+	e.skipped.Store(u, true)
+	return false
+}
+
+// EstimateCrawl fetches and renders up to sample pages of the crawl of
+// startURL, with every setting of a real run, and projects the size and
+// duration of the whole crawl from them. Nothing is archived. Any
+// credentials in the returned error are masked.
+func (s *Scraper) EstimateCrawl(startURL string, sample int) (*CrawlEstimate, error) {
+	if sample < 1 {
+		return nil, fmt.Errorf("an estimate needs to sample at least one page")
+	}
+	if s.Resume {
+		return nil, fmt.Errorf("an estimate starts a new crawl, it can't be resumed")
+	}
+	dir, err := os.MkdirTemp("", "scrapdf-estimate-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	s.estimate = &estimator{sample: sample}
+	est, err := s.estimateCrawl(startURL, filepath.Join(dir, "estimate.zip"))
+	return est, s.redactError(err)
+}
+
+func (s *Scraper) estimateCrawl(startURL, outputPath string) (*CrawlEstimate, error) {
+	started := time.Now()
+	if err := s.scrapeAndSave(startURL, outputPath); err != nil {
+		return nil, err
+	}
+	est := s.estimated
+	est.Elapsed = time.Since(started)
+	est.Sampled = min(int(s.estimate.fetched.Load()), s.estimate.sample)
+
+	// The sitemaps know about pages the sample never linked to
+	est.SitemapPages = -1
+	if !s.offline() {
+		if report, err := s.probe(startURL); err == nil {
+			est.SitemapPages = report.EstimatedPages
+		}
+	}
+
+	est.Pages = est.Sampled + est.Discovered
+	if est.SitemapPages > est.Pages {
+		est.Pages, est.FromSitemap = est.SitemapPages, true
+	}
+	if est.Sampled > 0 {
+		est.Duration = est.Elapsed * time.Duration(est.Pages) / time.Duration(est.Sampled)
+		// Pages that fail take time but no space
+		est.ArchiveSize = est.ArchiveSize * int64(est.Pages) / int64(est.Sampled)
+	}
+	return est, nil
+}
+
+// measureSample fills s.estimated from the pages of the sample, before the
+// work directory is removed.
+func (s *Scraper) measureSample() {
+	est := &CrawlEstimate{Archived: len(s.pdfs)}
+	s.estimate.skipped.Range(func(_, _ any) bool {
+		est.Discovered++
+		return true
+	})
+	var total, compressed int64
+	for _, p := range s.pdfs {
+		data, err := os.ReadFile(p.File)
+		if err != nil {
+			continue
+		}
+		total += int64(len(data))
+		compressed += deflatedSize(data)
+	}
+	if est.Archived > 0 {
+		est.AverageSize = total / int64(est.Archived)
+		est.AverageRender = time.Duration(s.estimate.render.Load()) / time.Duration(est.Archived)
+	}
+	est.ArchiveSize = compressed
+	s.estimated = est
+}
+
+// deflatedSize returns the size of data compressed as createZip stores it.
+func deflatedSize(data []byte) int64 {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write(data)
+	w.Close()
+	return int64(buf.Len())
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEstimateCrawl(t *testing.T) {
+	// Every page links to the next three
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		if _, err := fmt.Sscanf(r.URL.Path, "/p%d", &n); err != nil && r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>Page %d</title></head><body><p>%s</p>", n, strings.Repeat("Some text. ", 50))
+		for i := n + 1; i <= n+3; i++ {
+			fmt.Fprintf(w, `<a href="/p%d">next</a>`, i)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.MaxDepth = 0
+	est, err := s.EstimateCrawl(srv.URL+"/", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Sampled != 3 || est.Archived != 3 {
+		t.Errorf("sampled %d pages and archived %d, want 3 and 3", est.Sampled, est.Archived)
+	}
+	if est.Discovered == 0 || est.Pages != est.Sampled+est.Discovered || est.FromSitemap {
+		t.Errorf("projected %d pages from %d discovered, want the sample and the discovered pages", est.Pages, est.Discovered)
+	}
+	if est.AverageSize == 0 || est.ArchiveSize == 0 || est.Duration < est.Elapsed {
+		t.Errorf("average page %d bytes, archive %d bytes, duration %s after %s; want a projection", est.AverageSize, est.ArchiveSize, est.Duration, est.Elapsed)
+	}
+	if est.ArchiveSize >= est.AverageSize*int64(est.Pages) {
+		t.Errorf("archive of %d bytes isn't compressed, pages are %d bytes", est.ArchiveSize, est.AverageSize)
+	}
+}
+
+func TestEstimateCrawlErrors(t *testing.T) {
+	s := NewScraper(false, false)
+	if _, err := s.EstimateCrawl("http://example.com/", 0); err == nil {
+		t.Error("EstimateCrawl() with no sample succeeded")
+	}
+	s.Resume = true
+	if _, err := s.EstimateCrawl("http://example.com/", 5); err == nil {
+		t.Error("EstimateCrawl() of a resumed crawl succeeded")
+	}
+}
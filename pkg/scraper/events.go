@@ -0,0 +1,127 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event types written to events.jsonl.
+const (
+	eventRunStart   = "run_start"
+	eventRunEnd     = "run_end"
+	eventDiscover   = "discover"
+	eventSkip       = "skip"
+	eventFetchStart = "fetch_start"
+	eventFetchEnd   = "fetch_end"
+	eventRetry      = "retry"
+	eventRender     = "render"
+	eventError      = "error"
+)
+
+// event is one line of events.jsonl.
+type event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	URL      string    `json:"url,omitempty"`
+	From     string    `json:"from,omitempty"`
+	Status   int       `json:"status,omitempty"`
+	Duration float64   `json:"duration_ms,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// eventLog writes events as JSON Lines to a file as they happen, and keeps
+// them for the archive.
+type eventLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	stream  io.Writer
+	archive *bytes.Buffer
+}
+
+// openEvents starts the event log when Events, EventsFile or EventStream
+// is set.
+func (s *Scraper) openEvents() error {
+	if !s.Events && s.EventsFile == "" && s.EventStream == nil {
+		return nil
+	}
+	log := &eventLog{stream: s.EventStream}
+	if s.Events {
+		log.archive = &bytes.Buffer{}
+	}
+	if s.EventsFile != "" {
+		f, err := os.Create(s.EventsFile)
+		if err != nil {
+			return fmt.Errorf("failed to create events file: %w", err)
+		}
+		log.file = f
+	}
+	s.events = log
+	return nil
+}
+
+// closeEvents flushes the events file.
+func (s *Scraper) closeEvents() {
+	if s.events == nil || s.events.file == nil {
+		return
+	}
+	if err := s.events.file.Close(); err != nil {
+		s.logf("Warning: failed to write events file: %v\n", err)
+	}
+}
+
+// event records e with the current time, credentials masked. It does
+// nothing when no event log is open. Skips also go to the plan of a dry
+// run.
+func (s *Scraper) event(e event) {
+	if s.plan != nil && e.Type == eventSkip {
+		s.plan.skip(e.URL, e.Reason)
+	}
+	if s.events == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	e.URL = s.Redact(e.URL)
+	e.From = s.Redact(e.From)
+	e.Error = s.Redact(e.Error)
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	log := s.events
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	var out []io.Writer
+	if log.archive != nil {
+		out = append(out, log.archive)
+	}
+	if log.file != nil {
+		out = append(out, log.file)
+	}
+	if log.stream != nil {
+		out = append(out, log.stream)
+	}
+	for _, w := range out {
+		w.Write(line)
+	}
+}
+
+// eventsJSONL returns the events kept for the archive.
+func (s *Scraper) eventsJSONL() []byte {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	return append([]byte(nil), s.events.archive.Bytes()...)
+}
+
+// milliseconds returns d as fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEventLog(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Events = true
+	s.EventsFile = filepath.Join(t.TempDir(), "events.jsonl")
+	if err := s.openEvents(); err != nil {
+		t.Fatal(err)
+	}
+	s.event(event{Type: eventDiscover, URL: "https://example.com/a?token=abc", From: "https://example.com/"})
+	s.event(event{Type: eventFetchEnd, URL: "https://example.com/a", Status: 200, Duration: 12.5})
+	s.closeEvents()
+
+	onDisk, err := os.ReadFile(s.EventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived := s.eventsJSONL()
+	if !bytes.Equal(onDisk, archived) {
+		t.Errorf("events file and archive differ:\n%s\n%s", onDisk, archived)
+	}
+
+	var got []event
+	lines := bufio.NewScanner(bytes.NewReader(archived))
+	for lines.Scan() {
+		var e event
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if got[0].URL != "https://example.com/a?token=REDACTED" || got[0].From != "https://example.com/" {
+		t.Errorf("discover event = %+v", got[0])
+	}
+	if got[1].Status != 200 || got[1].Duration != 12.5 || got[1].Time.IsZero() {
+		t.Errorf("fetch_end event = %+v", got[1])
+	}
+}
+
+func TestEventDisabled(t *testing.T) {
+	s := NewScraper(false, false)
+	if err := s.openEvents(); err != nil {
+		t.Fatal(err)
+	}
+	s.event(event{Type: eventRunStart})
+	if s.events != nil {
+		t.Error("event log opened without --events or --events-file")
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Home</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	var stream, log bytes.Buffer
+	s := NewScraper(true, false)
+	s.EventStream = &stream
+	s.Log = &log
+	if err := s.ScrapeAndSave(srv.URL+"/", filepath.Join(t.TempDir(), "site.zip")); err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	lines := bufio.NewScanner(&stream)
+	for lines.Scan() {
+		var e event
+		if err := json.Unmarshal(lines.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		types = append(types, e.Type)
+	}
+	if len(types) < 2 || types[0] != eventRunStart || types[len(types)-1] != eventRunEnd {
+		t.Errorf("streamed events %v, want the run from start to end", types)
+	}
+	if !strings.Contains(log.String(), "Created PDF for "+srv.URL+"/") {
+		t.Errorf("log = %q, want the progress messages", log.String())
+	}
+}
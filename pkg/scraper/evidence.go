@@ -0,0 +1,268 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// evidenceDir holds the evidence files in the archive.
+const evidenceDir = "evidence"
+
+// certificate describes a certificate of the server's TLS chain.
+type certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Serial    string    `json:"serial"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	SHA256    string    `json:"sha256"`
+}
+
+// tlsDetails records the TLS connection a page was fetched over.
+type tlsDetails struct {
+	Version     string        `json:"version"`
+	CipherSuite string        `json:"cipher_suite"`
+	ServerName  string        `json:"server_name"`
+	Chain       []certificate `json:"chain"`
+}
+
+// newTLSDetails summarises a connection state.
+func newTLSDetails(state *tls.ConnectionState) *tlsDetails {
+	d := &tlsDetails{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	for _, cert := range state.PeerCertificates {
+		d.Chain = append(d.Chain, newCertificate(cert))
+	}
+	return d
+}
+
+func newCertificate(cert *x509.Certificate) certificate {
+	sum := sha256.Sum256(cert.Raw)
+	return certificate{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		Serial:    cert.SerialNumber.Text(16),
+		NotBefore: cert.NotBefore.UTC(),
+		NotAfter:  cert.NotAfter.UTC(),
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// tlsRecorder keeps the TLS details of every response, by request URL, so
+// they can be matched with the pages.
+type tlsRecorder struct {
+	base http.RoundTripper
+	seen *sync.Map // map[url]*tlsDetails
+}
+
+func (t *tlsRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.TLS != nil {
+		t.seen.Store(req.URL.String(), newTLSDetails(resp.TLS))
+	}
+	return resp, err
+}
+
+// evidenceRecord is the chain of custody entry of one archived page.
+type evidenceRecord struct {
+	URL       string              `json:"url"`
+	FetchedAt time.Time           `json:"fetched_at"`
+	Status    int                 `json:"status"`
+	Headers   map[string][]string `json:"headers"`
+	TLS       *tlsDetails         `json:"tls,omitempty"`
+	Raw       string              `json:"raw"`
+	RawSHA256 string              `json:"raw_sha256"`
+	RawBytes  int64               `json:"raw_bytes"`
+	PDF       string              `json:"pdf"`
+	PDFSHA256 string              `json:"pdf_sha256"`
+}
+
+// custodyReport is evidence/custody.json.
+type custodyReport struct {
+	StartURL   string           `json:"start_url"`
+	Operator   string           `json:"operator"`
+	Machine    string           `json:"machine"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+	TimeSource timeSource       `json:"time_source"`
+	Pages      []evidenceRecord `json:"pages"`
+}
+
+// capturedResponse is what evidence mode keeps of a response before it is
+// processed.
+type capturedResponse struct {
+	FetchedAt time.Time
+	File      string // raw body in the temporary directory
+	SHA256    string
+	Bytes     int64
+}
+
+// rawName returns the archive name of the raw response of u.
+func rawName(u *url.URL, contentType string) string {
+	ext := ".bin"
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		ext = ".html"
+	}
+	return path.Join(evidenceDir, "raw", strings.TrimSuffix(entryName(u), ".pdf")+ext)
+}
+
+// captureResponse saves the bytes of r as received, before any rendering
+// or extraction, with the trusted time they arrived at.
+func (s *Scraper) captureResponse(r *colly.Response, tmpDir string) {
+	fetched := s.trustedNow()
+	sum := sha256.Sum256(r.Body)
+	file := path.Join(tmpDir, "raw-"+strings.TrimSuffix(entryName(r.Request.URL), ".pdf"))
+	if err := os.WriteFile(file, r.Body, 0644); err != nil {
+		s.logf("Warning: failed to keep raw response of %s: %v\n", r.Request.URL, err)
+		return
+	}
+	s.captured.Store(r.Request.URL.String(), &capturedResponse{
+		FetchedAt: fetched,
+		File:      file,
+		SHA256:    hex.EncodeToString(sum[:]),
+		Bytes:     int64(len(r.Body)),
+	})
+}
+
+// evidenceEntries returns the raw responses, custody.json and SHA256SUMS
+// for the archived pages. SHA256SUMS covers every other file of the
+// archive, extras included.
+func (s *Scraper) evidenceEntries(startURL string, startedAt time.Time, extras []archiveEntry) ([]archiveEntry, error) {
+	report := custodyReport{
+		StartURL:   s.Redact(startURL),
+		Operator:   currentUser(),
+		Machine:    hostname(),
+		StartedAt:  startedAt,
+		FinishedAt: s.trustedNow(),
+		TimeSource: s.clock,
+		Pages:      []evidenceRecord{},
+	}
+
+	var entries []archiveEntry
+	for _, p := range s.pdfs {
+		value, ok := s.captured.Load(p.URL.String())
+		if !ok {
+			continue
+		}
+		raw := value.(*capturedResponse)
+		pdfSum, err := fileSHA256(p.File)
+		if err != nil {
+			return nil, err
+		}
+		rec := evidenceRecord{
+			URL:       s.Redact(p.URL.String()),
+			FetchedAt: raw.FetchedAt,
+			Status:    p.Status,
+			Headers:   s.redactHeader(p.Header),
+			Raw:       rawName(p.URL, p.Header.Get("Content-Type")),
+			RawSHA256: raw.SHA256,
+			RawBytes:  raw.Bytes,
+			PDF:       s.pageEntryName(p.URL),
+			PDFSHA256: pdfSum,
+		}
+		if d, ok := s.tlsSeen.Load(p.URL.String()); ok {
+			rec.TLS = d.(*tlsDetails)
+		}
+		report.Pages = append(report.Pages, rec)
+		entries = append(entries, archiveEntry{Name: rec.Raw, File: raw.File})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, archiveEntry{Name: path.Join(evidenceDir, "custody.json"), Data: data})
+
+	sums, err := s.sha256Sums(append(extras, entries...))
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, archiveEntry{Name: path.Join(evidenceDir, "SHA256SUMS"), Data: sums}), nil
+}
+
+// sha256Sums lists the SHA-256 of the PDFs and extras in the format of
+// sha256sum, sorted by name, so the archive can be checked with
+// "sha256sum -c".
+func (s *Scraper) sha256Sums(extras []archiveEntry) ([]byte, error) {
+	sums := make(map[string]string)
+	for _, p := range s.pdfs {
+		sum, err := fileSHA256(p.File)
+		if err != nil {
+			return nil, err
+		}
+		sums[s.pageEntryName(p.URL)] = sum
+		for _, c := range s.companions(p) {
+			if sums[c.Name], err = fileSHA256(c.File); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, e := range extras {
+		if e.File != "" {
+			sum, err := fileSHA256(e.File)
+			if err != nil {
+				return nil, err
+			}
+			sums[e.Name] = sum
+			continue
+		}
+		sum := sha256.Sum256(e.Data)
+		sums[e.Name] = hex.EncodeToString(sum[:])
+	}
+
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	return []byte(b.String()), nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file.
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
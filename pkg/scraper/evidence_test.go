@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ntpReply builds an SNTP server reply with the given receive and transmit
+// timestamps.
+func ntpReply(received, sent time.Time) []byte {
+	b := make([]byte, 48)
+	b[0] = 0x24 // version 4, server mode
+	b[1] = 2    // stratum
+	put := func(off int, t time.Time) {
+		binary.BigEndian.PutUint32(b[off:], uint32(t.Unix()+ntpEpochOffset))
+		binary.BigEndian.PutUint32(b[off+4:], uint32((int64(t.Nanosecond())<<32)/1e9))
+	}
+	put(32, received)
+	put(40, sent)
+	return b
+}
+
+func TestParseNTPResponse(t *testing.T) {
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	received := sent.Add(100 * time.Millisecond)
+	// The server is 2s ahead and answers half way through the round trip
+	server := sent.Add(2*time.Second + 50*time.Millisecond)
+
+	offset, err := parseNTPResponse(ntpReply(server, server), sent, received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := offset - 2*time.Second; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("offset = %v, want 2s", offset)
+	}
+
+	unsynced := ntpReply(server, server)
+	unsynced[1] = 0
+	client := ntpReply(server, server)
+	client[0] = 0x23
+	for name, resp := range map[string][]byte{
+		"short":        make([]byte, 12),
+		"unsynced":     unsynced,
+		"client reply": client,
+	} {
+		if _, err := parseNTPResponse(resp, sent, received); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTLSRecorder(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var seen sync.Map
+	client := &http.Client{Transport: &tlsRecorder{base: server.Client().Transport, seen: &seen}}
+	resp, err := client.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	value, ok := seen.Load(server.URL + "/page")
+	if !ok {
+		t.Fatal("TLS details not recorded")
+	}
+	d := value.(*tlsDetails)
+	if !strings.HasPrefix(d.Version, "TLS") || d.CipherSuite == "" {
+		t.Errorf("unexpected connection details %+v", d)
+	}
+	if len(d.Chain) == 0 || len(d.Chain[0].SHA256) != 64 || d.Chain[0].NotAfter.IsZero() {
+		t.Errorf("unexpected certificate chain %+v", d.Chain)
+	}
+}
+
+func TestEvidenceEntries(t *testing.T) {
+	dir := t.TempDir()
+	u, _ := url.Parse("https://example.com/docs/intro")
+	pdf := filepath.Join(dir, entryName(u))
+	if err := os.WriteFile(pdf, []byte("%PDF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &Scraper{Secrets: []string{"hunter2"}}
+	s.pdfs = []*page{{
+		URL:    u,
+		Status: 200,
+		Header: http.Header{"Content-Type": {"text/html"}, "Set-Cookie": {"sid=hunter2"}},
+		File:   pdf,
+	}}
+	raw := filepath.Join(dir, "raw")
+	if err := os.WriteFile(raw, []byte("<html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.captured.Store(u.String(), &capturedResponse{File: raw, SHA256: "abc", Bytes: 6})
+
+	entries, err := s.evidenceEntries(u.String(), time.Now(), []archiveEntry{{Name: "index.html", Data: []byte("index")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]archiveEntry)
+	for _, e := range entries {
+		files[e.Name] = e
+	}
+
+	if e, ok := files["evidence/raw/example.com_docs_intro.html"]; !ok || e.File != raw {
+		t.Errorf("raw response entry missing: %v", entries)
+	}
+
+	var report custodyReport
+	if err := json.Unmarshal(files["evidence/custody.json"].Data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pages) != 1 {
+		t.Fatalf("custody report has %d pages, want 1", len(report.Pages))
+	}
+	rec := report.Pages[0]
+	if rec.PDF != "example.com_docs_intro.pdf" || rec.RawSHA256 != "abc" || len(rec.PDFSHA256) != 64 {
+		t.Errorf("unexpected record %+v", rec)
+	}
+	if strings.Contains(string(files["evidence/custody.json"].Data), "hunter2") {
+		t.Error("custody report contains a secret")
+	}
+
+	sums := string(files["evidence/SHA256SUMS"].Data)
+	for _, name := range []string{"example.com_docs_intro.pdf", "index.html", "evidence/custody.json", "evidence/raw/example.com_docs_intro.html"} {
+		if !strings.Contains(sums, "  "+name+"\n") {
+			t.Errorf("SHA256SUMS lacks %s:\n%s", name, sums)
+		}
+	}
+	// sha256 of "index"
+	if !strings.Contains(sums, "1bc04b5291c26a46d918139138b992d2de976d6851d0893b0476b85bfbdfc6e6  index.html") {
+		t.Errorf("wrong checksum of index.html:\n%s", sums)
+	}
+}
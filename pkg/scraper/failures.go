@@ -0,0 +1,54 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// errorsEntryName is the archive file listing the pages the crawl failed
+// on, written when there are any.
+const errorsEntryName = "errors.csv"
+
+// recordFetchFailure records the page of r as failed to fetch with err.
+func (s *Scraper) recordFetchFailure(r *colly.Response, err error) {
+	msg := err.Error()
+	if s.Retries > 0 && transient(r.StatusCode, err) {
+		msg = fmt.Sprintf("%v, after %d retries", err, s.Retries)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, pageFailure{
+		URL:    s.Redact(stripFragment(r.Request.URL).String()),
+		Stage:  stageFetch,
+		Status: r.StatusCode,
+		Error:  s.Redact(msg),
+	})
+}
+
+// errorsCSV returns the failures of the crawl, in the order they happened,
+// as CSV with the columns url, stage, status and error.
+func (s *Scraper) errorsCSV() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"url", "stage", "status", "error"}); err != nil {
+		return nil, err
+	}
+	for _, f := range s.failures {
+		status := ""
+		if f.Status != 0 {
+			status = strconv.Itoa(f.Status)
+		}
+		if err := w.Write([]string{f.URL, f.Stage, status, f.Error}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
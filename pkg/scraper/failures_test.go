@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorsReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p><a href="/gone">Gone</a> <a href="/ok">OK</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	if pages, failures := s.Stats(); pages != 2 || failures != 1 {
+		t.Errorf("%d pages archived, %d failed, want 2 and 1", pages, failures)
+	}
+	rows, err := csv.NewReader(strings.NewReader(zipEntries(t, zipname)[errorsEntryName])).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("%s has %d rows, want a header and the missing page:\n%v", errorsEntryName, len(rows), rows)
+	}
+	if got := rows[1][:3]; got[0] != srv.URL+"/gone" || got[1] != stageFetch || got[2] != "404" {
+		t.Errorf("failure %v, want %s/gone failing to fetch with 404", got, srv.URL)
+	}
+}
+
+func TestNoErrorsReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p></body></html>`)
+	}))
+	defer srv.Close()
+
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := zipEntries(t, zipname)[errorsEntryName]; ok {
+		t.Errorf("%s written without failures", errorsEntryName)
+	}
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// renderFallback records a page rendered by the other renderer after the
+// one asked for failed on it.
+type renderFallback struct {
+	Renderer string `json:"renderer"` // used instead
+	Reason   string `json:"reason"`
+}
+
+// fallbackBrowser is the browser started on first use to print the pages
+// the text renderer got no text out of, when the run has none.
+type fallbackBrowser struct {
+	once   sync.Once
+	chrome *chromeRenderer
+	err    error
+}
+
+// fallsBack reports whether a page the renderer fails on is rendered by the
+// other one. Markdown and EPUB are converted from the HTML, not rendered.
+func (s *Scraper) fallsBack() bool {
+	return !s.NoRenderFallback && (s.Format == "" || s.Format == FormatPDF)
+}
+
+// renderPage renders p in its own goroutine, see isolate, falling back to
+// the text renderer for pages the browser crashed or timed out on, and to
+// the browser for pages the text renderer got no text out of.
+func (s *Scraper) renderPage(p *page) error {
+	renderer := s.renderSettings(p.URL).Renderer
+	if renderer == RendererText && s.fallsBack() && s.printsEmptyPages() && emptyText(p.Text) {
+		err := s.isolateRender(p, s.printFallback)
+		if err == nil {
+			s.logf("No text extracted from %s, printed it with the browser instead\n", p.URL)
+			p.Fallback = &renderFallback{Renderer: RendererChrome, Reason: "no text extracted"}
+			return nil
+		}
+		s.logf("Warning: no text extracted from %s, and the browser couldn't print it: %v\n", p.URL, err)
+	}
+
+	err := s.isolateRender(p, s.render)
+	if err == nil || renderer != RendererChrome || !s.fallsBack() {
+		return err
+	}
+	if textErr := s.isolateRender(p, s.renderText); textErr != nil {
+		return err
+	}
+	s.logf("The browser failed on %s, rendered it as text instead: %v\n", p.URL, err)
+	p.Fallback = &renderFallback{Renderer: RendererText, Reason: s.Redact(err.Error())}
+	return nil
+}
+
+// isolateRender runs render in its own goroutine, see isolate, on a copy
+// of p writing a file of its own. Only a render that finished is moved
+// into p, so one abandoned after a timeout can't write over the page the
+// fallback renders in its place.
+func (s *Scraper) isolateRender(p *page, render func(*page) error) error {
+	attempt := *p
+	ext := filepath.Ext(p.File)
+	attempt.File = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(p.File, ext), s.renderAttempts.Add(1), ext)
+	_, err := isolate(s.PageTimeout, func() (struct{}, error) {
+		return struct{}{}, render(&attempt)
+	})
+	if err != nil {
+		os.Remove(attempt.File)
+		return err
+	}
+	if err := os.Rename(attempt.File, p.File); err != nil {
+		return err
+	}
+	attempt.File = p.File
+	*p = attempt
+	return nil
+}
+
+// printsEmptyPages reports whether the pages with no text can be printed
+// with the browser: the live site is crawled, and no content has to be
+// redacted, which the browser's print would show.
+func (s *Scraper) printsEmptyPages() bool {
+	return !s.Offline && s.WARC == nil && len(s.RedactContent) == 0
+}
+
+// emptyText reports whether the extracted text has nothing to read.
+func emptyText(text string) bool {
+	return strings.TrimSpace(stripImageMarkers(text)) == ""
+}
+
+// printFallback prints p with the run's browser, or one started for the
+// fallback when the run has none.
+func (s *Scraper) printFallback(p *page) error {
+	chrome := s.chrome
+	if chrome == nil {
+		b := s.fallback
+		b.once.Do(func() {
+			execPath, err := findBrowser(s.BrowserPath, s.BrowserDir)
+			if err == nil {
+				b.chrome, err = newChromeRenderer(s.crawlContext(), execPath)
+			}
+			b.err = err
+		})
+		if b.err != nil {
+			return fmt.Errorf("no browser: %w", b.err)
+		}
+		chrome = b.chrome
+	}
+	header, footer := s.chromeTemplates(p)
+	if err := chrome.printPDF(p.URL.String(), s.beforeCapture(p.URL.Host), s.browserRequest(p.URL), p.File, header, footer, s.printSettings(p.URL), s.PageTimeout); err != nil {
+		return err
+	}
+	return s.thumbnail(p)
+}
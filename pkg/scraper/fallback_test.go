@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// brokenBrowser returns a chrome renderer whose browser is gone, so every
+// print fails.
+func brokenBrowser() *chromeRenderer {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return &chromeRenderer{browser: ctx, cancel: cancel}
+}
+
+func fallbackPage(t *testing.T, text string) *page {
+	return &page{
+		URL:  &url.URL{Scheme: "https", Host: "example.com", Path: "/guide"},
+		Text: text,
+		File: filepath.Join(t.TempDir(), "guide.pdf"),
+	}
+}
+
+func TestRenderPageFallsBackToText(t *testing.T) {
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	s.Renderer = RendererChrome
+	s.chrome = brokenBrowser()
+	p := fallbackPage(t, "Installing the tool")
+	if err := s.renderPage(p); err != nil {
+		t.Fatalf("renderPage() = %v, want the page rendered as text", err)
+	}
+	if p.Fallback == nil || p.Fallback.Renderer != RendererText || p.Fallback.Reason == "" {
+		t.Errorf("fallback = %+v, want the text renderer and why", p.Fallback)
+	}
+	if _, err := os.Stat(p.File); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRenderPageNoFallback(t *testing.T) {
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	s.Renderer = RendererChrome
+	s.chrome = brokenBrowser()
+	s.NoRenderFallback = true
+	p := fallbackPage(t, "Installing the tool")
+	if err := s.renderPage(p); err == nil {
+		t.Error("renderPage() fell back with NoRenderFallback")
+	}
+	if p.Fallback != nil {
+		t.Errorf("fallback = %+v, want none", p.Fallback)
+	}
+}
+
+func TestRenderPageEmptyTextWithoutBrowser(t *testing.T) {
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	s.BrowserPath = filepath.Join(t.TempDir(), "chrome")
+	s.fallback = &fallbackBrowser{}
+	p := fallbackPage(t, " \n")
+	if err := s.renderPage(p); err != nil {
+		t.Fatalf("renderPage() = %v, want the empty text rendered", err)
+	}
+	if p.Fallback != nil {
+		t.Errorf("fallback = %+v, want none without a browser", p.Fallback)
+	}
+	if _, err := os.Stat(p.File); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsolateRenderAbandoned(t *testing.T) {
+	s := New(Options{StripHTML: true})
+	s.PageTimeout = 10 * time.Millisecond
+	p := fallbackPage(t, "Installing the tool")
+	written := make(chan struct{})
+	err := s.isolateRender(p, func(p *page) error {
+		time.Sleep(50 * time.Millisecond)
+		p.Thumb = []byte("late")
+		defer close(written)
+		return os.WriteFile(p.File, []byte("late"), 0644)
+	})
+	if err == nil {
+		t.Fatal("isolateRender() of a slow render succeeded")
+	}
+	if err := s.isolateRender(p, func(p *page) error {
+		p.Thumb = []byte("fallback")
+		return os.WriteFile(p.File, []byte("fallback"), 0644)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-written
+	// The abandoned render wrote its own copy
+	data, err := os.ReadFile(p.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "fallback" || string(p.Thumb) != "fallback" {
+		t.Errorf("page = %q with thumbnail %q, want the fallback's", data, p.Thumb)
+	}
+}
+
+func TestEmptyText(t *testing.T) {
+	for text, want := range map[string]bool{
+		"":                      true,
+		" \n\t":                 true,
+		imageMarker + "a.png\n": true,
+		"Hello":                 false,
+	} {
+		if got := emptyText(text); got != want {
+			t.Errorf("emptyText(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
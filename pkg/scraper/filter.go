@@ -0,0 +1,425 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filterVars are the page properties a filter can refer to, with their
+// types.
+var filterVars = map[string]filterType{
+	"status":       filterNumber,
+	"words":        filterNumber,
+	"bytes":        filterNumber,
+	"depth":        filterNumber,
+	"url":          filterString,
+	"host":         filterString,
+	"path":         filterString,
+	"query":        filterString,
+	"title":        filterString,
+	"content_type": filterString,
+}
+
+// FilterVarNames lists the variables available in filter expressions.
+func FilterVarNames() []string {
+	names := make([]string, 0, len(filterVars))
+	for name := range filterVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type filterType int
+
+const (
+	filterBool filterType = iota
+	filterNumber
+	filterString
+)
+
+func (t filterType) String() string {
+	switch t {
+	case filterNumber:
+		return "number"
+	case filterString:
+		return "string"
+	}
+	return "boolean"
+}
+
+// filterValue is the result of evaluating a filter node.
+type filterValue struct {
+	b bool
+	n float64
+	s string
+}
+
+// filterNode is a node of a parsed filter expression.
+type filterNode interface {
+	typ() filterType
+	eval(vars map[string]filterValue) filterValue
+}
+
+type filterLiteral struct {
+	t filterType
+	v filterValue
+}
+
+func (l filterLiteral) typ() filterType                         { return l.t }
+func (l filterLiteral) eval(map[string]filterValue) filterValue { return l.v }
+
+type filterVar struct {
+	name string
+	t    filterType
+}
+
+func (v filterVar) typ() filterType                              { return v.t }
+func (v filterVar) eval(vars map[string]filterValue) filterValue { return vars[v.name] }
+
+type filterNot struct{ x filterNode }
+
+func (n filterNot) typ() filterType { return filterBool }
+func (n filterNot) eval(vars map[string]filterValue) filterValue {
+	return filterValue{b: !n.x.eval(vars).b}
+}
+
+type filterLogic struct {
+	op   string
+	l, r filterNode
+}
+
+func (n filterLogic) typ() filterType { return filterBool }
+func (n filterLogic) eval(vars map[string]filterValue) filterValue {
+	l := n.l.eval(vars).b
+	if n.op == "&&" {
+		return filterValue{b: l && n.r.eval(vars).b}
+	}
+	return filterValue{b: l || n.r.eval(vars).b}
+}
+
+type filterCompare struct {
+	op   string
+	l, r filterNode
+	re   *regexp.Regexp // for =~ and !~
+}
+
+func (n filterCompare) typ() filterType { return filterBool }
+func (n filterCompare) eval(vars map[string]filterValue) filterValue {
+	l := n.l.eval(vars)
+	switch n.op {
+	case "=~":
+		return filterValue{b: n.re.MatchString(l.s)}
+	case "!~":
+		return filterValue{b: !n.re.MatchString(l.s)}
+	}
+	r := n.r.eval(vars)
+	var cmp int
+	switch n.l.typ() {
+	case filterNumber:
+		cmp = compareFloat(l.n, r.n)
+	case filterString:
+		cmp = strings.Compare(l.s, r.s)
+	default:
+		if l.b != r.b {
+			cmp = 1
+		}
+	}
+	switch n.op {
+	case "==":
+		return filterValue{b: cmp == 0}
+	case "!=":
+		return filterValue{b: cmp != 0}
+	case "<":
+		return filterValue{b: cmp < 0}
+	case "<=":
+		return filterValue{b: cmp <= 0}
+	case ">":
+		return filterValue{b: cmp > 0}
+	}
+	return filterValue{b: cmp >= 0}
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Filter is a compiled page filter expression, see ParseFilter.
+type Filter struct {
+	Expr string
+	root filterNode
+}
+
+// ParseFilter compiles an expression such as
+//
+//	status == 200 && words > 100 && path =~ "^/docs"
+//
+// Comparisons (==, !=, <, <=, >, >=) take two values of the same type,
+// =~ and !~ match a string against a regular expression literal, and
+// results combine with &&, || and ! and parentheses. Variables are listed by
+// FilterVarNames.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err == nil && root.typ() != filterBool {
+		err = fmt.Errorf("expression is a %s, not a condition", root.typ())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return &Filter{Expr: expr, root: root}, nil
+}
+
+// match evaluates the filter for a page.
+func (f *Filter) match(vars map[string]filterValue) bool {
+	return f.root.eval(vars).b
+}
+
+type filterTokenKind int
+
+const (
+	tokOp filterTokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string // operator, identifier or unquoted string
+	num  float64
+}
+
+// filterOps are the operators, longest first so "==" is not read as "=".
+var filterOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	rest := expr
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+
+		matched := false
+		for _, op := range filterOps {
+			if strings.HasPrefix(rest, op) {
+				tokens = append(tokens, filterToken{kind: tokOp, text: op})
+				rest = rest[len(op):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		c := rune(rest[0])
+		switch {
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated string at %q", rest)
+			}
+			text, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, filterToken{kind: tokString, text: text})
+			rest = rest[len(quoted):]
+		case unicode.IsDigit(c) || c == '-' || c == '.':
+			end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != '-' })
+			if end < 0 {
+				end = len(rest)
+			}
+			n, err := strconv.ParseFloat(rest[:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", rest[:end])
+			}
+			tokens = append(tokens, filterToken{kind: tokNumber, text: rest[:end], num: n})
+			rest = rest[end:]
+		case unicode.IsLetter(c) || c == '_':
+			end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' })
+			if end < 0 {
+				end = len(rest)
+			}
+			tokens = append(tokens, filterToken{kind: tokIdent, text: rest[:end]})
+			rest = rest[end:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(c))
+		}
+	}
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// peekOp returns the operator at the current position, or "".
+func (p *filterParser) peekOp() string {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokOp {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	return p.parseLogic("||", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	return p.parseLogic("&&", p.parseUnary)
+}
+
+func (p *filterParser) parseLogic(op string, operand func() (filterNode, error)) (filterNode, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekOp() == op {
+		p.pos++
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if l.typ() != filterBool || r.typ() != filterBool {
+			return nil, fmt.Errorf("%s needs conditions on both sides", op)
+		}
+		l = filterLogic{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peekOp() == "!" {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != filterBool {
+			return nil, fmt.Errorf("! needs a condition")
+		}
+		return filterNot{x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	l, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peekOp()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		return l, nil
+	}
+	p.pos++
+	r, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "=~" || op == "!~" {
+		lit, ok := r.(filterLiteral)
+		if l.typ() != filterString || !ok || lit.t != filterString {
+			return nil, fmt.Errorf("%s needs a string and a quoted regular expression", op)
+		}
+		re, err := regexp.Compile(lit.v.s)
+		if err != nil {
+			return nil, err
+		}
+		return filterCompare{op: op, l: l, r: r, re: re}, nil
+	}
+	if l.typ() != r.typ() {
+		return nil, fmt.Errorf("cannot compare %s with %s", l.typ(), r.typ())
+	}
+	if l.typ() == filterBool && op != "==" && op != "!=" {
+		return nil, fmt.Errorf("%s does not apply to conditions", op)
+	}
+	return filterCompare{op: op, l: l, r: r}, nil
+}
+
+func (p *filterParser) parseOperand() (filterNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokString:
+		return filterLiteral{t: filterString, v: filterValue{s: t.text}}, nil
+	case tokNumber:
+		return filterLiteral{t: filterNumber, v: filterValue{n: t.num}}, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return filterLiteral{t: filterBool, v: filterValue{b: t.text == "true"}}, nil
+		}
+		typ, ok := filterVars[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q (available: %s)", t.text, strings.Join(FilterVarNames(), ", "))
+		}
+		return filterVar{name: t.text, t: typ}, nil
+	}
+	if t.text == "(" {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peekOp() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// filterPageVars returns the filter variables of p, whose content has been
+// extracted.
+func (s *Scraper) filterPageVars(p *page) map[string]filterValue {
+	return map[string]filterValue{
+		"status":       {n: float64(p.Status)},
+		"words":        {n: float64(len(strings.Fields(s.searchText(p))))},
+		"bytes":        {n: float64(len(p.Body))},
+		"depth":        {n: float64(p.Depth)},
+		"url":          {s: p.URL.String()},
+		"host":         {s: p.URL.Host},
+		"path":         {s: p.URL.Path},
+		"query":        {s: p.URL.RawQuery},
+		"title":        {s: p.Title},
+		"content_type": {s: p.Header.Get("Content-Type")},
+	}
+}
+
+// filtered reports whether p is excluded by the Filter, logging why.
+func (s *Scraper) filtered(p *page) bool {
+	if s.Filter == nil || s.Filter.match(s.filterPageVars(p)) {
+		return false
+	}
+	s.logf("Skipping %s: does not match filter\n", p.URL)
+	s.event(event{Type: eventSkip, URL: p.URL.String(), Reason: "filter " + s.Filter.Expr})
+	return true
+}
@@ -0,0 +1,68 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/intro?lang=en")
+	p := &page{
+		URL:    u,
+		Status: 200,
+		Depth:  2,
+		Title:  "Introduction",
+		Header: http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:   []byte("<p>one two three</p>"),
+		Text:   "one two three",
+	}
+	s := NewScraper(true, false)
+	vars := s.filterPageVars(p)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`status == 200`, true},
+		{`status == 200 && words > 100`, false},
+		{`status == 200 && words >= 3 && path =~ "^/docs"`, true},
+		{`path !~ "^/docs" || title == "Introduction"`, true},
+		{`!(depth > 1)`, false},
+		{`(status < 300 || status == 304) && query == "lang=en"`, true},
+		{`content_type =~ "^text/html"`, true},
+		{`host != "example.com"`, false},
+		{`(bytes < 1) == false`, true},
+		{`title > "A" && title < "J"`, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) error = %v", tt.expr, err)
+		}
+		if got := f.match(vars); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`status`,
+		`status == "200"`,
+		`size > 10`,
+		`path =~ title`,
+		`path =~ "("`,
+		`status == 200 &&`,
+		`(status == 200`,
+		`status == 200)`,
+		`!status`,
+		`title == "open`,
+		`status = 200`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) accepted an invalid filter", expr)
+		}
+	}
+}
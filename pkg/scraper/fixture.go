@@ -0,0 +1,280 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Files and directories of a fixture.
+const (
+	fixtureSiteDir   = "pages"
+	fixtureFile      = "fixture.yaml"
+	fixtureGoldenDir = "golden"
+	// fixtureHost replaces the address of the fixture server in the golden
+	// files, which changes on every run.
+	fixtureHost = "fixture.test"
+)
+
+// fixtureHeaders are the manifest headers left out of the golden manifest,
+// as they follow the modification times of the site's files.
+var fixtureHeaders = []string{"Last-Modified", "ETag"}
+
+// Fixture is a known site kept next to the archive scrapdf is expected to
+// make of it, so changes to crawling, extraction and rendering show up as
+// differences from its golden files. A fixture directory holds:
+//
+//	pages/           the site, served from the root
+//	fixture.yaml     optional start path and scrape flags
+//	config.yaml      optional config file, see LoadConfig
+//	recipes/         optional site recipes
+//	golden/          the expected manifest.json and text/ of every page
+type Fixture struct {
+	// Dir is the fixture directory.
+	Dir string `yaml:"-"`
+	// Start is the path the crawl starts from, "/" by default.
+	Start string `yaml:"start"`
+	// Args are the scrape flags the site is crawled with.
+	Args []string `yaml:"args"`
+}
+
+// LoadFixture reads the fixture in dir.
+func LoadFixture(dir string) (*Fixture, error) {
+	info, err := os.Stat(filepath.Join(dir, fixtureSiteDir))
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s: not a fixture, it has no %s directory", dir, fixtureSiteDir)
+	}
+	f := &Fixture{}
+	data, err := os.ReadFile(filepath.Join(dir, fixtureFile))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	default:
+		if err := yaml.Unmarshal(data, f); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Join(dir, fixtureFile), err)
+		}
+	}
+	f.Dir = dir
+	if f.Start == "" {
+		f.Start = "/"
+	}
+	if !strings.HasPrefix(f.Start, "/") {
+		return nil, fmt.Errorf("%s: start %q must be a path starting with /", filepath.Join(dir, fixtureFile), f.Start)
+	}
+	return f, nil
+}
+
+// ConfigPath returns the fixture's config file, whether or not it exists.
+func (f *Fixture) ConfigPath() string {
+	return filepath.Join(f.Dir, "config.yaml")
+}
+
+// RecipesDir returns the fixture's recipe directory, whether or not it
+// exists.
+func (f *Fixture) RecipesDir() string {
+	return filepath.Join(f.Dir, "recipes")
+}
+
+// Serve starts a local server for the fixture's site. The caller closes it.
+func (f *Fixture) Serve() *httptest.Server {
+	return httptest.NewServer(http.FileServer(http.Dir(filepath.Join(f.Dir, fixtureSiteDir))))
+}
+
+// Crawl serves the fixture's site, archives it with s and returns the
+// golden files of the archive by their path under golden/. The archive is
+// always written as a directory, so s can't make a single PDF or an EPUB
+// book.
+func (f *Fixture) Crawl(s *Scraper) (map[string][]byte, error) {
+	switch {
+	case s.SinglePDF:
+		return nil, fmt.Errorf("a fixture is compared page by page, it can't be archived as a single PDF")
+	case s.Format == FormatEPUB:
+		return nil, fmt.Errorf("a fixture is compared page by page, it can't be archived as an EPUB book")
+	}
+	s.OutputMode = OutputDir
+	s.ArchiveFormat = ""
+
+	srv := f.Serve()
+	defer srv.Close()
+	tmp, err := os.MkdirTemp("", "scrapdf-fixture-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	out := filepath.Join(tmp, "archive")
+	if err := s.ScrapeAndSave(srv.URL+f.Start, out); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		return nil, err
+	}
+	return fixtureGoldens(os.DirFS(out), u.Host)
+}
+
+// fixtureGoldens returns the golden files of the archive in files: its
+// manifest, without the fetch and generation times and file dependent
+// headers, and
+// the text of every page. host is replaced with fixtureHost throughout.
+func fixtureGoldens(files fs.FS, host string) (map[string][]byte, error) {
+	normalize := func(s string) string {
+		return strings.ReplaceAll(s, host, fixtureHost)
+	}
+
+	data, err := fs.ReadFile(files, "manifest.json")
+	if err != nil {
+		return nil, fmt.Errorf("archive has no manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	goldens := make(map[string][]byte)
+	for i, p := range m.Pages {
+		// The page texts are compared instead of their sources' hashes
+		m.Pages[i].SHA256 = ""
+		m.Pages[i].FetchedAt = nil
+		for _, name := range fixtureHeaders {
+			delete(p.Headers, name)
+		}
+		if len(p.Headers) == 0 {
+			m.Pages[i].Headers = nil
+		}
+		data, err := fs.ReadFile(files, p.File)
+		if err != nil {
+			return nil, fmt.Errorf("archive lacks %s listed in its manifest: %w", p.File, err)
+		}
+		text := string(data)
+		if path.Ext(p.File) != ".md" {
+			text = pdfText(data)
+		}
+		name := path.Join("text", strings.TrimSuffix(p.File, path.Ext(p.File))+".txt")
+		goldens[normalize(name)] = []byte(normalize(strings.TrimRight(text, "\n") + "\n"))
+	}
+
+	// The golden manifest leaves out generated_at
+	data, err = json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "generated_at")
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	goldens["manifest.json"] = []byte(normalize(indented.String()))
+	return goldens, nil
+}
+
+// Compare returns how got differs from the fixture's golden files, one
+// description per file, sorted by name. None means the archive matches.
+func (f *Fixture) Compare(got map[string][]byte) ([]string, error) {
+	want, err := f.goldens()
+	if err != nil {
+		return nil, err
+	}
+	var diffs []string
+	for name, data := range got {
+		golden, ok := want[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: not in the golden files", name))
+		case !bytes.Equal(data, golden):
+			diffs = append(diffs, fmt.Sprintf("%s: %s", name, firstDifference(string(data), string(golden))))
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: missing from the archive", name))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs, nil
+}
+
+// Update replaces the fixture's golden files with got.
+func (f *Fixture) Update(got map[string][]byte) error {
+	dir := filepath.Join(f.Dir, fixtureGoldenDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear golden files: %w", err)
+	}
+	for name, data := range got {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return fmt.Errorf("failed to write golden file: %w", err)
+		}
+	}
+	return nil
+}
+
+// goldens reads the fixture's golden files by their slash separated path
+// under golden/. A fixture without golden files has none.
+func (f *Fixture) goldens() (map[string][]byte, error) {
+	dir := filepath.Join(f.Dir, fixtureGoldenDir)
+	goldens := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && file == dir {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		goldens[filepath.ToSlash(name)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden files: %w", err)
+	}
+	return goldens, nil
+}
+
+// firstDifference describes the first line where got and want differ.
+func firstDifference(got, want string) string {
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for i := 0; ; i++ {
+		switch {
+		case i >= len(gotLines):
+			return fmt.Sprintf("line %d: want %q, the archive ends", i+1, wantLines[i])
+		case i >= len(wantLines):
+			return fmt.Sprintf("line %d: got %q, the golden file ends", i+1, gotLines[i])
+		case gotLines[i] != wantLines[i]:
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, gotLines[i], wantLines[i])
+		}
+	}
+}
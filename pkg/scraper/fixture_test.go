@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFixture(t *testing.T) {
+	dir := t.TempDir()
+	pages := map[string]string{
+		"index.html":      `<html><head><title>Home</title></head><body><p>Welcome home.</p><a href="/docs/a.html">A</a></body></html>`,
+		"docs/a.html":     `<html><head><title>A</title></head><body><p>Page A.</p><a href="/">Back</a></body></html>`,
+		"docs/other.html": `<html><body><p>Not linked.</p></body></html>`,
+	}
+	for name, body := range pages {
+		file := filepath.Join(dir, fixtureSiteDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	crawl := func() map[string][]byte {
+		t.Helper()
+		f, err := LoadFixture(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.Crawl(NewScraper(true, false))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	f, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := crawl()
+	for _, name := range []string{"manifest.json", "text/index.txt", "text/docs/a.html.txt"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("golden %s missing, got %d files", name, len(got))
+		}
+	}
+	manifest := string(got["manifest.json"])
+	if !strings.Contains(manifest, `"url": "http://fixture.test/docs/a.html"`) || strings.Contains(manifest, "127.0.0.1") || strings.Contains(manifest, "generated_at") {
+		t.Errorf("manifest not normalized:\n%s", manifest)
+	}
+
+	// Without golden files every file is reported
+	diffs, err := f.Compare(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != len(got) {
+		t.Errorf("Compare() without goldens = %q", diffs)
+	}
+
+	if err := f.Update(got); err != nil {
+		t.Fatal(err)
+	}
+	// A second crawl, from another port, matches
+	if diffs, err := f.Compare(crawl()); err != nil || len(diffs) != 0 {
+		t.Fatalf("Compare() after Update = %q, %v", diffs, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, fixtureSiteDir, "docs", "a.html"), []byte(strings.Replace(pages["docs/a.html"], "Page A.", "Page B.", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err = f.Compare(crawl())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `text/docs/a.html.txt: line 1: got "APage B.", want "APage A."`
+	if len(diffs) != 1 || diffs[0] != want {
+		t.Errorf("Compare() = %q, want [%q]", diffs, want)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadFixture(dir); err == nil {
+		t.Error("LoadFixture() accepted a directory without pages")
+	}
+	if err := os.Mkdir(filepath.Join(dir, fixtureSiteDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := LoadFixture(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Start != "/" || f.Args != nil {
+		t.Errorf("LoadFixture() = %+v, want the defaults", f)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, fixtureFile), []byte("start: /docs/\nargs: [--strip]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = LoadFixture(dir); err != nil {
+		t.Fatal(err)
+	}
+	if f.Start != "/docs/" || len(f.Args) != 1 || f.Args[0] != "--strip" {
+		t.Errorf("LoadFixture() = %+v", f)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, fixtureFile), []byte("start: docs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixture(dir); err == nil {
+		t.Error("LoadFixture() accepted a start that isn't a path")
+	}
+}
@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// The text renderer embeds DejaVu fonts, which cover Latin, Greek and
+// Cyrillic scripts. See fonts/LICENSE.
+var (
+	//go:embed fonts/DejaVuSans.ttf
+	dejaVuSans []byte
+	//go:embed fonts/DejaVuSerif.ttf
+	dejaVuSerif []byte
+)
+
+// Font families registered in every PDF of the text renderer.
+const (
+	fontSans  = "DejaVuSans"
+	fontSerif = "DejaVuSerif"
+)
+
+// loadFont reads the TrueType font at Font, if set, and checks gofpdf can
+// use it, so a bad file fails the run up front.
+func (s *Scraper) loadFont() error {
+	if s.Font == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.Font)
+	if err != nil {
+		return fmt.Errorf("failed to read font: %w", err)
+	}
+	if err := checkFont(data); err != nil {
+		return fmt.Errorf("font %s: %w", s.Font, err)
+	}
+	s.fontData = data
+	return nil
+}
+
+// checkFont writes a sample with the font, as gofpdf reports most broken
+// fonts only when the document is output.
+func checkFont(data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("not a usable TrueType font: %v", r)
+		}
+	}()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(fontSans, "", data)
+	pdf.SetFont(fontSans, "", 12)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Sample")
+	return pdf.Output(io.Discard)
+}
+
+// addFonts registers the sans and serif families in pdf. A custom font
+// replaces both.
+func (s *Scraper) addFonts(pdf *gofpdf.Fpdf) error {
+	sans, serif := dejaVuSans, dejaVuSerif
+	if s.fontData != nil {
+		sans, serif = s.fontData, s.fontData
+	}
+	if err := addFont(pdf, fontSans, sans); err != nil {
+		return err
+	}
+	return addFont(pdf, fontSerif, serif)
+}
+
+// addFont registers a UTF-8 TrueType font under family. gofpdf panics on
+// some malformed files, which is reported as an error instead.
+func addFont(pdf *gofpdf.Fpdf, family string, data []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("not a usable TrueType font: %v", r)
+		}
+	}()
+	// gofpdf pads font tables in place when subsetting, so each PDF gets
+	// its own copy of the shared font data
+	pdf.AddUTF8FontFromBytes(family, "", append([]byte(nil), data...))
+	return pdf.Error()
+}
@@ -0,0 +1,47 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFont(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "serif.ttf")
+	if err := os.WriteFile(good, dejaVuSerif, 0644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.ttf")
+	if err := os.WriteFile(bad, []byte("not a font"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"embedded fonts", "", false},
+		{"custom font", good, false},
+		{"missing file", filepath.Join(dir, "missing.ttf"), true},
+		{"not a font", bad, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper(true, false)
+			s.Font = tt.path
+			err := s.loadFont()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadFont() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			file := filepath.Join(dir, tt.name+".pdf")
+			if err := s.createPDF(file, &page{Text: "Grüße, Привет"}); err != nil {
+				t.Fatalf("createPDF() error = %v", err)
+			}
+		})
+	}
+}
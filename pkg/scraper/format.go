@@ -0,0 +1,68 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Output formats accepted by Scraper.Format.
+const (
+	FormatPDF      = "pdf"
+	FormatMarkdown = "markdown"
+	// FormatEPUB writes the whole crawl to one EPUB book instead of a ZIP.
+	FormatEPUB = "epub"
+)
+
+// checkFormat validates the Format setting against the options that only
+// make sense for PDFs or for a ZIP archive.
+func (s *Scraper) checkFormat() error {
+	switch s.Format {
+	case "", FormatPDF, FormatMarkdown, FormatEPUB:
+	default:
+		return fmt.Errorf("unknown format %q (available: %s, %s, %s)", s.Format, FormatPDF, FormatMarkdown, FormatEPUB)
+	}
+	if s.FrontMatter != "" && s.Format != FormatMarkdown {
+		return fmt.Errorf("front matter is written to Markdown files, it needs the %s format", FormatMarkdown)
+	}
+	if s.Format == "" || s.Format == FormatPDF {
+		return nil
+	}
+	switch {
+	case s.SinglePDF:
+		return fmt.Errorf("the %s format can't be combined with a single PDF", s.Format)
+	case s.printsLivePage():
+		return fmt.Errorf("the %s format is converted from the page's HTML, it can't be combined with the %s renderer", s.Format, s.Renderer)
+	case s.Thumbnails:
+		return fmt.Errorf("thumbnails preview PDFs, they can't be combined with the %s format", s.Format)
+	case s.StampSource:
+		return fmt.Errorf("the source stamp is drawn on PDFs, it can't be combined with the %s format", s.Format)
+	}
+	if s.Format == FormatEPUB {
+		switch {
+		case s.SelfContained:
+			return fmt.Errorf("self-contained HTML copies need a ZIP archive, they can't be combined with the %s format", s.Format)
+		case s.Evidence:
+			return fmt.Errorf("evidence needs a ZIP archive, it can't be combined with the %s format", s.Format)
+		}
+	}
+	return nil
+}
+
+// pageEntryName returns the archive name of the file of the page at u in
+// the output format.
+func (s *Scraper) pageEntryName(u *url.URL) string {
+	switch s.Format {
+	case FormatMarkdown:
+		return strings.TrimSuffix(s.baseEntryName(u), ".pdf") + ".md"
+	case FormatEPUB:
+		return chapterEntryName(u)
+	}
+	return s.baseEntryName(u)
+}
+
+// convertsHTML reports whether pages are converted from their DOM instead
+// of being rendered from their text.
+func (s *Scraper) convertsHTML() bool {
+	return s.Format == FormatMarkdown || s.Format == FormatEPUB
+}
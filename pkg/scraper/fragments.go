@@ -0,0 +1,45 @@
+package scraper
+
+import (
+	"net/url"
+	"sort"
+)
+
+// stripFragment returns a copy of u without its fragment, so /docs#a and
+// /docs#b are fetched and deduplicated as a single page.
+func stripFragment(u *url.URL) *url.URL {
+	clean := *u
+	clean.Fragment = ""
+	clean.RawFragment = ""
+	return &clean
+}
+
+// recordFragment remembers that a link on base points at a fragment of
+// another (or the same) page. Fragments are dropped for fetching but kept
+// here so merged outputs can turn such links into intra-document links.
+func (s *Scraper) recordFragment(base *url.URL, href string) {
+	ref, err := url.Parse(href)
+	if err != nil || ref.Fragment == "" {
+		return
+	}
+	target := stripFragment(base.ResolveReference(ref)).String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fragments[target] == nil {
+		s.fragments[target] = make(map[string]bool)
+	}
+	s.fragments[target][ref.Fragment] = true
+}
+
+// linkedFragments returns the fragments of pageURL that other pages link to.
+func (s *Scraper) linkedFragments(pageURL string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fragments []string
+	for f := range s.fragments[pageURL] {
+		fragments = append(fragments, f)
+	}
+	sort.Strings(fragments)
+	return fragments
+}
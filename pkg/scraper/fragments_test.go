@@ -0,0 +1,35 @@
+package scraper
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestStripFragment(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/api?v=2#section-3")
+	if got := stripFragment(u).String(); got != "https://example.com/docs/api?v=2" {
+		t.Errorf("stripFragment() = %q", got)
+	}
+	if u.Fragment != "section-3" {
+		t.Errorf("stripFragment() modified its argument")
+	}
+}
+
+func TestRecordFragment(t *testing.T) {
+	s := NewScraper(false, false)
+	base, _ := url.Parse("https://example.com/docs/intro")
+
+	s.recordFragment(base, "/docs/api#section-3")
+	s.recordFragment(base, "api#auth")
+	s.recordFragment(base, "/docs/api#section-3")
+	s.recordFragment(base, "/docs/api")
+	s.recordFragment(base, "#top")
+
+	if got, want := s.linkedFragments("https://example.com/docs/api"), []string{"auth", "section-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("linkedFragments(api) = %v, want %v", got, want)
+	}
+	if got, want := s.linkedFragments("https://example.com/docs/intro"), []string{"top"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("linkedFragments(intro) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Front matter styles accepted by Scraper.FrontMatter.
+const (
+	FrontMatterHugo   = "hugo"
+	FrontMatterJekyll = "jekyll"
+)
+
+// hugoFrontMatter and jekyllFrontMatter are the YAML front matter of a page,
+// with the keys and date formats each generator reads.
+type hugoFrontMatter struct {
+	Title   string   `yaml:"title"`
+	Date    string   `yaml:"date"`
+	Lastmod string   `yaml:"lastmod,omitempty"`
+	Source  string   `yaml:"source_url"`
+	Tags    []string `yaml:"tags,omitempty"`
+}
+
+type jekyllFrontMatter struct {
+	Title          string   `yaml:"title"`
+	Date           string   `yaml:"date"`
+	LastModifiedAt string   `yaml:"last_modified_at,omitempty"`
+	Source         string   `yaml:"source_url"`
+	Tags           []string `yaml:"tags,omitempty"`
+}
+
+// validFrontMatter checks the FrontMatter setting.
+func validFrontMatter(style string) error {
+	switch style {
+	case "", FrontMatterHugo, FrontMatterJekyll:
+		return nil
+	}
+	return fmt.Errorf("unknown front matter style %q (available: %s, %s)", style, FrontMatterHugo, FrontMatterJekyll)
+}
+
+// frontMatter returns the front matter block of p in style, including its
+// "---" delimiters. The date is when the page was fetched, tags come from
+// the page's keywords and article tags.
+func (s *Scraper) frontMatter(p *page, style string) ([]byte, error) {
+	title := p.Title
+	if title == "" {
+		title = entryName(p.URL)
+	}
+	source := s.Redact(p.URL.String())
+	tags := pageTags(p.Body)
+	var lastModified time.Time
+	if lm := p.Header.Get("Last-Modified"); lm != "" {
+		lastModified, _ = http.ParseTime(lm)
+	}
+
+	var fields interface{}
+	switch style {
+	case FrontMatterHugo:
+		fm := hugoFrontMatter{
+			Title:  title,
+			Date:   p.FetchedAt.UTC().Format(time.RFC3339),
+			Source: source,
+			Tags:   tags,
+		}
+		if !lastModified.IsZero() {
+			fm.Lastmod = lastModified.UTC().Format(time.RFC3339)
+		}
+		fields = fm
+	case FrontMatterJekyll:
+		fm := jekyllFrontMatter{
+			Title:  title,
+			Date:   p.FetchedAt.UTC().Format("2006-01-02 15:04:05 -0700"),
+			Source: source,
+			Tags:   tags,
+		}
+		if !lastModified.IsZero() {
+			fm.LastModifiedAt = lastModified.UTC().Format("2006-01-02 15:04:05 -0700")
+		}
+		fields = fm
+	default:
+		return nil, validFrontMatter(style)
+	}
+
+	data, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("---\n" + string(data) + "---\n\n"), nil
+}
+
+// pageTags returns the page's tags: the comma separated keywords meta tag
+// and article:tag properties, deduplicated and sorted.
+func pageTags(body []byte) []string {
+	meta := metaTags(body)
+	seen := make(map[string]bool)
+	var tags []string
+	add := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, keywords := range meta["keywords"] {
+		for _, tag := range strings.Split(keywords, ",") {
+			add(tag)
+		}
+	}
+	for _, tag := range meta["article:tag"] {
+		add(tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
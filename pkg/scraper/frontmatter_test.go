@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFrontMatter(t *testing.T) {
+	u, _ := url.Parse("https://example.com/blog/post?token=hunter2")
+	p := &page{
+		URL:       u,
+		Title:     "A: post",
+		FetchedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		Header:    http.Header{"Last-Modified": {"Wed, 28 Feb 2024 08:00:00 GMT"}},
+		Body: []byte(`<html><head>
+<meta name="keywords" content="go, scraping,Go">
+<meta property="article:tag" content="archives">
+</head><body></body></html>`),
+	}
+	s := &Scraper{Secrets: []string{"hunter2"}}
+
+	tests := []struct {
+		style string
+		want  map[string]interface{}
+	}{
+		{FrontMatterHugo, map[string]interface{}{
+			"title":      "A: post",
+			"date":       "2024-03-01T12:30:00Z",
+			"lastmod":    "2024-02-28T08:00:00Z",
+			"source_url": "https://example.com/blog/post?token=REDACTED",
+		}},
+		{FrontMatterJekyll, map[string]interface{}{
+			"title":            "A: post",
+			"date":             "2024-03-01 12:30:00 +0000",
+			"last_modified_at": "2024-02-28 08:00:00 +0000",
+			"source_url":       "https://example.com/blog/post?token=REDACTED",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			data, err := s.frontMatter(p, tt.style)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := string(data)
+			if !strings.HasPrefix(text, "---\n") || !strings.HasSuffix(text, "---\n\n") {
+				t.Fatalf("front matter not delimited:\n%s", text)
+			}
+			var got map[string]interface{}
+			if err := yaml.Unmarshal([]byte(strings.Trim(text, "-\n")), &got); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+			tags, _ := got["tags"].([]interface{})
+			if len(tags) != 3 || tags[0] != "archives" || tags[1] != "go" || tags[2] != "scraping" {
+				t.Errorf("tags = %v, want [archives go scraping]", got["tags"])
+			}
+		})
+	}
+
+	if _, err := s.frontMatter(p, "gatsby"); err == nil {
+		t.Error("accepted an unknown style")
+	}
+}
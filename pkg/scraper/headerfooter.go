@@ -0,0 +1,122 @@
+package scraper
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pagePlaceholder matches the placeholders of Header and Footer templates.
+var pagePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// pagePlaceholders are the placeholders of Header and Footer templates.
+var pagePlaceholders = []string{"url", "title", "date", "page", "pages"}
+
+// totalPagesAlias stands for the number of pages of a text renderer PDF
+// until gofpdf writes the document and knows it.
+const totalPagesAlias = "{nb}"
+
+// checkPageTemplates validates the Header and Footer templates.
+func (s *Scraper) checkPageTemplates() error {
+	if s.Header == "" && s.Footer == "" {
+		return nil
+	}
+	if s.Format != "" && s.Format != FormatPDF {
+		return fmt.Errorf("headers and footers are drawn on PDFs, they can't be combined with the %s format", s.Format)
+	}
+	for _, tmpl := range []string{s.Header, s.Footer} {
+		for _, m := range pagePlaceholder.FindAllStringSubmatch(tmpl, -1) {
+			if !slices.Contains(pagePlaceholders, m[1]) {
+				return fmt.Errorf("unknown placeholder %s in %q (available: {%s})", m[0], tmpl, strings.Join(pagePlaceholders, "}, {"))
+			}
+		}
+	}
+	return nil
+}
+
+// expandTemplate fills the placeholders of tmpl for p. The page number and
+// count are given by the renderer.
+func (s *Scraper) expandTemplate(tmpl string, p *page, pageNo, pages string) string {
+	return pagePlaceholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch m {
+		case "{url}":
+			return s.Redact(p.URL.String())
+		case "{title}":
+			return p.Title
+		case "{date}":
+			return s.formatTime(p.FetchedAt, isoDateTime)
+		case "{page}":
+			return pageNo
+		case "{pages}":
+			return pages
+		}
+		return m
+	})
+}
+
+// drawHeaderFooter draws the Header and Footer templates on every page of
+// pdf for the page current returns, which changes along a single PDF. The
+// header takes the top of the text area, the footer is centred in the
+// bottom margin. Pages with no current page, such as a table of contents,
+// get neither.
+func (s *Scraper) drawHeaderFooter(pdf *gofpdf.Fpdf, current func() *page) {
+	if s.Header == "" && s.Footer == "" {
+		return
+	}
+	const lineHeight = 4.0
+	pdf.AliasNbPages(totalPagesAlias)
+	// gofpdf draws a footer when the next page is added, by which time
+	// current has moved on to the page starting there
+	var shown *page
+	line := func(tmpl string) {
+		left, _, right, _ := pdf.GetMargins()
+		pageWidth, _ := pdf.GetPageSize()
+		text := s.expandTemplate(tmpl, shown, fmt.Sprint(pdf.PageNo()), totalPagesAlias)
+		pdf.SetFont(fontSans, "", 8)
+		pdf.SetTextColor(96, 96, 96)
+		pdf.SetX(left)
+		pdf.CellFormat(pageWidth-left-right, lineHeight, fitText(pdf, text, pageWidth-left-right), "", 1, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.SetHeaderFuncMode(func() {
+		shown = current()
+		if s.Header != "" && shown != nil {
+			line(s.Header)
+			pdf.Ln(2)
+		}
+	}, false)
+	if s.Footer != "" {
+		pdf.SetFooterFunc(func() {
+			if shown == nil {
+				return
+			}
+			_, bottom := pdf.GetAutoPageBreak()
+			pdf.SetY(-(bottom + lineHeight) / 2)
+			line(s.Footer)
+		})
+	}
+}
+
+// chromeTemplates returns the Header and Footer templates of p as the HTML
+// Chrome prints in the page margins, or "" for the ones not set.
+func (s *Scraper) chromeTemplates(p *page) (header, footer string) {
+	convert := func(tmpl string) string {
+		if tmpl == "" {
+			// Chrome prints its own date and title without one
+			return "<span></span>"
+		}
+		text := html.EscapeString(s.expandTemplate(tmpl, p, "\x00page\x00", "\x00pages\x00"))
+		text = strings.ReplaceAll(text, "\x00pages\x00", `<span class="totalPages"></span>`)
+		text = strings.ReplaceAll(text, "\x00page\x00", `<span class="pageNumber"></span>`)
+		// Templates have no default font size and render at zero
+		return `<div style="font-size:8px;color:#606060;width:100%;text-align:center;margin:0 10mm">` + text + `</div>`
+	}
+	if s.Header == "" && s.Footer == "" {
+		return "", ""
+	}
+	return convert(s.Header), convert(s.Footer)
+}
@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderFooterText(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Header = "{title}"
+	s.Footer = "{url} — page {page}/{pages} — {date}"
+	s.StampSource = true
+	u, _ := url.Parse("https://example.com/guide?token=secret")
+	p := &page{
+		URL:       u,
+		Title:     "The Guide",
+		Status:    200,
+		FetchedAt: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		Text:      strings.Repeat("A line of the guide.\n", 120),
+	}
+	file := filepath.Join(t.TempDir(), "guide.pdf")
+	if err := s.createPDF(file, p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := pdfText(data)
+
+	pages := strings.Count(text, "The Guide")
+	if pages < 2 {
+		t.Fatalf("header printed %d times, want once per page\n%s", pages, text)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("https://example.com/guide?token=REDACTED — page 1/%d — 2024-05-01 09:30 UTC", pages),
+		fmt.Sprintf("page %d/%d", pages, pages),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF text lacks %q\n%s", want, text)
+		}
+	}
+	// The source stamp starts below the header
+	if header, stamp := strings.Index(text, "The Guide"), strings.Index(text, "Source: "); stamp < header {
+		t.Errorf("source stamp comes before the header\n%s", text)
+	}
+}
+
+func TestChromeTemplates(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Footer = "<{title}> page {page} of {pages}"
+	u, _ := url.Parse("https://example.com/")
+	header, footer := s.chromeTemplates(&page{URL: u, Title: "A & B"})
+	if header != "<span></span>" {
+		t.Errorf("header = %q, want an empty template", header)
+	}
+	want := `&lt;A &amp; B&gt; page <span class="pageNumber"></span> of <span class="totalPages"></span></div>`
+	if !strings.HasSuffix(footer, want) {
+		t.Errorf("footer = %q, want it to end with %q", footer, want)
+	}
+
+	s.Footer = ""
+	if header, footer := s.chromeTemplates(&page{URL: u}); header != "" || footer != "" {
+		t.Errorf("chromeTemplates() = %q, %q without templates, want none", header, footer)
+	}
+}
+
+func TestCheckPageTemplates(t *testing.T) {
+	tests := []struct {
+		header, footer string
+		format         string
+		wantErr        bool
+	}{
+		{"", "", FormatMarkdown, false},
+		{"{title}", "{url} {page}/{pages} {date}", "", false},
+		{"", "{url}", FormatPDF, false},
+		{"{author}", "", "", true},
+		{"", "{url}", FormatEPUB, true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.Header, s.Footer, s.Format = tt.header, tt.footer, tt.format
+		if err := s.checkPageTemplates(); (err != nil) != tt.wantErr {
+			t.Errorf("checkPageTemplates(%q, %q, %q) error = %v, want error %v", tt.header, tt.footer, tt.format, err, tt.wantErr)
+		}
+	}
+}
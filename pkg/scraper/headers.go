@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/gocolly/colly/v2"
+)
+
+// ParseHeader parses a request header given as "Name: value".
+func ParseHeader(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q, expected \"Name: value\"", s)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// ParseCookies parses cookies given as in a Cookie header, "name=value"
+// pairs separated by semicolons, as copied from a browser's developer tools.
+func ParseCookies(s string) ([]*http.Cookie, error) {
+	cookies, err := http.ParseCookie(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "Cookie:")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookies %q: %w", s, err)
+	}
+	return cookies, nil
+}
+
+// LoadCookieFile reads the cookies in path, either a cookies.txt file in
+// the Netscape format written by curl and browser extensions, or a Cookie
+// header. Cookies of other sites are kept; the collector only sends each
+// cookie to its domain.
+func LoadCookieFile(path string) ([]*http.Cookie, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	if !strings.Contains(string(data), "\t") {
+		return ParseCookies(strings.ReplaceAll(string(data), "\n", " "))
+	}
+
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: expected 7 tab separated fields, found %d", path, line, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry %q", path, line, fields[4])
+		}
+		c := &http.Cookie{
+			Domain:   strings.TrimPrefix(fields[0], "."),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Name:     fields[5],
+			Value:    fields[6],
+			HttpOnly: httpOnly,
+		}
+		if expires > 0 {
+			// Zero marks a session cookie
+			c.Expires = time.Unix(expires, 0)
+		}
+		cookies = append(cookies, c)
+	}
+	return cookies, scanner.Err()
+}
+
+// requestSecrets returns the values of Cookies and of sensitive Headers, and
+// the API key of the Embeddings, which are masked like other Secrets. Short
+// values would mask too much.
+func (s *Scraper) requestSecrets() []string {
+	var secrets []string
+	for name, values := range s.Headers {
+		if !isSensitiveName(name) {
+			continue
+		}
+		for _, v := range values {
+			if len(v) >= 4 {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	for _, c := range s.Cookies {
+		if len(c.Value) >= 4 {
+			secrets = append(secrets, c.Value)
+		}
+	}
+	if s.Embeddings != nil {
+		if key := s.Embeddings.apiKey(); len(key) >= 4 {
+			secrets = append(secrets, key)
+		}
+	}
+	return secrets
+}
+
+// setCookies adds Cookies to the collector's jar for the start URL. Cookies
+// without a path apply to the whole site.
+func (s *Scraper) setCookies(c *colly.Collector, start *url.URL) {
+	if len(s.Cookies) == 0 {
+		return
+	}
+	cookies := make([]*http.Cookie, 0, len(s.Cookies))
+	for _, cookie := range s.Cookies {
+		cookie := *cookie
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		cookies = append(cookies, &cookie)
+	}
+	c.SetCookies(start.String(), cookies)
+}
+
+// setHeaders replaces the headers of r with Headers when it goes to the
+// site of start, see sendsHeaders.
+func (s *Scraper) setHeaders(r *colly.Request, start *url.URL) {
+	if !s.sendsHeaders(start, r.URL) {
+		return
+	}
+	for name, values := range s.Headers {
+		r.Headers.Del(name)
+		for _, v := range values {
+			r.Headers.Add(name, v)
+		}
+	}
+}
+
+// sendsHeaders reports whether Headers go with a request for u. Like the
+// browser's, see sameOrigin, they are only sent to the crawled site and over
+// the scheme of start, so tokens reach neither other hosts, such as CDNs,
+// nor the network in the clear.
+func (s *Scraper) sendsHeaders(start, u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, start.Scheme) && s.sameSite(start, u)
+}
+
+// browserHeaders converts Headers for the headless browser.
+func (s *Scraper) browserHeaders() network.Headers {
+	if len(s.Headers) == 0 {
+		return nil
+	}
+	headers := make(network.Headers, len(s.Headers))
+	for name, values := range s.Headers {
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantName  string
+		wantValue string
+		wantErr   bool
+	}{
+		{"Authorization: Bearer abc", "Authorization", "Bearer abc", false},
+		{"x-api-key:abc:def", "X-Api-Key", "abc:def", false},
+		{"Accept-Language:", "Accept-Language", "", false},
+		{"no colon", "", "", true},
+		{": value", "", "", true},
+		{"Bad Name: value", "", "", true},
+	}
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHeader(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if name != tt.wantName || value != tt.wantValue {
+			t.Errorf("ParseHeader(%q) = %q, %q, want %q, %q", tt.in, name, value, tt.wantName, tt.wantValue)
+		}
+	}
+}
+
+func TestParseCookies(t *testing.T) {
+	cookies, err := ParseCookies("Cookie: sid=abc123; theme=dark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 2 || cookies[0].Name != "sid" || cookies[0].Value != "abc123" || cookies[1].Name != "theme" {
+		t.Errorf("ParseCookies() = %v", cookies)
+	}
+	if _, err := ParseCookies("novalue"); err == nil {
+		t.Error("parsed a cookie without a value")
+	}
+}
+
+func TestLoadCookieFile(t *testing.T) {
+	dir := t.TempDir()
+	netscape := filepath.Join(dir, "cookies.txt")
+	if err := os.WriteFile(netscape, []byte(`# Netscape HTTP Cookie File
+.example.com	TRUE	/	TRUE	0	sid	abc123
+#HttpOnly_docs.example.com	FALSE	/docs	FALSE	4102444800	csrf	xyz
+other.org	FALSE	/	FALSE	0	tracker	1
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := LoadCookieFile(netscape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cookies) != 3 {
+		t.Fatalf("loaded %d cookies, want 3", len(cookies))
+	}
+	if c := cookies[0]; c.Domain != "example.com" || !c.Secure || !c.Expires.IsZero() || c.Value != "abc123" {
+		t.Errorf("session cookie = %+v", c)
+	}
+	if c := cookies[1]; !c.HttpOnly || c.Path != "/docs" || c.Expires.Year() != 2100 {
+		t.Errorf("HttpOnly cookie = %+v", c)
+	}
+
+	header := filepath.Join(dir, "cookie-header")
+	if err := os.WriteFile(header, []byte("sid=abc123; theme=dark\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cookies, err := LoadCookieFile(header); err != nil || len(cookies) != 2 {
+		t.Errorf("LoadCookieFile(header) = %v, %v", cookies, err)
+	}
+
+	broken := filepath.Join(dir, "broken.txt")
+	if err := os.WriteFile(broken, []byte("example.com\tTRUE\t/\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCookieFile(broken); err == nil {
+		t.Error("loaded a cookie with missing fields")
+	}
+}
+
+func TestRequestCredentials(t *testing.T) {
+	s := &Scraper{
+		Headers: http.Header{"Authorization": {"Bearer abc"}, "X-Team": {"docs"}},
+		Cookies: []*http.Cookie{
+			{Name: "sid", Value: "abc123"},
+			{Name: "tracker", Value: "1", Domain: "other.org"},
+		},
+	}
+
+	start, _ := url.Parse("https://example.com/docs/intro")
+	page, _ := url.Parse("https://example.com/docs/setup")
+	r := &colly.Request{URL: page, Headers: &http.Header{"Authorization": {"old"}, "Accept": {"*/*"}}}
+	s.setHeaders(r, start)
+	if got := r.Headers.Values("Authorization"); len(got) != 1 || got[0] != "Bearer abc" {
+		t.Errorf("Authorization = %v", got)
+	}
+	if r.Headers.Get("X-Team") != "docs" || r.Headers.Get("Accept") != "*/*" {
+		t.Errorf("headers = %v", *r.Headers)
+	}
+
+	// Requests leaving the site, or its scheme, go without them
+	for _, raw := range []string{"https://cdn.example.net/app.js", "http://example.com/docs/setup", "https://example.com:8443/docs/setup"} {
+		offsite, _ := url.Parse(raw)
+		r := &colly.Request{URL: offsite, Headers: &http.Header{"Accept": {"*/*"}}}
+		s.setHeaders(r, start)
+		if r.Headers.Get("Authorization") != "" || r.Headers.Get("X-Team") != "" {
+			t.Errorf("headers sent to %s: %v", raw, *r.Headers)
+		}
+	}
+
+	c := colly.NewCollector()
+	s.setCookies(c, start)
+	got := c.Cookies("https://example.com/blog/post")
+	if len(got) != 1 || got[0].Name != "sid" {
+		t.Errorf("cookies sent to the site = %v", got)
+	}
+	if s.Cookies[0].Path != "" {
+		t.Error("setCookies changed the configured cookie")
+	}
+
+	secrets := s.requestSecrets()
+	if len(secrets) != 2 || !contains(secrets, "Bearer abc") || !contains(secrets, "abc123") {
+		t.Errorf("requestSecrets() = %v", secrets)
+	}
+}
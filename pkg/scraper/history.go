@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Run is one scrape recorded in the run history.
+type Run struct {
+	ID         int       `json:"id"`
+	Site       string    `json:"site"`
+	StartURL   string    `json:"start_url"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Archive is the absolute path of the ZIP, PDF or directory written by
+	// the run.
+	Archive  string `json:"archive,omitempty"`
+	Bytes    int64  `json:"bytes,omitempty"`
+	Pages    int    `json:"pages"`
+	Failures int    `json:"failures"`
+	// Error is set when the run did not produce an archive.
+	Error string `json:"error,omitempty"`
+	// Pruned is set once the archive was deleted by a retention policy.
+	Pruned bool `json:"pruned,omitempty"`
+}
+
+// History is the run history, a JSON file listing every run. Scrapes
+// running at the same time, e.g. watch modes of several sites, take turns
+// updating it, see lock. It holds a record per run, so reading it whole is
+// cheap, and unlike SQLite it keeps scrapdf a pure Go, cross-compiled binary.
+type History struct {
+	Path string
+}
+
+// historyLockWait is how long to wait for another scrape updating the
+// history, and historyLockStale how old its lock file can get before it is
+// taken as left behind by a scrape that crashed.
+const (
+	historyLockWait  = 30 * time.Second
+	historyLockStale = 2 * time.Minute
+)
+
+// lock takes the lock file next to the history, so the run histories read
+// and written by concurrent scrapes don't overwrite each other's runs, and
+// returns the func releasing it.
+func (h *History) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create run history directory: %w", err)
+	}
+	name := h.Path + ".lock"
+	deadline := time.Now().Add(historyLockWait)
+	for {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock run history: %w", err)
+		}
+		if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > historyLockStale {
+			os.Remove(name)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("run history is locked by another scrape, remove %s if none is running", name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// DefaultHistoryPath returns the file the run history is kept in.
+func DefaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scrapdf", "runs.json")
+}
+
+// Runs returns the recorded runs, oldest first.
+func (h *History) Runs() ([]Run, error) {
+	data, err := os.ReadFile(h.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+	var runs []Run
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("failed to parse run history %s: %w", h.Path, err)
+	}
+	return runs, nil
+}
+
+// Run returns the run with the given ID.
+func (h *History) Run(id int) (Run, error) {
+	runs, err := h.Runs()
+	if err != nil {
+		return Run{}, err
+	}
+	for _, r := range runs {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Run{}, fmt.Errorf("no run %d in the history", id)
+}
+
+// Add records run with the next free ID and returns it.
+func (h *History) Add(run Run) (Run, error) {
+	unlock, err := h.lock()
+	if err != nil {
+		return run, err
+	}
+	defer unlock()
+	runs, err := h.Runs()
+	if err != nil {
+		return run, err
+	}
+	run.ID = 1
+	if len(runs) > 0 {
+		run.ID = runs[len(runs)-1].ID + 1
+	}
+	return run, h.write(append(runs, run))
+}
+
+// Prune deletes the archives of site's runs beyond the keep most recently
+// finished ones, or of every site when site is empty, and marks those runs
+// pruned. Interrupted runs count like the others, as they leave an archive
+// too. Archives still used by a kept run, e.g. when runs overwrite the same
+// file, are not deleted. It returns the pruned runs.
+func (h *History) Prune(site string, keep int) ([]Run, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("cannot keep %d archives", keep)
+	}
+	unlock, err := h.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	runs, err := h.Runs()
+	if err != nil {
+		return nil, err
+	}
+
+	bySite := make(map[string][]int) // indexes of runs with an archive, last added first
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		if r.Archive == "" || r.Pruned || (site != "" && r.Site != site) {
+			continue
+		}
+		bySite[r.Site] = append(bySite[r.Site], i)
+	}
+
+	kept := make(map[string]bool)
+	var expired []int
+	for _, indexes := range bySite {
+		// Newest first, by when the runs finished
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return runs[indexes[a]].FinishedAt.After(runs[indexes[b]].FinishedAt)
+		})
+		for n, i := range indexes {
+			if n < keep {
+				kept[runs[i].Archive] = true
+			} else {
+				expired = append(expired, i)
+			}
+		}
+	}
+	sort.Ints(expired)
+
+	var pruned []Run
+	for _, i := range expired {
+		if !kept[runs[i].Archive] {
+			// Output directories go with everything in them
+			if err := os.RemoveAll(runs[i].Archive); err != nil {
+				return pruned, fmt.Errorf("failed to delete archive of run %d: %w", runs[i].ID, err)
+			}
+		}
+		runs[i].Pruned = true
+		pruned = append(pruned, runs[i])
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+	return pruned, h.write(runs)
+}
+
+// write replaces the history file with runs.
+func (h *History) write(runs []Run) error {
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := h.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run history: %w", err)
+	}
+	return os.Rename(tmp, h.Path)
+}
+
+// Failure is a page the last crawl failed to fetch or convert, and the
+// Stage it failed at.
+type Failure struct {
+	URL   string `json:"url"`
+	Stage string `json:"stage"`
+	// Status is the HTTP status of a page that failed to fetch, if the
+	// server answered.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Failures returns the pages the last crawl failed on, credentials masked.
+func (s *Scraper) Failures() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failures := make([]Failure, 0, len(s.failures))
+	for _, f := range s.failures {
+		failures = append(failures, Failure(f))
+	}
+	return failures
+}
+
+// Stats returns how many pages the last crawl archived and how many failed.
+func (s *Scraper) Stats() (pages, failures int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pdfs), len(s.failures)
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHistoryPrune(t *testing.T) {
+	dir := t.TempDir()
+	h := &History{Path: filepath.Join(dir, "runs.json")}
+
+	archive := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("zip"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	runs := []Run{
+		{Site: "a.example", Archive: archive("a-1.zip")},
+		{Site: "b.example", Archive: archive("b.zip")},
+		{Site: "a.example", Archive: archive("a-2.zip")},
+		{Site: "a.example", Error: "no pages were successfully scraped"},
+		{Site: "a.example", Archive: archive("a-3.zip")},
+		{Site: "b.example", Archive: filepath.Join(dir, "b.zip")},
+	}
+	for i, r := range runs {
+		added, err := h.Add(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added.ID != i+1 {
+			t.Errorf("run %d got ID %d", i+1, added.ID)
+		}
+	}
+
+	pruned, err := h.Prune("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, r := range pruned {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("pruned runs %v, want [1 2 3]", ids)
+	}
+
+	for name, want := range map[string]bool{"a-1.zip": false, "a-2.zip": false, "a-3.zip": true, "b.zip": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+
+	r, err := h.Run(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Pruned {
+		t.Error("run 1 not marked pruned")
+	}
+	if again, err := h.Prune("", 1); err != nil || len(again) != 0 {
+		t.Errorf("second prune = %v, %v", again, err)
+	}
+}
+
+func TestHistoryPruneInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	h := &History{Path: filepath.Join(dir, "runs.json")}
+
+	finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var archives []string
+	for i, failed := range []string{"", "interrupted", "interrupted", ""} {
+		archive := filepath.Join(dir, fmt.Sprintf("a-%d.zip", i+1))
+		if err := os.WriteFile(archive, []byte("zip"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, archive)
+		run := Run{Site: "a.example", Archive: archive, Error: failed, FinishedAt: finished.Add(time.Duration(i) * time.Hour)}
+		if i == 3 {
+			// Added last, but finished before the interrupted runs
+			run.FinishedAt = finished.Add(-time.Hour)
+		}
+		if _, err := h.Add(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := h.Prune("a.example", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, r := range pruned {
+		ids = append(ids, r.ID)
+	}
+	// The interrupted runs are the two newest archives
+	if !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Errorf("pruned runs %v, want [1 4]", ids)
+	}
+	for i, archive := range archives {
+		_, err := os.Stat(archive)
+		if exists, want := err == nil, i == 1 || i == 2; exists != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(archive), exists, want)
+		}
+	}
+}
+
+func TestHistoryConcurrentAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scrapdf", "runs.json")
+	const scrapes = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, scrapes)
+	for range scrapes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each scrape has a History of its own
+			_, err := (&History{Path: path}).Add(Run{Site: "example.com"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := (&History{Path: path}).Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != scrapes {
+		t.Errorf("got %d runs, want %d", len(runs), scrapes)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+
+	// A lock left by a scrape that crashed is taken over
+	if err := os.WriteFile(path+".lock", nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-historyLockStale - time.Minute)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&History{Path: path}).Add(Run{Site: "example.com"}); err != nil {
+		t.Errorf("Add() with a stale lock = %v", err)
+	}
+}
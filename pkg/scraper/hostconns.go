@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// hostLimitTransport lets at most max requests to the same host be in
+// flight, each holding its slot until its body is closed, so many workers
+// can fetch and render while the origin only sees a few connections.
+type hostLimitTransport struct {
+	base  http.RoundTripper
+	max   int
+	mu    sync.Mutex
+	slots map[string]chan struct{} // map[host]held slots
+}
+
+func newHostLimitTransport(base http.RoundTripper, max int) *hostLimitTransport {
+	return &hostLimitTransport{base: base, max: max, slots: make(map[string]chan struct{})}
+}
+
+// hostSlots returns the slots of host.
+func (t *hostLimitTransport) hostSlots(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	host = strings.ToLower(host)
+	slots, ok := t.slots[host]
+	if !ok {
+		slots = make(chan struct{}, t.max)
+		t.slots[host] = slots
+	}
+	return slots
+}
+
+func (t *hostLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slots := t.hostSlots(req.URL.Host)
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func(bool, string) { <-slots }
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release(true, "")
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
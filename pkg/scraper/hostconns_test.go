@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimitTransport(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newHostLimitTransport(http.DefaultTransport, 2)}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak requests in flight = %d, want 2", got)
+	}
+}
+
+func TestHostLimitTransportPerHost(t *testing.T) {
+	tr := newHostLimitTransport(http.DefaultTransport, 1)
+	a, b := tr.hostSlots("a.example.com"), tr.hostSlots("B.example.com")
+	a <- struct{}{}
+	select {
+	case b <- struct{}{}:
+	default:
+		t.Error("a request to one host holds the slot of another")
+	}
+	if tr.hostSlots("A.EXAMPLE.COM") != a {
+		t.Error("host names are compared case sensitively")
+	}
+}
@@ -0,0 +1,230 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+// pageLink is a piece of a page's text inside a link, in the order the text
+// renderer lays it out.
+type pageLink struct {
+	Text string
+	URL  string // absolute
+}
+
+// linkSpan places a pageLink in a line of the page's text.
+type linkSpan struct {
+	start, end int // byte offsets in the line trimmed of spaces
+	url        string
+}
+
+// pageLinks returns the text of the links below doc that lead somewhere a
+// reader can follow, relative to base, a piece per line of the text.
+func pageLinks(doc *html.Node, base *url.URL) []pageLink {
+	var links []pageLink
+	var walk func(n *html.Node, target string)
+	walk = func(n *html.Node, target string) {
+		switch n.Type {
+		case html.CommentNode:
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "meta", "link", "noscript":
+				return
+			case "a":
+				if t := linkTarget(n, base); t != "" {
+					target = t
+				}
+			}
+		case html.TextNode:
+			if target == "" {
+				return
+			}
+			for _, line := range strings.Split(n.Data, "\n") {
+				if text := strings.TrimSpace(line); text != "" {
+					links = append(links, pageLink{Text: text, URL: target})
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, target)
+		}
+	}
+	walk(doc, "")
+	return links
+}
+
+// pageAnchor is an element of a page that links can point at, placed at
+// the first line of text at or after it.
+type pageAnchor struct {
+	Text string
+	URL  string // of the page, with the element's id as fragment
+}
+
+// pageAnchors returns the elements below doc with an id, or the a elements
+// with a name, that have text at or after them, in document order, for
+// the page at pageURL.
+func pageAnchors(doc *html.Node, pageURL *url.URL) []pageAnchor {
+	base := stripFragment(pageURL).String()
+	var anchors []pageAnchor
+	var pending []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.CommentNode:
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "meta", "link", "noscript":
+				return
+			}
+			id := getAttr(n, "id")
+			if id == "" && n.Data == "a" {
+				id = getAttr(n, "name")
+			}
+			if id != "" {
+				pending = append(pending, id)
+			}
+		case html.TextNode:
+			if len(pending) == 0 {
+				return
+			}
+			for _, line := range strings.Split(n.Data, "\n") {
+				if text := strings.TrimSpace(line); text != "" {
+					for _, id := range pending {
+						anchors = append(anchors, pageAnchor{Text: text, URL: base + "#" + id})
+					}
+					pending = nil
+					return
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return anchors
+}
+
+// placeAnchors finds the anchors in the lines of a page's text like
+// placeLinks finds links, and returns their URLs by line.
+func placeAnchors(lines []string, anchors []pageAnchor) [][]string {
+	links := make([]pageLink, len(anchors))
+	for i, a := range anchors {
+		links[i] = pageLink{Text: a.Text, URL: a.URL}
+	}
+	spans := placeLinks(lines, links)
+	if spans == nil {
+		return nil
+	}
+	placed := make([][]string, len(lines))
+	for i, line := range spans {
+		for _, span := range line {
+			placed[i] = append(placed[i], span.url)
+		}
+	}
+	return placed
+}
+
+// docLink returns the internal link of the single PDF being written to
+// target, the element or else the page it points at, if it is in the
+// document.
+func (s *Scraper) docLink(target string) (int, bool) {
+	if s.docLinks == nil {
+		return 0, false
+	}
+	if link, ok := s.docLinks[target]; ok {
+		return link, true
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return 0, false
+	}
+	link, ok := s.docLinks[stripFragment(u).String()]
+	return link, ok
+}
+
+// linkTarget returns the absolute target of the link a, empty for links
+// that only work on the live page, such as javascript: ones.
+func linkTarget(a *html.Node, base *url.URL) string {
+	href := strings.TrimSpace(getAttr(a, "href"))
+	if href == "" {
+		return ""
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return u.String()
+	}
+	return ""
+}
+
+// placeLinks finds the links in the lines of a page's text, in order, and
+// returns their spans by line. A link whose text is no longer there, left
+// out by a filter or redacted, is skipped.
+func placeLinks(lines []string, links []pageLink) [][]linkSpan {
+	if len(links) == 0 {
+		return nil
+	}
+	spans := make([][]linkSpan, len(lines))
+	line, col := 0, 0
+	for _, l := range links {
+		for i, from := line, col; i < len(lines); i, from = i+1, 0 {
+			text := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(text, imageMarker) || from > len(text) {
+				continue
+			}
+			if at := strings.Index(text[from:], l.Text); at >= 0 {
+				start := from + at
+				spans[i] = append(spans[i], linkSpan{start: start, end: start + len(l.Text), url: l.URL})
+				line, col = i, start+len(l.Text)
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// writeLinkedLine writes a line of text with its links clickable from the
+// current position of pdf, followed by their targets in parentheses with
+// LinkURLs, and moves to the next line. Links to targets matching a
+// RedactContent rule are written as plain text, and links to pages in the
+// single PDF being written jump to them.
+func (s *Scraper) writeLinkedLine(pdf *gofpdf.Fpdf, line string, spans []linkSpan, lineHeight float64) {
+	at := 0
+	for i, span := range spans {
+		pdf.Write(lineHeight, line[at:span.start])
+		at = span.end
+		target := s.Redact(span.url)
+		if s.redactContent(target) != target {
+			pdf.Write(lineHeight, line[span.start:span.end])
+			continue
+		}
+		if link, ok := s.docLink(span.url); ok {
+			pdf.WriteLinkID(lineHeight, line[span.start:span.end], link)
+		} else {
+			pdf.WriteLinkString(lineHeight, line[span.start:span.end], target)
+		}
+		// The pieces of a link's text are spans of their own
+		last := i+1 == len(spans) || spans[i+1].url != span.url || strings.TrimSpace(line[at:spans[i+1].start]) != ""
+		if s.LinkURLs && last && !shownTarget(line[span.start:span.end], target) {
+			pdf.Write(lineHeight, " ("+target+")")
+		}
+	}
+	pdf.Write(lineHeight, line[at:])
+	pdf.Ln(lineHeight)
+}
+
+// shownTarget reports whether a link's text already reads as its target.
+func shownTarget(text, target string) bool {
+	text = strings.TrimSuffix(text, "/")
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "mailto:"), "/")
+	return text == target || strings.TrimPrefix(strings.TrimPrefix(target, "https://"), "http://") == text
+}
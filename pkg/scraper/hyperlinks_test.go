@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestPageLinks(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<p>See <a href="/guide">the <b>guide</b></a> and <a href="mailto:team@example.com">team@example.com</a>.</p>
+<a href="#top">Top</a> <a href="javascript:void(0)">Menu</a> <a>Anchor</a>
+<li><a href="https://other.example/a">Multi
+  line</a></li><script><a href="/x">x</a></script>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := url.Parse("https://example.com/docs/intro")
+	want := []pageLink{
+		{"the", "https://example.com/guide"},
+		{"guide", "https://example.com/guide"},
+		{"team@example.com", "mailto:team@example.com"},
+		{"Top", "https://example.com/docs/intro#top"},
+		{"Multi", "https://other.example/a"},
+		{"line", "https://other.example/a"},
+	}
+	if got := pageLinks(doc, base); !reflect.DeepEqual(got, want) {
+		t.Errorf("pageLinks() = %v, want %v", got, want)
+	}
+}
+
+func TestPlaceLinks(t *testing.T) {
+	lines := []string{"  Intro to the guide  ", imageMarker + "guide.png", "", "guide and guide", "end"}
+	links := []pageLink{
+		{"guide", "https://example.com/1"},
+		{"redacted", "https://example.com/2"},
+		{"guide", "https://example.com/3"},
+		{"guide", "https://example.com/4"},
+	}
+	want := [][]linkSpan{
+		{{13, 18, "https://example.com/1"}},
+		nil,
+		nil,
+		{{0, 5, "https://example.com/3"}, {10, 15, "https://example.com/4"}},
+		nil,
+	}
+	if got := placeLinks(lines, links); !reflect.DeepEqual(got, want) {
+		t.Errorf("placeLinks() = %v, want %v", got, want)
+	}
+	if got := placeLinks(lines, nil); got != nil {
+		t.Errorf("placeLinks() without links = %v", got)
+	}
+}
+
+func TestWriteLinks(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/intro")
+	p := &page{
+		URL:  u,
+		Text: "Read the guide first.\n\nhttps://example.com/faq\n",
+		Links: []pageLink{
+			{"guide", "https://example.com/guide"},
+			{"https://example.com/faq", "https://example.com/faq"},
+		},
+	}
+	tests := []struct {
+		name     string
+		linkURLs bool
+		want     string
+	}{
+		{"links", false, "Read the \nguide\n first."},
+		{"link URLs", true, "Read the \nguide\n (https://example.com/guide)\n first."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scraper{stripHTML: true, LinkURLs: tt.linkURLs}
+			file := filepath.Join(t.TempDir(), "page.pdf")
+			if err := s.createPDF(file, p); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, target := range []string{"https://example.com/guide", "https://example.com/faq"} {
+				if !bytes.Contains(data, []byte("/URI ("+target+")")) {
+					t.Errorf("no link to %s", target)
+				}
+			}
+			text := pdfText(data)
+			if !strings.Contains(text, tt.want) {
+				t.Errorf("text %q, want %q", text, tt.want)
+			}
+			// A link reading as its target isn't followed by it
+			if strings.Contains(text, "(https://example.com/faq)") {
+				t.Errorf("text %q repeats the target of a bare URL", text)
+			}
+		})
+	}
+
+	// Targets a redaction rule matches are neither linked nor shown
+	s := &Scraper{stripHTML: true, LinkURLs: true, RedactContent: []*regexp.Regexp{regexp.MustCompile(`example\.com/guide`)}}
+	file := filepath.Join(t.TempDir(), "page.pdf")
+	if err := s.createPDF(file, p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("example.com/guide")) || strings.Contains(pdfText(data), "example.com/guide") {
+		t.Error("redacted link target written to the PDF")
+	}
+	if !bytes.Contains(data, []byte("/URI (https://example.com/faq)")) {
+		t.Error("link not matching the rules was dropped")
+	}
+}
+
+func TestPageAnchors(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<h2 id="install">Install
+the tool</h2><a name="run"></a><p>Run it</p><div id="empty"></div><script id="x">x</script>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/guide#top")
+	want := []pageAnchor{
+		{"Install", "https://example.com/guide#install"},
+		{"Run it", "https://example.com/guide#run"},
+	}
+	if got := pageAnchors(doc, u); !reflect.DeepEqual(got, want) {
+		t.Errorf("pageAnchors() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+// imageMarker starts the lines of extracted text that stand for an image,
+// followed by its src. U+FFFC is Unicode's placeholder for embedded objects.
+const imageMarker = "\uFFFC"
+
+// minImageSize is the smallest width and height in pixels of an embedded
+// image; smaller ones are spacers and tracking pixels.
+const minImageSize = 3
+
+// imgTagPattern matches an img tag in the raw HTML of a page.
+var imgTagPattern = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+
+// lazySrcAttrs hold the real src of lazily loaded images, whose src is a
+// placeholder until a script swaps them in, most common first.
+var lazySrcAttrs = []string{"data-src", "data-lazy-src", "data-original"}
+
+// imageTargetWidth is the width in pixels preferred from a srcset: twice
+// the text column at 96 dpi, sharp in print without fetching the largest
+// candidate.
+const imageTargetWidth = 1280
+
+// pdfImage is an image fetched for PDFs, kept in a file of the work
+// directory so images shared by many pages are downloaded once.
+type pdfImage struct {
+	File          string
+	Type          string // gofpdf image type, JPG or PNG
+	Width, Height int    // pixels
+}
+
+// embedsImages reports whether images are placed in the PDFs.
+func (s *Scraper) embedsImages() bool {
+	return !s.NoImages && (s.Format == "" || s.Format == FormatPDF)
+}
+
+// imageLines returns the images referenced by a line of page text: the src
+// after an image marker, or those of the img tags of a line of raw HTML.
+func imageLines(line string, raw bool) []string {
+	if raw {
+		var srcs []string
+		for _, tag := range imgTagPattern.FindAllString(line, -1) {
+			z := html.NewTokenizer(strings.NewReader(tag))
+			z.Next()
+			img := &html.Node{Type: html.ElementNode, Data: "img", Attr: z.Token().Attr}
+			if src := imageSource(img); src != "" {
+				srcs = append(srcs, src)
+			}
+		}
+		return srcs
+	}
+	if src, ok := strings.CutPrefix(line, imageMarker); ok {
+		return []string{strings.TrimSpace(src)}
+	}
+	return nil
+}
+
+// imageSource returns the source of the img element n: the best srcset
+// candidate, then the src of a lazy loader, then src. Placeholder data URIs
+// are skipped when a real source is set.
+func imageSource(n *html.Node) string {
+	for _, key := range []string{"data-srcset", "srcset"} {
+		if src := pickSrcset(getAttr(n, key)); src != "" {
+			return src
+		}
+	}
+	var placeholder string
+	for _, key := range append(lazySrcAttrs, "src") {
+		src := strings.TrimSpace(getAttr(n, key))
+		switch {
+		case src == "" || strings.ContainsAny(src, "\n\r"):
+		case strings.HasPrefix(src, "data:"):
+			if placeholder == "" {
+				placeholder = src
+			}
+		default:
+			return src
+		}
+	}
+	return placeholder
+}
+
+// pickSrcset returns the URL of the srcset candidate nearest to
+// imageTargetWidth: the narrowest at least that wide, otherwise the widest.
+// Pixel density descriptors count as multiples of half the target, so 2x is
+// preferred. Data URIs are skipped, they are lazy loading placeholders.
+func pickSrcset(srcset string) string {
+	var best string
+	var bestWidth float64
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "data:") {
+			continue
+		}
+		width := float64(imageTargetWidth / 2)
+		if len(fields) > 1 {
+			d := fields[1]
+			v, err := strconv.ParseFloat(d[:len(d)-1], 64)
+			if err != nil || v <= 0 {
+				continue
+			}
+			switch d[len(d)-1] {
+			case 'w':
+				width = v
+			case 'x':
+				width = v * imageTargetWidth / 2
+			default:
+				continue
+			}
+		}
+		better := best == "" ||
+			(width >= imageTargetWidth && (bestWidth < imageTargetWidth || width < bestWidth)) ||
+			(bestWidth < imageTargetWidth && width > bestWidth)
+		if better {
+			best, bestWidth = fields[0], width
+		}
+	}
+	return best
+}
+
+// stripImageMarkers removes the lines standing for images from text.
+func stripImageMarkers(text string) string {
+	if !strings.Contains(text, imageMarker) {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), imageMarker) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// writeImage places the image src, relative to base, at the current
+// position of pdf, scaled down to fit the page. Images that can't be
+// fetched or decoded are left out.
+func (s *Scraper) writeImage(pdf *gofpdf.Fpdf, src string, base *url.URL) {
+	if s.assets == nil {
+		return
+	}
+	var img *pdfImage
+	if strings.HasPrefix(src, "data:") {
+		img = s.assets.pdfImage(src)
+	} else if u, err := base.Parse(src); err == nil && (u.Scheme == "http" || u.Scheme == "https" || (u.Scheme == "file" && s.localRoot != "")) {
+		img = s.assets.pdfImage(u.String())
+	}
+	if img == nil {
+		return
+	}
+	f, err := os.Open(img.File)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	info := pdf.RegisterImageOptionsReader(img.File, gofpdf.ImageOptions{ImageType: img.Type}, f)
+	if !pdf.Ok() || info == nil {
+		// A broken image must not fail the whole PDF
+		pdf.ClearError()
+		return
+	}
+
+	left, top, right, bottom := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	// Pixels at 96 dpi, as browsers show them
+	w := float64(img.Width) * 25.4 / 96
+	h := float64(img.Height) * 25.4 / 96
+	if maxWidth := pageWidth - left - right; w > maxWidth {
+		w, h = maxWidth, h*maxWidth/w
+	}
+	if maxHeight := pageHeight - top - bottom; h > maxHeight {
+		w, h = w*maxHeight/h, maxHeight
+	}
+	pdf.ImageOptions(img.File, left, 0, w, h, true, gofpdf.ImageOptions{ImageType: img.Type}, 0, "")
+	pdf.Ln(2)
+}
+
+// pdfImage returns the image at u, an http(s) or data URI, ready for a PDF,
+// or nil when it can't be fetched or decoded, see recompress.
+func (f *assetFetcher) pdfImage(u string) *pdfImage {
+	f.mu.Lock()
+	cached, ok := f.images[u]
+	f.mu.Unlock()
+	if ok {
+		return cached
+	}
+	img, err := f.fetchImage(u)
+	if err != nil {
+		img = nil
+	}
+	f.mu.Lock()
+	f.images[u] = img
+	f.mu.Unlock()
+	return img
+}
+
+// fetchImage downloads the image at u and writes it to the image
+// directory.
+func (f *assetFetcher) fetchImage(u string) (*pdfImage, error) {
+	var body []byte
+	if strings.HasPrefix(u, "data:") {
+		meta, data, ok := strings.Cut(strings.TrimPrefix(u, "data:"), ",")
+		if !ok || !strings.HasSuffix(meta, ";base64") {
+			return nil, fmt.Errorf("unsupported data URI")
+		}
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(data); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if body, _, err = f.fetch(u); err != nil {
+			return nil, err
+		}
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		// SVG and WebP among others
+		return nil, err
+	}
+	if config.Width < minImageSize || config.Height < minImageSize {
+		return nil, fmt.Errorf("%dx%d image is too small", config.Width, config.Height)
+	}
+	img := &pdfImage{Width: config.Width, Height: config.Height}
+	if body, img.Type, err = f.recompress(body, format, config.Width); err != nil {
+		return nil, err
+	}
+	if f.maxImageWidth > 0 && img.Width > f.maxImageWidth {
+		img.Width, img.Height = f.maxImageWidth, max(1, img.Height*f.maxImageWidth/img.Width)
+	}
+
+	if err := os.MkdirAll(f.imageDir, 0755); err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(u))
+	img.File = filepath.Join(f.imageDir, hex.EncodeToString(sum[:])+"."+strings.ToLower(img.Type))
+	if err := os.WriteFile(img.File, body, 0644); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
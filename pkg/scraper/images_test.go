@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestLayoutTextImages(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<p>Before</p><img src="a.png"><p>After <img src="b.png"> icon</p><img alt="no source">`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Before\n\n" + imageMarker + "a.png\n"
+	if got := layoutText(doc, true); !strings.HasPrefix(got, want) || !strings.Contains(got, "\n"+imageMarker+"b.png\n") {
+		t.Errorf("layoutText(images) = %q", got)
+	}
+	if got := layoutText(doc, false); strings.Contains(got, imageMarker) {
+		t.Errorf("layoutText() = %q, has images", got)
+	}
+	if got := stripImageMarkers(layoutText(doc, true)); strings.Contains(got, imageMarker) || !strings.Contains(got, "After") {
+		t.Errorf("stripImageMarkers() = %q", got)
+	}
+}
+
+func TestImageLines(t *testing.T) {
+	tests := []struct {
+		line string
+		raw  bool
+		want []string
+	}{
+		{imageMarker + "/img/a.png", false, []string{"/img/a.png"}},
+		{"plain text", false, nil},
+		{`<p><img class="x" src="a.png"> and <IMG SRC='b.jpg'/></p>`, true, []string{"a.png", "b.jpg"}},
+		{`<img alt="x">`, true, nil},
+		{`<img src="data:image/gif;base64,R0lGOD" data-src="/real.jpg" loading="lazy">`, true, []string{"/real.jpg"}},
+		{`<img loading="lazy" src="a.png" srcset="a-640.png 640w, a-1600.png 1600w">`, true, []string{"a-1600.png"}},
+	}
+	for _, tt := range tests {
+		got := imageLines(tt.line, tt.raw)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("imageLines(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestImageSource(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{`<img src="a.png">`, "a.png"},
+		{`<img src="data:image/gif;base64,R0lGOD" data-src="lazy.png">`, "lazy.png"},
+		{`<img src="placeholder.svg" data-lazy-src="lazy.png">`, "lazy.png"},
+		{`<img src="data:image/gif;base64,R0lGOD">`, "data:image/gif;base64,R0lGOD"},
+		{`<img src="a.png" srcset="a-1x.png, a-2x.png 2x, a-3x.png 3x">`, "a-2x.png"},
+		{`<img src="a.png" srcset="a-480.png 480w, a-1280.png 1280w, a-2560.png 2560w">`, "a-1280.png"},
+		{`<img src="a.png" srcset="a-320.png 320w, a-800.png 800w">`, "a-800.png"},
+		{`<img src="a.png" srcset="data:image/gif;base64,R0lGOD 1w" data-srcset="real.png 1000w">`, "real.png"},
+		{`<img src="a.png" srcset="bad.png 12q">`, "a.png"},
+		{`<img alt="none">`, ""},
+	}
+	for _, tt := range tests {
+		doc, err := html.Parse(strings.NewReader(tt.tag))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := findElement(doc, atom.Img)
+		if got := imageSource(img); got != tt.want {
+			t.Errorf("imageSource(%s) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+// encodeImage returns a w×h image in the format of encode.
+func encodeImage(t *testing.T, w, h int, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.NRGBA64{R: 0xffff, A: 0x8000})
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriteImages(t *testing.T) {
+	images := map[string][]byte{
+		// 16-bit with transparency, which the PDF library can't take as is
+		"/deep.png":  encodeImage(t, 40, 30, func(b *bytes.Buffer, i image.Image) error { return png.Encode(b, i) }),
+		"/photo.jpg": encodeImage(t, 3000, 1000, func(b *bytes.Buffer, i image.Image) error { return jpeg.Encode(b, i, nil) }),
+		"/anim.gif":  encodeImage(t, 20, 20, func(b *bytes.Buffer, i image.Image) error { return gif.Encode(b, i, nil) }),
+		"/pixel.png": encodeImage(t, 1, 1, func(b *bytes.Buffer, i image.Image) error { return png.Encode(b, i) }),
+		"/logo.svg":  []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	u, _ := url.Parse(server.URL + "/docs/page")
+	s := &Scraper{stripHTML: true, assets: newAssetFetcher(http.DefaultTransport, "", nil)}
+	s.assets.imageDir = filepath.Join(dir, "images")
+	var lines []string
+	for _, src := range []string{"/deep.png", "../photo.jpg", server.URL + "/anim.gif", "/pixel.png", "/logo.svg", "/missing.png", "/deep.png"} {
+		lines = append(lines, "Text", imageMarker+src)
+	}
+	p := &page{URL: u, Text: strings.Join(lines, "\n")}
+
+	tests := []struct {
+		name       string
+		noImages   bool
+		wantImages int
+	}{
+		// Three images, and the transparency of the PNG as a fourth
+		{"images", false, 4},
+		{"no images", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.NoImages = tt.noImages
+			file := filepath.Join(dir, tt.name+".pdf")
+			if err := s.createPDF(file, p); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The repeated image is embedded once
+			if got := bytes.Count(data, []byte("/Subtype /Image")); got != tt.wantImages {
+				t.Errorf("PDF has %d images, want %d", got, tt.wantImages)
+			}
+			if bytes.Contains(data, []byte("deep.png")) {
+				t.Error("image source written as text")
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package scraper
+
+import (
+	"bytes"
+	"html/template"
+	"time"
+)
+
+// indexTemplate is the archive's index.html, a page listing every PDF so
+// the archive can be browsed from a file manager or a browser.
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Archive of {{.StartURL}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+ul { list-style: none; padding: 0; display: flex; flex-wrap: wrap; gap: 1.5em; }
+li { width: 160px; }
+li img { display: block; border: 1px solid #ccc; margin-bottom: .4em; }
+li a { text-decoration: none; color: #0645ad; word-break: break-word; }
+li small { color: #666; word-break: break-all; }
+#search { font-size: 1.1em; padding: .3em; width: 100%; max-width: 30em; }
+#results li { width: auto; margin-bottom: 1em; }
+#results p { margin: .2em 0; color: #444; }
+</style>
+</head>
+<body>
+<h1>Archive of {{.StartURL}}</h1>
+{{- if .DocsVersion}}
+<p>Documentation version {{.DocsVersion}}</p>
+{{- end}}
+<p>{{.PageCount}} pages, generated {{.Generated}}.</p>
+{{- if .Search}}
+<input id="search" type="search" placeholder="Search the archive" autofocus>
+<ol id="results"></ol>
+{{- end}}
+<ul id="pages">
+{{- range .Pages}}
+<li>
+<a href="{{.File}}">{{if .Thumbnail}}<img src="{{.Thumbnail}}" width="150" alt="">{{end}}{{.Title}}</a>
+<small>{{.URL}}</small>
+{{- if .Chrome}}
+<small><a href="{{.Chrome}}">Chrome print</a></small>
+{{- end}}
+{{- if .Snapshot}}
+<small><a href="{{.Snapshot}}">HTML</a></small>
+{{- end}}
+</li>
+{{- end}}
+</ul>
+{{- if .Search}}
+<script src="search-index.js"></script>
+<script>
+(function () {
+  var index = scrapdfSearchIndex;
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var pages = document.getElementById("pages");
+
+  // Same rules as the Go indexer: lower-cased runs of letters and digits,
+  // longer than one character.
+  function tokenize(text) {
+    return text.toLowerCase().split(/[^\p{L}\p{N}]+/u).filter(function (t) {
+      return Array.from(t).length > 1;
+    });
+  }
+
+  function snippet(text, term) {
+    var at = text.toLowerCase().indexOf(term);
+    if (at < 0) {
+      return text.slice(0, 160);
+    }
+    var start = Math.max(0, at - 80);
+    return (start > 0 ? "…" : "") + text.slice(start, at + term.length + 80) + "…";
+  }
+
+  function search(query) {
+    var terms = tokenize(query);
+    var scores = null;
+    terms.forEach(function (term) {
+      var postings = index.terms[term] || [];
+      var idf = Math.log(1 + index.docs.length / Math.max(1, postings.length));
+      var next = {};
+      postings.forEach(function (p) {
+        if (scores === null || p[0] in scores) {
+          next[p[0]] = (scores === null ? 0 : scores[p[0]]) + p[1] * idf;
+        }
+      });
+      scores = next;
+    });
+    if (scores === null) {
+      return [];
+    }
+    return Object.keys(scores).sort(function (a, b) {
+      return scores[b] - scores[a];
+    }).map(function (i) {
+      return { doc: index.docs[i], snippet: snippet(index.docs[i].text, terms[0]) };
+    });
+  }
+
+  input.addEventListener("input", function () {
+    results.textContent = "";
+    var query = input.value.trim();
+    pages.hidden = query !== "";
+    search(query).forEach(function (r) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = r.doc.file;
+      a.textContent = r.doc.title || r.doc.file;
+      var p = document.createElement("p");
+      p.textContent = r.snippet;
+      var small = document.createElement("small");
+      small.textContent = r.doc.url;
+      li.append(a, p, small);
+      results.append(li);
+    });
+  });
+})();
+</script>
+{{- end}}
+</body>
+</html>
+`))
+
+// indexPage is one entry of index.html.
+type indexPage struct {
+	Title     string
+	URL       string
+	File      string
+	Thumbnail string
+	Chrome    string // Chrome print made by RendererBoth
+	Snapshot  string // self-contained HTML copy
+}
+
+// indexHTML renders index.html for the archived pages, in archive order.
+func (s *Scraper) indexHTML(startURL string) ([]byte, error) {
+	s.mu.Lock()
+	data := struct {
+		StartURL    string
+		Generated   string
+		PageCount   string
+		Search      bool
+		DocsVersion string
+		Pages       []indexPage
+	}{
+		StartURL:    s.Redact(startURL),
+		Generated:   s.formatTime(time.Now(), isoDateTime),
+		PageCount:   s.formatNumber(len(s.pdfs)),
+		Search:      s.SearchIndex,
+		DocsVersion: s.docsVersion,
+	}
+	for _, p := range s.pdfs {
+		entry := indexPage{
+			Title: s.displayTitle(p),
+			URL:   s.Redact(p.URL.String()),
+			File:  s.pageEntryName(p.URL),
+		}
+		if entry.Title == "" {
+			entry.Title = entry.File
+		}
+		if p.Thumb != nil {
+			entry.Thumbnail = s.thumbName(p)
+		}
+		if p.ChromeFile != "" {
+			entry.Chrome = s.chromeEntryName(p.URL)
+		}
+		if p.SnapshotFile != "" {
+			entry.Snapshot = s.snapshotEntryName(p.URL)
+		}
+		data.Pages = append(data.Pages, entry)
+	}
+	s.mu.Unlock()
+
+	var buf bytes.Buffer
+	if err := indexTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,403 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxInlineAsset is the largest asset embedded in a self-contained page;
+// bigger ones keep pointing at the site.
+const maxInlineAsset = 8 << 20
+
+// maxCSSImports bounds how deep @import chains are followed.
+const maxCSSImports = 3
+
+var (
+	// cssImportPattern matches an @import rule and captures its URL.
+	cssImportPattern = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"')\s;]+)["']?\s*\)?[^;]*;`)
+	// cssURLPattern matches a url() reference and captures its URL.
+	cssURLPattern = regexp.MustCompile(`url\(\s*(["']?)([^"')]+)(["']?)\s*\)`)
+)
+
+// assetFetcher downloads the stylesheets, images and fonts of pages for
+// self-contained snapshots, and the images of PDFs. Assets shared by several
+// pages are fetched once.
+type assetFetcher struct {
+	client    *http.Client
+	userAgent string
+	// nextUserAgent returns the user agent of each request instead, when
+	// user agents rotate.
+	nextUserAgent func() string
+	// cookies returns the crawl's cookies for a URL, so assets behind a
+	// login load too.
+	cookies func(u string) []*http.Cookie
+
+	mu     sync.Mutex
+	data   map[string]string    // map[url]data URI, empty when it failed
+	sheets map[string]string    // map[url]stylesheet with its assets inlined
+	images map[string]*pdfImage // map[url]image for PDFs, nil when it failed
+	// imageDir holds the files of images.
+	imageDir string
+	// imageQuality and maxImageWidth are the ImageQuality and
+	// MaxImageWidth images for PDFs are recompressed with.
+	imageQuality  int
+	maxImageWidth int
+}
+
+func newAssetFetcher(transport http.RoundTripper, userAgent string, cookies func(string) []*http.Cookie) *assetFetcher {
+	client := &http.Client{Transport: transport}
+	return &assetFetcher{
+		client:    client,
+		userAgent: userAgent,
+		cookies:   cookies,
+		data:      make(map[string]string),
+		sheets:    make(map[string]string),
+		images:    make(map[string]*pdfImage),
+	}
+}
+
+// agent returns the user agent of the next request.
+func (f *assetFetcher) agent() string {
+	if f.nextUserAgent != nil {
+		return f.nextUserAgent()
+	}
+	return f.userAgent
+}
+
+// fetch downloads u and returns its body and media type.
+func (f *assetFetcher) fetch(u string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if ua := f.agent(); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if f.cookies != nil {
+		for _, c := range f.cookies(u) {
+			req.AddCookie(c)
+		}
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s returned %d", u, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxInlineAsset+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxInlineAsset {
+		return nil, "", fmt.Errorf("%s is larger than %d bytes", u, maxInlineAsset)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/octet-stream", "text/plain":
+		// Generic types are often sent for fonts and images by
+		// misconfigured servers; the extension says more
+		if byExt := mime.TypeByExtension(path.Ext(req.URL.Path)); byExt != "" {
+			mediaType, _, _ = mime.ParseMediaType(byExt)
+		}
+	}
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+	}
+	return body, mediaType, nil
+}
+
+// dataURI returns u as a data URI, or "" when it can't be fetched.
+func (f *assetFetcher) dataURI(u string) string {
+	f.mu.Lock()
+	cached, ok := f.data[u]
+	f.mu.Unlock()
+	if ok {
+		return cached
+	}
+	var uri string
+	if body, mediaType, err := f.fetch(u); err == nil {
+		uri = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(body)
+	}
+	f.mu.Lock()
+	f.data[u] = uri
+	f.mu.Unlock()
+	return uri
+}
+
+// stylesheet returns the stylesheet at u with its imports and assets
+// inlined, and whether it could be fetched.
+func (f *assetFetcher) stylesheet(u *url.URL, depth int) (string, bool) {
+	key := u.String()
+	f.mu.Lock()
+	cached, ok := f.sheets[key]
+	f.mu.Unlock()
+	if ok {
+		return cached, true
+	}
+	body, _, err := f.fetch(key)
+	if err != nil {
+		return "", false
+	}
+	css := f.inlineCSS(string(body), u, depth)
+	f.mu.Lock()
+	f.sheets[key] = css
+	f.mu.Unlock()
+	return css, true
+}
+
+// inlineCSS replaces the @import rules and url() references of css,
+// relative to base, with the content they point at.
+func (f *assetFetcher) inlineCSS(css string, base *url.URL, depth int) string {
+	css = cssImportPattern.ReplaceAllStringFunc(css, func(rule string) string {
+		ref := cssImportPattern.FindStringSubmatch(rule)[1]
+		target, err := base.Parse(ref)
+		if err != nil || depth >= maxCSSImports {
+			return rule
+		}
+		if imported, ok := f.stylesheet(target, depth+1); ok {
+			return imported
+		}
+		return rule
+	})
+	return cssURLPattern.ReplaceAllStringFunc(css, func(ref string) string {
+		m := cssURLPattern.FindStringSubmatch(ref)
+		target := strings.TrimSpace(m[2])
+		if strings.HasPrefix(target, "data:") || strings.HasPrefix(target, "#") {
+			return ref
+		}
+		abs, err := base.Parse(target)
+		if err != nil {
+			return ref
+		}
+		if uri := f.dataURI(abs.String()); uri != "" {
+			return `url("` + uri + `")`
+		}
+		return `url("` + abs.String() + `")`
+	})
+}
+
+// selfContained returns body as a single HTML file: stylesheets become
+// style elements, images, icons and fonts data URIs, and the remaining
+// links absolute URLs.
+func (f *assetFetcher) selfContained(body []byte, base *url.URL) ([]byte, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	base = documentBase(doc, base)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if c.Type == html.ElementNode && f.inlineElement(c, base) {
+				walk(c)
+			}
+			c = next
+		}
+	}
+	walk(doc)
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// documentBase returns the URL relative links of doc resolve against,
+// honouring a base element.
+func documentBase(doc *html.Node, base *url.URL) *url.URL {
+	var found *url.URL
+	var find func(n *html.Node)
+	find = func(n *html.Node) {
+		if found != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Base {
+			if href := getAttr(n, "href"); href != "" {
+				found, _ = base.Parse(href)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(doc)
+	if found != nil {
+		return found
+	}
+	return base
+}
+
+// inlineElement inlines or absolutises the references of n. It returns
+// false when n was removed from the document.
+func (f *assetFetcher) inlineElement(n *html.Node, base *url.URL) bool {
+	if style := getAttr(n, "style"); style != "" {
+		setAttr(n, "style", f.inlineCSS(style, base, 0))
+	}
+	switch n.DataAtom {
+	case atom.Base:
+		// Every link is made absolute instead
+		n.Parent.RemoveChild(n)
+		return false
+	case atom.Link:
+		rel := strings.ToLower(getAttr(n, "rel"))
+		href := getAttr(n, "href")
+		target, err := base.Parse(href)
+		if href == "" || err != nil {
+			return true
+		}
+		switch {
+		case hasToken(rel, "stylesheet"):
+			css, ok := f.stylesheet(target, 0)
+			if !ok {
+				setAttr(n, "href", target.String())
+				return true
+			}
+			style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+			if media := getAttr(n, "media"); media != "" {
+				style.Attr = []html.Attribute{{Key: "media", Val: media}}
+			}
+			style.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+			n.Parent.InsertBefore(style, n)
+			n.Parent.RemoveChild(n)
+			return false
+		case hasToken(rel, "icon"):
+			f.inlineAttr(n, "href", base)
+		default:
+			setAttr(n, "href", target.String())
+		}
+	case atom.Style:
+		if c := n.FirstChild; c != nil && c.Type == html.TextNode {
+			c.Data = f.inlineCSS(c.Data, base, 0)
+		}
+	case atom.Img:
+		if f.inlineAttr(n, "src", base) {
+			// The inlined source is the only one available offline
+			removeAttr(n, "srcset")
+			removeAttr(n, "sizes")
+		}
+	case atom.Source:
+		if n.Parent != nil && n.Parent.DataAtom == atom.Picture {
+			// The picture falls back to its inlined img
+			n.Parent.RemoveChild(n)
+			return false
+		}
+		absolutiseAttr(n, "src", base)
+	case atom.Video:
+		f.inlineAttr(n, "poster", base)
+		absolutiseAttr(n, "src", base)
+	case atom.Input:
+		if strings.EqualFold(getAttr(n, "type"), "image") {
+			f.inlineAttr(n, "src", base)
+		}
+	case atom.A, atom.Area:
+		absolutiseAttr(n, "href", base)
+	case atom.Form:
+		absolutiseAttr(n, "action", base)
+	case atom.Script, atom.Iframe, atom.Audio, atom.Embed:
+		absolutiseAttr(n, "src", base)
+	}
+	return true
+}
+
+// inlineAttr replaces the URL in attribute key of n with a data URI, or
+// with the absolute URL when it can't be fetched. It reports whether the
+// asset was inlined.
+func (f *assetFetcher) inlineAttr(n *html.Node, key string, base *url.URL) bool {
+	ref := strings.TrimSpace(getAttr(n, key))
+	if ref == "" || strings.HasPrefix(ref, "data:") {
+		return false
+	}
+	target, err := base.Parse(ref)
+	if err != nil {
+		return false
+	}
+	if uri := f.dataURI(target.String()); uri != "" {
+		setAttr(n, key, uri)
+		return true
+	}
+	setAttr(n, key, target.String())
+	return false
+}
+
+// absolutiseAttr resolves the URL in attribute key of n against base.
+func absolutiseAttr(n *html.Node, key string, base *url.URL) {
+	ref := getAttr(n, key)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return
+	}
+	if target, err := base.Parse(strings.TrimSpace(ref)); err == nil {
+		setAttr(n, key, target.String())
+	}
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
+
+// snapshotEntryName returns the archive name of the self-contained HTML of
+// the page at u.
+func (s *Scraper) snapshotEntryName(u *url.URL) string {
+	return strings.TrimSuffix(s.baseEntryName(u), ".pdf") + ".html"
+}
+
+// saveSnapshot writes the self-contained HTML of p next to its file. A
+// failure only loses the snapshot.
+func (s *Scraper) saveSnapshot(p *page) {
+	if !strings.Contains(strings.ToLower(p.Header.Get("Content-Type")), "html") {
+		return
+	}
+	data, err := s.assets.selfContained(p.Body, contentBase(p))
+	if err == nil {
+		file := strings.TrimSuffix(p.File, filepath.Ext(p.File)) + ".html"
+		if err = os.WriteFile(file, data, 0644); err == nil {
+			p.SnapshotFile = file
+			return
+		}
+	}
+	s.logf("Warning: no self-contained copy of %s: %v\n", p.URL, err)
+	s.event(event{Type: eventError, URL: p.URL.String(), Stage: "snapshot", Error: err.Error()})
+}
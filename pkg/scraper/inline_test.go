@@ -0,0 +1,68 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSelfContained(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/css/site.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`@import "base.css"; body { background: url(../img/bg.png); } @font-face { src: url('/fonts/f.woff2'); }`))
+	})
+	mux.HandleFunc("/css/base.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`p { color: red; }`))
+	})
+	mux.HandleFunc("/img/bg.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	mux.HandleFunc("/img/logo.gif", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("GIF89a"))
+	})
+	mux.HandleFunc("/fonts/f.woff2", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("wOF2font"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL + "/docs/page")
+	body := []byte(`<html><head><link rel="stylesheet" href="/css/site.css" media="screen"></head>
+<body><img src="../img/logo.gif" srcset="big.gif 2x"><img src="/missing.png">
+<a href="other">next</a><a href="#top">top</a></body></html>`)
+
+	f := newAssetFetcher(http.DefaultTransport, "", nil)
+	data, err := f.selfContained(body, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+
+	for _, want := range []string{
+		`<style media="screen">p { color: red; } body { background: url("data:image/png;base64,cG5n"); }`,
+		`url("data:font/woff2;base64,d09GMmZvbnQ=")`,
+		`<img src="data:image/gif;base64,R0lGODlh"/>`,
+		`<img src="` + server.URL + `/missing.png"/>`,
+		`<a href="` + server.URL + `/docs/other">`,
+		`<a href="#top">`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("snapshot lacks %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<link") || strings.Contains(got, "srcset") {
+		t.Errorf("snapshot still references remote assets:\n%s", got)
+	}
+}
+
+func TestSnapshotEntryName(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/intro")
+	if got, want := NewScraper(false, false).snapshotEntryName(u), "example.com_docs_intro.html"; got != want {
+		t.Errorf("snapshotEntryName() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Kinds of output reported by InspectArchive.
+const (
+	KindZIP  = "zip"
+	KindEPUB = "epub"
+	KindPDF  = "pdf"
+)
+
+// ArchiveInfo describes an archive written by scrapdf: its container and,
+// when it has one, its manifest.
+type ArchiveInfo struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Size int64  `json:"size"` // bytes on disk
+	// Entries, CompressedSize and UncompressedSize describe the files in a
+	// ZIP or EPUB, and Methods counts them by compression method.
+	Entries          int            `json:"entries,omitempty"`
+	CompressedSize   uint64         `json:"compressed_size,omitempty"`
+	UncompressedSize uint64         `json:"uncompressed_size,omitempty"`
+	Methods          map[string]int `json:"methods,omitempty"`
+	// Zip64 is set when the archive ends with a ZIP64 end of central
+	// directory record, needed past 65,535 entries or 4 GiB.
+	Zip64 bool `json:"zip64,omitempty"`
+	// LargeEntries are the entries of 4 GiB or more, whose sizes are stored
+	// in ZIP64 extra fields.
+	LargeEntries []string `json:"large_entries,omitempty"`
+	// Manifest is the archive's manifest.json, nil when it has none.
+	Manifest *ArchiveManifest `json:"manifest,omitempty"`
+}
+
+// ArchiveManifest summarises the manifest of an archive.
+type ArchiveManifest struct {
+	StartURL    string    `json:"start_url"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Format      string    `json:"format"`
+	Renderer    string    `json:"renderer"`
+	Pages       int       `json:"pages"`
+	Failures    int       `json:"failures"`
+}
+
+// zip64Locator and endOfDirectory are the signatures of the ZIP64 end of
+// central directory locator and of the end of central directory record.
+var (
+	zip64Locator   = []byte("PK\x06\x07")
+	endOfDirectory = []byte("PK\x05\x06")
+)
+
+// zip64LocatorSize is the length of the ZIP64 locator, which immediately
+// precedes the end of central directory record.
+const zip64LocatorSize = 20
+
+// maxEndOfDirectory is the longest end of central directory record: 22
+// bytes and a comment of up to 65,535.
+const maxEndOfDirectory = 22 + math.MaxUint16
+
+// InspectArchive reports the container format and contents of an archive
+// written by scrapdf: a ZIP, an EPUB or a single PDF.
+func InspectArchive(path string) (*ArchiveInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	info := &ArchiveInfo{Path: path, Size: stat.Size()}
+
+	head := make([]byte, 5)
+	if _, err := io.ReadFull(f, head); err == nil && string(head) == "%PDF-" {
+		info.Kind = KindPDF
+		return info, nil
+	}
+
+	r, err := zip.NewReader(f, info.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%s is neither a ZIP archive nor a PDF: %w", path, err)
+	}
+	if info.Zip64, err = hasZip64End(f, info.Size); err != nil {
+		return nil, err
+	}
+	info.Kind = KindZIP
+	info.Methods = make(map[string]int)
+	for i, entry := range r.File {
+		if i == 0 && entry.Name == "mimetype" {
+			info.Kind = KindEPUB
+		}
+		info.Entries++
+		info.CompressedSize += entry.CompressedSize64
+		info.UncompressedSize += entry.UncompressedSize64
+		info.Methods[methodName(entry.Method)]++
+		if entry.UncompressedSize64 >= math.MaxUint32 || entry.CompressedSize64 >= math.MaxUint32 {
+			info.LargeEntries = append(info.LargeEntries, entry.Name)
+		}
+	}
+
+	if data, err := readZipEntry(r, "manifest.json"); err == nil {
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+		info.Manifest = &ArchiveManifest{
+			StartURL:    m.StartURL,
+			GeneratedAt: m.GeneratedAt,
+			Format:      m.Format,
+			Renderer:    m.Renderer,
+			Pages:       len(m.Pages),
+			Failures:    len(m.Failures),
+		}
+		if info.Manifest.Format == "" {
+			// Archives from before the format was recorded are PDFs
+			info.Manifest.Format = FormatPDF
+		}
+	}
+	return info, nil
+}
+
+// hasZip64End reports whether the ZIP file r of the given size ends with a
+// ZIP64 end of central directory locator and record.
+func hasZip64End(r io.ReaderAt, size int64) (bool, error) {
+	n := int64(maxEndOfDirectory + zip64LocatorSize)
+	if n > size {
+		n = size
+	}
+	tail := make([]byte, n)
+	if _, err := r.ReadAt(tail, size-n); err != nil && err != io.EOF {
+		return false, err
+	}
+	end := bytes.LastIndex(tail, endOfDirectory)
+	if end < zip64LocatorSize {
+		return false, nil
+	}
+	return bytes.Equal(tail[end-zip64LocatorSize:end-zip64LocatorSize+len(zip64Locator)], zip64Locator), nil
+}
+
+// methodName returns the name of a ZIP compression method.
+func methodName(method uint16) string {
+	switch method {
+	case zip.Store:
+		return "store"
+	case zip.Deflate:
+		return "deflate"
+	}
+	return fmt.Sprintf("method %d", method)
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"archive/zip"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// archivePage returns a page of s whose file holds size bytes.
+func archivePage(t *testing.T, dir, path string, size int64) *page {
+	t.Helper()
+	u, _ := url.Parse("https://example.com" + path)
+	file := filepath.Join(dir, entryName(u))
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sparse, so huge pages don't need the disk space
+	if err := f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return &page{URL: u, File: file}
+}
+
+func TestCreateZipManyEntries(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		extras    int
+		wantZip64 bool
+	}{
+		{"small", 10, false},
+		// A synthetic crawl past the 65,535 entries of a plain ZIP
+		{"many entries", math.MaxUint16 + 100, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Scraper{pdfs: []*page{archivePage(t, dir, "/", 100)}}
+			extras := make([]archiveEntry, tt.extras)
+			for i := range extras {
+				extras[i] = archiveEntry{Name: fmt.Sprintf("thumbs/%d.png", i), Data: []byte{byte(i)}}
+			}
+			archive := filepath.Join(dir, tt.name+".zip")
+			if err := s.createZip(archive, extras); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := zip.OpenReader(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if len(r.File) != tt.extras+1 {
+				t.Errorf("read %d entries, want %d", len(r.File), tt.extras+1)
+			}
+
+			info, err := InspectArchive(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Kind != KindZIP || info.Entries != tt.extras+1 || info.Zip64 != tt.wantZip64 {
+				t.Errorf("InspectArchive() = kind %s, %d entries, ZIP64 %v", info.Kind, info.Entries, info.Zip64)
+			}
+		})
+	}
+}
+
+func TestCreateZipLargeEntry(t *testing.T) {
+	if os.Getenv("SCRAPDF_LARGE_TESTS") != "1" {
+		t.Skip("writes and compresses over 4GB, set SCRAPDF_LARGE_TESTS=1 to run it")
+	}
+	dir := t.TempDir()
+	size := int64(math.MaxUint32) + 1<<20
+	s := &Scraper{pdfs: []*page{archivePage(t, dir, "/huge", size), archivePage(t, dir, "/after", 100)}}
+	archive := filepath.Join(dir, "large.zip")
+	if err := s.createZip(archive, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got := r.File[0].UncompressedSize64; got != uint64(size) {
+		t.Errorf("huge entry is %d bytes, want %d", got, size)
+	}
+	// The entry after the huge one is found through a ZIP64 offset
+	rc, err := r.File[1].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	info, err := InspectArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Zip64 || len(info.LargeEntries) != 1 {
+		t.Errorf("InspectArchive() = ZIP64 %v, large entries %v", info.Zip64, info.LargeEntries)
+	}
+}
+
+func TestInspectArchive(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "example.com.pdf")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := InspectArchive(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Kind != KindPDF {
+		t.Errorf("kind = %s, want %s", info.Kind, KindPDF)
+	}
+
+	s := &Scraper{pdfs: []*page{archivePage(t, dir, "/", 100)}}
+	data, err := s.manifestJSON("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "example.com.zip")
+	if err := s.createZip(archive, []archiveEntry{{Name: "manifest.json", Data: data}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err = InspectArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := info.Manifest; m == nil || m.StartURL != "https://example.com/" || m.Pages != 1 || m.Format != FormatPDF {
+		t.Errorf("manifest = %+v", info.Manifest)
+	}
+
+	if _, err := InspectArchive(filepath.Join(dir, "example.com_index.pdf")); err == nil {
+		t.Error("inspected a file that is neither a ZIP nor a PDF")
+	}
+}
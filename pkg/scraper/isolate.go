@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// pageFailure records a page that could not be converted.
+type pageFailure struct {
+	URL   string `json:"url"`
+	Stage string `json:"stage"`
+	// Status is the HTTP status of a page that failed to fetch, if the
+	// server answered.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Conversion stages reported in failures.
+const (
+	stageExtract = "extract"
+	stageRender  = "render"
+	stageArchive = "archive"
+)
+
+// isolate runs fn for a single page in its own goroutine, turning panics
+// into errors and giving up after timeout (zero waits forever). A timed out
+// fn keeps running in the background but its result is discarded, so one
+// pathological document can't stall the crawl.
+func isolate[T any](timeout time.Duration, fn func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				done <- result{zero, fmt.Errorf("panic: %v\n%s", r, debug.Stack())}
+			}
+		}()
+		v, err := fn()
+		done <- result{v, err}
+	}()
+
+	if timeout <= 0 {
+		r := <-done
+		return r.value, r.err
+	}
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-time.After(timeout):
+		var zero T
+		return zero, fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// recordFailure logs a failed page and keeps it for the manifest.
+func (s *Scraper) recordFailure(p *page, stage string, err error) {
+	s.logf("Failed to create PDF for %s: %v\n", p.URL, err)
+	s.event(event{Type: eventError, URL: p.URL.String(), Stage: stage, Error: err.Error()})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, pageFailure{
+		URL:   s.Redact(p.URL.String()),
+		Stage: stage,
+		Error: s.Redact(err.Error()),
+	})
+}
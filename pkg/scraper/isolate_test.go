@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsolate(t *testing.T) {
+	got, err := isolate(time.Second, func() (int, error) { return 42, nil })
+	if err != nil || got != 42 {
+		t.Errorf("isolate() = %d, %v; want 42, nil", got, err)
+	}
+
+	want := errors.New("boom")
+	if _, err := isolate(0, func() (int, error) { return 0, want }); !errors.Is(err, want) {
+		t.Errorf("isolate() error = %v, want %v", err, want)
+	}
+}
+
+func TestIsolatePanic(t *testing.T) {
+	_, err := isolate(time.Second, func() (string, error) {
+		var m map[string]int
+		m["x"] = 1
+		return "", nil
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "panic:") {
+		t.Errorf("isolate() error = %v, want a recovered panic", err)
+	}
+}
+
+func TestIsolateTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	_, err := isolate(20*time.Millisecond, func() (string, error) {
+		<-release
+		return "late", nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("isolate() error = %v, want a timeout", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("isolate() waited %s for a stuck page", time.Since(start))
+	}
+}
@@ -0,0 +1,150 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/gocolly/colly/v2"
+)
+
+// fetchHTML loads pageURL in a new tab authenticated with req, runs
+// prepare on it and returns the document once its scripts have built the
+// body. A zero timeout waits forever.
+func (r *chromeRenderer) fetchHTML(pageURL string, prepare chromedp.Tasks, req browserRequest, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := chromedp.NewContext(r.browser)
+	defer cancel()
+	if timeout > 0 {
+		var cancelTimeout func()
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+
+	var doc string
+	actions := append(req.prepareTab(ctx),
+		chromedp.Navigate(pageURL),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		prepare,
+		chromedp.OuterHTML("html", &doc, chromedp.ByQuery),
+	)
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return nil, fmt.Errorf("chrome failed to load %s: %w", pageURL, err)
+	}
+	return []byte("<!DOCTYPE html>\n" + doc), nil
+}
+
+// browserRequest is how the headless browser authenticates as the crawl
+// when it loads a page: with the crawl's cookies, its user agent, and
+// Headers sent to the page's origin only, so tokens don't reach the CDNs,
+// analytics and ads the page loads.
+type browserRequest struct {
+	origin    *url.URL
+	cookies   []*network.CookieParam
+	headers   network.Headers
+	userAgent string
+}
+
+// browserRequest returns how the browser loads the page at u.
+func (s *Scraper) browserRequest(u *url.URL) browserRequest {
+	req := browserRequest{origin: u, headers: s.browserHeaders(), userAgent: s.requestUserAgent()}
+	if s.jar != nil {
+		req.cookies = cookieParams(u, s.jar(u.String()))
+	}
+	return req
+}
+
+// prepareTab returns the actions setting up the tab of ctx for req before
+// it navigates. The tab's requests to other origins go out without the
+// headers.
+func (req browserRequest) prepareTab(ctx context.Context) []chromedp.Action {
+	actions := []chromedp.Action{network.Enable()}
+	if len(req.cookies) > 0 {
+		actions = append(actions, network.SetCookies(req.cookies))
+	}
+	if req.userAgent != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(req.userAgent))
+	}
+	if len(req.headers) == 0 {
+		return actions
+	}
+	chromedp.ListenTarget(ctx, func(ev any) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		go func() {
+			c := chromedp.FromContext(ctx)
+			cont := fetch.ContinueRequest(paused.RequestID)
+			if u, err := url.Parse(paused.Request.URL); err == nil && sameOrigin(u, req.origin) {
+				cont = cont.WithHeaders(withHeaders(paused.Request.Headers, req.headers))
+			}
+			// Fails only once the tab is gone
+			cont.Do(cdp.WithExecutor(ctx, c.Target))
+		}()
+	})
+	return append(actions, fetch.Enable())
+}
+
+// sameOrigin reports whether a and b have the same scheme and host.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Host, b.Host)
+}
+
+// withHeaders returns the headers of a request with extra added, replacing
+// those of the same name.
+func withHeaders(headers, extra network.Headers) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(headers)+len(extra))
+	for name, v := range headers {
+		replaced := false
+		for extraName := range extra {
+			replaced = replaced || strings.EqualFold(name, extraName)
+		}
+		if !replaced {
+			entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(v)})
+		}
+	}
+	for name, v := range extra {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(v)})
+	}
+	return entries
+}
+
+// cookieParams converts the collector's cookies for u, e.g. from a login
+// session, so the browser is authenticated the same way.
+func cookieParams(u *url.URL, cookies []*http.Cookie) []*network.CookieParam {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		params = append(params, &network.CookieParam{
+			Name:   c.Name,
+			Value:  c.Value,
+			URL:    u.String(),
+			Secure: strings.EqualFold(u.Scheme, "https"),
+		})
+	}
+	return params
+}
+
+// renderJS replaces the static body of r with the document built by the
+// page's scripts, so text extraction and link discovery see the rendered
+// content. Non-HTML responses are left alone, and so is the static body
+// when the browser fails.
+func (s *Scraper) renderJS(r *colly.Response) {
+	if !strings.Contains(strings.ToLower(r.Headers.Get("Content-Type")), "html") {
+		return
+	}
+	body, err := s.chrome.fetchHTML(r.Request.URL.String(), s.beforeCapture(r.Request.URL.Host), s.browserRequest(r.Request.URL), s.PageTimeout)
+	if err != nil {
+		s.logf("Warning: using the static page, %v\n", err)
+		s.event(event{Type: eventError, URL: r.Request.URL.String(), Stage: "render_js", Error: err.Error()})
+		return
+	}
+	r.Body = body
+}
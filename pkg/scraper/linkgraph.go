@@ -0,0 +1,265 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats of the link graph accepted by Scraper.LinkGraph.
+const (
+	GraphML   = "graphml"
+	GraphDOT  = "dot"
+	GraphJSON = "json"
+)
+
+// graphEdge is a link between two pages of the site.
+type graphEdge struct {
+	From, To string
+	Text     string // anchor text
+}
+
+// graphNode is a page of the link graph. Pages linked to but not archived,
+// because they were excluded, failed or lay beyond MaxDepth, are nodes too.
+type graphNode struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Title    string `json:"title,omitempty"`
+	File     string `json:"file,omitempty"`
+	Archived bool   `json:"archived"`
+}
+
+// graphLink is an edge of the link graph, between node IDs.
+type graphLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Text   string `json:"text,omitempty"`
+}
+
+// linkGraph is the exported graph, archived pages first in archive order.
+type linkGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphLink `json:"edges"`
+}
+
+// checkLinkGraph validates the LinkGraph setting.
+func (s *Scraper) checkLinkGraph() error {
+	switch s.LinkGraph {
+	case "":
+		return nil
+	case GraphML, GraphDOT, GraphJSON:
+	default:
+		return fmt.Errorf("unknown link graph format %q (available: %s, %s, %s)", s.LinkGraph, GraphML, GraphDOT, GraphJSON)
+	}
+	if s.SinglePDF || s.Format == FormatEPUB {
+		return fmt.Errorf("the link graph is written to the ZIP archive, it can't be combined with a single PDF or EPUB")
+	}
+	return nil
+}
+
+// recordEdge remembers that source links to the page href of the same site
+// with the anchor text text.
+func (s *Scraper) recordEdge(source *url.URL, href, text string) {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return
+	}
+	target := stripFragment(source.ResolveReference(ref))
+	from := stripFragment(source)
+	if !s.sameSite(source, target) || (target.Scheme != "http" && target.Scheme != "https") || target.String() == from.String() {
+		return
+	}
+	edge := graphEdge{From: from.String(), To: target.String(), Text: strings.Join(strings.Fields(text), " ")}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.edgeSeen == nil {
+		s.edgeSeen = make(map[graphEdge]bool)
+	}
+	if !s.edgeSeen[edge] {
+		s.edgeSeen[edge] = true
+		s.edges = append(s.edges, edge)
+	}
+}
+
+// buildLinkGraph returns the link graph of the archived pages: their links
+// to each other and to the pages of the site that weren't archived.
+func (s *Scraper) buildLinkGraph() linkGraph {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := linkGraph{Nodes: []graphNode{}}
+	ids := make(map[string]string)
+	archived := make(map[string]bool)
+	add := func(n graphNode) {
+		n.ID = "n" + strconv.Itoa(len(g.Nodes))
+		ids[n.URL] = n.ID
+		n.URL = s.Redact(n.URL)
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, p := range s.pdfs {
+		u := stripFragment(p.URL).String()
+		if !archived[u] {
+			archived[u] = true
+			add(graphNode{URL: u, Title: s.redactContent(p.Title), File: s.pageEntryName(p.URL), Archived: true})
+		}
+	}
+	var unarchived []string
+	for _, e := range s.edges {
+		if _, ok := ids[e.To]; archived[e.From] && !ok {
+			unarchived = append(unarchived, e.To)
+			ids[e.To] = ""
+		}
+	}
+	sort.Strings(unarchived)
+	for _, u := range unarchived {
+		add(graphNode{URL: u})
+	}
+
+	g.Edges = []graphLink{}
+	for _, e := range s.edges {
+		if !archived[e.From] {
+			continue
+		}
+		g.Edges = append(g.Edges, graphLink{ids[e.From], ids[e.To], s.redactContent(e.Text)})
+	}
+	// Concurrent fetches record links in any order
+	node := func(id string) int {
+		n, _ := strconv.Atoi(strings.TrimPrefix(id, "n"))
+		return n
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Source != b.Source {
+			return node(a.Source) < node(b.Source)
+		}
+		if a.Target != b.Target {
+			return node(a.Target) < node(b.Target)
+		}
+		return a.Text < b.Text
+	})
+	return g
+}
+
+// linkGraphEntry returns the archive entry of the link graph in the
+// LinkGraph format.
+func (s *Scraper) linkGraphEntry() (archiveEntry, error) {
+	g := s.buildLinkGraph()
+	var data []byte
+	var err error
+	switch s.LinkGraph {
+	case GraphML:
+		data, err = g.graphML()
+	case GraphDOT:
+		data = g.dot()
+	default:
+		data, err = json.MarshalIndent(g, "", "  ")
+	}
+	return archiveEntry{Name: "link-graph." + s.LinkGraph, Data: data}, err
+}
+
+// graphML returns the graph in the GraphML format read by Gephi, yEd and
+// networkx.
+func (g linkGraph) graphML() ([]byte, error) {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data,omitempty"`
+	}
+	doc := struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			ID          string `xml:"id,attr"`
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{"url", "node", "url", "string"},
+			{"title", "node", "title", "string"},
+			{"file", "node", "file", "string"},
+			{"archived", "node", "archived", "boolean"},
+			{"text", "edge", "text", "string"},
+		},
+	}
+	doc.Graph.ID = "site"
+	doc.Graph.EdgeDefault = "directed"
+	for _, n := range g.Nodes {
+		d := []data{{"url", n.URL}}
+		if n.Title != "" {
+			d = append(d, data{"title", n.Title})
+		}
+		if n.File != "" {
+			d = append(d, data{"file", n.File})
+		}
+		d = append(d, data{"archived", strconv.FormatBool(n.Archived)})
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{n.ID, d})
+	}
+	for _, e := range g.Edges {
+		var d []data
+		if e.Text != "" {
+			d = []data{{"text", e.Text}}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{e.Source, e.Target, d})
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// dot returns the graph in the DOT language of Graphviz. Pages are labelled
+// with their titles; pages that weren't archived are dashed.
+func (g linkGraph) dot() []byte {
+	var b bytes.Buffer
+	b.WriteString("digraph site {\n  node [shape=box];\n")
+	for _, n := range g.Nodes {
+		label := n.Title
+		if label == "" {
+			label = n.URL
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, URL=%s", n.ID, dotString(label), dotString(n.URL))
+		if !n.Archived {
+			b.WriteString(", style=dashed")
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s", e.Source, e.Target)
+		if e.Text != "" {
+			fmt.Fprintf(&b, " [label=%s]", dotString(e.Text))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// dotString quotes s as a DOT string.
+func dotString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func linkGraphScraper(format string) *Scraper {
+	s := NewScraper(false, false)
+	s.LinkGraph = format
+	home, _ := url.Parse("https://example.com/")
+	guide, _ := url.Parse("https://example.com/guide")
+	s.pdfs = []*page{{URL: home, Title: "Home"}, {URL: guide, Title: `The "Guide"`}}
+
+	s.recordEdge(guide, "/#top", "Back  home")
+	s.recordEdge(home, "guide", "Read the\n guide")
+	s.recordEdge(home, "guide", "Read the guide")
+	s.recordEdge(home, "/old", "Old docs")
+	s.recordEdge(home, "#main", "Skip")
+	s.recordEdge(home, "https://other.example/", "Elsewhere")
+	s.recordEdge(home, "mailto:docs@example.com", "Mail")
+	// Links of pages that weren't archived are left out
+	s.recordEdge(&url.URL{Scheme: "https", Host: "example.com", Path: "/old"}, "/", "Home")
+	return s
+}
+
+func TestLinkGraphDOT(t *testing.T) {
+	entry, err := linkGraphScraper(GraphDOT).linkGraphEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph site {
+  node [shape=box];
+  n0 [label="Home", URL="https://example.com/"];
+  n1 [label="The \"Guide\"", URL="https://example.com/guide"];
+  n2 [label="https://example.com/old", URL="https://example.com/old", style=dashed];
+  n0 -> n1 [label="Read the guide"];
+  n0 -> n2 [label="Old docs"];
+  n1 -> n0 [label="Back home"];
+}
+`
+	if entry.Name != "link-graph.dot" || string(entry.Data) != want {
+		t.Errorf("linkGraphEntry() = %s:\n%s\nwant link-graph.dot:\n%s", entry.Name, entry.Data, want)
+	}
+}
+
+func TestLinkGraphJSON(t *testing.T) {
+	entry, err := linkGraphScraper(GraphJSON).linkGraphEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g linkGraph
+	if err := json.Unmarshal(entry.Data, &g); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 || len(g.Edges) != 3 {
+		t.Fatalf("got %d nodes and %d edges, want 3 and 3", len(g.Nodes), len(g.Edges))
+	}
+	if n := g.Nodes[1]; n.File != "example.com_guide.pdf" || !n.Archived {
+		t.Errorf("node n1 = %+v, want the archived guide", n)
+	}
+	if n := g.Nodes[2]; n.URL != "https://example.com/old" || n.Archived {
+		t.Errorf("node n2 = %+v, want the unarchived /old", n)
+	}
+}
+
+func TestLinkGraphML(t *testing.T) {
+	entry, err := linkGraphScraper(GraphML).linkGraphEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+			Data   string `xml:"data"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(entry.Data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != 3 || len(doc.Edges) != 3 {
+		t.Fatalf("got %d nodes and %d edges, want 3 and 3", len(doc.Nodes), len(doc.Edges))
+	}
+	if e := doc.Edges[2]; e.Source != "n1" || e.Target != "n0" || e.Data != "Back home" {
+		t.Errorf("last edge = %+v, want n1 -> n0 Back home", e)
+	}
+	if !strings.Contains(string(entry.Data), `The &#34;Guide&#34;`) {
+		t.Errorf("title is not escaped:\n%s", entry.Data)
+	}
+}
+
+func TestCheckLinkGraph(t *testing.T) {
+	tests := []struct {
+		format    string
+		singlePDF bool
+		epub      bool
+		wantErr   bool
+	}{
+		{"", false, false, false},
+		{GraphML, false, false, false},
+		{GraphDOT, false, false, false},
+		{GraphJSON, false, false, false},
+		{"svg", false, false, true},
+		{GraphDOT, true, false, true},
+		{GraphJSON, false, true, true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.LinkGraph = tt.format
+		s.SinglePDF = tt.singlePDF
+		if tt.epub {
+			s.Format = FormatEPUB
+		}
+		if err := s.checkLinkGraph(); (err != nil) != tt.wantErr {
+			t.Errorf("checkLinkGraph(%q, single PDF %v, EPUB %v) error = %v, want error %v", tt.format, tt.singlePDF, tt.epub, err, tt.wantErr)
+		}
+	}
+}
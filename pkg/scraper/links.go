@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// brokenLink is an internal link target that could not be fetched.
+type brokenLink struct {
+	URL    string
+	Status int // zero when no response arrived, e.g. on timeouts
+	Error  string
+}
+
+// recordLink remembers that source links to the internal page href, so a
+// broken target can be reported with the pages pointing at it.
+func (s *Scraper) recordLink(source *url.URL, href string) {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return
+	}
+	target := stripFragment(source.ResolveReference(ref))
+	if !s.sameSite(source, target) || (target.Scheme != "http" && target.Scheme != "https") {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := target.String()
+	from := stripFragment(source).String()
+	for _, existing := range s.linkSources[key] {
+		if existing == from {
+			return
+		}
+	}
+	s.linkSources[key] = append(s.linkSources[key], from)
+}
+
+// recordBrokenLink keeps a failed fetch for broken-links.csv.
+func (s *Scraper) recordBrokenLink(u *url.URL, status int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.brokenLinks = append(s.brokenLinks, brokenLink{
+		URL:    stripFragment(u).String(),
+		Status: status,
+		Error:  err.Error(),
+	})
+}
+
+// brokenLinksCSV lists every broken link with the pages linking to it, one
+// row per source page.
+func (s *Scraper) brokenLinksCSV() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	broken := append([]brokenLink(nil), s.brokenLinks...)
+	sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"source", "url", "status", "error"}); err != nil {
+		return nil, err
+	}
+	for _, b := range broken {
+		status := ""
+		if b.Status != 0 {
+			status = strconv.Itoa(b.Status)
+		}
+		sources := s.linkSources[b.URL]
+		if len(sources) == 0 {
+			// The start URL has no referring page
+			sources = []string{""}
+		}
+		for _, source := range sources {
+			if err := w.Write([]string{s.Redact(source), s.Redact(b.URL), status, s.Redact(b.Error)}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,36 @@
+package scraper
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestBrokenLinksCSV(t *testing.T) {
+	s := NewScraper(false, false)
+	home, _ := url.Parse("https://example.com/")
+	docs, _ := url.Parse("https://example.com/docs/")
+
+	s.recordLink(home, "/missing#top")
+	s.recordLink(home, "/missing")
+	s.recordLink(docs, "../missing")
+	s.recordLink(docs, "https://other.example/missing")
+	s.recordLink(docs, "mailto:someone@example.com")
+
+	missing, _ := url.Parse("https://example.com/missing")
+	slow, _ := url.Parse("https://example.com/slow?token=abc")
+	s.recordBrokenLink(missing, 404, errors.New("Not Found"))
+	s.recordBrokenLink(slow, 0, errors.New("timeout"))
+
+	data, err := s.brokenLinksCSV()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "source,url,status,error\n" +
+		"https://example.com/,https://example.com/missing,404,Not Found\n" +
+		"https://example.com/docs/,https://example.com/missing,404,Not Found\n" +
+		",https://example.com/slow?token=REDACTED,,timeout\n"
+	if string(data) != want {
+		t.Errorf("brokenLinksCSV() =\n%s\nwant\n%s", data, want)
+	}
+}
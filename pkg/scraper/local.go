@@ -0,0 +1,218 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// localPageExts are the files of a local tree archived as pages.
+var localPageExts = []string{".html", ".htm", ".xhtml"}
+
+// LocalURL returns the file:// URL of a local directory or file, to crawl
+// it with ScrapeAndSave. Directories get a trailing slash.
+func LocalURL(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	p := filepath.ToSlash(abs)
+	if !strings.HasPrefix(p, "/") {
+		// Windows drive letters
+		p = "/" + p
+	}
+	if info.IsDir() && !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String(), nil
+}
+
+// SiteName returns the name the archives of a crawl from u are named
+// after: its host, or for a local tree its directory's name.
+func SiteName(u *url.URL) string {
+	if u.Scheme != "file" {
+		return u.Host
+	}
+	dir := u.Path
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	return path.Base(strings.TrimSuffix(dir, "/"))
+}
+
+// filePath returns the local path of a file:// URL.
+func filePath(u *url.URL) string {
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p)
+}
+
+// startLocal prepares the crawl of a local tree from the file:// URL
+// start: the tree is its directory, or the directory of its file. Every
+// page of a directory is seeded, so files no link leads to are archived
+// too, and the crawl is kept to the tree.
+func (s *Scraper) startLocal(start *url.URL) (*url.URL, error) {
+	name := filePath(start)
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start URL: %w", err)
+	}
+	root := start.Path
+	if !info.IsDir() {
+		root = path.Dir(root)
+	}
+	s.localRoot = strings.TrimSuffix(root, "/") + "/"
+	if s.PathPrefix == "" {
+		s.PathPrefix = s.localRoot
+	}
+	if !info.IsDir() {
+		return start, nil
+	}
+
+	var pages []string
+	err = filepath.WalkDir(name, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && file != name && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && isLocalPage(file) {
+			u, err := LocalURL(file)
+			if err != nil {
+				return err
+			}
+			pages = append(pages, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%s has no HTML files", name)
+	}
+	for _, page := range pages {
+		u, _ := url.Parse(page)
+		s.seeds = append(s.seeds, s.localTarget(u).String())
+	}
+	if _, err := os.Stat(filepath.Join(name, "index.html")); err != nil {
+		// Without an index the crawl starts from the first page
+		first, _ := url.Parse(s.seeds[0])
+		s.seeds = s.seeds[1:]
+		return first, nil
+	}
+	return start, nil
+}
+
+// isLocalPage reports whether the local file is archived as a page.
+func isLocalPage(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range localPageExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// localTarget maps a link of a local page to the file it means: paths
+// from the site root, such as /docs/, are taken from the tree's root, and
+// index.html files are named by their directory, as a web server would.
+// Links to files that exist outside the tree are left to PathPrefix.
+func (s *Scraper) localTarget(u *url.URL) *url.URL {
+	if s.localRoot == "" || u.Scheme != "file" {
+		return u
+	}
+	target := *u
+	if _, err := os.Stat(filePath(u)); err != nil && !strings.HasPrefix(target.Path, s.localRoot) {
+		target.Path = s.localRoot + strings.TrimPrefix(target.Path, "/")
+	}
+	if path.Base(target.Path) == "index.html" {
+		target.Path = strings.TrimSuffix(target.Path, "index.html")
+	}
+	return &target
+}
+
+// localEntryURL returns the URL a local page is named after in the
+// archive: its path in the tree, on a host named after the tree's root
+// directory.
+func (s *Scraper) localEntryURL(u *url.URL) *url.URL {
+	if s.localRoot == "" || u.Scheme != "file" || !strings.HasPrefix(u.Path, s.localRoot) {
+		return u
+	}
+	return &url.URL{
+		Host: path.Base(s.localRoot),
+		Path: "/" + strings.TrimPrefix(u.Path, s.localRoot),
+	}
+}
+
+// fileTransport answers file:// requests from the files under root, and
+// passes others to base. A directory is answered with its index.html.
+type fileTransport struct {
+	base http.RoundTripper
+	root string // URL path of the tree, ending in a slash
+}
+
+func (t *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "file" {
+		if t.base == nil {
+			return http.DefaultTransport.RoundTrip(req)
+		}
+		return t.base.RoundTrip(req)
+	}
+	if !strings.HasPrefix(path.Clean(req.URL.Path)+"/", t.root) {
+		return fileResponse(req, http.StatusForbidden, nil, ""), nil
+	}
+	name := filePath(req.URL)
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, "index.html")
+	}
+	info, err := os.Stat(name)
+	if err != nil || info.IsDir() {
+		return fileResponse(req, http.StatusNotFound, nil, ""), nil
+	}
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	resp := fileResponse(req, http.StatusOK, body, mime.TypeByExtension(filepath.Ext(name)))
+	resp.Header.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	return resp, nil
+}
+
+// fileResponse returns a response to req with status and body.
+func fileResponse(req *http.Request, status int, body []byte, contentType string) *http.Response {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	resp.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	if contentType != "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+	return resp
+}
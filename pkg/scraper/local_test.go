@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTree writes files, map[slash path]content, under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScrapeLocalTree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "exported-site")
+	writeTree(t, dir, map[string]string{
+		"index.html":       `<html><head><title>Home</title></head><body><p>Home page</p><a href="docs/">Docs</a> <a href="/about.html">About</a></body></html>`,
+		"docs/index.html":  `<html><body><p>Docs index</p><a href="intro.html">Intro</a> <a href="../index.html">Home</a></body></html>`,
+		"docs/intro.html":  `<html><body><p>Introduction</p><a href="../../outside.html">Outside</a></body></html>`,
+		"about.html":       `<html><body><p>About us</p></body></html>`,
+		"orphan/page.htm":  `<html><body><p>Not linked from anywhere</p></body></html>`,
+		"assets/style.css": `body { color: red }`,
+		".git/HEAD.html":   `<html><body><p>Hidden</p></body></html>`,
+	})
+	writeTree(t, filepath.Dir(dir), map[string]string{
+		"outside.html": `<html><body><p>Outside the tree</p></body></html>`,
+	})
+
+	start, err := LocalURL(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(start, "file:///") || !strings.HasSuffix(start, "/") {
+		t.Fatalf("LocalURL(%s) = %s", dir, start)
+	}
+	s := NewScraper(true, false)
+	s.NoTOC = true
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(start, zipname); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for name := range zipEntries(t, zipname) {
+		if strings.HasSuffix(name, ".pdf") {
+			got = append(got, name)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"exported-site_about.html.pdf",
+		"exported-site_docs.pdf",
+		"exported-site_docs_intro.html.pdf",
+		"exported-site_index.pdf",
+		"exported-site_orphan_page.htm.pdf",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("archived %q, want %q", got, want)
+	}
+}
+
+func TestFileTransport(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"site/index.html": "<p>home</p>",
+		"site/page.html":  "<p>page</p>",
+		"secret.txt":      "secret",
+	})
+	root, _ := LocalURL(filepath.Join(dir, "site"))
+	rootURL, _ := url.Parse(root)
+	client := &http.Client{Transport: &fileTransport{root: rootURL.Path}}
+	for name, want := range map[string]int{
+		"":                http.StatusOK,
+		"page.html":       http.StatusOK,
+		"missing.html":    http.StatusNotFound,
+		"../secret.txt":   http.StatusForbidden,
+		"..%2Fsecret.txt": http.StatusForbidden,
+	} {
+		resp, err := client.Get(root + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", name, resp.StatusCode, want)
+		}
+		if want == http.StatusOK && resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("GET %s Content-Type = %q", name, resp.Header.Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// locale holds how dates and numbers are written for an audience.
+type locale struct {
+	// dateTime is a time layout written with English month names, which
+	// are replaced by months.
+	dateTime string
+	months   [12]string
+	// group separates thousands in numbers.
+	group string
+}
+
+// Layouts shown without a locale.
+const (
+	isoDateTime        = "2006-01-02 15:04 MST"
+	isoDateTimeSeconds = "2006-01-02 15:04:05 MST"
+)
+
+var (
+	englishMonths = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	cjkMonths     = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+)
+
+// locales are the locales accepted by Scraper.Locale, by lower case BCP 47
+// tag. A tag that isn't listed falls back to its language.
+var locales = map[string]locale{
+	"en":    {dateTime: "January 2, 2006, 3:04 PM MST", months: englishMonths, group: ","},
+	"en-gb": {dateTime: "2 January 2006, 15:04 MST", months: englishMonths, group: ","},
+	"de": {dateTime: "2. January 2006, 15:04 MST", group: ".",
+		months: [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"}},
+	"fr": {dateTime: "2 January 2006 à 15:04 MST", group: "\u202f",
+		months: [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}},
+	"es": {dateTime: "2 de January de 2006, 15:04 MST", group: ".",
+		months: [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"}},
+	"it": {dateTime: "2 January 2006, 15:04 MST", group: ".",
+		months: [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"}},
+	"pt": {dateTime: "2 de January de 2006, 15:04 MST", group: ".",
+		months: [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"}},
+	"nl": {dateTime: "2 January 2006 15:04 MST", group: ".",
+		months: [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"}},
+	"ja": {dateTime: "2006年January2日 15:04 MST", months: cjkMonths, group: ","},
+	"zh": {dateTime: "2006年January2日 15:04 MST", months: cjkMonths, group: ","},
+}
+
+// LocaleNames returns the locales accepted by Scraper.Locale, sorted.
+func LocaleNames() []string {
+	names := make([]string, 0, len(locales))
+	for tag := range locales {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupLocale returns the locale of a BCP 47 tag such as "de" or "pt-BR".
+// POSIX names such as "de_DE.UTF-8" are accepted too.
+func lookupLocale(tag string) (locale, error) {
+	name := strings.ToLower(tag)
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.ReplaceAll(name, "_", "-")
+	if l, ok := locales[name]; ok {
+		return l, nil
+	}
+	lang, _, _ := strings.Cut(name, "-")
+	if l, ok := locales[lang]; ok {
+		return l, nil
+	}
+	return locale{}, fmt.Errorf("unknown locale %q (available: %s)", tag, strings.Join(LocaleNames(), ", "))
+}
+
+// checkLocale rejects an unknown Locale.
+func (s *Scraper) checkLocale() error {
+	if s.Locale == "" {
+		return nil
+	}
+	_, err := lookupLocale(s.Locale)
+	return err
+}
+
+// formatTime writes t in the TimeZone and Locale for display. Without a
+// locale it uses layout.
+func (s *Scraper) formatTime(t time.Time, layout string) string {
+	zone := s.TimeZone
+	if zone == nil {
+		zone = time.UTC
+	}
+	t = t.In(zone)
+	l, err := lookupLocale(s.Locale)
+	if s.Locale == "" || err != nil {
+		return t.Format(layout)
+	}
+	return localMonth(t.Format(l.dateTime), t.Month(), l)
+}
+
+// localMonth replaces the English name of month in formatted with the
+// locale's.
+func localMonth(formatted string, month time.Month, l locale) string {
+	return strings.Replace(formatted, month.String(), l.months[month-1], 1)
+}
+
+// formatNumber writes n with the Locale's thousands separator.
+func (s *Scraper) formatNumber(n int) string {
+	digits := strconv.Itoa(n)
+	l, err := lookupLocale(s.Locale)
+	if s.Locale == "" || err != nil {
+		return digits
+	}
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(l.group)
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	at := time.Date(2025, time.March, 3, 13, 5, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		zone   *time.Location
+		want   string
+	}{
+		{"", nil, "2025-03-03 13:05 UTC"},
+		{"", berlin, "2025-03-03 14:05 CET"},
+		{"en", nil, "March 3, 2025, 1:05 PM UTC"},
+		{"en-GB", berlin, "3 March 2025, 14:05 CET"},
+		{"de_DE.UTF-8", berlin, "3. März 2025, 14:05 CET"},
+		{"fr-CA", nil, "3 mars 2025 à 13:05 UTC"},
+		{"pt-BR", nil, "3 de março de 2025, 13:05 UTC"},
+		{"ja", berlin, "2025年3月3日 14:05 CET"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			s := &Scraper{Locale: tt.locale, TimeZone: tt.zone}
+			if got := s.formatTime(at, isoDateTime); got != tt.want {
+				t.Errorf("formatTime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{"", 1204, "1204"},
+		{"en", 1204, "1,204"},
+		{"en", 999, "999"},
+		{"de", 1234567, "1.234.567"},
+		{"fr", 1204, "1\u202f204"},
+		{"es", -1204, "-1.204"},
+	}
+	for _, tt := range tests {
+		s := &Scraper{Locale: tt.locale}
+		if got := s.formatNumber(tt.n); got != tt.want {
+			t.Errorf("formatNumber(%d) in %q = %q, want %q", tt.n, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLocale(t *testing.T) {
+	for _, tag := range []string{"", "de", "EN-gb", "zh_CN", "nl-BE"} {
+		if err := (&Scraper{Locale: tag}).checkLocale(); err != nil {
+			t.Errorf("checkLocale(%q) = %v", tag, err)
+		}
+	}
+	err := (&Scraper{Locale: "klingon"}).checkLocale()
+	if err == nil || !strings.Contains(err.Error(), "available") {
+		t.Errorf("checkLocale(klingon) = %v, want the available locales", err)
+	}
+}
@@ -0,0 +1,175 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// manifestHeaders are the response headers captured for every page, chosen
+// so consumers can judge freshness and indexing policy of archived content.
+var manifestHeaders = []string{
+	"Content-Type",
+	"Last-Modified",
+	"ETag",
+	"Cache-Control",
+	"X-Robots-Tag",
+}
+
+// manifestPage describes one archived page in manifest.json.
+type manifestPage struct {
+	// URL is where the page was fetched from, after redirects, and
+	// RequestedURL the link that redirected there.
+	URL          string `json:"url"`
+	RequestedURL string `json:"requested_url,omitempty"`
+	Status       int    `json:"status"`
+	Title        string `json:"title,omitempty"`
+	Author       string `json:"author,omitempty"`
+	// Published is when the page says it was published, if it does.
+	Published *time.Time `json:"published,omitempty"`
+	// FetchedAt is when the page was fetched, or captured when replayed.
+	FetchedAt *time.Time `json:"fetched_at,omitempty"`
+	File      string     `json:"file"`
+	// Renderer is set when a render override or a Fallback gave the page
+	// another renderer than the run's.
+	Renderer string `json:"renderer,omitempty"`
+	// Fallback is set when the page was rendered by Renderer after the
+	// renderer asked for failed on it.
+	Fallback *renderFallback `json:"fallback,omitempty"`
+	// ChromeFile is the Chrome print of the page made by RendererBoth.
+	ChromeFile string `json:"chrome_file,omitempty"`
+	// Snapshot is the self-contained HTML copy of the page.
+	Snapshot  string            `json:"snapshot,omitempty"`
+	Parts     []string          `json:"parts,omitempty"`
+	Variant   string            `json:"variant,omitempty"`
+	Thumbnail string            `json:"thumbnail,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// SHA256 is the hash of the page's body as fetched, which later runs
+	// compare against with Scraper.Baseline.
+	SHA256 string `json:"sha256,omitempty"`
+	// Change is how the page differs from the Baseline: new or changed.
+	Change string `json:"change,omitempty"`
+	// Transforms lists the rules that changed the page's content, in the
+	// order they were applied.
+	Transforms []transform `json:"transforms,omitempty"`
+}
+
+// manifest is written to manifest.json in the archive.
+type manifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	StartURL    string    `json:"start_url"`
+	// Seeds are the further start URLs, see Scraper.Seeds.
+	Seeds    []string `json:"seeds,omitempty"`
+	Format   string   `json:"format,omitempty"`
+	Renderer string   `json:"renderer"`
+	// RenderJS is set when pages were fetched with the headless browser.
+	RenderJS bool `json:"render_js,omitempty"`
+	// RendererFallback explains why the requested renderer or RenderJS was
+	// replaced.
+	RendererFallback string `json:"renderer_fallback,omitempty"`
+	// ReplayedFrom is the WARC file or cache an offline crawl was answered
+	// from.
+	ReplayedFrom string `json:"replayed_from,omitempty"`
+	DocsVersion  string `json:"docs_version,omitempty"`
+	// Locale and TimeZone are those the dates shown in the PDFs and
+	// index.html were written for; the manifest's own times are in UTC.
+	Locale   string            `json:"locale,omitempty"`
+	TimeZone string            `json:"time_zone,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Pages    []manifestPage    `json:"pages"`
+	// Attachments are the documents linked from the pages, archived as
+	// they are, see Scraper.IncludeAttachments.
+	Attachments []attachment  `json:"attachments,omitempty"`
+	Failures    []pageFailure `json:"failures,omitempty"`
+	// Baseline describes the archive a delta archive was compared with,
+	// see Scraper.Baseline.
+	Baseline *baselineSummary `json:"baseline,omitempty"`
+	// Interrupted is set when the crawl was stopped before it was done,
+	// so the archive holds only the pages converted until then.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// Section is the site section of the archive, see Scraper.SplitBy.
+	Section string `json:"section,omitempty"`
+}
+
+// captureHeaders returns the manifestHeaders present in h. Repeated headers
+// are joined with commas as allowed by RFC 9110.
+func captureHeaders(h http.Header) map[string]string {
+	captured := make(map[string]string)
+	for _, name := range manifestHeaders {
+		if values := h.Values(name); len(values) > 0 {
+			captured[name] = strings.Join(values, ", ")
+		}
+	}
+	if len(captured) == 0 {
+		return nil
+	}
+	return captured
+}
+
+func (s *Scraper) manifestJSON(startURL string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := manifest{
+		GeneratedAt:      time.Now().UTC(),
+		StartURL:         s.Redact(startURL),
+		Seeds:            s.redactAll(s.seeds),
+		Format:           s.Format,
+		Renderer:         s.renderer(),
+		RenderJS:         s.RenderJS,
+		RendererFallback: s.rendererFallback,
+		ReplayedFrom:     s.replayedFrom(),
+		DocsVersion:      s.docsVersion,
+		Locale:           s.Locale,
+		Meta:             s.Meta,
+		Pages:            make([]manifestPage, 0, len(s.pdfs)),
+		Attachments:      s.archivedAttachments(),
+		Failures:         s.failures,
+		Baseline:         s.baselineSummary(),
+		Interrupted:      s.interrupted,
+		Section:          s.section,
+	}
+	if s.TimeZone != nil {
+		m.TimeZone = s.TimeZone.String()
+	}
+	for _, p := range s.pdfs {
+		fetchedAt := p.FetchedAt.UTC()
+		m.Pages = append(m.Pages, manifestPage{
+			URL:          s.Redact(p.URL.String()),
+			RequestedURL: s.Redact(p.RequestedURL),
+			Status:       p.Status,
+			Title:        p.Title,
+			Author:       p.Author,
+			FetchedAt:    &fetchedAt,
+			File:         s.pageEntryName(p.URL),
+			Parts:        s.redactAll(p.Parts),
+			Variant:      s.Redact(p.Variant),
+			Headers:      captureHeaders(s.redactHeader(p.Header)),
+			SHA256:       p.SHA256,
+			Change:       p.Change,
+			Transforms:   s.redactTransforms(p.Transforms),
+		})
+		if r := s.renderSettings(p.URL).Renderer; r != m.Renderer {
+			m.Pages[len(m.Pages)-1].Renderer = r
+		}
+		if p.Fallback != nil {
+			m.Pages[len(m.Pages)-1].Renderer = p.Fallback.Renderer
+			m.Pages[len(m.Pages)-1].Fallback = p.Fallback
+		}
+		if !p.Published.IsZero() {
+			published := p.Published.UTC()
+			m.Pages[len(m.Pages)-1].Published = &published
+		}
+		if p.Thumb != nil {
+			m.Pages[len(m.Pages)-1].Thumbnail = s.thumbName(p)
+		}
+		if p.ChromeFile != "" {
+			m.Pages[len(m.Pages)-1].ChromeFile = s.chromeEntryName(p.URL)
+		}
+		if p.SnapshotFile != "" {
+			m.Pages[len(m.Pages)-1].Snapshot = s.snapshotEntryName(p.URL)
+		}
+	}
+	return json.MarshalIndent(m, "", "  ")
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCaptureHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("ETag", `"abc123"`)
+	h.Add("X-Robots-Tag", "noarchive")
+	h.Add("X-Robots-Tag", "googlebot: nofollow")
+	h.Set("Set-Cookie", "session=secret")
+
+	want := map[string]string{
+		"Content-Type": "text/html; charset=utf-8",
+		"ETag":         `"abc123"`,
+		"X-Robots-Tag": "noarchive, googlebot: nofollow",
+	}
+	if got := captureHeaders(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("captureHeaders() = %v, want %v", got, want)
+	}
+
+	if got := captureHeaders(http.Header{}); got != nil {
+		t.Errorf("captureHeaders(empty) = %v, want nil", got)
+	}
+}
+
+func TestManifestPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body><p>Home</p><a href="/old">Moved</a></body></html>`)
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><head><title>New place</title></head><body><p>Moved here</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	before := time.Now().UTC().Add(-time.Second)
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(zipEntries(t, zipname)["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Pages) != 2 {
+		t.Fatalf("manifest lists %d pages, want 2", len(m.Pages))
+	}
+	moved := m.Pages[1]
+	if moved.URL != srv.URL+"/new" || moved.RequestedURL != srv.URL+"/old" {
+		t.Errorf("redirected page is %s requested as %s, want /new requested as /old", moved.URL, moved.RequestedURL)
+	}
+	if m.Pages[0].RequestedURL != "" {
+		t.Errorf("start page requested as %s, want no redirect", m.Pages[0].RequestedURL)
+	}
+	for _, p := range m.Pages {
+		if p.Status != http.StatusOK || p.Title == "" || p.SHA256 == "" || p.File == "" {
+			t.Errorf("page %s = %+v, want status, title, hash and file", p.URL, p)
+		}
+		if p.FetchedAt == nil || p.FetchedAt.Before(before) {
+			t.Errorf("page %s fetched at %v, want the time of the crawl", p.URL, p.FetchedAt)
+		}
+	}
+	if moved.Title != "New place" {
+		t.Errorf("title = %q, want New place", moved.Title)
+	}
+}
@@ -0,0 +1,515 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownBlocks are the elements that start a block of their own.
+var markdownBlocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Dd: true, atom.Details: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Fieldset: true, atom.Figcaption: true,
+	atom.Figure: true, atom.Footer: true, atom.Form: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+	atom.H6: true, atom.Header: true, atom.Hr: true, atom.Li: true,
+	atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true,
+	atom.Pre: true, atom.Section: true, atom.Summary: true, atom.Table: true,
+	atom.Ul: true,
+}
+
+// skippedElements are the elements left out of converted pages entirely.
+var skippedElements = map[atom.Atom]bool{
+	atom.Button: true, atom.Canvas: true, atom.Embed: true, atom.Head: true,
+	atom.Iframe: true, atom.Input: true, atom.Link: true, atom.Meta: true,
+	atom.Noscript: true, atom.Object: true, atom.Script: true, atom.Select: true,
+	atom.Style: true, atom.Svg: true, atom.Template: true, atom.Textarea: true,
+	atom.Title: true,
+}
+
+// markdownLineStart matches text at the start of a line that Markdown would
+// read as a heading, list item or quote.
+var markdownLineStart = regexp.MustCompile(`^(#{1,6}|[-+>]|\d+[.)])(\s|$)`)
+
+// markdownBlock is a converted block element.
+type markdownBlock struct {
+	Text string
+	// List is set for lists, which follow the text of a list item without
+	// a blank line.
+	List bool
+}
+
+// markdownWriter converts HTML to CommonMark with GitHub tables.
+type markdownWriter struct {
+	// base resolves the relative URLs of links and images.
+	base *url.URL
+}
+
+// htmlToMarkdown converts the body of doc to Markdown, resolving links and
+// images against base.
+func htmlToMarkdown(doc *html.Node, base *url.URL) string {
+	m := &markdownWriter{base: documentBase(doc, base)}
+	root := doc
+	if body := findElement(doc, atom.Body); body != nil {
+		root = body
+	}
+	return m.join(m.blocks(root), false) + "\n"
+}
+
+// findElement returns the first element of type a in n, depth first.
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// join separates blocks with blank lines. In list items, nested lists
+// are kept tight.
+func (m *markdownWriter) join(blocks []markdownBlock, item bool) string {
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			if item && block.List {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(block.Text)
+	}
+	return b.String()
+}
+
+// blocks converts the children of n. Runs of inline content become
+// paragraphs.
+func (m *markdownWriter) blocks(n *html.Node) []markdownBlock {
+	var out []markdownBlock
+	var para strings.Builder
+	flush := func() {
+		if text := paragraph(para.String()); text != "" {
+			out = append(out, markdownBlock{Text: text})
+		}
+		para.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && skippedElements[c.DataAtom] {
+			continue
+		}
+		if c.Type == html.ElementNode && markdownBlocks[c.DataAtom] {
+			flush()
+			if text := m.block(c); text != "" {
+				out = append(out, markdownBlock{Text: text, List: c.DataAtom == atom.Ul || c.DataAtom == atom.Ol})
+			}
+			continue
+		}
+		m.inline(&para, c)
+	}
+	flush()
+	return out
+}
+
+// block converts the block element n.
+func (m *markdownWriter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.Join(strings.Fields(m.inlineString(n)), " ")
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + text
+	case atom.P, atom.Dt, atom.Summary, atom.Figcaption:
+		return paragraph(m.inlineString(n))
+	case atom.Ul, atom.Ol:
+		return m.list(n)
+	case atom.Pre:
+		return codeBlock(n)
+	case atom.Blockquote:
+		return prefixLines(m.join(m.blocks(n), false), "> ", ">")
+	case atom.Table:
+		return m.table(n)
+	case atom.Hr:
+		return "---"
+	}
+	return m.join(m.blocks(n), false)
+}
+
+// list converts an ul or ol element. Items are indented to line up with
+// their marker, so nested blocks stay inside them.
+func (m *markdownWriter) list(n *html.Node) string {
+	number := 1
+	if start, err := strconv.Atoi(getAttr(n, "start")); err == nil {
+		number = start
+	}
+	var items []string
+	var indent string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || skippedElements[c.DataAtom] {
+			continue
+		}
+		if c.DataAtom != atom.Li {
+			// A list nested directly in a list belongs to the item before
+			if text := m.block(c); text != "" && len(items) > 0 {
+				items[len(items)-1] += "\n" + prefixLines(text, indent, "")
+			}
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		indent = strings.Repeat(" ", len(marker))
+		item := marker + strings.TrimPrefix(prefixLines(m.join(m.blocks(c), true), indent, ""), indent)
+		items = append(items, strings.TrimRight(item, " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table converts a table to a GitHub table, its first row the header.
+func (m *markdownWriter) table(n *html.Node) string {
+	var rows [][]string
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				collect(c)
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						text := strings.Join(strings.Fields(m.inlineString(cell)), " ")
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	collect(n)
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return ""
+	}
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// inlineString converts the children of n as inline content.
+func (m *markdownWriter) inlineString(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		m.inline(&b, c)
+	}
+	return b.String()
+}
+
+// inline writes n as inline content to b.
+func (m *markdownWriter) inline(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		writeSpaced(b, escapeMarkdown(collapseSpace(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skippedElements[n.DataAtom] {
+		return
+	}
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		m.wrap(b, n, "**", "**")
+	case atom.Em, atom.I, atom.Cite:
+		m.wrap(b, n, "*", "*")
+	case atom.Del, atom.S, atom.Strike:
+		m.wrap(b, n, "~~", "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		writeSpaced(b, codeSpan(textContent(n)))
+	case atom.Br:
+		b.WriteString("\\\n")
+	case atom.A:
+		text := strings.TrimSpace(m.inlineString(n))
+		href := strings.TrimSpace(getAttr(n, "href"))
+		target := m.resolve(href)
+		if text == "" {
+			return
+		}
+		if target == "" {
+			writeSpaced(b, text)
+			return
+		}
+		writeSpaced(b, "["+text+"]("+target+")")
+	case atom.Img:
+		src := m.resolve(strings.TrimSpace(getAttr(n, "src")))
+		if src == "" {
+			return
+		}
+		alt := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(collapseSpace(getAttr(n, "alt")))
+		writeSpaced(b, "!["+strings.TrimSpace(alt)+"]("+src+")")
+	default:
+		if markdownBlocks[n.DataAtom] {
+			// Block content inside inline content, e.g. in a table cell
+			writeSpaced(b, " ")
+			defer writeSpaced(b, " ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			m.inline(b, c)
+		}
+	}
+}
+
+// wrap writes the inline content of n between open and close, keeping its
+// surrounding spaces outside the markers.
+func (m *markdownWriter) wrap(b *strings.Builder, n *html.Node, open, close string) {
+	inner := m.inlineString(n)
+	trimmed := strings.TrimSpace(inner)
+	if trimmed == "" {
+		if inner != "" {
+			writeSpaced(b, " ")
+		}
+		return
+	}
+	if strings.TrimLeft(inner, " \n") != inner {
+		writeSpaced(b, " ")
+	}
+	b.WriteString(open + trimmed + close)
+	if strings.TrimRight(inner, " \n") != inner {
+		b.WriteString(" ")
+	}
+}
+
+// resolve returns href as an absolute URL, or "" for links that lead
+// nowhere outside the page, such as javascript: links. Fragments of the
+// page itself are kept as they are.
+func (m *markdownWriter) resolve(href string) string {
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	if strings.HasPrefix(href, "#") {
+		return href
+	}
+	target, err := m.base.Parse(href)
+	if err != nil {
+		return ""
+	}
+	s := target.String()
+	if strings.ContainsAny(s, " ()") {
+		return "<" + s + ">"
+	}
+	return s
+}
+
+// writeSpaced writes s to b without doubling the space between them.
+func writeSpaced(b *strings.Builder, s string) {
+	if s == "" {
+		return
+	}
+	if s[0] == ' ' {
+		if str := b.String(); strings.HasSuffix(str, " ") || strings.HasSuffix(str, "\n") {
+			s = s[1:]
+		}
+	}
+	b.WriteString(s)
+}
+
+// collapseSpace replaces every run of white space in s with one space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// escapeMarkdown escapes the characters of text that Markdown would read as
+// formatting. Underscores inside words are left alone, they never start
+// emphasis.
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for i, r := range runes {
+		switch r {
+		case '\\', '*', '`', '[', ']':
+			b.WriteByte('\\')
+		case '_':
+			inWord := i > 0 && i < len(runes)-1 && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+			if !inWord {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// paragraph trims the lines of converted inline content and escapes line
+// starts that would read as block syntax.
+func paragraph(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if markdownLineStart.MatchString(line) {
+			line = `\` + line
+		}
+		lines[i] = line
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// prefixLines prefixes every line of text, empty lines with empty.
+func prefixLines(text, prefix, empty string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = empty
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text of n as is, with line breaks for br.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// codeSpan returns code as an inline code span, with enough backticks
+// around it to hold the ones inside.
+func codeSpan(code string) string {
+	code = collapseSpace(code)
+	if strings.TrimSpace(code) == "" {
+		return code
+	}
+	fence := strings.Repeat("`", longestRun(code, '`')+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// codeBlock converts a pre element to a fenced code block, tagged with the
+// language named by a language-* or lang-* class of the pre or its code.
+func codeBlock(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+	if code == "" {
+		return ""
+	}
+	lang := codeLanguage(n)
+	if c := n.FirstChild; lang == "" && c != nil && c.DataAtom == atom.Code {
+		lang = codeLanguage(c)
+	}
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(getAttr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(class, prefix) {
+				return strings.TrimPrefix(class, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// longestRun returns the length of the longest run of r in s.
+func longestRun(s string, r rune) int {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == r {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// renderMarkdown writes the page's Markdown, converted when its content
+// was extracted, with front matter when FrontMatter is set.
+func (s *Scraper) renderMarkdown(p *page) error {
+	var data []byte
+	if s.FrontMatter != "" {
+		fm, err := s.frontMatter(p, s.FrontMatter)
+		if err != nil {
+			return err
+		}
+		data = fm
+	}
+	data = append(data, strings.TrimSpace(p.Text)+"\n"...)
+	if err := os.WriteFile(p.File, data, 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown: %w", err)
+	}
+	return nil
+}
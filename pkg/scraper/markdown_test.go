@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/intro")
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"headings and paragraphs",
+			`<h1>Title</h1><p>Some <b>bold</b> and <em>italic</em> text.</p><h3> Sub  <i>head</i></h3>`,
+			"# Title\n\nSome **bold** and *italic* text.\n\n### Sub *head*"},
+		{"spaces stay outside emphasis",
+			`<p>a<strong> b </strong>c</p>`,
+			"a **b** c"},
+		{"links and images are absolute",
+			`<p><a href="../guide">Guide</a>, <a href="#top">top</a>, <a href="javascript:void(0)">menu</a> <img src="/logo.png" alt="Logo"></p>`,
+			"[Guide](https://example.com/guide), [top](#top), menu ![Logo](https://example.com/logo.png)"},
+		{"nested lists",
+			`<ul><li>One<ul><li>Nested</li></ul></li><li>Two</li></ul><ol start="3"><li>Three</li><li><p>Four</p><p>More</p></li></ol>`,
+			"- One\n  - Nested\n- Two\n\n3. Three\n4. Four\n\n   More"},
+		{"code",
+			"<p>Run <code>go test</code></p><pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>",
+			"Run `go test`\n\n```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```"},
+		{"code with backticks",
+			"<p><code>a`b</code></p><pre>```\nfenced\n```</pre>",
+			"``a`b``\n\n````\n```\nfenced\n```\n````"},
+		{"table",
+			`<table><thead><tr><th>Name</th><th>Value</th></tr></thead><tbody><tr><td>a|b</td><td><p>1</p></td></tr><tr><td>c</td></tr></tbody></table>`,
+			"| Name | Value |\n| --- | --- |\n| a\\|b | 1 |\n| c |  |"},
+		{"blockquote and rule",
+			`<blockquote><p>Quoted</p><p>Twice</p></blockquote><hr><p>After<br>break</p>`,
+			"> Quoted\n>\n> Twice\n\n---\n\nAfter\\\nbreak"},
+		{"escaping",
+			`<p>2 * 3 = [six] in snake_case or _this_</p><p># not a heading</p>`,
+			"2 \\* 3 = \\[six\\] in snake_case or \\_this\\_\n\n\\# not a heading"},
+		{"scripts and forms are left out",
+			`<script>alert(1)</script><style>p{}</style><p>Kept</p><form><input name="q"><button>Go</button></form>`,
+			"Kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := htmlToMarkdown(doc, base); got != tt.want+"\n" {
+				t.Errorf("htmlToMarkdown() =\n%q\nwant\n%q", got, tt.want+"\n")
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	u, _ := url.Parse("https://example.com/post")
+	s := &Scraper{Format: FormatMarkdown, FrontMatter: FrontMatterHugo}
+	p := &page{
+		URL:   u,
+		Title: "Post",
+		Body:  []byte(`<html><body><h1>Post</h1><p>Text</p></body></html>`),
+		File:  filepath.Join(t.TempDir(), s.pageEntryName(u)),
+	}
+	text, err := s.extractText(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Text = text
+	if err := s.render(p); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(p.File) != "example.com_post.md" {
+		t.Errorf("file = %s", p.File)
+	}
+	data, err := os.ReadFile(p.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "---\ntitle: Post\n") || !strings.HasSuffix(string(data), "---\n\n# Post\n\nText\n") {
+		t.Errorf("markdown file:\n%s", data)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       *Scraper
+		wantErr bool
+	}{
+		{"default", &Scraper{}, false},
+		{"markdown", &Scraper{Format: FormatMarkdown, FrontMatter: FrontMatterJekyll}, false},
+		{"unknown", &Scraper{Format: "docx"}, true},
+		{"front matter without markdown", &Scraper{FrontMatter: FrontMatterHugo}, true},
+		{"markdown in a single PDF", &Scraper{Format: FormatMarkdown, SinglePDF: true}, true},
+		{"markdown printed by chrome", &Scraper{Format: FormatMarkdown, Renderer: RendererChrome}, true},
+		{"markdown thumbnails", &Scraper{Format: FormatMarkdown, Thumbnails: true}, true},
+		{"epub", &Scraper{Format: FormatEPUB}, false},
+		{"epub front matter", &Scraper{Format: FormatEPUB, FrontMatter: FrontMatterHugo}, true},
+		{"epub evidence", &Scraper{Format: FormatEPUB, Evidence: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.s.checkFormat(); (err != nil) != tt.wantErr {
+				t.Errorf("checkFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
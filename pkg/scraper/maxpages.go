@@ -0,0 +1,30 @@
+package scraper
+
+// takePage reserves a place in the archive for a page once it passed the
+// filters. It returns false when MaxPages pages already have one.
+func (s *Scraper) takePage() bool {
+	if s.pagesTaken.Add(1) <= int64(s.MaxPages) || s.MaxPages <= 0 {
+		return true
+	}
+	s.pagesTaken.Add(-1)
+	return false
+}
+
+// releasePage gives back the place of a page that failed to render, so
+// another page can take it.
+func (s *Scraper) releasePage() {
+	s.pagesTaken.Add(-1)
+}
+
+// pageLimitReached reports whether MaxPages pages have a place in the
+// archive, after which no more pages are fetched. It logs the first time
+// the crawl stops for it.
+func (s *Scraper) pageLimitReached() bool {
+	if s.MaxPages <= 0 || s.pagesTaken.Load() < int64(s.MaxPages) {
+		return false
+	}
+	s.pageLimitOnce.Do(func() {
+		s.logf("Reached the limit of %d pages, finishing the archive\n", s.MaxPages)
+	})
+	return true
+}
@@ -0,0 +1,25 @@
+package scraper
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMaxPages(t *testing.T) {
+	srv := linkedSite(40, 10)
+	defer srv.Close()
+
+	for _, parallelism := range []int{1, 8} {
+		s := NewScraper(true, false)
+		s.MaxDepth = 0
+		s.MaxPages = 5
+		s.Parallelism = parallelism
+		zipname := filepath.Join(t.TempDir(), "site.zip")
+		if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+			t.Fatal(err)
+		}
+		if got := archivedPDFs(t, zipname); got != 5 {
+			t.Errorf("parallelism %d: archived %d PDFs, want 5", parallelism, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// metaKeyPattern restricts metadata keys to valid XML names, as they become
+// XMP property names.
+var metaKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// ParseMeta turns key=value pairs into a metadata map. Later pairs override
+// earlier ones with the same key.
+func ParseMeta(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	meta := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fmt.Errorf("invalid metadata %q, expected key=value", pair)
+		}
+		if !metaKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid metadata key %q: use letters, digits, '_', '-' and '.'", key)
+		}
+		meta[key] = value
+	}
+	return meta, nil
+}
+
+// sortedKeys returns the keys of meta in a stable order.
+func sortedKeys(meta map[string]string) []string {
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metaKeywords formats meta as key=value keywords, quoting values that
+// contain spaces.
+func metaKeywords(meta map[string]string) string {
+	var parts []string
+	for _, k := range sortedKeys(meta) {
+		v := meta[k]
+		if strings.ContainsAny(v, " \t\"") {
+			v = strconv.Quote(v)
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// xmpMetadata returns an XMP packet holding meta as custom document
+// properties in the pdfx namespace, which is where Acrobat and most document
+// management systems look for them.
+func xmpMetadata(meta map[string]string) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\ufeff\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdfx=\"http://ns.adobe.com/pdfx/1.3/\">\n")
+	for _, k := range sortedKeys(meta) {
+		fmt.Fprintf(&b, "   <pdfx:%s>", k)
+		xml.EscapeText(&b, []byte(meta[k]))
+		fmt.Fprintf(&b, "</pdfx:%s>\n", k)
+	}
+	b.WriteString("  </rdf:Description>\n")
+	b.WriteString(" </rdf:RDF>\n")
+	b.WriteString("</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// stampMetadata sets the PDF's keywords to keywords followed by the archive
+// metadata, which also goes in its XMP packet. gofpdf can't read keywords
+// back, so they are set once here.
+func stampMetadata(pdf *gofpdf.Fpdf, meta map[string]string, keywords string) {
+	if k := strings.TrimSpace(keywords + " " + metaKeywords(meta)); k != "" {
+		pdf.SetKeywords(k, false)
+	}
+	if len(meta) > 0 {
+		pdf.SetXmpMetadata(xmpMetadata(meta))
+	}
+}
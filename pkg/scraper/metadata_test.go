@@ -0,0 +1,52 @@
+package scraper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"pairs", []string{"case=2024-117", "project=Atlas"}, map[string]string{"case": "2024-117", "project": "Atlas"}, false},
+		{"value with equals sign", []string{"query=a=b"}, map[string]string{"query": "a=b"}, false},
+		{"later pair wins", []string{"case=1", "case=2"}, map[string]string{"case": "2"}, false},
+		{"empty value", []string{"note="}, map[string]string{"note": ""}, false},
+		{"missing equals sign", []string{"case"}, nil, true},
+		{"key with space", []string{"case number=1"}, nil, true},
+		{"key starting with digit", []string{"1case=1"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMeta(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMeta() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMeta() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetaKeywords(t *testing.T) {
+	got := metaKeywords(map[string]string{"project": "Atlas", "client": "ACME Corp"})
+	want := `client="ACME Corp" project=Atlas`
+	if got != want {
+		t.Errorf("metaKeywords() = %q, want %q", got, want)
+	}
+}
+
+func TestXMPMetadataEscapes(t *testing.T) {
+	xmp := string(xmpMetadata(map[string]string{"note": "a < b & c"}))
+	if !strings.Contains(xmp, "<pdfx:note>a &lt; b &amp; c</pdfx:note>") {
+		t.Errorf("xmpMetadata() = %s", xmp)
+	}
+}
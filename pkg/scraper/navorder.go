@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Page orders accepted by Scraper.Order.
+const (
+	// OrderCrawl keeps pages in the order they were fetched.
+	OrderCrawl = "crawl"
+	// OrderNav follows the site's navigation, using NavSelector or, when it
+	// is empty, the detected sidebar.
+	OrderNav = "nav"
+	// OrderDate puts pages oldest first by publication date, such as the
+	// posts of a blog, and pages without a date last.
+	OrderDate = "date"
+)
+
+// navCandidates selects the elements that may hold a site's table of
+// contents when no selector is configured.
+var navCandidates = cascadia.MustCompile(`nav, aside, [role=navigation], [class*=sidebar], [class*=toc], [id*=sidebar], [id*=toc], [class*=menu]`)
+
+// navLinks returns the absolute, fragment-free targets of the links matched
+// by sel, in document order and without duplicates.
+func navLinks(body []byte, base *url.URL, sel cascadia.Selector) []string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return linkTargets(sel.MatchAll(doc), base)
+}
+
+// detectNavLinks picks the navigation-like element with the most links to
+// base's host and returns its link targets.
+func detectNavLinks(body []byte, base *url.URL) []string {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var best []string
+	for _, n := range navCandidates.MatchAll(doc) {
+		var links []string
+		for _, link := range linkTargets([]*html.Node{n}, base) {
+			if u, err := url.Parse(link); err == nil && u.Host == base.Host {
+				links = append(links, link)
+			}
+		}
+		if len(links) > len(best) {
+			best = links
+		}
+	}
+	return best
+}
+
+// linkTargets collects the href targets of the <a> elements in or below
+// nodes, resolved against base, without fragments or duplicates.
+func linkTargets(nodes []*html.Node, base *url.URL) []string {
+	seen := make(map[string]bool)
+	var links []string
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			for _, a := range n.Attr {
+				if a.Key != "href" {
+					continue
+				}
+				ref, err := url.Parse(a.Val)
+				if err != nil {
+					continue
+				}
+				target := stripFragment(base.ResolveReference(ref)).String()
+				if !seen[target] {
+					seen[target] = true
+					links = append(links, target)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	for _, n := range nodes {
+		visit(n)
+	}
+	return links
+}
+
+// recordNav keeps the longest navigation list seen so far. Sidebars often
+// only expand the current section, so the fullest one is the best guess at
+// the site's table of contents.
+func (s *Scraper) recordNav(p *page) {
+	var links []string
+	if s.navSelector != nil {
+		links = navLinks(p.Body, p.URL, s.navSelector)
+	} else {
+		links = detectNavLinks(p.Body, p.URL)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(links) > len(s.navOrder) {
+		s.navOrder = links
+	}
+}
+
+// sortByNav orders the generated pages by their position in the recorded
+// navigation. Pages missing from it keep their crawl order after the others.
+func (s *Scraper) sortByNav() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.navOrder) == 0 {
+		return
+	}
+	position := make(map[string]int, len(s.navOrder))
+	for i, link := range s.navOrder {
+		position[link] = i
+	}
+	rank := func(p *page) int {
+		if i, ok := position[stripFragment(p.URL).String()]; ok {
+			return i
+		}
+		return len(s.navOrder)
+	}
+	sort.SliceStable(s.pdfs, func(i, j int) bool {
+		return rank(s.pdfs[i]) < rank(s.pdfs[j])
+	})
+}
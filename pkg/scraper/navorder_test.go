@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+)
+
+func TestNavLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/intro")
+	body := []byte(`<aside class="sidebar">
+		<a href="intro">Intro</a>
+		<a href="/docs/install#linux">Install</a>
+		<a href="/docs/install">Install again</a>
+		<a href="usage">Usage</a>
+	</aside><main><a href="/blog">Blog</a></main>`)
+
+	got := navLinks(body, base, cascadia.MustCompile(".sidebar a"))
+	want := []string{
+		"https://example.com/docs/intro",
+		"https://example.com/docs/install",
+		"https://example.com/docs/usage",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("navLinks() = %v, want %v", got, want)
+	}
+}
+
+func TestSortByNav(t *testing.T) {
+	mustPage := func(raw string) *page {
+		u, _ := url.Parse(raw)
+		return &page{URL: u}
+	}
+	s := NewScraper(true, false)
+	s.pdfs = []*page{
+		mustPage("https://example.com/docs/usage"),
+		mustPage("https://example.com/about"),
+		mustPage("https://example.com/docs/intro"),
+		mustPage("https://example.com/contact"),
+		mustPage("https://example.com/docs/install"),
+	}
+	s.navOrder = []string{
+		"https://example.com/docs/intro",
+		"https://example.com/docs/install",
+		"https://example.com/docs/usage",
+	}
+
+	s.sortByNav()
+
+	var got []string
+	for _, p := range s.pdfs {
+		got = append(got, p.URL.Path)
+	}
+	want := []string{"/docs/intro", "/docs/install", "/docs/usage", "/about", "/contact"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestDetectNavLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/intro")
+	body := []byte(`<header><nav><a href="/">Home</a><a href="https://github.com/x">GitHub</a></nav></header>
+		<div class="sidebar-wrapper"><ul>
+			<li><a href="/docs/intro">Intro</a></li>
+			<li><a href="/docs/install">Install</a></li>
+			<li><a href="/docs/usage">Usage</a></li>
+		</ul></div>
+		<main><p>Content with <a href="/docs/usage">a link</a>.</p></main>`)
+
+	got := detectNavLinks(body, base)
+	want := []string{
+		"https://example.com/docs/intro",
+		"https://example.com/docs/install",
+		"https://example.com/docs/usage",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("detectNavLinks() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Output modes accepted by Scraper.OutputMode.
+const (
+	// OutputZip writes one archive file, in Scraper.ArchiveFormat.
+	OutputZip = "zip"
+	// OutputDir writes the archive's files to a directory instead, pages
+	// in a tree mirroring their URL paths.
+	OutputDir = "dir"
+)
+
+// treeDir is the work directory subdirectory the tree of OutputDir is built
+// in before it's moved to the output.
+const treeDir = "tree"
+
+// checkOutputMode rejects unknown output modes and directories for formats
+// that write a single file.
+func (s *Scraper) checkOutputMode() error {
+	switch s.OutputMode {
+	case "", OutputZip:
+		return nil
+	case OutputDir:
+	default:
+		return fmt.Errorf("unknown output mode %q (available: %s, %s)", s.OutputMode, OutputZip, OutputDir)
+	}
+	switch {
+	case s.SinglePDF:
+		return fmt.Errorf("a single PDF is one file, it can't be written as a directory")
+	case s.Format == FormatEPUB:
+		return fmt.Errorf("an EPUB book is one file, it can't be written as a directory")
+	}
+	return nil
+}
+
+// writesTree reports whether the output is a directory tree.
+func (s *Scraper) writesTree() bool {
+	return s.OutputMode == OutputDir
+}
+
+// treeEntryName returns the path of the PDF of u in a directory tree: the
+// URL path with ".pdf" appended, "index.pdf" for paths ending in a slash.
+// Dot segments are resolved so no path leaves the tree.
+func treeEntryName(u *url.URL) string {
+	p := path.Clean("/" + u.Path)
+	if p == "/" || strings.HasSuffix(u.Path, "/") {
+		p = path.Join(p, "index")
+	}
+	return strings.TrimPrefix(p, "/") + ".pdf"
+}
+
+// baseEntryName returns the archive name of the PDF of u in the output
+// mode, under its author's directory with GroupByAuthor, from which the
+// names of its other files are derived.
+func (s *Scraper) baseEntryName(u *url.URL) string {
+	dir := s.authorDir(u)
+	u = s.localEntryURL(u)
+	name := entryName(u)
+	if s.writesTree() {
+		name = s.hostTreeName(u, treeEntryName(u))
+	}
+	if dir != "" {
+		return dir + "/" + name
+	}
+	return name
+}
+
+// createDir writes the pages and extras to the directory dirname. The tree
+// is built in the work directory and replaces dirname once complete.
+func (s *Scraper) createDir(dirname string, extras []archiveEntry) error {
+	tree := filepath.Join(workDirName(dirname), treeDir)
+	if err := os.RemoveAll(tree); err != nil {
+		return fmt.Errorf("failed to clear work directory: %w", err)
+	}
+	for _, entry := range s.archiveEntries(extras) {
+		name := filepath.Join(tree, filepath.FromSlash(entry.Name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := writeEntry(name, entry); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.Name, err)
+		}
+	}
+	if err := os.RemoveAll(dirname); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", dirname, err)
+	}
+	return os.Rename(tree, dirname)
+}
+
+// writeEntry writes the content of entry to the file name.
+func writeEntry(name string, entry archiveEntry) error {
+	if entry.File == "" {
+		return os.WriteFile(name, entry.Data, 0644)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(f, entry.File); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
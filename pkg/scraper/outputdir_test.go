@@ -0,0 +1,94 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeEntryName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com", "index.pdf"},
+		{"https://example.com/", "index.pdf"},
+		{"https://example.com/docs/getting-started/install", "docs/getting-started/install.pdf"},
+		{"https://example.com/docs/", "docs/index.pdf"},
+		{"https://example.com/guide.html?page=2", "guide.html.pdf"},
+		{"https://example.com/a/../../etc/passwd", "etc/passwd.pdf"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := treeEntryName(u); got != tt.want {
+			t.Errorf("treeEntryName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCreateDir(t *testing.T) {
+	work := t.TempDir()
+	s := NewScraper(false, false)
+	s.OutputMode = OutputDir
+	for _, raw := range []string{"https://example.com/", "https://example.com/docs/install"} {
+		u, _ := url.Parse(raw)
+		file := filepath.Join(work, entryName(u))
+		if err := os.WriteFile(file, []byte(raw), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s.pdfs = append(s.pdfs, &page{URL: u, File: file})
+	}
+
+	out := filepath.Join(t.TempDir(), "example.com")
+	// An earlier run's directory is replaced
+	if err := os.MkdirAll(filepath.Join(out, "stale"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.createDir(out, []archiveEntry{{Name: "index.html", Data: []byte("<html>")}}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"index.pdf":        "https://example.com/",
+		"docs/install.pdf": "https://example.com/docs/install",
+		"index.html":       "<html>",
+	} {
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "stale")); !os.IsNotExist(err) {
+		t.Errorf("stale directory of the earlier run kept: %v", err)
+	}
+}
+
+func TestCheckOutputMode(t *testing.T) {
+	tests := []struct {
+		mode      string
+		singlePDF bool
+		format    string
+		wantErr   bool
+	}{
+		{"", false, "", false},
+		{OutputZip, true, "", false},
+		{OutputDir, false, FormatMarkdown, false},
+		{OutputDir, true, "", true},
+		{OutputDir, false, FormatEPUB, true},
+		{"rar", false, "", true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.OutputMode, s.SinglePDF, s.Format = tt.mode, tt.singlePDF, tt.format
+		if err := s.checkOutputMode(); (err != nil) != tt.wantErr {
+			t.Errorf("checkOutputMode(%q, single PDF %v, format %q) error = %v, want error %v", tt.mode, tt.singlePDF, tt.format, err, tt.wantErr)
+		}
+	}
+}
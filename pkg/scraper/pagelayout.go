@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Page sizes accepted by Scraper.PageSize, in any case.
+const (
+	PageSizeA4     = "A4"
+	PageSizeA3     = "A3"
+	PageSizeLetter = "Letter"
+	PageSizeLegal  = "Legal"
+)
+
+// pageSizes are the portrait dimensions of the page sizes in millimetres,
+// by lowercase name.
+var pageSizes = map[string]gofpdf.SizeType{
+	"a4":     {Wd: 210, Ht: 297},
+	"a3":     {Wd: 297, Ht: 420},
+	"letter": {Wd: 215.9, Ht: 279.4},
+	"legal":  {Wd: 215.9, Ht: 355.6},
+}
+
+// mmPerInch converts millimetres to the inches the browser prints in.
+const mmPerInch = 25.4
+
+// Margins are the blank space around the content of a PDF page, in
+// millimetres.
+type Margins struct {
+	Top, Right, Bottom, Left float64
+}
+
+// ParseMargins parses margins in millimetres written like in CSS: "15" for
+// every side, "20,15" for the top and bottom then the sides, or
+// "20,15,25,15" for the top, right, bottom and left.
+func ParseMargins(s string) (*Margins, error) {
+	fields := strings.Split(s, ",")
+	values := make([]float64, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("invalid margins %q: want one, two or four millimetre values, e.g. 15 or 20,15", s)
+		}
+		values = append(values, v)
+	}
+	switch len(values) {
+	case 1:
+		return &Margins{values[0], values[0], values[0], values[0]}, nil
+	case 2:
+		return &Margins{values[0], values[1], values[0], values[1]}, nil
+	case 4:
+		return &Margins{values[0], values[1], values[2], values[3]}, nil
+	}
+	return nil, fmt.Errorf("invalid margins %q: want one, two or four millimetre values, e.g. 15 or 20,15", s)
+}
+
+// checkPageLayout rejects unknown page sizes and orientations, and margins
+// leaving no room for the content.
+func (s *Scraper) checkPageLayout() error {
+	if s.PageSize == "" && s.Orientation == "" && s.Margins == nil {
+		return nil
+	}
+	if s.Format != "" && s.Format != FormatPDF {
+		return fmt.Errorf("the page size, orientation and margins apply to PDFs, they can't be combined with the %s format", s.Format)
+	}
+	if _, ok := pageSizes[strings.ToLower(s.PageSize)]; !ok && s.PageSize != "" {
+		return fmt.Errorf("unknown page size %q (available: %s, %s, %s, %s)", s.PageSize, PageSizeA4, PageSizeA3, PageSizeLetter, PageSizeLegal)
+	}
+	switch s.Orientation {
+	case "", OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("unknown orientation %q (available: %s, %s)", s.Orientation, OrientationPortrait, OrientationLandscape)
+	}
+	if m := s.Margins; m != nil {
+		// The narrower side of either orientation
+		size := s.pageSize()
+		if m.Left+m.Right >= size.Wd || m.Top+m.Bottom >= size.Wd {
+			return fmt.Errorf("margins of %gmm leave no room on a %.0fmm wide page", max(m.Left+m.Right, m.Top+m.Bottom), size.Wd)
+		}
+	}
+	return nil
+}
+
+// pageSize returns the portrait dimensions of the PDF pages in
+// millimetres, A4 unless PageSize is set.
+func (s *Scraper) pageSize() gofpdf.SizeType {
+	if size, ok := pageSizes[strings.ToLower(s.PageSize)]; ok {
+		return size
+	}
+	return pageSizes["a4"]
+}
+
+// applyMargins sets the Margins on a document of the text renderer, in
+// place of those of its typography.
+func (s *Scraper) applyMargins(pdf *gofpdf.Fpdf) {
+	if m := s.Margins; m != nil {
+		pdf.SetMargins(m.Left, m.Top, m.Right)
+		pdf.SetAutoPageBreak(true, m.Bottom)
+	}
+}
+
+// printSettings are how the browser prints a page.
+type printSettings struct {
+	size      gofpdf.SizeType // portrait, in millimetres
+	landscape bool
+	margins   *Margins // nil keeps the browser's
+	noScripts bool     // the page's JavaScript isn't run
+}
+
+// printSettings returns how the browser prints the page at u.
+func (s *Scraper) printSettings(u *url.URL) printSettings {
+	rs := s.renderSettings(u)
+	return printSettings{size: s.pageSize(), landscape: rs.landscape(), margins: s.Margins, noScripts: !rs.JavaScript}
+}
@@ -0,0 +1,84 @@
+package scraper
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestParseMargins(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Margins
+	}{
+		{"15", Margins{15, 15, 15, 15}},
+		{"20, 10", Margins{20, 10, 20, 10}},
+		{"20,15,25,10.5", Margins{20, 15, 25, 10.5}},
+	}
+	for _, tt := range tests {
+		got, err := ParseMargins(tt.in)
+		if err != nil {
+			t.Errorf("ParseMargins(%q): %v", tt.in, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseMargins(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "10,20,30", "-5", "1cm"} {
+		if _, err := ParseMargins(in); err == nil {
+			t.Errorf("ParseMargins(%q) succeeded", in)
+		}
+	}
+}
+
+func TestCheckPageLayout(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *Scraper
+		ok   bool
+	}{
+		{"defaults", &Scraper{}, true},
+		{"letter landscape", &Scraper{PageSize: "letter", Orientation: OrientationLandscape}, true},
+		{"unknown size", &Scraper{PageSize: "B5"}, false},
+		{"unknown orientation", &Scraper{Orientation: "sideways"}, false},
+		{"margins too wide", &Scraper{Margins: &Margins{Left: 110, Right: 110}}, false},
+		{"markdown", &Scraper{Format: FormatMarkdown, PageSize: PageSizeLetter}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.s.checkPageLayout(); (err == nil) != tt.ok {
+			t.Errorf("%s: checkPageLayout() = %v", tt.name, err)
+		}
+	}
+}
+
+func TestCreatePDFPageLayout(t *testing.T) {
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	s.PageSize = PageSizeLetter
+	s.Orientation = OrientationLandscape
+	s.Margins = &Margins{Top: 10, Right: 12, Bottom: 14, Left: 16}
+	p := fallbackPage(t, "A wide table")
+	if err := s.createPDF(filepath.Join(t.TempDir(), "page.pdf"), p); err != nil {
+		t.Fatal(err)
+	}
+
+	pdf, err := s.newPDF(Typography{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf.AddPageFormat(s.renderSettings(p.URL).pdfOrientation(), s.pageSize())
+	w, h := pdf.GetPageSize()
+	if w != 279.4 || h != 215.9 {
+		t.Errorf("page of %gx%gmm, want Letter in landscape", w, h)
+	}
+	left, top, right, _ := pdf.GetMargins()
+	if left != 16 || top != 10 || right != 12 {
+		t.Errorf("margins %g, %g, %g, want 16, 10, 12", left, top, right)
+	}
+	if got := s.printSettings(p.URL); got.size != (gofpdf.SizeType{Wd: 215.9, Ht: 279.4}) || !got.landscape || got.margins != s.Margins {
+		t.Errorf("printSettings = %+v, want Letter in landscape with the margins", got)
+	}
+}
@@ -0,0 +1,608 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// The PDF objects read by pdfFile: null is nil, booleans are bool, numbers
+// float64, strings pdfString and arrays []any.
+type (
+	pdfName   string
+	pdfString string
+	pdfDict   map[pdfName]any
+	pdfRef    struct{ num int }
+	pdfStream struct {
+		dict pdfDict
+		raw  []byte // still encoded by the dictionary's filters
+	}
+	// pdfOperator is a keyword of a content stream, such as "Tj".
+	pdfOperator string
+)
+
+// pdfObjectStart matches the "12 0 obj" line an indirect object starts with.
+var pdfObjectStart = regexp.MustCompile(`(?m)(?:^|[\s>\]])(\d+)\s+\d+\s+obj\b`)
+
+// pdfFile reads the objects of a PDF. It is only meant for the files scrapdf
+// writes, gofpdf's and Chrome's: objects are found by scanning the file
+// rather than through the cross-reference table, and broken ones are
+// skipped.
+type pdfFile struct {
+	data    []byte
+	offsets map[int]int // where each object's body starts
+	objects map[int]any // parsed objects
+	// packed holds the objects of object streams, which have no offset.
+	packed map[int]any
+}
+
+// readPDF indexes the objects of data.
+func readPDF(data []byte) (*pdfFile, error) {
+	f := &pdfFile{data: data, offsets: make(map[int]int), objects: make(map[int]any), packed: make(map[int]any)}
+	for _, m := range pdfObjectStart.FindAllSubmatchIndex(data, -1) {
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		// Later definitions, from incremental updates, win
+		f.offsets[num] = m[1]
+	}
+	if len(f.offsets) == 0 {
+		return nil, fmt.Errorf("not a PDF")
+	}
+	if bytes.Contains(data, []byte("/ObjStm")) {
+		for num := range f.offsets {
+			if s, ok := f.object(num).(*pdfStream); ok && s.dict.name("Type") == "ObjStm" {
+				f.unpack(s)
+			}
+		}
+	}
+	return f, nil
+}
+
+// object returns the object num, or nil when it is missing or broken.
+func (f *pdfFile) object(num int) any {
+	if obj, ok := f.objects[num]; ok {
+		return obj
+	}
+	off, ok := f.offsets[num]
+	if !ok {
+		return f.packed[num]
+	}
+	f.objects[num] = nil // a broken file may refer to the object from itself
+	l := &pdfLexer{data: f.data, pos: off, file: f}
+	obj, err := l.object()
+	if err != nil {
+		return nil
+	}
+	if d, ok := obj.(pdfDict); ok {
+		if s := l.stream(d); s != nil {
+			obj = s
+		}
+	}
+	f.objects[num] = obj
+	return obj
+}
+
+// unpack reads the objects packed in the object stream s.
+func (f *pdfFile) unpack(s *pdfStream) {
+	data, err := f.decode(s)
+	if err != nil {
+		return
+	}
+	n, first := int(f.number(s.dict["N"])), int(f.number(s.dict["First"]))
+	header := &pdfLexer{data: data, file: f}
+	for i := 0; i < n; i++ {
+		num, err1 := header.object()
+		off, err2 := header.object()
+		if err1 != nil || err2 != nil {
+			return
+		}
+		l := &pdfLexer{data: data, pos: first + int(f.number(off)), file: f}
+		if obj, err := l.object(); err == nil {
+			f.packed[int(f.number(num))] = obj
+		}
+	}
+}
+
+// resolve returns the object obj refers to, or obj itself.
+func (f *pdfFile) resolve(obj any) any {
+	for i := 0; i < 8; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.object(ref.num)
+	}
+	return nil
+}
+
+// dict returns obj as a dictionary, or nil. Streams give their dictionary.
+func (f *pdfFile) dict(obj any) pdfDict {
+	switch v := f.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// array returns obj as an array, or nil.
+func (f *pdfFile) array(obj any) []any {
+	a, _ := f.resolve(obj).([]any)
+	return a
+}
+
+// number returns obj as a number, or 0.
+func (f *pdfFile) number(obj any) float64 {
+	n, _ := f.resolve(obj).(float64)
+	return n
+}
+
+// numbers returns obj as an array of numbers.
+func (f *pdfFile) numbers(obj any) []float64 {
+	var nums []float64
+	for _, v := range f.array(obj) {
+		nums = append(nums, f.number(v))
+	}
+	return nums
+}
+
+// name returns the name of key, or "".
+func (d pdfDict) name(key pdfName) pdfName {
+	n, _ := d[key].(pdfName)
+	return n
+}
+
+// catalog returns the document catalog.
+func (f *pdfFile) catalog() pdfDict {
+	for num := range f.offsets {
+		if d, ok := f.object(num).(pdfDict); ok && d.name("Type") == "Catalog" {
+			return d
+		}
+	}
+	for _, obj := range f.packed {
+		if d, ok := obj.(pdfDict); ok && d.name("Type") == "Catalog" {
+			return d
+		}
+	}
+	return nil
+}
+
+// firstPage returns the first page of the document.
+func (f *pdfFile) firstPage() (pdfDict, error) {
+	catalog := f.catalog()
+	if catalog == nil {
+		return nil, fmt.Errorf("no document catalog")
+	}
+	node := f.dict(catalog["Pages"])
+	for depth := 0; node != nil && depth < 32; depth++ {
+		if node.name("Type") == "Page" {
+			return node, nil
+		}
+		kids := f.array(node["Kids"])
+		if len(kids) == 0 {
+			break
+		}
+		node = f.dict(kids[0])
+	}
+	return nil, fmt.Errorf("no pages")
+}
+
+// inherited returns key of page, or of the nearest parent page tree node
+// that has it.
+func (f *pdfFile) inherited(page pdfDict, key pdfName) any {
+	for depth := 0; page != nil && depth < 32; depth++ {
+		if v, ok := page[key]; ok {
+			return f.resolve(v)
+		}
+		page = f.dict(page["Parent"])
+	}
+	return nil
+}
+
+// decode returns the data of s with its filters undone, except DCTDecode,
+// which is left to the JPEG decoder.
+func (f *pdfFile) decode(s *pdfStream) ([]byte, error) {
+	filters := []any{f.resolve(s.dict["Filter"])}
+	if a, ok := filters[0].([]any); ok {
+		filters = a
+	}
+	params := []any{f.resolve(s.dict["DecodeParms"])}
+	if a, ok := params[0].([]any); ok {
+		params = a
+	}
+
+	data := s.raw
+	for i, filter := range filters {
+		var err error
+		switch f.resolve(filter) {
+		case nil:
+			continue
+		case pdfName("FlateDecode"):
+			if data, err = inflate(data); err != nil {
+				return nil, err
+			}
+			if i < len(params) {
+				if data, err = f.unpredict(data, f.dict(params[i])); err != nil {
+					return nil, err
+				}
+			}
+		case pdfName("ASCIIHexDecode"):
+			data = bytes.Map(func(r rune) rune {
+				if r == '>' || bytes.ContainsRune([]byte(" \t\r\n\f\x00"), r) {
+					return -1
+				}
+				return r
+			}, data)
+			if len(data)%2 == 1 {
+				data = append(data, '0')
+			}
+			if data, err = hex.DecodeString(string(data)); err != nil {
+				return nil, err
+			}
+		case pdfName("ASCII85Decode"):
+			data = bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			out := make([]byte, len(data))
+			n, _, err := ascii85.Decode(out, data, true)
+			if err != nil {
+				return nil, err
+			}
+			data = out[:n]
+		case pdfName("DCTDecode"):
+			return data, nil
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+// inflate undoes FlateDecode. Truncated streams keep what was read.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(zr)
+	if len(out) == 0 && err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unpredict undoes the PNG predictors of FlateDecode parameters p.
+func (f *pdfFile) unpredict(data []byte, p pdfDict) ([]byte, error) {
+	if p == nil || f.number(p["Predictor"]) < 10 {
+		return data, nil
+	}
+	colors, bpc, columns := 1, 8, 1
+	if v := f.number(p["Colors"]); v > 0 {
+		colors = int(v)
+	}
+	if v := f.number(p["BitsPerComponent"]); v > 0 {
+		bpc = int(v)
+	}
+	if v := f.number(p["Columns"]); v > 0 {
+		columns = int(v)
+	}
+	bpp := max(1, colors*bpc/8)
+	stride := (colors*bpc*columns + 7) / 8
+
+	out := make([]byte, 0, len(data)/(stride+1)*stride)
+	prev := make([]byte, stride)
+	for len(data) >= stride+1 {
+		filter, row := data[0], append([]byte(nil), data[1:stride+1]...)
+		data = data[stride+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth is the PNG Paeth predictor.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pdfLexer reads PDF objects from data, and operators too in content
+// streams.
+type pdfLexer struct {
+	data []byte
+	pos  int
+	file *pdfFile // resolves stream lengths, may be nil
+}
+
+// isPDFSpace reports whether c is PDF whitespace.
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether c ends a name, number or keyword.
+func isPDFDelimiter(c byte) bool {
+	return isPDFSpace(c) || bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace moves past whitespace and comments.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// object reads the next object, or operator. References "1 0 R" are read
+// as a pdfRef.
+func (l *pdfLexer) object() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return pdfName(l.name()), nil
+	case '(':
+		return l.literalString(), nil
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.dictionary()
+		}
+		return l.hexString(), nil
+	case '[':
+		l.pos++
+		var arr []any
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			v, err := l.object()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case ']', '>', ')', '{', '}':
+		l.pos++
+		return pdfOperator([]byte{c}), nil
+	}
+
+	word := l.word()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfOperator(word), nil
+	}
+	// An integer may start a reference
+	if l.file != nil && n >= 0 && n == float64(int(n)) {
+		save := l.pos
+		l.skipSpace()
+		if gen := l.word(); gen != "" {
+			if _, err := strconv.Atoi(gen); err == nil {
+				l.skipSpace()
+				if l.word() == "R" {
+					return pdfRef{num: int(n)}, nil
+				}
+			}
+		}
+		l.pos = save
+	}
+	return n, nil
+}
+
+// word reads a keyword or number.
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// name reads a name after its slash, undoing #xx escapes.
+func (l *pdfLexer) name() string {
+	raw := l.word()
+	if !bytes.ContainsRune([]byte(raw), '#') {
+		return raw
+	}
+	var b []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, raw[i])
+	}
+	return string(b)
+}
+
+// dictionary reads the entries of a dictionary after its "<<".
+func (l *pdfLexer) dictionary() (pdfDict, error) {
+	d := make(pdfDict)
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return d, nil
+		}
+		key, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		value, err := l.object()
+		if err != nil {
+			return nil, err
+		}
+		if name, ok := key.(pdfName); ok {
+			d[name] = value
+		}
+	}
+}
+
+// literalString reads a (string), undoing its escapes.
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(b)
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+// hexString reads a <hex string>.
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return pdfString(b)
+}
+
+// stream reads the stream following dictionary d, if there is one.
+func (l *pdfLexer) stream(d pdfDict) *pdfStream {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return nil
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+	end := -1
+	if n := int(l.file.number(d["Length"])); n > 0 && start+n <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[start+n:], " \t\r\n")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + n
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			return nil
+		}
+		end = start + i
+		for end > start && (l.data[end-1] == '\n' || l.data[end-1] == '\r') {
+			end--
+		}
+	}
+	return &pdfStream{dict: d, raw: l.data[start:end]}
+}
@@ -0,0 +1,1294 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"unicode/utf16"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxPDFNesting limits how deep forms and Type 3 glyphs may draw each other.
+const maxPDFNesting = 8
+
+// rasterizePDF draws the first page of the PDF data on a white image width
+// pixels wide. It covers what gofpdf and Chrome write: paths, clipping,
+// TrueType and Type 3 text, and images. Shadings are painted in a flat
+// colour, and fonts that aren't embedded are drawn with DejaVu Sans.
+func rasterizePDF(data []byte, width int) (*image.RGBA, error) {
+	f, err := readPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	page, err := f.firstPage()
+	if err != nil {
+		return nil, err
+	}
+	box := f.numbers(f.inherited(page, "CropBox"))
+	if len(box) != 4 {
+		box = f.numbers(f.inherited(page, "MediaBox"))
+	}
+	if len(box) != 4 || box[2] == box[0] || box[3] == box[1] {
+		return nil, fmt.Errorf("the first page has no size")
+	}
+	x0, y0, x1, y1 := math.Min(box[0], box[2]), math.Min(box[1], box[3]), math.Max(box[0], box[2]), math.Max(box[1], box[3])
+	scale := float64(width) / (x1 - x0)
+	height := max(1, int(math.Round((y1-y0)*scale)))
+
+	r := &pdfRenderer{
+		file:  f,
+		dst:   image.NewRGBA(image.Rect(0, 0, width, height)),
+		fonts: make(map[int]*pdfFont),
+	}
+	draw.Draw(r.dst, r.dst.Bounds(), image.White, image.Point{}, draw.Src)
+
+	var content []byte
+	contents := []any{page["Contents"]}
+	if a := f.array(page["Contents"]); a != nil {
+		contents = a
+	}
+	for _, c := range contents {
+		if s, ok := f.resolve(c).(*pdfStream); ok {
+			if data, err := f.decode(s); err == nil {
+				content = append(append(content, data...), '\n')
+			}
+		}
+	}
+	gs := newGraphicsState(pdfMatrix{scale, 0, 0, -scale, -x0 * scale, y1 * scale})
+	r.run(content, f.dict(f.inherited(page, "Resources")), gs)
+	return r.dst, nil
+}
+
+// pdfMatrix is a PDF transformation [a b c d e f], which maps (x, y) to
+// (a*x + c*y + e, b*x + d*y + f).
+type pdfMatrix [6]float64
+
+var identityMatrix = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// mul returns the transformation applying m, then n.
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// apply transforms the point (x, y).
+func (m pdfMatrix) apply(x, y float64) pdfPoint {
+	return pdfPoint{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+}
+
+// scale returns how much m stretches lengths, on average.
+func (m pdfMatrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// toMatrix returns nums as a matrix, or the identity when they aren't one.
+func toMatrix(nums []float64) pdfMatrix {
+	if len(nums) != 6 {
+		return identityMatrix
+	}
+	return pdfMatrix(nums)
+}
+
+// pdfPoint is a point in device space, in pixels.
+type pdfPoint struct{ x, y float64 }
+
+// pdfSegment is a piece of a path: a move, a line, a cubic curve through
+// two control points, or the closing of the subpath.
+type pdfSegment struct {
+	op  byte // 'm', 'l', 'c' or 'h'
+	pts [3]pdfPoint
+}
+
+// pdfColorSpace is a colour space: the number of components of a colour,
+// and the palette of an indexed space or the pattern space.
+type pdfColorSpace struct {
+	n       int
+	palette []color.NRGBA
+	pattern bool
+	tint    bool // 1 is full ink
+}
+
+var deviceGray = pdfColorSpace{n: 1}
+
+// pdfGraphicsState is the state q saves and Q restores.
+type pdfGraphicsState struct {
+	ctm                    pdfMatrix // to device space
+	fill, stroke           color.NRGBA
+	fillSpace, strokeSpace pdfColorSpace
+	fillAlpha, strokeAlpha float64
+	lineWidth              float64
+	clip                   *image.Alpha // nil when nothing is clipped
+
+	font                           *pdfFont
+	fontSize, charSpace, wordSpace float64
+	hScale, leading, rise          float64
+	textRender                     int
+}
+
+func newGraphicsState(ctm pdfMatrix) *pdfGraphicsState {
+	return &pdfGraphicsState{
+		ctm:         ctm,
+		fill:        color.NRGBA{A: 255},
+		stroke:      color.NRGBA{A: 255},
+		fillSpace:   deviceGray,
+		strokeSpace: deviceGray,
+		fillAlpha:   1,
+		strokeAlpha: 1,
+		lineWidth:   1,
+		hScale:      1,
+	}
+}
+
+// pdfRenderer paints content streams on dst.
+type pdfRenderer struct {
+	file     *pdfFile
+	dst      *image.RGBA
+	fonts    map[int]*pdfFont // by object number
+	fallback *sfnt.Font
+	buf      sfnt.Buffer
+	nesting  int
+}
+
+// run interprets content with resources res, starting from state gs.
+func (r *pdfRenderer) run(content []byte, res pdfDict, gs *pdfGraphicsState) {
+	var (
+		stack          []*pdfGraphicsState
+		path           []pdfSegment
+		current, start pdfPoint
+		clipPending    bool
+		tm, tlm        = identityMatrix, identityMatrix
+		operands       []any
+	)
+	l := &pdfLexer{data: content}
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfOperator)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		nums := make([]float64, 0, len(operands))
+		for _, v := range operands {
+			if n, ok := v.(float64); ok {
+				nums = append(nums, n)
+			}
+		}
+		num := func(i int) float64 {
+			if i < len(nums) {
+				return nums[i]
+			}
+			return 0
+		}
+		name := func() pdfName {
+			if len(operands) > 0 {
+				n, _ := operands[len(operands)-1].(pdfName)
+				return n
+			}
+			return ""
+		}
+		point := func(i int) pdfPoint {
+			return gs.ctm.apply(num(i), num(i+1))
+		}
+		paint := func(fill, stroke, close bool) {
+			if close {
+				path = append(path, pdfSegment{op: 'h'})
+			}
+			if fill {
+				r.fillPath(path, gs, gs.fill, gs.fillAlpha)
+			}
+			if stroke {
+				r.strokePath(path, gs)
+			}
+			if clipPending {
+				gs.clip = r.intersectClip(gs.clip, path)
+				clipPending = false
+			}
+			path = path[:0]
+		}
+
+		switch op {
+		case "q":
+			saved := *gs
+			stack = append(stack, &saved)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(nums) == 6 {
+				gs.ctm = toMatrix(nums).mul(gs.ctm)
+			}
+		case "w":
+			gs.lineWidth = num(0)
+		case "gs":
+			r.extGState(r.file.dict(r.file.dict(res["ExtGState"])[name()]), gs)
+
+		case "m":
+			current = point(0)
+			start = current
+			path = append(path, pdfSegment{op: 'm', pts: [3]pdfPoint{current}})
+		case "l":
+			current = point(0)
+			path = append(path, pdfSegment{op: 'l', pts: [3]pdfPoint{current}})
+		case "c":
+			path = append(path, pdfSegment{op: 'c', pts: [3]pdfPoint{point(0), point(2), point(4)}})
+			current = point(4)
+		case "v":
+			path = append(path, pdfSegment{op: 'c', pts: [3]pdfPoint{current, point(0), point(2)}})
+			current = point(2)
+		case "y":
+			path = append(path, pdfSegment{op: 'c', pts: [3]pdfPoint{point(0), point(2), point(2)}})
+			current = point(2)
+		case "h":
+			path = append(path, pdfSegment{op: 'h'})
+			current = start
+		case "re":
+			x, y, w, h := num(0), num(1), num(2), num(3)
+			path = append(path,
+				pdfSegment{op: 'm', pts: [3]pdfPoint{gs.ctm.apply(x, y)}},
+				pdfSegment{op: 'l', pts: [3]pdfPoint{gs.ctm.apply(x+w, y)}},
+				pdfSegment{op: 'l', pts: [3]pdfPoint{gs.ctm.apply(x+w, y+h)}},
+				pdfSegment{op: 'l', pts: [3]pdfPoint{gs.ctm.apply(x, y+h)}},
+				pdfSegment{op: 'h'})
+			current = gs.ctm.apply(x, y)
+			start = current
+		case "S":
+			paint(false, true, false)
+		case "s":
+			paint(false, true, true)
+		case "f", "F", "f*":
+			paint(true, false, false)
+		case "B", "B*":
+			paint(true, true, false)
+		case "b", "b*":
+			paint(true, true, true)
+		case "n":
+			paint(false, false, false)
+		case "W", "W*":
+			clipPending = true
+
+		case "g", "G", "rg", "RG", "k", "K":
+			space := pdfColorSpace{n: len(nums)}
+			if op == "g" || op == "rg" || op == "k" {
+				gs.fillSpace, gs.fill = space, space.color(nums)
+			} else {
+				gs.strokeSpace, gs.stroke = space, space.color(nums)
+			}
+		case "cs":
+			gs.fillSpace = r.colorSpace(operands, res)
+			gs.fill = gs.fillSpace.color(make([]float64, gs.fillSpace.n))
+		case "CS":
+			gs.strokeSpace = r.colorSpace(operands, res)
+			gs.stroke = gs.strokeSpace.color(make([]float64, gs.strokeSpace.n))
+		case "sc", "scn":
+			gs.fill = r.spaceColor(gs.fillSpace, nums, name(), res)
+		case "SC", "SCN":
+			gs.stroke = r.spaceColor(gs.strokeSpace, nums, name(), res)
+
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tf":
+			if len(operands) == 2 {
+				n, _ := operands[0].(pdfName)
+				gs.font = r.font(r.file.dict(res["Font"])[n])
+				gs.fontSize = num(0)
+			}
+		case "Tc":
+			gs.charSpace = num(0)
+		case "Tw":
+			gs.wordSpace = num(0)
+		case "Tz":
+			gs.hScale = num(0) / 100
+		case "TL":
+			gs.leading = num(0)
+		case "Ts":
+			gs.rise = num(0)
+		case "Tr":
+			gs.textRender = int(num(0))
+		case "Td", "TD":
+			if op == "TD" {
+				gs.leading = -num(1)
+			}
+			tlm = pdfMatrix{1, 0, 0, 1, num(0), num(1)}.mul(tlm)
+			tm = tlm
+		case "Tm":
+			tlm = toMatrix(nums)
+			tm = tlm
+		case "T*":
+			tlm = pdfMatrix{1, 0, 0, 1, 0, -gs.leading}.mul(tlm)
+			tm = tlm
+		case "Tj", "'", "\"":
+			if op == "\"" && len(nums) == 2 {
+				gs.wordSpace, gs.charSpace = nums[0], nums[1]
+			}
+			if op != "Tj" {
+				tlm = pdfMatrix{1, 0, 0, 1, 0, -gs.leading}.mul(tlm)
+				tm = tlm
+			}
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(pdfString); ok {
+					tm = r.showText(s, tm, gs, res)
+				}
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				a, _ := operands[0].([]any)
+				for _, v := range a {
+					switch v := v.(type) {
+					case pdfString:
+						tm = r.showText(v, tm, gs, res)
+					case float64:
+						tm = pdfMatrix{1, 0, 0, 1, -v / 1000 * gs.fontSize * gs.hScale, 0}.mul(tm)
+					}
+				}
+			}
+
+		case "Do":
+			r.xObject(r.file.resolve(r.file.dict(res["XObject"])[name()]), res, gs)
+		case "sh":
+			// Shadings fill the clipped area, which without a clip is the
+			// whole page: most likely a background
+			if gs.clip != nil {
+				c := r.shadingColor(r.file.dict(r.file.dict(res["Shading"])[name()]))
+				r.fillMask(gs.clip, gs.clip.Bounds(), c, gs.fillAlpha, nil)
+			}
+		case "BI":
+			// Inline images are skipped, up to the EI after their data
+			for {
+				obj, err := l.object()
+				if err != nil {
+					return
+				}
+				if obj == pdfOperator("ID") {
+					break
+				}
+			}
+			end := bytes.Index(l.data[l.pos:], []byte("EI"))
+			for end >= 0 && l.pos+end+2 < len(l.data) && !isPDFDelimiter(l.data[l.pos+end+2]) {
+				next := bytes.Index(l.data[l.pos+end+2:], []byte("EI"))
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 2 + next
+			}
+			if end < 0 {
+				return
+			}
+			l.pos += end + 2
+		}
+		operands = operands[:0]
+	}
+}
+
+// extGState applies the opacity and line width of a graphics state
+// parameter dictionary.
+func (r *pdfRenderer) extGState(d pdfDict, gs *pdfGraphicsState) {
+	if d == nil {
+		return
+	}
+	if v, ok := r.file.resolve(d["ca"]).(float64); ok {
+		gs.fillAlpha = v
+	}
+	if v, ok := r.file.resolve(d["CA"]).(float64); ok {
+		gs.strokeAlpha = v
+	}
+	if v, ok := r.file.resolve(d["LW"]).(float64); ok {
+		gs.lineWidth = v
+	}
+}
+
+// colorSpace returns the colour space selected by the operands of cs or CS.
+func (r *pdfRenderer) colorSpace(operands []any, res pdfDict) pdfColorSpace {
+	if len(operands) == 0 {
+		return deviceGray
+	}
+	n, _ := operands[0].(pdfName)
+	switch n {
+	case "DeviceGray", "G", "CalGray":
+		return deviceGray
+	case "DeviceRGB", "RGB", "CalRGB":
+		return pdfColorSpace{n: 3}
+	case "DeviceCMYK", "CMYK":
+		return pdfColorSpace{n: 4}
+	case "Pattern":
+		return pdfColorSpace{pattern: true}
+	}
+	return r.parseColorSpace(r.file.dict(res["ColorSpace"])[n])
+}
+
+// parseColorSpace reads a colour space object.
+func (r *pdfRenderer) parseColorSpace(obj any) pdfColorSpace {
+	obj = r.file.resolve(obj)
+	if n, ok := obj.(pdfName); ok {
+		return r.colorSpace([]any{n}, nil)
+	}
+	a, _ := obj.([]any)
+	if len(a) == 0 {
+		return deviceGray
+	}
+	switch r.file.resolve(a[0]) {
+	case pdfName("ICCBased"):
+		if len(a) > 1 {
+			if n := int(r.file.number(r.file.dict(a[1])["N"])); n > 0 {
+				return pdfColorSpace{n: n}
+			}
+		}
+		return pdfColorSpace{n: 3}
+	case pdfName("CalRGB"), pdfName("Lab"):
+		return pdfColorSpace{n: 3}
+	case pdfName("Pattern"):
+		return pdfColorSpace{pattern: true}
+	case pdfName("Indexed"):
+		if len(a) < 4 {
+			return deviceGray
+		}
+		base := r.parseColorSpace(a[1])
+		var lookup []byte
+		switch v := r.file.resolve(a[3]).(type) {
+		case pdfString:
+			lookup = []byte(v)
+		case *pdfStream:
+			lookup, _ = r.file.decode(v)
+		}
+		cs := pdfColorSpace{n: 1}
+		for i := 0; base.n > 0 && i+base.n <= len(lookup); i += base.n {
+			comps := make([]float64, base.n)
+			for j := range comps {
+				comps[j] = float64(lookup[i+j]) / 255
+			}
+			cs.palette = append(cs.palette, base.color(comps))
+		}
+		return cs
+	case pdfName("Separation"), pdfName("DeviceN"):
+		// Without the tint transform, draw tints as shades of grey
+		return pdfColorSpace{n: 1, tint: true}
+	}
+	return deviceGray
+}
+
+// color converts components of the space, each from 0 to 1, or a palette
+// index, to a colour.
+func (cs pdfColorSpace) color(c []float64) color.NRGBA {
+	at := func(i int) uint8 {
+		if i >= len(c) {
+			return 0
+		}
+		return uint8(math.Round(math.Max(0, math.Min(1, c[i])) * 255))
+	}
+	switch {
+	case cs.palette != nil:
+		if len(c) > 0 && int(c[0]) >= 0 && int(c[0]) < len(cs.palette) {
+			return cs.palette[int(c[0])]
+		}
+		return color.NRGBA{A: 255}
+	case len(c) == 3:
+		return color.NRGBA{at(0), at(1), at(2), 255}
+	case cs.tint:
+		return color.NRGBA{255 - at(0), 255 - at(0), 255 - at(0), 255}
+	case len(c) == 4:
+		k := 255 - int(at(3))
+		return color.NRGBA{uint8((255 - int(at(0))) * k / 255), uint8((255 - int(at(1))) * k / 255), uint8((255 - int(at(2))) * k / 255), 255}
+	}
+	return color.NRGBA{at(0), at(0), at(0), 255}
+}
+
+// spaceColor returns the colour set by sc or scn in space cs: components,
+// or a pattern, painted in its average colour.
+func (r *pdfRenderer) spaceColor(cs pdfColorSpace, nums []float64, pattern pdfName, res pdfDict) color.NRGBA {
+	if !cs.pattern {
+		return cs.color(nums)
+	}
+	p := r.file.dict(r.file.dict(res["Pattern"])[pattern])
+	if p == nil {
+		return color.NRGBA{A: 255}
+	}
+	if r.file.number(p["PatternType"]) == 2 {
+		return r.shadingColor(r.file.dict(p["Shading"]))
+	}
+	// Tiling patterns are mostly textures and hatchings
+	return color.NRGBA{192, 192, 192, 255}
+}
+
+// shadingColor returns the colour in the middle of a shading.
+func (r *pdfRenderer) shadingColor(sh pdfDict) color.NRGBA {
+	grey := color.NRGBA{192, 192, 192, 255}
+	if sh == nil {
+		return grey
+	}
+	cs := r.parseColorSpace(sh["ColorSpace"])
+	fn := r.file.dict(sh["Function"])
+	if a := r.file.array(sh["Function"]); len(a) > 0 {
+		fn = r.file.dict(a[0])
+	}
+	for depth := 0; fn != nil && depth < 4; depth++ {
+		switch r.file.number(fn["FunctionType"]) {
+		case 2:
+			c0, c1 := r.file.numbers(fn["C0"]), r.file.numbers(fn["C1"])
+			if c0 == nil {
+				c0 = []float64{0}
+			}
+			if c1 == nil {
+				c1 = []float64{1}
+			}
+			mid := make([]float64, min(len(c0), len(c1)))
+			for i := range mid {
+				mid[i] = (c0[i] + c1[i]) / 2
+			}
+			if cs.n != len(mid) {
+				cs = pdfColorSpace{n: len(mid)}
+			}
+			return cs.color(mid)
+		case 3:
+			fns := r.file.array(fn["Functions"])
+			if len(fns) == 0 {
+				return grey
+			}
+			fn = r.file.dict(fns[len(fns)/2])
+		default:
+			return grey
+		}
+	}
+	return grey
+}
+
+// fillPath paints the inside of path in c.
+func (r *pdfRenderer) fillPath(path []pdfSegment, gs *pdfGraphicsState, c color.NRGBA, alpha float64) {
+	if mask, bounds := r.coverage(path); mask != nil {
+		r.fillMask(mask, bounds, c, alpha, gs.clip)
+	}
+}
+
+// strokePath paints the outline of path with the line width of gs. Each
+// line becomes a rectangle, extended by half the width at both ends so
+// joins are covered.
+func (r *pdfRenderer) strokePath(path []pdfSegment, gs *pdfGraphicsState) {
+	half := math.Max(gs.lineWidth*gs.ctm.scale(), 0.5) / 2
+	var outline []pdfSegment
+	for _, line := range flatten(path) {
+		p, q := line[0], line[1]
+		dx, dy := q.x-p.x, q.y-p.y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		dx, dy = dx/length*half, dy/length*half
+		corners := [4]pdfPoint{
+			{p.x - dx - dy, p.y - dy + dx},
+			{q.x + dx - dy, q.y + dy + dx},
+			{q.x + dx + dy, q.y + dy - dx},
+			{p.x - dx + dy, p.y - dy - dx},
+		}
+		// Overlapping rectangles must wind the same way, or they would
+		// cancel out
+		if (corners[1].x-corners[0].x)*(corners[2].y-corners[0].y)-(corners[1].y-corners[0].y)*(corners[2].x-corners[0].x) < 0 {
+			corners[1], corners[3] = corners[3], corners[1]
+		}
+		outline = append(outline, pdfSegment{op: 'm', pts: [3]pdfPoint{corners[0]}})
+		for _, c := range corners[1:] {
+			outline = append(outline, pdfSegment{op: 'l', pts: [3]pdfPoint{c}})
+		}
+		outline = append(outline, pdfSegment{op: 'h'})
+	}
+	if mask, bounds := r.coverage(outline); mask != nil {
+		r.fillMask(mask, bounds, gs.stroke, gs.strokeAlpha, gs.clip)
+	}
+}
+
+// flatten returns the lines of path, with curves split into short lines.
+func flatten(path []pdfSegment) [][2]pdfPoint {
+	var lines [][2]pdfPoint
+	var current, start pdfPoint
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			current, start = seg.pts[0], seg.pts[0]
+		case 'l':
+			lines = append(lines, [2]pdfPoint{current, seg.pts[0]})
+			current = seg.pts[0]
+		case 'c':
+			const steps = 8
+			p0 := current
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / steps
+				u := 1 - t
+				next := pdfPoint{
+					u*u*u*p0.x + 3*u*u*t*seg.pts[0].x + 3*u*t*t*seg.pts[1].x + t*t*t*seg.pts[2].x,
+					u*u*u*p0.y + 3*u*u*t*seg.pts[0].y + 3*u*t*t*seg.pts[1].y + t*t*t*seg.pts[2].y,
+				}
+				lines = append(lines, [2]pdfPoint{current, next})
+				current = next
+			}
+		case 'h':
+			if current != start {
+				lines = append(lines, [2]pdfPoint{current, start})
+			}
+			current = start
+		}
+	}
+	return lines
+}
+
+// coverage rasterizes path to an alpha mask of its bounds on the page, or
+// returns nil when it is off the page.
+func (r *pdfRenderer) coverage(path []pdfSegment) (*image.Alpha, image.Rectangle) {
+	if len(path) == 0 {
+		return nil, image.Rectangle{}
+	}
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, seg := range path {
+		n := map[byte]int{'m': 1, 'l': 1, 'c': 3}[seg.op]
+		for _, p := range seg.pts[:n] {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	if math.IsInf(minX, 0) || math.IsNaN(minX+minY+maxX+maxY) {
+		return nil, image.Rectangle{}
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).Intersect(r.dst.Bounds())
+	if bounds.Empty() {
+		return nil, image.Rectangle{}
+	}
+
+	// Points far off the page are clamped, as the rasterizer expects
+	// coordinates near its size
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	pt := func(p pdfPoint) (float32, float32) {
+		const far = 1 << 14
+		return float32(math.Max(-far, math.Min(far, p.x-ox))), float32(math.Max(-far, math.Min(far, p.y-oy)))
+	}
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	open := false
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			if open {
+				z.ClosePath()
+			}
+			z.MoveTo(pt(seg.pts[0]))
+			open = true
+		case 'l':
+			if open {
+				z.LineTo(pt(seg.pts[0]))
+			}
+		case 'c':
+			if open {
+				x1, y1 := pt(seg.pts[0])
+				x2, y2 := pt(seg.pts[1])
+				x3, y3 := pt(seg.pts[2])
+				z.CubeTo(x1, y1, x2, y2, x3, y3)
+			}
+		case 'h':
+			if open {
+				z.ClosePath()
+				open = false
+			}
+		}
+	}
+	if open {
+		z.ClosePath()
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	z.DrawOp = draw.Src
+	z.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return mask, bounds
+}
+
+// fillMask paints c with opacity alpha through mask, whose origin is at
+// bounds.Min, within clip.
+func (r *pdfRenderer) fillMask(mask *image.Alpha, bounds image.Rectangle, c color.NRGBA, alpha float64, clip *image.Alpha) {
+	if clip != nil {
+		clipped := image.NewAlpha(mask.Rect)
+		for y := 0; y < bounds.Dy(); y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				a := uint32(mask.AlphaAt(mask.Rect.Min.X+x, mask.Rect.Min.Y+y).A)
+				a = a * uint32(clip.AlphaAt(bounds.Min.X+x, bounds.Min.Y+y).A) / 255
+				clipped.SetAlpha(mask.Rect.Min.X+x, mask.Rect.Min.Y+y, color.Alpha{A: uint8(a)})
+			}
+		}
+		mask = clipped
+	}
+	c.A = uint8(math.Round(float64(c.A) * math.Max(0, math.Min(1, alpha))))
+	draw.DrawMask(r.dst, bounds, image.NewUniform(c), image.Point{}, mask, mask.Rect.Min, draw.Over)
+}
+
+// intersectClip returns the clip of clip and path, as a mask of the page.
+func (r *pdfRenderer) intersectClip(clip *image.Alpha, path []pdfSegment) *image.Alpha {
+	next := image.NewAlpha(r.dst.Bounds())
+	mask, bounds := r.coverage(path)
+	if mask == nil {
+		return next
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := uint32(mask.AlphaAt(x-bounds.Min.X, y-bounds.Min.Y).A)
+			if clip != nil {
+				a = a * uint32(clip.AlphaAt(x, y).A) / 255
+			}
+			next.SetAlpha(x, y, color.Alpha{A: uint8(a)})
+		}
+	}
+	return next
+}
+
+// xObject draws an image or a form.
+func (r *pdfRenderer) xObject(obj any, res pdfDict, gs *pdfGraphicsState) {
+	s, ok := obj.(*pdfStream)
+	if !ok {
+		return
+	}
+	switch s.dict.name("Subtype") {
+	case "Image":
+		img := r.image(s, gs.fill)
+		if img == nil {
+			return
+		}
+		b := img.Bounds()
+		m := pdfMatrix{1 / float64(b.Dx()), 0, 0, -1 / float64(b.Dy()), 0, 1}.mul(gs.ctm)
+		opts := &xdraw.Options{}
+		if gs.clip != nil {
+			opts.DstMask = gs.clip
+		}
+		if gs.fillAlpha < 1 {
+			opts.SrcMask = image.NewUniform(color.Alpha{A: uint8(math.Round(math.Max(0, gs.fillAlpha) * 255))})
+		}
+		xdraw.ApproxBiLinear.Transform(r.dst, f64.Aff3{m[0], m[2], m[4], m[1], m[3], m[5]}, img, b, xdraw.Over, opts)
+	case "Form":
+		if r.nesting >= maxPDFNesting {
+			return
+		}
+		data, err := r.file.decode(s)
+		if err != nil {
+			return
+		}
+		form := *gs
+		form.ctm = toMatrix(r.file.numbers(s.dict["Matrix"])).mul(gs.ctm)
+		if bbox := r.file.numbers(s.dict["BBox"]); len(bbox) == 4 {
+			corner := func(x, y float64) pdfSegment {
+				return pdfSegment{op: 'l', pts: [3]pdfPoint{form.ctm.apply(x, y)}}
+			}
+			rect := []pdfSegment{corner(bbox[0], bbox[1]), corner(bbox[2], bbox[1]), corner(bbox[2], bbox[3]), corner(bbox[0], bbox[3]), {op: 'h'}}
+			rect[0].op = 'm'
+			form.clip = r.intersectClip(gs.clip, rect)
+		}
+		formRes := r.file.dict(s.dict["Resources"])
+		if formRes == nil {
+			formRes = res
+		}
+		r.nesting++
+		r.run(data, formRes, &form)
+		r.nesting--
+	}
+}
+
+// image decodes an image XObject. Stencil masks are painted in fill.
+func (r *pdfRenderer) image(s *pdfStream, fill color.NRGBA) image.Image {
+	f := r.file
+	data, err := f.decode(s)
+	if err != nil {
+		return nil
+	}
+	if isDCT(f, s.dict) {
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		if smask, ok := f.resolve(s.dict["SMask"]).(*pdfStream); ok {
+			return withAlpha(img, r.image(smask, fill))
+		}
+		return img
+	}
+
+	w, h := int(f.number(s.dict["Width"])), int(f.number(s.dict["Height"]))
+	if w <= 0 || h <= 0 || w*h > 1<<26 {
+		return nil
+	}
+	bpc := int(f.number(s.dict["BitsPerComponent"]))
+	stencil := f.resolve(s.dict["ImageMask"]) == true
+	cs := pdfColorSpace{n: 1}
+	if stencil {
+		bpc = 1
+	} else {
+		cs = r.parseColorSpace(s.dict["ColorSpace"])
+	}
+	if bpc <= 0 {
+		bpc = 8
+	}
+	decode := f.numbers(s.dict["Decode"])
+	invert := len(decode) >= 2 && decode[0] > decode[1]
+
+	stride := (w*cs.n*bpc + 7) / 8
+	if len(data) < stride*h {
+		return nil
+	}
+	maxValue := float64(int(1)<<bpc - 1)
+	sample := func(row []byte, i int) float64 {
+		switch bpc {
+		case 8:
+			return float64(row[i])
+		case 16:
+			return float64(int(row[2*i])<<8 | int(row[2*i+1]))
+		}
+		bit := i * bpc
+		return float64(int(row[bit/8]>>(8-bpc-bit%8)) & (1<<bpc - 1))
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	comps := make([]float64, cs.n)
+	for y := 0; y < h; y++ {
+		row := data[y*stride : (y+1)*stride]
+		for x := 0; x < w; x++ {
+			for c := range comps {
+				v := sample(row, x*cs.n+c)
+				if cs.palette == nil {
+					v /= maxValue
+					if invert {
+						v = 1 - v
+					}
+				}
+				comps[c] = v
+			}
+			switch {
+			case stencil:
+				// Samples of 0 are painted
+				if comps[0] == 0 {
+					img.SetNRGBA(x, y, fill)
+				}
+			default:
+				img.SetNRGBA(x, y, cs.color(comps))
+			}
+		}
+	}
+
+	switch mask := f.resolve(s.dict["SMask"]).(type) {
+	case *pdfStream:
+		return withAlpha(img, r.image(mask, fill))
+	}
+	if key := f.numbers(s.dict["Mask"]); len(key) == 2*cs.n && !stencil {
+		// Colour key masking, as gofpdf writes for transparent PNGs
+		for y := 0; y < h; y++ {
+			row := data[y*stride : (y+1)*stride]
+			for x := 0; x < w; x++ {
+				masked := true
+				for c := 0; c < cs.n; c++ {
+					v := sample(row, x*cs.n+c)
+					masked = masked && v >= key[2*c] && v <= key[2*c+1]
+				}
+				if masked {
+					img.SetNRGBA(x, y, color.NRGBA{})
+				}
+			}
+		}
+	}
+	return img
+}
+
+// isDCT reports whether the image in d is a JPEG.
+func isDCT(f *pdfFile, d pdfDict) bool {
+	filters := []any{f.resolve(d["Filter"])}
+	if a, ok := filters[0].([]any); ok {
+		filters = a
+	}
+	for _, filter := range filters {
+		if f.resolve(filter) == pdfName("DCTDecode") {
+			return true
+		}
+	}
+	return false
+}
+
+// withAlpha returns img with the grey levels of mask as opacity.
+func withAlpha(img, mask image.Image) image.Image {
+	if mask == nil {
+		return img
+	}
+	b, mb := img.Bounds(), mask.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			mx := mb.Min.X + (x-b.Min.X)*mb.Dx()/b.Dx()
+			my := mb.Min.Y + (y-b.Min.Y)*mb.Dy()/b.Dy()
+			c.A = uint8(uint32(c.A) * uint32(color.GrayModel.Convert(mask.At(mx, my)).(color.Gray).Y) / 255)
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+// pdfFont is a font of a content stream, and how its character codes map
+// to glyphs.
+type pdfFont struct {
+	twoByte bool               // codes are two bytes, as in Identity-H fonts
+	widths  map[int]float64    // advance by code, in thousandths of the size
+	missing float64            // advance of codes without a width
+	face    *sfnt.Font         // the embedded font, or the fallback
+	gids    []uint16           // glyph by code, when not the code itself
+	runes   map[int]rune       // Unicode by code, for the fallback
+	simple  bool               // one byte codes, mapped through runes
+	procs   map[int]*pdfStream // glyph procedures of a Type 3 font
+	matrix  pdfMatrix          // of a Type 3 font
+	res     pdfDict            // of a Type 3 font
+}
+
+// font loads the font obj refers to.
+func (r *pdfRenderer) font(obj any) *pdfFont {
+	ref, isRef := obj.(pdfRef)
+	if isRef {
+		if font, ok := r.fonts[ref.num]; ok {
+			return font
+		}
+	}
+	font := r.loadFont(r.file.dict(obj))
+	if isRef {
+		r.fonts[ref.num] = font
+	}
+	return font
+}
+
+func (r *pdfRenderer) loadFont(d pdfDict) *pdfFont {
+	f := r.file
+	if d == nil {
+		return nil
+	}
+	font := &pdfFont{widths: make(map[int]float64), runes: r.toUnicode(d["ToUnicode"])}
+	desc := d
+	switch d.name("Subtype") {
+	case "Type0":
+		font.twoByte = true
+		descendants := f.array(d["DescendantFonts"])
+		if len(descendants) == 0 {
+			return font
+		}
+		desc = f.dict(descendants[0])
+		font.missing = 1000
+		if v, ok := f.resolve(desc["DW"]).(float64); ok {
+			font.missing = v
+		}
+		w := f.array(desc["W"])
+		for i := 0; i+1 < len(w); {
+			first := int(f.number(w[i]))
+			if list := f.array(w[i+1]); list != nil {
+				for j, v := range list {
+					font.widths[first+j] = f.number(v)
+				}
+				i += 2
+				continue
+			}
+			if i+2 >= len(w) {
+				break
+			}
+			last, width := int(f.number(w[i+1])), f.number(w[i+2])
+			for c := first; c <= last && c-first < 1<<16; c++ {
+				font.widths[c] = width
+			}
+			i += 3
+		}
+		if m, ok := f.resolve(desc["CIDToGIDMap"]).(*pdfStream); ok {
+			if data, err := f.decode(m); err == nil {
+				font.gids = make([]uint16, len(data)/2)
+				for i := range font.gids {
+					font.gids[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+				}
+			}
+		}
+	case "Type3":
+		font.matrix = toMatrix(f.numbers(d["FontMatrix"]))
+		font.res = f.dict(d["Resources"])
+		font.procs = make(map[int]*pdfStream)
+		procs := f.dict(d["CharProcs"])
+		for code, name := range r.encoding(d) {
+			if s, ok := f.resolve(procs[name]).(*pdfStream); ok {
+				font.procs[code] = s
+			}
+		}
+		fallthrough
+	default:
+		font.simple = true
+		first := int(f.number(d["FirstChar"]))
+		for i, v := range f.array(d["Widths"]) {
+			font.widths[first+i] = f.number(v)
+		}
+		if len(font.runes) == 0 {
+			font.runes = make(map[int]rune)
+			for code := 0; code < 256; code++ {
+				font.runes[code] = charmap.Windows1252.DecodeByte(byte(code))
+			}
+		}
+	}
+	if font.procs != nil {
+		return font
+	}
+
+	fd := f.dict(desc["FontDescriptor"])
+	font.missing = math.Max(font.missing, f.number(fd["MissingWidth"]))
+	for _, key := range []pdfName{"FontFile2", "FontFile3"} {
+		if s, ok := f.resolve(fd[key]).(*pdfStream); ok {
+			if data, err := f.decode(s); err == nil {
+				if face, err := sfnt.Parse(data); err == nil {
+					font.face = face
+				}
+			}
+		}
+	}
+	if font.face == nil {
+		// Draw the text in DejaVu Sans, by Unicode
+		font.face = r.fallbackFace()
+		font.twoByte = font.twoByte && len(font.runes) > 0
+		font.gids = nil
+		font.simple = true
+	}
+	return font
+}
+
+// encoding returns the glyph names of a Type 3 font by code.
+func (r *pdfRenderer) encoding(d pdfDict) map[int]pdfName {
+	names := make(map[int]pdfName)
+	enc := r.file.dict(d["Encoding"])
+	code := 0
+	for _, v := range r.file.array(enc["Differences"]) {
+		switch v := r.file.resolve(v).(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			names[code] = v
+			code++
+		}
+	}
+	return names
+}
+
+// toUnicode reads the character codes of a ToUnicode CMap.
+func (r *pdfRenderer) toUnicode(obj any) map[int]rune {
+	s, ok := r.file.resolve(obj).(*pdfStream)
+	if !ok {
+		return nil
+	}
+	data, err := r.file.decode(s)
+	if err != nil {
+		return nil
+	}
+	code := func(v any) int {
+		s, _ := v.(pdfString)
+		n := 0
+		for i := 0; i < len(s); i++ {
+			n = n<<8 | int(s[i])
+		}
+		return n
+	}
+	unicode := func(v any) rune {
+		s, _ := v.(pdfString)
+		units := make([]uint16, len(s)/2)
+		for i := range units {
+			units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+		}
+		if runes := utf16.Decode(units); len(runes) > 0 {
+			return runes[0]
+		}
+		return 0
+	}
+
+	runes := make(map[int]rune)
+	var operands []any
+	l := &pdfLexer{data: data}
+	mode := pdfOperator("")
+	for {
+		obj, err := l.object()
+		if err != nil {
+			break
+		}
+		op, isOp := obj.(pdfOperator)
+		if !isOp {
+			operands = append(operands, obj)
+			switch {
+			case mode == "beginbfchar" && len(operands) == 2:
+				runes[code(operands[0])] = unicode(operands[1])
+				operands = operands[:0]
+			case mode == "beginbfrange" && len(operands) == 3:
+				lo, hi := code(operands[0]), code(operands[1])
+				for c := lo; c <= hi && c-lo < 1<<16; c++ {
+					if list, ok := operands[2].([]any); ok {
+						if c-lo < len(list) {
+							runes[c] = unicode(list[c-lo])
+						}
+					} else {
+						runes[c] = unicode(operands[2]) + rune(c-lo)
+					}
+				}
+				operands = operands[:0]
+			}
+			continue
+		}
+		switch op {
+		case "beginbfchar", "beginbfrange":
+			mode = op
+		case "endbfchar", "endbfrange":
+			mode = ""
+		}
+		operands = operands[:0]
+	}
+	return runes
+}
+
+// fallbackFace returns DejaVu Sans, for fonts that aren't embedded.
+func (r *pdfRenderer) fallbackFace() *sfnt.Font {
+	if r.fallback == nil {
+		r.fallback, _ = sfnt.Parse(dejaVuSans)
+	}
+	return r.fallback
+}
+
+// width returns the advance of code, in text space units for a size of 1.
+func (font *pdfFont) width(r *pdfRenderer, code int) float64 {
+	w, ok := font.widths[code]
+	if font.procs != nil {
+		return w * font.matrix[0]
+	}
+	if !ok {
+		w = font.missing
+		if w == 0 && font.face != nil {
+			upem := float64(font.face.UnitsPerEm())
+			if adv, err := font.face.GlyphAdvance(&r.buf, font.glyph(r, code), fixed.Int26_6(upem), 0); err == nil {
+				w = float64(adv) / upem * 1000
+			}
+		}
+	}
+	return w / 1000
+}
+
+// glyph returns the glyph of code in the font's face.
+func (font *pdfFont) glyph(r *pdfRenderer, code int) sfnt.GlyphIndex {
+	switch {
+	case font.face == r.fallback && font.face != nil:
+		if u, ok := font.runes[code]; ok {
+			gid, _ := font.face.GlyphIndex(&r.buf, u)
+			return gid
+		}
+		return 0
+	case font.simple:
+		if u, ok := font.runes[code]; ok {
+			if gid, err := font.face.GlyphIndex(&r.buf, u); err == nil && gid != 0 {
+				return gid
+			}
+		}
+		// Symbolic fonts map their codes into the private use area
+		if gid, err := font.face.GlyphIndex(&r.buf, rune(0xF000+code)); err == nil && gid != 0 {
+			return gid
+		}
+		return sfnt.GlyphIndex(code)
+	case font.gids != nil:
+		if code < len(font.gids) {
+			return sfnt.GlyphIndex(font.gids[code])
+		}
+		return 0
+	}
+	return sfnt.GlyphIndex(code)
+}
+
+// showText draws s with text matrix tm and returns the matrix after it.
+// Type 3 glyphs without resources of their own use res.
+func (r *pdfRenderer) showText(s pdfString, tm pdfMatrix, gs *pdfGraphicsState, res pdfDict) pdfMatrix {
+	font := gs.font
+	if font == nil {
+		return tm
+	}
+	visible := gs.textRender != 3 && gs.textRender != 7
+	var path []pdfSegment
+	for i := 0; i < len(s); i++ {
+		code := int(s[i])
+		if font.twoByte && i+1 < len(s) {
+			code = code<<8 | int(s[i+1])
+			i++
+		}
+		trm := pdfMatrix{gs.fontSize * gs.hScale, 0, 0, gs.fontSize, 0, gs.rise}.mul(tm).mul(gs.ctm)
+		switch {
+		case !visible:
+		case font.procs != nil:
+			if proc := font.procs[code]; proc != nil && r.nesting < maxPDFNesting {
+				if data, err := r.file.decode(proc); err == nil {
+					glyph := *gs
+					glyph.ctm = font.matrix.mul(trm)
+					if font.res != nil {
+						res = font.res
+					}
+					r.nesting++
+					r.run(data, res, &glyph)
+					r.nesting--
+				}
+			}
+		case font.face != nil:
+			path = r.appendGlyph(path, font, font.glyph(r, code), trm)
+		}
+
+		tx := font.width(r, code)*gs.fontSize + gs.charSpace
+		if code == ' ' && !font.twoByte {
+			tx += gs.wordSpace
+		}
+		tm = pdfMatrix{1, 0, 0, 1, tx * gs.hScale, 0}.mul(tm)
+	}
+	if len(path) > 0 {
+		r.fillPath(path, gs, gs.fill, gs.fillAlpha)
+	}
+	return tm
+}
+
+// appendGlyph appends the outline of glyph, placed by trm, to path.
+func (r *pdfRenderer) appendGlyph(path []pdfSegment, font *pdfFont, glyph sfnt.GlyphIndex, trm pdfMatrix) []pdfSegment {
+	upem := float64(font.face.UnitsPerEm())
+	segments, err := font.face.LoadGlyph(&r.buf, glyph, fixed.Int26_6(upem), nil)
+	if err != nil {
+		return path
+	}
+	// In font units, with the Y axis increasing down
+	pt := func(p fixed.Point26_6) pdfPoint {
+		return trm.apply(float64(p.X)/upem, -float64(p.Y)/upem)
+	}
+	var current pdfPoint
+	for _, seg := range segments {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			if len(path) > 0 && path[len(path)-1].op != 'h' {
+				path = append(path, pdfSegment{op: 'h'})
+			}
+			current = pt(seg.Args[0])
+			path = append(path, pdfSegment{op: 'm', pts: [3]pdfPoint{current}})
+		case sfnt.SegmentOpLineTo:
+			current = pt(seg.Args[0])
+			path = append(path, pdfSegment{op: 'l', pts: [3]pdfPoint{current}})
+		case sfnt.SegmentOpQuadTo:
+			q, end := pt(seg.Args[0]), pt(seg.Args[1])
+			c1 := pdfPoint{current.x + 2*(q.x-current.x)/3, current.y + 2*(q.y-current.y)/3}
+			c2 := pdfPoint{end.x + 2*(q.x-end.x)/3, end.y + 2*(q.y-end.y)/3}
+			path = append(path, pdfSegment{op: 'c', pts: [3]pdfPoint{c1, c2, end}})
+			current = end
+		case sfnt.SegmentOpCubeTo:
+			current = pt(seg.Args[2])
+			path = append(path, pdfSegment{op: 'c', pts: [3]pdfPoint{pt(seg.Args[0]), pt(seg.Args[1]), current}})
+		}
+	}
+	if len(path) > 0 && path[len(path)-1].op != 'h' {
+		path = append(path, pdfSegment{op: 'h'})
+	}
+	return path
+}
@@ -0,0 +1,72 @@
+package scraper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestRasterizePDF(t *testing.T) {
+	// A 100mm square page: a red square at the top left, a blue PNG at the
+	// bottom right and a line of text in between
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "mm", Size: gofpdf.SizeType{Wd: 100, Ht: 100}})
+	pdf.SetCompression(true)
+	pdf.AddPage()
+	pdf.SetFillColor(255, 0, 0)
+	pdf.Rect(10, 10, 30, 30, "F")
+
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = []uint8{0, 0, 255, 255}[i%4]
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	pdf.RegisterImageOptionsReader("blue", gofpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	pdf.ImageOptions("blue", 60, 60, 30, 30, false, gofpdf.ImageOptions{}, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 24)
+	pdf.Text(10, 52, "Hello")
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := rasterizePDF(out.Bytes(), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != image.Rect(0, 0, 200, 200) {
+		t.Fatalf("size = %v, want 200x200", got.Bounds())
+	}
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"red square", 50, 50, color.RGBA{255, 0, 0, 255}},
+		{"blue image", 150, 150, color.RGBA{0, 0, 255, 255}},
+		{"background", 150, 50, color.RGBA{255, 255, 255, 255}},
+	}
+	for _, tt := range tests {
+		if c := got.RGBAAt(tt.x, tt.y); c != tt.want {
+			t.Errorf("%s at (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, c, tt.want)
+		}
+	}
+	if !hasInk(got.SubImage(image.Rect(20, 85, 100, 106))) {
+		t.Error("no text drawn")
+	}
+}
+
+func TestRasterizePDFInvalid(t *testing.T) {
+	for _, data := range []string{"", "%PDF-1.4\n%%EOF", "1 0 obj << /Type /Catalog >> endobj"} {
+		if _, err := rasterizePDF([]byte(data), 100); err == nil {
+			t.Errorf("rasterizePDF(%q) succeeded", data)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	// pdfStreamPattern matches the content streams of a PDF.
+	pdfStreamPattern = regexp.MustCompile(`(?s)stream\r?\n(.*?)endstream`)
+	// pdfShowTextPattern matches a literal string shown with Tj.
+	pdfShowTextPattern = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)\s*Tj`)
+)
+
+// pdfText recovers the text of a PDF written by the text renderer, one line
+// per text operator. It only understands the simple literal strings gofpdf
+// writes; other PDFs, such as Chrome's, yield little or nothing.
+func pdfText(data []byte) string {
+	// gofpdf's Unicode fonts are composite fonts showing UTF-16 strings
+	unicode := bytes.Contains(data, []byte("/Subtype /Type0"))
+	var lines []string
+	for _, m := range pdfStreamPattern.FindAllSubmatch(data, -1) {
+		content := m[1]
+		if r, err := zlib.NewReader(bytes.NewReader(content)); err == nil {
+			if inflated, err := io.ReadAll(r); err == nil {
+				content = inflated
+			}
+		}
+		for _, t := range pdfShowTextPattern.FindAllSubmatch(content, -1) {
+			if unicode {
+				lines = append(lines, utf16BEToUTF8(string(unescapePDFBytes(t[1]))))
+			} else {
+				lines = append(lines, unescapePDFString(t[1]))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unescapePDFBytes decodes the escape sequences of a PDF literal string.
+func unescapePDFBytes(s []byte) []byte {
+	var b bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			n, _ := strconv.ParseUint(string(s[i:j]), 8, 8)
+			b.WriteByte(byte(n))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.Bytes()
+}
+
+// unescapePDFString decodes a PDF literal string shown with a simple font.
+func unescapePDFString(s []byte) string {
+	text := string(unescapePDFBytes(s))
+	// Older gofpdf output passed UTF-8 through as is, other writers use
+	// single byte encodings.
+	if utf8.ValidString(text) {
+		return text
+	}
+	return latin1ToUTF8(text)
+}
+
+// utf16BEToUTF8 converts a big-endian UTF-16 string to UTF-8, dropping a
+// byte order mark.
+func utf16BEToUTF8(s string) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	if len(units) > 0 && units[0] == 0xfeff {
+		units = units[1:]
+	}
+	return string(utf16.Decode(units))
+}
+
+// latin1ToUTF8 converts a single byte encoded string to UTF-8.
+func latin1ToUTF8(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
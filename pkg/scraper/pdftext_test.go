@@ -0,0 +1,46 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnescapePDFString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`plain`, "plain"},
+		{`a \(paren\) and \\ backslash`, `a (paren) and \ backslash`},
+		{`tab\there`, "tab\there"},
+		{`octal \101\102`, "octal AB"},
+		{"caf\xe9", "café"},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		if got := unescapePDFString([]byte(tt.in)); got != tt.want {
+			t.Errorf("unescapePDFString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFTextRoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.pdf")
+	s := NewScraper(true, false)
+	p := &page{Text: "First line (with parens)\n\nSecond line: Ελληνικά, кириллица"}
+	if err := s.createPDF(file, p); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := pdfText(data)
+	for _, want := range []string{"First line (with parens)", "Second line: Ελληνικά, кириллица"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("pdfText() = %q, missing %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// pipe runs command with the shell, writing input to its stdin, and
+// returns what it printed. The command gets the page's URL and title in
+// SCRAPDF_URL and SCRAPDF_TITLE, so one script can serve several sites, and
+// is killed after PageTimeout, or when the crawl is cancelled.
+func (s *Scraper) pipe(command string, p *page, input []byte) ([]byte, error) {
+	ctx := s.crawlContext()
+	if s.PageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.PageTimeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	// Children the shell started may hold stdout open after it is killed
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), "SCRAPDF_URL="+p.URL.String(), "SCRAPDF_TITLE="+p.Title)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if err := s.cancelled(); err != nil {
+			return nil, fmt.Errorf("%q stopped: %w", command, err)
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%q timed out after %s", command, s.PageTimeout)
+		}
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); msg != "" && errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("%q failed: %v: %s", command, err, msg)
+		}
+		return nil, fmt.Errorf("%q failed: %w", command, err)
+	}
+	// An empty page is more likely a broken script than the intent
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return nil, fmt.Errorf("%q printed nothing", command)
+	}
+	return stdout.Bytes(), nil
+}
+
+// pipeHTML passes the body of p through PipeHTML before its text is
+// extracted. The title is read again, in case the command changed it.
+func (s *Scraper) pipeHTML(p *page) error {
+	body, err := s.pipe(s.PipeHTML, p, p.Body)
+	if err != nil {
+		return fmt.Errorf("HTML filter %w", err)
+	}
+	if !bytes.Equal(body, p.Body) {
+		p.audit(transform{Kind: transformPipeHTML, Rule: s.PipeHTML})
+	}
+	p.Body = body
+	if title := pageTitle(body); title != "" {
+		p.Title = title
+	}
+	return nil
+}
+
+// pipeText passes the content extracted from p through PipeText.
+func (s *Scraper) pipeText(p *page, content string) (string, error) {
+	text, err := s.pipe(s.PipeText, p, []byte(content))
+	if err != nil {
+		return "", fmt.Errorf("text filter %w", err)
+	}
+	p.auditLines(transformPipeText, s.PipeText, content, string(text))
+	return string(text), nil
+}
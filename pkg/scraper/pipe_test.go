@@ -0,0 +1,64 @@
+package scraper
+
+import (
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPageContentPipes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are POSIX shell")
+	}
+	u, _ := url.Parse("https://example.com/guide")
+	body := "<html><head><title>Guide</title></head><body><p>Keep this</p><aside>Ad</aside></body></html>"
+
+	tests := []struct {
+		name      string
+		strip     bool
+		pipeHTML  string
+		pipeText  string
+		want      string
+		notWant   string
+		wantTitle string
+		wantErr   string
+	}{
+		{name: "html filter", strip: true,
+			pipeHTML: `sed -e 's/<aside>Ad<\/aside>//' -e 's/<title>Guide/<title>Manual/'`,
+			want:     "Keep this", notWant: "Ad", wantTitle: "Manual"},
+		{name: "text filter", strip: true, pipeText: "tr a-z A-Z", want: "KEEP THIS", notWant: "Keep", wantTitle: "Guide"},
+		{name: "page variables", pipeHTML: `printf '%s %s' "$SCRAPDF_URL" "$SCRAPDF_TITLE"`,
+			want: "https://example.com/guide Guide"},
+		{name: "failing command", pipeHTML: "echo broken >&2; exit 3", wantErr: "exit status 3: broken"},
+		{name: "no output", pipeText: "cat >/dev/null", wantErr: "printed nothing"},
+		{name: "timeout", pipeHTML: "sleep 5", wantErr: "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper(tt.strip, false)
+			s.PipeHTML = tt.pipeHTML
+			s.PipeText = tt.pipeText
+			s.PageTimeout = 200 * time.Millisecond
+			p := &page{URL: u, Body: []byte(body), Title: pageTitle([]byte(body))}
+
+			got, err := s.pageContent(p)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("pageContent() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(got, tt.want) || (tt.notWant != "" && strings.Contains(got, tt.notWant)) {
+				t.Errorf("pageContent() = %q, want %q", got, tt.want)
+			}
+			if tt.wantTitle != "" && p.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", p.Title, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// CrawlPlan lists the pages a crawl would archive, found by crawling
+// without rendering anything.
+type CrawlPlan struct {
+	Pages []PlannedPage `json:"pages"`
+	// Skipped lists the URLs the crawl reached but left out, such as
+	// links excluded by URL patterns, with why.
+	Skipped []PlannedSkip `json:"skipped"`
+}
+
+// PlannedPage is a page a crawl would archive.
+type PlannedPage struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"` // 1 for the start URLs
+	Title string `json:"title,omitempty"`
+}
+
+// PlannedSkip is a URL a crawl would leave out.
+type PlannedSkip struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// planner collects the plan of a crawl for PlanCrawl.
+type planner struct {
+	mu      sync.Mutex
+	plan    CrawlPlan
+	skipped map[string]bool
+}
+
+// add records that p would be archived.
+func (l *planner) add(p *page) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.plan.Pages = append(l.plan.Pages, PlannedPage{URL: p.URL.String(), Depth: p.Depth, Title: p.Title})
+}
+
+// skip records that u would be left out for reason, once per URL.
+func (l *planner) skip(u, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.skipped[u] {
+		return
+	}
+	l.skipped[u] = true
+	l.plan.Skipped = append(l.plan.Skipped, PlannedSkip{URL: u, Reason: reason})
+}
+
+// PlanCrawl crawls from startURL with every setting of a real run, to list
+// the pages it would archive and their depth, without rendering them or
+// writing an archive. The pages are listed by depth, in the order they
+// were reached. Any credentials in the returned plan and error are masked.
+func (s *Scraper) PlanCrawl(startURL string) (*CrawlPlan, error) {
+	if s.Resume {
+		return nil, fmt.Errorf("a dry run starts a new crawl, it can't be resumed")
+	}
+	dir, err := os.MkdirTemp("", "scrapdf-plan-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	s.plan = &planner{skipped: make(map[string]bool)}
+	if err := s.scrapeAndSave(startURL, filepath.Join(dir, "plan.zip")); err != nil {
+		return nil, s.redactError(err)
+	}
+	plan := s.plan.plan
+	sort.SliceStable(plan.Pages, func(i, j int) bool {
+		return plan.Pages[i].Depth < plan.Pages[j].Depth
+	})
+	for i := range plan.Pages {
+		plan.Pages[i].URL = s.Redact(plan.Pages[i].URL)
+	}
+	for i := range plan.Skipped {
+		plan.Skipped[i].URL = s.Redact(plan.Skipped[i].URL)
+	}
+	if plan.Pages == nil {
+		plan.Pages = []PlannedPage{}
+	}
+	if plan.Skipped == nil {
+		plan.Skipped = []PlannedSkip{}
+	}
+	return &plan, nil
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPlanCrawl(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body>
+				<a href="/guide">Guide</a> <a href="/private/keys">Keys</a></body></html>`)
+		case "/guide":
+			fmt.Fprint(w, `<html><head><title>Guide</title></head><body><a href="/guide/deep">Deep</a></body></html>`)
+		case "/guide/deep":
+			fmt.Fprint(w, `<html><head><title>Deep</title></head><body><a href="/guide/deeper">Deeper</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	private, err := ParseURLPattern("/private/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewScraper(true, false)
+	s.MaxDepth = 3
+	s.Exclude = []URLPattern{private}
+	plan, err := s.PlanCrawl(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PlannedPage{
+		{URL: srv.URL + "/", Depth: 1, Title: "Home"},
+		{URL: srv.URL + "/guide", Depth: 2, Title: "Guide"},
+		{URL: srv.URL + "/guide/deep", Depth: 3, Title: "Deep"},
+	}
+	if fmt.Sprint(plan.Pages) != fmt.Sprint(want) {
+		t.Errorf("planned pages = %v, want %v", plan.Pages, want)
+	}
+	if len(plan.Skipped) != 1 || plan.Skipped[0].URL != srv.URL+"/private/keys" {
+		t.Errorf("skipped = %v, want the excluded link", plan.Skipped)
+	}
+}
+
+func TestPlanCrawlResume(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Resume = true
+	if _, err := s.PlanCrawl("http://example.com/"); err == nil {
+		t.Error("PlanCrawl() of a resumed crawl succeeded")
+	}
+}
@@ -0,0 +1,36 @@
+package scraper
+
+import (
+	"fmt"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// limitRule returns the per host limits of the crawl.
+func (s *Scraper) limitRule() (*colly.LimitRule, error) {
+	if s.Delay < 0 || s.RandomDelay < 0 {
+		return nil, fmt.Errorf("delays can't be negative")
+	}
+	if s.Parallelism < 0 {
+		return nil, fmt.Errorf("parallelism can't be negative")
+	}
+	if s.MaxConnsPerHost < 0 {
+		return nil, fmt.Errorf("connections per host can't be negative")
+	}
+	rule := &colly.LimitRule{
+		DomainGlob:  "*",
+		Delay:       s.Delay,
+		RandomDelay: s.RandomDelay,
+		Parallelism: s.Parallelism,
+	}
+	if s.Adaptive {
+		// The adaptive limiter decides, colly only enforces the ceiling
+		rule.Parallelism = s.adaptiveMax()
+	}
+	return rule, nil
+}
+
+// concurrent reports whether pages are fetched in parallel.
+func (s *Scraper) concurrent() bool {
+	return s.Parallelism > 1 || s.Adaptive
+}
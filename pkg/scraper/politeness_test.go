@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitRule(t *testing.T) {
+	s := &Scraper{Delay: time.Second, RandomDelay: 500 * time.Millisecond, Parallelism: 3}
+	rule, err := s.limitRule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Delay != time.Second || rule.RandomDelay != 500*time.Millisecond || rule.Parallelism != 3 {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+	if err := rule.Init(); err != nil {
+		t.Errorf("rule does not apply to every host: %v", err)
+	}
+	if !s.concurrent() {
+		t.Error("parallelism 3 should fetch concurrently")
+	}
+
+	for _, bad := range []*Scraper{{Delay: -time.Second}, {RandomDelay: -time.Second}, {Parallelism: -1}} {
+		if _, err := bad.limitRule(); err == nil {
+			t.Errorf("expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestConcurrentCrawl(t *testing.T) {
+	// Every page links to the next ten, so workers keep finding the same
+	// pages at once
+	const pages = 40
+	srv := linkedSite(pages, 10)
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.MaxDepth = 0
+	s.Parallelism = 8
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	if got := archivedPDFs(t, zipname); got != pages || len(s.pdfs) != pages {
+		t.Errorf("archived %d PDFs of %d pages, want %d", got, len(s.pdfs), pages)
+	}
+}
+
+// linkedSite serves pages /p1 to /p<pages-1> after the start page /, each
+// linking to the next fanout pages.
+func linkedSite(pages, fanout int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		if _, err := fmt.Sscanf(r.URL.Path, "/p%d", &n); err != nil && r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>Page %d</title></head><body><p>Page %d.</p>", n, n)
+		for i := n + 1; i <= min(n+fanout, pages-1); i++ {
+			fmt.Fprintf(w, `<a href="/p%d">next</a>`, i)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+}
+
+// archivedPDFs counts the page PDFs in the ZIP zipname.
+func archivedPDFs(t *testing.T, zipname string) int {
+	t.Helper()
+	var pdfs int
+	for name := range zipEntries(t, zipname) {
+		if strings.HasSuffix(name, ".pdf") && !strings.HasPrefix(name, "_") {
+			pdfs++
+		}
+	}
+	return pdfs
+}
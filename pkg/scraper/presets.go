@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Preset bundles the scope, content selectors and navigation selector of a
+// documentation platform, so its sites are crawled and ordered correctly
+// without hand tuning.
+type Preset struct {
+	Name string
+	// Content and Remove are applied like a Recipe on every page.
+	Content string
+	Remove  []string
+	// Nav selects the sidebar links whose order becomes the page order.
+	Nav string
+	// scope returns the path prefix the crawl is limited to.
+	scope func(start *url.URL) string
+}
+
+var presets = map[string]Preset{
+	"github-wiki": {
+		Name:    "github-wiki",
+		Content: "h1.gh-header-title, #wiki-body .markdown-body, .wiki-page-title, .wiki-page-details .md",
+		Remove:  []string{".wiki-footer", "a.anchor"},
+		Nav:     "#wiki-pages-box a, .wiki-custom-sidebar a, .wiki-pages a",
+		scope:   wikiScope,
+	},
+	"readthedocs": {
+		Name:    "readthedocs",
+		Content: "div[role=main]",
+		Remove:  []string{"a.headerlink", ".rst-footer-buttons", ".wy-breadcrumbs", "footer"},
+		Nav:     ".wy-menu-vertical a, .sidebar-tree a",
+		scope:   readTheDocsScope,
+	},
+	"mkdocs": {
+		Name:    "mkdocs",
+		Content: "article.md-content__inner, div[role=main]",
+		Remove:  []string{"a.headerlink", ".md-source-file", ".md-content__button"},
+		Nav:     ".md-nav--primary a.md-nav__link, .wy-menu-vertical a, .bs-sidebar a",
+		scope:   directoryScope,
+	},
+	"docusaurus": {
+		Name:    "docusaurus",
+		Content: "article",
+		Remove:  []string{".theme-doc-toc-mobile", ".theme-doc-footer", ".pagination-nav", ".theme-doc-breadcrumbs", "a.hash-link"},
+		Nav:     ".theme-doc-sidebar-menu a, nav.menu a",
+		scope:   firstSegmentScope,
+	},
+}
+
+// PresetNames returns the names accepted by LookupPreset.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupPreset returns the preset called name.
+func LookupPreset(name string) (Preset, error) {
+	p, ok := presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return p, nil
+}
+
+// recipe returns the preset's selectors as a recipe for host.
+func (p Preset) recipe(host string) (*Recipe, error) {
+	r := &Recipe{
+		Name:    "preset:" + p.Name,
+		Domains: []string{host},
+		Content: p.Content,
+		Remove:  p.Remove,
+	}
+	if err := r.compile(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// applyPreset configures the scraper for the preset and start URL. Explicit
+// settings for the path prefix and navigation selector take precedence.
+func (s *Scraper) applyPreset(name string, start *url.URL) error {
+	p, err := LookupPreset(name)
+	if err != nil {
+		return err
+	}
+	r, err := p.recipe(start.Hostname())
+	if err != nil {
+		return err
+	}
+	s.Recipes = append([]*Recipe{r}, s.Recipes...)
+	if s.PathPrefix == "" {
+		s.PathPrefix = p.scope(start)
+	}
+	if s.NavSelector == "" {
+		s.NavSelector = p.Nav
+	}
+	return nil
+}
+
+// wikiScope limits the crawl to a GitHub (/owner/repo/wiki) or GitLab
+// (/group/project/-/wikis) wiki.
+func wikiScope(start *url.URL) string {
+	if i := strings.Index(start.Path, "/-/wikis"); i >= 0 {
+		return start.Path[:i] + "/-/wikis"
+	}
+	segments := strings.Split(strings.Trim(start.Path, "/"), "/")
+	if len(segments) >= 3 && segments[2] == "wiki" {
+		return "/" + strings.Join(segments[:3], "/")
+	}
+	return directoryScope(start)
+}
+
+// readTheDocsScope limits the crawl to one language and version, e.g.
+// /en/latest/.
+func readTheDocsScope(start *url.URL) string {
+	segments := strings.Split(strings.Trim(start.Path, "/"), "/")
+	if len(segments) >= 2 && segments[0] != "" {
+		return "/" + segments[0] + "/" + segments[1] + "/"
+	}
+	return "/"
+}
+
+// directoryScope limits the crawl to the directory of the start page.
+func directoryScope(start *url.URL) string {
+	if strings.HasSuffix(start.Path, "/") {
+		return start.Path
+	}
+	if i := strings.LastIndex(start.Path, "/"); i >= 0 {
+		return start.Path[:i+1]
+	}
+	return "/"
+}
+
+// firstSegmentScope limits the crawl to the first path segment of the start
+// page, e.g. /docs/.
+func firstSegmentScope(start *url.URL) string {
+	trimmed := strings.Trim(start.Path, "/")
+	if trimmed == "" {
+		return "/"
+	}
+	segments := strings.Split(trimmed, "/")
+	if len(segments) >= 2 || strings.HasSuffix(start.Path, "/") {
+		return "/" + segments[0] + "/"
+	}
+	return "/"
+}
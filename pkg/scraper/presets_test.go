@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPresetScopes(t *testing.T) {
+	tests := []struct {
+		preset string
+		start  string
+		want   string
+	}{
+		{"github-wiki", "https://github.com/owner/repo/wiki", "/owner/repo/wiki"},
+		{"github-wiki", "https://github.com/owner/repo/wiki/Getting-Started", "/owner/repo/wiki"},
+		{"github-wiki", "https://gitlab.com/group/sub/project/-/wikis/home", "/group/sub/project/-/wikis"},
+		{"readthedocs", "https://requests.readthedocs.io/en/latest/user/quickstart/", "/en/latest/"},
+		{"readthedocs", "https://requests.readthedocs.io/", "/"},
+		{"mkdocs", "https://example.github.io/project/guide/intro/", "/project/guide/intro/"},
+		{"mkdocs", "https://example.github.io/project/index.html", "/project/"},
+		{"docusaurus", "https://example.com/docs/intro", "/docs/"},
+		{"docusaurus", "https://example.com/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset+" "+tt.start, func(t *testing.T) {
+			p, err := LookupPreset(tt.preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, _ := url.Parse(tt.start)
+			if got := p.scope(start); got != tt.want {
+				t.Errorf("scope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresetsCompile(t *testing.T) {
+	for _, name := range PresetNames() {
+		s := NewScraper(true, false)
+		start, _ := url.Parse("https://docs.example.com/")
+		if err := s.applyPreset(name, start); err != nil {
+			t.Errorf("applyPreset(%s) error = %v", name, err)
+		}
+	}
+	if _, err := LookupPreset("wordpress"); err == nil {
+		t.Errorf("LookupPreset() accepted an unknown preset")
+	}
+}
@@ -0,0 +1,441 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/temoto/robotstxt"
+	"golang.org/x/net/html"
+)
+
+// maxProbedSitemaps bounds how many sitemaps a probe reads, following
+// sitemap indexes.
+const maxProbedSitemaps = 50
+
+// probeTimeout bounds each request of a probe.
+const probeTimeout = 10 * time.Second
+
+// ProbeReport describes a site before it is crawled: how its start page
+// responds, what robots.txt asks of scrapdf and how many pages its sitemaps
+// list.
+type ProbeReport struct {
+	URL         string        `json:"url"`
+	FinalURL    string        `json:"final_url"` // after redirects
+	Status      int           `json:"status"`
+	Elapsed     time.Duration `json:"-"` // until the response headers arrived
+	Proto       string        `json:"proto"`
+	Server      string        `json:"server,omitempty"`
+	CDN         string        `json:"cdn,omitempty"` // guessed from the response headers, "" if none
+	ContentType string        `json:"content_type"`
+	Compressed  bool          `json:"compressed"`
+	// RateLimit holds the rate limit headers of the response, such as
+	// X-RateLimit-Remaining and Retry-After, as "Name: value".
+	RateLimit []string `json:"rate_limit,omitempty"`
+	// Links counts the links of the start page to its own site, Scripts
+	// its script tags and TextLength the characters of its text. A page of
+	// scripts with little text and few links is likely built by JavaScript.
+	Links      int    `json:"links"`
+	Scripts    int    `json:"scripts"`
+	TextLength int    `json:"text_length"`
+	UserAgent  string `json:"user_agent"`
+	// Robots is the site's robots.txt, nil when it has none.
+	Robots   *RobotsSummary   `json:"robots"`
+	Sitemaps []SitemapSummary `json:"sitemaps"`
+	// EstimatedPages is the number of sitemap URLs the crawl may visit,
+	// -1 when the site has no sitemap.
+	EstimatedPages int `json:"estimated_pages"`
+}
+
+// RobotsSummary is what robots.txt asks of the crawler.
+type RobotsSummary struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	// Group is the user agent of the group that applies to scrapdf, "*"
+	// for the default one, and Allow and Disallow its rules.
+	Group      string        `json:"group,omitempty"`
+	Allow      []string      `json:"allow,omitempty"`
+	Disallow   []string      `json:"disallow,omitempty"`
+	CrawlDelay time.Duration `json:"-"`
+	// StartAllowed reports whether the start URL may be crawled.
+	StartAllowed bool     `json:"start_allowed"`
+	Sitemaps     []string `json:"sitemaps,omitempty"`
+}
+
+// SitemapSummary describes a sitemap, or a sitemap index.
+type SitemapSummary struct {
+	URL   string `json:"url"`
+	Index bool   `json:"index"` // a sitemap index, whose entries are sitemaps
+	URLs  int    `json:"urls"`  // pages, or sitemaps of an index
+	// InScope counts the pages the crawl may visit, on the start URL's host
+	// and allowed by Include and Exclude.
+	InScope    int    `json:"in_scope"`
+	LastMod    string `json:"last_mod,omitempty"` // most recent lastmod of the pages
+	Compressed bool   `json:"compressed"`
+	Err        string `json:"error,omitempty"`
+}
+
+// MarshalJSON writes the report with its elapsed time in milliseconds,
+// like the event log.
+func (r ProbeReport) MarshalJSON() ([]byte, error) {
+	type plain ProbeReport
+	return json.Marshal(struct {
+		plain
+		Elapsed float64 `json:"elapsed_ms"`
+	}{plain(r), milliseconds(r.Elapsed)})
+}
+
+// MarshalJSON writes the summary with its crawl delay in milliseconds.
+func (r RobotsSummary) MarshalJSON() ([]byte, error) {
+	type plain RobotsSummary
+	return json.Marshal(struct {
+		plain
+		CrawlDelay float64 `json:"crawl_delay_ms,omitempty"`
+	}{plain(r), milliseconds(r.CrawlDelay)})
+}
+
+// Probe fetches the start page, robots.txt and sitemaps of startURL with the
+// headers, cookies and transport of a crawl, without crawling. Any
+// credentials in the returned error are masked.
+func (s *Scraper) Probe(startURL string) (*ProbeReport, error) {
+	s.Secrets = append(s.Secrets, s.requestSecrets()...)
+	report, err := s.probe(startURL)
+	return report, s.redactError(err)
+}
+
+func (s *Scraper) probe(startURL string) (*ProbeReport, error) {
+	start, err := url.Parse(startURL)
+	if err != nil || start.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", startURL)
+	}
+	client, err := s.probeClient(start)
+	if err != nil {
+		return nil, err
+	}
+	report := &ProbeReport{URL: start.String(), UserAgent: colly.NewCollector().UserAgent, EstimatedPages: -1}
+
+	get := func(u string) (*http.Response, []byte, error) {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		for name, values := range s.Headers {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+		if req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", report.UserAgent)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp, body, err
+	}
+
+	started := time.Now()
+	resp, body, err := get(start.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", start, err)
+	}
+	report.Elapsed = time.Since(started)
+	report.FinalURL = resp.Request.URL.String()
+	report.Status = resp.StatusCode
+	report.Proto = resp.Proto
+	report.Server = resp.Header.Get("Server")
+	report.CDN = detectCDN(resp.Header)
+	report.ContentType = resp.Header.Get("Content-Type")
+	report.Compressed = resp.Uncompressed || resp.Header.Get("Content-Encoding") != ""
+	report.RateLimit = rateLimitHeaders(resp.Header)
+	if strings.Contains(report.ContentType, "html") {
+		report.Links, report.Scripts, report.TextLength = pageShape(body, resp.Request.URL)
+	}
+
+	robotsURL := start.Scheme + "://" + start.Host + "/robots.txt"
+	// Without a Sitemap line in robots.txt, look where sitemaps usually are
+	guessed := start.Scheme + "://" + start.Host + "/sitemap.xml"
+	sitemaps := []string{guessed}
+	if resp, body, err := get(robotsURL); err == nil && resp.StatusCode < 400 {
+		report.Robots = summarizeRobots(robotsURL, resp.StatusCode, body, start, report.UserAgent)
+		if len(report.Robots.Sitemaps) > 0 {
+			sitemaps, guessed = report.Robots.Sitemaps, ""
+		}
+	}
+
+	queue, seen := sitemaps, make(map[string]bool)
+	for len(queue) > 0 && len(report.Sitemaps) < maxProbedSitemaps {
+		u := queue[0]
+		queue = queue[1:]
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		sum := SitemapSummary{URL: u}
+		resp, body, err := get(u)
+		switch {
+		case err != nil:
+			sum.Err = err.Error()
+		case resp.StatusCode != http.StatusOK:
+			sum.Err = resp.Status
+		default:
+			var children []string
+			children, err = s.readSitemap(&sum, body, start)
+			if err != nil {
+				sum.Err = err.Error()
+			}
+			queue = append(queue, children...)
+		}
+		if sum.Err != "" && u == guessed {
+			continue
+		}
+		report.Sitemaps = append(report.Sitemaps, sum)
+	}
+	for _, sum := range report.Sitemaps {
+		if !sum.Index && sum.Err == "" {
+			report.EstimatedPages = max(report.EstimatedPages, 0) + sum.InScope
+		}
+	}
+	return report, nil
+}
+
+// probeClient returns the HTTP client of a probe, sending Cookies to the
+// start URL's site like a crawl does.
+func (s *Scraper) probeClient(start *url.URL) (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	cookies := make([]*http.Cookie, 0, len(s.Cookies))
+	for _, cookie := range s.Cookies {
+		cookie := *cookie
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		cookies = append(cookies, &cookie)
+	}
+	jar.SetCookies(start, cookies)
+	client := &http.Client{Jar: jar, Timeout: probeTimeout}
+	if transport := s.transport(); transport != nil {
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// cdnSignatures recognise CDNs and hosts by a response header containing a
+// value, any value when it's empty.
+var cdnSignatures = []struct {
+	header, value, name string
+}{
+	{"Cf-Ray", "", "Cloudflare"},
+	{"Server", "cloudflare", "Cloudflare"},
+	{"X-Amz-Cf-Id", "", "Amazon CloudFront"},
+	{"X-Fastly-Request-Id", "", "Fastly"},
+	{"X-Served-By", "cache-", "Fastly"},
+	{"Akamai-Grn", "", "Akamai"},
+	{"Server", "AkamaiGHost", "Akamai"},
+	{"X-Azure-Ref", "", "Azure Front Door"},
+	{"X-Vercel-Id", "", "Vercel"},
+	{"X-Nf-Request-Id", "", "Netlify"},
+	{"X-Github-Request-Id", "", "GitHub Pages"},
+	{"Via", "varnish", "Varnish"},
+}
+
+// detectCDN returns the CDN that served a response with header, or "".
+func detectCDN(header http.Header) string {
+	for _, sig := range cdnSignatures {
+		if v := header.Get(sig.header); v != "" && strings.Contains(strings.ToLower(v), strings.ToLower(sig.value)) {
+			return sig.name
+		}
+	}
+	return ""
+}
+
+// rateLimitHeaders returns the rate limit headers of header as
+// "Name: value", sorted.
+func rateLimitHeaders(header http.Header) []string {
+	var found []string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-ratelimit") || strings.HasPrefix(lower, "x-rate-limit") || strings.HasPrefix(lower, "ratelimit") || lower == "retry-after" {
+			found = append(found, name+": "+strings.Join(values, ", "))
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// pageShape counts the links of the page at base to its own host and its
+// script tags, and measures its text.
+func pageShape(body []byte, base *url.URL) (links, scripts, textLength int) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, 0
+	}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script":
+				scripts++
+			case "a":
+				if u, err := base.Parse(getAttr(n, "href")); err == nil && u.Host == base.Host && getAttr(n, "href") != "" {
+					links++
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return links, scripts, len([]rune(strings.Join(strings.Fields(nodeText(doc)), " ")))
+}
+
+// summarizeRobots reads the robots.txt at robotsURL for agent.
+func summarizeRobots(robotsURL string, status int, body []byte, start *url.URL, agent string) *RobotsSummary {
+	sum := &RobotsSummary{URL: robotsURL, Status: status, StartAllowed: true}
+	robots, err := robotstxt.FromStatusAndBytes(status, body)
+	if err != nil {
+		return sum
+	}
+	sum.Sitemaps = robots.Sitemaps
+	group := robots.FindGroup(agent)
+	sum.CrawlDelay = group.CrawlDelay
+	path := start.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if start.RawQuery != "" {
+		path += "?" + start.RawQuery
+	}
+	sum.StartAllowed = group.Test(path)
+	sum.Group, sum.Allow, sum.Disallow = robotsRules(body, agent)
+	return sum
+}
+
+// robotsRules returns the user agent of the group of the robots.txt body
+// that applies to agent, the most specific one like robotstxt picks, and its
+// Allow and Disallow rules, which robotstxt doesn't expose.
+func robotsRules(body []byte, agent string) (group string, allow, disallow []string) {
+	type rules struct{ allow, disallow []string }
+	groups := make(map[string]*rules)
+	var current []*rules
+	agentLines := false
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "user-agent":
+			if !agentLines {
+				// A run of user-agent lines starts a new group
+				current = nil
+			}
+			agentLines = true
+			name := strings.ToLower(value)
+			if groups[name] == nil {
+				groups[name] = &rules{}
+			}
+			current = append(current, groups[name])
+			continue
+		case "allow":
+			for _, r := range current {
+				if value != "" {
+					r.allow = append(r.allow, value)
+				}
+			}
+		case "disallow":
+			for _, r := range current {
+				if value != "" {
+					r.disallow = append(r.disallow, value)
+				}
+			}
+		}
+		agentLines = false
+	}
+
+	agent = strings.ToLower(agent)
+	if groups["*"] != nil {
+		group = "*"
+	}
+	for name := range groups {
+		if name != "*" && strings.HasPrefix(agent, name) && (group == "*" || len(name) > len(group)) {
+			group = name
+		}
+	}
+	if r := groups[group]; r != nil {
+		return group, r.allow, r.disallow
+	}
+	return "", nil, nil
+}
+
+// sitemapDocument is a sitemap or a sitemap index.
+type sitemapDocument struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// readSitemap fills sum from the sitemap body, gzipped or not, and returns
+// the sitemaps listed when it is an index.
+func (s *Scraper) readSitemap(sum *SitemapSummary, body []byte, start *url.URL) ([]string, error) {
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body, err = io.ReadAll(gz); err != nil {
+			return nil, err
+		}
+		sum.Compressed = true
+	}
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("not a sitemap: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case "sitemapindex":
+		sum.Index = true
+		sum.URLs = len(doc.Sitemaps)
+		var children []string
+		for _, child := range doc.Sitemaps {
+			children = append(children, strings.TrimSpace(child.Loc))
+		}
+		return children, nil
+	case "urlset":
+		sum.URLs = len(doc.URLs)
+		for _, entry := range doc.URLs {
+			u, err := url.Parse(strings.TrimSpace(entry.Loc))
+			if err == nil && u.Host == start.Host && s.urlAllowed(u) {
+				sum.InScope++
+			}
+			// W3C dates compare as strings
+			if lastMod := strings.TrimSpace(entry.LastMod); lastMod > sum.LastMod {
+				sum.LastMod = lastMod
+			}
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("not a sitemap: <%s> root element", doc.XMLName.Local)
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", "59")
+			w.Header().Set("Cf-Ray", "8a1b2c3d")
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><script src="app.js"></script><div id="root"></div><a href="/docs/a">A</a><a href="https://other.example/">B</a></body></html>`)
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /private/\nCrawl-delay: 2\nSitemap: %s/sitemap_index.xml\n", srv.URL)
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%[1]s/pages.xml.gz</loc></sitemap><sitemap><loc>%[1]s/missing.xml</loc></sitemap></sitemapindex>`, srv.URL)
+		case "/pages.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, `<urlset><url><loc>%[1]s/docs/a</loc><lastmod>2024-03-01</lastmod></url><url><loc>%[1]s/blog/b</loc><lastmod>2024-05-02</lastmod></url><url><loc>https://other.example/c</loc></url></urlset>`, srv.URL)
+			gz.Close()
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(false, false)
+	s.Headers = http.Header{"Authorization": {"Bearer token"}}
+	blog, err := ParseURLPattern("/blog/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Exclude = []URLPattern{blog}
+	r, err := s.Probe(srv.URL + "/docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Status != http.StatusOK || r.CDN != "Cloudflare" || !reflect.DeepEqual(r.RateLimit, []string{"X-Ratelimit-Remaining: 59"}) {
+		t.Errorf("status %d, CDN %q, rate limits %q; want 200, Cloudflare and X-Ratelimit-Remaining", r.Status, r.CDN, r.RateLimit)
+	}
+	if r.Links != 1 || r.Scripts != 1 || r.TextLength != 3 {
+		t.Errorf("start page has %d links, %d scripts and %d characters, want 1, 1 and 3", r.Links, r.Scripts, r.TextLength)
+	}
+	if r.Robots == nil {
+		t.Fatal("robots.txt not found")
+	}
+	if rb := r.Robots; rb.Group != "*" || rb.CrawlDelay != 2*time.Second || !rb.StartAllowed || !reflect.DeepEqual(rb.Disallow, []string{"/private/"}) {
+		t.Errorf("robots.txt = %+v", rb)
+	}
+	if len(r.Sitemaps) != 3 {
+		t.Fatalf("got %d sitemaps, want 3: %+v", len(r.Sitemaps), r.Sitemaps)
+	}
+	if sm := r.Sitemaps[1]; sm.URLs != 3 || sm.InScope != 1 || sm.LastMod != "2024-05-02" || !sm.Compressed {
+		t.Errorf("gzipped sitemap = %+v, want 3 pages, 1 in scope, last modified 2024-05-02", sm)
+	}
+	if sm := r.Sitemaps[2]; sm.Err == "" {
+		t.Errorf("missing sitemap = %+v, want an error", sm)
+	}
+	if r.EstimatedPages != 1 {
+		t.Errorf("EstimatedPages = %d, want 1", r.EstimatedPages)
+	}
+}
+
+func TestProbeGuessedSitemap(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, "<html><body>Home</body></html>")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	r, err := NewScraper(false, false).Probe(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Robots != nil || len(r.Sitemaps) != 0 || r.EstimatedPages != -1 {
+		t.Errorf("robots %+v, sitemaps %+v, estimate %d; want none and -1", r.Robots, r.Sitemaps, r.EstimatedPages)
+	}
+}
+
+func TestRobotsRules(t *testing.T) {
+	body := []byte(`# rules
+User-agent: *
+Disallow: /private/
+Allow: /private/ok
+
+User-agent: Googlebot
+User-agent: scrapdf
+Disallow: /   # everything
+`)
+	tests := []struct {
+		agent    string
+		group    string
+		disallow []string
+	}{
+		{"colly - https://github.com/gocolly/colly/v2", "*", []string{"/private/"}},
+		{"scrapdf/1.0", "scrapdf", []string{"/"}},
+		{"Googlebot-Image", "googlebot", []string{"/"}},
+	}
+	for _, tt := range tests {
+		group, _, disallow := robotsRules(body, tt.agent)
+		if group != tt.group || !reflect.DeepEqual(disallow, tt.disallow) {
+			t.Errorf("robotsRules(%q) = %q, %q; want %q, %q", tt.agent, group, disallow, tt.group, tt.disallow)
+		}
+	}
+}
+
+func TestDetectCDN(t *testing.T) {
+	tests := []struct {
+		header http.Header
+		want   string
+	}{
+		{http.Header{"Server": {"cloudflare"}}, "Cloudflare"},
+		{http.Header{"X-Amz-Cf-Id": {"abc"}}, "Amazon CloudFront"},
+		{http.Header{"X-Served-By": {"cache-mad2200-MAD"}}, "Fastly"},
+		{http.Header{"Via": {"1.1 varnish (Varnish/7.1)"}}, "Varnish"},
+		{http.Header{"Server": {"nginx"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := detectCDN(tt.header); got != tt.want {
+			t.Errorf("detectCDN(%v) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
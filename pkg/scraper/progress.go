@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+)
+
+// progressInterval is how often OnProgress is called while a crawl runs.
+const progressInterval = 250 * time.Millisecond
+
+// Progress is the state of a running crawl, reported to OnProgress.
+type Progress struct {
+	Discovered int // pages found so far, processed or waiting
+	Processed  int // pages fetched and processed, archived or not
+	Converted  int // pages archived
+	Failed     int // pages that failed to fetch or convert
+	Elapsed    time.Duration
+	// ETA is the time left to process the pages waiting, at the pace so
+	// far, zero until a page was processed. Pages not yet discovered
+	// aren't counted, so it grows as the crawl finds more.
+	ETA  time.Duration
+	Done bool // set on the last report, once the archive is written
+}
+
+// progress returns the state of the crawl started at started, with
+// before pages already processed by a resumed run.
+func (s *Scraper) progress(started time.Time, before int) Progress {
+	s.mu.Lock()
+	p := Progress{
+		Processed: len(s.state.done),
+		Converted: len(s.pdfs),
+		Failed:    len(s.failures),
+		Elapsed:   time.Since(started),
+	}
+	waiting := len(s.state.queue)
+	s.mu.Unlock()
+	p.Discovered = p.Processed + waiting
+	if n := p.Processed - before; n > 0 {
+		p.ETA = p.Elapsed * time.Duration(waiting) / time.Duration(n)
+	}
+	return p
+}
+
+// startProgress reports the state of the crawl to OnProgress until the
+// returned function is called, which makes the last report.
+func (s *Scraper) startProgress() func() {
+	started := time.Now()
+	s.mu.Lock()
+	before := len(s.state.done)
+	s.mu.Unlock()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.OnProgress(s.progress(started, before))
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		wg.Wait()
+		p := s.progress(started, before)
+		p.ETA, p.Done = 0, true
+		s.OnProgress(p)
+	}
+}
@@ -0,0 +1,58 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		// Slow enough for reports while the crawl runs
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p><a href="/a">a</a> <a href="/b">b</a> <a href="/missing">gone</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var reports []Progress
+	var log bytes.Buffer
+	s := NewScraper(true, false)
+	s.Retries = 0
+	s.Log = &log
+	s.OnProgress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	}
+	if err := s.ScrapeAndSave(srv.URL+"/", filepath.Join(t.TempDir(), "site.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("got %d progress reports, want some while crawling and a last one", len(reports))
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Converted != 3 || last.Failed != 1 || last.Processed != 4 || last.Discovered != 4 {
+		t.Errorf("last report = %+v, want 3 pages archived and 1 failed of 4", last)
+	}
+	for _, p := range reports[:len(reports)-1] {
+		if p.Done || p.Processed > p.Discovered {
+			t.Errorf("report while crawling = %+v", p)
+		}
+	}
+	if strings.Contains(log.String(), "Created PDF") {
+		t.Errorf("archived pages were logged along with the progress:\n%s", log.String())
+	}
+}
@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// publishedMeta are the meta tags, by name, property or itemprop, that
+// give an article's publication date, most specific first.
+var publishedMeta = []string{
+	"article:published_time",
+	"datepublished",
+	"og:published_time",
+	"citation_publication_date",
+	"dc.date.issued",
+	"dcterms.created",
+	"pubdate",
+	"publish-date",
+	"publish_date",
+	"date",
+}
+
+// jsonLDPublished matches the publication date of JSON-LD metadata.
+var jsonLDPublished = regexp.MustCompile(`"datePublished"\s*:\s*"([^"]+)"`)
+
+// urlDatePattern matches dates in article URLs, such as /2023/04/05/,
+// /2023-04-05-title and /2023/04/.
+var urlDatePattern = regexp.MustCompile(`/((?:19|20)\d\d)[/-](0[1-9]|1[0-2])(?:[/-](0[1-9]|[12]\d|3[01]))?(?:[/-]|$)`)
+
+// dateLayouts are the date formats publication dates are read in.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"20060102",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// parseDate reads a publication date in any of dateLayouts.
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// publishedDate returns when the page at u with body was published, zero
+// when it can't tell. The date is taken from meta tags, then JSON-LD
+// metadata, then the first <time> element, and last from a date in the
+// URL.
+func publishedDate(body []byte, u *url.URL) time.Time {
+	meta := make(map[string]string)
+	var timeAttr string
+	z := html.NewTokenizer(bytes.NewReader(body))
+scan:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break scan
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "meta":
+				var key, content string
+				for _, a := range t.Attr {
+					switch a.Key {
+					case "name", "property", "itemprop":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = a.Val
+					}
+				}
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = content
+				}
+			case "time":
+				var datetime string
+				published := false
+				for _, a := range t.Attr {
+					switch {
+					case a.Key == "datetime":
+						datetime = a.Val
+					case a.Key == "pubdate", a.Key == "itemprop" && strings.EqualFold(a.Val, "datePublished"):
+						published = true
+					}
+				}
+				if datetime != "" && (timeAttr == "" || published) {
+					timeAttr = datetime
+				}
+			}
+		}
+	}
+	for _, name := range publishedMeta {
+		if t, ok := parseDate(meta[name]); ok {
+			return t
+		}
+	}
+	if m := jsonLDPublished.FindSubmatch(body); m != nil {
+		if t, ok := parseDate(string(m[1])); ok {
+			return t
+		}
+	}
+	if t, ok := parseDate(timeAttr); ok {
+		return t
+	}
+	if m := urlDatePattern.FindStringSubmatch(u.Path); m != nil {
+		day := m[3]
+		if day == "" {
+			day = "01"
+		}
+		if t, ok := parseDate(m[1] + "-" + m[2] + "-" + day); ok {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// sortByDate orders the archived pages oldest first by publication date
+// for OrderDate. Pages without a date go last, in crawl order.
+func (s *Scraper) sortByDate() {
+	if s.Order != OrderDate {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.SliceStable(s.pdfs, func(i, j int) bool {
+		a, b := s.pdfs[i].Published, s.pdfs[j].Published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+}
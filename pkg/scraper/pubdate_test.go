@@ -0,0 +1,116 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPublishedDate(t *testing.T) {
+	tests := []struct {
+		name, url, body string
+		want            string
+	}{
+		{"article meta", "/post", `<meta property="article:published_time" content="2021-03-04T10:00:00Z"><time datetime="2020-01-01">`, "2021-03-04"},
+		{"itemprop meta", "/post", `<meta itemprop="datePublished" content="2019-07-08">`, "2019-07-08"},
+		{"json-ld", "/post", `<script type="application/ld+json">{"@type": "BlogPosting", "datePublished": "2018-05-06T08:00:00+02:00"}</script>`, "2018-05-06"},
+		{"time", "/post", `<p>Posted <time datetime="2017-02-03">Feb 3</time></p>`, "2017-02-03"},
+		{"pubdate time", "/post", `<time datetime="2016-01-01">edited</time><time pubdate datetime="2015-12-24">`, "2015-12-24"},
+		{"written date", "/post", `<meta name="date" content="March 9, 2014">`, "2014-03-09"},
+		{"url", "/blog/2013/11/22/post/", `<p>No date</p>`, "2013-11-22"},
+		{"url month", "/2012/06/post.html", ``, "2012-06-01"},
+		{"url slug", "/posts/2011-10-09-post", ``, "2011-10-09"},
+		{"unparseable meta", "/2010/05/post", `<meta name="date" content="last tuesday">`, "2010-05-01"},
+		{"none", "/about", `<p>About us</p>`, ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse("https://example.com" + tt.url)
+		got := publishedDate([]byte("<html><head>"+tt.body+"</head></html>"), u)
+		var date string
+		if !got.IsZero() {
+			date = got.Format("2006-01-02")
+		}
+		if date != tt.want {
+			t.Errorf("%s: publishedDate() = %q, want %q", tt.name, date, tt.want)
+		}
+	}
+}
+
+func TestSortByDate(t *testing.T) {
+	mustPage := func(raw, date string) *page {
+		u, _ := url.Parse(raw)
+		p := &page{URL: u}
+		if date != "" {
+			p.Published, _ = time.Parse("2006-01-02", date)
+		}
+		return p
+	}
+	s := NewScraper(true, false)
+	s.Order = OrderDate
+	s.pdfs = []*page{
+		mustPage("https://example.com/", ""),
+		mustPage("https://example.com/new", "2024-01-01"),
+		mustPage("https://example.com/about", ""),
+		mustPage("https://example.com/old", "2020-01-01"),
+		mustPage("https://example.com/mid", "2022-01-01"),
+	}
+
+	s.sortByDate()
+
+	var got []string
+	for _, p := range s.pdfs {
+		got = append(got, p.URL.Path)
+	}
+	want := []string{"/old", "/mid", "/new", "/", "/about"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
+
+func TestScrapeOrderDate(t *testing.T) {
+	// The index links to the newest posts first
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/2023/05/third">3</a> <a href="/2022/01/second">2</a> <a href="/2021/09/first">1</a></body></html>`)
+		default:
+			fmt.Fprintf(w, `<html><head><title>%s</title></head><body><p>Post</p></body></html>`, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.Order = OrderDate
+	s.NoTOC = true
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Pages []struct {
+			URL       string     `json:"url"`
+			Published *time.Time `json:"published"`
+		} `json:"pages"`
+	}
+	if err := json.Unmarshal([]byte(zipEntries(t, zipname)["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range m.Pages {
+		got = append(got, p.URL)
+	}
+	want := []string{srv.URL + "/2021/09/first", srv.URL + "/2022/01/second", srv.URL + "/2023/05/third", srv.URL + "/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("manifest order = %v, want %v", got, want)
+	}
+	if m.Pages[0].Published == nil || m.Pages[3].Published != nil {
+		t.Errorf("published dates = %v and %v, want the post's only", m.Pages[0].Published, m.Pages[3].Published)
+	}
+}
@@ -0,0 +1,218 @@
+package scraper
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// The readability extractor follows the approach of Arc90's Readability:
+// paragraphs vote for the element containing them, weighted by their length
+// and punctuation, class and id names nudge the scores, and the best scoring
+// element is kept together with its siblings that look like content.
+
+var (
+	// unlikelyCandidate matches class and id names of page chrome.
+	unlikelyCandidate = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|consent|cookie|disqus|extra|footer|gdpr|header|menu|modal|nav|pager|pagination|popup|related|remark|replies|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|newsletter|ad-break|agegate`)
+	// maybeCandidate rescues elements that match unlikelyCandidate but also
+	// look like a content wrapper, e.g. "main-header-content".
+	maybeCandidate = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// positiveName and negativeName adjust the score of a candidate by its
+	// class and id.
+	positiveName = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeName = regexp.MustCompile(`(?i)-ad-|hidden|^hid$|banner|combx|comment|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// readabilityDropTags never hold article content.
+var readabilityDropTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "header": true,
+	"footer": true, "aside": true, "form": true, "iframe": true, "button": true,
+	"dialog": true, "svg": true, "select": true,
+}
+
+// readabilityScored are the elements whose text votes for their ancestors.
+var readabilityScored = map[string]bool{
+	"p": true, "pre": true, "td": true, "blockquote": true, "li": true,
+	"h2": true, "h3": true, "h4": true, "section": true,
+}
+
+// minParagraphChars is the shortest text that takes part in the vote.
+const minParagraphChars = 25
+
+// readability reduces doc to its main article. It returns false, leaving
+// the content in place, when no element scores as an article.
+func readability(doc *html.Node) bool {
+	removeUnlikely(doc)
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	addCandidate := func(n *html.Node, share float64) {
+		if n == nil || n.Type != html.ElementNode || n.Data == "body" || n.Data == "html" {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += share
+	}
+
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+		if n.Type != html.ElementNode || !readabilityScored[n.Data] {
+			return
+		}
+		text := strings.Join(strings.Fields(nodeText(n)), " ")
+		chars := utf8.RuneCountInString(text)
+		if chars < minParagraphChars {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(chars)/100, 3)
+		addCandidate(n.Parent, score)
+		if n.Parent != nil {
+			addCandidate(n.Parent.Parent, score/2)
+		}
+	}
+	visit(doc)
+
+	var top *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+		if top == nil || scores[n] > scores[top] {
+			top = n
+		}
+	}
+	if top == nil {
+		return false
+	}
+
+	// Siblings scoring close to the top candidate, and plain paragraphs
+	// next to it, are part of the same article.
+	threshold := math.Max(10, scores[top]*0.2)
+	var keep []*html.Node
+	for sib := top.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode {
+			continue
+		}
+		if sib == top {
+			keep = append(keep, sib)
+			continue
+		}
+		if score, ok := scores[sib]; ok && score >= threshold {
+			keep = append(keep, sib)
+			continue
+		}
+		if sib.Data == "p" {
+			chars := utf8.RuneCountInString(strings.Join(strings.Fields(nodeText(sib)), " "))
+			if chars > 80 && linkDensity(sib) < 0.25 {
+				keep = append(keep, sib)
+			}
+		}
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body"}
+	for _, n := range keep {
+		n.Parent.RemoveChild(n)
+		body.AppendChild(n)
+	}
+	for c := doc.FirstChild; c != nil; {
+		next := c.NextSibling
+		doc.RemoveChild(c)
+		c = next
+	}
+	doc.AppendChild(body)
+	return true
+}
+
+// removeUnlikely drops elements that are page chrome by tag or by name.
+func removeUnlikely(doc *html.Node) {
+	var drop []*html.Node
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if readabilityDropTags[n.Data] || unlikelyByName(n) {
+				drop = append(drop, n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+	for _, n := range drop {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// unlikelyByName reports whether the class or id of n names page chrome.
+func unlikelyByName(n *html.Node) bool {
+	switch n.Data {
+	case "html", "body", "main", "article":
+		return false
+	}
+	names := classAndID(n)
+	if names == "" {
+		return false
+	}
+	if getAttr(n, "role") == "complementary" || getAttr(n, "role") == "navigation" {
+		return true
+	}
+	return unlikelyCandidate.MatchString(names) && !maybeCandidate.MatchString(names)
+}
+
+// initialScore is a candidate's score before paragraphs vote for it.
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.Data {
+	case "article", "main":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	if names := classAndID(n); names != "" {
+		if positiveName.MatchString(names) {
+			score += 25
+		}
+		if negativeName.MatchString(names) {
+			score -= 25
+		}
+	}
+	return score
+}
+
+// linkDensity is the share of the text of n inside links.
+func linkDensity(n *html.Node) float64 {
+	total, links := textChars(n, false, nil)
+	if total == 0 {
+		return 0
+	}
+	return float64(links) / float64(total)
+}
+
+// classAndID joins the class and id attributes of n.
+func classAndID(n *html.Node) string {
+	return strings.TrimSpace(getAttr(n, "class") + " " + getAttr(n, "id"))
+}
+
+// getAttr returns the value of the attribute key of n, or "".
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestReadability(t *testing.T) {
+	page := `<html><head><title>Post</title></head><body>
+<div id="cookie-banner"><p>We use cookies to improve your experience, accept them all please.</p></div>
+<header><a href="/">Home</a> <a href="/blog">Blog</a></header>
+<div class="sidebar"><ul><li><a href="/a">A related article with a long title</a></li><li><a href="/b">Another related article here</a></li></ul></div>
+<div class="post-content">
+<h1>Understanding widgets</h1>
+<p>Widgets are small components, and they are combined to build larger interfaces in many applications.</p>
+<p>Short.</p>
+<p>Each widget owns its state, renders itself, and reacts to events sent by its parent, which keeps things simple.</p>
+</div>
+<div class="comments"><p>Great post, thanks for writing it, I learned a lot today!</p></div>
+<footer><p>Copyright 2024 Example Inc, all rights reserved worldwide.</p></footer>
+</body></html>`
+
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !readability(doc) {
+		t.Fatal("readability() found no article")
+	}
+	text := nodeText(doc)
+	for _, want := range []string{"Understanding widgets", "Widgets are small components", "Short.", "Each widget owns its state"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("article lost %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"cookies", "Home", "related article", "Great post", "Copyright"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("article kept %q:\n%s", unwanted, text)
+		}
+	}
+}
+
+func TestReadabilityNoArticle(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body><p>Hi</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readability(doc) {
+		t.Error("readability() found an article in a page without paragraphs")
+	}
+	if !strings.Contains(nodeText(doc), "Hi") {
+		t.Error("page content was removed")
+	}
+}
@@ -0,0 +1,214 @@
+package scraper
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed recipes/*.yaml
+var builtinRecipes embed.FS
+
+// Recipe maps a set of domains to the selectors that extract their content
+// cleanly. Recipes are only applied when HTML is stripped.
+type Recipe struct {
+	// Name identifies the recipe; a user recipe replaces the built-in one
+	// with the same name.
+	Name string `yaml:"name"`
+	// Domains the recipe applies to, including their subdomains.
+	Domains []string `yaml:"domains"`
+	// Content selects the elements holding the page content. Everything
+	// else is dropped. Empty keeps the whole page.
+	Content string `yaml:"content"`
+	// Remove lists selectors for elements dropped before extraction.
+	Remove []string `yaml:"remove"`
+
+	content cascadia.Selector
+	remove  []cascadia.Selector
+}
+
+// compile parses the recipe's selectors.
+func (r *Recipe) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("recipe has no name")
+	}
+	if r.Content != "" {
+		sel, err := cascadia.Compile(r.Content)
+		if err != nil {
+			return fmt.Errorf("recipe %s: invalid content selector %q: %w", r.Name, r.Content, err)
+		}
+		r.content = sel
+	}
+	r.remove = nil
+	for _, s := range r.Remove {
+		sel, err := cascadia.Compile(s)
+		if err != nil {
+			return fmt.Errorf("recipe %s: invalid remove selector %q: %w", r.Name, s, err)
+		}
+		r.remove = append(r.remove, sel)
+	}
+	return nil
+}
+
+// matches reports whether the recipe applies to host.
+func (r *Recipe) matches(host string) bool {
+	return domainMatches(r.Domains, host)
+}
+
+// domainMatches reports whether host is one of domains or a subdomain of
+// one. A leading "*." on a domain is ignored.
+func domainMatches(domains []string, host string) bool {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "*."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply reduces doc to the recipe's content. It returns false when the
+// content selector matched nothing, in which case doc is left as is apart
+// from the removals. Each selector that dropped elements is recorded in the
+// audit trail of p, when set.
+func (r *Recipe) apply(doc *html.Node, p *page) bool {
+	for i, sel := range r.remove {
+		before := countElements(doc)
+		for _, n := range sel.MatchAll(doc) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+		if p != nil {
+			p.auditNodes(transformRecipeRemove, recipeRule(r, r.Remove[i]), doc, before)
+		}
+	}
+	if r.content == nil {
+		return true
+	}
+
+	matches := r.content.MatchAll(doc)
+	if len(matches) == 0 {
+		return false
+	}
+	before := countElements(doc)
+	if p != nil {
+		defer p.auditNodes(transformRecipeContent, recipeRule(r, r.Content), doc, before)
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body"}
+	for _, n := range outermost(matches) {
+		n.Parent.RemoveChild(n)
+		body.AppendChild(n)
+	}
+	for c := doc.FirstChild; c != nil; {
+		next := c.NextSibling
+		doc.RemoveChild(c)
+		c = next
+	}
+	doc.AppendChild(body)
+	return true
+}
+
+// outermost drops nodes nested inside other nodes of the list, keeping
+// document order.
+func outermost(nodes []*html.Node) []*html.Node {
+	in := make(map[*html.Node]bool, len(nodes))
+	for _, n := range nodes {
+		in[n] = true
+	}
+	var out []*html.Node
+	for _, n := range nodes {
+		nested := false
+		for p := n.Parent; p != nil; p = p.Parent {
+			if in[p] {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// LoadRecipes returns the built-in recipes overridden by the *.yaml files in
+// userDir, if it exists. A user recipe with the same name as a built-in one
+// replaces it.
+func LoadRecipes(userDir string) ([]*Recipe, error) {
+	byName := make(map[string]*Recipe)
+
+	if err := loadRecipes(builtinRecipes, "recipes", byName); err != nil {
+		return nil, err
+	}
+	if userDir != "" {
+		if _, err := os.Stat(userDir); err == nil {
+			if err := loadRecipes(os.DirFS(userDir), ".", byName); err != nil {
+				return nil, err
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read recipes directory: %w", err)
+		}
+	}
+
+	recipes := make([]*Recipe, 0, len(byName))
+	for _, r := range byName {
+		recipes = append(recipes, r)
+	}
+	sort.Slice(recipes, func(i, j int) bool { return recipes[i].Name < recipes[j].Name })
+	return recipes, nil
+}
+
+func loadRecipes(fsys fs.FS, dir string, byName map[string]*Recipe) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read recipe %s: %w", file, err)
+		}
+		var r Recipe
+		if err := yaml.Unmarshal(data, &r); err != nil {
+			return fmt.Errorf("failed to parse recipe %s: %w", file, err)
+		}
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		byName[r.Name] = &r
+	}
+	return nil
+}
+
+// DefaultRecipesDir returns the directory user recipes are read from.
+func DefaultRecipesDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scrapdf", "recipes")
+}
+
+// recipeFor returns the first recipe matching host, or nil.
+func (s *Scraper) recipeFor(host string) *Recipe {
+	for _, r := range s.Recipes {
+		if r.matches(host) {
+			return r
+		}
+	}
+	return nil
+}
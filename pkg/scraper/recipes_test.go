@@ -0,0 +1,85 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestLoadRecipesBuiltin(t *testing.T) {
+	recipes, err := LoadRecipes("")
+	if err != nil {
+		t.Fatalf("LoadRecipes() error = %v", err)
+	}
+
+	s := NewScraper(true, false)
+	s.Recipes = recipes
+	for host, want := range map[string]string{
+		"developer.mozilla.org":   "mdn",
+		"en.wikipedia.org":        "wikipedia",
+		"requests.readthedocs.io": "readthedocs",
+	} {
+		r := s.recipeFor(host)
+		if r == nil || r.Name != want {
+			t.Errorf("recipeFor(%q) = %v, want %s", host, r, want)
+		}
+	}
+	if r := s.recipeFor("example.com"); r != nil {
+		t.Errorf("recipeFor(example.com) = %s, want nil", r.Name)
+	}
+}
+
+func TestLoadRecipesUserOverride(t *testing.T) {
+	dir := t.TempDir()
+	recipe := "name: wikipedia\ndomains: [wiki.example.com]\ncontent: main\n"
+	if err := os.WriteFile(filepath.Join(dir, "wiki.yaml"), []byte(recipe), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recipes, err := LoadRecipes(dir)
+	if err != nil {
+		t.Fatalf("LoadRecipes() error = %v", err)
+	}
+	s := NewScraper(true, false)
+	s.Recipes = recipes
+	if r := s.recipeFor("en.wikipedia.org"); r != nil {
+		t.Errorf("built-in wikipedia recipe was not replaced")
+	}
+	if r := s.recipeFor("wiki.example.com"); r == nil || r.Name != "wikipedia" {
+		t.Errorf("user recipe not found")
+	}
+}
+
+func TestLoadRecipesInvalidSelector(t *testing.T) {
+	dir := t.TempDir()
+	recipe := "name: broken\ndomains: [example.com]\nremove: ['div[']\n"
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte(recipe), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRecipes(dir); err == nil {
+		t.Errorf("LoadRecipes() accepted an invalid selector")
+	}
+}
+
+func TestRecipeApply(t *testing.T) {
+	r := &Recipe{Name: "test", Content: "main", Remove: []string{".ad", "a.headerlink"}}
+	if err := r.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := html.Parse(strings.NewReader(`<nav><a href="/">Home</a></nav>
+		<main><h1>Title<a class="headerlink" href="#t">¶</a></h1><div class="ad">Buy now</div><p>Body text</p></main>
+		<footer>Footer</footer>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.apply(doc, nil) {
+		t.Fatalf("apply() found no content")
+	}
+	if got, want := nodeText(doc), "Title\n\nBody text\n\n"; got != want {
+		t.Errorf("nodeText() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// DefaultImageQuality is the JPEG quality downscaled images are encoded at
+// when ImageQuality isn't set.
+const DefaultImageQuality = 85
+
+// checkImageOptions rejects image settings out of range, or set when no
+// images are embedded.
+func (s *Scraper) checkImageOptions() error {
+	if s.ImageQuality < 0 || s.ImageQuality > 100 {
+		return fmt.Errorf("image quality %d is out of range, expected 1 to 100", s.ImageQuality)
+	}
+	if s.MaxImageWidth < 0 {
+		return fmt.Errorf("invalid maximum image width %d", s.MaxImageWidth)
+	}
+	if (s.ImageQuality > 0 || s.MaxImageWidth > 0) && (!s.embedsImages() || !s.rendersAnyText()) {
+		return fmt.Errorf("image quality and width only apply to the images the %s renderer embeds in PDFs", RendererText)
+	}
+	return nil
+}
+
+// recompress returns the image body, decoded as format, downscaled to the
+// fetcher's maxImageWidth and encoded again at its imageQuality, with its
+// gofpdf type. Images needing neither are returned as they are: JPEGs
+// unchanged, others as 8-bit PNGs, which PDFs take without conversion.
+func (f *assetFetcher) recompress(body []byte, format string, width int) ([]byte, string, error) {
+	shrink := f.maxImageWidth > 0 && width > f.maxImageWidth
+	if format == "jpeg" && !shrink && f.imageQuality == 0 {
+		return body, "JPG", nil
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	var img image.Image = decoded
+	if shrink {
+		img = downscale(decoded, f.maxImageWidth)
+	}
+
+	quality := f.imageQuality
+	if quality == 0 {
+		quality = DefaultImageQuality
+	}
+	if format == "jpeg" {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+		// Recompressing an already smaller JPEG only loses detail
+		if !shrink && buf.Len() >= len(body) {
+			return body, "JPG", nil
+		}
+		return buf.Bytes(), "JPG", nil
+	}
+
+	// Interlaced and 16-bit PNGs and GIF frames can't go in as they are
+	rgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	enc := png.Encoder{}
+	if f.imageQuality > 0 {
+		enc.CompressionLevel = png.BestCompression
+	}
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, rgba); err != nil {
+		return nil, "", err
+	}
+	// Photos saved as PNG shrink a lot as JPEGs, diagrams and screenshots
+	// don't and keep their sharp edges
+	if f.imageQuality > 0 && rgba.Opaque() {
+		var photo bytes.Buffer
+		if err := jpeg.Encode(&photo, rgba, &jpeg.Options{Quality: quality}); err == nil && photo.Len() < buf.Len()/2 {
+			return photo.Bytes(), "JPG", nil
+		}
+	}
+	return buf.Bytes(), "PNG", nil
+}
+
+// downscale returns img resized to width pixels, keeping its aspect ratio,
+// each pixel averaging the source pixels it covers.
+func downscale(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	out := image.NewNRGBA64(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/width)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			out.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+	return out
+}
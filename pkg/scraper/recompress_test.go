@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+)
+
+// noisyImage returns a w×h gradient with grain, like a photo.
+func noisyImage(w, h int) *image.NRGBA {
+	rnd := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			grain := uint8(rnd.Intn(24))
+			img.Set(x, y, color.NRGBA{uint8(x*200/w) + grain, uint8(y*200/h) + grain, 128 + grain, 255})
+		}
+	}
+	return img
+}
+
+func TestDownscale(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 100; y++ {
+			if x < 200 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+	out := downscale(src, 100)
+	if b := out.Bounds(); b.Dx() != 100 || b.Dy() != 25 {
+		t.Fatalf("downscaled to %dx%d, want 100x25", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := out.At(10, 10).RGBA(); r != 0xffff {
+		t.Errorf("left half red = %x, want white", r)
+	}
+	if r, _, _, _ := out.At(90, 10).RGBA(); r != 0 {
+		t.Errorf("right half red = %x, want black", r)
+	}
+}
+
+func TestRecompress(t *testing.T) {
+	var photo bytes.Buffer
+	if err := jpeg.Encode(&photo, noisyImage(800, 400), &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newAssetFetcher(nil, "", nil)
+	body, typ, err := f.recompress(photo.Bytes(), "jpeg", 800)
+	if err != nil || typ != "JPG" || !bytes.Equal(body, photo.Bytes()) {
+		t.Errorf("without settings the JPEG changed: type %s, err %v", typ, err)
+	}
+
+	f.maxImageWidth, f.imageQuality = 200, 50
+	body, typ, err = f.recompress(photo.Bytes(), "jpeg", 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != "JPG" || config.Width != 200 || config.Height != 100 {
+		t.Errorf("recompressed to a %dx%d %s, want a 200x100 JPG", config.Width, config.Height, typ)
+	}
+	if len(body) >= photo.Len() {
+		t.Errorf("recompressed JPEG is %d bytes, the original %d", len(body), photo.Len())
+	}
+
+	// A photo saved as PNG becomes a JPEG, a flat diagram stays a PNG
+	f.maxImageWidth = 0
+	var pngPhoto, diagram bytes.Buffer
+	png.Encode(&pngPhoto, noisyImage(300, 300))
+	png.Encode(&diagram, image.NewNRGBA(image.Rect(0, 0, 300, 300)))
+	if _, typ, _ := f.recompress(pngPhoto.Bytes(), "png", 300); typ != "JPG" {
+		t.Errorf("photo PNG recompressed as %s, want JPG", typ)
+	}
+	if _, typ, _ := f.recompress(diagram.Bytes(), "png", 300); typ != "PNG" {
+		t.Errorf("diagram PNG recompressed as %s, want PNG", typ)
+	}
+}
+
+func TestCheckImageOptions(t *testing.T) {
+	tests := []struct {
+		quality, width int
+		noImages       bool
+		renderer       string
+		ok             bool
+	}{
+		{0, 0, false, "", true},
+		{75, 1200, false, "", true},
+		{101, 0, false, "", false},
+		{-1, 0, false, "", false},
+		{0, -5, false, "", false},
+		{75, 0, true, "", false},
+		{0, 1200, false, RendererChrome, false},
+	}
+	for _, tt := range tests {
+		s := NewScraper(true, false)
+		s.ImageQuality, s.MaxImageWidth, s.NoImages, s.Renderer = tt.quality, tt.width, tt.noImages, tt.renderer
+		if err := s.checkImageOptions(); (err == nil) != tt.ok {
+			t.Errorf("checkImageOptions() with %+v = %v", tt, err)
+		}
+	}
+}
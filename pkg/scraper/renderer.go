@@ -0,0 +1,287 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Renderers accepted by Scraper.Renderer.
+const (
+	RendererText   = "text"
+	RendererChrome = "chrome"
+	// RendererBoth makes both PDFs of every page, the Chrome print named
+	// after the text one with a ".chrome.pdf" suffix, to compare them.
+	RendererBoth = "both"
+)
+
+// browserNames are the executables looked up on PATH for the Chrome
+// renderer, in order of preference.
+var browserNames = []string{
+	"headless_shell",
+	"headless-shell",
+	"chromium",
+	"chromium-browser",
+	"google-chrome",
+	"google-chrome-stable",
+	"chrome",
+}
+
+// browserPaths are well-known install locations that are usually not on
+// PATH.
+var browserPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+	},
+	"windows": {
+		`C:\Program Files\Google\Chrome\Application\chrome.exe`,
+		`C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`,
+	},
+}
+
+// browserInstructions is printed when the Chrome renderer can't start.
+const browserInstructions = `The chrome renderer needs Google Chrome or Chromium. Run
+"scrapdf browser install" to download one, install it system wide, or pass
+--browser-path with the location of a Chrome executable.
+`
+
+// findBrowser returns the Chrome executable to use: explicit when set,
+// otherwise the browser installed in managedDir, or the first browser found
+// on PATH or in a well-known location.
+func findBrowser(explicit, managedDir string) (string, error) {
+	if explicit != "" {
+		info, err := os.Stat(explicit)
+		if err != nil {
+			return "", fmt.Errorf("browser %s: %w", explicit, err)
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("browser %s is a directory", explicit)
+		}
+		return explicit, nil
+	}
+	if exe := InstalledBrowser(managedDir); exe != "" {
+		return exe, nil
+	}
+	for _, name := range browserNames {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	for _, p := range browserPaths[runtime.GOOS] {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome or Chromium executable found")
+}
+
+// chromeRenderer prints pages to PDF with a headless browser shared by the
+// whole crawl. Each page gets its own tab.
+type chromeRenderer struct {
+	browser context.Context
+	cancel  func()
+}
+
+// newChromeRenderer launches the browser at execPath, closed when ctx is
+// done. Starting it up front turns a broken install into one error instead
+// of a failure per page.
+func newChromeRenderer(ctx context.Context, execPath string) (*chromeRenderer, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.ExecPath(execPath))
+	if os.Geteuid() == 0 {
+		// Chrome refuses to start its sandbox as root, e.g. in containers
+		opts = append(opts, chromedp.NoSandbox)
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, opts...)
+	browserCtx, cancelBrowser := chromedp.NewContext(allocCtx)
+	r := &chromeRenderer{
+		browser: browserCtx,
+		cancel: func() {
+			cancelBrowser()
+			cancelAlloc()
+		},
+	}
+	if err := chromedp.Run(browserCtx); err != nil {
+		r.cancel()
+		return nil, fmt.Errorf("failed to start %s: %w", execPath, err)
+	}
+	return r, nil
+}
+
+// printPDF loads pageURL in a new tab authenticated with req, runs prepare
+// on it and writes its print output to filename with settings ps, with the header and footer
+// templates when either is set. A zero timeout waits forever.
+func (r *chromeRenderer) printPDF(pageURL string, prepare chromedp.Tasks, req browserRequest, filename, header, footer string, ps printSettings, timeout time.Duration) error {
+	ctx, cancel := chromedp.NewContext(r.browser)
+	defer cancel()
+	if timeout > 0 {
+		var cancelTimeout func()
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		defer cancelTimeout()
+	}
+
+	var data []byte
+	actions := req.prepareTab(ctx)
+	if ps.noScripts {
+		actions = append(actions, emulation.SetScriptExecutionDisabled(true))
+	}
+	actions = append(actions,
+		chromedp.Navigate(pageURL),
+		prepare,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			params := cdppage.PrintToPDF().WithPrintBackground(true).WithLandscape(ps.landscape).
+				WithPaperWidth(ps.size.Wd / mmPerInch).WithPaperHeight(ps.size.Ht / mmPerInch)
+			if m := ps.margins; m != nil {
+				params = params.WithMarginTop(m.Top / mmPerInch).WithMarginRight(m.Right / mmPerInch).
+					WithMarginBottom(m.Bottom / mmPerInch).WithMarginLeft(m.Left / mmPerInch)
+			}
+			if header != "" || footer != "" {
+				params = params.WithDisplayHeaderFooter(true).WithHeaderTemplate(header).WithFooterTemplate(footer)
+			}
+			data, _, err = params.Do(ctx)
+			return err
+		}),
+	)
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return fmt.Errorf("chrome failed to print %s: %w", pageURL, err)
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// close shuts the browser down.
+func (r *chromeRenderer) close() {
+	r.cancel()
+}
+
+// startRenderer validates the Renderer setting and launches the browser
+// when the chrome renderer or RenderJS is requested, by the run or a
+// RenderOverride. An unavailable browser is not an error: the run falls
+// back to the text renderer and static fetches, and the manifest records
+// why.
+func (s *Scraper) startRenderer() error {
+	switch s.Renderer {
+	case "", RendererText, RendererChrome, RendererBoth:
+	default:
+		return fmt.Errorf("unknown renderer %q (available: %s, %s, %s)", s.Renderer, RendererText, RendererChrome, RendererBoth)
+	}
+	s.fallback = &fallbackBrowser{}
+	if !s.needsBrowser() {
+		return nil
+	}
+
+	execPath, err := findBrowser(s.BrowserPath, s.BrowserDir)
+	if err == nil {
+		s.chrome, err = newChromeRenderer(s.crawlContext(), execPath)
+	}
+	if err != nil {
+		s.logf("Warning: the headless browser is unavailable: %v\n", err)
+		s.logf("%s", browserInstructions)
+		if s.printsAnyLivePage() {
+			s.logf("Falling back to the text renderer for this run.\n")
+		}
+		if s.fetchesAnyJS() {
+			s.logf("Pages are fetched without running their JavaScript for this run.\n")
+		}
+		s.rendererFallback = err.Error()
+		s.Renderer = RendererText
+		s.RenderJS = false
+		return nil
+	}
+	s.logf("Using browser %s\n", execPath)
+	return nil
+}
+
+// stopRenderer shuts down the browsers, if any were started.
+func (s *Scraper) stopRenderer() {
+	if s.chrome != nil {
+		s.chrome.close()
+		s.chrome = nil
+	}
+	if s.fallback != nil && s.fallback.chrome != nil {
+		s.fallback.chrome.close()
+	}
+}
+
+// printsLivePage reports whether the requested renderer prints pages with
+// the headless browser.
+func (s *Scraper) printsLivePage() bool {
+	return s.Renderer == RendererChrome || s.Renderer == RendererBoth
+}
+
+// renderer returns the name of the renderer in use.
+func (s *Scraper) renderer() string {
+	if s.chrome != nil && s.printsLivePage() {
+		return s.Renderer
+	}
+	return RendererText
+}
+
+// render writes the page's PDF with the renderer in use, or its file in
+// the other formats.
+func (s *Scraper) render(p *page) error {
+	switch s.Format {
+	case FormatMarkdown:
+		return s.renderMarkdown(p)
+	case FormatEPUB:
+		return s.renderChapter(p)
+	}
+	settings := s.renderSettings(p.URL)
+	switch settings.Renderer {
+	case RendererChrome:
+		header, footer := s.chromeTemplates(p)
+		if err := s.chrome.printPDF(p.URL.String(), s.beforeCapture(p.URL.Host), s.browserRequest(p.URL), p.File, header, footer, s.printSettings(p.URL), s.PageTimeout); err != nil {
+			return err
+		}
+		return s.thumbnail(p)
+	case RendererBoth:
+		return s.renderBoth(p, s.printSettings(p.URL))
+	}
+	return s.renderText(p)
+}
+
+// renderBoth prints the page with the browser with settings ps while the
+// text renderer lays it out. The text PDF is the page's PDF; a failed
+// print only loses the comparison.
+func (s *Scraper) renderBoth(p *page, ps printSettings) error {
+	chromeFile := strings.TrimSuffix(p.File, ".pdf") + chromeSuffix
+	printed := make(chan error, 1)
+	header, footer := s.chromeTemplates(p)
+	go func() {
+		printed <- s.chrome.printPDF(p.URL.String(), s.beforeCapture(p.URL.Host), s.browserRequest(p.URL), chromeFile, header, footer, ps, s.PageTimeout)
+	}()
+	err := s.renderText(p)
+	if printErr := <-printed; printErr != nil {
+		s.logf("Warning: no Chrome print of %s: %v\n", p.URL, printErr)
+		s.event(event{Type: eventError, URL: p.URL.String(), Stage: "render_chrome", Error: printErr.Error()})
+	} else {
+		p.ChromeFile = chromeFile
+	}
+	return err
+}
+
+// chromeSuffix replaces ".pdf" in the name of the Chrome print made by
+// RendererBoth.
+const chromeSuffix = ".chrome.pdf"
+
+// chromeEntryName returns the archive name of the Chrome print of u.
+func (s *Scraper) chromeEntryName(u *url.URL) string {
+	return strings.TrimSuffix(s.baseEntryName(u), ".pdf") + chromeSuffix
+}
+
+// renderText writes the page's PDF with the text renderer.
+func (s *Scraper) renderText(p *page) error {
+	if err := s.createPDF(p.File, p); err != nil {
+		return err
+	}
+	return s.thumbnail(p)
+}
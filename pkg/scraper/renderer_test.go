@@ -0,0 +1,160 @@
+package scraper
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestFindBrowserExplicit(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "chrome")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"existing executable", exe, false},
+		{"missing file", filepath.Join(dir, "missing"), true},
+		{"directory", dir, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findBrowser(tt.path, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findBrowser(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.path {
+				t.Errorf("findBrowser(%q) = %q", tt.path, got)
+			}
+		})
+	}
+}
+
+func TestStartRendererFallsBack(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Renderer = RendererChrome
+	s.BrowserPath = filepath.Join(t.TempDir(), "missing-chrome")
+
+	if err := s.startRenderer(); err != nil {
+		t.Fatalf("startRenderer() error = %v", err)
+	}
+	defer s.stopRenderer()
+
+	if s.renderer() != RendererText {
+		t.Errorf("renderer() = %q, want %q", s.renderer(), RendererText)
+	}
+	if s.rendererFallback == "" {
+		t.Error("fallback reason not recorded")
+	}
+}
+
+func TestStartRendererBothFallsBack(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Renderer = RendererBoth
+	s.BrowserPath = filepath.Join(t.TempDir(), "missing-chrome")
+
+	if err := s.startRenderer(); err != nil {
+		t.Fatalf("startRenderer() error = %v", err)
+	}
+	defer s.stopRenderer()
+
+	if s.renderer() != RendererText {
+		t.Errorf("renderer() = %q, want %q", s.renderer(), RendererText)
+	}
+	if s.rendererFallback == "" {
+		t.Error("fallback reason not recorded")
+	}
+}
+
+func TestChromeEntryName(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/intro")
+	if got, want := NewScraper(false, false).chromeEntryName(u), "example.com_docs_intro.chrome.pdf"; got != want {
+		t.Errorf("chromeEntryName() = %q, want %q", got, want)
+	}
+}
+
+func TestStartRendererRenderJSFallsBack(t *testing.T) {
+	s := NewScraper(false, false)
+	s.RenderJS = true
+	s.BrowserPath = filepath.Join(t.TempDir(), "missing-chrome")
+
+	if err := s.startRenderer(); err != nil {
+		t.Fatalf("startRenderer() error = %v", err)
+	}
+	defer s.stopRenderer()
+
+	if s.RenderJS {
+		t.Error("RenderJS still set without a browser")
+	}
+	if s.rendererFallback == "" {
+		t.Error("fallback reason not recorded")
+	}
+}
+
+func TestCookieParams(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/")
+	params := cookieParams(u, []*http.Cookie{{Name: "sid", Value: "abc"}})
+	if len(params) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(params))
+	}
+	p := params[0]
+	if p.Name != "sid" || p.Value != "abc" || p.URL != u.String() || !p.Secure {
+		t.Errorf("cookieParams() = %+v", p)
+	}
+}
+
+func TestBrowserRequest(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/")
+	s := NewScraper(false, false)
+	s.Headers = http.Header{"Authorization": {"Bearer token"}}
+	s.UserAgent = "archiver/1.0"
+	s.jar = func(string) []*http.Cookie { return []*http.Cookie{{Name: "sid", Value: "abc"}} }
+	req := s.browserRequest(u)
+	if len(req.cookies) != 1 || req.userAgent != "archiver/1.0" || req.headers["Authorization"] != "Bearer token" {
+		t.Errorf("browserRequest() = %+v", req)
+	}
+
+	for _, tt := range []struct {
+		target string
+		want   bool
+	}{
+		{"https://example.com/app.js", true},
+		{"https://EXAMPLE.com/img.png", true},
+		{"http://example.com/app.js", false},
+		{"https://cdn.example.com/app.js", false},
+		{"https://ads.example.net/pixel", false},
+	} {
+		target, _ := url.Parse(tt.target)
+		if got := sameOrigin(target, u); got != tt.want {
+			t.Errorf("sameOrigin(%s) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+
+	entries := withHeaders(network.Headers{"authorization": "old", "Accept": "text/html"}, req.headers)
+	got := map[string]string{}
+	for _, e := range entries {
+		got[e.Name] = e.Value
+	}
+	if want := map[string]string{"Authorization": "Bearer token", "Accept": "text/html"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("withHeaders() = %v, want %v", got, want)
+	}
+}
+
+func TestStartRendererUnknown(t *testing.T) {
+	s := NewScraper(false, false)
+	s.Renderer = "pdfium"
+	if err := s.startRenderer(); err == nil {
+		t.Error("startRenderer() accepted an unknown renderer")
+	}
+}
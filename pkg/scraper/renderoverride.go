@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Page orientations accepted by RenderOverride.Orientation.
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// RenderOverride changes the render settings of the pages whose URL matches
+// Match, e.g. landscape pages for /diagrams/* or the chrome renderer only
+// for a single-page app under /app/*. Empty settings keep the run's.
+type RenderOverride struct {
+	// Match is a URL pattern, see ParseURLPattern.
+	Match string `yaml:"match"`
+	// Renderer is RendererText, RendererChrome or RendererBoth.
+	Renderer string `yaml:"renderer"`
+	// Orientation is OrientationPortrait or OrientationLandscape.
+	Orientation string `yaml:"orientation"`
+	// RenderJS fetches the pages with the headless browser, or without it
+	// when false.
+	RenderJS *bool `yaml:"render_js"`
+	// JavaScript, when false, has the browser print the pages without
+	// running their scripts, leaving out the ads and trackers they load,
+	// and fetch them statically; true runs them despite NoJavaScript.
+	JavaScript *bool `yaml:"javascript"`
+
+	pattern URLPattern
+}
+
+// compile parses the override's pattern and checks its settings.
+func (o *RenderOverride) compile() error {
+	if o.Match == "" {
+		return fmt.Errorf("render override without a match pattern")
+	}
+	var err error
+	if o.pattern, err = ParseURLPattern(o.Match); err != nil {
+		return err
+	}
+	switch o.Renderer {
+	case "", RendererText, RendererChrome, RendererBoth:
+	default:
+		return fmt.Errorf("render override for %s: unknown renderer %q (available: %s, %s, %s)", o.Match, o.Renderer, RendererText, RendererChrome, RendererBoth)
+	}
+	switch o.Orientation {
+	case "", OrientationPortrait, OrientationLandscape:
+	default:
+		return fmt.Errorf("render override for %s: unknown orientation %q (available: %s, %s)", o.Match, o.Orientation, OrientationPortrait, OrientationLandscape)
+	}
+	if o.RenderJS != nil && *o.RenderJS && o.JavaScript != nil && !*o.JavaScript {
+		return fmt.Errorf("render override for %s: render_js runs the pages' JavaScript, it can't be combined with javascript: false", o.Match)
+	}
+	return nil
+}
+
+// printsLivePage reports whether the override prints pages with the
+// headless browser.
+func (o *RenderOverride) printsLivePage() bool {
+	return o.Renderer == RendererChrome || o.Renderer == RendererBoth
+}
+
+// renderSettings are the settings a page is rendered with.
+type renderSettings struct {
+	Renderer    string
+	Orientation string
+	RenderJS    bool
+	JavaScript  bool // the browser runs the page's scripts
+}
+
+// landscape reports whether the page is printed in landscape.
+func (rs renderSettings) landscape() bool {
+	return rs.Orientation == OrientationLandscape
+}
+
+// pdfOrientation returns the gofpdf orientation of the page.
+func (rs renderSettings) pdfOrientation() string {
+	if rs.landscape() {
+		return "L"
+	}
+	return "P"
+}
+
+// renderSettings returns the settings of the page at u: the run's, changed
+// by every RenderOverride matching it in order, so a later override wins
+// for the settings both set. Without a usable browser pages get the text
+// renderer and static fetches whatever the overrides say.
+func (s *Scraper) renderSettings(u *url.URL) renderSettings {
+	rs := renderSettings{Renderer: s.Renderer, Orientation: OrientationPortrait, RenderJS: s.RenderJS, JavaScript: !s.NoJavaScript}
+	if s.Orientation != "" {
+		rs.Orientation = s.Orientation
+	}
+	for _, o := range s.RenderOverrides {
+		if !o.pattern.match(u) {
+			continue
+		}
+		if o.Renderer != "" {
+			rs.Renderer = o.Renderer
+		}
+		if o.Orientation != "" {
+			rs.Orientation = o.Orientation
+		}
+		if o.RenderJS != nil {
+			rs.RenderJS = *o.RenderJS
+			// Fetching with the browser is for the pages' scripts
+			rs.JavaScript = rs.JavaScript || rs.RenderJS
+		}
+		if o.JavaScript != nil {
+			rs.JavaScript = *o.JavaScript
+			rs.RenderJS = rs.RenderJS && rs.JavaScript
+		}
+	}
+	if s.chrome == nil {
+		rs.Renderer = RendererText
+		rs.RenderJS = false
+	}
+	if rs.Renderer == "" {
+		rs.Renderer = RendererText
+	}
+	return rs
+}
+
+// printsAnyLivePage reports whether the run's renderer or an override
+// prints pages with the headless browser.
+func (s *Scraper) printsAnyLivePage() bool {
+	if s.printsLivePage() {
+		return true
+	}
+	for _, o := range s.RenderOverrides {
+		if o.printsLivePage() {
+			return true
+		}
+	}
+	return false
+}
+
+// rendersAnyText reports whether the run's renderer or an override lays
+// pages out with the text renderer alone.
+func (s *Scraper) rendersAnyText() bool {
+	if !s.printsLivePage() {
+		return true
+	}
+	for _, o := range s.RenderOverrides {
+		if o.Renderer == RendererText {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchesAnyJS reports whether the run or an override fetches pages with
+// the headless browser.
+func (s *Scraper) fetchesAnyJS() bool {
+	if s.RenderJS {
+		return true
+	}
+	for _, o := range s.RenderOverrides {
+		if o.RenderJS != nil && *o.RenderJS {
+			return true
+		}
+	}
+	return false
+}
+
+// needsBrowser reports whether any page may be printed or fetched with the
+// headless browser.
+func (s *Scraper) needsBrowser() bool {
+	// A dry run prints nothing
+	return (s.printsAnyLivePage() && s.plan == nil) || s.fetchesAnyJS()
+}
+
+// checkRenderOverrides compiles the overrides and rejects those that would
+// give pages settings the run can't combine with its other options.
+func (s *Scraper) checkRenderOverrides() error {
+	if s.NoJavaScript && s.RenderJS {
+		return fmt.Errorf("rendering with JavaScript runs the pages' scripts, it can't be combined with turning JavaScript off")
+	}
+	for _, o := range s.RenderOverrides {
+		if err := o.compile(); err != nil {
+			return err
+		}
+		switch {
+		case o.Orientation != "" && s.Format != "" && s.Format != FormatPDF:
+			return fmt.Errorf("render override for %s: the orientation applies to PDFs, it can't be combined with the %s format", o.Match, s.Format)
+		case o.RenderJS != nil && *o.RenderJS && s.offline():
+			return fmt.Errorf("render override for %s: pages can't be rendered with JavaScript offline, the browser would fetch them again", o.Match)
+		case !o.printsLivePage():
+			continue
+		case s.SinglePDF:
+			return fmt.Errorf("render override for %s: a single PDF can only be built by the %s renderer", o.Match, RendererText)
+		case s.Format != "" && s.Format != FormatPDF:
+			return fmt.Errorf("render override for %s: the %s format is converted from the page's HTML, it can't be combined with the %s renderer", o.Match, s.Format, o.Renderer)
+		case len(s.RedactContent) > 0:
+			return fmt.Errorf("render override for %s: redaction rules only apply to the %s renderer, the %s renderer prints the live page", o.Match, RendererText, o.Renderer)
+		case s.PipeHTML != "" || s.PipeText != "":
+			return fmt.Errorf("render override for %s: page filters only apply to the %s renderer, the %s renderer prints the live page", o.Match, RendererText, o.Renderer)
+		case s.offline():
+			return fmt.Errorf("render override for %s: the %s renderer prints the live page, render offline with the %s renderer", o.Match, o.Renderer, RendererText)
+		}
+	}
+	return nil
+}
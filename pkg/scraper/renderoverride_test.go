@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestRenderSettings(t *testing.T) {
+	js, noJS := true, false
+	s := NewScraper(false, false)
+	s.Renderer = RendererText
+	s.RenderOverrides = []*RenderOverride{
+		{Match: "/diagrams/*", Orientation: OrientationLandscape},
+		{Match: "/app/*", Renderer: RendererChrome, RenderJS: &js},
+		{Match: "/app/print/*", Renderer: RendererBoth},
+		{Match: "/news/*", Renderer: RendererChrome, JavaScript: &noJS},
+		{Match: "/app/ads/*", JavaScript: &noJS},
+	}
+	if err := s.checkRenderOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	s.chrome = &chromeRenderer{}
+
+	tests := []struct {
+		path string
+		want renderSettings
+	}{
+		{"/docs/intro", renderSettings{RendererText, OrientationPortrait, false, true}},
+		{"/diagrams/flow", renderSettings{RendererText, OrientationLandscape, false, true}},
+		{"/app/home", renderSettings{RendererChrome, OrientationPortrait, true, true}},
+		{"/app/print/report", renderSettings{RendererBoth, OrientationPortrait, true, true}},
+		{"/news/today", renderSettings{RendererChrome, OrientationPortrait, false, false}},
+		// A later override turning JavaScript off fetches the pages statically
+		{"/app/ads/banner", renderSettings{RendererChrome, OrientationPortrait, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := s.renderSettings(&url.URL{Scheme: "https", Host: "example.com", Path: tt.path}); got != tt.want {
+				t.Errorf("renderSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Without a browser every page gets the text renderer
+	s.chrome = nil
+	got := s.renderSettings(&url.URL{Scheme: "https", Host: "example.com", Path: "/app/home"})
+	if got.Renderer != RendererText || got.RenderJS {
+		t.Errorf("renderSettings() without a browser = %+v", got)
+	}
+	if !s.needsBrowser() || !s.printsAnyLivePage() || !s.rendersAnyText() {
+		t.Error("the overrides' browser pages and the run's text pages were not both accounted for")
+	}
+}
+
+func TestCheckRenderOverrides(t *testing.T) {
+	js, noJS := true, false
+	tests := []struct {
+		name     string
+		override RenderOverride
+		setup    func(*Scraper)
+		wantErr  bool
+	}{
+		{"landscape", RenderOverride{Match: "/diagrams/*", Orientation: OrientationLandscape}, nil, false},
+		{"no pattern", RenderOverride{Renderer: RendererChrome}, nil, true},
+		{"bad pattern", RenderOverride{Match: "re:(", Renderer: RendererChrome}, nil, true},
+		{"unknown renderer", RenderOverride{Match: "/app/*", Renderer: "word"}, nil, true},
+		{"unknown orientation", RenderOverride{Match: "/app/*", Orientation: "sideways"}, nil, true},
+		{"chrome in a single PDF", RenderOverride{Match: "/app/*", Renderer: RendererChrome}, func(s *Scraper) { s.SinglePDF = true }, true},
+		{"text in a single PDF", RenderOverride{Match: "/app/*", Renderer: RendererText, Orientation: OrientationLandscape}, func(s *Scraper) { s.SinglePDF = true }, false},
+		{"chrome for Markdown", RenderOverride{Match: "/app/*", Renderer: RendererChrome}, func(s *Scraper) { s.Format = FormatMarkdown }, true},
+		{"orientation for Markdown", RenderOverride{Match: "/app/*", Orientation: OrientationLandscape}, func(s *Scraper) { s.Format = FormatMarkdown }, true},
+		{"chrome with redaction", RenderOverride{Match: "/app/*", Renderer: RendererBoth}, func(s *Scraper) { s.RedactContent = []*regexp.Regexp{regexp.MustCompile("secret")} }, true},
+		{"JavaScript offline", RenderOverride{Match: "/app/*", RenderJS: &js}, func(s *Scraper) { s.Offline = true; s.CacheDir = t.TempDir() }, true},
+		{"JavaScript on and off", RenderOverride{Match: "/app/*", RenderJS: &js, JavaScript: &noJS}, nil, true},
+		{"rendering JavaScript without it", RenderOverride{Match: "/news/*", Renderer: RendererChrome}, func(s *Scraper) { s.RenderJS = true; s.NoJavaScript = true }, true},
+		{"JavaScript for some pages", RenderOverride{Match: "/app/*", RenderJS: &js}, func(s *Scraper) { s.NoJavaScript = true }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper(false, false)
+			if tt.setup != nil {
+				tt.setup(s)
+			}
+			override := tt.override
+			s.RenderOverrides = []*RenderOverride{&override}
+			if err := s.checkRenderOverrides(); (err != nil) != tt.wantErr {
+				t.Errorf("checkRenderOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLandscapeOverride(t *testing.T) {
+	s := NewScraper(true, false)
+	s.RenderOverrides = []*RenderOverride{{Match: "/diagrams/*", Orientation: OrientationLandscape}}
+	if err := s.checkRenderOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		path      string
+		landscape bool
+	}{
+		{"/docs/intro", false},
+		{"/diagrams/flow", true},
+	} {
+		file := filepath.Join(dir, "page.pdf")
+		p := &page{URL: &url.URL{Scheme: "https", Host: "example.com", Path: tt.path}, Text: "A diagram."}
+		if err := s.createPDF(file, p); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// gofpdf gives pages another orientation than the document's their
+		// own media box
+		if got := bytes.Contains(data, []byte("/MediaBox [0 0 841.89 595.28]")); got != tt.landscape {
+			t.Errorf("%s: landscape media box = %v, want %v", tt.path, got, tt.landscape)
+		}
+	}
+}
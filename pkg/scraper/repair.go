@@ -0,0 +1,279 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveFile returns the path in the work directory dir the ZIP is written
+// to before it is moved to the output.
+func archiveFile(dir string) string {
+	return filepath.Join(dir, "archive.zip")
+}
+
+// journalFile returns the path of the recovery journal of the ZIP in dir.
+func journalFile(dir string) string {
+	return filepath.Join(dir, "archive.journal")
+}
+
+// journalEntry is a line of the recovery journal: an entry whose data was
+// completely written to the archive.
+type journalEntry struct {
+	Name string `json:"name"`
+	// Offset is where the compressed data starts, after the local header.
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	CRC32  uint32 `json:"crc32"`
+}
+
+// countWriter counts the bytes written through it.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// journaledZip is a ZIP writer that appends each finished entry to a
+// recovery journal. A ZIP is unreadable until its central directory is
+// written by Close, so the journal is what RepairArchive salvages the
+// entries of an archive from when the process dies first.
+type journaledZip struct {
+	*zip.Writer
+	out     *countWriter
+	journal *os.File
+	// last is the entry being written, finished by the next Create or Close
+	last *journalEntry
+	crc  hash.Hash32
+	size *countWriter
+}
+
+// newJournaledZip returns a ZIP writer to w journaling to the file journal.
+func newJournaledZip(w io.Writer, journal string) (*journaledZip, error) {
+	f, err := os.Create(journal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recovery journal: %w", err)
+	}
+	out := &countWriter{w: w}
+	return &journaledZip{Writer: zip.NewWriter(out), out: out, journal: f}, nil
+}
+
+// Create adds a compressed entry, journaling the previous one.
+func (z *journaledZip) Create(name string) (io.Writer, error) {
+	w, err := z.Writer.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	// Creating an entry writes the end of the previous one; flushing it puts
+	// that and the new local header on disk, so the offset is the new data's
+	if err := z.Writer.Flush(); err != nil {
+		return nil, err
+	}
+	if err := z.record(); err != nil {
+		return nil, err
+	}
+	z.last = &journalEntry{Name: name, Offset: z.out.n}
+	z.crc = crc32.NewIEEE()
+	z.size = &countWriter{w: w}
+	return io.MultiWriter(z.size, z.crc), nil
+}
+
+// record appends the finished entry to the journal.
+func (z *journaledZip) record() error {
+	if z.last == nil {
+		return nil
+	}
+	z.last.Size = z.size.n
+	z.last.CRC32 = z.crc.Sum32()
+	line, err := json.Marshal(z.last)
+	if err != nil {
+		return err
+	}
+	if _, err := z.journal.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write recovery journal: %w", err)
+	}
+	return nil
+}
+
+// Close writes the central directory. The archive is then complete, so the
+// journal is removed.
+func (z *journaledZip) Close() error {
+	if err := z.Writer.Close(); err != nil {
+		return err
+	}
+	if err := z.journal.Close(); err != nil {
+		return err
+	}
+	return os.Remove(z.journal.Name())
+}
+
+// RepairResult describes an archive salvaged by RepairArchive.
+type RepairResult struct {
+	Output  string `json:"output"`
+	Entries int    `json:"entries"`
+	// Lost is the entry that was being written when the run died, if any.
+	Lost string `json:"lost,omitempty"`
+}
+
+// RepairArchive salvages the finished entries of a ZIP that was being
+// written in the work directory dir, such as "example.com.zip.partial",
+// when the run died before completing it. They are written to a new ZIP at
+// output, by default the name of dir without ".partial".
+func RepairArchive(dir, output string) (*RepairResult, error) {
+	if output == "" {
+		output = strings.TrimSuffix(filepath.Clean(dir), ".partial")
+		if output == filepath.Clean(dir) {
+			return nil, fmt.Errorf("%s is not a work directory, whose names end in .partial", dir)
+		}
+	}
+	entries, err := readJournal(journalFile(dir))
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Stat(checkpointFile(dir)); err == nil {
+			return nil, fmt.Errorf("%s has no archive being written; the run died during the crawl, run it again with --resume", dir)
+		}
+		return nil, fmt.Errorf("%s has no archive to repair", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entry of the archive in %s was finished", dir)
+	}
+
+	partial, err := os.Open(archiveFile(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partial archive: %w", err)
+	}
+	defer partial.Close()
+	stat, err := partial.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RepairResult{Output: output}
+	if err := writeSalvaged(output, partial, stat.Size(), entries); err != nil {
+		os.Remove(output)
+		return nil, err
+	}
+	result.Entries = len(entries)
+	result.Lost = lostEntry(partial, entries[len(entries)-1], stat.Size())
+	return result, nil
+}
+
+// readJournal reads the entries of the recovery journal at path. A line cut
+// short by the crash ends the journal.
+func readJournal(path string) ([]journalEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []journalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// writeSalvaged writes the journaled entries of the partial archive r to a
+// new ZIP at output, checking each against its size and checksum.
+func writeSalvaged(output string, r io.ReaderAt, size int64, entries []journalEntry) error {
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create zip file: %w", err)
+	}
+	defer f.Close()
+	archive := zip.NewWriter(f)
+	if err := copySalvaged(archive.Create, r, size, entries); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	return f.Close()
+}
+
+// copySalvaged writes the journaled entries of the partial archive r to the
+// entries create returns, checking each against its size and checksum.
+func copySalvaged(create func(name string) (io.Writer, error), r io.ReaderAt, size int64, entries []journalEntry) error {
+	for _, e := range entries {
+		w, err := create(e.Name)
+		if err != nil {
+			return fmt.Errorf("failed to create zip entry: %w", err)
+		}
+		data := flate.NewReader(io.NewSectionReader(r, e.Offset, size-e.Offset))
+		crc := crc32.NewIEEE()
+		n, err := io.Copy(io.MultiWriter(w, crc), data)
+		data.Close()
+		if err != nil || n != e.Size || crc.Sum32() != e.CRC32 {
+			return fmt.Errorf("entry %s of the partial archive is damaged", e.Name)
+		}
+	}
+	return nil
+}
+
+// lostEntry returns the name of the entry after last in the partial archive
+// r, which was not finished, or "" when there is none.
+func lostEntry(r io.ReaderAt, last journalEntry, size int64) string {
+	// Skip the compressed data of last and its data descriptor to the next
+	// local header
+	data := &countReader{r: bufio.NewReader(io.NewSectionReader(r, last.Offset, size-last.Offset))}
+	if _, err := io.Copy(io.Discard, flate.NewReader(data)); err != nil {
+		return ""
+	}
+	offset := last.Offset + data.n + 16
+	if last.Size >= math.MaxUint32 || data.n >= math.MaxUint32 {
+		offset += 8 // ZIP64 sizes
+	}
+	header := make([]byte, 30)
+	if _, err := r.ReadAt(header, offset); err != nil || string(header[:4]) != "PK\x03\x04" {
+		return ""
+	}
+	name := make([]byte, int(header[26])|int(header[27])<<8)
+	if _, err := r.ReadAt(name, offset+30); err != nil {
+		return ""
+	}
+	return string(name)
+}
+
+// countReader counts the bytes read through it. It reads a byte at a time
+// for flate, which then reads no further than the end of its stream.
+type countReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// killedArchive writes the entries to an archive in the work directory of
+// output and leaves it as a killed run would, without a central directory.
+func killedArchive(t *testing.T, output string, entries map[string]string, names ...string) string {
+	t.Helper()
+	dir := workDirName(output)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(archiveFile(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	archive, err := newJournaledZip(f, journalFile(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.journal.Close()
+	for _, name := range names {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, entries[name])
+	}
+	if err := archive.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRepairArchive(t *testing.T) {
+	entries := map[string]string{
+		"example.com_index.pdf": strings.Repeat("%PDF-1.4 home ", 1000),
+		"example.com_guide.pdf": "%PDF-1.4 guide",
+		"manifest.json":         `{"start_url":"https://example.com/"}`,
+	}
+	names := []string{"example.com_index.pdf", "example.com_guide.pdf", "manifest.json"}
+
+	tests := []struct {
+		name      string
+		tornLine  bool
+		wantFiles []string
+		wantLost  string
+	}{
+		{"killed while writing the last entry", false, names[:2], "manifest.json"},
+		// The journal line of the second entry was cut short by the crash
+		{"torn journal", true, names[:1], "example.com_guide.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "example.com.zip")
+			dir := killedArchive(t, output, entries, names...)
+			if tt.tornLine {
+				journal, _ := os.ReadFile(journalFile(dir))
+				lines := bytes.SplitAfter(journal, []byte("\n"))
+				torn := append(lines[0], lines[1][:len(lines[1])/2]...)
+				if err := os.WriteFile(journalFile(dir), torn, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := zip.OpenReader(archiveFile(dir)); err == nil {
+				t.Fatal("the killed archive is readable without repair")
+			}
+
+			result, err := RepairArchive(dir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Output != output || result.Entries != len(tt.wantFiles) || result.Lost != tt.wantLost {
+				t.Errorf("RepairArchive() = %+v", result)
+			}
+			r, err := zip.OpenReader(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if len(r.File) != len(tt.wantFiles) {
+				t.Fatalf("repaired archive has %d entries, want %d", len(r.File), len(tt.wantFiles))
+			}
+			for i, f := range r.File {
+				if f.Name != tt.wantFiles[i] {
+					t.Errorf("entry %d is %s, want %s", i, f.Name, tt.wantFiles[i])
+				}
+				data, err := readZipEntry(&r.Reader, f.Name)
+				if err != nil || string(data) != entries[f.Name] {
+					t.Errorf("%s = %q, %v", f.Name, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairArchiveErrors(t *testing.T) {
+	root := t.TempDir()
+	crawling := workDirName(filepath.Join(root, "crawling.zip"))
+	os.MkdirAll(crawling, 0755)
+	os.WriteFile(checkpointFile(crawling), []byte("{}"), 0644)
+	damaged := killedArchive(t, filepath.Join(root, "damaged.zip"), map[string]string{"a.pdf": "a", "b.pdf": "b"}, "a.pdf", "b.pdf")
+	os.WriteFile(archiveFile(damaged), []byte("PK\x03\x04 overwritten"), 0644)
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"not a work directory", root, "not a work directory"},
+		{"died during the crawl", crawling, "--resume"},
+		{"damaged entry", damaged, "a.pdf of the partial archive is damaged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RepairArchive(tt.dir, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RepairArchive() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(root, "damaged.zip")); err == nil {
+		t.Error("a failed repair left its output")
+	}
+}
+
+func TestCreateZipMovesArchive(t *testing.T) {
+	dir := t.TempDir()
+	s := &Scraper{pdfs: []*page{archivePage(t, dir, "/", 100)}}
+	output := filepath.Join(dir, "example.com.zip")
+	if err := s.createZip(output, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zip.OpenReader(output); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{archiveFile(workDirName(output)), journalFile(workDirName(output))} {
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("%s left behind", path)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// repeatedBlock is a block of text stripped from every page it appeared on.
+type repeatedBlock struct {
+	Text     string  `json:"text"`
+	Pages    int     `json:"pages"`
+	Fraction float64 `json:"fraction"`
+}
+
+// repeatedReport is written to repeated-blocks.json in the archive.
+type repeatedReport struct {
+	Threshold float64         `json:"threshold"`
+	Pages     int             `json:"pages"`
+	Removed   []repeatedBlock `json:"removed"`
+}
+
+// removeRepeatedBlocks strips the paragraphs and lines that appear on at
+// least threshold of the pages from every page's text. Paragraphs are
+// compared first; lines are only compared inside paragraphs that are not
+// repeated as a whole, so a footer merged into a content paragraph is still
+// found. Text has to appear on two pages or more to count as repeated.
+func removeRepeatedBlocks(pages []*page, threshold float64) repeatedReport {
+	report := repeatedReport{Threshold: threshold, Pages: len(pages)}
+
+	counts := make(map[string]int)
+	for _, p := range pages {
+		seen := make(map[string]bool)
+		for _, para := range strings.Split(p.Text, "\n\n") {
+			units := []string{normalizeBlock(para)}
+			if strings.Contains(strings.TrimSpace(para), "\n") {
+				units = append(units, strings.Split(para, "\n")...)
+			}
+			for _, u := range units {
+				if u = normalizeBlock(u); u != "" && !seen[u] {
+					seen[u] = true
+					counts[u]++
+				}
+			}
+		}
+	}
+
+	repeated := make(map[string]bool)
+	for text, n := range counts {
+		fraction := float64(n) / float64(len(pages))
+		if n >= 2 && fraction >= threshold {
+			repeated[text] = true
+		}
+	}
+
+	removed := make(map[string]bool)
+	for _, p := range pages {
+		var kept []string
+		for _, para := range strings.Split(p.Text, "\n\n") {
+			if key := normalizeBlock(para); repeated[key] {
+				removed[key] = true
+				continue
+			}
+			var lines []string
+			for _, line := range strings.Split(para, "\n") {
+				if key := normalizeBlock(line); repeated[key] {
+					removed[key] = true
+					continue
+				}
+				lines = append(lines, line)
+			}
+			if para = strings.Join(lines, "\n"); strings.TrimSpace(para) != "" {
+				kept = append(kept, para)
+			}
+		}
+		text := strings.Join(kept, "\n\n")
+		p.auditLines(transformRepeated, fmt.Sprintf("%g", threshold), p.Text, text)
+		p.Text = text
+	}
+
+	for text := range removed {
+		report.Removed = append(report.Removed, repeatedBlock{
+			Text:     text,
+			Pages:    counts[text],
+			Fraction: float64(counts[text]) / float64(len(pages)),
+		})
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		if report.Removed[i].Pages != report.Removed[j].Pages {
+			return report.Removed[i].Pages > report.Removed[j].Pages
+		}
+		return report.Removed[i].Text < report.Removed[j].Text
+	})
+	return report
+}
+
+// normalizeBlock collapses whitespace so blocks compare equal regardless of
+// the page layout around them.
+func normalizeBlock(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
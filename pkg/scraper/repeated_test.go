@@ -0,0 +1,47 @@
+package scraper
+
+import "testing"
+
+func TestRemoveRepeatedBlocks(t *testing.T) {
+	pages := []*page{
+		{Text: "Site Header\n\nFirst article body\n\nCopyright 2024 Example"},
+		{Text: "Site Header\n\nSecond article body\nCopyright 2024 Example"},
+		{Text: "Third article body\n\nCopyright 2024 Example"},
+	}
+
+	report := removeRepeatedBlocks(pages, 0.6)
+
+	want := []string{
+		"First article body",
+		"Second article body",
+		"Third article body",
+	}
+	for i, p := range pages {
+		if p.Text != want[i] {
+			t.Errorf("page %d text = %q, want %q", i, p.Text, want[i])
+		}
+	}
+
+	if len(report.Removed) != 2 {
+		t.Fatalf("removed %d blocks, want 2: %+v", len(report.Removed), report.Removed)
+	}
+	if report.Removed[0].Text != "Copyright 2024 Example" || report.Removed[0].Pages != 3 {
+		t.Errorf("first removed block = %+v, want the footer on 3 pages", report.Removed[0])
+	}
+	if report.Removed[1].Text != "Site Header" || report.Removed[1].Pages != 2 {
+		t.Errorf("second removed block = %+v, want the header on 2 pages", report.Removed[1])
+	}
+}
+
+func TestRemoveRepeatedBlocksSinglePage(t *testing.T) {
+	pages := []*page{{Text: "Only page\n\nWith a footer"}}
+
+	report := removeRepeatedBlocks(pages, 0.5)
+
+	if len(report.Removed) != 0 {
+		t.Errorf("removed %v from a single page crawl", report.Removed)
+	}
+	if pages[0].Text != "Only page\n\nWith a footer" {
+		t.Errorf("text changed to %q", pages[0].Text)
+	}
+}
@@ -0,0 +1,100 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Defaults of the request timeout and retry policy.
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultRetries        = 2
+	DefaultRetryBackoff   = time.Second
+)
+
+// maxRetryAfter bounds how long a Retry-After header may hold a retry.
+const maxRetryAfter = 5 * time.Minute
+
+// stageFetch is reported in the failures of pages that could not be
+// fetched after their retries.
+const stageFetch = "fetch"
+
+// checkRetryPolicy rejects a negative timeout or retry setting.
+func (s *Scraper) checkRetryPolicy() error {
+	if s.RequestTimeout < 0 || s.Retries < 0 || s.RetryBackoff < 0 {
+		return fmt.Errorf("the request timeout and retry settings can't be negative")
+	}
+	return nil
+}
+
+// transient reports whether a failed fetch may succeed if tried again: the
+// server is overloaded (429 or 5xx) or the request timed out or lost its
+// connection.
+func transient(status int, err error) bool {
+	if status != 0 {
+		return overloaded(status)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// retryDelay returns how long to wait before the attempt-th retry: the
+// server's Retry-After, or RetryBackoff doubled for every earlier retry.
+func (s *Scraper) retryDelay(attempt int, header *http.Header) time.Duration {
+	if header != nil {
+		if after := header.Get("Retry-After"); after != "" {
+			if secs, err := strconv.Atoi(after); err == nil && secs >= 0 {
+				return min(time.Duration(secs)*time.Second, maxRetryAfter)
+			}
+			if at, err := http.ParseTime(after); err == nil {
+				return min(max(time.Until(at), 0), maxRetryAfter)
+			}
+		}
+	}
+	return s.RetryBackoff << (attempt - 1)
+}
+
+// retry tries a request that failed with a transient error again after a
+// backoff, and reports whether it did. Requests out of retries fail like
+// any other, see recordFetchFailure.
+func (s *Scraper) retry(r *colly.Response, err error) bool {
+	if !transient(r.StatusCode, err) {
+		return false
+	}
+	// Requests share the context of the page linking to them, so
+	// attempts are counted by URL
+	u := r.Request.URL.String()
+	s.mu.Lock()
+	attempt := s.retried[u]
+	if attempt < s.Retries {
+		if s.retried == nil {
+			s.retried = make(map[string]int)
+		}
+		s.retried[u] = attempt + 1
+	}
+	s.mu.Unlock()
+	if attempt >= s.Retries {
+		return false
+	}
+	attempt++
+	delay := s.retryDelay(attempt, r.Headers)
+	s.logf("Retrying %s in %s (%d/%d): %v\n", r.Request.URL, delay, attempt, s.Retries, err)
+	s.event(event{Type: eventRetry, URL: r.Request.URL.String(), Status: r.StatusCode, Error: err.Error()})
+	if !s.wait(delay) {
+		return false
+	}
+	// Without Async the retry runs right away, and its error is that of
+	// the retried request, handled by its own OnError
+	_ = r.Request.Retry()
+	return true
+}
@@ -0,0 +1,98 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransient(t *testing.T) {
+	timeout := &net.DNSError{Err: "i/o timeout", IsTimeout: true}
+	refused := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	tests := []struct {
+		status int
+		err    error
+		want   bool
+	}{
+		{http.StatusServiceUnavailable, errors.New("Service Unavailable"), true},
+		{http.StatusTooManyRequests, errors.New("Too Many Requests"), true},
+		{http.StatusNotFound, errors.New("Not Found"), false},
+		{0, fmt.Errorf("Get: %w", timeout), true},
+		{0, refused, true},
+		{0, errors.New("not in the cache"), false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.status, tt.err); got != tt.want {
+			t.Errorf("transient(%d, %v) = %v, want %v", tt.status, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	s := NewScraper(false, false)
+	s.RetryBackoff = time.Second
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second} {
+		if got := s.retryDelay(attempt, nil); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+	h := http.Header{"Retry-After": {"7"}}
+	if got := s.retryDelay(1, &h); got != 7*time.Second {
+		t.Errorf("retryDelay with Retry-After: 7 = %s", got)
+	}
+	h = http.Header{"Retry-After": {"86400"}}
+	if got := s.retryDelay(1, &h); got != maxRetryAfter {
+		t.Errorf("retryDelay with a day long Retry-After = %s, want %s", got, maxRetryAfter)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var flaky, down atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><p>Home</p><a href="/flaky">Flaky</a> <a href="/down">Down</a></body></html>`)
+		case "/flaky":
+			if flaky.Add(1) <= 2 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><p>Flaky but fine</p></body></html>`)
+		case "/down":
+			down.Add(1)
+			http.Error(w, "broken", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.Retries, s.RetryBackoff = 2, time.Millisecond
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	entries := zipEntries(t, zipname)
+	if _, ok := entries[strings.TrimPrefix(srv.URL, "http://")+"_flaky.pdf"]; !ok {
+		t.Error("page recovering after two retries wasn't archived")
+	}
+	if got := down.Load(); got != 3 {
+		t.Errorf("failing page requested %d times, want 3", got)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(entries["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Failures) != 1 || m.Failures[0].Stage != stageFetch || !strings.HasSuffix(m.Failures[0].URL, "/down") {
+		t.Errorf("failures = %+v, want the page out of retries", m.Failures)
+	}
+}
@@ -0,0 +1,35 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule maps discovered URLs matching Pattern to Replacement before
+// they are visited. Replacement may refer to capture groups as $1 or ${name}.
+type RewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ParseRewriteRule parses a rule written as 'regex=>replacement'.
+func ParseRewriteRule(rule string) (RewriteRule, error) {
+	pattern, replacement, ok := strings.Cut(rule, "=>")
+	if !ok {
+		return RewriteRule{}, fmt.Errorf("invalid rewrite rule %q, expected 'regex=>replacement'", rule)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("invalid rewrite pattern %q: %w", pattern, err)
+	}
+	return RewriteRule{Pattern: re, Replacement: replacement}, nil
+}
+
+// rewriteURL applies every rewrite rule in order to the absolute URL u.
+func (s *Scraper) rewriteURL(u string) string {
+	for _, r := range s.Rewrites {
+		u = r.Pattern.ReplaceAllString(u, r.Replacement)
+	}
+	return u
+}
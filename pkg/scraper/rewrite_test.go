@@ -0,0 +1,49 @@
+package scraper
+
+import "testing"
+
+func TestParseRewriteRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+	}{
+		{`cdn\.example\.com=>example.com`, false},
+		{`/v(\d+)/=>/v$1/`, false},
+		{`^http://=>https://`, false},
+		{`no arrow`, true},
+		{`([a-z=>x`, true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRewriteRule(tt.rule); (err != nil) != tt.wantErr {
+			t.Errorf("ParseRewriteRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRewriteURL(t *testing.T) {
+	s := NewScraper(false, false)
+	for _, r := range []string{
+		`//cdn\.example\.com/=>//example.com/`,
+		`/docs/v[0-9.]+/=>/docs/latest/`,
+		`^http://=>https://`,
+	} {
+		rule, err := ParseRewriteRule(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Rewrites = append(s.Rewrites, rule)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"https://cdn.example.com/guide", "https://example.com/guide"},
+		{"http://example.com/docs/v2.1/intro", "https://example.com/docs/latest/intro"},
+		{"https://example.com/blog", "https://example.com/blog"},
+	}
+	for _, tt := range tests {
+		if got := s.rewriteURL(tt.in); got != tt.want {
+			t.Errorf("rewriteURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,1846 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/gocolly/colly/v2"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+type Scraper struct {
+	// StampSource adds a header box to the first page of every PDF with the
+	// canonical URL, fetch date and HTTP status of the source page.
+	StampSource bool
+	// Header and Footer are printed on every page of the PDFs, with {url},
+	// {title}, {date}, {page} and {pages} filled in for the page, e.g.
+	// "{url} — page {page}/{pages}".
+	Header, Footer string
+	// Locale writes the dates of the stamp, headers, footers and index.html
+	// and the page count of index.html for an audience, e.g. "de" or
+	// "pt-BR", see LocaleNames. Empty keeps ISO dates.
+	Locale string
+	// TimeZone is the zone those dates are shown in, UTC when nil.
+	TimeZone *time.Location
+	// NoTOC leaves out the table of contents of PDF archives, _toc.pdf or
+	// the first section of a single PDF.
+	NoTOC bool
+	// CoverTemplate is an html/template file executed into a cover page,
+	// _cover.pdf at the start of the archive or the first section of a
+	// single PDF, with the site, its logo, run statistics and the archived
+	// pages, see coverData.
+	CoverTemplate string
+	// MaxDepth limits how many links away from the start page the crawl
+	// goes: 1 fetches only the start page. Zero is unlimited.
+	MaxDepth int
+	// MaxPages stops the crawl once this many pages were converted, and
+	// finishes the archive with them. Zero is unlimited.
+	MaxPages int
+	// Seeds are more pages the crawl starts from after the start URL, all
+	// archived together, e.g. a curated list of pages across a site's
+	// sections. They must be on the start URL's host, or its domain with
+	// IncludeSubdomains.
+	Seeds []string
+	// IncludeSubdomains also follows links to the other hosts of the start
+	// page's domain, e.g. api.example.com and blog.example.com from
+	// docs.example.com. Otherwise the crawl stays on the start page's host.
+	IncludeSubdomains bool
+	// AcceptTypes are media types converted to pages besides HTML, e.g.
+	// text/plain, or text/* for every text type. Other responses, such as
+	// images, stylesheets and downloads, are skipped.
+	AcceptTypes []string
+	// RespectRobots skips pages disallowed by robots.txt or marked noarchive,
+	// and does not follow links on pages marked nofollow.
+	RespectRobots bool
+	// ComplianceReport writes compliance.json into the archive, recording the
+	// robots.txt and meta robots policy applied to each URL.
+	ComplianceReport bool
+
+	// Readability keeps only the main article of stripped pages without a
+	// site recipe, scoring elements by the paragraphs they contain, so menus,
+	// cookie banners and footers are left out.
+	Readability bool
+	// Boilerplate configures removal of navigation and other repeated text
+	// from stripped pages.
+	Boilerplate BoilerplateFilter
+	// RepeatedBlocks removes text blocks found on at least this fraction of
+	// the crawled pages (0-1), such as headers, footers and sidebars. Pages
+	// are rendered once the crawl has finished. Zero disables the pass.
+	RepeatedBlocks float64
+	// Recipes are the site specific extraction rules applied to stripped
+	// pages, see LoadRecipes.
+	Recipes []*Recipe
+	// BrowserScripts are the actions run on the pages of their sites before
+	// the headless browser captures them, see LoadBrowserScripts.
+	BrowserScripts []*BrowserScript
+	// AutoScroll scrolls pages in the headless browser to their bottom
+	// before capture, up to MaxScrolls times and waiting ScrollSettle after
+	// each scroll, so content loaded on scroll is included.
+	AutoScroll   bool
+	MaxScrolls   int
+	ScrollSettle time.Duration
+	// Preset applies the scope, selectors and page ordering of a
+	// documentation platform, see PresetNames.
+	Preset string
+	// PathPrefix limits the crawl to URLs whose path starts with it.
+	PathPrefix string
+	// Order is the order of the pages in the archive, OrderCrawl (the
+	// default), OrderNav or OrderDate.
+	Order string
+	// GroupByAuthor groups the pages by the author of their byline: their
+	// files go in a directory per author, and the table of contents and
+	// the bookmarks of a single PDF list them under their author's name.
+	// The pages of each author keep the Order.
+	GroupByAuthor bool
+	// NavSelector selects the navigation links whose order the archived
+	// pages follow, instead of crawl order. Setting it implies OrderNav.
+	NavSelector string
+	// StitchPages merges the parts of paginated articles (rel="next"
+	// chains, ?page=N URLs) into the PDF of their first part. Pages are
+	// rendered once the crawl has finished.
+	StitchPages bool
+	// PreferVariant takes the content of pages from their simplified
+	// VariantAMP or VariantPrint version when they advertise one. Pages are
+	// still named and deduplicated by their canonical URL.
+	PreferVariant string
+	// PageTimeout bounds the extraction and the rendering of each page. A
+	// page that takes longer, or panics, is recorded as failed and the crawl
+	// continues. Zero means no limit.
+	PageTimeout time.Duration
+	// Secrets are literal values, such as tokens and cookie values, masked in
+	// every log line, report and error message.
+	Secrets []string
+	// LogRequests prints every request and response with its headers, with
+	// credentials masked.
+	LogRequests bool
+	// StreamThreshold is the body size in bytes above which stripped pages
+	// are extracted with a streaming tokenizer instead of a full DOM. Site
+	// recipes and link density pruning don't apply to them. Zero always
+	// builds the DOM.
+	StreamThreshold int64
+	// MaxBandwidth caps the download rate of the whole crawl in bytes per
+	// second. Zero is unlimited.
+	MaxBandwidth int64
+	// RequestTimeout bounds each request, its body included. Zero waits
+	// forever.
+	RequestTimeout time.Duration
+	// Retries is how many times a request failing with a transient error,
+	// a 429, a 5xx or a timeout, is tried again, waiting RetryBackoff
+	// before the first retry and twice as long before each next one, or
+	// as long as the server's Retry-After says. Pages still failing are
+	// recorded as failed.
+	Retries      int
+	RetryBackoff time.Duration
+	// Delay is the least time between two requests to the same host, and
+	// RandomDelay adds up to that much more at random, so the crawl doesn't
+	// get rate limited or blocked.
+	Delay       time.Duration
+	RandomDelay time.Duration
+	// Parallelism is how many requests to the same host may be in flight at
+	// once. Above 1 pages are fetched concurrently, and their order in the
+	// archive depends on when they finish unless Order is OrderNav.
+	Parallelism int
+	// MaxConnsPerHost caps the requests to the same host in flight at
+	// once, whatever Parallelism is, so pages can be processed by many
+	// workers while only a few connections reach the origin. Zero leaves
+	// it to Parallelism.
+	MaxConnsPerHost int
+	// Adaptive fetches pages concurrently, starting with one request at a
+	// time and adding more while responses stay fast and successful, up to
+	// Parallelism or DefaultAdaptiveMax. 429s, 5xxs and timeouts halve the
+	// number of requests in flight.
+	Adaptive bool
+	// SinglePDF writes every page, in archive order, to one PDF with a
+	// bookmark per source page instead of a ZIP of PDFs. It needs the text
+	// renderer, and the archive's index and reports are not written.
+	SinglePDF bool
+	// OutputMode selects how the archive is written: OutputZip, the
+	// default, or OutputDir for a directory with the pages in a tree
+	// mirroring their URL paths.
+	OutputMode string
+	// ArchiveFormat is the file format of the archive: ArchiveZip, the
+	// default, ArchiveTar or ArchiveTarGz.
+	ArchiveFormat string
+	// SplitBy writes an archive per site section instead of one, each
+	// with its own index and manifest: SplitTopLevelPath, or "" for one
+	// archive. See Outputs for the archives written.
+	SplitBy string
+	// Renderer selects how pages become PDFs: RendererText lays out the
+	// extracted text, RendererChrome prints the live page with a headless
+	// browser and RendererBoth does both. Without a usable browser the run
+	// falls back to RendererText.
+	Renderer string
+	// RenderJS fetches pages with the headless browser instead of a plain
+	// HTTP request, so content built by JavaScript is extracted and its
+	// links followed. Without a usable browser pages are fetched statically.
+	RenderJS bool
+	// NoJavaScript has the browser print pages without running their
+	// scripts, for captures without the ads and trackers they load. It
+	// can't be combined with RenderJS.
+	NoJavaScript bool
+	// RenderOverrides change the renderer, orientation, RenderJS or
+	// JavaScript of the pages matching their patterns, applied in order.
+	RenderOverrides []*RenderOverride
+	// NoRenderFallback records the pages the browser crashed or timed out
+	// on as failed, instead of rendering them as text, and keeps the pages
+	// the text renderer got no text out of empty instead of printing them
+	// with the browser, started for them if the run has none.
+	NoRenderFallback bool
+	// BrowserPath is the Chrome executable used by RendererChrome and
+	// RenderJS. Empty
+	// uses the browser installed in BrowserDir, or searches PATH and the
+	// usual install locations.
+	BrowserPath string
+	// BrowserDir holds the browser managed by InstallBrowser.
+	BrowserDir string
+	// PageSize is the size of the PDF pages: PageSizeA4, the default,
+	// PageSizeA3, PageSizeLetter or PageSizeLegal.
+	PageSize string
+	// Orientation is the orientation of the PDF pages, OrientationPortrait
+	// or OrientationLandscape, unless a RenderOverride changes it.
+	Orientation string
+	// Margins replace the margins of the PDF pages, those of the
+	// Typography for the text renderer and the browser's own for the
+	// chrome renderer.
+	Margins *Margins
+	// Font is a TrueType font file used by the text renderer instead of
+	// the embedded DejaVu fonts, e.g. for CJK scripts.
+	Font string
+	// Typography names the text renderer's layout preset, see
+	// TypographyNames. Empty keeps the default layout.
+	Typography string
+	// LinkURLs follows the text of links in the text renderer's PDFs with
+	// their targets in parentheses, so they survive printing. The links
+	// are clickable either way.
+	LinkURLs bool
+	// Thumbnails adds a PNG preview of the first page of every PDF to the
+	// archive's thumbs folder and shows them in index.html.
+	Thumbnails bool
+	// SearchIndex adds a full-text index of the extracted text to the
+	// archive and a search box to index.html that works offline.
+	SearchIndex bool
+	// Embeddings, when set, adds embeddings.jsonl to the archive: the
+	// extracted text of every page in chunks with their vector
+	// embeddings, so the archive can serve as a retrieval corpus.
+	Embeddings *Embeddings
+	// DocsVersion limits the crawl to one version of a documentation site:
+	// a version name such as "latest" or "v2" replaces the version found in
+	// the start URL, a path starting with "/" is used as is. The version is
+	// recorded in the manifest and index.html.
+	DocsVersion string
+	// RedactContent blacks out matches in the extracted text and title of
+	// every page before it is rendered, e.g. e-mail addresses or API keys.
+	// The chrome renderer prints the live page, so it can't be combined
+	// with redaction.
+	RedactContent []*regexp.Regexp
+	// Filter leaves out pages for which it is false, evaluated once their
+	// content has been extracted.
+	Filter *Filter
+	// Include, when set, limits the crawl to links matching one of its
+	// patterns, and Exclude leaves out links matching any of its patterns.
+	// Excluded links are not fetched; an excluded start page is fetched for
+	// its links but not archived.
+	Include []URLPattern
+	Exclude []URLPattern
+	// SkipRules leave out pages whose response header or meta tag matches
+	// one of them.
+	SkipRules []SkipRule
+	// Rewrites are applied to every discovered link before it is visited,
+	// e.g. to map a CDN host back to the site or pin a docs version.
+	Rewrites []RewriteRule
+	// IncludeAttachments downloads the documents linked from the pages,
+	// such as PDFs, Word files and spreadsheets, into the archive's
+	// attachments folder as they are, instead of following the links. They
+	// may be on other hosts.
+	IncludeAttachments bool
+	// CheckLinks records internal links that fail to load, such as 404s and
+	// timeouts, in broken-links.csv with the pages linking to them.
+	CheckLinks bool
+	// LinkGraph writes the links between the pages of the site, with their
+	// anchor text, to link-graph.<format> in the archive. One of GraphML,
+	// GraphDOT or GraphJSON; empty for none.
+	LinkGraph string
+	// Headers are set on every request of the crawl, e.g. an Authorization
+	// header for documentation behind a login. Assets fetched for
+	// SelfContained copies, often from other hosts, don't get them.
+	Headers http.Header
+	// Cookies are sent with every request to the site, e.g. a session
+	// cookie copied from the browser. Cookies with a Domain are only sent
+	// to that domain. A Session restores its own cookies over them.
+	Cookies []*http.Cookie
+	// UserAgent replaces colly's user agent on the requests of the crawl,
+	// its assets and the headless browser, for sites that block or strip
+	// pages for unknown clients.
+	UserAgent string
+	// RotateUserAgents sends each request with the next user agent of a
+	// built-in pool of browsers instead of a single one.
+	RotateUserAgents bool
+	// Session saves the site's cookies after the crawl and restores them on
+	// the next run, logging in again only when they have expired. Nil
+	// disables persistence.
+	Session *Session
+	// Events adds events.jsonl to the archive: a timeline of link
+	// discovery, fetches, renders and errors.
+	Events bool
+	// EventsFile writes the same timeline to this path as the crawl runs,
+	// so it survives a crash.
+	EventsFile string
+	// EventStream receives the same timeline as JSON Lines as the crawl
+	// runs, e.g. os.Stderr for a script following its progress.
+	EventStream io.Writer
+	// Log receives the progress messages meant for people, os.Stdout when
+	// nil.
+	Log io.Writer
+	// OnProgress, when set, is called with the state of the crawl several
+	// times a second while it runs, from another goroutine, and once more
+	// when it ends. The message logged for every page archived is left
+	// out, the progress reports them.
+	OnProgress func(Progress)
+	// Evidence keeps the raw response, headers, TLS certificates and a
+	// SHA-256 of every archived page, timestamped by TimeServer, and adds a
+	// chain of custody report and checksums of every file to the archive.
+	Evidence bool
+	// TimeServer is the NTP server evidence timestamps are taken from. When
+	// it can't be reached the local clock is used and the report says so.
+	TimeServer string
+	// CacheDir keeps every response of the crawl in this directory, and
+	// answers requests from it instead of the network when it has them, so
+	// the pages can be rendered again later.
+	CacheDir string
+	// Offline never goes to the network: requests are answered from WARC
+	// or CacheDir, and what they don't have fails.
+	Offline bool
+	// WARC replays the responses captured in a WARC file instead of fetching
+	// them, and implies Offline.
+	WARC *WARC
+	// Resume continues the interrupted run that was writing the same
+	// output, from the checkpoint in its work directory, instead of
+	// starting over.
+	Resume bool
+	// Shared is shared with the other crawls of a batch, so a page one of
+	// them fetched isn't fetched again by the others, see VisitedStore.
+	// Start URLs and Seeds are always fetched.
+	Shared *VisitedStore
+	// Baseline is an earlier archive of the site, a ZIP file, tarball or
+	// output directory. Pages whose body hashes the same as recorded in its
+	// manifest are left out, so the archive only holds new and changed
+	// pages. ScrapeAndSave returns ErrNoChanges when there are none.
+	Baseline string
+	// PipeHTML is a shell command each page's HTML is passed through, on
+	// stdin and stdout, before its content is extracted, for site-specific
+	// fixes without Go code. The page's URL and title are in SCRAPDF_URL
+	// and SCRAPDF_TITLE.
+	PipeHTML string
+	// PipeText is a shell command the extracted content is passed through
+	// before it is rendered: text, or Markdown with FormatMarkdown.
+	PipeText string
+	// NoImages leaves the images of pages out of the PDFs of the text
+	// renderer, which otherwise places them where they appear in the text.
+	NoImages bool
+	// ImageQuality recompresses the JPEGs embedded by the text renderer at
+	// this quality (1-100), and PNGs with the best compression, as JPEGs
+	// when they are photos. Zero keeps the images as they are.
+	ImageQuality int
+	// MaxImageWidth downscales embedded images wider than this many
+	// pixels. Zero keeps their size.
+	MaxImageWidth int
+	// SelfContained adds a single file HTML copy of every page next to its
+	// PDF, with stylesheets, images and fonts inlined as data URIs.
+	SelfContained bool
+	// Format is the format pages are exported in, FormatPDF (the default),
+	// FormatMarkdown, which converts the extracted HTML to a Markdown file
+	// per page, or FormatEPUB, which converts it to the chapters of one
+	// book. Like SinglePDF, an EPUB has no index or reports.
+	Format string
+	// FrontMatter prepends YAML front matter in the FrontMatterHugo or
+	// FrontMatterJekyll style, with the title, fetch date, source URL and
+	// tags, to every Markdown file, so the files can be dropped into a
+	// static site's content directory.
+	FrontMatter string
+	// Meta is recorded in the manifest and in the custom XMP properties and
+	// keywords of every PDF made by the text renderer, so document systems
+	// can route the files.
+	Meta map[string]string
+
+	visited       sync.Map
+	nofollow      sync.Map
+	pdfs          []*page // pages with a generated PDF, in archive order
+	stripHTML     bool
+	mu            sync.Mutex
+	compliance    []complianceRecord
+	failures      []pageFailure
+	seenLines     map[string]int             // map[line]pages containing it
+	pending       []*page                    // pages waiting for cross-page analysis
+	baseline      map[string]baselinePage    // map[url]page of the Baseline
+	baselineTime  time.Time                  // when the Baseline was made
+	unchanged     []string                   // pages found unchanged since the Baseline
+	requested     sync.Map                   // map[request ID]URL requested, before redirects
+	fragments     map[string]map[string]bool // map[url]fragments linked to
+	navSelector   cascadia.Selector
+	navOrder      []string // page URLs in navigation order
+	chrome        *chromeRenderer
+	fallback      *fallbackBrowser
+	linkSources   map[string][]string // map[url]pages linking to it
+	edges         []graphEdge         // links between pages, for LinkGraph
+	edgeSeen      map[graphEdge]bool
+	docsVersion   string               // version name resolved from DocsVersion
+	cookieExpiry  map[string]time.Time // map[cookie name]expiry seen at login
+	events        *eventLog
+	discovered    sync.Map // map[url]bool of links already reported
+	fetchStarted  sync.Map // map[url]time.Time
+	brokenLinks   []brokenLink
+	fontData      []byte // custom font read from Font
+	state         *crawlState
+	assets        *assetFetcher
+	estimate      *estimator     // samples the crawl for EstimateCrawl
+	estimated     *CrawlEstimate // measured from the sample
+	plan          *planner       // lists the pages for PlanCrawl
+	authors       sync.Map       // map[url]author of the pages, for GroupByAuthor
+	stream        *zipStream     // ZIP pages are written to as they render
+	domain        string         // registrable domain, for IncludeSubdomains
+	seeds         []string       // parsed Seeds
+	pagesTaken    atomic.Int64   // pages converted or being converted, for MaxPages
+	pageLimitOnce sync.Once
+	userAgentTurn atomic.Uint64 // requests sent with RotateUserAgents
+	clock         timeSource
+	clockOffset   time.Duration // added to the local clock for evidence
+	captured      sync.Map      // map[url]*capturedResponse
+	tlsSeen       sync.Map      // map[url]*tlsDetails
+	// rendererFallback explains why the chrome renderer was replaced by the
+	// text renderer.
+	rendererFallback string
+	cover            *template.Template     // parsed CoverTemplate
+	logo             string                 // logo of the start page, for the cover
+	attachments      map[string]*attachment // map[url]document linked from pages
+	attachmentOrder  []string               // attachment URLs, as first linked
+	retried          map[string]int         // map[url]retries so far
+	localRoot        string                 // URL path of a local tree being crawled
+	titles           map[*page]string       // titles of pages sharing one, told apart
+	ctx              context.Context        // of ScrapeAndSaveContext
+	interrupted      bool                   // the crawl stopped when ctx was done
+	client           *http.Client           // of WithHTTPClient
+	section          string                 // being written, see createSections
+	outputs          []string               // written by the last crawl
+	// jar returns the crawl's cookies for a URL, for the browser.
+	jar func(u string) []*http.Cookie
+	// docLinks are the internal links of the single PDF being written, by
+	// URL of the pages and the elements linked to, see createSinglePDF.
+	docLinks map[string]int
+	// crawlID numbers the crawl in Shared, see VisitedStore.claim.
+	crawlID uint64
+	// renderAttempts names the file each render writes, see isolateRender.
+	renderAttempts atomic.Uint64
+}
+
+// archiveEntry is an additional file written to the archive next to the PDFs.
+type archiveEntry struct {
+	Name string
+	Data []byte
+	File string // copied instead of Data when set
+}
+
+// page holds a fetched document together with the metadata captured for it.
+type page struct {
+	URL *url.URL
+	// RequestedURL is the URL that redirected to URL, if any.
+	RequestedURL string
+	Status       int
+	FetchedAt    time.Time
+	Header       http.Header
+	Body         []byte
+	Title        string
+	Canonical    string   // rel="canonical" target, kept once Body is dropped
+	Depth        int      // links followed from the start page, starting at 1
+	Next         string   // rel="next" target, when stitching pages
+	Parts        []string // URLs of later parts stitched into this page
+	Variant      string   // AMP or print variant the content was taken from
+	Text         string   // content rendered into the PDF
+	File         string   // path of the generated PDF
+	// ChromeFile is the path of the Chrome print made by RendererBoth.
+	ChromeFile string
+	// SnapshotFile is the path of the self-contained HTML copy.
+	SnapshotFile string
+	Thumb        []byte // PNG preview of the first page
+	// Streamed is set once the page's files were moved into the ZIP.
+	Streamed bool
+	// Transforms is the audit trail of rules that changed the content.
+	Transforms []transform
+	SHA256     string // hash of the body as fetched
+	// Change is how the page differs from the Baseline, changeNew or
+	// changeChanged.
+	Change string
+	// Published is when the page says it was published, zero when it
+	// doesn't.
+	Published time.Time
+	Author    string // from the page's byline
+	// Links are the links in Text, made clickable by the text renderer.
+	Links []pageLink
+	// Anchors are the elements in Text that links can point at, for the
+	// single PDF.
+	Anchors []pageAnchor
+	// Fallback is set when the page was rendered by the other renderer
+	// after the one asked for failed on it.
+	Fallback *renderFallback
+}
+
+// DefaultMaxDepth is the crawl depth used unless MaxDepth is changed.
+const DefaultMaxDepth = 5
+
+// Options are the settings a Scraper is created with. Its other settings
+// are its exported fields.
+type Options struct {
+	// StripHTML renders the text extracted from pages instead of their
+	// HTML source, like scrapdf scrape --strip. The boilerplate filters
+	// need it.
+	StripHTML bool
+	// Clean removes lines with two words or less from the text of pages,
+	// like Boilerplate.MinWords = 3. It needs StripHTML.
+	Clean bool
+}
+
+// New returns a Scraper with opts and the default settings.
+func New(opts Options) *Scraper {
+	return NewScraper(opts.StripHTML, opts.Clean)
+}
+
+// NewScraper returns a Scraper stripping HTML and cleaning lines as New
+// does with Options.
+func NewScraper(stripHTML bool, clean bool) *Scraper {
+	s := &Scraper{
+		visited:         sync.Map{},
+		stripHTML:       stripHTML,
+		seenLines:       make(map[string]int),
+		fragments:       make(map[string]map[string]bool),
+		linkSources:     make(map[string][]string),
+		StreamThreshold: DefaultStreamThreshold,
+		MaxDepth:        DefaultMaxDepth,
+		RequestTimeout:  DefaultRequestTimeout,
+		Retries:         DefaultRetries,
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxScrolls:      DefaultMaxScrolls,
+		ScrollSettle:    DefaultScrollSettle,
+	}
+	if clean && stripHTML {
+		// --clean predates the configurable filter and keeps its meaning:
+		// drop lines with two words or less.
+		s.Boilerplate.MinWords = 3
+	}
+	return s
+}
+
+// ScrapeAndSave crawls startURL and writes the archive to outputPath. Any
+// credentials in the returned error are masked.
+func (s *Scraper) ScrapeAndSave(startURL string, outputPath string) error {
+	return s.redactError(s.scrapeAndSave(startURL, outputPath))
+}
+
+func (s *Scraper) scrapeAndSave(startURL string, outputPath string) error {
+	// Parse the starting URL to get the domain
+	parsedURL, err := url.Parse(startURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	startURL = stripFragment(parsedURL).String()
+	if s.Shared != nil {
+		// Each crawl of a reused Scraper is a crawl of its own
+		s.crawlID = crawlIDs.Add(1)
+	}
+
+	if s.DocsVersion != "" {
+		if parsedURL, err = s.applyDocsVersion(s.DocsVersion, parsedURL); err != nil {
+			return err
+		}
+		startURL = parsedURL.String()
+	}
+	if s.Preset != "" {
+		if err := s.applyPreset(s.Preset, parsedURL); err != nil {
+			return err
+		}
+	}
+	if err := s.parseSeeds(parsedURL); err != nil {
+		return err
+	}
+	if parsedURL.Scheme == "file" {
+		if parsedURL, err = s.startLocal(parsedURL); err != nil {
+			return err
+		}
+		startURL = parsedURL.String()
+	}
+	if err := validVariant(s.PreferVariant); err != nil {
+		return err
+	}
+	if err := validFrontMatter(s.FrontMatter); err != nil {
+		return err
+	}
+	if err := s.checkFormat(); err != nil {
+		return err
+	}
+	if err := s.checkPageTemplates(); err != nil {
+		return err
+	}
+	if err := s.checkLocale(); err != nil {
+		return err
+	}
+	if err := s.checkAcceptTypes(); err != nil {
+		return err
+	}
+	if err := s.checkCover(); err != nil {
+		return err
+	}
+	if err := s.checkImageOptions(); err != nil {
+		return err
+	}
+	if err := s.checkRetryPolicy(); err != nil {
+		return err
+	}
+	if err := s.checkUserAgent(); err != nil {
+		return err
+	}
+	if err := s.loadBaseline(); err != nil {
+		return err
+	}
+	switch s.Order {
+	case "", OrderCrawl, OrderNav, OrderDate:
+	default:
+		return fmt.Errorf("unknown page order %q", s.Order)
+	}
+	if s.NavSelector != "" {
+		s.Order = OrderNav
+		sel, err := cascadia.Compile(s.NavSelector)
+		if err != nil {
+			return fmt.Errorf("invalid navigation selector %q: %w", s.NavSelector, err)
+		}
+		s.navSelector = sel
+	}
+	if _, err := LookupTypography(s.Typography); err != nil {
+		return err
+	}
+	if err := s.loadFont(); err != nil {
+		return err
+	}
+	if s.SinglePDF && s.printsLivePage() {
+		return fmt.Errorf("a single PDF can only be built by the %s renderer", RendererText)
+	}
+	if len(s.RedactContent) > 0 && s.printsLivePage() {
+		return fmt.Errorf("redaction rules only apply to the %s renderer, the %s renderer prints the live page", RendererText, s.Renderer)
+	}
+	if len(s.RedactContent) > 0 && s.SelfContained {
+		return fmt.Errorf("redaction rules only apply to the extracted text, the self-contained copies are the pages as fetched")
+	}
+	if len(s.RedactContent) > 0 && s.Evidence {
+		return fmt.Errorf("redaction rules only apply to the extracted text, evidence keeps the raw responses as the server sent them")
+	}
+	if err := s.checkOffline(); err != nil {
+		return err
+	}
+	if err := s.checkLinkGraph(); err != nil {
+		return err
+	}
+	if err := s.checkBrowserScripts(); err != nil {
+		return err
+	}
+	if err := s.checkAutoScroll(); err != nil {
+		return err
+	}
+	if err := s.checkOutputMode(); err != nil {
+		return err
+	}
+	if err := s.checkArchiveFormat(); err != nil {
+		return err
+	}
+	if err := s.checkSplitBy(); err != nil {
+		return err
+	}
+	if err := s.checkPageLayout(); err != nil {
+		return err
+	}
+	if err := s.checkEmbeddings(); err != nil {
+		return err
+	}
+	if err := s.checkRenderOverrides(); err != nil {
+		return err
+	}
+	if (s.PipeHTML != "" || s.PipeText != "") && s.printsLivePage() {
+		return fmt.Errorf("page filters only apply to the %s renderer, the %s renderer prints the live page", RendererText, s.Renderer)
+	}
+	if s.PipeText != "" && s.Format == FormatEPUB {
+		return fmt.Errorf("the text of EPUB chapters is XHTML, filter the page's HTML instead")
+	}
+	if s.SelfContained && s.SinglePDF {
+		return fmt.Errorf("self-contained HTML copies need a ZIP archive, they can't be combined with a single PDF")
+	}
+	if s.IncludeAttachments && (s.SinglePDF || s.Format == FormatEPUB) {
+		return fmt.Errorf("attachments need an archive, they can't be combined with a single PDF or an EPUB")
+	}
+	if s.Evidence && s.SinglePDF {
+		return fmt.Errorf("evidence needs a ZIP archive, it can't be combined with a single PDF")
+	}
+	if s.Evidence && s.Resume {
+		return fmt.Errorf("evidence can't be resumed, the raw responses of the interrupted run are not kept")
+	}
+	if s.Evidence {
+		s.syncClock()
+	}
+	runStartedAt := s.trustedNow()
+	if err := s.startRenderer(); err != nil {
+		return err
+	}
+	defer s.stopRenderer()
+
+	if err := s.openEvents(); err != nil {
+		return err
+	}
+	defer s.closeEvents()
+	runStarted := time.Now()
+	s.event(event{Type: eventRunStart, URL: startURL})
+
+	// Ensure output directory exists
+	outputDir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// PDFs and the checkpoint are kept next to the output until the archive
+	// is complete, so an interrupted run can be resumed
+	queue, err := s.openWorkDir(outputPath, startURL)
+	if err != nil {
+		return err
+	}
+	tmpDir := s.state.dir
+	completed := false
+	defer func() {
+		if s.stream != nil {
+			s.stream.abandon()
+		}
+		if !completed && len(s.pdfs) > 0 {
+			s.saveCheckpoint()
+			s.logf("The crawl state is kept in %s, run again with --resume to continue\n", tmpDir)
+			return
+		}
+		if err := os.RemoveAll(tmpDir); err != nil {
+			s.logf("Warning: failed to clean up work directory: %v\n", err)
+		}
+	}()
+
+	lost, err := s.startArchive(tmpDir)
+	if err != nil {
+		return err
+	}
+	queue = append(queue, lost...)
+	s.pagesTaken.Store(int64(len(s.pdfs)))
+	if s.OnProgress != nil {
+		defer s.startProgress()()
+	}
+
+	// Resumed requests carry their depth, which colly doesn't know about
+	maxDepth := s.MaxDepth
+	if s.Resume {
+		maxDepth = 0
+	}
+
+	// Initialize the collector
+	c := colly.NewCollector(
+		colly.MaxDepth(maxDepth),
+		colly.IgnoreRobotsTxt(),
+	)
+	if s.UserAgent != "" {
+		c.UserAgent = s.UserAgent
+	}
+	s.scopeCollector(c, parsedURL)
+
+	c.SetRequestTimeout(s.RequestTimeout)
+	s.useClient(c)
+	s.jar = c.Cookies
+	rule, err := s.limitRule()
+	if err != nil {
+		return err
+	}
+	if err := c.Limit(rule); err != nil {
+		return fmt.Errorf("invalid rate limit: %w", err)
+	}
+	c.Async = s.concurrent()
+	c.CacheDir = s.CacheDir
+	transport := s.transport()
+	if transport != nil {
+		c.WithTransport(transport)
+	}
+
+	s.Secrets = append(s.Secrets, s.requestSecrets()...)
+	s.setCookies(c, parsedURL)
+	if s.Session != nil {
+		if err := s.startSession(c, parsedURL); err != nil {
+			return err
+		}
+	}
+
+	if s.SelfContained || (s.embedsImages() && s.rendersAnyText()) {
+		base := transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		s.assets = newAssetFetcher(base, c.UserAgent, c.Cookies)
+		s.assets.imageDir = filepath.Join(tmpDir, "images")
+		s.assets.imageQuality = s.ImageQuality
+		s.assets.maxImageWidth = s.MaxImageWidth
+		if s.RotateUserAgents {
+			s.assets.nextUserAgent = s.nextUserAgent
+		}
+	}
+
+	checkRobots := s.RespectRobots || s.ComplianceReport
+	robots := newRobotsChecker()
+	if transport != nil {
+		robots.client.Transport = transport
+	}
+
+	c.OnRequest(func(r *colly.Request) {
+		if s.cancelled() != nil {
+			r.Abort()
+			return
+		}
+		s.setHeaders(r, parsedURL)
+		if s.RotateUserAgents {
+			r.Headers.Set("User-Agent", s.nextUserAgent())
+		}
+		if s.LogRequests {
+			s.logf("> %s %s\n", r.Method, r.URL)
+			s.logHeader(">   ", *r.Headers)
+		}
+		if !s.inScope(r.URL) {
+			r.Abort()
+			return
+		}
+		if s.PathPrefix != "" && !strings.HasPrefix(r.URL.Path, s.PathPrefix) {
+			s.event(event{Type: eventSkip, URL: r.URL.String(), Reason: "outside " + s.PathPrefix})
+			r.Abort()
+			return
+		}
+		if checkRobots && s.RespectRobots && !robots.allowed(r.URL, c.UserAgent) {
+			s.recordCompliance(complianceRecord{
+				URL:      r.URL.String(),
+				Decision: decisionSkipped,
+				Reason:   "disallowed by robots.txt",
+			})
+			s.event(event{Type: eventSkip, URL: r.URL.String(), Reason: "disallowed by robots.txt"})
+			r.Abort()
+			return
+		}
+		if s.Resume && (s.resumedDone(stripFragment(r.URL)) || (s.MaxDepth > 0 && depth(r) > s.MaxDepth)) {
+			r.Abort()
+			return
+		}
+		if s.estimate != nil && r.Ctx.Get(ctxVariantOf) == "" && !s.estimate.admit(stripFragment(r.URL).String()) {
+			r.Abort()
+			return
+		}
+		if r.Ctx.Get(ctxVariantOf) == "" && s.pageLimitReached() {
+			r.Abort()
+			return
+		}
+		if s.Shared != nil && r.Ctx.Get(ctxVariantOf) == "" && !s.Shared.claim(stripFragment(r.URL).String(), s.crawlID) && depth(r) > 1 {
+			s.event(event{Type: eventSkip, URL: r.URL.String(), Reason: "fetched by another crawl of the batch"})
+			r.Abort()
+			return
+		}
+		s.fetchStarted.Store(r.URL.String(), time.Now())
+		s.requested.Store(r.ID, r.URL.String())
+		s.event(event{Type: eventFetchStart, URL: r.URL.String()})
+	})
+
+	// Responses that aren't pages are dropped before their body is read
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if r.Ctx.Get(ctxVariantOf) != "" || r.StatusCode < 200 || r.StatusCode > 299 {
+			return
+		}
+		ct := r.Headers.Get("Content-Type")
+		if ct != "" && s.skipType(r.Request.URL, mediaType(ct)) {
+			r.Request.Abort()
+		}
+	})
+
+	// Handle each page
+	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		if _, skip := s.nofollow.Load(e.Request.URL.String()); skip {
+			return
+		}
+		if e.Request.Ctx.Get(ctxVariantOf) != "" {
+			return
+		}
+		link := e.Attr("href")
+		if len(s.Rewrites) > 0 {
+			if abs := e.Request.AbsoluteURL(link); abs != "" {
+				link = s.rewriteURL(abs)
+			}
+		}
+		s.recordFragment(e.Request.URL, link)
+		if s.LinkGraph != "" {
+			s.recordEdge(e.Request.URL, link, e.Text)
+		}
+		if s.events != nil {
+			if abs := e.Request.AbsoluteURL(link); abs != "" {
+				if _, seen := s.discovered.LoadOrStore(abs, true); !seen {
+					s.event(event{Type: eventDiscover, URL: abs, From: e.Request.URL.String()})
+				}
+			}
+		}
+		if len(s.Include) > 0 || len(s.Exclude) > 0 {
+			target, err := e.Request.URL.Parse(link)
+			if err != nil {
+				return
+			}
+			if !s.urlAllowed(target) {
+				if s.plan != nil {
+					s.plan.skip(stripFragment(target).String(), "excluded by URL patterns")
+				}
+				return
+			}
+		}
+		if s.CheckLinks {
+			s.recordLink(e.Request.URL, link)
+		}
+		target, err := e.Request.URL.Parse(link)
+		if err != nil {
+			return
+		}
+		if s.IncludeAttachments && isAttachment(target) {
+			s.recordAttachment(target, e.Request.URL)
+			return
+		}
+		if s.localRoot != "" {
+			target = s.localTarget(target)
+			link = target.String()
+		}
+		queued := stripFragment(target).String()
+		s.enqueue(queued, depth(e.Request)+1)
+		if err := e.Request.Visit(link); err != nil {
+			s.dequeue(queued)
+			// We can safely ignore the error here as it's usually due to:
+			// - Already visited URLs (handled by colly)
+			// - URLs outside allowed domain (handled by colly)
+			// - Malformed URLs (handled by colly)
+			return
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		if errors.Is(err, colly.ErrAbortedAfterHeaders) {
+			// Not a page, see skipType
+			s.finish(stripFragment(r.Request.URL).String())
+			return
+		}
+		if s.cancelled() != nil {
+			// Not a failure of the page, the crawl was interrupted
+			return
+		}
+		if s.retry(r, err) {
+			return
+		}
+		s.logf("Failed to fetch %s: %v\n", r.Request.URL, err)
+		if r.Ctx.Get(ctxVariantOf) == "" {
+			s.recordFetchFailure(r, err)
+			s.finish(stripFragment(r.Request.URL).String())
+		}
+		s.event(event{Type: eventError, URL: r.Request.URL.String(), Stage: "fetch", Status: r.StatusCode, Error: err.Error()})
+		if s.CheckLinks && r.Ctx.Get(ctxVariantOf) == "" {
+			s.recordBrokenLink(r.Request.URL, r.StatusCode, err)
+		}
+	})
+
+	c.OnResponse(func(r *colly.Response) {
+		if s.LogRequests {
+			s.logf("< %d %s\n", r.StatusCode, r.Request.URL)
+			s.logHeader("<   ", *r.Headers)
+		}
+		if s.events != nil {
+			e := event{Type: eventFetchEnd, URL: r.Request.URL.String(), Status: r.StatusCode}
+			if started, ok := s.fetchStarted.Load(r.Request.URL.String()); ok {
+				e.Duration = milliseconds(time.Since(started.(time.Time)))
+			}
+			s.event(e)
+		}
+
+		requested, _ := s.requested.LoadAndDelete(r.Request.ID)
+
+		// Variants are handed back to the canonical page that requested them
+		if r.Ctx.Get(ctxVariantOf) != "" {
+			body, _ := toUTF8(r.Body, r.Headers.Get("Content-Type"))
+			r.Ctx.Put("variantBody", body)
+			return
+		}
+
+		// Skip if already processed
+		if _, exists := s.visited.LoadOrStore(stripFragment(r.Request.URL).String(), true); exists {
+			return
+		}
+		mt := responseType(*r.Headers, r.Body)
+		if s.skipType(r.Request.URL, mt) {
+			return
+		}
+		if s.Evidence {
+			s.captureResponse(r, tmpDir)
+		}
+		sum := bodySHA256(r.Body)
+		// Links and content are read from the converted body from here on
+		var convertedFrom string
+		r.Body, convertedFrom = toUTF8(r.Body, r.Headers.Get("Content-Type"))
+		if s.renderSettings(r.Request.URL).RenderJS {
+			s.renderJS(r)
+		}
+		if !s.urlAllowed(r.Request.URL) {
+			// The start page or a redirect target: its links are still
+			// followed, but it is not archived
+			s.event(event{Type: eventSkip, URL: r.Request.URL.String(), Reason: "excluded by URL patterns"})
+			return
+		}
+		if rule := s.skipRule(*r.Headers, r.Body); rule != nil {
+			s.logf("Skipping %s: %s\n", r.Request.URL, rule.Expr)
+			s.event(event{Type: eventSkip, URL: r.Request.URL.String(), Reason: rule.Expr})
+			return
+		}
+
+		if checkRobots {
+			rec := complianceRecord{
+				URL:           r.Request.URL.String(),
+				RobotsAllowed: robots.allowed(r.Request.URL, c.UserAgent),
+				MetaRobots:    metaRobots(r.Body),
+				Decision:      decisionArchived,
+			}
+			switch {
+			case s.RespectRobots && hasDirective(rec.MetaRobots, "noarchive", "none"):
+				rec.Decision = decisionSkipped
+				rec.Reason = "meta robots noarchive"
+				s.recordCompliance(rec)
+				return
+			case s.RespectRobots && hasDirective(rec.MetaRobots, "nofollow"):
+				s.nofollow.Store(r.Request.URL.String(), true)
+				rec.Reason = "links not followed (meta robots nofollow)"
+			case !rec.RobotsAllowed:
+				rec.Reason = "robots.txt ignored"
+			}
+			s.recordCompliance(rec)
+		}
+
+		var change string
+		if s.baseline != nil {
+			if change = s.baselineChange(r.Request.URL.String(), sum, *r.Headers); change == "" {
+				// Its links are still followed
+				s.recordUnchanged(r.Request.URL.String())
+				return
+			}
+		}
+
+		p := &page{
+			URL:       r.Request.URL,
+			Status:    r.StatusCode,
+			Depth:     depth(r.Request),
+			FetchedAt: s.fetchedAt(r.Request.URL),
+			Header:    *r.Headers,
+			Body:      r.Body,
+			Title:     pageTitle(r.Body),
+			Canonical: canonicalURL(r.Body, r.Request.URL),
+			Published: publishedDate(r.Body, r.Request.URL),
+			Author:    s.redactContent(pageAuthor(r.Body, s.StreamThreshold)),
+			SHA256:    sum,
+			Change:    change,
+		}
+		// Files are named after the author with GroupByAuthor, so it is
+		// redacted before the page's text
+		s.recordAuthor(p)
+		// Create sanitized filename from URL, flat in the work directory
+		// whatever the output's layout
+		p.File = path.Join(tmpDir, strings.ReplaceAll(s.pageEntryName(r.Request.URL), "/", "_"))
+		if requested, ok := requested.(string); ok && requested != r.Request.URL.String() {
+			p.RequestedURL = requested
+		}
+		if convertedFrom != "" {
+			p.audit(transform{Kind: transformCharset, Rule: convertedFrom})
+		}
+		if !isHTML(mt) {
+			p.Body = wrapText(r.Body)
+		} else if s.cover != nil {
+			s.recordLogo(r.Body, r.Request.URL)
+		}
+		if s.StitchPages {
+			p.Next = relNext(r.Body, r.Request.URL)
+		}
+
+		if s.Order == OrderNav {
+			s.recordNav(p)
+		}
+		if s.PreferVariant != "" {
+			s.fetchVariant(c, p)
+		}
+
+		content, err := isolate(s.PageTimeout, func() (string, error) {
+			return s.pageContent(p)
+		})
+		if err != nil {
+			s.recordFailure(p, stageExtract, err)
+			return
+		}
+		p.Text = content
+		if len(s.RedactContent) > 0 {
+			s.redactPage(p)
+		}
+		if s.filtered(p) {
+			return
+		}
+		if !s.takePage() {
+			// Pages fetched while the last places were taken
+			s.event(event{Type: eventSkip, URL: p.URL.String(), Reason: "max pages reached"})
+			return
+		}
+		if s.plan != nil {
+			// A dry run stops short of rendering
+			s.plan.add(p)
+			return
+		}
+
+		// Cross-page analysis needs every page before anything is rendered
+		if s.deferRender() {
+			s.mu.Lock()
+			s.pending = append(s.pending, p)
+			s.mu.Unlock()
+			return
+		}
+
+		s.savePDF(p)
+	})
+
+	// A page is finished once its links were queued
+	c.OnScraped(func(r *colly.Response) {
+		if r.Ctx.Get(ctxVariantOf) == "" {
+			s.finish(stripFragment(r.Request.URL).String())
+		}
+	})
+
+	// Start scraping
+	if s.Resume {
+		s.resumeQueue(c, queue)
+	} else {
+		s.enqueue(startURL, 1)
+		for _, seed := range s.seeds {
+			s.enqueue(seed, 1)
+		}
+		if err := c.Visit(startURL); err != nil {
+			return fmt.Errorf("failed to start scraping: %w", err)
+		}
+		s.visitSeeds(c)
+	}
+	c.Wait()
+	if err := s.cancelled(); err != nil {
+		// The pages converted so far are archived, see ScrapeAndSaveContext
+		s.interrupted = true
+		if len(s.pdfs) == 0 && len(s.pending) == 0 {
+			return fmt.Errorf("%w before any page was converted: %w", ErrInterrupted, err)
+		}
+		s.logf("Interrupted, archiving the %d pages converted so far\n", len(s.pdfs)+len(s.pending))
+	}
+
+	if s.Session != nil {
+		if err := s.saveSession(c, parsedURL); err != nil {
+			s.logf("Warning: failed to save session: %v\n", err)
+		}
+	}
+	if s.plan != nil {
+		completed = true
+		s.event(event{Type: eventRunEnd, URL: startURL, Duration: milliseconds(time.Since(runStarted))})
+		return nil
+	}
+
+	if s.IncludeAttachments && s.estimate == nil && !s.interrupted {
+		base := transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		fetcher := newAssetFetcher(base, c.UserAgent, c.Cookies)
+		if s.RotateUserAgents {
+			fetcher.nextUserAgent = s.nextUserAgent
+		}
+		s.downloadAttachments(tmpDir, parsedURL, fetcher)
+	}
+
+	var extras []archiveEntry
+	if len(s.pending) > 0 {
+		if s.StitchPages {
+			before := len(s.pending)
+			s.pending = stitchPages(s.pending)
+			s.logf("Stitched %d paginated parts into their articles\n", before-len(s.pending))
+		}
+
+		if s.stripHTML && s.RepeatedBlocks > 0 {
+			report := removeRepeatedBlocks(s.pending, s.RepeatedBlocks)
+			s.logf("Removed %d repeated blocks across %d pages\n", len(report.Removed), report.Pages)
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to build repeated blocks report: %w", err)
+			}
+			extras = append(extras, archiveEntry{Name: "repeated-blocks.json", Data: data})
+		}
+
+		for _, p := range s.pending {
+			s.savePDF(p)
+		}
+	}
+
+	if s.estimate != nil {
+		// The sample is measured and thrown away with the work directory
+		s.measureSample()
+		completed = true
+		return nil
+	}
+
+	s.sortByNav()
+	s.sortByDate()
+	s.groupByAuthor()
+	s.disambiguateTitles()
+
+	// The pages of each section get their own, see createSections
+	if s.SplitBy == "" {
+		catalog, err := s.catalogEntries(startURL)
+		if err != nil {
+			return err
+		}
+		extras = append(extras, catalog...)
+	}
+	extras = append(extras, s.attachmentEntries()...)
+
+	if len(s.failures) > 0 {
+		data, err := s.errorsCSV()
+		if err != nil {
+			return fmt.Errorf("failed to build errors report: %w", err)
+		}
+		extras = append(extras, archiveEntry{Name: errorsEntryName, Data: data})
+	}
+
+	if s.CheckLinks {
+		data, err := s.brokenLinksCSV()
+		if err != nil {
+			return fmt.Errorf("failed to build broken links report: %w", err)
+		}
+		s.logf("Found %d broken links\n", len(s.brokenLinks))
+		extras = append(extras, archiveEntry{Name: "broken-links.csv", Data: data})
+	}
+
+	if s.LinkGraph != "" {
+		entry, err := s.linkGraphEntry()
+		if err != nil {
+			return fmt.Errorf("failed to build link graph: %w", err)
+		}
+		extras = append(extras, entry)
+	}
+
+	if s.events != nil {
+		s.event(event{Type: eventRunEnd, URL: startURL, Duration: milliseconds(time.Since(runStarted))})
+		if s.Events {
+			extras = append(extras, archiveEntry{Name: "events.jsonl", Data: s.eventsJSONL()})
+		}
+	}
+
+	if s.ComplianceReport {
+		data, err := s.complianceJSON(c.UserAgent)
+		if err != nil {
+			return fmt.Errorf("failed to build compliance report: %w", err)
+		}
+		extras = append(extras, archiveEntry{Name: "compliance.json", Data: data})
+	}
+
+	// Create the output only if we have PDFs to store
+	if len(s.pdfs) == 0 && s.baseline != nil {
+		return ErrNoChanges
+	}
+	if len(s.pdfs) == 0 {
+		return fmt.Errorf("no pages were successfully scraped")
+	}
+	s.outputs = []string{outputPath}
+	if s.SinglePDF {
+		if err := s.createSinglePDF(outputPath, startURL); err != nil {
+			return fmt.Errorf("failed to create PDF file: %w", err)
+		}
+		completed = true
+		return nil
+	}
+	if s.Format == FormatEPUB {
+		if err := s.createEPUB(outputPath, startURL); err != nil {
+			return fmt.Errorf("failed to create EPUB file: %w", err)
+		}
+		completed = true
+		return nil
+	}
+	if s.Evidence {
+		entries, err := s.evidenceEntries(startURL, runStartedAt, extras)
+		if err != nil {
+			return fmt.Errorf("failed to build evidence: %w", err)
+		}
+		extras = append(extras, entries...)
+	}
+	if s.SplitBy != "" {
+		if err := s.createSections(startURL, outputPath, extras); err != nil {
+			return err
+		}
+		completed = true
+		return nil
+	}
+	if s.writesTree() {
+		if err := s.createDir(outputPath, extras); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		completed = true
+		return nil
+	}
+	if s.writesTar() {
+		if err := s.createTar(outputPath, extras); err != nil {
+			return fmt.Errorf("failed to create tar file: %w", err)
+		}
+		completed = true
+		return nil
+	}
+	if err := s.createZip(outputPath, extras); err != nil {
+		return fmt.Errorf("failed to create ZIP file: %w", err)
+	}
+
+	completed = true
+	return nil
+}
+
+// transport returns the HTTP transport of the crawl, layering the
+// bandwidth cap, TLS recording, adaptive concurrency and per host
+// connection cap as configured over the transport of WithHTTPClient, or nil
+// when none is.
+func (s *Scraper) transport() http.RoundTripper {
+	transport := s.bandwidthTransport()
+	switch {
+	case s.WARC != nil:
+		transport = s.WARC
+	case s.Offline:
+		transport = offlineTransport{}
+	}
+	if transport == nil && s.client != nil && s.client.Transport != nil {
+		transport = s.client.Transport
+	}
+	if s.Evidence {
+		if transport == nil {
+			transport = s.baseTransport()
+		}
+		transport = &tlsRecorder{base: transport, seen: &s.tlsSeen}
+	}
+	if s.Adaptive {
+		if transport == nil {
+			transport = s.baseTransport()
+		}
+		transport = &adaptiveTransport{base: transport, limiter: newAdaptiveLimiter(s.adaptiveMax(), s.logf)}
+	}
+	if s.localRoot != "" {
+		transport = &fileTransport{base: transport, root: s.localRoot}
+	}
+	if s.MaxConnsPerHost > 0 {
+		// Outside the adaptive limiter, so waiting for the host doesn't
+		// count as a slow response
+		if transport == nil {
+			transport = s.baseTransport()
+		}
+		transport = newHostLimitTransport(transport, s.MaxConnsPerHost)
+	}
+	if s.ctx != nil {
+		if transport == nil {
+			transport = s.baseTransport()
+		}
+		transport = &contextTransport{base: transport, ctx: s.ctx}
+	}
+	return transport
+}
+
+// deferRender reports whether rendering must wait until the crawl finishes.
+func (s *Scraper) deferRender() bool {
+	return (s.stripHTML && s.RepeatedBlocks > 0) || s.StitchPages
+}
+
+// pageContent returns the text that ends up in the page's PDF.
+func (s *Scraper) pageContent(p *page) (string, error) {
+	if s.PipeHTML != "" {
+		if err := s.pipeHTML(p); err != nil {
+			return "", err
+		}
+	}
+	content := string(p.Body)
+	if s.stripHTML || s.convertsHTML() {
+		var err error
+		if content, err = s.extractText(p); err != nil {
+			return "", fmt.Errorf("failed to strip HTML tags: %w", err)
+		}
+	}
+	if s.PipeText != "" {
+		return s.pipeText(p, content)
+	}
+	return content, nil
+}
+
+// savePDF renders the page to its PDF file and records it for the archive.
+func (s *Scraper) savePDF(p *page) {
+	started := time.Now()
+	if err := s.renderPage(p); err != nil {
+		s.recordFailure(p, stageRender, err)
+		s.releasePage()
+		// Clean up the failed PDF file if it exists
+		if err := os.Remove(p.File); err != nil && !os.IsNotExist(err) {
+			s.logf("Warning: failed to clean up failed PDF file: %v\n", err)
+		}
+		return
+	}
+
+	if s.SelfContained {
+		s.saveSnapshot(p)
+	}
+	if s.stream != nil {
+		if err := s.streamPage(p); err != nil {
+			s.recordFailure(p, stageArchive, err)
+			s.releasePage()
+			return
+		}
+	}
+
+	// The raw body is no longer needed once the PDF is written; dropping it
+	// keeps memory flat on large crawls.
+	p.Body = nil
+
+	if s.estimate != nil {
+		s.estimate.render.Add(int64(time.Since(started)))
+	}
+	s.mu.Lock()
+	s.pdfs = append(s.pdfs, p)
+	s.mu.Unlock()
+	s.event(event{Type: eventRender, URL: p.URL.String(), Duration: milliseconds(time.Since(started))})
+	if s.OnProgress != nil {
+		return
+	}
+	switch s.Format {
+	case FormatMarkdown:
+		s.logf("Created Markdown for %s\n", p.URL)
+	case FormatEPUB:
+		s.logf("Created chapter for %s\n", p.URL)
+	default:
+		s.logf("Created PDF for %s\n", p.URL)
+	}
+}
+
+func (s *Scraper) createPDF(filename string, p *page) error {
+	t, err := LookupTypography(s.Typography)
+	if err != nil {
+		return err
+	}
+	pdf, err := s.newPDF(t)
+	if err != nil {
+		return err
+	}
+	s.drawHeaderFooter(pdf, func() *page { return p })
+	pdf.AddPageFormat(s.renderSettings(p.URL).pdfOrientation(), s.pageSize())
+	var keywords string
+	if s.StampSource {
+		// The file is the page's alone, so the document describes it too
+		pdf.SetSubject(sourceURL(p), false)
+		keywords = sourceKeywords(p)
+	}
+	stampMetadata(pdf, s.Meta, keywords)
+	s.writePage(pdf, p, t)
+
+	return pdf.OutputFileAndClose(filename)
+}
+
+// newPDF returns an empty document laid out with t on pages of PageSize
+// and Orientation, with its fonts registered.
+func (s *Scraper) newPDF(t Typography) (*gofpdf.Fpdf, error) {
+	orientation := renderSettings{Orientation: s.Orientation}.pdfOrientation()
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{OrientationStr: orientation, UnitStr: "mm", Size: s.pageSize()})
+	if err := s.addFonts(pdf); err != nil {
+		return nil, err
+	}
+	if s.Typography != "" {
+		// The default layout keeps gofpdf's own margins
+		t.apply(pdf)
+	}
+	s.applyMargins(pdf)
+	return pdf, nil
+}
+
+// writePage writes the page's text, stamped with its source when enabled,
+// from the current position of pdf.
+func (s *Scraper) writePage(pdf *gofpdf.Fpdf, p *page, t Typography) {
+	if s.StampSource {
+		s.stampSource(pdf, p)
+	}
+	pdf.SetFont(t.Font, "", t.FontSize)
+
+	// Split content into lines and write to PDF
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, _ := pdf.GetPageSize()
+	raw := !s.stripHTML && !s.convertsHTML()
+	lines := strings.Split(p.Text, "\n")
+	var links [][]linkSpan
+	var anchors [][]string
+	if !raw {
+		links = placeLinks(lines, p.Links)
+		if s.docLinks != nil {
+			anchors = placeAnchors(lines, p.Anchors)
+		}
+	}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if anchors != nil {
+			for _, u := range anchors[i] {
+				if link, ok := s.docLinks[u]; ok {
+					pdf.SetLink(link, -1, -1)
+				}
+			}
+		}
+		srcs := imageLines(line, raw)
+		switch {
+		case line == "" || (!raw && len(srcs) > 0):
+		case links != nil && len(links[i]) > 0:
+			s.writeLinkedLine(pdf, line, links[i], t.LineHeight)
+		default:
+			pdf.MultiCell(pageWidth-left-right, t.LineHeight, line, "0", "L", false)
+		}
+		if s.embedsImages() {
+			for _, src := range srcs {
+				s.writeImage(pdf, src, contentBase(p))
+			}
+		}
+	}
+}
+
+// stripHTMLTags removes HTML tags and extracts text content
+func stripHTMLTags(htmlContent string) (string, error) {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", err
+	}
+	return nodeText(doc), nil
+}
+
+// nodeText extracts the readable text below doc, laid out with blank lines
+// between block elements.
+func nodeText(doc *html.Node) string {
+	return layoutText(doc, false)
+}
+
+// layoutText extracts the text below doc like nodeText, with images as
+// lines of their own starting with imageMarker when images is set.
+func layoutText(doc *html.Node, images bool) string {
+	var textBuilder strings.Builder
+	var extractText func(*html.Node)
+	var lastNodeWasBlock bool
+	var lastNodeWasText bool
+
+	// List of styling tags that should not add newlines
+	stylingTags := map[string]bool{
+		"strong": true,
+		"b":      true,
+		"em":     true,
+		"i":      true,
+		"u":      true,
+		"span":   true,
+		"mark":   true,
+		"small":  true,
+		"sub":    true,
+		"sup":    true,
+		"code":   true,
+	}
+
+	extractText = func(n *html.Node) {
+		if n.Type == html.CommentNode ||
+			(n.Type == html.ElementNode && (n.Data == "script" ||
+				n.Data == "style" ||
+				n.Data == "meta" ||
+				n.Data == "link" ||
+				n.Data == "noscript")) {
+			return
+		}
+
+		if images && n.Type == html.ElementNode && n.Data == "img" {
+			if src := imageSource(n); src != "" {
+				textBuilder.WriteString("\n" + imageMarker + src + "\n")
+				lastNodeWasBlock = false
+				lastNodeWasText = false
+			}
+			return
+		}
+
+		if n.Type == html.ElementNode && stylingTags[n.Data] {
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				extractText(c)
+			}
+			return
+		}
+
+		if n.Type == html.TextNode {
+			var text string
+			if n.Parent != nil && stylingTags[n.Parent.Data] {
+				text = strings.TrimRightFunc(n.Data, unicode.IsSpace)
+			} else {
+				text = strings.TrimSpace(n.Data)
+			}
+
+			if text != "" {
+				if n.Parent != nil {
+					switch n.Parent.Data {
+					case "p":
+						textBuilder.WriteString(text)
+						textBuilder.WriteString("\n\n")
+						lastNodeWasBlock = true
+						lastNodeWasText = false
+					case "li":
+						textBuilder.WriteString("• ")
+						textBuilder.WriteString(text)
+						textBuilder.WriteString("\n")
+						lastNodeWasBlock = false
+						lastNodeWasText = false
+					case "h1", "h2", "h3", "h4", "h5", "h6":
+						if !lastNodeWasBlock {
+							textBuilder.WriteString("\n")
+						}
+						textBuilder.WriteString(text)
+						textBuilder.WriteString("\n\n")
+						lastNodeWasBlock = true
+						lastNodeWasText = false
+					case "a":
+						if lastNodeWasText {
+							textBuilder.WriteString("\n")
+						}
+						textBuilder.WriteString(text)
+						if n.Parent.Parent != nil && n.Parent.Parent.Data == "nav" && n.Parent.NextSibling == nil {
+							textBuilder.WriteString("\n")
+						}
+						lastNodeWasBlock = false
+						lastNodeWasText = true
+					default:
+						if lastNodeWasText {
+							textBuilder.WriteString(" ")
+						}
+						textBuilder.WriteString(text)
+						lastNodeWasBlock = false
+						lastNodeWasText = true
+					}
+				} else {
+					if lastNodeWasText {
+						textBuilder.WriteString(" ")
+					}
+					textBuilder.WriteString(text)
+					lastNodeWasText = true
+				}
+			}
+		}
+
+		// Process child nodes
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extractText(c)
+		}
+	}
+
+	extractText(doc)
+
+	// Clean up multiple consecutive newlines and ensure proper trailing newlines
+	content := textBuilder.String()
+	content = strings.ReplaceAll(content, "\n\n\n", "\n\n")
+	content = strings.TrimSpace(content)
+
+	// Ensure content ends with exactly two newlines for block elements
+	if !strings.HasSuffix(content, "\n\n") && content != "" {
+		if strings.HasSuffix(content, "\n") {
+			content += "\n"
+		} else {
+			content += "\n\n"
+		}
+	}
+
+	return content
+}
+
+// entryName returns the archive file name for the PDF of u, built from the
+// host and the URL path with slashes replaced by underscores.
+func entryName(u *url.URL) string {
+	urlPath := u.Path
+	if urlPath == "" || urlPath == "/" {
+		urlPath = "index"
+	}
+	urlPath = strings.Trim(urlPath, "/")
+	urlPath = strings.ReplaceAll(urlPath, "/", "_")
+
+	return fmt.Sprintf("%s_%s.pdf", u.Host, urlPath)
+}
+
+func (s *Scraper) createZip(zipname string, extras []archiveEntry) error {
+	if s.stream != nil {
+		return s.stream.finish(zipname, extras)
+	}
+	// The archive is written in the work directory with a recovery journal,
+	// and only moved to the output once complete
+	dir := workDirName(zipname)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	zipfile, err := os.Create(archiveFile(dir))
+	if err != nil {
+		return fmt.Errorf("failed to create zip file: %w", err)
+	}
+	defer zipfile.Close()
+
+	archive, err := newJournaledZip(zipfile, journalFile(dir))
+	if err != nil {
+		return err
+	}
+	defer archive.journal.Close()
+
+	for _, entry := range s.archiveEntries(extras) {
+		if err := writeZipEntry(archive, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	if err := zipfile.Close(); err != nil {
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	return os.Rename(archiveFile(dir), zipname)
+}
+
+// writeZipEntry adds entry to archive.
+func writeZipEntry(archive *journaledZip, entry archiveEntry) error {
+	writer, err := archive.Create(entry.Name)
+	if err != nil {
+		return fmt.Errorf("failed to create zip entry: %w", err)
+	}
+	if entry.File != "" {
+		if err := copyFile(writer, entry.File); err != nil {
+			return fmt.Errorf("failed to write to zip: %w", err)
+		}
+		return nil
+	}
+	if _, err := writer.Write(entry.Data); err != nil {
+		return fmt.Errorf("failed to write to zip: %w", err)
+	}
+	return nil
+}
+
+// catalogEntries returns the files describing the pages of the archive:
+// their thumbnails, search index, index.html, cover, table of contents and
+// manifest.
+func (s *Scraper) catalogEntries(startURL string) ([]archiveEntry, error) {
+	var entries []archiveEntry
+	for _, p := range s.pdfs {
+		if p.Thumb != nil {
+			entries = append(entries, archiveEntry{Name: s.thumbName(p), Data: p.Thumb})
+		}
+	}
+
+	if s.SearchIndex {
+		data, err := s.searchIndexJS()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build search index: %w", err)
+		}
+		entries = append(entries, archiveEntry{Name: searchIndexName, Data: data})
+	}
+
+	if s.Embeddings != nil {
+		// The pages are worth more than their embeddings
+		if data, err := s.embeddingsJSONL(); err != nil {
+			s.logf("Warning: failed to compute embeddings, leaving them out: %v\n", err)
+		} else {
+			entries = append(entries, archiveEntry{Name: embeddingsEntryName, Data: data})
+		}
+	}
+
+	index, err := s.indexHTML(startURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build index: %w", err)
+	}
+	entries = append(entries, archiveEntry{Name: "index.html", Data: index})
+
+	if s.cover != nil && !s.SinglePDF {
+		data, err := s.coverPDF(startURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build cover: %w", err)
+		}
+		entries = append(entries, archiveEntry{Name: coverEntryName, Data: data})
+	}
+
+	if s.writesTOC() && !s.SinglePDF {
+		data, err := s.tocPDF(startURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build table of contents: %w", err)
+		}
+		entries = append(entries, archiveEntry{Name: tocEntryName, Data: data})
+	}
+
+	data, err := s.manifestJSON(startURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build manifest: %w", err)
+	}
+	entries = append(entries, archiveEntry{Name: "manifest.json", Data: data})
+	return entries, nil
+}
+
+// archiveEntries returns every file of the archive: the cover, the page
+// files, each followed by its companions, then the other extras.
+func (s *Scraper) archiveEntries(extras []archiveEntry) []archiveEntry {
+	var entries, rest []archiveEntry
+	for _, entry := range extras {
+		if entry.Name == coverEntryName {
+			entries = append(entries, entry)
+		} else {
+			rest = append(rest, entry)
+		}
+	}
+	for _, p := range s.pdfs {
+		entries = append(entries, s.pageEntries(p)...)
+	}
+	return append(entries, rest...)
+}
+
+// pageEntries returns the files of p in the archive: its page file followed
+// by its companions.
+func (s *Scraper) pageEntries(p *page) []archiveEntry {
+	entries := []archiveEntry{{Name: s.pageEntryName(p.URL), File: p.File}}
+	return append(entries, s.companions(p)...)
+}
+
+// companions returns the files archived next to the PDF of p.
+func (s *Scraper) companions(p *page) []archiveEntry {
+	var files []archiveEntry
+	if p.ChromeFile != "" {
+		files = append(files, archiveEntry{Name: s.chromeEntryName(p.URL), File: p.ChromeFile})
+	}
+	if p.SnapshotFile != "" {
+		files = append(files, archiveEntry{Name: s.snapshotEntryName(p.URL), File: p.SnapshotFile})
+	}
+	return files
+}
+
+// copyFile writes the content of the file name to w.
+func copyFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
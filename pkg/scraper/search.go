@@ -0,0 +1,273 @@
+package scraper
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// searchIndexName is the archive entry of the search index. It is a script
+// rather than plain JSON so index.html can load it from disk, where browsers
+// block fetch.
+const searchIndexName = "search-index.js"
+
+// searchIndexPrefix and searchIndexSuffix wrap the JSON index in
+// search-index.js.
+const (
+	searchIndexPrefix = "const scrapdfSearchIndex = "
+	searchIndexSuffix = ";\n"
+)
+
+// searchDoc is a page of the search index.
+type searchDoc struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	File  string `json:"file"`
+	Text  string `json:"text"`
+}
+
+// searchIndex is an inverted index over the extracted text of an archive.
+type searchIndex struct {
+	Version int         `json:"version"`
+	Docs    []searchDoc `json:"docs"`
+	// Terms maps each term to the documents containing it, as pairs of
+	// document index and number of occurrences.
+	Terms map[string][][2]int `json:"terms"`
+}
+
+// tokenize splits text into lower-cased terms of letters and digits. Single
+// characters are dropped. index.html tokenizes queries the same way.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	terms := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) > 1 {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// buildSearchIndex indexes the title and text of docs.
+func buildSearchIndex(docs []searchDoc) *searchIndex {
+	idx := &searchIndex{Version: 1, Docs: docs, Terms: make(map[string][][2]int)}
+	for i, d := range docs {
+		counts := make(map[string]int)
+		for _, term := range tokenize(d.Title + " " + d.Text) {
+			counts[term]++
+		}
+		terms := make([]string, 0, len(counts))
+		for term := range counts {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+		for _, term := range terms {
+			idx.Terms[term] = append(idx.Terms[term], [2]int{i, counts[term]})
+		}
+	}
+	return idx
+}
+
+// searchText returns the plain text of an archived page for indexing.
+func (s *Scraper) searchText(p *page) string {
+	if s.stripHTML && s.Format != FormatEPUB {
+		return stripImageMarkers(p.Text)
+	}
+	text, err := stripHTMLTags(p.Text)
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// searchIndexJS builds search-index.js for the archived pages.
+func (s *Scraper) searchIndexJS() ([]byte, error) {
+	s.mu.Lock()
+	docs := make([]searchDoc, 0, len(s.pdfs))
+	for _, p := range s.pdfs {
+		docs = append(docs, searchDoc{
+			Title: p.Title,
+			URL:   s.Redact(p.URL.String()),
+			File:  s.pageEntryName(p.URL),
+			Text:  s.Redact(strings.Join(strings.Fields(s.searchText(p)), " ")),
+		})
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(buildSearchIndex(docs))
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(searchIndexPrefix), data...), searchIndexSuffix...), nil
+}
+
+// parseSearchIndex reads the index back from search-index.js.
+func parseSearchIndex(data []byte) (*searchIndex, error) {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte(searchIndexPrefix)) {
+		return nil, fmt.Errorf("not a search index")
+	}
+	data = bytes.TrimSuffix(bytes.TrimPrefix(data, []byte(searchIndexPrefix)), []byte(";"))
+	var idx searchIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("invalid search index: %w", err)
+	}
+	return &idx, nil
+}
+
+// SearchResult is a page matching a search query.
+type SearchResult struct {
+	Title   string  `json:"title,omitempty"`
+	URL     string  `json:"url"`
+	File    string  `json:"file"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// search returns the documents containing every term of query, best match
+// first, scored like the search box of index.html.
+func (idx *searchIndex) search(query string) []SearchResult {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var scores map[int]float64
+	for _, term := range terms {
+		postings := idx.Terms[term]
+		idf := math.Log(1 + float64(len(idx.Docs))/float64(max(1, len(postings))))
+		next := make(map[int]float64)
+		for _, p := range postings {
+			if prev, ok := scores[p[0]]; scores == nil || ok {
+				next[p[0]] = prev + float64(p[1])*idf
+			}
+		}
+		scores = next
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for i, score := range scores {
+		if i < 0 || i >= len(idx.Docs) {
+			continue
+		}
+		d := idx.Docs[i]
+		results = append(results, SearchResult{
+			Title:   d.Title,
+			URL:     d.URL,
+			File:    d.File,
+			Snippet: snippet(d.Text, terms[0]),
+			Score:   score,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].File < results[j].File
+	})
+	return results
+}
+
+// snippet returns the text around the first occurrence of term.
+func snippet(text, term string) string {
+	const context = 80
+	runes := []rune(text)
+	lower := strings.ToLower(text)
+	at := strings.Index(lower, term)
+	// Lower-casing can change the length of a few runes; offsets into the
+	// original are then unreliable and the start of the text is shown.
+	if at < 0 || utf8.RuneCountInString(lower) != len(runes) {
+		return string(runes[:min(len(runes), 2*context)])
+	}
+	at = utf8.RuneCountInString(lower[:at])
+	start := max(0, at-context)
+	end := min(len(runes), at+utf8.RuneCountInString(term)+context)
+	out := string(runes[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
+
+// SearchArchive searches the pages of a scrapdf archive, a ZIP file or an
+// output directory, for query. It uses the archive's search index and
+// otherwise indexes the text of its PDFs on the fly.
+func SearchArchive(archive, query string) ([]SearchResult, error) {
+	var files fs.FS
+	if info, err := os.Stat(archive); err == nil && info.IsDir() {
+		files = os.DirFS(archive)
+	} else {
+		r, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer r.Close()
+		files = r
+	}
+
+	idx, err := archiveSearchIndex(files)
+	if err != nil {
+		return nil, err
+	}
+	return idx.search(query), nil
+}
+
+// archiveSearchIndex loads the bundled index of an archive, or builds one
+// from the text of the PDFs and Markdown files listed in its manifest.
+func archiveSearchIndex(files fs.FS) (*searchIndex, error) {
+	if data, err := fs.ReadFile(files, searchIndexName); err == nil {
+		return parseSearchIndex(data)
+	}
+
+	data, err := fs.ReadFile(files, "manifest.json")
+	if err != nil {
+		return nil, fmt.Errorf("archive has neither a search index nor a manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	docs := make([]searchDoc, 0, len(m.Pages))
+	for _, p := range m.Pages {
+		data, err := fs.ReadFile(files, p.File)
+		if err != nil {
+			continue
+		}
+		text := string(data)
+		if path.Ext(p.File) != ".md" {
+			text = pdfText(data)
+		}
+		docs = append(docs, searchDoc{
+			URL:  p.URL,
+			File: p.File,
+			Text: strings.Join(strings.Fields(text), " "),
+		})
+	}
+	return buildSearchIndex(docs), nil
+}
+
+// readZipEntry returns the content of the named archive entry.
+func readZipEntry(r *zip.Reader, name string) ([]byte, error) {
+	f, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
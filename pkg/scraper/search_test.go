@@ -0,0 +1,126 @@
+package scraper
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"a b cd", []string{"cd"}},
+		{"Größe über 42km", []string{"größe", "über", "42km"}},
+		{"snake_case-and.dots", []string{"snake", "case", "and", "dots"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSearchIndex(t *testing.T) {
+	idx := buildSearchIndex([]searchDoc{
+		{Title: "Install", Text: "Install the tool. Install it again."},
+		{Title: "Usage", Text: "Run the tool"},
+	})
+
+	tests := []struct {
+		term string
+		want [][2]int
+	}{
+		{"install", [][2]int{{0, 3}}},
+		{"tool", [][2]int{{0, 1}, {1, 1}}},
+		{"usage", [][2]int{{1, 1}}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := idx.Terms[tt.term]; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms[%q] = %v, want %v", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestSearchIndexJSRoundTrip(t *testing.T) {
+	u, _ := url.Parse("https://example.com/guide?token=secret")
+	s := NewScraper(false, false)
+	s.pdfs = []*page{{URL: u, Title: "Guide", Text: "<html><body><p>Read the <b>guide</b></p><script>x()</script></body></html>"}}
+
+	data, err := s.searchIndexJS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := parseSearchIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Docs) != 1 {
+		t.Fatalf("got %d docs", len(idx.Docs))
+	}
+	doc := idx.Docs[0]
+	if doc.Text != "Read the guide" {
+		t.Errorf("text = %q, want the page text without markup", doc.Text)
+	}
+	if doc.URL != "https://example.com/guide?token=REDACTED" {
+		t.Errorf("url = %q, want credentials masked", doc.URL)
+	}
+	if _, ok := idx.Terms["guide"]; !ok {
+		t.Error("term guide not indexed")
+	}
+
+	if _, err := parseSearchIndex([]byte("{}")); err == nil {
+		t.Error("parseSearchIndex accepted plain JSON")
+	}
+}
+
+func TestSearchIndexSearch(t *testing.T) {
+	idx := buildSearchIndex([]searchDoc{
+		{Title: "Install", File: "install.pdf", Text: "Install the tool with the package manager."},
+		{Title: "Usage", File: "usage.pdf", Text: "Run the tool. The tool prints a report."},
+		{Title: "About", File: "about.pdf", Text: "Who wrote this."},
+	})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"tool", []string{"usage.pdf", "install.pdf"}},
+		{"TOOL package", []string{"install.pdf"}},
+		{"tool nowhere", []string{}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := []string{}
+			for _, r := range idx.search(tt.query) {
+				got = append(got, r.File)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("x", 100) + " needle " + strings.Repeat("y", 100)
+	tests := []struct {
+		text, term, want string
+	}{
+		{"short text with needle", "needle", "short text with needle"},
+		{long, "needle", "…" + strings.Repeat("x", 79) + " needle " + strings.Repeat("y", 79) + "…"},
+	}
+	for _, tt := range tests {
+		if got := snippet(tt.text, tt.term); got != tt.want {
+			t.Errorf("snippet() = %q, want %q", got, tt.want)
+		}
+	}
+}
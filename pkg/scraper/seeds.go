@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// parseSeeds keeps the Seeds without their fragments, rejecting those
+// outside the site crawled from start: its host, or with IncludeSubdomains
+// its domain.
+func (s *Scraper) parseSeeds(start *url.URL) error {
+	s.seeds = nil
+	for _, seed := range s.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			return fmt.Errorf("invalid start URL %q: %w", seed, err)
+		}
+		if start.Scheme == "file" {
+			return fmt.Errorf("start URL %s: a local tree is crawled from its directory alone", seed)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid start URL %q: only http and https URLs can be crawled", seed)
+		}
+		switch {
+		case u.Hostname() == start.Hostname():
+		case s.IncludeSubdomains && inDomain(u.Hostname(), siteDomain(start.Hostname())):
+		case s.IncludeSubdomains:
+			return fmt.Errorf("start URL %s is outside %s, archive other sites separately", seed, siteDomain(start.Hostname()))
+		default:
+			return fmt.Errorf("start URL %s is on another host than %s, archive other sites separately or crawl the hosts of a domain with --include-subdomains", seed, start.Host)
+		}
+		s.seeds = append(s.seeds, stripFragment(u).String())
+	}
+	return nil
+}
+
+// visitSeeds crawls the seeds after the start page. Seeds the crawl already
+// reached from an earlier start page are skipped; seeds the collector
+// refuses, e.g. excluded ones, are reported without failing the run.
+func (s *Scraper) visitSeeds(c *colly.Collector) {
+	for _, seed := range s.seeds {
+		if err := c.Visit(seed); err != nil {
+			s.dequeue(seed)
+			if errors.Is(err, colly.ErrAlreadyVisited) {
+				continue
+			}
+			s.logf("Warning: skipping start URL %s: %v\n", s.Redact(seed), err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSeeds(t *testing.T) {
+	srv := linkedSite(40, 1)
+	defer srv.Close()
+
+	// Each seed is crawled to MaxDepth like the start page; /p1 is reached
+	// from the start page already
+	s := NewScraper(true, false)
+	s.MaxDepth = 2
+	s.Seeds = []string{srv.URL + "/p20#intro", srv.URL + "/p1", srv.URL + "/p30"}
+	zipname := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", zipname); err != nil {
+		t.Fatal(err)
+	}
+	if got := archivedPDFs(t, zipname); got != 6 {
+		t.Errorf("archived %d PDFs, want 6", got)
+	}
+	manifest := zipEntries(t, zipname)["manifest.json"]
+	if !strings.Contains(manifest, srv.URL+"/p20\"") || strings.Contains(manifest, "#intro") {
+		t.Errorf("manifest seeds not listed without fragments:\n%s", manifest)
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	start, _ := url.Parse("https://docs.example.com/")
+	tests := []struct {
+		seed       string
+		subdomains bool
+		wantErr    bool
+	}{
+		{"https://docs.example.com/guide", false, false},
+		{"http://docs.example.com/guide", false, false},
+		{"https://api.example.com/", false, true},
+		{"https://api.example.com/", true, false},
+		{"https://example.org/", true, true},
+		{"ftp://docs.example.com/", false, true},
+		{"docs.example.com/guide", false, true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.IncludeSubdomains = tt.subdomains
+		s.Seeds = []string{tt.seed}
+		if err := s.parseSeeds(start); (err != nil) != tt.wantErr {
+			t.Errorf("parseSeeds(%q, subdomains %v) error = %v, wantErr %v", tt.seed, tt.subdomains, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package scraper
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// sessionKeyEnv holds a passphrase used instead of the generated key file to
+// encrypt saved sessions.
+const sessionKeyEnv = "SCRAPDF_SESSION_KEY"
+
+// saltSize is the length of the random salt a session file starts with. The
+// key is derived from the passphrase and the salt with scrypt.
+const saltSize = 16
+
+// sessionName is what a session name may contain, so its file stays in Dir.
+var sessionName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Session persists the cookies of an authenticated crawl between runs,
+// encrypted at rest. The login form is only submitted again when the saved
+// session has expired.
+type Session struct {
+	// Name identifies the saved session, e.g. the site it logs into.
+	Name string
+	// Dir holds the encrypted session files and the key file.
+	Dir string
+	// LoginURL is where LoginFields are posted to log in.
+	LoginURL string
+	// LoginFields are the form fields of the login request, such as the
+	// user name and password.
+	LoginFields map[string]string
+}
+
+// savedSession is the decrypted content of a session file.
+type savedSession struct {
+	SavedAt time.Time      `json:"saved_at"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
+// DefaultSessionDir returns the directory sessions are saved in.
+func DefaultSessionDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "scrapdf", "sessions")
+}
+
+// checkName rejects session names that aren't a plain file name, such as
+// "../other", which would read and write outside Dir.
+func (sess *Session) checkName() error {
+	if !sessionName.MatchString(sess.Name) {
+		return fmt.Errorf("invalid session name %q: use letters, digits, '.', '_' and '-'", sess.Name)
+	}
+	return nil
+}
+
+// file returns the path of the session's encrypted file.
+func (sess *Session) file() string {
+	return filepath.Join(sess.Dir, sess.Name+".session")
+}
+
+// key returns the encryption key: derived from $SCRAPDF_SESSION_KEY and salt
+// when set, otherwise read from (or created in) a key file only the user can
+// read.
+func (sess *Session) key(salt []byte) ([]byte, error) {
+	if passphrase := os.Getenv(sessionKeyEnv); passphrase != "" {
+		return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	}
+
+	keyFile := filepath.Join(sess.Dir, "session.key")
+	key, err := os.ReadFile(keyFile)
+	if err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read session key: %w", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(sess.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(keyFile, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write session key: %w", err)
+	}
+	return key, nil
+}
+
+// load returns the saved session, or nil if there is none.
+func (sess *Session) load() (*savedSession, error) {
+	if err := sess.checkName(); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(sess.file())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	if len(data) < saltSize {
+		return nil, fmt.Errorf("session file %s is truncated", sess.Name)
+	}
+	key, err := sess.key(data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decrypt(key, data[saltSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt session %s (was the key changed?): %w", sess.Name, err)
+	}
+	var saved savedSession
+	if err := json.Unmarshal(plain, &saved); err != nil {
+		return nil, fmt.Errorf("invalid session %s: %w", sess.Name, err)
+	}
+	return &saved, nil
+}
+
+// save encrypts and writes cookies as the session, after a fresh salt.
+func (sess *Session) save(cookies []*http.Cookie) error {
+	if err := sess.checkName(); err != nil {
+		return err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := sess.key(salt)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(savedSession{SavedAt: time.Now().UTC(), Cookies: cookies})
+	if err != nil {
+		return err
+	}
+	sealed, err := encrypt(key, plain)
+	if err != nil {
+		return err
+	}
+	data := append(salt, sealed...)
+
+	tmp := sess.file() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return os.Rename(tmp, sess.file())
+}
+
+// expired reports whether a saved session can't be reused: it has no
+// cookies, or one of them has passed its expiry date.
+func (saved *savedSession) expired(now time.Time) bool {
+	if saved == nil || len(saved.Cookies) == 0 {
+		return true
+	}
+	for _, c := range saved.Cookies {
+		if !c.Expires.IsZero() && c.Expires.Before(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// encrypt seals plain with AES-GCM, prefixing the random nonce.
+func encrypt(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// decrypt opens data sealed by encrypt.
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("session file is truncated")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// startSession restores the saved session into the collector's cookie jar,
+// logging in again when it is missing or expired. A saved session is also
+// replaced when the start page redirects to the login page.
+func (s *Scraper) startSession(c *colly.Collector, start *url.URL) error {
+	sess := s.Session
+	saved, err := sess.load()
+	if err != nil {
+		return err
+	}
+
+	if !saved.expired(time.Now()) {
+		c.SetCookies(start.String(), saved.Cookies)
+		// Saved again after the crawl, the cookies keep their expiry
+		for _, cookie := range saved.Cookies {
+			s.setCookieExpiry(cookie.Name, cookie.Expires)
+		}
+		if !s.sessionRejected(c, start) {
+			s.logf("Reusing saved session %s from %s\n", sess.Name, saved.SavedAt.Format(time.RFC3339))
+			return nil
+		}
+		s.logf("Saved session %s is no longer accepted\n", sess.Name)
+	}
+	return s.login(c)
+}
+
+// sessionRejected fetches the start page and reports whether the site sent
+// it to the login page instead.
+func (s *Scraper) sessionRejected(c *colly.Collector, start *url.URL) bool {
+	login, err := url.Parse(s.Session.LoginURL)
+	if err != nil {
+		return true
+	}
+	// Synchronous, so the result is known when Visit returns even when the
+	// crawl's collector is async
+	probe := c.Clone()
+	probe.Async = false
+	probe.AllowURLRevisit = true
+	rejected := false
+	probe.OnResponse(func(r *colly.Response) {
+		rejected = r.Request.URL.Host == login.Host && r.Request.URL.Path == login.Path
+	})
+	probe.OnError(func(r *colly.Response, err error) {
+		rejected = r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden
+	})
+	_ = probe.Visit(start.String())
+	return rejected
+}
+
+// login posts the login form. The cookies it sets land in the collector's
+// shared cookie jar.
+func (s *Scraper) login(c *colly.Collector) error {
+	sess := s.Session
+	if sess.LoginURL == "" {
+		return fmt.Errorf("session %s has expired and no login URL is configured", sess.Name)
+	}
+	s.logf("Logging in at %s\n", sess.LoginURL)
+
+	form := c.Clone()
+	form.Async = false
+	form.AllowURLRevisit = true
+	form.OnResponse(func(r *colly.Response) {
+		s.recordCookieExpiry(*r.Headers)
+	})
+	var loginErr error
+	form.OnError(func(r *colly.Response, err error) {
+		loginErr = fmt.Errorf("login failed: %d %w", r.StatusCode, err)
+	})
+	if err := form.Post(sess.LoginURL, sess.LoginFields); err != nil && loginErr == nil {
+		loginErr = fmt.Errorf("login failed: %w", err)
+	}
+	return loginErr
+}
+
+// saveSession stores the cookies the collector holds for the site.
+func (s *Scraper) saveSession(c *colly.Collector, start *url.URL) error {
+	cookies := c.Cookies(start.String())
+	if len(cookies) == 0 {
+		return nil
+	}
+	// The jar only hands out names and values; add the expiry dates seen at
+	// login. Other cookies stay valid until the site rejects them.
+	s.mu.Lock()
+	for _, cookie := range cookies {
+		cookie.Path = "/"
+		cookie.Expires = s.cookieExpiry[cookie.Name]
+	}
+	s.mu.Unlock()
+	return s.Session.save(cookies)
+}
+
+// recordCookieExpiry remembers when the cookies set by a response expire.
+func (s *Scraper) recordCookieExpiry(h http.Header) {
+	now := time.Now()
+	for _, cookie := range (&http.Response{Header: h}).Cookies() {
+		expires := cookie.Expires
+		if cookie.MaxAge > 0 {
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+		s.setCookieExpiry(cookie.Name, expires)
+	}
+}
+
+// setCookieExpiry remembers when the cookie name expires, unless never.
+func (s *Scraper) setCookieExpiry(name string, expires time.Time) {
+	if expires.IsZero() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cookieExpiry == nil {
+		s.cookieExpiry = make(map[string]time.Time)
+	}
+	s.cookieExpiry[name] = expires
+}
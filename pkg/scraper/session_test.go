@@ -0,0 +1,223 @@
+package scraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+func TestSessionSaveLoad(t *testing.T) {
+	sess := &Session{Name: "site", Dir: t.TempDir()}
+	cookies := []*http.Cookie{{Name: "sid", Value: "secret-value", Path: "/"}}
+	if err := sess.save(cookies); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(sess.file())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-value") {
+		t.Error("session stored in clear text")
+	}
+
+	saved, err := sess.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Cookies) != 1 || saved.Cookies[0].Value != "secret-value" {
+		t.Errorf("load() cookies = %v", saved.Cookies)
+	}
+
+	t.Setenv(sessionKeyEnv, "another key")
+	if _, err := sess.load(); err == nil {
+		t.Error("load() decrypted the session with the wrong key")
+	}
+}
+
+func TestSessionPassphrase(t *testing.T) {
+	t.Setenv(sessionKeyEnv, "correct horse")
+	sess := &Session{Name: "site", Dir: t.TempDir()}
+	cookies := []*http.Cookie{{Name: "sid", Value: "secret-value", Path: "/"}}
+	if err := sess.save(cookies); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(sess.file())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sess.save(cookies); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(sess.file())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first[:saltSize]) == string(second[:saltSize]) {
+		t.Error("session saved twice with the same salt")
+	}
+
+	saved, err := sess.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Cookies) != 1 || saved.Cookies[0].Value != "secret-value" {
+		t.Errorf("load() cookies = %v", saved.Cookies)
+	}
+	if _, err := os.Stat(filepath.Join(sess.Dir, "session.key")); !os.IsNotExist(err) {
+		t.Error("key file created although a passphrase is set")
+	}
+}
+
+func TestSessionName(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"../escape", "a/b", "", ".hidden"} {
+		sess := &Session{Name: name, Dir: filepath.Join(dir, "sessions")}
+		if err := sess.save([]*http.Cookie{{Name: "sid", Value: "v"}}); err == nil {
+			t.Errorf("save() accepted session name %q", name)
+		}
+		if _, err := sess.load(); err == nil {
+			t.Errorf("load() accepted session name %q", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.session")); !os.IsNotExist(err) {
+		t.Error("session written outside its directory")
+	}
+}
+
+func TestSessionLoadMissing(t *testing.T) {
+	sess := &Session{Name: "none", Dir: t.TempDir()}
+	saved, err := sess.load()
+	if err != nil || saved != nil {
+		t.Errorf("load() = %v, %v, want nil, nil", saved, err)
+	}
+	if _, err := os.Stat(filepath.Join(sess.Dir, "session.key")); !os.IsNotExist(err) {
+		t.Error("key file created without a session to read")
+	}
+}
+
+func TestSavedSessionExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		saved *savedSession
+		want  bool
+	}{
+		{"no session", nil, true},
+		{"no cookies", &savedSession{}, true},
+		{"session cookie", &savedSession{Cookies: []*http.Cookie{{Name: "sid"}}}, false},
+		{"valid", &savedSession{Cookies: []*http.Cookie{{Name: "sid", Expires: now.Add(time.Hour)}}}, false},
+		{"expired", &savedSession{Cookies: []*http.Cookie{{Name: "sid", Expires: now.Add(-time.Hour)}}}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.saved.expired(now); got != tt.want {
+			t.Errorf("%s: expired() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStartSession(t *testing.T) {
+	logins := 0
+	valid := "first"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/login":
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: valid, Path: "/", MaxAge: 3600})
+			w.Write([]byte("welcome"))
+		case r.URL.Path == "/login":
+			w.Write([]byte("<form></form>"))
+		default:
+			if c, err := r.Cookie("sid"); err != nil || c.Value != valid {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			w.Write([]byte("members"))
+		}
+	}))
+	defer srv.Close()
+	start, _ := url.Parse(srv.URL + "/")
+	dir := t.TempDir()
+
+	run := func() {
+		t.Helper()
+		s := NewScraper(false, false)
+		s.Session = &Session{Name: "test", Dir: dir, LoginURL: srv.URL + "/login", LoginFields: map[string]string{"password": "pw"}}
+		c := colly.NewCollector()
+		if err := s.startSession(c, start); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.saveSession(c, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run()
+	if logins != 1 {
+		t.Fatalf("first run: %d logins, want 1", logins)
+	}
+	run()
+	if logins != 1 {
+		t.Errorf("second run logged in again instead of reusing the session")
+	}
+	// Saved again by the second run, the cookie keeps its expiry
+	saved, err := (&Session{Name: "test", Dir: dir}).load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Cookies) != 1 || saved.Cookies[0].Expires.IsZero() {
+		t.Errorf("reused session saved as %+v, want the cookie's expiry", saved.Cookies)
+	}
+	valid = "rotated"
+	run()
+	if logins != 2 {
+		t.Errorf("rejected session not replaced: %d logins, want 2", logins)
+	}
+}
+
+func TestStartSessionAsync(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.FormValue("password") != "pw":
+			http.Error(w, "wrong password", http.StatusUnauthorized)
+		case r.Method == http.MethodPost:
+			time.Sleep(50 * time.Millisecond)
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "ok", Path: "/"})
+			w.Write([]byte("welcome"))
+		default:
+			w.Write([]byte("members"))
+		}
+	}))
+	defer srv.Close()
+	start, _ := url.Parse(srv.URL + "/")
+
+	for _, password := range []string{"pw", "wrong"} {
+		s := NewScraper(false, false)
+		s.Session = &Session{Name: "test", Dir: t.TempDir(), LoginURL: srv.URL + "/login", LoginFields: map[string]string{"password": password}}
+		// As set up for --parallelism 4
+		c := colly.NewCollector(colly.Async(true))
+		if err := c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: 4}); err != nil {
+			t.Fatal(err)
+		}
+		err := s.startSession(c, start)
+		if password != "pw" {
+			if err == nil {
+				t.Error("failed login not reported with an async collector")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cookies := c.Cookies(start.String()); len(cookies) != 1 || cookies[0].Value != "ok" {
+			t.Errorf("cookies after login = %v, want the session cookie", cookies)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package scraper
+
+import (
+	"net/url"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// bookmarkTitle is the outline entry of p in a single PDF: its title, told
+// apart from pages sharing it, or its URL when it has none.
+func (s *Scraper) bookmarkTitle(p *page) string {
+	if title := s.displayTitle(p); title != "" {
+		return title
+	}
+	return s.Redact(p.URL.String())
+}
+
+// createSinglePDF writes the text of every archived page to filename, each
+// starting on a new page with a bookmark, in archive order, after the cover
+// and a table of contents linking to them. Links between the pages, and to
+// the elements of a page other pages link to, jump within the document.
+func (s *Scraper) createSinglePDF(filename, startURL string) error {
+	t, err := LookupTypography(s.Typography)
+	if err != nil {
+		return err
+	}
+	pdf, err := s.newPDF(t)
+	if err != nil {
+		return err
+	}
+	stampMetadata(pdf, s.Meta, "")
+	var current *page
+	s.drawHeaderFooter(pdf, func() *page { return current })
+	// Bookmarks are encoded for the current font, which must be a UTF-8
+	// one from the first page on
+	pdf.SetFont(t.Font, "", t.FontSize)
+	if s.cover != nil {
+		cover, err := s.coverPage(startURL)
+		if err != nil {
+			return err
+		}
+		pdf.AddPage()
+		pdf.Bookmark(cover.Title, 0, -1)
+		s.writeCover(pdf, cover, t)
+	}
+	starts := s.addDocLinks(pdf)
+	defer func() { s.docLinks = nil }()
+	var links []int
+	if s.writesTOC() {
+		links = starts
+		pdf.AddPage()
+		pdf.Bookmark("Contents", 0, -1)
+		s.writeTOC(pdf, t, startURL, links)
+	}
+	for i, p := range s.pdfs {
+		current = p
+		pdf.AddPageFormat(s.renderSettings(p.URL).pdfOrientation(), s.pageSize())
+		level := 0
+		if s.GroupByAuthor {
+			// Pages are listed under their author
+			if heading := s.authorHeading(i); heading != "" {
+				pdf.Bookmark(heading, 0, -1)
+			}
+			level = 1
+		}
+		pdf.Bookmark(s.bookmarkTitle(p), level, -1)
+		// Elements not found in the text are linked to the page's start
+		pdf.SetLink(starts[i], 0, -1)
+		for _, u := range s.pageURLs(p) {
+			for _, f := range s.linkedFragments(u) {
+				pdf.SetLink(s.docLinks[u+"#"+f], 0, -1)
+			}
+		}
+		s.writePage(pdf, p, t)
+	}
+	return pdf.OutputFileAndClose(filename)
+}
+
+// addDocLinks adds the internal links of the single PDF to pdf: one to the
+// start of every archived page, returned in archive order, and one to
+// every element of a page that other pages link to.
+func (s *Scraper) addDocLinks(pdf *gofpdf.Fpdf) []int {
+	s.docLinks = make(map[string]int)
+	starts := make([]int, len(s.pdfs))
+	for i, p := range s.pdfs {
+		starts[i] = pdf.AddLink()
+		for _, u := range s.pageURLs(p) {
+			s.docLinks[u] = starts[i]
+			for _, f := range s.linkedFragments(u) {
+				s.docLinks[u+"#"+f] = pdf.AddLink()
+			}
+		}
+	}
+	return starts
+}
+
+// pageURLs returns the URL of p and of the parts stitched into it, without
+// their fragments.
+func (s *Scraper) pageURLs(p *page) []string {
+	urls := []string{stripFragment(p.URL).String()}
+	for _, part := range p.Parts {
+		if u, err := url.Parse(part); err == nil {
+			urls = append(urls, stripFragment(u).String())
+		}
+	}
+	return urls
+}
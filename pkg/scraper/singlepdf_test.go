@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCreateSinglePDF(t *testing.T) {
+	s := NewScraper(true, false)
+	home, _ := url.Parse("https://example.com/")
+	guide, _ := url.Parse("https://example.com/guide?token=abc")
+	s.pdfs = []*page{
+		{URL: home, Title: "Welcome", Text: "First page text"},
+		{URL: guide, Text: "Second page text"},
+	}
+
+	out := filepath.Join(t.TempDir(), "example.com.pdf")
+	if err := s.createSinglePDF(out, "https://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The table of contents comes first
+	if got := bytes.Count(data, []byte("/Type /Page\n")); got != 3 {
+		t.Errorf("got %d pages, want 3", got)
+	}
+	text := pdfText(data)
+	for _, want := range []string{"First page text", "Second page text"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF text missing %q", want)
+		}
+	}
+	// Bookmark titles are UTF-16 strings
+	for _, want := range []string{"Contents", "Welcome", "https://example.com/guide?token=REDACTED"} {
+		title := []byte("/Title (\xfe\xff")
+		for _, r := range want {
+			title = append(title, 0, byte(r))
+		}
+		if !bytes.Contains(data, title) {
+			t.Errorf("PDF missing bookmark %s", want)
+		}
+	}
+}
+
+func TestSinglePDFInternalLinks(t *testing.T) {
+	s := NewScraper(true, false)
+	home, _ := url.Parse("https://example.com/")
+	guide, _ := url.Parse("https://example.com/guide")
+	s.recordFragment(home, "/guide#install")
+	s.pdfs = []*page{
+		{URL: home, Text: "Read the install steps", Links: []pageLink{{"install steps", "https://example.com/guide#install"}}},
+		{URL: guide, Text: "Intro\n\nInstall\n\nRun the installer", Anchors: []pageAnchor{{"Install", "https://example.com/guide#install"}}},
+	}
+
+	out := filepath.Join(t.TempDir(), "example.com.pdf")
+	if err := s.createSinglePDF(out, "https://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("/URI (https://example.com/guide#install)")) {
+		t.Error("link to an archived page leaves the document")
+	}
+	// The guide's page is jumped to at its top from the contents, at its
+	// bookmark, and at the element from the link
+	dests := regexp.MustCompile(`/Dest \[(\d+) 0 R /XYZ 0 ([\d.]+) null\]`).FindAllSubmatch(data, -1)
+	if len(dests) < 2 {
+		t.Fatalf("got %d internal links, want the contents' and the link's", len(dests))
+	}
+	guidePage := string(dests[1][1])
+	ys := make(map[string]bool)
+	for _, d := range dests {
+		if string(d[1]) == guidePage {
+			ys[string(d[2])] = true
+		}
+	}
+	if len(ys) != 3 {
+		t.Errorf("destinations on the guide's page at %v, want its top, its bookmark and the element", ys)
+	}
+	if s.docLinks != nil {
+		t.Error("internal links kept after the single PDF")
+	}
+}
@@ -0,0 +1,52 @@
+package scraper
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"64KB", 64 << 10, false},
+		{"8mb", 8 << 20, false},
+		{"1.5M", 3 << 19, false},
+		{"2 GiB", 2 << 30, false},
+		{"100B", 100, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-1KB", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		in   uint64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{64 << 10, "64.0KB"},
+		{3 << 19, "1.5MB"},
+		{5 << 30, "5.0GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.in); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
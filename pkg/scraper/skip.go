@@ -0,0 +1,159 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Operators of skip rules.
+const (
+	skipEquals    = "=="
+	skipNotEquals = "!="
+	skipContains  = "contains"
+	skipMatches   = "matches"
+	skipExists    = "exists"
+)
+
+// SkipRule skips pages whose response header or meta tag matches, e.g.
+// "header X-Robots-Tag contains noarchive" or "meta og:type == product".
+type SkipRule struct {
+	Expr   string
+	Source string // "header" or "meta"
+	Name   string
+	Op     string
+	Value  string
+	re     *regexp.Regexp
+}
+
+// ParseSkipRule parses "<header|meta> <name> <op> <value>", where op is one
+// of ==, !=, contains, matches (a regular expression) or exists, which
+// takes no value. Comparisons other than matches ignore case.
+func ParseSkipRule(expr string) (SkipRule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) < 3 {
+		return SkipRule{}, fmt.Errorf("invalid skip rule %q, expected '<header|meta> <name> <op> <value>'", expr)
+	}
+	r := SkipRule{
+		Expr:   expr,
+		Source: strings.ToLower(fields[0]),
+		Name:   fields[1],
+		Op:     strings.ToLower(fields[2]),
+		Value:  strings.Join(fields[3:], " "),
+	}
+	if r.Source != "header" && r.Source != "meta" {
+		return SkipRule{}, fmt.Errorf("invalid skip rule %q: %q is neither header nor meta", expr, fields[0])
+	}
+	switch r.Op {
+	case skipExists:
+		if r.Value != "" {
+			return SkipRule{}, fmt.Errorf("invalid skip rule %q: %s takes no value", expr, skipExists)
+		}
+	case skipEquals, skipNotEquals, skipContains:
+		if r.Value == "" {
+			return SkipRule{}, fmt.Errorf("invalid skip rule %q: missing value", expr)
+		}
+	case skipMatches:
+		re, err := regexp.Compile(r.Value)
+		if err != nil {
+			return SkipRule{}, fmt.Errorf("invalid skip rule %q: %w", expr, err)
+		}
+		r.re = re
+	default:
+		return SkipRule{}, fmt.Errorf("invalid skip rule %q: unknown operator %q", expr, fields[2])
+	}
+	return r, nil
+}
+
+// match reports whether the rule applies to a page with header h and meta
+// tags meta.
+func (r SkipRule) match(h http.Header, meta map[string][]string) bool {
+	var values []string
+	if r.Source == "header" {
+		if v := h.Values(r.Name); len(v) > 0 {
+			values = []string{strings.Join(v, ", ")}
+		}
+	} else {
+		values = meta[strings.ToLower(r.Name)]
+	}
+
+	if r.Op == skipExists {
+		return len(values) > 0
+	}
+	if r.Op == skipNotEquals {
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(v), r.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		switch r.Op {
+		case skipEquals:
+			if strings.EqualFold(strings.TrimSpace(v), r.Value) {
+				return true
+			}
+		case skipContains:
+			if strings.Contains(strings.ToLower(v), strings.ToLower(r.Value)) {
+				return true
+			}
+		case skipMatches:
+			if r.re.MatchString(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// metaTags returns the content of the page's <meta> tags keyed by their
+// lower-cased name or property, as used by Open Graph.
+func metaTags(body []byte) map[string][]string {
+	tags := make(map[string][]string)
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return tags
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data == "body" {
+				return tags
+			}
+			if t.Data != "meta" {
+				continue
+			}
+			var name, content string
+			for _, a := range t.Attr {
+				switch a.Key {
+				case "name", "property", "http-equiv":
+					name = strings.ToLower(strings.TrimSpace(a.Val))
+				case "content":
+					content = a.Val
+				}
+			}
+			if name != "" {
+				tags[name] = append(tags[name], content)
+			}
+		}
+	}
+}
+
+// skipRule returns the first skip rule matching the response, or nil.
+func (s *Scraper) skipRule(h http.Header, body []byte) *SkipRule {
+	if len(s.SkipRules) == 0 {
+		return nil
+	}
+	meta := metaTags(body)
+	for i, r := range s.SkipRules {
+		if r.match(h, meta) {
+			return &s.SkipRules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSkipRule(t *testing.T) {
+	body := []byte(`<html><head>
+<meta property="og:type" content="product">
+<meta name="Generator" content="Hugo 0.120">
+</head><body><meta name="late" content="ignored"></body></html>`)
+	header := http.Header{}
+	header.Add("X-Robots-Tag", "noindex")
+	header.Add("X-Robots-Tag", "NoArchive")
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"header X-Robots-Tag contains noarchive", true},
+		{"header x-robots-tag contains nosnippet", false},
+		{"header Cache-Control exists", false},
+		{"header X-Robots-Tag exists", true},
+		{"meta og:type == product", true},
+		{"meta og:type == Article", false},
+		{"meta og:type != article", true},
+		{"meta OG:TYPE != product", false},
+		{"meta generator matches ^Hugo 0\\.1", true},
+		{"meta late exists", false},
+	}
+	for _, tt := range tests {
+		r, err := ParseSkipRule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSkipRule(%q) error = %v", tt.expr, err)
+		}
+		if got := r.match(header, metaTags(body)); got != tt.want {
+			t.Errorf("%q matched = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseSkipRuleInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"header X-Robots-Tag",
+		"cookie session exists",
+		"meta og:type like product",
+		"meta og:type ==",
+		"meta og:type exists product",
+		"meta og:type matches (",
+	} {
+		if _, err := ParseSkipRule(expr); err == nil {
+			t.Errorf("ParseSkipRule(%q) accepted an invalid rule", expr)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Ways of splitting the archive accepted by Scraper.SplitBy.
+const (
+	// SplitTopLevelPath writes an archive per first segment of the URL
+	// paths, such as /docs, /blog and /api.
+	SplitTopLevelPath = "top-level-path"
+)
+
+// homeSection is the section of the pages at the root of the site.
+const homeSection = "home"
+
+// section is the pages of an archive split by SplitBy.
+type section struct {
+	name  string
+	pages []*page
+}
+
+// checkSplitBy rejects unknown ways of splitting and outputs that are one
+// file for the whole crawl.
+func (s *Scraper) checkSplitBy() error {
+	switch s.SplitBy {
+	case "":
+		return nil
+	case SplitTopLevelPath:
+	default:
+		return fmt.Errorf("unknown split %q (available: %s)", s.SplitBy, SplitTopLevelPath)
+	}
+	switch {
+	case s.SinglePDF:
+		return fmt.Errorf("a single PDF holds the whole crawl, it can't be split into sections")
+	case s.Format == FormatEPUB:
+		return fmt.Errorf("an EPUB book holds the whole crawl, it can't be split into sections")
+	case s.Evidence:
+		return fmt.Errorf("evidence covers the whole crawl, it can't be split into sections")
+	}
+	return nil
+}
+
+// topLevelSection returns the section of p: the first segment of its URL
+// path, or homeSection for the start page and the files at the root.
+func topLevelSection(p *page) string {
+	segment, rest, _ := strings.Cut(strings.TrimPrefix(path.Clean("/"+p.URL.Path), "/"), "/")
+	if segment == "" || rest == "" && !strings.HasSuffix(p.URL.Path, "/") && strings.Contains(segment, ".") {
+		// "/", "/index.html"
+		return homeSection
+	}
+	return segment
+}
+
+// sections groups the archived pages by section, sections and the pages
+// in each in archive order.
+func (s *Scraper) sections() []section {
+	var sections []section
+	index := make(map[string]int)
+	for _, p := range s.pdfs {
+		name := topLevelSection(p)
+		i, ok := index[name]
+		if !ok {
+			i = len(sections)
+			index[name] = i
+			sections = append(sections, section{name: name})
+		}
+		sections[i].pages = append(sections[i].pages, p)
+	}
+	return sections
+}
+
+// sectionOutput returns where the archive of the section name is written:
+// outputPath with the section appended to its name, before the extension
+// of archive files.
+func (s *Scraper) sectionOutput(outputPath, name string) string {
+	name = sectionSlug(name)
+	if s.writesTree() {
+		return outputPath + "-" + name
+	}
+	for _, ext := range []string{".tar.gz", ".tar", ".zip"} {
+		if base, ok := strings.CutSuffix(outputPath, ext); ok {
+			return base + "-" + name + ext
+		}
+	}
+	return outputPath + "-" + name
+}
+
+// sectionSlug makes the path segment name safe for a file name.
+func sectionSlug(name string) string {
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|', ' ':
+			return '-'
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// createSections writes an archive per section, each with the index,
+// table of contents and manifest of its pages, next to outputPath. extras,
+// the files about the whole crawl, go into every archive.
+func (s *Scraper) createSections(startURL, outputPath string, extras []archiveEntry) error {
+	all := s.pdfs
+	defer func() {
+		s.pdfs = all
+		s.section = ""
+	}()
+	s.outputs = nil
+	for _, sec := range s.sections() {
+		s.pdfs, s.section = sec.pages, sec.name
+		catalog, err := s.catalogEntries(startURL)
+		if err != nil {
+			return fmt.Errorf("section %s: %w", sec.name, err)
+		}
+		entries := append(catalog, extras...)
+		output := s.sectionOutput(outputPath, sec.name)
+		switch {
+		case s.writesTree():
+			err = s.createDir(output, entries)
+		case s.writesTar():
+			err = s.createTar(output, entries)
+		default:
+			err = s.createZip(output, entries)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write section %s: %w", sec.name, err)
+		}
+		if err := os.RemoveAll(workDirName(output)); err != nil {
+			s.logf("Warning: failed to clean up work directory: %v\n", err)
+		}
+		s.outputs = append(s.outputs, output)
+		s.logf("Wrote section %s, %d pages, to %s\n", sec.name, len(sec.pages), output)
+	}
+	return nil
+}
+
+// Outputs returns the files or directories the last crawl wrote: the
+// output path, or an archive per section with SplitBy.
+func (s *Scraper) Outputs() []string {
+	return s.outputs
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTopLevelSection(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", homeSection},
+		{"", homeSection},
+		{"/index.html", homeSection},
+		{"/about", "about"},
+		{"/docs/", "docs"},
+		{"/docs/guide/install", "docs"},
+		{"/api/v1.json", "api"},
+	}
+	for _, tt := range tests {
+		p := &page{URL: &url.URL{Scheme: "https", Host: "example.com", Path: tt.path}}
+		if got := topLevelSection(p); got != tt.want {
+			t.Errorf("topLevelSection(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSectionOutput(t *testing.T) {
+	tests := []struct {
+		mode, output, want string
+	}{
+		{OutputZip, "out/example.com.zip", "out/example.com-docs.zip"},
+		{OutputZip, "out/example.com.tar.gz", "out/example.com-docs.tar.gz"},
+		{OutputDir, "out/example.com", "out/example.com-docs"},
+	}
+	for _, tt := range tests {
+		s := &Scraper{OutputMode: tt.mode}
+		if got := s.sectionOutput(tt.output, "Docs"); got != tt.want {
+			t.Errorf("sectionOutput(%q) in %s mode = %q, want %q", tt.output, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSplitBy(t *testing.T) {
+	if err := (&Scraper{SplitBy: "host"}).checkSplitBy(); err == nil {
+		t.Error("unknown split accepted")
+	}
+	if err := (&Scraper{SplitBy: SplitTopLevelPath, SinglePDF: true}).checkSplitBy(); err == nil {
+		t.Error("single PDF split into sections")
+	}
+	if err := (&Scraper{SplitBy: SplitTopLevelPath}).checkSplitBy(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSplitByTopLevelPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><p>Page</p>
+			<a href="/docs/install">Install</a> <a href="/docs/usage">Usage</a>
+			<a href="/blog/launch">Launch</a></body></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	s := New(Options{StripHTML: true})
+	s.Log = io.Discard
+	s.SplitBy = SplitTopLevelPath
+	output := filepath.Join(t.TempDir(), "site.zip")
+	if err := s.ScrapeAndSave(srv.URL+"/", output); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"home": 1, "docs": 2, "blog": 1}
+	if len(s.Outputs()) != len(want) {
+		t.Fatalf("Outputs() = %v, want an archive per section", s.Outputs())
+	}
+	for name, pages := range want {
+		entries := zipEntries(t, s.sectionOutput(output, name))
+		var m manifest
+		if err := json.Unmarshal([]byte(entries["manifest.json"]), &m); err != nil {
+			t.Fatalf("section %s: %v", name, err)
+		}
+		if m.Section != name || len(m.Pages) != pages {
+			t.Errorf("section %s: manifest of section %q with %d pages, want %d", name, m.Section, len(m.Pages), pages)
+		}
+		if _, ok := entries["index.html"]; !ok {
+			t.Errorf("section %s has no index", name)
+		}
+	}
+	if files, _ := os.ReadDir(filepath.Dir(output)); len(files) != len(want) {
+		t.Errorf("%d files left next to the output, want only the %d archives", len(files), len(want))
+	}
+	if pages, _ := s.Stats(); pages != 4 {
+		t.Errorf("%d pages archived, want 4", pages)
+	}
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/net/html"
+)
+
+// stampSource draws a small provenance box at the top of the current page
+// showing where the document came from. The URL is added as a link annotation,
+// and the box carries a file attachment annotation with the fetch details, so
+// every page of a single PDF stays self-describing, not just the document.
+func (s *Scraper) stampSource(pdf *gofpdf.Fpdf, p *page) {
+	source := sourceURL(p)
+	fetched := s.formatTime(p.FetchedAt, isoDateTimeSeconds)
+	status := fmt.Sprintf("%d %s", p.Status, http.StatusText(p.Status))
+
+	// Below the page header, if there is one
+	left, _, right, _ := pdf.GetMargins()
+	top := pdf.GetY()
+	pageWidth, _ := pdf.GetPageSize()
+	width := pageWidth - left - right
+	const lineHeight = 5.0
+
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetFillColor(245, 245, 245)
+	pdf.Rect(left, top, width, 2*lineHeight+4, "FD")
+
+	pdf.SetFont(fontSans, "", 8)
+	pdf.SetTextColor(0, 0, 238)
+	pdf.SetXY(left+2, top+2)
+	label := fitText(pdf, "Source: "+source, width-4)
+	pdf.CellFormat(width-4, lineHeight, label, "", 1, "L", false, 0, "")
+	pdf.LinkString(left+2, top+2, pdf.GetStringWidth(label), lineHeight, source)
+
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetX(left + 2)
+	pdf.CellFormat(width-4, lineHeight, fmt.Sprintf("Fetched: %s    Status: %s", fetched, status), "", 1, "L", false, 0, "")
+
+	// Over the details line, clear of the source link
+	pdf.AddAttachmentAnnotation(&gofpdf.Attachment{
+		Content:     []byte(sourceKeywords(p) + "\n"),
+		Filename:    "source.txt",
+		Description: fmt.Sprintf("Source: %s\nFetched: %s\nStatus: %s", source, fetched, status),
+	}, left+2, top+2+lineHeight, width-4, lineHeight)
+
+	pdf.SetXY(left, top+2*lineHeight+8)
+}
+
+// sourceKeywords returns the provenance stored in the document keywords.
+func sourceKeywords(p *page) string {
+	return fmt.Sprintf("source=%s fetched=%s status=%d", sourceURL(p), p.FetchedAt.UTC().Format("2006-01-02T15:04:05Z"), p.Status)
+}
+
+// sourceURL returns the canonical URL of the page, or its URL for pages
+// that weren't fetched, such as the cover.
+func sourceURL(p *page) string {
+	if p.Canonical != "" {
+		return p.Canonical
+	}
+	return p.URL.String()
+}
+
+// canonicalURL returns the target of the page's <link rel="canonical"> tag,
+// resolved against the page URL, falling back to the page URL itself.
+func canonicalURL(body []byte, pageURL *url.URL) string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return pageURL.String()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data == "body" {
+				return pageURL.String()
+			}
+			if t.Data != "link" {
+				continue
+			}
+			var rel, href string
+			for _, a := range t.Attr {
+				switch a.Key {
+				case "rel":
+					rel = strings.ToLower(a.Val)
+				case "href":
+					href = strings.TrimSpace(a.Val)
+				}
+			}
+			if rel != "canonical" || href == "" {
+				continue
+			}
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			return pageURL.ResolveReference(ref).String()
+		}
+	}
+}
+
+// fitText shortens text with an ellipsis until it fits in width. It cuts
+// whole characters, as gofpdf can't encode a split one.
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,149 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	pageURL, _ := url.Parse("https://example.com/docs/page?ref=nav")
+
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "absolute canonical",
+			html: `<html><head><link rel="canonical" href="https://example.com/docs/page"></head></html>`,
+			want: "https://example.com/docs/page",
+		},
+		{
+			name: "relative canonical",
+			html: `<html><head><link rel="Canonical" href="/docs/other"/></head></html>`,
+			want: "https://example.com/docs/other",
+		},
+		{
+			name: "no canonical",
+			html: `<html><head><link rel="stylesheet" href="/style.css"></head><body></body></html>`,
+			want: "https://example.com/docs/page?ref=nav",
+		},
+		{
+			name: "canonical inside body is ignored",
+			html: `<html><body><link rel="canonical" href="/elsewhere"></body></html>`,
+			want: "https://example.com/docs/page?ref=nav",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalURL([]byte(tt.html), pageURL); got != tt.want {
+				t.Errorf("canonicalURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFitText(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	s := NewScraper(true, false)
+	if err := s.addFonts(pdf); err != nil {
+		t.Fatal(err)
+	}
+	pdf.SetFont(fontSans, "", 8)
+
+	text := "ЩЖЮШ — Источник: https://пример.рф/документация/страница"
+	if got := fitText(pdf, text, 500); got != text {
+		t.Errorf("fitText() = %q, want the text unchanged", got)
+	}
+	for width := 3.0; width < 60; width += 0.1 {
+		got := fitText(pdf, text, width)
+		if !utf8.ValidString(got) || !strings.HasSuffix(got, "...") {
+			t.Errorf("fitText(%g) = %q, want whole characters and an ellipsis", width, got)
+		}
+		if w := pdf.GetStringWidth(got); w > width && got != "..." {
+			t.Errorf("fitText(%g) is %gmm wide", width, w)
+		}
+	}
+}
+
+func TestStampSourceSinglePDF(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/home"></head><body><a href="/guide?ref=nav">Guide</a></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/guide"></head><body><p>Guide</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewScraper(true, false)
+	s.SinglePDF = true
+	s.StampSource = true
+	s.NoTOC = true
+	out := filepath.Join(t.TempDir(), "site.pdf")
+	if err := s.ScrapeAndSave(srv.URL+"/", out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each page has its own provenance, from the canonical URL its body
+	// named before it was dropped
+	got := stampAnnotations(t, data)
+	want := []string{srv.URL + "/home", srv.URL + "/guide"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d source annotations, want %d: %q", len(got), len(want), got)
+	}
+	for i, a := range got {
+		for _, part := range []string{"Source: " + want[i] + "\n", "\nFetched: ", "\nStatus: 200 OK"} {
+			if !strings.Contains(a, part) {
+				t.Errorf("annotation %d = %q, missing %q", i, a, part)
+			}
+		}
+	}
+}
+
+// stampAnnotations returns the descriptions of the file attachment
+// annotations of data's pages, in page order.
+func stampAnnotations(t *testing.T, data []byte) []string {
+	t.Helper()
+	f, err := readPDF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages []int
+	for num := range f.offsets {
+		if d, ok := f.object(num).(pdfDict); ok && d.name("Type") == "Page" {
+			pages = append(pages, num)
+		}
+	}
+	// gofpdf numbers its pages in order
+	sort.Ints(pages)
+	var descriptions []string
+	for _, num := range pages {
+		for _, a := range f.array(f.dict(pdfRef{num})["Annots"]) {
+			annot := f.dict(a)
+			if annot.name("Subtype") != "FileAttachment" {
+				continue
+			}
+			contents, _ := f.resolve(annot["Contents"]).(pdfString)
+			descriptions = append(descriptions, utf16BEToUTF8(string(contents)))
+		}
+	}
+	return descriptions
+}
@@ -0,0 +1,187 @@
+package scraper
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// pageNumberTitle matches page counters appended to article titles,
+	// e.g. "Title - Page 2", "Title (page 2 of 5)" or "Title | Part 3".
+	pageNumberTitle = regexp.MustCompile(`(?i)\s*[-|:,–—(]?\s*(page|part|p\.)\s*\d+(\s*(of|/)\s*\d+)?\s*\)?\s*$`)
+	// pageNumberPath matches numbered pagination path suffixes such as
+	// /page/2 or /2.
+	pageNumberPath = regexp.MustCompile(`^(.*?)(/page)?/(\d+)/?$`)
+)
+
+// pageNumberParams are the query parameters used for article pagination.
+var pageNumberParams = []string{"page", "p", "pg", "pagenum"}
+
+// pageTitle returns the text of the page's <title> element.
+func pageTitle(body []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "title" {
+				inTitle = true
+			}
+		case html.TextToken:
+			if inTitle {
+				return strings.Join(strings.Fields(string(z.Text())), " ")
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "title" || string(name) == "head" {
+				return ""
+			}
+		}
+	}
+}
+
+// relNext returns the absolute target of the page's rel="next" link or
+// anchor, without fragment, or "" if there is none.
+func relNext(body []byte, base *url.URL) string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data != "link" && t.Data != "a" {
+				continue
+			}
+			var rel, href string
+			for _, a := range t.Attr {
+				switch a.Key {
+				case "rel":
+					rel = strings.ToLower(a.Val)
+				case "href":
+					href = a.Val
+				}
+			}
+			if href == "" || !hasDirective(strings.Fields(rel), "next") {
+				continue
+			}
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			return stripFragment(base.ResolveReference(ref)).String()
+		}
+	}
+}
+
+// articleTitle strips page counters from a title so the parts of one
+// article compare equal.
+func articleTitle(title string) string {
+	return strings.ToLower(strings.TrimSpace(pageNumberTitle.ReplaceAllString(title, "")))
+}
+
+// paginationKey returns u with its page number removed, and the page
+// number (1 when the URL carries none).
+func paginationKey(u *url.URL) (string, int) {
+	key := stripFragment(u)
+	number := 1
+
+	q := key.Query()
+	for _, name := range pageNumberParams {
+		if v := q.Get(name); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				number = n
+				q.Del(name)
+				key.RawQuery = q.Encode()
+				return key.String(), number
+			}
+		}
+	}
+	if m := pageNumberPath.FindStringSubmatch(key.Path); m != nil {
+		if n, err := strconv.Atoi(m[3]); err == nil && n < 1000 {
+			number = n
+			key.Path = m[1]
+		}
+	}
+	return key.String(), number
+}
+
+// stitchPages merges the parts of paginated articles into their first part.
+// Parts are linked by a rel="next" chain, or by URLs that only differ in
+// their page number; either way they must share the article title. The
+// merged pages are returned in their original order, without the parts.
+func stitchPages(pages []*page) []*page {
+	byURL := make(map[string]*page, len(pages))
+	for _, p := range pages {
+		byURL[stripFragment(p.URL).String()] = p
+	}
+
+	next := make(map[*page]*page)
+	hasPrev := make(map[*page]bool)
+	link := func(a, b *page) {
+		if a == b || next[a] != nil || hasPrev[b] || articleTitle(a.Title) != articleTitle(b.Title) {
+			return
+		}
+		next[a] = b
+		hasPrev[b] = true
+	}
+
+	for _, p := range pages {
+		if n := byURL[p.Next]; n != nil {
+			link(p, n)
+		}
+	}
+
+	groups := make(map[string][]*page)
+	numbers := make(map[*page]int)
+	for _, p := range pages {
+		key, n := paginationKey(p.URL)
+		groups[key] = append(groups[key], p)
+		numbers[p] = n
+	}
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return numbers[group[i]] < numbers[group[j]] })
+		for i := 0; i+1 < len(group); i++ {
+			if numbers[group[i+1]] == numbers[group[i]]+1 {
+				link(group[i], group[i+1])
+			}
+		}
+	}
+
+	merged := make(map[*page]bool)
+	var out []*page
+	for _, p := range pages {
+		if merged[p] || hasPrev[p] {
+			continue
+		}
+		parts := []string{p.Text}
+		for n := next[p]; n != nil && !merged[n] && n != p; n = next[n] {
+			merged[n] = true
+			parts = append(parts, n.Text)
+			p.Links = append(p.Links, n.Links...)
+			p.Anchors = append(p.Anchors, n.Anchors...)
+			p.Parts = append(p.Parts, n.URL.String())
+			p.Transforms = append(p.Transforms, n.Transforms...)
+		}
+		p.Text = strings.Join(parts, "\n\n")
+		out = append(out, p)
+	}
+
+	// Parts whose chain loops back without a head are kept as they are.
+	for _, p := range pages {
+		if hasPrev[p] && !merged[p] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
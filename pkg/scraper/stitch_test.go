@@ -0,0 +1,79 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPaginationKey(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantKey string
+		wantNum int
+	}{
+		{"https://example.com/article", "https://example.com/article", 1},
+		{"https://example.com/article?page=3", "https://example.com/article", 3},
+		{"https://example.com/article?id=7&p=2", "https://example.com/article?id=7", 2},
+		{"https://example.com/article/page/2/", "https://example.com/article", 2},
+		{"https://example.com/article/4", "https://example.com/article", 4},
+		{"https://example.com/2024/article", "https://example.com/2024/article", 1},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.in)
+		key, n := paginationKey(u)
+		if key != tt.wantKey || n != tt.wantNum {
+			t.Errorf("paginationKey(%s) = %s, %d; want %s, %d", tt.in, key, n, tt.wantKey, tt.wantNum)
+		}
+	}
+}
+
+func TestArticleTitle(t *testing.T) {
+	for _, title := range []string{"Long Read", "Long Read - Page 2", "Long Read (page 3 of 5)", "Long Read | Part 4"} {
+		if got := articleTitle(title); got != "long read" {
+			t.Errorf("articleTitle(%q) = %q", title, got)
+		}
+	}
+}
+
+func TestPageTitleAndRelNext(t *testing.T) {
+	base, _ := url.Parse("https://example.com/story?page=1")
+	body := []byte(`<html><head><title>
+		The  Story</title><link rel="next" href="?page=2#top"></head><body></body></html>`)
+
+	if got := pageTitle(body); got != "The Story" {
+		t.Errorf("pageTitle() = %q", got)
+	}
+	if got := relNext(body, base); got != "https://example.com/story?page=2" {
+		t.Errorf("relNext() = %q", got)
+	}
+}
+
+func TestStitchPages(t *testing.T) {
+	mk := func(raw, title, next, text string) *page {
+		u, _ := url.Parse(raw)
+		return &page{URL: u, Title: title, Next: next, Text: text}
+	}
+	pages := []*page{
+		mk("https://example.com/story", "Story", "https://example.com/story?page=2", "part one"),
+		mk("https://example.com/other", "Other", "", "unrelated"),
+		mk("https://example.com/story?page=3", "Story - Page 3", "", "part three"),
+		mk("https://example.com/story?page=2", "Story - Page 2", "https://example.com/story?page=3", "part two"),
+		mk("https://example.com/list?page=2", "Listing", "", "different title page 2"),
+		mk("https://example.com/list", "Archive", "", "different title page 1"),
+	}
+
+	out := stitchPages(pages)
+
+	if len(out) != 4 {
+		t.Fatalf("stitchPages() returned %d pages, want 4", len(out))
+	}
+	if out[0].Text != "part one\n\npart two\n\npart three" {
+		t.Errorf("stitched text = %q", out[0].Text)
+	}
+	if len(out[0].Parts) != 2 || out[0].Parts[0] != "https://example.com/story?page=2" {
+		t.Errorf("parts = %v", out[0].Parts)
+	}
+	if out[1].URL.Path != "/other" || out[2].URL.String() != "https://example.com/list?page=2" {
+		t.Errorf("unrelated pages not kept in order: %s, %s", out[1].URL, out[2].URL)
+	}
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultStreamThreshold is the body size above which pages are extracted
+// with the streaming tokenizer instead of a full DOM.
+const DefaultStreamThreshold = 8 << 20
+
+// streamBlockTags end a block of text: a blank line follows them.
+var streamBlockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "footer": true, "aside": true, "nav": true, "table": true,
+	"tr": true, "ul": true, "ol": true, "blockquote": true, "pre": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// streamSkipTags have content that is never displayed.
+var streamSkipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "head": true, "template": true,
+}
+
+// streamText extracts readable text from r with the HTML tokenizer, without
+// building a DOM, so memory stays proportional to the text rather than the
+// markup. The layout follows nodeText: blank lines between blocks and a
+// bullet per list item.
+func streamText(r io.Reader) (string, error) {
+	z := html.NewTokenizer(r)
+	var b strings.Builder
+	skip := 0
+	// breaks is the number of newlines owed before the next text, space
+	// whether text written on the same line needs a separator.
+	breaks, space := 0, false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return "", err
+			}
+			return finishText(b.String()), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case streamSkipTags[tag]:
+				if z.Token().Type == html.StartTagToken {
+					skip++
+				}
+			case tag == "br":
+				breaks = max(breaks, 1)
+			case tag == "li":
+				breaks = max(breaks, 1)
+				if skip == 0 {
+					writeBreaks(&b, breaks)
+					b.WriteString("• ")
+					breaks, space = 0, false
+				}
+			case streamBlockTags[tag]:
+				breaks = 2
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case streamSkipTags[tag]:
+				if skip > 0 {
+					skip--
+				}
+			case tag == "li":
+				breaks = max(breaks, 1)
+			case streamBlockTags[tag]:
+				breaks = 2
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(z.Text())), " ")
+			if text == "" {
+				continue
+			}
+			if breaks > 0 {
+				writeBreaks(&b, breaks)
+			} else if space {
+				b.WriteByte(' ')
+			}
+			b.WriteString(text)
+			breaks, space = 0, true
+		}
+	}
+}
+
+// writeBreaks writes n newlines unless nothing has been written yet.
+func writeBreaks(b *strings.Builder, n int) {
+	if b.Len() > 0 {
+		b.WriteString(strings.Repeat("\n", n))
+	}
+}
+
+// finishText trims content and ends it with a blank line, like nodeText.
+func finishText(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+	return content + "\n\n"
+}
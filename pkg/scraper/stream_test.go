@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStreamText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and headings",
+			html: `<html><head><title>T</title></head><body><h1>Title</h1><p>First paragraph.</p><p>Second one.</p></body></html>`,
+			want: "Title\n\nFirst paragraph.\n\nSecond one.\n\n",
+		},
+		{
+			name: "list items",
+			html: `<ul><li>One</li><li>Two</li></ul>`,
+			want: "• One\n• Two\n\n",
+		},
+		{
+			name: "inline markup joined",
+			html: `<p>Some <b>bold</b> and <a href="/x">linked</a> text.</p>`,
+			want: "Some bold and linked text.\n\n",
+		},
+		{
+			name: "scripts and styles skipped",
+			html: `<body><script>var x = "<p>";</script><style>p{}</style><p>Visible</p><noscript>Enable JS</noscript></body>`,
+			want: "Visible\n\n",
+		},
+		{
+			name: "line breaks",
+			html: `<p>Line one<br>Line two</p>`,
+			want: "Line one\nLine two\n\n",
+		},
+		{
+			name: "empty",
+			html: `<html><head></head><body></body></html>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := streamText(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("streamText() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("streamText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTextStreamsLargePages(t *testing.T) {
+	u, _ := url.Parse("https://example.com/big")
+	body := []byte(`<html><body><article><p>Main content of the article.</p></article><div class="ads">Buy now</div></body></html>`)
+
+	s := NewScraper(true, false)
+	s.Recipes = []*Recipe{{Name: "example", Domains: []string{"example.com"}, Content: "article"}}
+	if err := s.Recipes[0].compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.StreamThreshold = int64(len(body)) + 1
+	got, err := s.extractText(&page{URL: u, Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "Buy now") {
+		t.Errorf("DOM path: recipe not applied, got %q", got)
+	}
+
+	s.StreamThreshold = int64(len(body)) - 1
+	got, err = s.extractText(&page{URL: u, Body: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "Main content of the article.") || !strings.Contains(got, "Buy now") {
+		t.Errorf("streaming path: got %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/publicsuffix"
+)
+
+// siteDomain returns the registrable domain of host, e.g. example.com for
+// docs.example.com or example.co.uk for www.example.co.uk. IP addresses and
+// hosts without a public suffix, such as localhost, are their own domain.
+func siteDomain(host string) string {
+	host = strings.ToLower(host)
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// inDomain reports whether host is domain or one of its subdomains.
+func inDomain(host, domain string) bool {
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// scopeCollector limits c to the start page's host, or with
+// IncludeSubdomains to every host of its domain, redirects included.
+func (s *Scraper) scopeCollector(c *colly.Collector, start *url.URL) {
+	if !s.IncludeSubdomains {
+		// colly compares host names, without the port
+		c.AllowedDomains = []string{start.Hostname()}
+		return
+	}
+	s.domain = siteDomain(start.Hostname())
+	c.SetRedirectHandler(func(req *http.Request, via []*http.Request) error {
+		if !inDomain(req.URL.Hostname(), s.domain) {
+			return fmt.Errorf("not following redirect to %s, it's outside %s", req.URL.Host, s.domain)
+		}
+		if len(via) >= 10 {
+			return http.ErrUseLastResponse
+		}
+		// Like colly, credentials aren't sent on to another host
+		if req.URL.Host != via[len(via)-1].URL.Host {
+			req.Header.Del("Authorization")
+		}
+		return nil
+	})
+}
+
+// inScope reports whether the crawl may visit u: it is on the start page's
+// host, or with IncludeSubdomains on any host of its domain.
+func (s *Scraper) inScope(u *url.URL) bool {
+	return !s.IncludeSubdomains || inDomain(u.Hostname(), s.domain)
+}
+
+// sameSite reports whether a link from source to target stays in the
+// crawled site, for the link reports.
+func (s *Scraper) sameSite(source, target *url.URL) bool {
+	if target.Host == source.Host {
+		return true
+	}
+	return s.IncludeSubdomains && inDomain(source.Hostname(), s.domain) && inDomain(target.Hostname(), s.domain)
+}
+
+// hostTreeName puts the tree path name of a page under a directory named
+// after its host when the crawl spans subdomains, so pages at the same path
+// on two hosts don't collide.
+func (s *Scraper) hostTreeName(u *url.URL, name string) string {
+	if !s.IncludeSubdomains {
+		return name
+	}
+	return path.Join(strings.ToLower(u.Host), name)
+}
@@ -0,0 +1,64 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSiteDomain(t *testing.T) {
+	tests := []struct {
+		host, want string
+	}{
+		{"docs.example.com", "example.com"},
+		{"Example.com", "example.com"},
+		{"www.example.co.uk", "example.co.uk"},
+		{"localhost", "localhost"},
+		{"127.0.0.1", "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := siteDomain(tt.host); got != tt.want {
+			t.Errorf("siteDomain(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestSameSite(t *testing.T) {
+	docs, _ := url.Parse("https://docs.example.com/guide")
+	tests := []struct {
+		target     string
+		subdomains bool
+		want       bool
+	}{
+		{"https://docs.example.com/api", false, true},
+		{"https://api.example.com/v1", false, false},
+		{"https://api.example.com/v1", true, true},
+		{"https://example.com/", true, true},
+		{"https://notexample.com/", true, false},
+		{"https://example.org/", true, false},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.IncludeSubdomains = tt.subdomains
+		s.domain = siteDomain(docs.Hostname())
+		target, _ := url.Parse(tt.target)
+		if got := s.sameSite(docs, target); got != tt.want {
+			t.Errorf("sameSite(%s, subdomains %v) = %v, want %v", tt.target, tt.subdomains, got, tt.want)
+		}
+		if got := s.inScope(target); tt.subdomains && got != tt.want {
+			t.Errorf("inScope(%s) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestHostTreeName(t *testing.T) {
+	s := NewScraper(false, false)
+	s.OutputMode = OutputDir
+	u, _ := url.Parse("https://api.example.com/v1/users")
+	if got := s.baseEntryName(u); got != "v1/users.pdf" {
+		t.Errorf("baseEntryName() = %q", got)
+	}
+	s.IncludeSubdomains = true
+	if got := s.baseEntryName(u); got != "api.example.com/v1/users.pdf" {
+		t.Errorf("baseEntryName() with subdomains = %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package scraper
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Archive formats accepted by Scraper.ArchiveFormat.
+const (
+	ArchiveZip   = "zip"
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+)
+
+// checkArchiveFormat rejects unknown archive formats and tarballs for
+// outputs that aren't an archive.
+func (s *Scraper) checkArchiveFormat() error {
+	switch s.ArchiveFormat {
+	case "", ArchiveZip:
+		return nil
+	case ArchiveTar, ArchiveTarGz:
+	default:
+		return fmt.Errorf("unknown archive format %q (available: %s, %s, %s)", s.ArchiveFormat, ArchiveZip, ArchiveTar, ArchiveTarGz)
+	}
+	switch {
+	case s.writesTree():
+		return fmt.Errorf("a directory output isn't an archive, it can't be written as %s", s.ArchiveFormat)
+	case s.SinglePDF:
+		return fmt.Errorf("a single PDF isn't an archive, it can't be written as %s", s.ArchiveFormat)
+	case s.Format == FormatEPUB:
+		return fmt.Errorf("an EPUB book is a ZIP file, it can't be written as %s", s.ArchiveFormat)
+	}
+	return nil
+}
+
+// writesTar reports whether the archive is a tarball.
+func (s *Scraper) writesTar() bool {
+	return s.ArchiveFormat == ArchiveTar || s.ArchiveFormat == ArchiveTarGz
+}
+
+// createTar writes the pages and extras to the tarball tarname, gzipped for
+// ArchiveTarGz. Entries are written one after the other, so a pipeline can
+// start on the archive as it streams in. Like the ZIP, it's written in the
+// work directory and only moved to tarname once complete.
+func (s *Scraper) createTar(tarname string, extras []archiveEntry) error {
+	dir := workDirName(tarname)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	partial := filepath.Join(dir, "archive.tar")
+	f, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create tar file: %w", err)
+	}
+	defer f.Close()
+
+	var out io.Writer = f
+	var gz *gzip.Writer
+	if s.ArchiveFormat == ArchiveTarGz {
+		gz = gzip.NewWriter(f)
+		out = gz
+	}
+	archive := tar.NewWriter(out)
+	modTime := time.Now()
+	for _, entry := range s.archiveEntries(extras) {
+		if err := writeTarEntry(archive, entry, modTime); err != nil {
+			return fmt.Errorf("failed to write %s to tar: %w", entry.Name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write tar: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write tar: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write tar: %w", err)
+	}
+	return os.Rename(partial, tarname)
+}
+
+// writeTarEntry adds entry to archive. The size goes in the header, so a
+// file entry is opened before it's written.
+func writeTarEntry(archive *tar.Writer, entry archiveEntry, modTime time.Time) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.Name,
+		Mode:     0644,
+		Size:     int64(len(entry.Data)),
+		ModTime:  modTime,
+	}
+	if entry.File == "" {
+		if err := archive.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := archive.Write(entry.Data)
+		return err
+	}
+
+	f, err := os.Open(entry.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr.Size = info.Size()
+	if err := archive.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(archive, f)
+	return err
+}
@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCreateTar(t *testing.T) {
+	for _, format := range []string{ArchiveTar, ArchiveTarGz} {
+		t.Run(format, func(t *testing.T) {
+			s := NewScraper(false, false)
+			s.ArchiveFormat = format
+			u, _ := url.Parse("https://example.com/docs/install")
+			file := filepath.Join(t.TempDir(), "page.pdf")
+			if err := os.WriteFile(file, []byte("%PDF-1.3"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			s.pdfs = []*page{{URL: u, File: file}}
+
+			out := filepath.Join(t.TempDir(), "example.com."+format)
+			if err := s.createTar(out, []archiveEntry{{Name: "manifest.json", Data: []byte("{}")}}); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var in io.Reader = f
+			if format == ArchiveTarGz {
+				gz, err := gzip.NewReader(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				in = gz
+			}
+
+			got := make(map[string]string)
+			r := tar.NewReader(in)
+			for {
+				hdr, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[hdr.Name] = string(data)
+			}
+			want := map[string]string{
+				"example.com_docs_install.pdf": "%PDF-1.3",
+				"manifest.json":                "{}",
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("tar holds %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckArchiveFormat(t *testing.T) {
+	tests := []struct {
+		format     string
+		outputMode string
+		singlePDF  bool
+		wantErr    bool
+	}{
+		{"", "", false, false},
+		{ArchiveZip, OutputDir, false, false},
+		{ArchiveTarGz, OutputZip, false, false},
+		{ArchiveTar, OutputDir, false, true},
+		{ArchiveTar, "", true, true},
+		{"7z", "", false, true},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.ArchiveFormat, s.OutputMode, s.SinglePDF = tt.format, tt.outputMode, tt.singlePDF
+		if err := s.checkArchiveFormat(); (err != nil) != tt.wantErr {
+			t.Errorf("checkArchiveFormat(%q, output %q, single PDF %v) error = %v, want error %v", tt.format, tt.outputMode, tt.singlePDF, err, tt.wantErr)
+		}
+	}
+}
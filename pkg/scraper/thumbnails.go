@@ -0,0 +1,97 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// thumbWidth is the width in pixels of page previews; the height follows
+// the aspect ratio of the page.
+const thumbWidth = 150
+
+// thumbName returns the archive entry of the page's preview.
+func (s *Scraper) thumbName(p *page) string {
+	return "thumbs/" + strings.TrimSuffix(s.baseEntryName(p.URL), ".pdf") + ".png"
+}
+
+// pdfThumbnail rasterizes the first page of the PDF file into a preview.
+// The page is drawn at twice the preview width and scaled down, which
+// smooths the tiny text.
+func pdfThumbnail(filename string) ([]byte, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	page, err := rasterizePDF(data, 2*thumbWidth)
+	if err != nil {
+		return nil, err
+	}
+	img := scaleImage(page, thumbWidth)
+
+	b := img.Bounds()
+	border := color.Gray{Y: 200}
+	for x := b.Min.X; x < b.Max.X; x++ {
+		img.Set(x, b.Min.Y, border)
+		img.Set(x, b.Max.Y-1, border)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		img.Set(b.Min.X, y, border)
+		img.Set(b.Max.X-1, y, border)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbnail sets the preview of p from its PDF when Thumbnails is set.
+func (s *Scraper) thumbnail(p *page) error {
+	if !s.Thumbnails {
+		return nil
+	}
+	thumb, err := pdfThumbnail(p.File)
+	if err != nil {
+		return fmt.Errorf("failed to draw thumbnail: %w", err)
+	}
+	p.Thumb = thumb
+	return nil
+}
+
+// scaleImage shrinks src to width pixels, keeping its aspect ratio, by
+// averaging the source pixels under each destination pixel.
+func scaleImage(src image.Image, width int) *image.RGBA {
+	b := src.Bounds()
+	if b.Dx() <= width {
+		dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0 := b.Min.Y + dy*b.Dy()/height
+		y1 := max(y0+1, b.Min.Y+(dy+1)*b.Dy()/height)
+		for dx := 0; dx < width; dx++ {
+			x0 := b.Min.X + dx*b.Dx()/width
+			x1 := max(x0+1, b.Min.X+(dx+1)*b.Dx()/width)
+			var r, g, bl, a, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					cr, cg, cb, ca := src.At(x, y).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(dx, dy, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPDFThumbnail(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantInk bool
+		stamped bool
+	}{
+		{"empty page", "", false, false},
+		{"text", "Title\n\nSome paragraph of text.\n\n", true, false},
+		{"stamped", "", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse("https://example.com/docs")
+			s := NewScraper(true, false)
+			s.StampSource = tt.stamped
+			p := &page{URL: u, Text: tt.text, File: filepath.Join(t.TempDir(), "page.pdf")}
+			if err := s.createPDF(p.File, p); err != nil {
+				t.Fatal(err)
+			}
+			data, err := pdfThumbnail(p.File)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Bounds().Dx() != thumbWidth || img.Bounds().Dy() != 212 {
+				t.Errorf("size = %v", img.Bounds())
+			}
+			if got := hasInk(img); got != tt.wantInk {
+				t.Errorf("ink = %v, want %v", got, tt.wantInk)
+			}
+		})
+	}
+}
+
+func TestPDFThumbnailLayout(t *testing.T) {
+	u, _ := url.Parse("https://example.com/diagrams/flow")
+	s := NewScraper(true, false)
+	s.Thumbnails = true
+	s.PageSize = PageSizeLetter
+	s.Margins = &Margins{Top: 20, Right: 20, Bottom: 20, Left: 120}
+	s.RenderOverrides = []*RenderOverride{{Match: "/diagrams/*", Orientation: OrientationLandscape}}
+	if err := s.checkRenderOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	p := &page{URL: u, Text: "A line of text long enough to wrap", File: filepath.Join(t.TempDir(), "page.pdf")}
+	if err := s.renderText(p); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(p.Thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Letter is 279.4mm by 215.9mm in landscape, give or take rounding
+	if want := thumbWidth * 2159 / 2794; abs(img.Bounds().Dy()-want) > 1 {
+		t.Errorf("height = %d, want %d", img.Bounds().Dy(), want)
+	}
+	// Nothing is drawn in the left margin
+	left := image.Rect(1, 1, thumbWidth*120*10/2794-1, img.Bounds().Dy()-1)
+	if hasInk(img.(*image.RGBA).SubImage(left)) {
+		t.Error("text drawn in the left margin")
+	}
+	if !hasInk(img) {
+		t.Error("no text drawn")
+	}
+}
+
+func TestPDFThumbnailInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "page.pdf")
+	if err := os.WriteFile(file, []byte("<html>not a PDF</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pdfThumbnail(file); err == nil {
+		t.Error("pdfThumbnail() succeeded on an HTML file")
+	}
+}
+
+// hasInk reports whether img has non-white pixels inside its border.
+func hasInk(img image.Image) bool {
+	b := img.Bounds()
+	for y := b.Min.Y + 1; y < b.Max.Y-1; y++ {
+		for x := b.Min.X + 1; x < b.Max.X-1; x++ {
+			if r, g, bl, _ := img.At(x, y).RGBA(); r != 0xffff || g != 0xffff || bl != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestScaleImage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 600, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 300; x++ {
+			src.Set(x, y, color.Black)
+		}
+		for x := 300; x < 600; x++ {
+			src.Set(x, y, color.White)
+		}
+	}
+
+	dst := scaleImage(src, 150)
+	if dst.Bounds().Dx() != 150 || dst.Bounds().Dy() != 100 {
+		t.Fatalf("size = %v, want 150x100", dst.Bounds())
+	}
+	if r, _, _, _ := dst.At(10, 50).RGBA(); r != 0 {
+		t.Errorf("left half not black: %v", dst.At(10, 50))
+	}
+	if r, _, _, _ := dst.At(140, 50).RGBA(); r != 0xffff {
+		t.Errorf("right half not white: %v", dst.At(140, 50))
+	}
+}
+
+func TestIndexHTML(t *testing.T) {
+	u, _ := url.Parse("https://example.com/docs/intro")
+	s := NewScraper(false, false)
+	s.pdfs = []*page{
+		{URL: u, Title: "Intro <b>", Thumb: []byte("png")},
+		{URL: &url.URL{Scheme: "https", Host: "example.com", Path: "/untitled"}},
+	}
+
+	data, err := s.indexHTML("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+	for _, want := range []string{
+		`href="example.com_docs_intro.pdf"`,
+		`src="thumbs/example.com_docs_intro.png"`,
+		`Intro &lt;b&gt;`,
+		`>example.com_untitled.pdf</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("index.html missing %s", want)
+		}
+	}
+}
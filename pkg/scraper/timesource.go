@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultTimeServer is the NTP server evidence timestamps are taken from.
+const DefaultTimeServer = "time.cloudflare.com"
+
+// ntpEpochOffset is the number of seconds between the NTP epoch (1900) and
+// the Unix epoch (1970).
+const ntpEpochOffset = 2208988800
+
+// timeSource records how evidence timestamps were obtained.
+type timeSource struct {
+	Server string `json:"server,omitempty"`
+	// Offset is added to the local clock, in milliseconds.
+	Offset    float64    `json:"offset_ms"`
+	QueriedAt *time.Time `json:"queried_at,omitempty"`
+	// Error explains why the local clock was used instead of the server.
+	Error string `json:"error,omitempty"`
+}
+
+// ntpTime converts a 64-bit NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(b[0:4])
+	fraction := binary.BigEndian.Uint32(b[4:8])
+	nanos := (int64(fraction) * 1e9) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, nanos)
+}
+
+// ntpOffset asks server, over SNTP, how far the local clock is off.
+func ntpOffset(server string, timeout time.Duration) (time.Duration, error) {
+	conn, err := net.DialTimeout("udp", net.JoinHostPort(server, "123"), timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // no leap warning, version 4, client mode
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	return parseNTPResponse(resp[:n], sent, received)
+}
+
+// parseNTPResponse returns the clock offset from an SNTP reply, given when
+// the request was sent and the reply received by the local clock.
+func parseNTPResponse(resp []byte, sent, received time.Time) (time.Duration, error) {
+	if len(resp) < 48 {
+		return 0, fmt.Errorf("short NTP reply of %d bytes", len(resp))
+	}
+	if mode := resp[0] & 0x07; mode != 4 {
+		return 0, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	if resp[0]>>6 == 3 || resp[1] == 0 {
+		return 0, fmt.Errorf("NTP server is not synchronised")
+	}
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// syncClock sets the offset of trustedNow from TimeServer. When the server
+// can't be reached the local clock is used, and the report says so.
+func (s *Scraper) syncClock() {
+	s.clock = timeSource{Server: s.TimeServer}
+	if s.TimeServer == "" {
+		s.clock.Error = "no time server configured"
+		return
+	}
+	offset, err := ntpOffset(s.TimeServer, 5*time.Second)
+	if err != nil {
+		s.logf("Warning: could not query time server %s, using the local clock: %v\n", s.TimeServer, err)
+		s.clock.Error = err.Error()
+		return
+	}
+	s.clockOffset = offset
+	s.clock.Offset = milliseconds(offset)
+	queried := s.trustedNow()
+	s.clock.QueriedAt = &queried
+}
+
+// trustedNow returns the current time corrected by the time server.
+func (s *Scraper) trustedNow() time.Time {
+	return time.Now().Add(s.clockOffset).UTC()
+}
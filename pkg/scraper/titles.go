@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// titleSeparator joins the parent sections naming a page apart from others
+// with its title.
+const titleSeparator = " → "
+
+// disambiguateTitles names the archived pages sharing a title, such as the
+// Overview of every section, apart in the table of contents, bookmarks and
+// index.html: their title is followed by their parent sections, as few as
+// tell them apart, e.g. "Overview (API → Auth)". A section is named by the
+// title of its archived page, or else by its path segment.
+func (s *Scraper) disambiguateTitles() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.titles = make(map[*page]string)
+	byTitle := make(map[string][]*page)
+	byPath := make(map[string]*page, len(s.pdfs))
+	for _, p := range s.pdfs {
+		if p.Title != "" {
+			byTitle[p.Title] = append(byTitle[p.Title], p)
+		}
+		byPath[sectionKey(p.URL.Host, p.URL.Path)] = p
+	}
+	for title, pages := range byTitle {
+		if len(pages) < 2 {
+			continue
+		}
+		sections := make([][]string, len(pages))
+		deepest := 0
+		for i, p := range pages {
+			sections[i] = parentSections(p.URL, title, byPath)
+			deepest = max(deepest, len(sections[i]))
+		}
+		// Parents are added from the nearest until the labels differ
+		var labels []string
+		for n := 1; n <= deepest; n++ {
+			labels = make([]string, len(pages))
+			seen := make(map[string]bool)
+			distinct := true
+			for i, names := range sections {
+				k := min(n, len(names))
+				parts := make([]string, k)
+				for j := range parts {
+					parts[j] = names[k-1-j]
+				}
+				labels[i] = strings.Join(parts, titleSeparator)
+				if seen[labels[i]] {
+					distinct = false
+				}
+				seen[labels[i]] = true
+			}
+			if distinct {
+				break
+			}
+		}
+		for i, p := range pages {
+			if labels != nil && labels[i] != "" {
+				s.titles[p] = title + " (" + labels[i] + ")"
+			}
+		}
+	}
+}
+
+// displayTitle is the title p is listed under, told apart from the pages
+// sharing its title by disambiguateTitles.
+func (s *Scraper) displayTitle(p *page) string {
+	if title, ok := s.titles[p]; ok {
+		return title
+	}
+	return p.Title
+}
+
+// parentSections returns the names of the sections above the page at u,
+// nearest first. Sections with an archived page are named by its title,
+// unless it is the ambiguous title itself.
+func parentSections(u *url.URL, title string, byPath map[string]*page) []string {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var names []string
+	for i := len(segments) - 2; i >= 0; i-- {
+		if segments[i] == "" {
+			continue
+		}
+		name := sectionName(segments[i])
+		if parent, ok := byPath[sectionKey(u.Host, "/"+strings.Join(segments[:i+1], "/"))]; ok && parent.Title != "" && parent.Title != title {
+			name = parent.Title
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// sectionKey identifies the page at a path whether or not it ends in a
+// slash or index.html.
+func sectionKey(host, p string) string {
+	p = strings.TrimSuffix(p, "/index.html")
+	return host + strings.TrimSuffix(p, "/")
+}
+
+// sectionName returns a path segment as words: "getting-started" becomes
+// "Getting started".
+func sectionName(segment string) string {
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+	segment = strings.TrimSuffix(segment, ".html")
+	segment = strings.Join(strings.FieldsFunc(segment, func(r rune) bool {
+		return r == '-' || r == '_' || r == '+'
+	}), " ")
+	r, size := utf8.DecodeRuneInString(segment)
+	return string(unicode.ToUpper(r)) + segment[size:]
+}
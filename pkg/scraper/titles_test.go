@@ -0,0 +1,58 @@
+package scraper
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDisambiguateTitles(t *testing.T) {
+	mustPage := func(raw, title string) *page {
+		u, _ := url.Parse(raw)
+		return &page{URL: u, Title: title}
+	}
+	s := NewScraper(true, false)
+	s.pdfs = []*page{
+		mustPage("https://example.com/api/", "API"),
+		mustPage("https://example.com/api/auth/overview", "Overview"),
+		mustPage("https://example.com/api/billing/overview", "Overview"),
+		mustPage("https://example.com/guides/auth/overview", "Overview"),
+		mustPage("https://example.com/getting-started/overview/", "Overview"),
+		mustPage("https://example.com/overview", "Overview"),
+		mustPage("https://example.com/about", "About"),
+	}
+
+	s.disambiguateTitles()
+
+	var got []string
+	for _, p := range s.pdfs {
+		got = append(got, s.bookmarkTitle(p))
+	}
+	want := []string{
+		"API",
+		"Overview (API → Auth)",
+		"Overview (API → Billing)",
+		"Overview (Guides → Auth)",
+		"Overview (Getting started)",
+		"Overview",
+		"About",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("titles = %q, want %q", got, want)
+	}
+}
+
+func TestSectionName(t *testing.T) {
+	tests := map[string]string{
+		"auth":            "Auth",
+		"getting-started": "Getting started",
+		"user_guide":      "User guide",
+		"%C3%A9t%C3%A9":   "Été",
+		"install.html":    "Install",
+	}
+	for segment, want := range tests {
+		if got := sectionName(segment); got != want {
+			t.Errorf("sectionName(%q) = %q, want %q", segment, got, want)
+		}
+	}
+}
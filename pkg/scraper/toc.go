@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// tocEntryName is the archive entry of the table of contents.
+const tocEntryName = "_toc.pdf"
+
+// writesTOC reports whether the output gets a table of contents: a PDF of
+// its own in the archive, or the first section of a single PDF.
+func (s *Scraper) writesTOC() bool {
+	return !s.NoTOC && (s.Format == "" || s.Format == FormatPDF)
+}
+
+// tocPDF returns the table of contents of the archive, each title linking
+// to the page's PDF next to it.
+func (s *Scraper) tocPDF(startURL string) ([]byte, error) {
+	t, err := LookupTypography(s.Typography)
+	if err != nil {
+		return nil, err
+	}
+	pdf, err := s.newPDF(t)
+	if err != nil {
+		return nil, err
+	}
+	stampMetadata(pdf, s.Meta, "")
+	pdf.SetTitle("Contents", true)
+	pdf.AddPage()
+	s.writeTOC(pdf, t, startURL, nil)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTOC lists the archived pages from the current page of pdf, with their
+// titles, URLs and archive files. With links, the internal links of the
+// pages in a single PDF, titles point at them instead of the files.
+func (s *Scraper) writeTOC(pdf *gofpdf.Fpdf, t Typography, startURL string, links []int) {
+	left, _, right, _ := pdf.GetMargins()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	_, bottom := pdf.GetAutoPageBreak()
+	width := pageWidth - left - right
+	const smallLine = 4.0
+
+	pdf.SetFont(t.Font, "", t.FontSize+8)
+	pdf.CellFormat(width, t.LineHeight+4, "Contents", "", 1, "L", false, 0, "")
+	pdf.SetFont(fontSans, "", 8)
+	pdf.SetTextColor(96, 96, 96)
+	pdf.CellFormat(width, smallLine, fitText(pdf, fmt.Sprintf("%d pages captured from %s", len(s.pdfs), s.Redact(startURL)), width), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	for i, p := range s.pdfs {
+		heading := s.authorHeading(i)
+		// Keep every entry on one page, and a heading with its first entry
+		needed := t.LineHeight + 2*smallLine
+		if heading != "" {
+			needed += t.LineHeight + 6
+		}
+		if pdf.GetY()+needed > pageHeight-bottom {
+			pdf.AddPage()
+		}
+		if heading != "" {
+			pdf.Ln(2)
+			pdf.SetFont(t.Font, "", t.FontSize+3)
+			pdf.SetTextColor(0, 0, 0)
+			pdf.CellFormat(width, t.LineHeight+4, fitText(pdf, heading, width), "", 1, "L", false, 0, "")
+		}
+		file := s.pageEntryName(p.URL)
+		source := s.Redact(p.URL.String())
+
+		pdf.SetFont(t.Font, "", t.FontSize)
+		pdf.SetTextColor(0, 0, 238)
+		title := fitText(pdf, fmt.Sprintf("%d. %s", i+1, s.bookmarkTitle(p)), width)
+		if links != nil {
+			pdf.CellFormat(width, t.LineHeight, title, "", 1, "L", false, links[i], "")
+		} else {
+			// Relative links open the file when the archive is extracted
+			pdf.CellFormat(width, t.LineHeight, title, "", 1, "L", false, 0, file)
+		}
+
+		pdf.SetFont(fontSans, "", 8)
+		pdf.SetTextColor(96, 96, 96)
+		pdf.CellFormat(width, smallLine, fitText(pdf, source, width), "", 1, "L", false, 0, source)
+		if links == nil {
+			pdf.CellFormat(width, smallLine, fitText(pdf, file, width), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(2)
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTOCPDF(t *testing.T) {
+	s := NewScraper(false, false)
+	home, _ := url.Parse("https://example.com/")
+	guide, _ := url.Parse("https://example.com/guide?token=abc")
+	s.pdfs = []*page{
+		{URL: home, Title: "Welcome"},
+		{URL: guide},
+	}
+	data, err := s.tocPDF("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := pdfText(data)
+	for _, want := range []string{
+		"2 pages captured from https://example.com/",
+		"1. Welcome",
+		"2. https://example.com/guide?token=REDACTED",
+		s.pageEntryName(home),
+		s.pageEntryName(guide),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("table of contents lacks %q\n%s", want, text)
+		}
+	}
+	if !strings.Contains(string(data), "/URI ("+s.pageEntryName(home)+")") {
+		t.Errorf("table of contents doesn't link to %s", s.pageEntryName(home))
+	}
+}
+
+func TestWritesTOC(t *testing.T) {
+	tests := []struct {
+		format string
+		noTOC  bool
+		want   bool
+	}{
+		{"", false, true},
+		{FormatPDF, false, true},
+		{FormatPDF, true, false},
+		{FormatMarkdown, false, false},
+		{FormatEPUB, false, false},
+	}
+	for _, tt := range tests {
+		s := NewScraper(false, false)
+		s.Format, s.NoTOC = tt.format, tt.noTOC
+		if got := s.writesTOC(); got != tt.want {
+			t.Errorf("writesTOC() with format %q, NoTOC %v = %v, want %v", tt.format, tt.noTOC, got, tt.want)
+		}
+	}
+}
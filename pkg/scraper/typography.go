@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Typography bundles the page layout of the text renderer. Lengths are in
+// millimetres.
+type Typography struct {
+	Name string
+	// Font is the font family, fontSans or fontSerif.
+	Font string
+	// FontSize is in points.
+	FontSize float64
+	// LineHeight is the distance between two lines of text.
+	LineHeight float64
+	// Margin is kept free on every side of the page.
+	Margin float64
+	// MaxWidth caps the text column; narrower columns are centred.
+	MaxWidth float64
+}
+
+// defaultTypography is the layout used without a typography preset.
+var defaultTypography = Typography{
+	Name:       "default",
+	Font:       fontSans,
+	FontSize:   12,
+	LineHeight: 10,
+	Margin:     10,
+	MaxWidth:   190,
+}
+
+var typographies = map[string]Typography{
+	"compact": {
+		Name:       "compact",
+		Font:       fontSans,
+		FontSize:   10,
+		LineHeight: 4.6,
+		Margin:     12,
+		MaxWidth:   186,
+	},
+	"comfortable": {
+		Name:       "comfortable",
+		Font:       fontSans,
+		FontSize:   12,
+		LineHeight: 6.8,
+		Margin:     20,
+		MaxWidth:   140,
+	},
+	"print": {
+		Name:       "print",
+		Font:       fontSerif,
+		FontSize:   11,
+		LineHeight: 5.6,
+		Margin:     25,
+		MaxWidth:   160,
+	},
+}
+
+// TypographyNames returns the names accepted by LookupTypography.
+func TypographyNames() []string {
+	names := make([]string, 0, len(typographies))
+	for name := range typographies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupTypography returns the typography preset called name. An empty name
+// is the default layout.
+func LookupTypography(name string) (Typography, error) {
+	if name == "" {
+		return defaultTypography, nil
+	}
+	t, ok := typographies[name]
+	if !ok {
+		return Typography{}, fmt.Errorf("unknown typography %q (available: %s)", name, strings.Join(TypographyNames(), ", "))
+	}
+	return t, nil
+}
+
+// sideMargin returns the left and right margin that centres the text column
+// on a page of pageWidth.
+func (t Typography) sideMargin(pageWidth float64) float64 {
+	width := pageWidth - 2*t.Margin
+	if t.MaxWidth > 0 && t.MaxWidth < width {
+		width = t.MaxWidth
+	}
+	return (pageWidth - width) / 2
+}
+
+// apply sets up pdf's margins and font. It must be called before the first
+// page is added.
+func (t Typography) apply(pdf *gofpdf.Fpdf) {
+	pageWidth, _ := pdf.GetPageSize()
+	side := t.sideMargin(pageWidth)
+	pdf.SetMargins(side, t.Margin, side)
+	pdf.SetAutoPageBreak(true, t.Margin)
+}
@@ -0,0 +1,42 @@
+package scraper
+
+import "testing"
+
+func TestLookupTypography(t *testing.T) {
+	for _, name := range TypographyNames() {
+		ty, err := LookupTypography(name)
+		if err != nil {
+			t.Fatalf("LookupTypography(%q) error = %v", name, err)
+		}
+		if ty.Name != name || ty.FontSize <= 0 || ty.LineHeight <= 0 {
+			t.Errorf("LookupTypography(%q) = %+v", name, ty)
+		}
+	}
+	if ty, err := LookupTypography(""); err != nil || ty != defaultTypography {
+		t.Errorf("LookupTypography(\"\") = %+v, %v", ty, err)
+	}
+	if _, err := LookupTypography("huge"); err == nil {
+		t.Error("LookupTypography accepted an unknown name")
+	}
+}
+
+func TestTypographySideMargin(t *testing.T) {
+	tests := []struct {
+		name string
+		ty   Typography
+		want float64
+	}{
+		{"default fills the page", defaultTypography, 10},
+		{"narrow column centred", Typography{Margin: 20, MaxWidth: 150}, 30},
+		{"no max width", Typography{Margin: 15}, 15},
+		{"max width wider than the page", Typography{Margin: 25, MaxWidth: 500}, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ty.sideMargin(210); got != tt.want {
+				t.Errorf("sideMargin(210) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
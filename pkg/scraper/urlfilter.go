@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URLPattern selects URLs for Scraper.Include and Scraper.Exclude.
+type URLPattern struct {
+	Expr string
+	re   *regexp.Regexp
+	full bool // matched against the whole URL instead of its path
+}
+
+// ParseURLPattern parses a glob such as "/docs/*" or "/blog/20??/*",
+// matched against the URL path, or against the whole URL when it contains
+// "://". "*" matches any run of characters, slashes included, and "?" a
+// single character. A pattern starting with "re:" is a regular expression
+// searched for in the whole URL instead.
+func ParseURLPattern(expr string) (URLPattern, error) {
+	if rest, ok := strings.CutPrefix(expr, "re:"); ok {
+		re, err := regexp.Compile(rest)
+		if err != nil {
+			return URLPattern{}, fmt.Errorf("invalid URL pattern %q: %w", expr, err)
+		}
+		return URLPattern{Expr: expr, re: re, full: true}, nil
+	}
+	if expr == "" {
+		return URLPattern{}, fmt.Errorf("empty URL pattern")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range expr {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return URLPattern{Expr: expr, re: regexp.MustCompile(b.String()), full: strings.Contains(expr, "://")}, nil
+}
+
+// match reports whether u matches the pattern.
+func (p URLPattern) match(u *url.URL) bool {
+	if p.full {
+		return p.re.MatchString(u.String())
+	}
+	return p.re.MatchString(u.Path)
+}
+
+// urlAllowed reports whether u passes Include and Exclude: it matches one
+// of the include patterns, if there are any, and none of the excludes.
+func (s *Scraper) urlAllowed(u *url.URL) bool {
+	for _, p := range s.Exclude {
+		if p.match(u) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, p := range s.Include {
+		if p.match(u) {
+			return true
+		}
+	}
+	return false
+}
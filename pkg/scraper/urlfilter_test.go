@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestURLAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		url     string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "https://example.com/anything", true},
+		{"included", []string{"/docs/*"}, nil, "https://example.com/docs/guide/install", true},
+		{"not included", []string{"/docs/*"}, nil, "https://example.com/blog/post", false},
+		{"excluded", nil, []string{"/tags/*"}, "https://example.com/tags/go", false},
+		{"exclude wins", []string{"/docs/*"}, []string{"/docs/old/*"}, "https://example.com/docs/old/page", false},
+		{"single character", []string{"/blog/20??/*"}, nil, "https://example.com/blog/2024/post", true},
+		{"glob is anchored", []string{"/docs"}, nil, "https://example.com/docs/page", false},
+		{"full URL glob", []string{"https://example.com/*"}, nil, "http://example.com/page", false},
+		{"regex on full URL", nil, []string{`re:[?&]print=1`}, "https://example.com/page?print=1", false},
+		{"literal dots", []string{"/a.html"}, nil, "https://example.com/axhtml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScraper(false, false)
+			for _, p := range tt.include {
+				pattern, err := ParseURLPattern(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Include = append(s.Include, pattern)
+			}
+			for _, p := range tt.exclude {
+				pattern, err := ParseURLPattern(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Exclude = append(s.Exclude, pattern)
+			}
+			u, _ := url.Parse(tt.url)
+			if got := s.urlAllowed(u); got != tt.want {
+				t.Errorf("urlAllowed(%s) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseURLPatternInvalid(t *testing.T) {
+	for _, p := range []string{"", "re:("} {
+		if _, err := ParseURLPattern(p); err == nil {
+			t.Errorf("ParseURLPattern(%q) accepted an invalid pattern", p)
+		}
+	}
+}
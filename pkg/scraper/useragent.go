@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// browserUserAgents are the user agents RotateUserAgents cycles through,
+// of current desktop and mobile browsers.
+var browserUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36",
+}
+
+// checkUserAgent rejects a UserAgent that isn't a header value, or set
+// along with RotateUserAgents or a User-Agent in Headers.
+func (s *Scraper) checkUserAgent() error {
+	if strings.ContainsAny(s.UserAgent, "\r\n") {
+		return fmt.Errorf("invalid user agent %q", s.UserAgent)
+	}
+	if s.UserAgent != "" && s.RotateUserAgents {
+		return fmt.Errorf("a user agent can't be set when rotating user agents")
+	}
+	if (s.UserAgent != "" || s.RotateUserAgents) && s.Headers.Get("User-Agent") != "" {
+		return fmt.Errorf("the User-Agent header can't be set along with a user agent")
+	}
+	return nil
+}
+
+// nextUserAgent returns the user agent of the next request when user
+// agents rotate, each of browserUserAgents in turn.
+func (s *Scraper) nextUserAgent() string {
+	n := s.userAgentTurn.Add(1) - 1
+	return browserUserAgents[n%uint64(len(browserUserAgents))]
+}
+
+// requestUserAgent returns the user agent the next request of the crawl
+// is sent with, "" for the collector's own.
+func (s *Scraper) requestUserAgent() string {
+	if s.RotateUserAgents {
+		return s.nextUserAgent()
+	}
+	return s.UserAgent
+}
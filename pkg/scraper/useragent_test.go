@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// userAgentServer serves a page linking to three others, recording the
+// user agent of every request.
+func userAgentServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var agents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.UserAgent())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body><p>Page</p><a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a></body></html>`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), agents...)
+	}
+}
+
+func TestCustomUserAgent(t *testing.T) {
+	srv, agents := userAgentServer(t)
+	s := NewScraper(true, false)
+	s.UserAgent = "Mozilla/5.0 (compatible; Archiver/1.0)"
+	if err := s.ScrapeAndSave(srv.URL+"/", filepath.Join(t.TempDir(), "site.zip")); err != nil {
+		t.Fatal(err)
+	}
+	got := agents()
+	if len(got) != 4 {
+		t.Fatalf("%d requests, want 4", len(got))
+	}
+	for _, ua := range got {
+		if ua != s.UserAgent {
+			t.Errorf("request sent as %q, want %q", ua, s.UserAgent)
+		}
+	}
+}
+
+func TestRotateUserAgents(t *testing.T) {
+	srv, agents := userAgentServer(t)
+	s := NewScraper(true, false)
+	s.RotateUserAgents = true
+	if err := s.ScrapeAndSave(srv.URL+"/", filepath.Join(t.TempDir(), "site.zip")); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, ua := range agents() {
+		if !strings.HasPrefix(ua, "Mozilla/5.0 (") {
+			t.Errorf("request sent as %q, want a browser", ua)
+		}
+		seen[ua] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("4 requests sent with %d user agents, want one each", len(seen))
+	}
+}
+
+func TestCheckUserAgent(t *testing.T) {
+	tests := []struct {
+		ua     string
+		rotate bool
+		header string
+		ok     bool
+	}{
+		{"", false, "", true},
+		{"Archiver/1.0", false, "", true},
+		{"", true, "", true},
+		{"", false, "Archiver/1.0", true},
+		{"Archiver/1.0\r\nX-Evil: 1", false, "", false},
+		{"Archiver/1.0", true, "", false},
+		{"Archiver/1.0", false, "Other/2.0", false},
+		{"", true, "Other/2.0", false},
+	}
+	for _, tt := range tests {
+		s := NewScraper(true, false)
+		s.UserAgent, s.RotateUserAgents = tt.ua, tt.rotate
+		if tt.header != "" {
+			s.Headers = http.Header{"User-Agent": {tt.header}}
+		}
+		if err := s.checkUserAgent(); (err == nil) != tt.ok {
+			t.Errorf("checkUserAgent() with %+v = %v", tt, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/html"
+)
+
+// Simplified page variants accepted by Scraper.PreferVariant.
+const (
+	VariantAMP   = "amp"
+	VariantPrint = "print"
+)
+
+// ctxVariantOf marks a request for a page's variant in the colly context;
+// the value is the canonical page URL.
+const ctxVariantOf = "variantOf"
+
+// findVariant returns the absolute URL of the page's AMP or print variant on
+// the same host, or "" if the page doesn't advertise one.
+func findVariant(body []byte, base *url.URL, kind string) string {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data != "link" && t.Data != "a" {
+				continue
+			}
+			attrs := make(map[string]string)
+			for _, a := range t.Attr {
+				attrs[a.Key] = a.Val
+			}
+			href := strings.TrimSpace(attrs["href"])
+			if href == "" {
+				continue
+			}
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			target := stripFragment(base.ResolveReference(ref))
+			if target.Host != base.Host || target.String() == stripFragment(base).String() {
+				continue
+			}
+
+			rel := strings.Fields(strings.ToLower(attrs["rel"]))
+			switch kind {
+			case VariantAMP:
+				if t.Data == "link" && hasDirective(rel, "amphtml") {
+					return target.String()
+				}
+			case VariantPrint:
+				if t.Data == "link" && hasDirective(rel, "alternate") && strings.Contains(strings.ToLower(attrs["media"]), "print") {
+					return target.String()
+				}
+				if isPrintURL(target) {
+					return target.String()
+				}
+			}
+		}
+	}
+}
+
+// isPrintURL reports whether u looks like a printer friendly page, e.g.
+// ?print=1 or /print/.
+func isPrintURL(u *url.URL) bool {
+	if v := strings.ToLower(u.Query().Get("print")); v == "1" || v == "true" || v == "yes" {
+		return true
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if strings.EqualFold(segment, "print") {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchVariant fetches the preferred variant of p through the collector, so
+// it gets the same headers and limits, and swaps its body into p. The page
+// keeps its canonical URL for naming and deduplication. On failure p is left
+// unchanged.
+func (s *Scraper) fetchVariant(c *colly.Collector, p *page) {
+	variant := findVariant(p.Body, p.URL, s.PreferVariant)
+	if variant == "" {
+		return
+	}
+
+	if c.Async {
+		// The variant is needed before the page is processed, so it is
+		// fetched synchronously, still sharing the collector's limits
+		fetcher := c.Clone()
+		fetcher.Async = false
+		fetcher.OnResponse(func(r *colly.Response) {
+			r.Ctx.Put("variantBody", r.Body)
+		})
+		c = fetcher
+	}
+
+	ctx := colly.NewContext()
+	ctx.Put(ctxVariantOf, p.URL.String())
+	if err := c.Request("GET", variant, nil, ctx, nil); err != nil {
+		s.logf("Could not fetch %s variant of %s: %v\n", s.PreferVariant, p.URL, err)
+		return
+	}
+	body, ok := ctx.GetAny("variantBody").([]byte)
+	if !ok {
+		return
+	}
+	p.Body = body
+	p.Variant = variant
+}
+
+// contentBase returns the URL the links in the body of p are relative to:
+// the variant's when its content came from one.
+func contentBase(p *page) *url.URL {
+	if p.Variant != "" {
+		if u, err := url.Parse(p.Variant); err == nil {
+			return u
+		}
+	}
+	return p.URL
+}
+
+// validVariant checks the PreferVariant setting.
+func validVariant(kind string) error {
+	switch kind {
+	case "", VariantAMP, VariantPrint:
+		return nil
+	}
+	return fmt.Errorf("unknown page variant %q (available: %s, %s)", kind, VariantAMP, VariantPrint)
+}
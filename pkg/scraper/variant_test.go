@@ -0,0 +1,62 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFindVariant(t *testing.T) {
+	base, _ := url.Parse("https://example.com/news/story")
+
+	tests := []struct {
+		name string
+		html string
+		kind string
+		want string
+	}{
+		{
+			name: "amphtml link",
+			html: `<head><link rel="amphtml" href="/amp/news/story"></head>`,
+			kind: VariantAMP,
+			want: "https://example.com/amp/news/story",
+		},
+		{
+			name: "amp on another host ignored",
+			html: `<head><link rel="amphtml" href="https://cdn.ampproject.org/c/example.com/story"></head>`,
+			kind: VariantAMP,
+			want: "",
+		},
+		{
+			name: "print stylesheet alternate",
+			html: `<head><link rel="alternate" media="print" href="story?format=printable"></head>`,
+			kind: VariantPrint,
+			want: "https://example.com/news/story?format=printable",
+		},
+		{
+			name: "print query link",
+			html: `<body><a href="?print=1">Print</a></body>`,
+			kind: VariantPrint,
+			want: "https://example.com/news/story?print=1",
+		},
+		{
+			name: "print path link",
+			html: `<body><a href="/news/story/print/">Print this</a></body>`,
+			kind: VariantPrint,
+			want: "https://example.com/news/story/print/",
+		},
+		{
+			name: "amp link not used for print",
+			html: `<head><link rel="amphtml" href="/amp/news/story"></head>`,
+			kind: VariantPrint,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findVariant([]byte(tt.html), base, tt.kind); got != tt.want {
+				t.Errorf("findVariant() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
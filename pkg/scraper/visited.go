@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// VisitedStore records the pages fetched by several crawls of the same
+// process, such as the jobs of a batch, so pages they share, e.g. on a
+// common documentation host, are fetched and archived once. It is safe for
+// concurrent use by crawls running at the same time.
+type VisitedStore struct {
+	owners sync.Map // map[url]ID of the crawl that fetched it, see crawlIDs
+}
+
+// crawlIDs numbers the crawls sharing a VisitedStore, so the store
+// doesn't keep the crawls that finished alive.
+var crawlIDs atomic.Uint64
+
+// NewVisitedStore returns an empty store to share between Scrapers.
+func NewVisitedStore() *VisitedStore {
+	return &VisitedStore{}
+}
+
+// claim records that the crawl numbered id fetches u, and reports whether
+// u is its to fetch: no other crawl fetched it before.
+func (v *VisitedStore) claim(u string, id uint64) bool {
+	owner, _ := v.owners.LoadOrStore(u, id)
+	return owner == id
+}
+
+// Len returns the number of pages fetched by the crawls sharing v.
+func (v *VisitedStore) Len() int {
+	n := 0
+	v.owners.Range(func(any, any) bool {
+		n++
+		return true
+	})
+	return n
+}
@@ -0,0 +1,106 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSharedVisitedStore(t *testing.T) {
+	var common atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/guide/", "/api/":
+			fmt.Fprintf(w, `<html><body><p>Start of %s</p><a href="/common">Common</a> <a href="%sown">Own</a></body></html>`, r.URL.Path, r.URL.Path)
+		case "/common":
+			common.Add(1)
+			fmt.Fprint(w, `<html><body><p>Shared page</p></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><p>Own page</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	shared := NewVisitedStore()
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	zipnames := make([]string, 2)
+	for i, start := range []string{"/guide/", "/api/"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := NewScraper(true, false)
+			s.Shared = shared
+			zipnames[i] = filepath.Join(dir, fmt.Sprintf("job%d.zip", i))
+			if err := s.ScrapeAndSave(srv.URL+start, zipnames[i]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	if got := common.Load(); got != 1 {
+		t.Errorf("shared page fetched %d times, want once", got)
+	}
+	archived := 0
+	for i, zipname := range zipnames {
+		e := zipEntries(t, zipname)
+		if _, ok := e[host+"_common.pdf"]; ok {
+			archived++
+		}
+		for _, own := range []string{"_guide_own.pdf", "_api_own.pdf"}[i : i+1] {
+			if _, ok := e[host+own]; !ok {
+				t.Errorf("job %d lacks its own page %s", i, own)
+			}
+		}
+	}
+	if archived != 1 {
+		t.Errorf("shared page archived by %d jobs, want 1", archived)
+	}
+	if n := shared.Len(); n != 5 {
+		t.Errorf("store has %d pages, want 5", n)
+	}
+}
+
+func TestSharedVisitedStoreReusedScraper(t *testing.T) {
+	var common atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/guide/", "/api/":
+			fmt.Fprintf(w, `<html><body><p>Start of %s</p><a href="/common">Common</a></body></html>`, r.URL.Path)
+		default:
+			common.Add(1)
+			fmt.Fprint(w, `<html><body><p>Shared page</p></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	// One Scraper runs the jobs one after the other
+	s := NewScraper(true, false)
+	s.Shared = NewVisitedStore()
+	dir := t.TempDir()
+	var ids []uint64
+	for i, start := range []string{"/guide/", "/api/"} {
+		if err := s.ScrapeAndSave(srv.URL+start, filepath.Join(dir, fmt.Sprintf("job%d.zip", i))); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.crawlID)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("both crawls have ID %d", ids[0])
+	}
+	if got := common.Load(); got != 1 {
+		t.Errorf("shared page fetched %d times, want once", got)
+	}
+}
@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WARC holds the HTTP responses of a WARC file, the web archive format
+// written by wget --warc-file, Heritrix and browser-based crawlers. It
+// serves them as a transport, so a crawl can be replayed without the
+// network.
+type WARC struct {
+	Path      string
+	responses map[string]*warcResponse // by target URL
+	startURL  string
+}
+
+// warcResponse is a response record of a WARC file.
+type warcResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	date   time.Time // when it was captured
+}
+
+// OpenWARC reads the response records of the WARC file at path, gzipped or
+// not. When a URL was captured more than once the last capture is kept.
+func OpenWARC(path string) (*WARC, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		// Every record is usually a gzip member of its own, which the
+		// reader reads as one stream
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	w := &WARC{Path: path, responses: make(map[string]*warcResponse)}
+	tp := textproto.NewReader(bufio.NewReader(r))
+	for n := 1; ; n++ {
+		target, resp, err := readWARCRecord(tp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", path, n, err)
+		}
+		if resp == nil {
+			continue
+		}
+		w.responses[target] = resp
+		if w.startURL == "" && resp.status == http.StatusOK && strings.Contains(resp.header.Get("Content-Type"), "html") {
+			w.startURL = target
+		}
+	}
+	if len(w.responses) == 0 {
+		return nil, fmt.Errorf("%s has no HTTP responses", path)
+	}
+	return w, nil
+}
+
+// readWARCRecord reads the next record from r, returning its target URL and
+// response, or a nil response for other records such as requests and
+// metadata.
+func readWARCRecord(r *textproto.Reader) (string, *warcResponse, error) {
+	version, err := r.ReadLine()
+	for err == nil && version == "" {
+		// Records are separated by blank lines
+		version, err = r.ReadLine()
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return "", nil, fmt.Errorf("not a WARC record: %.40q", version)
+	}
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		return "", nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r.R, block); err != nil {
+		return "", nil, fmt.Errorf("truncated record: %w", err)
+	}
+
+	if header.Get("WARC-Type") != "response" || !strings.HasPrefix(header.Get("Content-Type"), "application/http") {
+		return "", nil, nil
+	}
+	// WARC 1.0 allowed the URI in angle brackets
+	target := strings.Trim(header.Get("WARC-Target-URI"), "<>")
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid HTTP response for %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid HTTP response for %s: %w", target, err)
+	}
+	date, _ := time.Parse(time.RFC3339, header.Get("WARC-Date"))
+	return target, &warcResponse{status: resp.StatusCode, header: resp.Header, body: body, date: date}, nil
+}
+
+// StartURL returns the first HTML page captured, where a replayed crawl
+// starts unless told otherwise.
+func (w *WARC) StartURL() string {
+	return w.startURL
+}
+
+// Len returns the number of URLs captured.
+func (w *WARC) Len() int {
+	return len(w.responses)
+}
+
+// RoundTrip serves the captured response for the request's URL. URLs that
+// were not captured fail.
+func (w *WARC) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, ok := w.responses[req.URL.String()]
+	if !ok || req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%s %s is not in %s", req.Method, req.URL, w.Path)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.status, http.StatusText(resp.status)),
+		StatusCode:    resp.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(resp.body)),
+		ContentLength: int64(len(resp.body)),
+		Request:       req,
+	}, nil
+}
+
+// capturedAt returns when the response for u was captured, if it was.
+func (w *WARC) capturedAt(u *url.URL) (time.Time, bool) {
+	resp, ok := w.responses[u.String()]
+	if !ok || resp.date.IsZero() {
+		return time.Time{}, false
+	}
+	return resp.date, true
+}
+
+// offlineTransport fails every request, so a crawl answered from CacheDir
+// never goes to the network for what the cache doesn't have.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%s is not in the cache", req.URL)
+}
+
+// cacheFile returns the file colly keeps the response for u in, under the
+// cache directory dir.
+func cacheFile(dir string, u *url.URL) string {
+	sum := sha1.Sum([]byte(u.String()))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(dir, name[:2], name)
+}
+
+// fetchedAt returns when the response for u was captured: the WARC-Date of
+// a replayed response, when it was stored in CacheDir, or now.
+func (s *Scraper) fetchedAt(u *url.URL) time.Time {
+	if s.WARC != nil {
+		if date, ok := s.WARC.capturedAt(u); ok {
+			return date
+		}
+	}
+	if s.CacheDir != "" {
+		if info, err := os.Stat(cacheFile(s.CacheDir, u)); err == nil {
+			return info.ModTime().UTC()
+		}
+	}
+	return s.trustedNow()
+}
+
+// offline reports whether the crawl is answered without the network.
+func (s *Scraper) offline() bool {
+	return s.Offline || s.WARC != nil
+}
+
+// replayedFrom returns the WARC file or cache an offline crawl is answered
+// from, or "".
+func (s *Scraper) replayedFrom() string {
+	switch {
+	case s.WARC != nil:
+		return s.WARC.Path
+	case s.Offline:
+		return s.CacheDir
+	}
+	return ""
+}
+
+// checkOffline rejects the options that need the live site.
+func (s *Scraper) checkOffline() error {
+	switch {
+	case !s.offline():
+		return nil
+	case s.WARC == nil && s.CacheDir == "":
+		return fmt.Errorf("an offline crawl needs a WARC file or a cache to answer it")
+	case s.printsLivePage():
+		return fmt.Errorf("the %s renderer prints the live page, render offline with the %s renderer", s.Renderer, RendererText)
+	case s.RenderJS:
+		return fmt.Errorf("pages can't be rendered with JavaScript offline, the browser would fetch them again")
+	case s.Session != nil:
+		return fmt.Errorf("sessions log in to the live site, they can't be used offline")
+	case s.Evidence:
+		return fmt.Errorf("evidence records the live responses, it can't be gathered offline")
+	}
+	return nil
+}
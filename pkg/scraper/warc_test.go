@@ -0,0 +1,122 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// warcRecord returns a WARC record of the given type holding block.
+func warcRecord(typ, target, date, contentType, block string) string {
+	return fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\nWARC-Target-URI: %s\r\nWARC-Date: %s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		typ, target, date, contentType, len(block), block)
+}
+
+func TestOpenWARC(t *testing.T) {
+	records := []string{
+		warcRecord("warcinfo", "", "2024-03-01T10:00:00Z", "application/warc-fields", "software: test\r\n"),
+		warcRecord("request", "https://example.com/", "2024-03-01T10:00:00Z", "application/http; msgtype=request",
+			"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		warcRecord("response", "https://example.com/style.css", "2024-03-01T10:00:00Z", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/css\r\nContent-Length: 3\r\n\r\np{}"),
+		warcRecord("response", "<https://example.com/>", "2024-03-01T10:00:01Z", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nTransfer-Encoding: chunked\r\n\r\n6\r\n<p>Hi \r\n5\r\nthere\r\n0\r\n\r\n"),
+		warcRecord("response", "https://example.com/old", "2024-03-01T10:00:02Z", "application/http; msgtype=response",
+			"HTTP/1.1 301 Moved Permanently\r\nLocation: https://example.com/\r\n\r\n"),
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "site.warc")
+	if err := os.WriteFile(plain, []byte(strings.Join(records, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Gzipped WARCs compress every record as a gzip member of its own
+	var gz bytes.Buffer
+	for _, r := range records {
+		w := gzip.NewWriter(&gz)
+		io.WriteString(w, r)
+		w.Close()
+	}
+	gzipped := filepath.Join(dir, "site.warc.gz")
+	if err := os.WriteFile(gzipped, gz.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{plain, gzipped} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			w, err := OpenWARC(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Len() != 3 || w.StartURL() != "https://example.com/" {
+				t.Errorf("Len() = %d, StartURL() = %q", w.Len(), w.StartURL())
+			}
+
+			client := &http.Client{Transport: w}
+			resp, err := client.Get("https://example.com/old")
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || string(body) != "<p>Hi there" || resp.Request.URL.Path != "/" {
+				t.Errorf("replayed %d %q from %s", resp.StatusCode, body, resp.Request.URL)
+			}
+			if _, err := client.Get("https://example.com/missing"); err == nil || !strings.Contains(err.Error(), "is not in") {
+				t.Errorf("uncaptured URL: %v", err)
+			}
+
+			u, _ := url.Parse("https://example.com/")
+			s := &Scraper{WARC: w}
+			if got := s.fetchedAt(u); !got.Equal(time.Date(2024, 3, 1, 10, 0, 1, 0, time.UTC)) {
+				t.Errorf("fetchedAt() = %v, want the WARC-Date", got)
+			}
+		})
+	}
+
+	if _, err := OpenWARC(filepath.Join(dir, "missing.warc")); err == nil {
+		t.Error("opened a missing file")
+	}
+	notWARC := filepath.Join(dir, "page.html")
+	os.WriteFile(notWARC, []byte("<html></html>"), 0644)
+	if _, err := OpenWARC(notWARC); err == nil || !strings.Contains(err.Error(), "not a WARC record") {
+		t.Errorf("OpenWARC(html) error = %v", err)
+	}
+}
+
+func TestCheckOffline(t *testing.T) {
+	w := &WARC{Path: "site.warc"}
+	tests := []struct {
+		name    string
+		s       *Scraper
+		wantErr string
+	}{
+		{"online", &Scraper{RenderJS: true}, ""},
+		{"warc", &Scraper{WARC: w}, ""},
+		{"cache", &Scraper{Offline: true, CacheDir: "cache"}, ""},
+		{"nothing to answer from", &Scraper{Offline: true}, "needs a WARC file or a cache"},
+		{"chrome", &Scraper{WARC: w, Renderer: RendererChrome}, "prints the live page"},
+		{"javascript", &Scraper{Offline: true, CacheDir: "cache", RenderJS: true}, "JavaScript"},
+		{"session", &Scraper{WARC: w, Session: &Session{Name: "docs"}}, "sessions"},
+		{"evidence", &Scraper{WARC: w, Evidence: true}, "evidence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.checkOffline()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkOffline() = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkOffline() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// zipStream is the ZIP of a crawl being written in the work directory.
+// Pages are added as soon as they are rendered and their files removed, so
+// a large crawl needs little more disk space than its archive. The journal
+// keeps the finished entries recoverable when the run dies.
+type zipStream struct {
+	mu     sync.Mutex
+	file   *os.File
+	zip    *journaledZip
+	closed bool
+}
+
+// streamsZip reports whether pages are written into the ZIP as they are
+// rendered. The other outputs, evidence and estimates read the page files
+// once the crawl is over, so they keep them in the work directory, and so
+// do a cover, which lists the pages and comes before them, and archives
+// split by section, whose pages are grouped once the crawl is over.
+func (s *Scraper) streamsZip() bool {
+	return !s.writesTree() && !s.writesTar() && !s.SinglePDF && s.Format != FormatEPUB && !s.Evidence && s.estimate == nil && s.plan == nil && s.CoverTemplate == "" && s.SplitBy == ""
+}
+
+// resumedArchive returns the path the ZIP of an interrupted run is moved to
+// while its entries are copied to the new one.
+func resumedArchive(dir string) string {
+	return filepath.Join(dir, "archive.resumed.zip")
+}
+
+// openZipStream starts the ZIP in the work directory dir.
+func openZipStream(dir string) (*zipStream, error) {
+	f, err := os.Create(archiveFile(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip file: %w", err)
+	}
+	z, err := newJournaledZip(f, journalFile(dir))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &zipStream{file: f, zip: z}, nil
+}
+
+// add writes entries to the archive.
+func (z *zipStream) add(entries []archiveEntry) error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	for _, entry := range entries {
+		if err := writeZipEntry(z.zip, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish adds extras, completes the archive and moves it to zipname.
+func (z *zipStream) finish(zipname string, extras []archiveEntry) error {
+	if err := z.add(extras); err != nil {
+		return err
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.closed = true
+	if err := z.zip.Close(); err != nil {
+		z.file.Close()
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	if err := z.file.Close(); err != nil {
+		return fmt.Errorf("failed to write zip: %w", err)
+	}
+	return os.Rename(z.file.Name(), zipname)
+}
+
+// abandon flushes the entries written so far and closes the archive
+// without completing it, leaving it and its journal for a resume or
+// RepairArchive.
+func (z *zipStream) abandon() {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.closed {
+		return
+	}
+	z.closed = true
+	z.zip.Writer.Flush()
+	z.zip.journal.Close()
+	z.file.Close()
+}
+
+// streamPage moves the files of p into the archive.
+func (s *Scraper) streamPage(p *page) error {
+	entries := s.pageEntries(p)
+	if err := s.stream.add(entries); err != nil {
+		return fmt.Errorf("failed to write to zip: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.Remove(entry.File); err != nil {
+			s.logf("Warning: failed to remove %s: %v\n", entry.File, err)
+		}
+	}
+	p.Streamed = true
+	return nil
+}
+
+// startArchive opens the ZIP pages are streamed to, in the work directory
+// dir. A resumed run carries over the entries of the pages its checkpoint
+// lists from the interrupted run's ZIP, and returns the pages whose entries
+// were lost with it, to be fetched again.
+func (s *Scraper) startArchive(dir string) ([]queuedURL, error) {
+	if !s.streamsZip() {
+		for _, p := range s.pdfs {
+			if p.Streamed {
+				return nil, fmt.Errorf("the interrupted run wrote its pages into a ZIP, resume it with the same output and without --evidence")
+			}
+		}
+		return nil, nil
+	}
+
+	var entries []journalEntry
+	if s.Resume {
+		var err error
+		entries, err = readJournal(journalFile(dir))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read the journal of the interrupted archive: %w", err)
+		default:
+			if err := os.Rename(archiveFile(dir), resumedArchive(dir)); err != nil {
+				return nil, fmt.Errorf("failed to keep the interrupted archive: %w", err)
+			}
+		}
+	}
+
+	stream, err := openZipStream(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.stream = stream
+	if !s.Resume {
+		return nil, nil
+	}
+
+	// Entries of pages finished after the last checkpoint are dropped,
+	// the pages are fetched again
+	wanted := make(map[string]bool)
+	for _, p := range s.pdfs {
+		if p.Streamed {
+			for _, entry := range s.pageEntries(p) {
+				wanted[entry.Name] = true
+			}
+		}
+	}
+	salvaged := make(map[string]bool)
+	var kept []journalEntry
+	for _, e := range entries {
+		if wanted[e.Name] && !salvaged[e.Name] {
+			salvaged[e.Name] = true
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) > 0 {
+		if err := s.copyResumed(dir, kept); err != nil {
+			return nil, err
+		}
+	}
+	os.Remove(resumedArchive(dir))
+
+	var requeue []queuedURL
+	pages := s.pdfs[:0]
+	for _, p := range s.pdfs {
+		if !p.Streamed {
+			if err := s.streamPage(p); err != nil {
+				return nil, err
+			}
+			pages = append(pages, p)
+			continue
+		}
+		lost := false
+		for _, entry := range s.pageEntries(p) {
+			lost = lost || !salvaged[entry.Name]
+		}
+		if !lost {
+			pages = append(pages, p)
+			continue
+		}
+		// The page's entry was still being written when the run died
+		u := stripFragment(p.URL).String()
+		s.visited.Delete(u)
+		delete(s.state.done, u)
+		s.state.queue[u] = p.Depth
+		requeue = append(requeue, queuedURL{URL: u, Depth: p.Depth})
+	}
+	s.pdfs = pages
+	if len(requeue) > 0 {
+		s.logf("Fetching %d pages again, their files were lost with the interrupted archive\n", len(requeue))
+	}
+	return requeue, nil
+}
+
+// copyResumed copies entries of the interrupted run's ZIP to the stream.
+func (s *Scraper) copyResumed(dir string, entries []journalEntry) error {
+	f, err := os.Open(resumedArchive(dir))
+	if err != nil {
+		return fmt.Errorf("failed to open the interrupted archive: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+	return copySalvaged(s.stream.zip.Create, f, info.Size(), entries)
+}
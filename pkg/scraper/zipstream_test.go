@@ -0,0 +1,130 @@
+package scraper
+
+import (
+	"archive/zip"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamPage(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "example.com.zip")
+	s := NewScraper(true, false)
+	if _, err := s.openWorkDir(output, "https://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.startArchive(s.state.dir); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/a")
+	p := &page{URL: u, File: filepath.Join(s.state.dir, entryName(u))}
+	if err := os.WriteFile(p.File, []byte("pdf of a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.streamPage(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(p.File); !os.IsNotExist(err) || !p.Streamed {
+		t.Errorf("page file kept after streaming: %v", err)
+	}
+	s.pdfs = []*page{p}
+	if err := s.createZip(output, []archiveEntry{{Name: "manifest.json", Data: []byte("{}")}}); err != nil {
+		t.Fatal(err)
+	}
+	got := zipEntries(t, output)
+	if got[entryName(u)] != "pdf of a" || got["manifest.json"] != "{}" || len(got) != 2 {
+		t.Errorf("archive holds %v", got)
+	}
+}
+
+func TestStreamResume(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "example.com.zip")
+	start := "https://example.com/"
+
+	// The run dies right after the checkpoint, while the end of b, the last
+	// entry of the ZIP, isn't journaled yet
+	s := NewScraper(true, false)
+	if _, err := s.openWorkDir(output, start); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.startArchive(s.state.dir); err != nil {
+		t.Fatal(err)
+	}
+	var pages []*page
+	for _, name := range []string{"a", "b"} {
+		u, _ := url.Parse(start + name)
+		p := &page{URL: u, Status: 200, Depth: 2, File: filepath.Join(s.state.dir, entryName(u))}
+		if err := os.WriteFile(p.File, []byte("pdf of "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.streamPage(p); err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, p)
+		s.pdfs = append(s.pdfs, p)
+		s.finish(u.String())
+	}
+	s.saveCheckpoint()
+	s.stream.abandon()
+
+	resumed := NewScraper(true, false)
+	resumed.Resume = true
+	if _, err := resumed.openWorkDir(output, start); err != nil {
+		t.Fatal(err)
+	}
+	lost, err := resumed.startArchive(resumed.state.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 1 || lost[0].URL != start+"b" || lost[0].Depth != 2 {
+		t.Errorf("pages to fetch again = %+v, want b", lost)
+	}
+	if resumed.resumedDone(pages[1].URL) {
+		t.Error("lost page still marked done")
+	}
+	if len(resumed.pdfs) != 1 || resumed.pdfs[0].URL.String() != start+"a" {
+		t.Fatalf("resumed pages %+v, want a", resumed.pdfs)
+	}
+	if err := resumed.createZip(output, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := zipEntries(t, output)
+	if len(got) != 1 || got[entryName(pages[0].URL)] != "pdf of a" {
+		t.Errorf("archive holds %v, want a only", got)
+	}
+
+	// Resuming into an output that doesn't stream would lose a
+	evidence := NewScraper(true, false)
+	evidence.Resume = true
+	evidence.Evidence = true
+	evidence.pdfs = []*page{{URL: pages[0].URL, Streamed: true}}
+	if _, err := evidence.startArchive(t.TempDir()); err == nil {
+		t.Error("resumed streamed pages without a ZIP to stream to")
+	}
+}
+
+// zipEntries returns the content of every entry of the ZIP at name.
+func zipEntries(t *testing.T, name string) map[string]string {
+	t.Helper()
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	entries := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(data)
+	}
+	return entries
+}